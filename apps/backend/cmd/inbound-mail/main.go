@@ -0,0 +1,74 @@
+// Command inbound-mail is the MTA delivery pipe for the inbound mail channel.
+// It reads one raw message on stdin and posts it to the server, e.g. in
+// /etc/aliases:
+//
+//	grievance: "|/usr/local/bin/inbound-mail"
+//
+// Exit codes follow sysexits.h so the MTA retries temporary failures.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	exOK       = 0
+	exDataErr  = 65
+	exTempFail = 75
+	exConfig   = 78
+)
+
+func main() {
+	url := getEnv("INBOUND_MAIL_URL", "http://localhost:8080/api/v1/inbound/email")
+	secret := os.Getenv("INBOUND_MAIL_SECRET")
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, "inbound-mail: INBOUND_MAIL_SECRET is not set")
+		os.Exit(exConfig)
+	}
+
+	raw, err := io.ReadAll(os.Stdin)
+	if err != nil || len(raw) == 0 {
+		fmt.Fprintln(os.Stderr, "inbound-mail: failed to read message from stdin")
+		os.Exit(exDataErr)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inbound-mail: %v\n", err)
+		os.Exit(exConfig)
+	}
+	req.Header.Set("Content-Type", "message/rfc822")
+	req.Header.Set("X-Inbound-Secret", secret)
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inbound-mail: delivery failed: %v\n", err)
+		os.Exit(exTempFail)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		os.Exit(exOK)
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		fmt.Fprintf(os.Stderr, "inbound-mail: rejected: %s\n", body)
+		os.Exit(exDataErr)
+	default:
+		fmt.Fprintf(os.Stderr, "inbound-mail: server returned %s\n", resp.Status)
+		os.Exit(exTempFail)
+	}
+}
+
+func getEnv(key, defaultVal string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultVal
+}
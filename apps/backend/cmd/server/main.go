@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"pixpivot/arc/config"
 	"pixpivot/arc/internal/api/handlers"
 	"pixpivot/arc/internal/api/middleware"
@@ -9,6 +14,7 @@ import (
 	"pixpivot/arc/internal/db"
 	"pixpivot/arc/internal/licensing"
 	"pixpivot/arc/internal/realtime"
+	"pixpivot/arc/internal/storage/blob"
 	"pixpivot/arc/internal/storage/repository"
 
 	"fmt"
@@ -46,10 +52,18 @@ func seedPermissions(db *gorm.DB) {
 		{Name: "consent-forms:manage", Description: "Can manage consent forms"},
 		{Name: "grievances:read", Description: "Can view grievances"},
 		{Name: "grievances:respond", Description: "Can respond to grievances"},
+		{Name: "grievances:manage", Description: "Can configure grievance SLA policies"},
 		{Name: "audit-logs:read", Description: "Can view audit logs"},
 		{Name: "api-keys:manage", Description: "Can manage API keys"},
 		{Name: "breaches:manage", Description: "Can manage breach notifications"},
 		{Name: "dpas:manage", Description: "Can manage Data Processing Agreements"},
+		{Name: "nominees:manage", Description: "Can review and approve nominee claims"},
+		{Name: "data_map:manage", Description: "Can manage the data map and data flows"},
+		{Name: "ropa:manage", Description: "Can generate and sign off the Record of Processing Activities"},
+		{Name: "dpia:manage", Description: "Can create and edit Data Protection Impact Assessments"},
+		{Name: "dpia:approve", Description: "Can approve or reject Data Protection Impact Assessments"},
+		{Name: "sdf:manage", Description: "Can manage Significant Data Fiduciary obligations and the DPO registry"},
+		{Name: "analytics:read", Description: "Can view the analytics dashboard and compliance alerts"},
 	}
 
 	for _, p := range permissions {
@@ -110,6 +124,7 @@ func main() {
 	if err != nil {
 		log.Logger.Fatal().Err(err).Msg("failed to load public key")
 	}
+	documentSigner := services.NewDocumentSigner(privateKey)
 
 	// ==== LICENSING SYSTEM ====
 	// 1. Redis for Usage Tracking
@@ -201,6 +216,12 @@ func main() {
 	userConsentRepo := repository.NewUserConsentRepository(db.MasterDB)
 	webhookSvc := services.NewWebhookService(db.MasterDB)
 
+	// Background jobs: a persistent queue shared by all replicas. Services
+	// register their handlers and cron schedules; the runner starts once the
+	// routes are wired.
+	jobRunner := services.NewJobRunner(repository.NewJobRepository(db.MasterDB), cfg.JobWorkers, cfg.JobPollInterval)
+	webhookSvc.RegisterJobs(jobRunner)
+
 	// SDK Service
 	sdkRepo := repository.NewSDKRepository(db.MasterDB)
 	sdkService := services.NewSDKGeneratorService(sdkRepo, consentFormRepo, cfg.BaseURL)
@@ -209,6 +230,7 @@ func main() {
 	cookieRepo := repository.NewCookieRepository(db.MasterDB)
 	cookieService := services.NewCookieService(cookieRepo)
 	cookieScannerService := services.NewCookieScannerService(cookieRepo)
+	cookieScannerService.RegisterJobs(jobRunner)
 
 	// Email Service (needed by enhanced breach notification)
 	emailService := services.NewEmailService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass, cfg.SMTPFrom)
@@ -222,6 +244,8 @@ func main() {
 	breachEvidenceRepo := repository.NewBreachEvidenceRepository(db.MasterDB)
 	breachTimelineRepo := repository.NewBreachTimelineRepository(db.MasterDB)
 	breachTemplateRepo := repository.NewBreachNotificationTemplateRepository(db.MasterDB)
+	breachRecipientListRepo := repository.NewBreachRecipientListRepository(db.MasterDB)
+	breachSimulationRepo := repository.NewBreachSimulationRepository(db.MasterDB)
 
 	// Legacy breach service (for backward compatibility)
 	breachNotificationSvc := services.NewBreachNotificationService(breachNotificationRepo)
@@ -236,12 +260,12 @@ func main() {
 		breachEvidenceRepo,
 		breachTimelineRepo,
 		breachTemplateRepo,
+		breachSimulationRepo,
 		emailService,
 	)
-
-	// DSR Service
-	dsrRepo := repository.NewDSRRepository(db.MasterDB, nil) // TenantDB is fetched dynamically
-	dsrService := services.NewDSRService(dsrRepo)
+	enhancedBreachNotificationSvc.RegisterJobs(jobRunner)
+	services.NewBreachReportingService(db.MasterDB).RegisterJobs(jobRunner)
+	breachImpactSvc := services.NewBreachImpactService(db.MasterDB, breachNotificationRepo, breachRecipientListRepo, breachTimelineRepo)
 
 	notificationPreferencesRepo := repository.NewNotificationPreferencesRepo(db.MasterDB)
 
@@ -259,9 +283,59 @@ func main() {
 	notificationPreferencesService := services.NewNotificationPreferencesService(notificationPreferencesRepo)
 	notificationService := services.NewNotificationService(notifRepo, notificationPreferencesRepo, emailService, hub, fiduciaryService)
 
+	// Breach notification campaigns: throttled email/SMS/in-app sends with delivery tracking
+	smsService := services.NewSMSService(cfg.SMSGatewayURL, cfg.SMSAPIKey, cfg.SMSSenderID)
+	breachCampaignSvc := services.NewBreachCampaignService(
+		repository.NewBreachCampaignRepository(db.MasterDB),
+		breachNotificationRepo,
+		breachImpactSvc,
+		breachTemplateRepo,
+		tenantRepo,
+		breachTimelineRepo,
+		notifRepo,
+		breachSimulationRepo,
+		hub,
+		emailService,
+		smsService,
+		documentSigner,
+		services.BreachCampaignLimits{
+			services.CampaignChannelEmail: cfg.BreachEmailPerMinute,
+			services.CampaignChannelSMS:   cfg.BreachSMSPerMinute,
+			services.CampaignChannelInApp: cfg.BreachInAppPerMinute,
+		},
+	)
+	breachCampaignSvc.RegisterJobs(jobRunner)
+
+	// Shared file store for uploads (local or S3/MinIO based on config)
+	blobStore := blob.NewStore(
+		cfg.StorageType,
+		cfg.StoragePath,
+		cfg.S3Bucket,
+		cfg.S3Endpoint,
+		cfg.S3AccessKey,
+		cfg.S3SecretKey,
+		cfg.S3Region,
+		cfg.S3UseSSL,
+		cfg.S3ForcePathStyle,
+	)
+
+	// DSR Service
+	dsrRoutingRepo := repository.NewDSRRoutingRepository(db.MasterDB)
+	dsrQueueService := services.NewDSRQueueService(dsrRoutingRepo, notificationService)
+	dsrQueueService.RegisterJobs(jobRunner)
+	dsrRepo := repository.NewDSRRepository(db.MasterDB, nil) // TenantDB is fetched dynamically
+	// Evidence files on grievances and DSRs; no malware scanner is wired by default
+	attachmentService := services.NewAttachmentService(repository.NewAttachmentRepository(db.MasterDB), blobStore, nil)
+	dsrTrackingService := services.NewDSRTrackingService(dsrRepo, emailService, blobStore, attachmentService, cfg.BaseURL)
+	dsrService := services.NewDSRService(dsrRepo, webhookSvc, dsrQueueService, dsrTrackingService)
+
+	// Grievance SLA monitor
+	grievanceSLAMonitor := services.NewGrievanceSLAMonitor(db.MasterDB, notificationService)
+	grievanceSLAMonitor.RegisterJobs(jobRunner)
+
 	// Backup Service
 	backupService := services.NewBackupService(db.MasterDB, cfg)
-	backupService.Start()
+	backupService.RegisterJobs(jobRunner)
 
 	// Receipt Service (supports local or S3/MinIO based on config)
 	receiptRepo := repository.NewReceiptRepository(db.MasterDB)
@@ -282,10 +356,21 @@ func main() {
 		cfg.S3UseSSL,
 		cfg.S3ForcePathStyle,
 	)
+	receiptService.RegisterJobs(jobRunner)
 
 	// User Consent Service (needs receipt service)
 	userConsentSvc := services.NewUserConsentService(userConsentRepo, consentFormRepo, receiptService)
 
+	// Significant Data Fiduciary obligations and the DPO registry, whose
+	// contact details are published on consent notices
+	sdfService := services.NewSDFService(repository.NewSDFRepository(db.MasterDB), tenantRepo, blobStore, notificationService)
+	sdfService.RegisterJobs(jobRunner)
+
+	// Nominee Service (DPDP Section 14)
+	nomineeRepo := repository.NewNomineeRepository(db.MasterDB)
+	nomineeService := services.NewNomineeService(nomineeRepo, emailService, cfg.BaseURL, blobStore)
+	nomineeHandler := handlers.NewNomineeHandler(nomineeService, auditService)
+
 	// Auth middleware
 	dataPrincipalAuth := middleware.RequireDataPrincipalAuth(publicKey)
 	fiduciaryAuth := middleware.RequireFiduciaryAuth(publicKey)
@@ -383,16 +468,47 @@ func main() {
 	}).Methods("GET")
 
 	// ==== USER DSR ====
-	DSRhandlers := handlers.NewDataRequestHandler(db.MasterDB, dsrService, auditService)
-	r.Handle("/api/v1/user/requests", dataPrincipalAuth(http.HandlerFunc(DSRhandlers.ListUserRequests))).Methods("GET")
-	r.Handle("/api/v1/user/requests", dataPrincipalAuth(http.HandlerFunc(DSRhandlers.CreateUserRequest))).Methods("POST")
-	r.Handle("/api/v1/user/requests/{id}", dataPrincipalAuth(http.HandlerFunc(DSRhandlers.GetRequestDetails))).Methods("GET")
+	DSRhandlers := handlers.NewDataRequestHandler(db.MasterDB, dsrService, dsrQueueService, auditService)
+	dsrTrackingHandler := handlers.NewDSRTrackingHandler(dsrTrackingService, auditService)
+	r.Handle("/api/v1/user/requests", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(DSRhandlers.ListUserRequests)))).Methods("GET")
+	r.Handle("/api/v1/user/requests", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(DSRhandlers.CreateUserRequest)))).Methods("POST")
+	r.Handle("/api/v1/user/requests/{id}", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(DSRhandlers.GetRequestDetails)))).Methods("GET")
 
 	// ==== FIDUCIARY DSR ====
 	r.Handle("/api/v1/fiduciary/requests", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(DSRhandlers.ListAdminRequests)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/reassign", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.BulkReassign)))).Methods("POST")
 	r.Handle("/api/v1/fiduciary/requests/{id}", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(DSRhandlers.GetAdminRequestDetails)))).Methods("GET")
 	r.Handle("/api/v1/fiduciary/requests/{id}/approve", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.ApproveRequest)))).Methods("POST")
 	r.Handle("/api/v1/fiduciary/requests/{id}/reject", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.RejectRequest)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/changes", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(DSRhandlers.GetRectificationDiff)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/{id}/changes/{changeId}/review", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.ReviewFieldChange)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/rectify", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.ApplyRectification)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/request-info", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(dsrTrackingHandler.RequestInfo)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/timeline", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(dsrTrackingHandler.GetTimeline)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/{id}/clarifications", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(dsrTrackingHandler.ListClarifications)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/{id}/clarifications/{clarificationId}/file", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(dsrTrackingHandler.DownloadClarificationFile)))).Methods("GET")
+
+	// ===== DSR comments & attachments =====
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, auditService)
+	r.Handle("/api/v1/user/requests/{id}/comments", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(DSRhandlers.ListUserComments)))).Methods("GET")
+	r.Handle("/api/v1/user/requests/{id}/comments", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(DSRhandlers.AddUserComment)))).Methods("POST")
+	r.Handle("/api/v1/user/requests/{id}/attachments", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(attachmentHandler.ListDSRAttachments)))).Methods("GET")
+	r.Handle("/api/v1/user/requests/{id}/attachments", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(attachmentHandler.UploadDSRAttachment)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/comments", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(DSRhandlers.ListAdminComments)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/{id}/comments", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(DSRhandlers.AddAdminComment)))).Methods("POST")
+	r.Handle("/api/v1/fiduciary/requests/{id}/attachments", fiduciaryAuth(middleware.RequirePermission("consents:read")(http.HandlerFunc(attachmentHandler.ListDSRAttachments)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/requests/{id}/attachments", fiduciaryAuth(middleware.RequirePermission("consents:update")(http.HandlerFunc(attachmentHandler.UploadDSRAttachment)))).Methods("POST")
+	r.Handle("/api/v1/user/attachments/{attachmentId}", dataPrincipalAuth(nomineeHandler.ActingAuthority(http.HandlerFunc(attachmentHandler.Download)))).Methods("GET")
+	r.Handle("/api/v1/fiduciary/attachments/{attachmentId}", fiduciaryAuth(middleware.RequirePermission("consents:read", "grievances:read")(http.HandlerFunc(attachmentHandler.Download)))).Methods("GET")
+
+	// ==== DSR ROUTING RULES ====
+	dsrRoutingHandler := handlers.NewDSRRoutingHandler(dsrQueueService)
+	dsrRoutingRouter := r.PathPrefix("/api/v1/fiduciary/dsr-routing-rules").Subrouter()
+	dsrRoutingRouter.Use(fiduciaryAuth, middleware.RequirePermission("roles:manage"))
+	dsrRoutingRouter.HandleFunc("", dsrRoutingHandler.ListRules).Methods("GET")
+	dsrRoutingRouter.HandleFunc("", dsrRoutingHandler.CreateRule).Methods("POST")
+	dsrRoutingRouter.HandleFunc("/{id}", dsrRoutingHandler.UpdateRule).Methods("PUT")
+	dsrRoutingRouter.HandleFunc("/{id}", dsrRoutingHandler.DeleteRule).Methods("DELETE")
 
 	// ==== PURPOSES ====
 	purposeHandler := handlers.NewPurposeHandler(db.MasterDB)
@@ -425,13 +541,31 @@ func main() {
 	fiduciaryGR.HandleFunc("/audit/logs", handlers.GetTenantAuditLogsHandler()).Methods("GET")
 
 	// ==== GRIEVANCES ====
-	grievHandler := handlers.NewGrievanceHandler(notificationService, hub, auditService)
+	grievHandler := handlers.NewGrievanceHandler(notificationService, hub, auditService, emailService, cfg.InboundMailAddress, cfg.InboundMailSecret)
 	r.Handle("/api/v1/dashboard/grievances", dataPrincipalAuth(http.HandlerFunc(grievHandler.Create))).Methods("POST")
 	r.Handle("/api/v1/dashboard/grievances", dataPrincipalAuth(http.HandlerFunc(grievHandler.ListForUser))).Methods("GET")
 
 	fiduciaryGR.Use(fiduciaryAuth)
 	fiduciaryGR.Handle("/grievances", middleware.RequirePermission("grievances:read")(http.HandlerFunc(grievHandler.List))).Methods("GET")
 	fiduciaryGR.Handle("/grievances/{id}", middleware.RequirePermission("grievances:respond")(http.HandlerFunc(grievHandler.Update))).Methods("PUT")
+	fiduciaryGR.Handle("/grievances/{id}/escalations", middleware.RequirePermission("grievances:read")(http.HandlerFunc(grievHandler.ListEscalations))).Methods("GET")
+	r.Handle("/api/v1/dashboard/grievances/{id}/dpb-complaint", dataPrincipalAuth(http.HandlerFunc(grievHandler.ReportDPBComplaint))).Methods("POST")
+
+	// ===== Grievance SLA policies =====
+	fiduciaryGR.Handle("/grievance-sla-policies", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.ListSLAPolicies))).Methods("GET")
+	fiduciaryGR.Handle("/grievance-sla-policies", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.CreateSLAPolicy))).Methods("POST")
+	fiduciaryGR.Handle("/grievance-sla-policies/{policyId}", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.UpdateSLAPolicy))).Methods("PUT")
+	fiduciaryGR.Handle("/grievance-sla-policies/{policyId}", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.DeleteSLAPolicy))).Methods("DELETE")
+
+	// Grievance auto-triage: rules, dry runs, override stats and per-grievance review
+	fiduciaryGR.Handle("/grievance-triage-rules", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.ListTriageRules))).Methods("GET")
+	fiduciaryGR.Handle("/grievance-triage-rules", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.CreateTriageRule))).Methods("POST")
+	fiduciaryGR.Handle("/grievance-triage-rules/stats", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.TriageStats))).Methods("GET")
+	fiduciaryGR.Handle("/grievance-triage-rules/test", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.TestTriageRules))).Methods("POST")
+	fiduciaryGR.Handle("/grievance-triage-rules/{ruleId}", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.UpdateTriageRule))).Methods("PUT")
+	fiduciaryGR.Handle("/grievance-triage-rules/{ruleId}", middleware.RequirePermission("grievances:manage")(http.HandlerFunc(grievHandler.DeleteTriageRule))).Methods("DELETE")
+	fiduciaryGR.Handle("/grievances/{id}/triage", middleware.RequirePermission("grievances:read")(http.HandlerFunc(grievHandler.GetTriage))).Methods("GET")
+	fiduciaryGR.Handle("/grievances/{id}/triage", middleware.RequirePermission("grievances:respond")(http.HandlerFunc(grievHandler.ReviewTriage))).Methods("POST")
 
 	// ===== Grievance Comments =====
 	r.Handle("/api/v1/dashboard/grievances/{id}/comments", dataPrincipalAuth(http.HandlerFunc(grievHandler.AddComment))).Methods("POST")
@@ -442,6 +576,12 @@ func main() {
 	fiduciaryGR.Handle("/grievances/{id}/comments", middleware.RequirePermission("grievances:read")(http.HandlerFunc(grievHandler.GetComments))).Methods("GET")
 	fiduciaryGR.Handle("/grievances/comments/{commentID}", middleware.RequirePermission("grievances:respond")(http.HandlerFunc(grievHandler.DeleteComment))).Methods("DELETE")
 
+	// ===== Grievance Attachments =====
+	r.Handle("/api/v1/dashboard/grievances/{id}/attachments", dataPrincipalAuth(http.HandlerFunc(attachmentHandler.UploadGrievanceAttachment))).Methods("POST")
+	r.Handle("/api/v1/dashboard/grievances/{id}/attachments", dataPrincipalAuth(http.HandlerFunc(attachmentHandler.ListGrievanceAttachments))).Methods("GET")
+	fiduciaryGR.Handle("/grievances/{id}/attachments", middleware.RequirePermission("grievances:respond")(http.HandlerFunc(attachmentHandler.UploadGrievanceAttachment))).Methods("POST")
+	fiduciaryGR.Handle("/grievances/{id}/attachments", middleware.RequirePermission("grievances:read")(http.HandlerFunc(attachmentHandler.ListGrievanceAttachments))).Methods("GET")
+
 	// ==== VENDOR ====
 	vendorRepo := repository.NewVendorRepository(db.MasterDB)
 	// TPRM repo/service
@@ -460,7 +600,15 @@ func main() {
 		cfg.S3ForcePathStyle,
 	)
 	vendorService := services.NewVendorService(vendorRepo)
-	vendorHandler := handlers.NewVendorHandler(vendorService)
+	subProcessorRepo := repository.NewSubProcessorRepository(db.MasterDB)
+	transferService := services.NewTransferService(
+		repository.NewTransferRepository(db.MasterDB),
+		vendorRepo,
+		subProcessorRepo,
+		repository.NewEncryptedDPARepository(db.MasterDB),
+		tenantRepo,
+	)
+	vendorHandler := handlers.NewVendorHandler(vendorService, transferService)
 
 	// Public or all-authenticated users can list/get vendor details
 	r.Handle("/api/v1/vendors", dataPrincipalAuth(http.HandlerFunc(vendorHandler.ListVendors))).Methods("GET")
@@ -525,13 +673,19 @@ func main() {
 	cookieRouter.HandleFunc("/scans/{scanId}", http.HandlerFunc(cookieHandler.GetScanResults)).Methods("GET")
 
 	// ==== PUBLIC CONSENT FLOW ====
-	publicConsentHandler := handlers.NewPublicConsentHandler(userConsentSvc, consentFormSvc, webhookSvc)
+	publicConsentHandler := handlers.NewPublicConsentHandler(userConsentSvc, consentFormSvc, webhookSvc, sdfService)
 	publicConsentRouter := r.PathPrefix("/api/v1/public/consent-forms").Subrouter()
 	publicConsentRouter.Use(apiKeyAuth)
 	publicConsentRouter.HandleFunc("/{formId}", http.HandlerFunc(publicConsentHandler.GetConsentForm)).Methods("GET")
 
+	// ==== PUBLIC PRIVACY POLICY ====
+	publicHandler := handlers.NewPublicHandler(userConsentSvc, receiptService, cookieService, sdfService)
+	privacyPolicyRouter := r.PathPrefix("/api/v1/public/privacy-policy").Subrouter()
+	privacyPolicyRouter.Use(apiKeyAuth)
+	privacyPolicyRouter.HandleFunc("/{tenantId}", http.HandlerFunc(publicHandler.GetPrivacyPolicy)).Methods("GET")
+
 	userConsentRouter := r.PathPrefix("/api/v1/user/consents").Subrouter()
-	userConsentRouter.Use(dataPrincipalAuth)
+	userConsentRouter.Use(dataPrincipalAuth, nomineeHandler.ActingAuthority)
 	userConsentRouter.Handle("/submit/{formId}", http.HandlerFunc(publicConsentHandler.SubmitConsent)).Methods("POST")
 	userConsentRouter.Handle("", http.HandlerFunc(publicConsentHandler.GetUserConsents)).Methods("GET")
 	userConsentRouter.Handle("/withdraw/{purposeId}", http.HandlerFunc(publicConsentHandler.WithdrawConsent)).Methods("POST")
@@ -594,7 +748,7 @@ func main() {
 	breachNotificationRouter.Handle("/{notificationId}", http.HandlerFunc(breachNotificationHandler.DeleteBreachNotification)).Methods("DELETE")
 
 	// ==== ENHANCED BREACH NOTIFICATIONS (DPDP-Compliant) ====
-	enhancedBreachHandler := handlers.NewEnhancedBreachNotificationHandler(enhancedBreachNotificationSvc, auditService)
+	enhancedBreachHandler := handlers.NewEnhancedBreachNotificationHandler(enhancedBreachNotificationSvc, breachCampaignSvc, auditService)
 	dpdpBreachRouter := r.PathPrefix("/api/v1/fiduciary/dpdp-breaches").Subrouter()
 	dpdpBreachRouter.Use(fiduciaryAuth, middleware.RequirePermission("breaches:manage"))
 
@@ -602,6 +756,12 @@ func main() {
 	dpdpBreachRouter.HandleFunc("", enhancedBreachHandler.CreateBreachNotification).Methods("POST")
 	dpdpBreachRouter.HandleFunc("/register", enhancedBreachHandler.GetBreachRegister).Methods("GET")
 
+	// Tabletop exercises: simulated breaches kept out of the register
+	dpdpBreachRouter.HandleFunc("/simulations", enhancedBreachHandler.ListSimulations).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/simulation/messages", enhancedBreachHandler.GetSimulationMessages).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/simulation/report", enhancedBreachHandler.GetAfterActionReport).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/simulation/complete", enhancedBreachHandler.CompleteSimulation).Methods("POST")
+
 	// Workflow endpoints
 	dpdpBreachRouter.HandleFunc("/{id}/submit-verification", enhancedBreachHandler.SubmitForVerification).Methods("POST")
 	dpdpBreachRouter.HandleFunc("/{id}/verify", enhancedBreachHandler.VerifyBreach).Methods("POST")
@@ -611,20 +771,140 @@ func main() {
 	dpdpBreachRouter.HandleFunc("/{id}/notify-dpb", enhancedBreachHandler.SendDPBNotification).Methods("POST")
 	dpdpBreachRouter.HandleFunc("/{id}/notify-data-principals", enhancedBreachHandler.SendDataPrincipalNotifications).Methods("POST")
 
+	// Affected Data Principals: impact preview and frozen recipient lists
+	breachImpactHandler := handlers.NewBreachImpactHandler(breachImpactSvc, auditService)
+	dpdpBreachRouter.HandleFunc("/{id}/impact/preview", breachImpactHandler.PreviewImpact).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/{id}/recipient-lists", breachImpactHandler.FreezeRecipientList).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/{id}/recipient-lists", breachImpactHandler.ListRecipientLists).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/recipient-lists/{listId}", breachImpactHandler.GetRecipientList).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/recipient-lists/{listId}/recipients", breachImpactHandler.ListRecipients).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/recipient-lists/{listId}/verify", breachImpactHandler.VerifyRecipientList).Methods("GET")
+
+	// Notification campaigns to the affected Data Principals
+	breachCampaignHandler := handlers.NewBreachCampaignHandler(breachCampaignSvc, auditService, cfg.BreachDeliverySecret)
+	dpdpBreachRouter.HandleFunc("/{id}/campaigns", breachCampaignHandler.LaunchCampaign).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/{id}/campaigns", breachCampaignHandler.ListCampaigns).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/campaigns/{campaignId}", breachCampaignHandler.GetCampaign).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/campaigns/{campaignId}/messages", breachCampaignHandler.ListCampaignMessages).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/campaigns/{campaignId}/cancel", breachCampaignHandler.CancelCampaign).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/campaigns/{campaignId}/proof", breachCampaignHandler.DownloadDeliveryProof).Methods("GET")
+	// Delivery and bounce reports from the mail and SMS providers
+	r.HandleFunc("/api/v1/breach-delivery/events", breachCampaignHandler.DeliveryEvent).Methods("POST")
+
 	// Compliance
 	dpdpBreachRouter.HandleFunc("/{id}/sla-status", enhancedBreachHandler.CheckSLACompliance).Methods("GET")
 
+	// DPB reports: initial intimation and 72-hour detailed report, signed JSON + PDF
+	dpbReportService := services.NewDPBReportService(
+		breachNotificationRepo,
+		breachImpactAssessmentRepo,
+		breachTimelineRepo,
+		breachEvidenceRepo,
+		breachCommunicationRepo,
+		tenantRepo,
+		repository.NewBreachDPBReportRepository(db.MasterDB),
+		breachRecipientListRepo,
+		blobStore,
+		documentSigner,
+	)
+	dpbReportHandler := handlers.NewDPBReportHandler(dpbReportService, auditService)
+	dpdpBreachRouter.HandleFunc("/{id}/dpb-reports/validate", dpbReportHandler.ValidateReport).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/dpb-reports", dpbReportHandler.GenerateReport).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/{id}/dpb-reports", dpbReportHandler.ListReports).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/dpb-reports/{reportId}", dpbReportHandler.GetReport).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/dpb-reports/{reportId}/pdf", dpbReportHandler.DownloadReportPDF).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/dpb-reports/{reportId}/verify", dpbReportHandler.VerifyReport).Methods("GET")
+
+	// CERT-In incident reporting: parallel 6-hour track
+	certInHandler := handlers.NewCERTInHandler(services.NewCERTInReportService(
+		breachNotificationRepo,
+		breachImpactAssessmentRepo,
+		breachTimelineRepo,
+		tenantRepo,
+		breachTemplateRepo,
+	), auditService)
+	dpdpBreachRouter.HandleFunc("/cert-in/incident-types", certInHandler.IncidentTypes).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/cert-in", certInHandler.UpdateTrack).Methods("PUT")
+	dpdpBreachRouter.HandleFunc("/{id}/cert-in/report", certInHandler.ValidateReport).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/cert-in/report/export", certInHandler.ExportReport).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/cert-in/reported", certInHandler.MarkReported).Methods("POST")
+
+	// Breach evidence: write-once files, custody log of every access, signed export
+	breachEvidenceHandler := handlers.NewBreachEvidenceHandler(services.NewBreachEvidenceService(
+		breachNotificationRepo,
+		breachEvidenceRepo,
+		repository.NewBreachEvidenceCustodyRepository(db.MasterDB),
+		breachTimelineRepo,
+		blobStore,
+		documentSigner,
+		cfg.EvidenceRetentionDays,
+	), auditService)
+	dpdpBreachRouter.HandleFunc("/{id}/evidence", breachEvidenceHandler.UploadEvidence).Methods("POST")
+	dpdpBreachRouter.HandleFunc("/{id}/evidence", breachEvidenceHandler.ListEvidence).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/{id}/evidence/export", breachEvidenceHandler.ExportEvidence).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/evidence/{evidenceId}", breachEvidenceHandler.GetEvidence).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/evidence/{evidenceId}/file", breachEvidenceHandler.DownloadEvidence).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/evidence/{evidenceId}/custody", breachEvidenceHandler.GetCustodyLog).Methods("GET")
+	dpdpBreachRouter.HandleFunc("/evidence/{evidenceId}/verify", breachEvidenceHandler.VerifyEvidence).Methods("POST")
+
 	// ==== THIRD-PARTY RISK MANAGEMENT (TPRM) ====
 	tprmHandler := handlers.NewTPRMHandler(tprmService)
 	tprmRouter := r.PathPrefix("/api/v1/fiduciary/tprm").Subrouter()
 	tprmRouter.Use(fiduciaryAuth, middleware.RequirePermission("vendors:manage"))
 	tprmHandler.RegisterRoutes(tprmRouter)
+	vendorQuestionnaireService := services.NewVendorQuestionnaireService(repository.NewVendorQuestionnaireRepository(db.MasterDB), tprmRepo)
+	handlers.NewVendorQuestionnaireHandler(vendorQuestionnaireService, tprmService, auditService).RegisterRoutes(tprmRouter)
+	dpaDocumentService := services.NewDPADocumentService(tprmRepo, vendorRepo, tenantRepo, blobStore)
+	handlers.NewDPADocumentHandler(dpaDocumentService, auditService).RegisterRoutes(tprmRouter)
+
+	// ==== VENDOR PORTAL ====
+	vendorPortalService := services.NewVendorPortalService(repository.NewVendorPortalRepository(db.MasterDB), tprmService, vendorQuestionnaireService, emailService, cfg.FrontendBaseURL)
+	vendorPortalService.RegisterJobs(jobRunner)
+	vendorPortalHandler := handlers.NewVendorPortalHandler(vendorPortalService, auditService)
+	vendorPortalHandler.RegisterFiduciaryRoutes(tprmRouter)
+	vendorPortalRouter := r.PathPrefix("/api/v1/vendor-portal").Subrouter()
+	vendorPortalRouter.Use(vendorPortalHandler.Authenticate)
+	vendorPortalHandler.RegisterPortalRoutes(vendorPortalRouter)
+
+	// ==== SUB-PROCESSORS ====
+	subProcessorService := services.NewSubProcessorService(
+		subProcessorRepo,
+		vendorRepo,
+		repository.NewEncryptedDPARepository(db.MasterDB),
+		transferService,
+		notificationService,
+		emailService,
+		cfg.FrontendBaseURL,
+	)
+	subProcessorService.RegisterJobs(jobRunner)
+	subProcessorHandler := handlers.NewSubProcessorHandler(subProcessorService, auditService)
+	subProcessorHandler.RegisterRoutes(tprmRouter)
+	subProcessorHandler.RegisterPortalRoutes(vendorPortalRouter)
+	r.Handle("/api/v1/user/sub-processors", dataPrincipalAuth(http.HandlerFunc(subProcessorHandler.ForPrincipal))).Methods("GET")
+
+	// ==== CROSS-BORDER TRANSFERS ====
+	handlers.NewTransferHandler(transferService, auditService).RegisterRoutes(tprmRouter)
 
 	// ==== DATA PROCESSING AGREEMENTS ====
 	dpaHandler := handlers.NewDPAHandler(db.MasterDB, auditService)
 	dpaRouter := r.PathPrefix("/api/v1/fiduciary/dpas").Subrouter()
 	dpaRouter.Use(fiduciaryAuth, middleware.RequirePermission("dpas:manage"))
+	dpaSigningService := services.NewDPASigningService(
+		repository.NewDPASigningRepository(db.MasterDB),
+		repository.NewEncryptedDPARepository(db.MasterDB),
+		vendorRepo,
+		tenantRepo,
+		blobStore,
+		documentSigner,
+		emailService,
+		cfg.FrontendBaseURL,
+	)
+	dpaSigningHandler := handlers.NewDPASigningHandler(dpaSigningService, auditService)
+	dpaSigningHandler.RegisterFiduciaryRoutes(dpaRouter)
 	dpaHandler.RegisterRoutes(dpaRouter)
+	dpaSigningRouter := r.PathPrefix("/api/v1/dpa-signing").Subrouter()
+	dpaSigningRouter.Use(dpaSigningHandler.Authenticate)
+	dpaSigningHandler.RegisterSignerRoutes(dpaSigningRouter)
 
 	// ==== RBAC MANAGEMENT (Roles, Permissions) ====
 	rbacHandler := handlers.NewRBACHandler(db.MasterDB)
@@ -666,6 +946,42 @@ func main() {
 	discoveryRouter.HandleFunc("/jobs/{id}", discoveryHandler.GetJobResults).Methods("GET")
 	discoveryRouter.HandleFunc("/dashboard", discoveryHandler.GetDashboardStats).Methods("GET")
 
+	// ==== DATA FLOW MAP ====
+	dataFlowService := services.NewDataFlowService(repository.NewDataFlowRepository(db.MasterDB), vendorRepo, subProcessorRepo)
+	dataFlowRouter := r.PathPrefix("/api/v1/fiduciary/data-flow").Subrouter()
+	dataFlowRouter.Use(fiduciaryAuth, middleware.RequirePermission("data_map:manage"))
+	handlers.NewDataFlowHandler(dataFlowService, auditService).RegisterRoutes(dataFlowRouter)
+
+	// ==== RECORD OF PROCESSING ACTIVITIES ====
+	ropaService := services.NewRopaService(repository.NewRopaRepository(db.MasterDB), vendorRepo, subProcessorRepo, repository.NewEncryptedDPARepository(db.MasterDB), transferService)
+	ropaRouter := r.PathPrefix("/api/v1/fiduciary/ropa").Subrouter()
+	ropaRouter.Use(fiduciaryAuth, middleware.RequirePermission("ropa:manage"))
+	handlers.NewRopaHandler(ropaService, auditService).RegisterRoutes(ropaRouter)
+
+	// ==== DATA PROTECTION IMPACT ASSESSMENTS (DPDP SECTION 10) ====
+	dpiaService := services.NewDPIAService(repository.NewDPIARepository(db.MasterDB), repository.NewDataFlowRepository(db.MasterDB), vendorRepo, notificationService)
+	dpiaService.RegisterJobs(jobRunner)
+	dpiaHandler := handlers.NewDPIAHandler(dpiaService, auditService)
+	// Sign-off needs the DPO's permission, so its routes are matched first
+	dpiaApprovalRouter := r.PathPrefix("/api/v1/fiduciary/dpia").Subrouter()
+	dpiaApprovalRouter.Use(fiduciaryAuth, middleware.RequirePermission("dpia:approve"))
+	dpiaHandler.RegisterApprovalRoutes(dpiaApprovalRouter)
+	dpiaRouter := r.PathPrefix("/api/v1/fiduciary/dpia").Subrouter()
+	dpiaRouter.Use(fiduciaryAuth, middleware.RequirePermission("dpia:manage"))
+	dpiaHandler.RegisterRoutes(dpiaRouter)
+
+	// ==== SIGNIFICANT DATA FIDUCIARY OBLIGATIONS (DPDP SECTION 10) ====
+	sdfRouter := r.PathPrefix("/api/v1/fiduciary/sdf").Subrouter()
+	sdfRouter.Use(fiduciaryAuth, middleware.RequirePermission("sdf:manage"))
+	handlers.NewSDFHandler(sdfService, auditService).RegisterRoutes(sdfRouter)
+
+	// ==== ANALYTICS ====
+	analyticsHandler := handlers.NewAnalyticsHandler(services.NewAnalyticsService(db.MasterDB, nil), auditService)
+	analyticsRouter := r.PathPrefix("/api/v1/fiduciary/analytics").Subrouter()
+	analyticsRouter.Use(fiduciaryAuth, middleware.RequirePermission("analytics:read"))
+	analyticsRouter.HandleFunc("/dashboard", analyticsHandler.GetDashboard).Methods("GET")
+	analyticsRouter.HandleFunc("/alerts", analyticsHandler.GetAlerts).Methods("GET")
+
 	// ==== CHILD CONSENT MANAGEMENT ====
 	childRepo := repository.NewChildConsentRepository(db.MasterDB)
 	childService := services.NewChildConsentService(childRepo)
@@ -682,6 +998,44 @@ func main() {
 	parentRouter.HandleFunc("/{requestId}/approve", childHandler.ApproveRequest).Methods("POST")
 	parentRouter.HandleFunc("/{requestId}/reject", childHandler.RejectRequest).Methods("POST")
 
+	// ==== NOMINEES (DPDP SECTION 14) ====
+	r.HandleFunc("/api/v1/auth/verify-nominee", nomineeHandler.VerifyNominee).Methods("GET")
+
+	// Inbound mail channel, fed by the local MTA (see cmd/inbound-mail)
+	inboundMailService := services.NewInboundMailService(db.MasterDB, dsrService, dsrTrackingService, dsrRepo, emailService, blobStore, cfg.InboundMailAddress, cfg.InboundMailSecret)
+	inboundMailHandler := handlers.NewInboundMailHandler(inboundMailService, cfg.InboundMailSecret)
+	r.HandleFunc("/api/v1/inbound/email", inboundMailHandler.Receive).Methods("POST")
+	r.Handle("/api/v1/fiduciary/inbound-emails", fiduciaryAuth(middleware.RequirePermission("grievances:read")(http.HandlerFunc(inboundMailHandler.List)))).Methods("GET")
+
+	// Public DSR status page (guarded by the signed status token)
+	r.HandleFunc("/api/v1/dsr-status", dsrTrackingHandler.GetStatus).Methods("GET")
+	r.HandleFunc("/api/v1/dsr-status/clarifications", dsrTrackingHandler.SubmitClarification).Methods("POST")
+	r.HandleFunc("/api/v1/dsr-status/result", dsrTrackingHandler.DownloadResult).Methods("GET")
+	r.HandleFunc("/api/v1/dsr-status/resend-link", dsrTrackingHandler.ResendStatusLink).Methods("POST")
+
+	nomineeRouter := r.PathPrefix("/api/v1/user/nominees").Subrouter()
+	nomineeRouter.Use(dataPrincipalAuth)
+	nomineeRouter.HandleFunc("", nomineeHandler.AddNominee).Methods("POST")
+	nomineeRouter.HandleFunc("", nomineeHandler.ListNominees).Methods("GET")
+	nomineeRouter.HandleFunc("/{nomineeId}/resend-verification", nomineeHandler.ResendVerification).Methods("POST")
+	nomineeRouter.HandleFunc("/{nomineeId}", nomineeHandler.RevokeNominee).Methods("DELETE")
+
+	nominationRouter := r.PathPrefix("/api/v1/user/nominations").Subrouter()
+	nominationRouter.Use(dataPrincipalAuth)
+	nominationRouter.HandleFunc("", nomineeHandler.ListNominations).Methods("GET")
+	nominationRouter.HandleFunc("/claims", nomineeHandler.SubmitClaim).Methods("POST")
+	nominationRouter.HandleFunc("/claims", nomineeHandler.ListMyClaims).Methods("GET")
+	nominationRouter.HandleFunc("/claims/{claimId}/evidence", nomineeHandler.UploadClaimEvidence).Methods("POST")
+
+	nomineeClaimRouter := r.PathPrefix("/api/v1/fiduciary/nominee-claims").Subrouter()
+	nomineeClaimRouter.Use(fiduciaryAuth, middleware.RequirePermission("nominees:manage"))
+	nomineeClaimRouter.HandleFunc("", nomineeHandler.ListClaims).Methods("GET")
+	nomineeClaimRouter.HandleFunc("/{claimId}", nomineeHandler.GetClaim).Methods("GET")
+	nomineeClaimRouter.HandleFunc("/{claimId}/evidence/{evidenceId}", nomineeHandler.DownloadClaimEvidence).Methods("GET")
+	nomineeClaimRouter.HandleFunc("/{claimId}/approve", nomineeHandler.ApproveClaim).Methods("POST")
+	nomineeClaimRouter.HandleFunc("/{claimId}/reject", nomineeHandler.RejectClaim).Methods("POST")
+	nomineeClaimRouter.HandleFunc("/{claimId}/revoke", nomineeHandler.RevokeClaim).Methods("POST")
+
 	// ==== REVIEW TOKEN & METRICS ====
 	r.HandleFunc("/api/v1/review", handlers.ReviewTokenHandler(db.MasterDB, publicKey)).Methods("GET")
 	r.Handle("/metrics", promhttp.Handler())
@@ -701,10 +1055,36 @@ func main() {
 	superAdminRouter.HandleFunc("/tenants/{id}", superAdminHandler.UpdateTenant).Methods("PUT")
 	superAdminRouter.HandleFunc("/tenants/{id}", superAdminHandler.DeleteTenant).Methods("DELETE")
 
+	// Background jobs
+	jobHandler := handlers.NewJobHandler(jobRunner, auditService)
+	superAdminRouter.HandleFunc("/jobs", jobHandler.ListJobs).Methods("GET")
+	superAdminRouter.HandleFunc("/jobs/status", jobHandler.GetStatus).Methods("GET")
+	superAdminRouter.HandleFunc("/jobs/{id}", jobHandler.GetJob).Methods("GET")
+	superAdminRouter.HandleFunc("/jobs/{id}/retry", jobHandler.RetryJob).Methods("POST")
+	superAdminRouter.HandleFunc("/jobs/{id}/cancel", jobHandler.CancelJob).Methods("POST")
+
+	jobRunner.Start()
+
 	// ==== START SERVER ====
 	handler := cors(r)
-	log.Logger.Info().Msgf("Server starting on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
-		log.Logger.Fatal().Err(err).Msg("server failed")
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: handler}
+	go func() {
+		log.Logger.Info().Msgf("Server starting on port %s", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Fatal().Err(err).Msg("server failed")
+		}
+	}()
+
+	// On shutdown, stop taking requests, let running jobs finish and hand
+	// the scheduler lease to another replica
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	log.Logger.Info().Msg("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Logger.Error().Err(err).Msg("server shutdown failed")
 	}
+	jobRunner.Stop()
 }
@@ -51,6 +51,28 @@ SMTPUser string
 SMTPPass string
 SMTPFrom string
 
+// Inbound mail
+InboundMailSecret  string // Shared secret the MTA pipe presents to the ingestion endpoint; also signs tenant plus-addresses
+InboundMailAddress string // Base inbound mailbox; each tenant is reached on a signed plus-address of it
+
+// SMS gateway
+SMSGatewayURL string
+SMSAPIKey     string
+SMSSenderID   string
+
+// Breach notification campaigns
+BreachEmailPerMinute int    // Per-channel send rates
+BreachSMSPerMinute   int
+BreachInAppPerMinute int
+BreachDeliverySecret string // Shared secret on delivery/bounce callbacks
+
+// Breach evidence
+EvidenceRetentionDays int // S3 object-lock retention on evidence files; 0 disables the lock
+
+// Background jobs
+JobWorkers      int           // Jobs run concurrently by each replica
+JobPollInterval time.Duration // How often an idle worker checks the queue
+
 // External Services
 UIDServiceURL     string
 FrontendBaseURL   string
@@ -137,6 +159,23 @@ SMTPUser: getEnv("SMTP_USER", ""),
 SMTPPass: getEnv("SMTP_PASS", ""),
 SMTPFrom: getEnv("SMTP_FROM", ""),
 
+InboundMailSecret:  getEnv("INBOUND_MAIL_SECRET", ""),
+InboundMailAddress: getEnv("INBOUND_MAIL_ADDRESS", ""),
+
+SMSGatewayURL: getEnv("SMS_GATEWAY_URL", ""),
+SMSAPIKey:     getEnv("SMS_API_KEY", ""),
+SMSSenderID:   getEnv("SMS_SENDER_ID", ""),
+
+BreachEmailPerMinute: mustParseInt(getEnv("BREACH_EMAIL_PER_MINUTE", "100")),
+BreachSMSPerMinute:   mustParseInt(getEnv("BREACH_SMS_PER_MINUTE", "30")),
+BreachInAppPerMinute: mustParseInt(getEnv("BREACH_INAPP_PER_MINUTE", "1000")),
+BreachDeliverySecret: getEnv("BREACH_DELIVERY_SECRET", ""),
+
+EvidenceRetentionDays: mustParseInt(getEnv("EVIDENCE_RETENTION_DAYS", "0")),
+
+JobWorkers:      mustParseInt(getEnv("JOB_WORKERS", "4")),
+JobPollInterval: mustParseDuration(getEnv("JOB_POLL_INTERVAL", "2s")),
+
 UIDServiceURL:     getEnv("UID_SERVICE_URL", "http://localhost:5001/generate"),
 FrontendBaseURL:   getEnv("FRONTEND_BASE_URL", "http://localhost:5173"),
 DigiLockerBaseURL: getEnv("DIGILOCKER_BASE_URL", "https://digilocker.gov.in"),
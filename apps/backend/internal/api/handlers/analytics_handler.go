@@ -28,7 +28,11 @@ func NewAnalyticsHandler(service *services.AnalyticsService, auditService *servi
 
 // GetDashboard handles GET /api/v1/fiduciary/analytics/dashboard
 func (h *AnalyticsHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	// Parse query parameters for filters
 	filter := &models.AnalyticsFilter{
@@ -70,7 +74,11 @@ func (h *AnalyticsHandler) GetDashboard(w http.ResponseWriter, r *http.Request)
 
 // GetConsentAnalytics handles GET /api/v1/fiduciary/analytics/consent
 func (h *AnalyticsHandler) GetConsentAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -85,7 +93,11 @@ func (h *AnalyticsHandler) GetConsentAnalytics(w http.ResponseWriter, r *http.Re
 
 // GetDSRAnalytics handles GET /api/v1/fiduciary/analytics/dsr
 func (h *AnalyticsHandler) GetDSRAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -100,7 +112,11 @@ func (h *AnalyticsHandler) GetDSRAnalytics(w http.ResponseWriter, r *http.Reques
 
 // GetUserEngagementAnalytics handles GET /api/v1/fiduciary/analytics/engagement
 func (h *AnalyticsHandler) GetUserEngagementAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -115,7 +131,11 @@ func (h *AnalyticsHandler) GetUserEngagementAnalytics(w http.ResponseWriter, r *
 
 // GetCookieAnalytics handles GET /api/v1/fiduciary/analytics/cookies
 func (h *AnalyticsHandler) GetCookieAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -130,7 +150,11 @@ func (h *AnalyticsHandler) GetCookieAnalytics(w http.ResponseWriter, r *http.Req
 
 // GetComplianceAnalytics handles GET /api/v1/fiduciary/analytics/compliance
 func (h *AnalyticsHandler) GetComplianceAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -145,7 +169,11 @@ func (h *AnalyticsHandler) GetComplianceAnalytics(w http.ResponseWriter, r *http
 
 // GetPerformanceAnalytics handles GET /api/v1/fiduciary/analytics/performance
 func (h *AnalyticsHandler) GetPerformanceAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -160,7 +188,11 @@ func (h *AnalyticsHandler) GetPerformanceAnalytics(w http.ResponseWriter, r *htt
 
 // GetRevenueAnalytics handles GET /api/v1/fiduciary/analytics/revenue
 func (h *AnalyticsHandler) GetRevenueAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -175,7 +207,11 @@ func (h *AnalyticsHandler) GetRevenueAnalytics(w http.ResponseWriter, r *http.Re
 
 // GetConsentTrend handles GET /api/v1/fiduciary/analytics/trends/consent
 func (h *AnalyticsHandler) GetConsentTrend(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -189,7 +225,11 @@ func (h *AnalyticsHandler) GetConsentTrend(w http.ResponseWriter, r *http.Reques
 
 // GetDSRTrend handles GET /api/v1/fiduciary/analytics/trends/dsr
 func (h *AnalyticsHandler) GetDSRTrend(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -203,7 +243,11 @@ func (h *AnalyticsHandler) GetDSRTrend(w http.ResponseWriter, r *http.Request) {
 
 // GetComplianceTrend handles GET /api/v1/fiduciary/analytics/trends/compliance
 func (h *AnalyticsHandler) GetComplianceTrend(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	filter := h.parseAnalyticsFilter(r, tenantID)
 
@@ -217,7 +261,11 @@ func (h *AnalyticsHandler) GetComplianceTrend(w http.ResponseWriter, r *http.Req
 
 // ExportAnalytics handles GET /api/v1/fiduciary/analytics/export
 func (h *AnalyticsHandler) ExportAnalytics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	// Parse export format
 	format := r.URL.Query().Get("format")
@@ -253,7 +301,11 @@ func (h *AnalyticsHandler) ExportAnalytics(w http.ResponseWriter, r *http.Reques
 
 // GetRealTimeMetrics handles GET /api/v1/fiduciary/analytics/realtime
 func (h *AnalyticsHandler) GetRealTimeMetrics(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	// This would typically connect to a real-time data source
 	// For now, return current snapshot
@@ -273,7 +325,11 @@ func (h *AnalyticsHandler) GetRealTimeMetrics(w http.ResponseWriter, r *http.Req
 
 // GetCustomReport handles POST /api/v1/fiduciary/analytics/custom-report
 func (h *AnalyticsHandler) GetCustomReport(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	var request struct {
 		Metrics    []string               `json:"metrics"`
@@ -315,7 +371,11 @@ func (h *AnalyticsHandler) GetCustomReport(w http.ResponseWriter, r *http.Reques
 
 // GetAlerts handles GET /api/v1/fiduciary/analytics/alerts
 func (h *AnalyticsHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
-	tenantID := r.Context().Value("tenant_id").(uuid.UUID)
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	// Parse alert type filter
 	alertType := r.URL.Query().Get("type")
@@ -328,12 +388,12 @@ func (h *AnalyticsHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alerts := dashboard.ActiveAlerts
-
-	// TODO: Implement proper alert filtering when alert models are defined
-	// For now, just return the alerts as-is
-	_ = alertType // Suppress unused variable warning
-	_ = category  // Suppress unused variable warning
+	alerts := []models.AnalyticsAlert{}
+	for _, a := range dashboard.ActiveAlerts {
+		if (alertType == "" || a.Type == alertType) && (category == "" || a.Category == category) {
+			alerts = append(alerts, a)
+		}
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"alerts": alerts,
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"pixpivot/arc/internal/claims"
+	"pixpivot/arc/internal/contextkeys"
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Multipart overhead allowed on top of the 10MB file limit
+const maxAttachmentRequestSize = 11 << 20
+
+// AttachmentHandler serves evidence uploads and downloads on grievances, DSRs
+// and their comments, for both principals and fiduciary staff.
+type AttachmentHandler struct {
+	service      *services.AttachmentService
+	auditService *services.AuditService
+}
+
+func NewAttachmentHandler(service *services.AttachmentService, auditService *services.AuditService) *AttachmentHandler {
+	return &AttachmentHandler{service: service, auditService: auditService}
+}
+
+// viewerFromRequest builds the access identity from whichever claims the route carries.
+func viewerFromRequest(r *http.Request) (services.AttachmentViewer, bool) {
+	if c, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims); ok && c != nil {
+		tenantID, err1 := uuid.Parse(c.TenantID)
+		staffID, err2 := uuid.Parse(c.FiduciaryID)
+		if err1 != nil || err2 != nil {
+			return services.AttachmentViewer{}, false
+		}
+		return services.AttachmentViewer{
+			TenantID:     tenantID,
+			StaffID:      &staffID,
+			Permissions:  c.Permissions,
+			IsSuperAdmin: c.IsSuperAdmin,
+		}, true
+	}
+	if c, ok := r.Context().Value(contextkeys.UserClaimsKey).(*claims.DataPrincipalClaims); ok && c != nil {
+		tenantID, err1 := uuid.Parse(c.TenantID)
+		principalID, err2 := uuid.Parse(c.PrincipalID)
+		if err1 != nil || err2 != nil {
+			return services.AttachmentViewer{}, false
+		}
+		return services.AttachmentViewer{TenantID: tenantID, PrincipalID: &principalID}, true
+	}
+	return services.AttachmentViewer{}, false
+}
+
+func writeAttachmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAttachmentForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrInvalidAttachment):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "failed to process attachment")
+	}
+}
+
+func (h *AttachmentHandler) upload(w http.ResponseWriter, r *http.Request, scope string) {
+	viewer, ok := viewerFromRequest(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	parentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentRequestSize)
+	if err := r.ParseMultipartForm(maxAttachmentRequestSize); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data or file too large")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file not provided")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+
+	up := services.AttachmentUpload{
+		Scope:       scope,
+		ParentID:    parentID,
+		FileName:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Data:        data,
+	}
+	if v := r.FormValue("commentId"); v != "" {
+		commentID, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid commentId")
+			return
+		}
+		up.CommentID = &commentID
+	}
+
+	a, err := h.service.Upload(r.Context(), up, viewer)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	go h.audit(viewer, "attachment_uploaded", getClientIP(r), map[string]interface{}{
+		"attachment_id": a.ID, "scope": scope, "parent_id": parentID, "sha256": a.SHA256,
+	})
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *AttachmentHandler) list(w http.ResponseWriter, r *http.Request, scope string) {
+	viewer, ok := viewerFromRequest(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	parentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	list, err := h.service.List(r.Context(), scope, parentID, viewer)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *AttachmentHandler) UploadGrievanceAttachment(w http.ResponseWriter, r *http.Request) {
+	h.upload(w, r, services.AttachmentScopeGrievance)
+}
+
+func (h *AttachmentHandler) ListGrievanceAttachments(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, services.AttachmentScopeGrievance)
+}
+
+func (h *AttachmentHandler) UploadDSRAttachment(w http.ResponseWriter, r *http.Request) {
+	h.upload(w, r, services.AttachmentScopeDSR)
+}
+
+func (h *AttachmentHandler) ListDSRAttachments(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, services.AttachmentScopeDSR)
+}
+
+// Download streams an attachment to the owning principal or assigned staff
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+	viewer, ok := viewerFromRequest(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	a, data, err := h.service.Download(r.Context(), id, viewer)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	go h.audit(viewer, "attachment_downloaded", getClientIP(r), map[string]interface{}{
+		"attachment_id": a.ID, "scope": a.Scope, "parent_id": a.ParentID,
+	})
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Content-SHA256", a.SHA256)
+	_, _ = w.Write(data)
+}
+
+func (h *AttachmentHandler) audit(v services.AttachmentViewer, action, ip string, details map[string]interface{}) {
+	if h.auditService == nil {
+		return
+	}
+	actorID, initiator := uuid.Nil, "principal"
+	if v.StaffID != nil {
+		actorID, initiator = *v.StaffID, "fiduciary"
+	} else if v.PrincipalID != nil {
+		actorID = *v.PrincipalID
+	}
+	_ = h.auditService.Create(context.Background(), actorID, v.TenantID, uuid.Nil, action, "", initiator, ip, "", "", details)
+}
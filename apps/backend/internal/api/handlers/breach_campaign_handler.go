@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// BreachCampaignHandler launches and tracks breach notification campaigns
+// and takes delivery reports from the mail and SMS providers
+type BreachCampaignHandler struct {
+	service        *services.BreachCampaignService
+	auditService   *services.AuditService
+	deliverySecret string
+}
+
+func NewBreachCampaignHandler(service *services.BreachCampaignService, auditService *services.AuditService, deliverySecret string) *BreachCampaignHandler {
+	return &BreachCampaignHandler{service: service, auditService: auditService, deliverySecret: deliverySecret}
+}
+
+func writeBreachCampaignError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBreachNotFound), errors.Is(err, services.ErrRecipientListNotFound), errors.Is(err, services.ErrCampaignNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotificationNotApproved):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidCampaign):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("breach campaign request failed")
+		writeError(w, http.StatusInternalServerError, "breach campaign request failed")
+	}
+}
+
+// LaunchCampaign queues notices to a recipient list over the chosen channels
+func (h *BreachCampaignHandler) LaunchCampaign(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	var req struct {
+		Channels        []string  `json:"channels"`
+		RecipientListID uuid.UUID `json:"recipientListId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	campaign, err := h.service.Launch(tenantID, breachID, req.RecipientListID, req.Channels, userID)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_campaign_launched", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "campaign_id": campaign.ID, "recipient_list_version": campaign.RecipientListVersion, "channels": req.Channels, "messages": campaign.TotalMessages,
+	})
+	writeJSON(w, http.StatusAccepted, campaign)
+}
+
+// ListCampaigns lists a breach's campaigns
+func (h *BreachCampaignHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	campaigns, err := h.service.List(tenantID, breachID)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"campaigns": campaigns})
+}
+
+func campaignIDVar(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["campaignId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid campaign ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// GetCampaign returns a campaign with its live counts
+func (h *BreachCampaignHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	campaignID, ok := campaignIDVar(w, r)
+	if !ok {
+		return
+	}
+	campaign, err := h.service.Get(tenantID, campaignID)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, campaign)
+}
+
+// ListCampaignMessages pages through messages with ?status=&limit=&offset=
+func (h *BreachCampaignHandler) ListCampaignMessages(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	campaignID, ok := campaignIDVar(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	messages, err := h.service.Messages(tenantID, campaignID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": messages, "limit": limit, "offset": offset})
+}
+
+// CancelCampaign stops sending whatever is still queued
+func (h *BreachCampaignHandler) CancelCampaign(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	campaignID, ok := campaignIDVar(w, r)
+	if !ok {
+		return
+	}
+	campaign, err := h.service.Cancel(tenantID, campaignID)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_campaign_cancelled", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": campaign.BreachID, "campaign_id": campaign.ID, "cancelled": campaign.Cancelled,
+	})
+	writeJSON(w, http.StatusOK, campaign)
+}
+
+// DownloadDeliveryProof serves the signed delivery proof
+func (h *BreachCampaignHandler) DownloadDeliveryProof(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	campaignID, ok := campaignIDVar(w, r)
+	if !ok {
+		return
+	}
+	doc, sig, err := h.service.Proof(tenantID, campaignID)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	sum := sha256.Sum256(doc)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("delivery-proof-%s.json", campaignID.String()[:8])))
+	w.Header().Set("X-Content-SHA256", hex.EncodeToString(sum[:]))
+	w.Header().Set("X-Signature", sig)
+	w.Header().Set("X-Signing-Key-ID", h.service.SigningKeyID())
+	_, _ = w.Write(doc)
+}
+
+// DeliveryEvent takes a delivery, bounce or failure report. Providers
+// authenticate with the shared secret in X-Delivery-Secret.
+func (h *BreachCampaignHandler) DeliveryEvent(w http.ResponseWriter, r *http.Request) {
+	if h.deliverySecret == "" {
+		writeError(w, http.StatusServiceUnavailable, "delivery reports are not configured")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Delivery-Secret")), []byte(h.deliverySecret)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req struct {
+		MessageID  string    `json:"messageId"`
+		Status     string    `json:"status"`
+		Reason     string    `json:"reason"`
+		OccurredAt time.Time `json:"occurredAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MessageID == "" {
+		writeError(w, http.StatusBadRequest, "messageId and status are required")
+		return
+	}
+	m, err := h.service.RecordDelivery(req.MessageID, req.Status, req.Reason, req.OccurredAt)
+	if err != nil {
+		writeBreachCampaignError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": m.ID.String(), "status": m.Status})
+}
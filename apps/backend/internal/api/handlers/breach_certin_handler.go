@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// CERTInHandler exposes the CERT-In reporting track of a breach
+type CERTInHandler struct {
+	service      *services.CERTInReportService
+	auditService *services.AuditService
+}
+
+func NewCERTInHandler(service *services.CERTInReportService, auditService *services.AuditService) *CERTInHandler {
+	return &CERTInHandler{service: service, auditService: auditService}
+}
+
+func writeCERTInError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCERTInNotRequired):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidCERTInIncident), errors.Is(err, services.ErrUnsupportedCERTInFormat):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeDPBReportError(w, err)
+	}
+}
+
+// IncidentTypes lists the CERT-In Annexure I incident types
+func (h *CERTInHandler) IncidentTypes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"incidentTypes":        breach.CERTInIncidentTypes,
+		"reportingWindowHours": breach.CERTInReportingWindow.Hours(),
+	})
+}
+
+// UpdateTrack puts the breach on or off the CERT-In track and records form details
+func (h *CERTInHandler) UpdateTrack(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	var req services.CERTInTrackUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	b, err := h.service.UpdateTrack(tenantID, breachID, req, userID)
+	if err != nil {
+		writeCERTInError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_cert_in_track_updated", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "required": b.RequiresCERTInReporting, "incident_type": b.CERTInIncidentType,
+	})
+	writeJSON(w, http.StatusOK, b)
+}
+
+// ValidateReport builds the CERT-In report and lists missing fields
+func (h *CERTInHandler) ValidateReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	report, issues, err := h.service.Validate(tenantID, breachID)
+	if err != nil {
+		writeCERTInError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ready":  !breach.HasErrors(issues),
+		"issues": issues,
+		"report": report,
+	})
+}
+
+// ExportReport downloads the report with ?format=json|text
+func (h *CERTInHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	doc, contentType, filename, err := h.service.Export(tenantID, breachID, format)
+	if err != nil {
+		writeCERTInError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_cert_in_report_exported", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "format": format,
+	})
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	_, _ = w.Write(doc)
+}
+
+// MarkReported records that the incident was reported to CERT-In
+func (h *CERTInHandler) MarkReported(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	var req struct {
+		Reference  string    `json:"reference"`
+		ReportedAt time.Time `json:"reportedAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	b, err := h.service.MarkReported(tenantID, breachID, req.Reference, req.ReportedAt, userID)
+	if err != nil {
+		writeCERTInError(w, err)
+		return
+	}
+	log.Logger.Info().Str("breach_id", breachID.String()).Str("status", b.CERTInStatus).Msg("breach reported to CERT-In")
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_cert_in_reported", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "reference": req.Reference, "status": b.CERTInStatus,
+	})
+	writeJSON(w, http.StatusOK, b)
+}
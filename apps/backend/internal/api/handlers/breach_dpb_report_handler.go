@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DPBReportHandler generates and serves breach intimations to the Data Protection Board
+type DPBReportHandler struct {
+	service      *services.DPBReportService
+	auditService *services.AuditService
+}
+
+func NewDPBReportHandler(service *services.DPBReportService, auditService *services.AuditService) *DPBReportHandler {
+	return &DPBReportHandler{service: service, auditService: auditService}
+}
+
+func writeDPBReportError(w http.ResponseWriter, err error) {
+	var incomplete *services.DPBReportIncompleteError
+	switch {
+	case errors.As(err, &incomplete):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": incomplete.Error(),
+			"issues":  incomplete.Issues,
+		})
+	case errors.Is(err, services.ErrBreachNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrBreachSimulated):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("breach report request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process breach report")
+	}
+}
+
+// ValidateReport dry-runs the report for ?kind=initial|detailed and lists missing fields
+func (h *DPBReportHandler) ValidateReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	kind := breach.DPBReportKind(r.URL.Query().Get("kind"))
+	if kind == "" {
+		kind = breach.DPBReportInitial
+	}
+	report, issues, err := h.service.Validate(tenantID, breachID, kind)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ready":  !breach.HasErrors(issues),
+		"issues": issues,
+		"report": report,
+	})
+}
+
+// GenerateReport builds, signs and stores a new report version
+func (h *DPBReportHandler) GenerateReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	var req struct {
+		Kind breach.DPBReportKind `json:"kind"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Kind != breach.DPBReportInitial && req.Kind != breach.DPBReportDetailed {
+		writeError(w, http.StatusBadRequest, "kind must be initial or detailed")
+		return
+	}
+
+	report, err := h.service.Generate(tenantID, breachID, req.Kind, userID)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_dpb_report_generated", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "report_id": report.ID, "kind": report.Kind, "version": report.Version, "pdf_sha256": report.PDFSHA256,
+	})
+	writeJSON(w, http.StatusCreated, report)
+}
+
+// ListReports lists generated reports for a breach
+func (h *DPBReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	reports, err := h.service.List(tenantID, breachID)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reports": reports})
+}
+
+// GetReport returns a generated report with its signed JSON payload
+func (h *DPBReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	reportID, err := uuid.Parse(mux.Vars(r)["reportId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+	report, err := h.service.Get(tenantID, reportID)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// DownloadReportPDF streams the signed PDF; the detached signature travels in headers
+func (h *DPBReportHandler) DownloadReportPDF(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	reportID, err := uuid.Parse(mux.Vars(r)["reportId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+	report, data, err := h.service.PDF(tenantID, reportID)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("dpb-%s-report-%s-v%d.pdf", report.Kind, report.BreachID.String()[:8], report.Version)))
+	w.Header().Set("X-Content-SHA256", report.PDFSHA256)
+	w.Header().Set("X-Signature", report.PDFSignature)
+	w.Header().Set("X-Signing-Key-ID", report.SigningKeyID)
+	_, _ = w.Write(data)
+}
+
+// VerifyReport re-checks the stored payload and PDF against their signatures
+func (h *DPBReportHandler) VerifyReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	reportID, err := uuid.Parse(mux.Vars(r)["reportId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+	valid, err := h.service.Verify(tenantID, reportID)
+	if err != nil {
+		writeDPBReportError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"valid": valid})
+}
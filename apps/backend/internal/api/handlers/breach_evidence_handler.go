@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// BreachEvidenceHandler serves breach evidence with a chain of custody
+type BreachEvidenceHandler struct {
+	service      *services.BreachEvidenceService
+	auditService *services.AuditService
+}
+
+func NewBreachEvidenceHandler(service *services.BreachEvidenceService, auditService *services.AuditService) *BreachEvidenceHandler {
+	return &BreachEvidenceHandler{service: service, auditService: auditService}
+}
+
+func writeBreachEvidenceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBreachNotFound), errors.Is(err, services.ErrEvidenceNotFound), errors.Is(err, services.ErrEvidenceNoFile):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrEvidenceEmpty):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrEvidenceTampered):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrEvidenceNotSigned):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("breach evidence request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process breach evidence")
+	}
+}
+
+// evidenceCaller resolves the caller and the custody details of the request
+func evidenceCaller(w http.ResponseWriter, r *http.Request) (uuid.UUID, services.CustodyActor, bool) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return uuid.Nil, services.CustodyActor{}, false
+	}
+	return tenantID, services.CustodyActor{ID: userID, IPAddress: getClientIP(r), UserAgent: r.UserAgent()}, true
+}
+
+func evidenceIDVar(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["evidenceId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid evidence ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// UploadEvidence stores a multipart file as write-once evidence on a breach
+func (h *BreachEvidenceHandler) UploadEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file not provided")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read file")
+		return
+	}
+	confidential, _ := strconv.ParseBool(r.FormValue("isConfidential"))
+
+	e, err := h.service.Upload(tenantID, breachID, services.EvidenceUpload{
+		EvidenceType:   r.FormValue("evidenceType"),
+		Title:          r.FormValue("title"),
+		Description:    r.FormValue("description"),
+		FileName:       header.Filename,
+		ContentType:    header.Header.Get("Content-Type"),
+		IsConfidential: confidential,
+	}, data, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), actor.ID, tenantID, uuid.Nil, "breach_evidence_uploaded", "", "fiduciary", actor.IPAddress, "", "", map[string]interface{}{
+		"breach_id": breachID, "evidence_id": e.ID, "sha256": e.FileHash, "size": e.FileSize,
+	})
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// ListEvidence lists the evidence recorded against a breach; the listing is
+// logged as a custody event on each item
+func (h *BreachEvidenceHandler) ListEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	items, err := h.service.List(tenantID, breachID, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"evidence": items})
+}
+
+// GetEvidence returns an item's record; the view is logged as a custody event
+func (h *BreachEvidenceHandler) GetEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	evidenceID, ok := evidenceIDVar(w, r)
+	if !ok {
+		return
+	}
+	e, err := h.service.View(tenantID, evidenceID, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// DownloadEvidence streams an item's file after checking it against its
+// upload digest; the download is logged as a custody event
+func (h *BreachEvidenceHandler) DownloadEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	evidenceID, ok := evidenceIDVar(w, r)
+	if !ok {
+		return
+	}
+	e, data, err := h.service.Download(tenantID, evidenceID, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", e.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.FileName))
+	w.Header().Set("X-Content-SHA256", e.FileHash)
+	_, _ = w.Write(data)
+}
+
+// GetCustodyLog returns an item's custody log with the state of its hash chain
+func (h *BreachEvidenceHandler) GetCustodyLog(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	evidenceID, ok := evidenceIDVar(w, r)
+	if !ok {
+		return
+	}
+	events, err := h.service.CustodyLog(tenantID, evidenceID)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	brokenAt := services.VerifyCustodyChain(events)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events":        events,
+		"chainIntact":   brokenAt == 0,
+		"chainBrokenAt": brokenAt,
+	})
+}
+
+// VerifyEvidence re-checks an item's file digest and custody chain
+func (h *BreachEvidenceHandler) VerifyEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	evidenceID, ok := evidenceIDVar(w, r)
+	if !ok {
+		return
+	}
+	v, err := h.service.Verify(tenantID, evidenceID, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// ExportEvidence downloads the breach's evidence as a zip with a signed
+// manifest; the detached signature also travels in headers
+func (h *BreachEvidenceHandler) ExportEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, actor, ok := evidenceCaller(w, r)
+	if !ok {
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	bundle, sig, err := h.service.Export(tenantID, breachID, actor)
+	if err != nil {
+		writeBreachEvidenceError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), actor.ID, tenantID, uuid.Nil, "breach_evidence_exported", "", "fiduciary", actor.IPAddress, "", "", map[string]interface{}{
+		"breach_id": breachID,
+	})
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("breach-evidence-%s.zip", breachID.String()[:8])))
+	w.Header().Set("X-Manifest-Signature", sig)
+	w.Header().Set("X-Signing-Key-ID", h.service.SigningKeyID())
+	_, _ = w.Write(bundle)
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// BreachImpactHandler resolves the Data Principals affected by a breach and
+// manages the frozen recipient lists
+type BreachImpactHandler struct {
+	service      *services.BreachImpactService
+	auditService *services.AuditService
+}
+
+func NewBreachImpactHandler(service *services.BreachImpactService, auditService *services.AuditService) *BreachImpactHandler {
+	return &BreachImpactHandler{service: service, auditService: auditService}
+}
+
+func writeBreachImpactError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBreachNotFound), errors.Is(err, services.ErrRecipientListNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, breach.ErrInvalidImpactQuery):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("breach impact request failed")
+		writeError(w, http.StatusInternalServerError, "failed to resolve breach impact")
+	}
+}
+
+// decodeImpactQuery reads the breach ID and impact query from the request
+func decodeImpactQuery(w http.ResponseWriter, r *http.Request) (uuid.UUID, breach.ImpactQuery, bool) {
+	var q breach.ImpactQuery
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return uuid.Nil, q, false
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return uuid.Nil, q, false
+		}
+	}
+	return breachID, q, true
+}
+
+// listIDVar parses {listId}; "latest" selects the newest list
+func listIDVar(r *http.Request) (uuid.UUID, error) {
+	v := mux.Vars(r)["listId"]
+	if v == "latest" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(v)
+}
+
+// PreviewImpact resolves an impact query without freezing it
+func (h *BreachImpactHandler) PreviewImpact(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, q, ok := decodeImpactQuery(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.service.Preview(tenantID, breachID, q)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// FreezeRecipientList resolves the query and stores it as the next list version
+func (h *BreachImpactHandler) FreezeRecipientList(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, q, ok := decodeImpactQuery(w, r)
+	if !ok {
+		return
+	}
+	list, res, err := h.service.Freeze(tenantID, breachID, q, userID)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "breach_recipient_list_frozen", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"breach_id": breachID, "list_id": list.ID, "version": list.Version, "total_recipients": list.TotalRecipients, "content_sha256": list.ContentSHA256,
+	})
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"list":     list,
+		"warnings": res.Warnings,
+	})
+}
+
+// ListRecipientLists lists the breach's recipient list versions
+func (h *BreachImpactHandler) ListRecipientLists(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	lists, err := h.service.Lists(tenantID, breachID)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"lists": lists})
+}
+
+// GetRecipientList returns one list version, or the latest
+func (h *BreachImpactHandler) GetRecipientList(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	listID, err := listIDVar(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid list ID")
+		return
+	}
+	list, err := h.service.Get(tenantID, breachID, listID)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ListRecipients pages through a list's recipients with ?limit=&offset=
+func (h *BreachImpactHandler) ListRecipients(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	listID, err := listIDVar(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid list ID")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	recipients, err := h.service.Recipients(tenantID, breachID, listID, limit, offset)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"recipients": recipients, "limit": limit, "offset": offset})
+}
+
+// VerifyRecipientList checks a frozen list's recipients against its digest
+func (h *BreachImpactHandler) VerifyRecipientList(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	listID, err := listIDVar(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid list ID")
+		return
+	}
+	valid, err := h.service.VerifyList(tenantID, breachID, listID)
+	if err != nil {
+		writeBreachImpactError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"valid": valid})
+}
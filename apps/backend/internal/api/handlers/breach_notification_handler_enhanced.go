@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"pixpivot/arc/internal/claims"
@@ -15,15 +16,18 @@ import (
 )
 
 type EnhancedBreachNotificationHandler struct {
-	service *services.EnhancedBreachNotificationService
+	service         *services.EnhancedBreachNotificationService
+	campaignService *services.BreachCampaignService
 }
 
 func NewEnhancedBreachNotificationHandler(
 	service *services.EnhancedBreachNotificationService,
+	campaignService *services.BreachCampaignService,
 	auditService *services.AuditService,
 ) *EnhancedBreachNotificationHandler {
 	return &EnhancedBreachNotificationHandler{
-		service: service,
+		service:         service,
+		campaignService: campaignService,
 	}
 }
 
@@ -152,8 +156,11 @@ func (h *EnhancedBreachNotificationHandler) SendDataPrincipalNotifications(w htt
 		return
 	}
 
+	// Without explicit addresses the notices go out as an email campaign to
+	// a frozen recipient list, the one named or else the latest
 	var req struct {
-		AffectedEmails []string `json:"affected_emails"`
+		AffectedEmails  []string  `json:"affected_emails"`
+		RecipientListID uuid.UUID `json:"recipient_list_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -163,6 +170,17 @@ func (h *EnhancedBreachNotificationHandler) SendDataPrincipalNotifications(w htt
 	claims := r.Context().Value(contextKey.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
 	sentBy, _ := uuid.Parse(claims.FiduciaryID)
 
+	if len(req.AffectedEmails) == 0 {
+		tenantID, _ := uuid.Parse(claims.TenantID)
+		campaign, err := h.campaignService.Launch(tenantID, breachID, req.RecipientListID, []string{services.CampaignChannelEmail}, sentBy)
+		if err != nil {
+			writeBreachCampaignError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, campaign)
+		return
+	}
+
 	if err := h.service.SendDataPrincipalNotifications(breachID, req.AffectedEmails, sentBy); err != nil {
 		log.Logger.Error().Err(err).Msg("failed to send data principal notifications")
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -205,3 +223,83 @@ func (h *EnhancedBreachNotificationHandler) GetBreachRegister(w http.ResponseWri
 
 	writeJSON(w, http.StatusOK, breaches)
 }
+
+func writeSimulationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrBreachNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotSimulation), errors.Is(err, services.ErrSimulationCompleted):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("breach simulation request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process breach simulation")
+	}
+}
+
+// ListSimulations lists the tenant's tabletop exercises, which the register leaves out
+func (h *EnhancedBreachNotificationHandler) ListSimulations(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(contextKey.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	breaches, err := h.service.ListSimulations(tenantID)
+	if err != nil {
+		writeSimulationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, breaches)
+}
+
+// GetSimulationMessages returns the notices the simulation sink captured
+func (h *EnhancedBreachNotificationHandler) GetSimulationMessages(w http.ResponseWriter, r *http.Request) {
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	claims := r.Context().Value(contextKey.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	msgs, err := h.service.SimulationMessages(tenantID, breachID)
+	if err != nil {
+		writeSimulationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"messages": msgs})
+}
+
+// GetAfterActionReport reviews an exercise so far
+func (h *EnhancedBreachNotificationHandler) GetAfterActionReport(w http.ResponseWriter, r *http.Request) {
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	claims := r.Context().Value(contextKey.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	report, err := h.service.AfterActionReport(tenantID, breachID)
+	if err != nil {
+		writeSimulationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// CompleteSimulation ends an exercise and returns its after-action report
+func (h *EnhancedBreachNotificationHandler) CompleteSimulation(w http.ResponseWriter, r *http.Request) {
+	breachID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid breach ID")
+		return
+	}
+	claims := r.Context().Value(contextKey.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	completedBy, _ := uuid.Parse(claims.FiduciaryID)
+
+	report, err := h.service.CompleteSimulation(tenantID, breachID, completedBy)
+	if err != nil {
+		writeSimulationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
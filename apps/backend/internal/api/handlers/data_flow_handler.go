@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DataFlowHandler serves the personal data flow map and the systems declared
+// for each purpose
+type DataFlowHandler struct {
+	service      *services.DataFlowService
+	auditService *services.AuditService
+}
+
+func NewDataFlowHandler(service *services.DataFlowService, auditService *services.AuditService) *DataFlowHandler {
+	return &DataFlowHandler{service: service, auditService: auditService}
+}
+
+func (h *DataFlowHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("", h.Graph).Methods("GET")
+	r.HandleFunc("/unmapped", h.Unmapped).Methods("GET")
+	r.HandleFunc("/purpose-systems", h.ListSystems).Methods("GET")
+	r.HandleFunc("/purpose-systems", h.DeclareSystem).Methods("POST")
+	r.HandleFunc("/purpose-systems/{purposeId}/{dataSourceId}", h.RemoveSystem).Methods("DELETE")
+}
+
+func writeDataFlowError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrFlowPurposeNotFound),
+		errors.Is(err, services.ErrFlowDataSourceNotFound),
+		errors.Is(err, services.ErrPurposeSystemNotFound),
+		errors.Is(err, services.ErrFlowNodeNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUnsupportedDataFlowFormat):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Graph returns the data flow map. ?dataObject=pan narrows it to where that
+// data flows and ?vendor= to the purposes feeding a vendor; ?format=graphml
+// or dot downloads it for diagramming tools.
+func (h *DataFlowHandler) Graph(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	g, err := h.service.Graph(tenantID, purposes)
+	if err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	if obj := q.Get("dataObject"); obj != "" {
+		if g, err = g.DataObjectFlow(obj); err != nil {
+			writeDataFlowError(w, err)
+			return
+		}
+	}
+	if vendor := q.Get("vendor"); vendor != "" {
+		if g, err = g.VendorFeeds(vendor); err != nil {
+			writeDataFlowError(w, err)
+			return
+		}
+	}
+	format := q.Get("format")
+	if format == "" || format == "json" {
+		writeJSON(w, http.StatusOK, g)
+		return
+	}
+	doc, contentType, filename, err := g.Export(format)
+	if err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	_, _ = w.Write(doc)
+}
+
+// Unmapped lists discovered PII no purpose accounts for and purposes with no
+// declared system
+func (h *DataFlowHandler) Unmapped(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	gaps, err := h.service.Gaps(tenantID, purposes)
+	if err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, gaps)
+}
+
+func (h *DataFlowHandler) ListSystems(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListSystems(tenantID)
+	if err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// DeclareSystem records that a purpose's data is processed in a discovery
+// data source
+func (h *DataFlowHandler) DeclareSystem(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req struct {
+		PurposeID    uuid.UUID `json:"purposeId"`
+		DataSourceID uuid.UUID `json:"dataSourceId"`
+		Note         string    `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	ps, err := h.service.DeclareSystem(tenantID, userID, purposes, req.PurposeID, req.DataSourceID, req.Note)
+	if err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, req.PurposeID, "purpose_system_declared", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"data_source_id": req.DataSourceID, "note": ps.Note,
+	})
+	writeJSON(w, http.StatusCreated, ps)
+}
+
+func (h *DataFlowHandler) RemoveSystem(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vars := mux.Vars(r)
+	purposeID, err := uuid.Parse(vars["purposeId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid purpose ID")
+		return
+	}
+	dataSourceID, err := uuid.Parse(vars["dataSourceId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid data source ID")
+		return
+	}
+	if err := h.service.RemoveSystem(tenantID, purposeID, dataSourceID); err != nil {
+		writeDataFlowError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, purposeID, "purpose_system_removed", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"data_source_id": dataSourceID,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "system removed from the purpose"})
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/dpa"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const signedDPAMaxBytes = 25 << 20
+
+// DPADocumentHandler serves DPA templates, the clause library, agreement
+// generation and signed copies
+type DPADocumentHandler struct {
+	service      *services.DPADocumentService
+	auditService *services.AuditService
+}
+
+func NewDPADocumentHandler(service *services.DPADocumentService, auditService *services.AuditService) *DPADocumentHandler {
+	return &DPADocumentHandler{service: service, auditService: auditService}
+}
+
+func (h *DPADocumentHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/dpa/templates", h.CreateTemplate).Methods("POST")
+	r.HandleFunc("/dpa/templates", h.ListTemplates).Methods("GET")
+	r.HandleFunc("/dpa/templates/{templateId}", h.GetTemplate).Methods("GET")
+	r.HandleFunc("/dpa/templates/{templateId}/versions", h.ReviseTemplate).Methods("POST")
+	r.HandleFunc("/dpa/clauses", h.SaveClause).Methods("POST")
+	r.HandleFunc("/dpa/clauses", h.ListClauses).Methods("GET")
+	r.HandleFunc("/dpa/clauses/{key}/history", h.ClauseHistory).Methods("GET")
+	r.HandleFunc("/dpa/fields", h.StandardFields).Methods("GET")
+
+	r.HandleFunc("/vendors/{vendorId}/dpa/preview", h.Preview).Methods("POST")
+	r.HandleFunc("/vendors/{vendorId}/dpa/generate", h.Generate).Methods("POST")
+	r.HandleFunc("/vendors/{vendorId}/dpa", h.ListAgreements).Methods("GET")
+	r.HandleFunc("/dpa/agreements/{agreementId}", h.GetAgreement).Methods("GET")
+	r.HandleFunc("/dpa/agreements/{agreementId}/document", h.Document).Methods("GET")
+	r.HandleFunc("/dpa/agreements/{agreementId}/signed", h.UploadSigned).Methods("POST")
+	r.HandleFunc("/dpa/agreements/{agreementId}/signed", h.SignedCopy).Methods("GET")
+}
+
+func writeDPADocumentError(w http.ResponseWriter, err error) {
+	var invalid *services.DPATemplateInvalidError
+	var values *services.DPAValuesInvalidError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": invalid.Error(),
+			"issues":  invalid.Issues,
+		})
+	case errors.As(err, &values):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": values.Error(),
+			"issues":  values.Issues,
+		})
+	case errors.Is(err, services.ErrDPATemplateNotFound),
+		errors.Is(err, services.ErrDPATemplateVersionNotFound),
+		errors.Is(err, services.ErrDPAClauseNotFound),
+		errors.Is(err, services.ErrDPANotFound),
+		errors.Is(err, services.ErrVendorNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidDPAClause):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDPAAlreadySigned):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrDPADocumentTampered):
+		log.Logger.Error().Err(err).Msg("DPA document integrity check failed")
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("DPA document request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process DPA request")
+	}
+}
+
+func (h *DPADocumentHandler) audit(r *http.Request, tenantID, userID uuid.UUID, event string, details map[string]interface{}) {
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, event, "", "fiduciary", getClientIP(r), "", "", details)
+}
+
+type dpaTemplateRequest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Body        string      `json:"body"`
+	Fields      []dpa.Field `json:"fields"`
+	Changelog   string      `json:"changelog"`
+}
+
+func (h *DPADocumentHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req dpaTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	detail, err := h.service.CreateTemplate(tenantID, userID, req.Name, req.Description, req.Body, req.Fields)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpa_template_created", map[string]interface{}{
+		"template_id": detail.Template.ID, "name": detail.Template.Name,
+	})
+	writeJSON(w, http.StatusCreated, detail)
+}
+
+func (h *DPADocumentHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListTemplates(tenantID)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPADocumentHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	templateID, err := uuid.Parse(mux.Vars(r)["templateId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid template ID")
+		return
+	}
+	detail, err := h.service.GetTemplate(tenantID, templateID)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h *DPADocumentHandler) ReviseTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	templateID, err := uuid.Parse(mux.Vars(r)["templateId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid template ID")
+		return
+	}
+	var req dpaTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v, err := h.service.ReviseTemplate(tenantID, templateID, userID, req.Body, req.Fields, req.Changelog)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpa_template_revised", map[string]interface{}{
+		"template_id": templateID, "version": v.Version,
+	})
+	writeJSON(w, http.StatusCreated, v)
+}
+
+// StandardFields lists the merge fields every template can use
+func (h *DPADocumentHandler) StandardFields(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, dpa.StandardFields)
+}
+
+func (h *DPADocumentHandler) SaveClause(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req struct {
+		Key      string `json:"key"`
+		Title    string `json:"title"`
+		Category string `json:"category"`
+		Body     string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	c, err := h.service.SaveClause(tenantID, userID, req.Key, req.Title, req.Category, req.Body)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpa_clause_saved", map[string]interface{}{
+		"clause_key": c.Key, "version": c.Version,
+	})
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *DPADocumentHandler) ListClauses(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListClauses(tenantID)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPADocumentHandler) ClauseHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ClauseHistory(tenantID, mux.Vars(r)["key"])
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPADocumentHandler) generateRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, *services.GenerateDPAInput, bool) {
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return uuid.Nil, nil, false
+	}
+	var in services.GenerateDPAInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return uuid.Nil, nil, false
+	}
+	return vendorID, &in, true
+}
+
+func (h *DPADocumentHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, in, ok := h.generateRequest(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.service.Preview(tenantID, vendorID, *in)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *DPADocumentHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, in, ok := h.generateRequest(w, r)
+	if !ok {
+		return
+	}
+	a, err := h.service.Generate(tenantID, vendorID, userID, *in)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpa_generated", map[string]interface{}{
+		"agreement_id": a.ID, "vendor_id": vendorID, "template_id": a.TemplateID,
+		"template_version": a.TemplateVersion, "document_sha256": a.DocumentSHA256,
+	})
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *DPADocumentHandler) ListAgreements(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	list, err := h.service.ListAgreements(tenantID, vendorID)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func agreementID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["agreementId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid agreement ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *DPADocumentHandler) GetAgreement(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := agreementID(w, r)
+	if !ok {
+		return
+	}
+	a, err := h.service.GetAgreement(tenantID, id)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *DPADocumentHandler) Document(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := agreementID(w, r)
+	if !ok {
+		return
+	}
+	a, data, err := h.service.Document(tenantID, id)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "dpa-"+a.ID.String()+".pdf"))
+	w.Header().Set("X-Content-SHA256", a.DocumentSHA256)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (h *DPADocumentHandler) UploadSigned(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := agreementID(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, signedDPAMaxBytes+1<<20)
+	if err := r.ParseMultipartForm(signedDPAMaxBytes); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file not provided")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, signedDPAMaxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+	if len(data) > signedDPAMaxBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "signed copies are limited to 25MB")
+		return
+	}
+
+	a, err := h.service.UploadSigned(tenantID, id, userID, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpa_signed_copy_uploaded", map[string]interface{}{
+		"agreement_id": a.ID, "vendor_id": a.VendorID, "file_name": header.Filename, "signed_sha256": a.SignedSHA256,
+	})
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (h *DPADocumentHandler) SignedCopy(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := agreementID(w, r)
+	if !ok {
+		return
+	}
+	a, data, err := h.service.SignedCopy(tenantID, id)
+	if err != nil {
+		writeDPADocumentError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "dpa-"+a.ID.String()+"-signed.pdf"))
+	w.Header().Set("X-Content-SHA256", a.SignedSHA256)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
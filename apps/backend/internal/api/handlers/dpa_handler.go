@@ -314,8 +314,6 @@ func (h *DPAHandler) UpdateDPA(w http.ResponseWriter, r *http.Request) {
 		GoverningLaw         *string  `json:"governingLaw,omitempty"`
 		SignatoryName        *string  `json:"signatoryName,omitempty"`
 		SignatoryTitle       *string  `json:"signatoryTitle,omitempty"`
-		Signature            *string  `json:"signature,omitempty"`
-		SignedDate           *string  `json:"signedDate,omitempty"`
 		Version              *string  `json:"version,omitempty"`
 	}
 
@@ -332,6 +330,11 @@ func (h *DPAHandler) UpdateDPA(w http.ResponseWriter, r *http.Request) {
 		dpaModel.AgreementNumber = *dpaRequest.AgreementNumber
 	}
 	if dpaRequest.Status != nil {
+		// Agreements become active only through the signing ceremony
+		if *dpaRequest.Status == string(dpa.DPAStatusActive) && dpaModel.Status != string(dpa.DPAStatusActive) {
+			writeError(w, http.StatusConflict, "DPA becomes active once all parties have signed")
+			return
+		}
 		dpaModel.Status = *dpaRequest.Status
 	}
 	if dpaRequest.EffectiveDate != nil {
@@ -428,17 +431,6 @@ func (h *DPAHandler) UpdateDPA(w http.ResponseWriter, r *http.Request) {
 	if dpaRequest.SignatoryTitle != nil {
 		dpaModel.SignatoryTitle = *dpaRequest.SignatoryTitle
 	}
-	if dpaRequest.Signature != nil {
-		dpaModel.Signature = dpaRequest.Signature
-	}
-	if dpaRequest.SignedDate != nil {
-		sd, err := time.Parse("2006-01-02", *dpaRequest.SignedDate)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "Invalid signed date format")
-			return
-		}
-		dpaModel.SignedDate = &sd
-	}
 	if dpaRequest.Version != nil {
 		dpaModel.Version = *dpaRequest.Version
 	}
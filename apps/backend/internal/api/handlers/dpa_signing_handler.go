@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"pixpivot/arc/internal/contextkeys"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SigningTokenHeader carries a DPA signing link token when it is not sent as
+// a bearer token
+const SigningTokenHeader = "X-Signing-Token"
+
+// DPASigningHandler serves DPA signing ceremonies: the fiduciary endpoints
+// that start and track them, and the signer-facing endpoints behind each
+// personal signing link
+type DPASigningHandler struct {
+	service      *services.DPASigningService
+	auditService *services.AuditService
+}
+
+func NewDPASigningHandler(service *services.DPASigningService, auditService *services.AuditService) *DPASigningHandler {
+	return &DPASigningHandler{service: service, auditService: auditService}
+}
+
+// RegisterFiduciaryRoutes mounts ceremony management on the DPA router
+func (h *DPASigningHandler) RegisterFiduciaryRoutes(r *mux.Router) {
+	r.HandleFunc("/{id}/signing", h.Start).Methods("POST")
+	r.HandleFunc("/{id}/signing", h.List).Methods("GET")
+	r.HandleFunc("/signing-requests/{requestId}", h.Get).Methods("GET")
+	r.HandleFunc("/signing-requests/{requestId}/events", h.Events).Methods("GET")
+	r.HandleFunc("/signing-requests/{requestId}/cancel", h.Cancel).Methods("POST")
+	r.HandleFunc("/signing-requests/{requestId}/seal", h.Seal).Methods("POST")
+	r.HandleFunc("/signing-requests/{requestId}/sealed", h.SealedCopy).Methods("GET")
+}
+
+// RegisterSignerRoutes mounts the signer-facing routes; the router must use
+// h.Authenticate
+func (h *DPASigningHandler) RegisterSignerRoutes(r *mux.Router) {
+	r.HandleFunc("", h.Open).Methods("GET")
+	r.HandleFunc("/document", h.Document).Methods("GET")
+	r.HandleFunc("/otp", h.SendOTP).Methods("POST")
+	r.HandleFunc("/sign", h.Sign).Methods("POST")
+	r.HandleFunc("/sealed", h.SignerSealedCopy).Methods("GET")
+}
+
+// Authenticate resolves the signing link token and stores the session in
+// the request context
+func (h *DPASigningHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(SigningTokenHeader)
+		if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		sess, err := h.service.Authenticate(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), contextkeys.DPASignerKey, sess)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func signingSession(r *http.Request) *services.DPASigningSession {
+	sess, _ := r.Context().Value(contextkeys.DPASignerKey).(*services.DPASigningSession)
+	return sess
+}
+
+// auditSigner records a signer action. The signer stands in for the user,
+// since signatories have no account.
+func (h *DPASigningHandler) auditSigner(r *http.Request, sess *services.DPASigningSession, action, status string, details map[string]interface{}) {
+	details["request_id"] = sess.Request.ID
+	details["dpa_id"] = sess.Request.DPAID
+	details["signer_id"] = sess.Signer.ID
+	details["email"] = sess.Signer.Email
+	details["party"] = sess.Signer.Party
+	details["user_agent"] = r.UserAgent()
+	go h.auditService.Create(context.Background(), sess.Signer.ID, sess.Request.TenantID, uuid.Nil, action, status, "signer", getClientIP(r), "", "", details)
+}
+
+func writeDPASigningError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrDPANotFound),
+		errors.Is(err, services.ErrDPASigningNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidDPASigning):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSigningOTPInvalid),
+		errors.Is(err, services.ErrSigningOTPLocked):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrSigningOTPTooSoon):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrDPANotSignable),
+		errors.Is(err, services.ErrDPASigningInProgress),
+		errors.Is(err, services.ErrDPASigningClosed),
+		errors.Is(err, services.ErrDPASignerAlreadySigned),
+		errors.Is(err, services.ErrDPASigningDocChanged),
+		errors.Is(err, services.ErrDPASigningNotSealable):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrDPASigningUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, services.ErrDPADocumentTampered):
+		log.Logger.Error().Err(err).Msg("DPA signing document integrity check failed")
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("DPA signing request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process signing request")
+	}
+}
+
+func writePDF(w http.ResponseWriter, name, sha string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Content-SHA256", sha)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// Fiduciary endpoints
+
+func requestID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["requestId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid signing request ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Start sends the agreement for signature. Signing links are only ever sent
+// by email.
+func (h *DPASigningHandler) Start(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	dpaID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid DPA ID")
+		return
+	}
+	var in services.StartSigningInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req, _, err := h.service.Start(tenantID, dpaID, userID, in)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "dpa_signing_started", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"dpa_id": dpaID, "request_id": req.ID, "signers": len(req.Signers), "document_sha256": req.DocumentSHA256,
+	})
+	writeJSON(w, http.StatusCreated, req)
+}
+
+func (h *DPASigningHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	dpaID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid DPA ID")
+		return
+	}
+	list, err := h.service.ListForDPA(tenantID, dpaID)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPASigningHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	req, err := h.service.Get(tenantID, id)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (h *DPASigningHandler) Events(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	events, err := h.service.Events(tenantID, id)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (h *DPASigningHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	req, err := h.service.Cancel(tenantID, id)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "dpa_signing_cancelled", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"dpa_id": req.DPAID, "request_id": req.ID,
+	})
+	writeJSON(w, http.StatusOK, req)
+}
+
+// Seal retries sealing once every party has signed
+func (h *DPASigningHandler) Seal(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	req, err := h.service.Seal(tenantID, id)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "dpa_signing_sealed", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"dpa_id": req.DPAID, "request_id": req.ID, "sealed_sha256": req.SealedSHA256,
+	})
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (h *DPASigningHandler) SealedCopy(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, ok := requestID(w, r)
+	if !ok {
+		return
+	}
+	req, err := h.service.Get(tenantID, id)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	data, err := h.service.Sealed(req)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	w.Header().Set("X-Signature", req.SealSignature)
+	w.Header().Set("X-Signing-Key-ID", req.SigningKeyID)
+	writePDF(w, "dpa-"+req.DPAID.String()+"-sealed.pdf", req.SealedSHA256, data)
+}
+
+// Signer endpoints
+
+func (h *DPASigningHandler) Open(w http.ResponseWriter, r *http.Request) {
+	sess := signingSession(r)
+	first := sess.Signer.ViewedAt == nil
+	view, err := h.service.Open(sess, getClientIP(r), r.UserAgent())
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	if first {
+		h.auditSigner(r, sess, "dpa_signing_viewed", "", map[string]interface{}{})
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *DPASigningHandler) Document(w http.ResponseWriter, r *http.Request) {
+	sess := signingSession(r)
+	data, err := h.service.Document(sess)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	writePDF(w, "dpa-"+sess.Request.DPAID.String()+".pdf", sess.Request.DocumentSHA256, data)
+}
+
+func (h *DPASigningHandler) SendOTP(w http.ResponseWriter, r *http.Request) {
+	sess := signingSession(r)
+	expires, err := h.service.SendOTP(sess, getClientIP(r), r.UserAgent())
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	h.auditSigner(r, sess, "dpa_signing_otp_sent", "", map[string]interface{}{})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"expiresAt": expires})
+}
+
+func (h *DPASigningHandler) Sign(w http.ResponseWriter, r *http.Request) {
+	sess := signingSession(r)
+	var in services.SignInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req, err := h.service.Sign(sess, in, getClientIP(r), r.UserAgent())
+	if err != nil {
+		if errors.Is(err, services.ErrSigningOTPInvalid) || errors.Is(err, services.ErrSigningOTPLocked) {
+			h.auditSigner(r, sess, "dpa_signing_otp_failed", "denied", map[string]interface{}{})
+		}
+		writeDPASigningError(w, err)
+		return
+	}
+	h.auditSigner(r, sess, "dpa_signed", "", map[string]interface{}{
+		"document_sha256": req.DocumentSHA256, "request_status": req.Status,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": req.Status, "documentSha256": req.DocumentSHA256})
+}
+
+func (h *DPASigningHandler) SignerSealedCopy(w http.ResponseWriter, r *http.Request) {
+	sess := signingSession(r)
+	data, err := h.service.Sealed(sess.Request)
+	if err != nil {
+		writeDPASigningError(w, err)
+		return
+	}
+	writePDF(w, "dpa-"+sess.Request.DPAID.String()+"-sealed.pdf", sess.Request.SealedSHA256, data)
+}
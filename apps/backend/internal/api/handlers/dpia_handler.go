@@ -0,0 +1,532 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/dpia"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DPIAHandler serves Data Protection Impact Assessments: templates,
+// answers, the risk register, mitigations, DPO sign-off and the audit report
+type DPIAHandler struct {
+	service      *services.DPIAService
+	auditService *services.AuditService
+}
+
+func NewDPIAHandler(service *services.DPIAService, auditService *services.AuditService) *DPIAHandler {
+	return &DPIAHandler{service: service, auditService: auditService}
+}
+
+func (h *DPIAHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/templates", h.ListTemplates).Methods("GET")
+	r.HandleFunc("/templates", h.CreateTemplate).Methods("POST")
+	r.HandleFunc("/templates/default", h.DefaultTemplate).Methods("GET")
+	r.HandleFunc("/templates/{id}", h.GetTemplate).Methods("GET")
+	r.HandleFunc("/templates/{id}", h.UpdateTemplate).Methods("PUT")
+	r.HandleFunc("", h.List).Methods("GET")
+	r.HandleFunc("", h.Create).Methods("POST")
+	r.HandleFunc("/{id}", h.Get).Methods("GET")
+	r.HandleFunc("/{id}", h.Update).Methods("PUT")
+	r.HandleFunc("/{id}/answers", h.SaveAnswers).Methods("PUT")
+	r.HandleFunc("/{id}/risks", h.AddRisk).Methods("POST")
+	r.HandleFunc("/{id}/risks/{riskId}", h.UpdateRisk).Methods("PUT")
+	r.HandleFunc("/{id}/risks/{riskId}", h.DeleteRisk).Methods("DELETE")
+	r.HandleFunc("/{id}/mitigations", h.AddMitigation).Methods("POST")
+	r.HandleFunc("/{id}/mitigations/{mitigationId}", h.UpdateMitigation).Methods("PUT")
+	r.HandleFunc("/{id}/mitigations/{mitigationId}", h.DeleteMitigation).Methods("DELETE")
+	r.HandleFunc("/{id}/submit", h.Submit).Methods("POST")
+	r.HandleFunc("/{id}/reassess", h.Reassess).Methods("POST")
+	r.HandleFunc("/{id}/export", h.Export).Methods("GET")
+}
+
+// RegisterApprovalRoutes registers the DPO's sign-off routes, which need
+// their own permission
+func (h *DPIAHandler) RegisterApprovalRoutes(r *mux.Router) {
+	r.HandleFunc("/{id}/approve", h.Approve).Methods("POST")
+	r.HandleFunc("/{id}/request-changes", h.RequestChanges).Methods("POST")
+}
+
+func writeDPIAError(w http.ResponseWriter, err error) {
+	var incomplete *services.DPIAIncompleteError
+	var invalid *services.QuestionnaireInvalidError
+	var rejected *services.AnswersRejectedError
+	switch {
+	case errors.As(err, &incomplete):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": incomplete.Error(),
+			"issues":  incomplete.Issues,
+		})
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": invalid.Error(),
+			"issues":  invalid.Issues,
+		})
+	case errors.As(err, &rejected):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": rejected.Error(),
+			"issues":  rejected.Issues,
+		})
+	case errors.Is(err, services.ErrDPIANotFound),
+		errors.Is(err, services.ErrDPIATemplateNotFound),
+		errors.Is(err, services.ErrDPIARiskNotFound),
+		errors.Is(err, services.ErrDPIAMitigationNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDPIANotEditable),
+		errors.Is(err, services.ErrDPIAWrongStatus):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrDPIASelfApproval):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrInvalidDPIA),
+		errors.Is(err, services.ErrUnsupportedDPIAFormat):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// dpiaChildID parses a risk or mitigation ID from the route
+func dpiaChildID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid "+name)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func decodeDPIABody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+func (h *DPIAHandler) audit(r *http.Request, tenantID, userID uuid.UUID, action, status string, details map[string]interface{}) {
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, action, status, "fiduciary", getClientIP(r), "", "", details)
+}
+
+func (h *DPIAHandler) DefaultTemplate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.DefaultTemplate())
+}
+
+func (h *DPIAHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListTemplates(tenantID)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPIAHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	t, err := h.service.GetTemplate(tenantID, id)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+type dpiaTemplateRequest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Content     dpia.Template `json:"content"`
+}
+
+func (h *DPIAHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req dpiaTemplateRequest
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	t, err := h.service.SaveTemplate(tenantID, userID, nil, req.Name, req.Description, req.Content)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_template_created", "", map[string]interface{}{"template_id": t.ID, "name": t.Name})
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// UpdateTemplate replaces a template; DPIAs already started keep their copy
+func (h *DPIAHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dpiaTemplateRequest
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	t, err := h.service.SaveTemplate(tenantID, userID, &id, req.Name, req.Description, req.Content)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_template_updated", "", map[string]interface{}{"template_id": t.ID, "name": t.Name})
+	writeJSON(w, http.StatusOK, t)
+}
+
+// List returns the tenant's DPIAs, optionally filtered with ?status=
+func (h *DPIAHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.List(tenantID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DPIAHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req services.DPIAInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	d, err := h.service.Create(tenantID, userID, purposes, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_created", d.Status, map[string]interface{}{"dpia_id": d.ID, "title": d.Title})
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// Get returns a DPIA with its answers, score, risks, mitigations and history
+func (h *DPIAHandler) Get(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	detail, err := h.service.Get(tenantID, id, purposes)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h *DPIAHandler) Update(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req services.DPIAInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	d, err := h.service.Update(tenantID, id, purposes, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_updated", d.Status, map[string]interface{}{"dpia_id": d.ID})
+	writeJSON(w, http.StatusOK, d)
+}
+
+// SaveAnswers merges answers keyed by question ID and returns the score and
+// any risks the answers raised
+func (h *DPIAHandler) SaveAnswers(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Answers map[string]services.DPIAAnswer `json:"answers"`
+	}
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	res, err := h.service.SaveAnswers(tenantID, id, req.Answers)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *DPIAHandler) AddRisk(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req services.DPIARiskInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	risk, err := h.service.AddRisk(tenantID, id, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_risk_added", risk.Level, map[string]interface{}{"dpia_id": id, "risk_id": risk.ID, "score": risk.Score})
+	writeJSON(w, http.StatusCreated, risk)
+}
+
+func (h *DPIAHandler) UpdateRisk(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	riskID, ok := dpiaChildID(w, r, "riskId")
+	if !ok {
+		return
+	}
+	var req services.DPIARiskInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	risk, err := h.service.UpdateRisk(tenantID, id, riskID, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_risk_updated", risk.Level, map[string]interface{}{
+		"dpia_id": id, "risk_id": risk.ID, "score": risk.Score, "residual_score": risk.ResidualScore,
+	})
+	writeJSON(w, http.StatusOK, risk)
+}
+
+func (h *DPIAHandler) DeleteRisk(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	riskID, ok := dpiaChildID(w, r, "riskId")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteRisk(tenantID, id, riskID); err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_risk_deleted", "", map[string]interface{}{"dpia_id": id, "risk_id": riskID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DPIAHandler) AddMitigation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req services.DPIAMitigationInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	m, err := h.service.AddMitigation(tenantID, id, purposes, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_mitigation_added", m.Status, map[string]interface{}{"dpia_id": id, "risk_id": m.RiskID, "mitigation_id": m.ID})
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// UpdateMitigation changes a mitigation, including tracking its progress
+// after the DPIA is approved
+func (h *DPIAHandler) UpdateMitigation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	mitigationID, ok := dpiaChildID(w, r, "mitigationId")
+	if !ok {
+		return
+	}
+	var req services.DPIAMitigationInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	m, err := h.service.UpdateMitigation(tenantID, id, mitigationID, purposes, req)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_mitigation_updated", m.Status, map[string]interface{}{"dpia_id": id, "risk_id": m.RiskID, "mitigation_id": m.ID})
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (h *DPIAHandler) DeleteMitigation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	mitigationID, ok := dpiaChildID(w, r, "mitigationId")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteMitigation(tenantID, id, mitigationID); err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_mitigation_deleted", "", map[string]interface{}{"dpia_id": id, "mitigation_id": mitigationID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Submit sends the DPIA to the DPO for sign-off
+func (h *DPIAHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	d, err := h.service.Submit(tenantID, id, userID)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_submitted", d.Status, map[string]interface{}{"dpia_id": d.ID, "cycle": d.Cycle})
+	writeJSON(w, http.StatusOK, d)
+}
+
+type dpiaNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// decodeDPIANote reads an optional {"note": "..."} body
+func decodeDPIANote(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req dpiaNoteRequest
+	if r.ContentLength != 0 && !decodeDPIABody(w, r, &req) {
+		return "", false
+	}
+	return req.Note, true
+}
+
+// Approve is the DPO's sign-off
+func (h *DPIAHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	note, ok := decodeDPIANote(w, r)
+	if !ok {
+		return
+	}
+	d, err := h.service.Approve(tenantID, id, userID, note)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_approved", d.Status, map[string]interface{}{
+		"dpia_id": d.ID, "cycle": d.Cycle, "risk_level": d.RiskLevel, "next_review_at": d.NextReviewAt, "note": d.DecisionNote,
+	})
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (h *DPIAHandler) RequestChanges(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	note, ok := decodeDPIANote(w, r)
+	if !ok {
+		return
+	}
+	d, err := h.service.RequestChanges(tenantID, id, userID, note)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_changes_requested", d.Status, map[string]interface{}{"dpia_id": d.ID, "note": d.DecisionNote})
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Reassess reopens an approved or review-due DPIA for its next cycle
+func (h *DPIAHandler) Reassess(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	note, ok := decodeDPIANote(w, r)
+	if !ok {
+		return
+	}
+	d, err := h.service.Reassess(tenantID, id, userID, note)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_reassessment_started", d.Status, map[string]interface{}{"dpia_id": d.ID, "cycle": d.Cycle})
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Export downloads the audit report with ?format=pdf|json
+func (h *DPIAHandler) Export(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	doc, contentType, filename, err := h.service.Export(tenantID, id, purposes, format)
+	if err != nil {
+		writeDPIAError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpia_exported", "", map[string]interface{}{"dpia_id": id, "format": format})
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	_, _ = w.Write(doc)
+}
@@ -6,8 +6,12 @@ import (
 	"pixpivot/arc/internal/api/middleware"
 	"pixpivot/arc/internal/models"
 	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/storage/repository"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,38 +22,127 @@ import (
 type DataRequestHandler struct {
 	DB           *gorm.DB
 	DSRService   *services.DSRService
+	QueueService *services.DSRQueueService
 	AuditService *services.AuditService
 }
 
-func NewDataRequestHandler(db *gorm.DB, dsrService *services.DSRService, auditService *services.AuditService) *DataRequestHandler {
-	return &DataRequestHandler{DB: db, DSRService: dsrService, AuditService: auditService}
+func NewDataRequestHandler(db *gorm.DB, dsrService *services.DSRService, queueService *services.DSRQueueService, auditService *services.AuditService) *DataRequestHandler {
+	return &DataRequestHandler{DB: db, DSRService: dsrService, QueueService: queueService, AuditService: auditService}
 }
 
-// ListAdminRequests lists all data requests for admins
+// ListAdminRequests returns the tenant's DSR queue, paginated and filtered by
+// status, type, subject_type, priority, assigned_to ("me", "unassigned" or a user
+// ID) and due_within_hours. Without a status filter only open requests are listed.
 func (h *DataRequestHandler) ListAdminRequests(w http.ResponseWriter, r *http.Request) {
-	if _, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims); !ok {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	var requests []models.DSRRequest
-	if err := h.DB.
-		Where("status = ?", "Pending").
-		Find(&requests).Error; err != nil {
+	tenantID, err := uuid.Parse(claims.TenantID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid tenant")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := repository.DSRQueueFilter{
+		TenantID:    tenantID,
+		Status:      q.Get("status"),
+		Type:        q.Get("type"),
+		SubjectType: q.Get("subject_type"),
+		Priority:    q.Get("priority"),
+		OpenOnly:    q.Get("status") == "",
+	}
+	switch assigned := q.Get("assigned_to"); assigned {
+	case "":
+	case "unassigned":
+		filter.Unassigned = true
+	case "me":
+		me, _ := uuid.Parse(claims.FiduciaryID)
+		filter.AssignedTo = &me
+	default:
+		id, err := uuid.Parse(assigned)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid assigned_to")
+			return
+		}
+		filter.AssignedTo = &id
+	}
+	if hours, err := strconv.Atoi(q.Get("due_within_hours")); err == nil && hours > 0 {
+		due := time.Now().Add(time.Duration(hours) * time.Hour)
+		filter.DueBefore = &due
+	}
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 25
+	}
+
+	requests, total, err := h.QueueService.ListQueue(filter, limit, (page-1)*limit)
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "db error")
 		return
 	}
-	writeJSON(w, http.StatusOK, requests)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"requests": requests,
+	})
+}
+
+// BulkReassign moves a set of requests to one assignee
+func (h *DataRequestHandler) BulkReassign(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	var req struct {
+		RequestIDs []uuid.UUID `json:"request_ids"`
+		AssigneeID uuid.UUID   `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	updated, err := h.QueueService.BulkReassign(tenantID, req.RequestIDs, req.AssigneeID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if h.AuditService != nil {
+		actorID, _ := uuid.Parse(claims.FiduciaryID)
+		go h.AuditService.Create(context.Background(), actorID, tenantID, uuid.Nil, "dsr_bulk_reassigned", "reassigned", claims.FiduciaryID, getClientIP(r), "", "", map[string]interface{}{
+			"request_ids": req.RequestIDs,
+			"assignee_id": req.AssigneeID.String(),
+			"updated":     updated,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"updated": updated})
 }
 
 // GetAdminRequestDetails retrieves details of a specific data request for admins
 func (h *DataRequestHandler) GetAdminRequestDetails(w http.ResponseWriter, r *http.Request) {
-	if _, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims); !ok {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
 	id := mux.Vars(r)["id"]
 	var req models.DSRRequest
-	if err := h.DB.Where("id = ?", id).First(&req).Error; err != nil {
+	if err := h.DB.Where("id = ? AND tenant_id = ?", id, tenantID).First(&req).Error; err != nil {
 		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
@@ -71,8 +164,9 @@ func (h *DataRequestHandler) ApproveRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	tenantID, _ := uuid.Parse(claims.TenantID)
 	var req models.DSRRequest
-	if err := h.DB.Where("id = ? AND status = ?", requestID, "Pending").First(&req).Error; err != nil {
+	if err := h.DB.Where("id = ? AND tenant_id = ? AND status = ?", requestID, tenantID, "Pending").First(&req).Error; err != nil {
 		writeError(w, http.StatusNotFound, "request not found or already processed")
 		return
 	}
@@ -104,6 +198,8 @@ func (h *DataRequestHandler) ApproveRequest(w http.ResponseWriter, r *http.Reque
 		writeError(w, http.StatusInternalServerError, "failed to update request")
 		return
 	}
+	actorID, _ := uuid.Parse(claims.FiduciaryID)
+	h.DSRService.RecordStatusChange(&req, &actorID)
 
 	writeJSON(w, http.StatusOK, req)
 }
@@ -115,9 +211,10 @@ func (h *DataRequestHandler) RejectRequest(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
 	id := mux.Vars(r)["id"]
 	var req models.DSRRequest
-	if err := h.DB.Where("id = ? AND status = ?", id, "Pending").
+	if err := h.DB.Where("id = ? AND tenant_id = ? AND status = ?", id, tenantID, "Pending").
 		First(&req).Error; err != nil {
 		writeError(w, http.StatusNotFound, "request not found or already processed")
 		return
@@ -128,6 +225,8 @@ func (h *DataRequestHandler) RejectRequest(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusInternalServerError, "failed to update request")
 		return
 	}
+	actorID, _ := uuid.Parse(claims.FiduciaryID)
+	h.DSRService.RecordStatusChange(&req, &actorID)
 	writeJSON(w, http.StatusOK, req)
 }
 
@@ -139,7 +238,7 @@ func (h *DataRequestHandler) ListUserRequests(w http.ResponseWriter, r *http.Req
 	}
 	var requests []models.DSRRequest
 	if err := h.DB.
-		Where("user_id = ?", claims.ID).
+		Where("user_id = ?", claims.PrincipalID).
 		Order("requested_at DESC").
 		Find(&requests).Error; err != nil {
 		writeError(w, http.StatusInternalServerError, "db error")
@@ -158,6 +257,12 @@ func (h *DataRequestHandler) CreateUserRequest(w http.ResponseWriter, r *http.Re
 		TenantID       string `json:"tenant_id"` // NEW: get from body, not JWT
 		Type           string `json:"type"`
 		CorrectionNote string `json:"correctionNote,omitempty"`
+		ConsentFormID  string `json:"consentFormId,omitempty"` // Form the request was raised from; scopes routing
+		Changes        []struct {
+			Field    string `json:"field"`
+			NewValue string `json:"newValue"`
+			Evidence string `json:"evidence,omitempty"`
+		} `json:"changes,omitempty"` // Field-level corrections for rectification requests
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Type == "" || req.TenantID == "" {
 		writeError(w, http.StatusBadRequest, "invalid request: missing tenant, type or invalid json")
@@ -170,22 +275,66 @@ func (h *DataRequestHandler) CreateUserRequest(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	userID, err := uuid.Parse(claims.PrincipalID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid user ID in token")
+		return
+	}
+
 	newRequest := models.DSRRequest{
-		ID:          uuid.New(),
-		UserID:      uuid.MustParse(claims.ID),
-		TenantID:    tenantUUID,
-		Type:        req.Type,
-		Status:      "Pending",
-		RequestedAt: time.Now(),
+		ID:             uuid.New(),
+		UserID:         userID,
+		TenantID:       tenantUUID,
+		Type:           req.Type,
+		Status:         "Pending",
+		RequestedAt:    time.Now(),
+		RequesterEmail: claims.Email,
 	}
 	if req.Type == "Data Correction" && req.CorrectionNote != "" {
 		newRequest.ResolutionNote = req.CorrectionNote // or another field as needed
 	}
-	
-	if err := h.DSRService.CreateRequest(&newRequest); err != nil {
+
+	if req.ConsentFormID != "" {
+		formID, err := uuid.Parse(req.ConsentFormID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid consentFormId")
+			return
+		}
+		if err := h.DSRService.ScopeToConsentForm(&newRequest, formID); err != nil {
+			writeError(w, http.StatusBadRequest, "consent form not found")
+			return
+		}
+	}
+
+	// A nominee acting under an approved claim files the request in the principal's name
+	nomineeClaim := nomineeClaimFromContext(r.Context())
+	if nomineeClaim != nil {
+		newRequest.ActingNomineeID = &nomineeClaim.NomineeID
+		newRequest.NomineeClaimID = &nomineeClaim.ID
+	}
+
+	if services.IsRectificationType(req.Type) && len(req.Changes) > 0 {
+		changes := make([]models.DSRFieldChange, 0, len(req.Changes))
+		for _, c := range req.Changes {
+			changes = append(changes, models.DSRFieldChange{Field: c.Field, NewValue: c.NewValue, Evidence: c.Evidence})
+		}
+		if err := h.DSRService.CreateRectificationRequest(&newRequest, changes); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err := h.DSRService.CreateRequest(&newRequest); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to create request")
 		return
 	}
+
+	if nomineeClaim != nil {
+		go h.AuditService.Create(context.Background(), userID, tenantUUID, uuid.Nil, "dsr_created_by_nominee", "created", "nominee", getClientIP(r), "", "", map[string]interface{}{
+			"dsrId":     newRequest.ID.String(),
+			"dsrType":   newRequest.Type,
+			"nomineeId": nomineeClaim.NomineeID.String(),
+			"claimId":   nomineeClaim.ID.String(),
+		})
+	}
 	writeJSON(w, http.StatusCreated, newRequest)
 }
 
@@ -197,10 +346,226 @@ func (h *DataRequestHandler) GetRequestDetails(w http.ResponseWriter, r *http.Re
 	}
 	id := mux.Vars(r)["id"]
 	var req models.DSRRequest
-	if err := h.DB.Where("id = ? AND user_id = ?", id, claims.ID).First(&req).Error; err != nil {
+	if err := h.DB.Where("id = ? AND user_id = ?", id, claims.PrincipalID).First(&req).Error; err != nil {
 		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
 	writeJSON(w, http.StatusOK, req)
 }
 
+
+// GetRectificationDiff returns the proposed field changes on a rectification request
+func (h *DataRequestHandler) GetRectificationDiff(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	requestID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	changes, err := h.DSRService.GetFieldChanges(requestID, tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "db error")
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// ReviewFieldChange approves or rejects a single proposed field change
+func (h *DataRequestHandler) ReviewFieldChange(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vars := mux.Vars(r)
+	requestID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+	changeID, err := uuid.Parse(vars["changeId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid change ID")
+		return
+	}
+
+	var req struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	reviewerID, _ := uuid.Parse(claims.FiduciaryID)
+	change, err := h.DSRService.ReviewFieldChange(requestID, tenantID, changeID, reviewerID, req.Approve, req.Note)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
+}
+
+// ApplyRectification applies all approved field changes to the principal record
+func (h *DataRequestHandler) ApplyRectification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	requestID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	reviewerID, _ := uuid.Parse(claims.FiduciaryID)
+	dsr, err := h.DSRService.ApplyRectification(requestID, tenantID, reviewerID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	case errors.Is(err, services.ErrRectificationNotApproved):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if h.AuditService != nil {
+		go h.AuditService.Create(context.Background(), dsr.UserID, tenantID, uuid.Nil, "data_principal_rectified", dsr.Status, claims.FiduciaryID, getClientIP(r), "", "", map[string]interface{}{
+			"request_id": requestID.String(),
+			"result":     dsr.ResultData,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, dsr)
+}
+
+// ListAdminComments returns all comments on a request, including internal notes
+func (h *DataRequestHandler) ListAdminComments(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req models.DSRRequest
+	if err := h.DB.Where("id = ? AND tenant_id = ?", mux.Vars(r)["id"], claims.TenantID).First(&req).Error; err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	comments, err := h.DSRService.GetComments(req.ID, true)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "db error")
+		return
+	}
+	writeJSON(w, http.StatusOK, comments)
+}
+
+// AddAdminComment adds a staff comment; comments are internal unless
+// isInternal is explicitly false
+func (h *DataRequestHandler) AddAdminComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.FiduciaryClaimsKey).(*claims.FiduciaryClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req models.DSRRequest
+	if err := h.DB.Where("id = ? AND tenant_id = ?", mux.Vars(r)["id"], claims.TenantID).First(&req).Error; err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var body struct {
+		Content    string `json:"content"`
+		IsInternal *bool  `json:"isInternal"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	authorID, _ := uuid.Parse(claims.FiduciaryID)
+	comment := &models.DSRComment{
+		RequestID:  req.ID,
+		AuthorID:   authorID,
+		AuthorType: "staff",
+		Content:    body.Content,
+		IsInternal: body.IsInternal == nil || *body.IsInternal,
+	}
+	if err := h.DSRService.AddComment(comment); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+// ListUserComments returns the comments on the principal's request that are
+// visible to them
+func (h *DataRequestHandler) ListUserComments(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.UserClaimsKey).(*claims.DataPrincipalClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req models.DSRRequest
+	if err := h.DB.Where("id = ? AND user_id = ?", mux.Vars(r)["id"], claims.PrincipalID).First(&req).Error; err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	comments, err := h.DSRService.GetComments(req.ID, false)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "db error")
+		return
+	}
+	writeJSON(w, http.StatusOK, comments)
+}
+
+// AddUserComment adds a comment from the principal, visible to both sides
+func (h *DataRequestHandler) AddUserComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(contextkeys.UserClaimsKey).(*claims.DataPrincipalClaims)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req models.DSRRequest
+	if err := h.DB.Where("id = ? AND user_id = ?", mux.Vars(r)["id"], claims.PrincipalID).First(&req).Error; err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	comment := &models.DSRComment{
+		RequestID:  req.ID,
+		AuthorID:   req.UserID,
+		AuthorType: "principal",
+		Content:    body.Content,
+		IsInternal: false,
+	}
+	if err := h.DSRService.AddComment(comment); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, comment)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DSRRoutingHandler manages the tenant's DSR routing rules.
+type DSRRoutingHandler struct {
+	service *services.DSRQueueService
+}
+
+func NewDSRRoutingHandler(service *services.DSRQueueService) *DSRRoutingHandler {
+	return &DSRRoutingHandler{service: service}
+}
+
+func (h *DSRRoutingHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	rules, err := h.service.ListRules(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list routing rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *DSRRoutingHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	var rule models.DSRRoutingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	rule.TenantID = tenantID
+	rule.LastAssignedUserID = nil
+
+	if err := h.service.CreateRule(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (h *DSRRoutingHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rule ID")
+		return
+	}
+
+	var rule models.DSRRoutingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	rule.ID = ruleID
+	rule.TenantID = tenantID
+
+	if err := h.service.UpdateRule(&rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *DSRRoutingHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	ruleID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid rule ID")
+		return
+	}
+
+	if err := h.service.DeleteRule(ruleID, tenantID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete routing rule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
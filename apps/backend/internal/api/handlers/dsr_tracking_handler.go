@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// DSRTrackingHandler serves the public, token-guarded DSR status page and the
+// fiduciary side of information requests.
+type DSRTrackingHandler struct {
+	service      *services.DSRTrackingService
+	auditService *services.AuditService
+}
+
+func NewDSRTrackingHandler(service *services.DSRTrackingService, auditService *services.AuditService) *DSRTrackingHandler {
+	return &DSRTrackingHandler{service: service, auditService: auditService}
+}
+
+// requestFromToken resolves the status token passed as ?token= and writes an
+// error response if it is invalid.
+func (h *DSRTrackingHandler) requestFromToken(w http.ResponseWriter, r *http.Request) *models.DSRRequest {
+	req, err := h.service.ResolveToken(r.URL.Query().Get("token"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid or expired status link")
+		return nil
+	}
+	return req
+}
+
+// ==== Public ====
+
+// GetStatus returns the status and public timeline of a request
+func (h *DSRTrackingHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	req := h.requestFromToken(w, r)
+	if req == nil {
+		return
+	}
+	status, err := h.service.PublicStatus(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load request status")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// SubmitClarification accepts a message and optional file from the requester
+func (h *DSRTrackingHandler) SubmitClarification(w http.ResponseWriter, r *http.Request) {
+	req := h.requestFromToken(w, r)
+	if req == nil {
+		return
+	}
+	if err := r.ParseMultipartForm(12 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data")
+		return
+	}
+
+	var (
+		data                  []byte
+		fileName, contentType string
+	)
+	if file, header, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		data, err = io.ReadAll(file)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "failed to read file")
+			return
+		}
+		fileName = header.Filename
+		contentType = header.Header.Get("Content-Type")
+	}
+
+	c, err := h.service.AddClarification(req, r.FormValue("message"), fileName, contentType, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), req.UserID, req.TenantID, uuid.Nil, "dsr_clarification_received", "received", "data_principal", getClientIP(r), "", "", map[string]interface{}{
+		"request_id":       req.ID.String(),
+		"clarification_id": c.ID.String(),
+		"sha256":           c.SHA256,
+	})
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// DownloadResult returns the result package of a closed request as a zip
+func (h *DSRTrackingHandler) DownloadResult(w http.ResponseWriter, r *http.Request) {
+	req := h.requestFromToken(w, r)
+	if req == nil {
+		return
+	}
+	pkg, err := h.service.ResultPackage(req)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), req.UserID, req.TenantID, uuid.Nil, "dsr_result_downloaded", "downloaded", "data_principal", getClientIP(r), "", "", map[string]interface{}{
+		"request_id": req.ID.String(),
+	})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+req.TrackingRef+".zip\"")
+	w.Header().Set("Content-Length", strconv.Itoa(len(pkg)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pkg)
+}
+
+// ResendStatusLink emails a new status link when the reference and email match.
+// The response is the same either way so references cannot be probed.
+func (h *DSRTrackingHandler) ResendStatusLink(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		TrackingRef string `json:"trackingRef"`
+		Email       string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.TrackingRef == "" || body.Email == "" {
+		writeError(w, http.StatusBadRequest, "trackingRef and email are required")
+		return
+	}
+	h.service.ResendLink(body.TrackingRef, body.Email)
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "If the details match a request, a status link has been emailed."})
+}
+
+// ==== Fiduciary ====
+
+// RequestInfo asks the requester for more information and pauses the request
+func (h *DSRTrackingHandler) RequestInfo(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	requestID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+	var body struct {
+		Question string `json:"question"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	actorID, _ := uuid.Parse(claims.FiduciaryID)
+	req, err := h.service.RequestInfo(requestID, tenantID, actorID, body.Question)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), req.UserID, tenantID, uuid.Nil, "dsr_info_requested", req.Status, claims.FiduciaryID, getClientIP(r), "", "", map[string]interface{}{
+		"request_id": req.ID.String(),
+	})
+	writeJSON(w, http.StatusOK, req)
+}
+
+// GetTimeline returns every timeline event, including internal ones
+func (h *DSRTrackingHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	requestID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	events, err := h.service.ListEvents(requestID, tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "db error")
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (h *DSRTrackingHandler) ListClarifications(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	requestID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	items, err := h.service.ListClarifications(requestID, tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "db error")
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (h *DSRTrackingHandler) DownloadClarificationFile(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vars := mux.Vars(r)
+	requestID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request ID")
+		return
+	}
+	clarificationID, err := uuid.Parse(vars["clarificationId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid clarification ID")
+		return
+	}
+
+	tenantID, _ := uuid.Parse(claims.TenantID)
+	c, data, err := h.service.GetClarificationFile(requestID, tenantID, clarificationID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	contentType := c.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+c.FileName+"\"")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 
 	"pixpivot/arc/internal/dto"
@@ -16,20 +17,26 @@ import (
 	"pixpivot/arc/internal/realtime"
 	"pixpivot/arc/internal/storage/repository"
 	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/pkg/log"
 )
 
 type GrievanceHandler struct {
 	notificationService *services.NotificationService
 	hub                 *realtime.Hub
 	auditService        *services.AuditService
+	emailService        *services.EmailService
+	replyTo             string // Inbound mail address, so principals' replies thread back
+	inboundSecret       string // Signs the tenant's plus-address on replyTo
 }
 
 func NewGrievanceHandler(
 	notificationService *services.NotificationService,
 	hub *realtime.Hub,
 	auditService *services.AuditService,
+	emailService *services.EmailService,
+	replyTo, inboundSecret string,
 ) *GrievanceHandler {
-	return &GrievanceHandler{notificationService: notificationService, hub: hub, auditService: auditService}
+	return &GrievanceHandler{notificationService: notificationService, hub: hub, auditService: auditService, emailService: emailService, replyTo: replyTo, inboundSecret: inboundSecret}
 }
 
 // ===== Helper functions to get per-request service =====
@@ -163,17 +170,36 @@ func (h *GrievanceHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	id := r.Context().Value("grievanceID").(string)
+	id := grievanceIDFromRequest(r)
 
 	var req dto.UpdateGrievanceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.AssignedTo != "" {
+		// Reassigning counts as a triage override so assignment rules can be tuned
+		if reviewerID, err := uuid.Parse(middleware.GetFiduciaryID(r.Context())); err == nil {
+			if _, err := svc.ReviewTriage(r.Context(), id, reviewerID, dto.GrievanceTriageReviewRequest{AssignedTo: req.AssignedTo}); err != nil {
+				log.Logger.Error().Err(err).Str("grievance_id", id).Msg("failed to record triage override")
+			}
+		}
+	}
 	if err := svc.Resolve(r.Context(), id, req); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	// On closure, tell the principal they may take the matter to the Data Protection Board
+	g, err := svc.IssueDPBNotice(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if g != nil {
+		go h.auditService.Create(context.Background(), g.UserID, g.TenantID, uuid.Nil, "grievance_dpb_notice_sent", g.Status, "system", r.RemoteAddr, "", "", map[string]interface{}{"grievance_id": g.ID})
+		go h.notify(context.Background(), g.UserID, "Grievance closed", services.DPBNoticeText)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -231,6 +257,9 @@ func (h *GrievanceHandler) AddComment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if comment.AdminId != nil {
+		go h.emailReply(context.Background(), svc, comment)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(comment)
 }
@@ -267,6 +296,341 @@ func (h *GrievanceHandler) DeleteComment(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ================== ESCALATIONS ==================
+func (h *GrievanceHandler) ListEscalations(w http.ResponseWriter, r *http.Request) {
+	svc, _, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	escalations, err := svc.ListEscalations(r.Context(), grievanceIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"escalations": escalations})
+}
+
+// ReportDPBComplaint lets the principal record that they have taken the grievance to the Board
+func (h *GrievanceHandler) ReportDPBComplaint(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req struct {
+		Reference string `json:"reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID := middleware.GetDataPrincipalID(r.Context())
+	e, err := svc.RecordDPBComplaint(r.Context(), grievanceIDFromRequest(r), userID, req.Reference)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go h.auditService.Create(context.Background(), uuid.MustParse(userID), uuid.MustParse(tenantID), uuid.Nil, "grievance_dpb_complaint", "", userID, r.RemoteAddr, "", "", map[string]interface{}{"grievance_id": e.GrievanceID, "reference": req.Reference})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// ================== SLA POLICIES ==================
+func (h *GrievanceHandler) ListSLAPolicies(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	policies, err := svc.ListSLAPolicies(r.Context(), uuid.MustParse(tenantID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"policies": policies})
+}
+
+func (h *GrievanceHandler) CreateSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var p models.GrievanceSLAPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	p.TenantID = uuid.MustParse(tenantID)
+	if err := svc.CreateSLAPolicy(r.Context(), &p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func (h *GrievanceHandler) UpdateSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	policyID, err := uuid.Parse(mux.Vars(r)["policyId"])
+	if err != nil {
+		http.Error(w, "invalid policy id", http.StatusBadRequest)
+		return
+	}
+	var p models.GrievanceSLAPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	p.ID = policyID
+	p.TenantID = uuid.MustParse(tenantID)
+	if err := svc.UpdateSLAPolicy(r.Context(), &p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func (h *GrievanceHandler) DeleteSLAPolicy(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	policyID, err := uuid.Parse(mux.Vars(r)["policyId"])
+	if err != nil {
+		http.Error(w, "invalid policy id", http.StatusBadRequest)
+		return
+	}
+	if err := svc.DeleteSLAPolicy(r.Context(), policyID, uuid.MustParse(tenantID)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ================== TRIAGE ==================
+func (h *GrievanceHandler) GetTriage(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g, err := svc.GetByID(r.Context(), grievanceIDFromRequest(r))
+	if err != nil || g.TenantID.String() != tenantID {
+		http.Error(w, "grievance not found", http.StatusNotFound)
+		return
+	}
+	decision, overrides, err := svc.GetTriage(r.Context(), g)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"decision": decision, "overrides": overrides})
+}
+
+// ReviewTriage lets a reviewer correct the triaged category, priority or
+// assignee; each change is kept as an override for rule tuning.
+func (h *GrievanceHandler) ReviewTriage(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	reviewerID, err := uuid.Parse(middleware.GetFiduciaryID(r.Context()))
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := grievanceIDFromRequest(r)
+	if g, err := svc.GetByID(r.Context(), id); err != nil || g.TenantID.String() != tenantID {
+		http.Error(w, "grievance not found", http.StatusNotFound)
+		return
+	}
+	var req dto.GrievanceTriageReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	overrides, err := svc.ReviewTriage(r.Context(), id, reviewerID, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(overrides) > 0 {
+		go h.auditService.Create(context.Background(), reviewerID, uuid.MustParse(tenantID), uuid.Nil, "grievance_triage_overridden", "", "fiduciary", r.RemoteAddr, "", "", map[string]interface{}{"grievance_id": id, "overrides": overrides})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"overrides": overrides})
+}
+
+func (h *GrievanceHandler) ListTriageRules(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rules, err := svc.ListTriageRules(r.Context(), uuid.MustParse(tenantID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"rules": rules})
+}
+
+func (h *GrievanceHandler) CreateTriageRule(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var rule models.GrievanceTriageRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.TenantID = uuid.MustParse(tenantID)
+	if err := svc.CreateTriageRule(r.Context(), &rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+func (h *GrievanceHandler) UpdateTriageRule(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ruleID, err := uuid.Parse(mux.Vars(r)["ruleId"])
+	if err != nil {
+		http.Error(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+	var rule models.GrievanceTriageRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	rule.ID = ruleID
+	rule.TenantID = uuid.MustParse(tenantID)
+	if err := svc.UpdateTriageRule(r.Context(), &rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rule)
+}
+
+func (h *GrievanceHandler) DeleteTriageRule(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ruleID, err := uuid.Parse(mux.Vars(r)["ruleId"])
+	if err != nil {
+		http.Error(w, "invalid rule id", http.StatusBadRequest)
+		return
+	}
+	if err := svc.DeleteTriageRule(r.Context(), ruleID, uuid.MustParse(tenantID)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestTriageRules dry-runs the tenant's active rules against sample text
+func (h *GrievanceHandler) TestTriageRules(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req struct {
+		Subject     string `json:"subject"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	res, err := svc.PreviewTriage(r.Context(), uuid.MustParse(tenantID), req.Subject, req.Description)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// TriageStats shows how often each rule's output was overridden
+func (h *GrievanceHandler) TriageStats(w http.ResponseWriter, r *http.Request) {
+	svc, tenantID, err := h.perAdminRequestSvc(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats, err := svc.TriageStats(r.Context(), uuid.MustParse(tenantID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"stats": stats})
+}
+
+// grievanceIDFromRequest reads the grievance ID from the request context, falling
+// back to the {id} route variable.
+func grievanceIDFromRequest(r *http.Request) string {
+	if id, ok := r.Context().Value("grievanceID").(string); ok && id != "" {
+		return id
+	}
+	return mux.Vars(r)["id"]
+}
+
+// ================== OUTBOUND EMAIL ==================
+
+// emailReply sends a fiduciary's reply to the principal. The subject carries the
+// grievance's thread token so an emailed answer is filed on the same grievance.
+func (h *GrievanceHandler) emailReply(ctx context.Context, svc *services.GrievanceService, comment *models.GrievanceComment) {
+	if h.emailService == nil {
+		return
+	}
+	g, err := svc.GetByID(ctx, comment.GrievanceID.String())
+	if err != nil {
+		return
+	}
+	to, err := svc.PrincipalEmail(ctx, g)
+	if err != nil || to == "" {
+		return
+	}
+	subject := services.ThreadSubject(g, "Re: "+g.GrievanceSubject)
+	body := comment.Comment + "<br><br>You can reply to this email to respond. Please keep the reference in the subject line."
+	replyTo := services.TenantInboundAddress(h.replyTo, h.inboundSecret, g.TenantID)
+	if err := h.emailService.SendReply(to, replyTo, subject, body); err != nil {
+		log.Logger.Error().Err(err).Str("grievance_id", g.ID.String()).Msg("failed to email grievance reply")
+	}
+}
+
 // ================== NOTIFY ==================
 func (h *GrievanceHandler) notify(ctx context.Context, user uuid.UUID, title, body string) {
 	n := models.Notification{
@@ -5,6 +5,10 @@ import (
 	"log"
 	"net/http"
 	"strings"
+
+	"pixpivot/arc/internal/api/middleware"
+
+	"github.com/google/uuid"
 )
 
 // writeJSON writes a JSON response with the given status code and data
@@ -55,6 +59,18 @@ func writeValidationError(w http.ResponseWriter, errors map[string]string) {
 }
 */
 
+// fiduciaryCaller returns the tenant and user of the authenticated fiduciary
+// user, with ok false when the request carries no valid fiduciary claims
+func fiduciaryCaller(r *http.Request) (tenantID, userID uuid.UUID, ok bool) {
+	c := middleware.GetFiduciaryAuthClaims(r.Context())
+	if c == nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	tenantID, err1 := uuid.Parse(c.TenantID)
+	userID, err2 := uuid.Parse(c.FiduciaryID)
+	return tenantID, userID, err1 == nil && err2 == nil
+}
+
 // getClientIP extracts the client IP address from the request
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header
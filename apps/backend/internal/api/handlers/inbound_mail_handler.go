@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+)
+
+const maxInboundMessageSize = 30 << 20
+
+// InboundMailHandler receives raw messages from the local MTA and exposes the
+// inbound mail log to fiduciary staff.
+type InboundMailHandler struct {
+	service *services.InboundMailService
+	secret  string
+}
+
+func NewInboundMailHandler(service *services.InboundMailService, secret string) *InboundMailHandler {
+	return &InboundMailHandler{service: service, secret: secret}
+}
+
+// Receive accepts one raw RFC 5322 message as the request body. The MTA pipe
+// authenticates with the X-Inbound-Secret header.
+func (h *InboundMailHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "inbound mail is not configured")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Inbound-Secret")), []byte(h.secret)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboundMessageSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "message too large")
+		return
+	}
+
+	record, err := h.service.Ingest(r.Context(), raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, record)
+}
+
+// List returns the tenant's inbound mail log
+func (h *InboundMailHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetFiduciaryAuthClaims(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(claims.TenantID)
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 25
+	}
+
+	items, total, err := h.service.ListInbound(tenantID, r.URL.Query().Get("outcome"), limit, (page-1)*limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list inbound email")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"emails": items,
+	})
+}
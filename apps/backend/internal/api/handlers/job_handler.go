@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// JobHandler is the superadmin view of the background job queue
+type JobHandler struct {
+	runner       *services.JobRunner
+	auditService *services.AuditService
+}
+
+func NewJobHandler(runner *services.JobRunner, auditService *services.AuditService) *JobHandler {
+	return &JobHandler{runner: runner, auditService: auditService}
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrJobNotRetryable), errors.Is(err, services.ErrJobFinished):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("job request failed")
+		writeError(w, http.StatusInternalServerError, "job request failed")
+	}
+}
+
+// ListJobs lists jobs, newest first, filtered by status, type and tenant
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.JobFilter{Status: q.Get("status"), Type: q.Get("type")}
+	if v := q.Get("tenantId"); v != "" {
+		tenantID, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid tenant ID")
+			return
+		}
+		filter.TenantID = &tenantID
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit > 0 {
+			filter.Limit = limit
+		}
+	}
+	jobs, err := h.runner.List(filter)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// GetStatus reports leadership, job types and cron schedules
+func (h *JobHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.runner.Status()
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job ID")
+		return
+	}
+	job, err := h.runner.Get(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// RetryJob requeues a failed or cancelled job
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, "job_retried", h.runner.Retry)
+}
+
+// CancelJob stops a pending or running job
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.act(w, r, "job_cancelled", h.runner.Cancel)
+}
+
+func (h *JobHandler) act(w http.ResponseWriter, r *http.Request, event string, action func(uuid.UUID) (*models.Job, error)) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job ID")
+		return
+	}
+	job, err := action(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	var userID uuid.UUID
+	if c := middleware.GetFiduciaryAuthClaims(r.Context()); c != nil {
+		userID, _ = uuid.Parse(c.FiduciaryID)
+	}
+	tenantID := uuid.Nil
+	if job.TenantID != nil {
+		tenantID = *job.TenantID
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, event, "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+	})
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/claims"
+	"pixpivot/arc/internal/contextkeys"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/pkg/response"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ActingForHeader names the principal a nominee is acting for on user endpoints.
+const ActingForHeader = "X-Acting-For-Principal"
+
+type NomineeHandler struct {
+	service      *services.NomineeService
+	auditService *services.AuditService
+}
+
+func NewNomineeHandler(service *services.NomineeService, auditService *services.AuditService) *NomineeHandler {
+	return &NomineeHandler{service: service, auditService: auditService}
+}
+
+// principalFromClaims extracts the caller's principal and tenant IDs.
+func principalFromClaims(w http.ResponseWriter, r *http.Request) (*claims.DataPrincipalClaims, uuid.UUID, uuid.UUID, bool) {
+	c := middleware.GetClaimsFromContext(r.Context())
+	if c == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+	principalID, err := uuid.Parse(c.PrincipalID)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "Invalid user ID in token")
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(c.TenantID)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "Invalid tenant ID in token")
+		return nil, uuid.Nil, uuid.Nil, false
+	}
+	return c, principalID, tenantID, true
+}
+
+// ==== Principal: manage own nominees ====
+
+func (h *NomineeHandler) AddNominee(w http.ResponseWriter, r *http.Request) {
+	_, principalID, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name         string `json:"name"`
+		Email        string `json:"email"`
+		Phone        string `json:"phone"`
+		Relationship string `json:"relationship"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	nominee, err := h.service.AddNominee(principalID, tenantID, req.Name, req.Email, req.Phone, req.Relationship)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), principalID, tenantID, uuid.Nil, "nominee_registered", "pending_verification", "data_principal", getClientIP(r), "", "", map[string]interface{}{
+		"nomineeId":    nominee.ID.String(),
+		"relationship": nominee.Relationship,
+	})
+
+	response.JSON(w, http.StatusCreated, nominee)
+}
+
+func (h *NomineeHandler) ListNominees(w http.ResponseWriter, r *http.Request) {
+	_, principalID, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	nominees, err := h.service.ListNominees(principalID, tenantID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, nominees)
+}
+
+func (h *NomineeHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	_, principalID, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+	nomineeID, err := uuid.Parse(mux.Vars(r)["nomineeId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid nominee ID")
+		return
+	}
+
+	if err := h.service.ResendVerification(nomineeID, principalID, tenantID); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"status": "verification_sent"})
+}
+
+func (h *NomineeHandler) RevokeNominee(w http.ResponseWriter, r *http.Request) {
+	_, principalID, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+	nomineeID, err := uuid.Parse(mux.Vars(r)["nomineeId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid nominee ID")
+		return
+	}
+
+	if err := h.service.RevokeNominee(nomineeID, principalID, tenantID); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), principalID, tenantID, uuid.Nil, "nominee_revoked", "revoked", "data_principal", getClientIP(r), "", "", map[string]interface{}{
+		"nomineeId": nomineeID.String(),
+	})
+
+	response.JSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// VerifyNominee is the public endpoint hit from the emailed verification link.
+func (h *NomineeHandler) VerifyNominee(w http.ResponseWriter, r *http.Request) {
+	nominee, err := h.service.VerifyNominee(r.URL.Query().Get("token"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), nominee.PrincipalID, nominee.TenantID, uuid.Nil, "nominee_verified", "verified", "nominee", getClientIP(r), "", "", map[string]interface{}{
+		"nomineeId": nominee.ID.String(),
+	})
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Nomination confirmed."})
+}
+
+// ==== Nominee: claims ====
+
+func (h *NomineeHandler) ListNominations(w http.ResponseWriter, r *http.Request) {
+	c, _, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	nominations, err := h.service.ListNominations(c.Email, tenantID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, nominations)
+}
+
+func (h *NomineeHandler) SubmitClaim(w http.ResponseWriter, r *http.Request) {
+	c, callerID, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		NomineeID string `json:"nominee_id"`
+		ClaimType string `json:"claim_type"` // death, incapacity
+		Statement string `json:"statement"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	nomineeID, err := uuid.Parse(req.NomineeID)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid nominee ID")
+		return
+	}
+
+	claim, err := h.service.SubmitClaim(nomineeID, tenantID, c.Email, req.ClaimType, req.Statement)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), claim.PrincipalID, tenantID, uuid.Nil, "nominee_claim_submitted", "submitted", "nominee", getClientIP(r), "", "", map[string]interface{}{
+		"nomineeId":        nomineeID.String(),
+		"claimId":          claim.ID.String(),
+		"claimType":        claim.ClaimType,
+		"nomineeAccountId": callerID.String(),
+	})
+
+	response.JSON(w, http.StatusCreated, claim)
+}
+
+func (h *NomineeHandler) UploadClaimEvidence(w http.ResponseWriter, r *http.Request) {
+	c, _, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+	claimID, err := uuid.Parse(mux.Vars(r)["claimId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid claim ID")
+		return
+	}
+
+	if err := r.ParseMultipartForm(25 << 20); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "File not provided")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+
+	evidence, err := h.service.AddClaimEvidence(claimID, tenantID, c.Email, r.FormValue("documentType"), header.Filename, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusCreated, evidence)
+}
+
+func (h *NomineeHandler) ListMyClaims(w http.ResponseWriter, r *http.Request) {
+	c, _, tenantID, ok := principalFromClaims(w, r)
+	if !ok {
+		return
+	}
+
+	claims, err := h.service.ListClaimsForNominee(c.Email, tenantID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, claims)
+}
+
+// ==== Fiduciary: review claims ====
+
+func (h *NomineeHandler) ListClaims(w http.ResponseWriter, r *http.Request) {
+	fc := middleware.GetFiduciaryAuthClaims(r.Context())
+	if fc == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(fc.TenantID)
+
+	claims, err := h.service.ListClaims(tenantID, r.URL.Query().Get("status"))
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, claims)
+}
+
+func (h *NomineeHandler) GetClaim(w http.ResponseWriter, r *http.Request) {
+	fc := middleware.GetFiduciaryAuthClaims(r.Context())
+	if fc == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(fc.TenantID)
+	claimID, err := uuid.Parse(mux.Vars(r)["claimId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid claim ID")
+		return
+	}
+
+	claim, err := h.service.GetClaim(claimID, tenantID)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Claim not found")
+		return
+	}
+	response.JSON(w, http.StatusOK, claim)
+}
+
+// DownloadClaimEvidence streams a claim's supporting document to a reviewer
+func (h *NomineeHandler) DownloadClaimEvidence(w http.ResponseWriter, r *http.Request) {
+	fc := middleware.GetFiduciaryAuthClaims(r.Context())
+	if fc == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(fc.TenantID)
+	claimID, err := uuid.Parse(mux.Vars(r)["claimId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid claim ID")
+		return
+	}
+	evidenceID, err := uuid.Parse(mux.Vars(r)["evidenceId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid evidence ID")
+		return
+	}
+
+	e, data, err := h.service.GetClaimEvidence(claimID, evidenceID, tenantID)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Evidence not found")
+		return
+	}
+
+	go h.auditService.Create(context.Background(), uuid.Nil, tenantID, uuid.Nil, "nominee_claim_evidence_downloaded", "success", fc.FiduciaryID, getClientIP(r), "", "", map[string]interface{}{
+		"claimId":    claimID.String(),
+		"evidenceId": e.ID.String(),
+	})
+
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.FileName))
+	w.Header().Set("X-Content-SHA256", e.SHA256)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (h *NomineeHandler) ApproveClaim(w http.ResponseWriter, r *http.Request) {
+	h.reviewClaim(w, r, true)
+}
+
+func (h *NomineeHandler) RejectClaim(w http.ResponseWriter, r *http.Request) {
+	h.reviewClaim(w, r, false)
+}
+
+func (h *NomineeHandler) reviewClaim(w http.ResponseWriter, r *http.Request, approve bool) {
+	fc := middleware.GetFiduciaryAuthClaims(r.Context())
+	if fc == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(fc.TenantID)
+	reviewerID, _ := uuid.Parse(fc.FiduciaryID)
+	claimID, err := uuid.Parse(mux.Vars(r)["claimId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid claim ID")
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	claim, err := h.service.ReviewClaim(claimID, tenantID, reviewerID, approve, req.Note)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), claim.PrincipalID, tenantID, uuid.Nil, "nominee_claim_reviewed", claim.Status, reviewerID.String(), getClientIP(r), "", "", map[string]interface{}{
+		"claimId":   claim.ID.String(),
+		"nomineeId": claim.NomineeID.String(),
+		"note":      req.Note,
+	})
+
+	response.JSON(w, http.StatusOK, claim)
+}
+
+func (h *NomineeHandler) RevokeClaim(w http.ResponseWriter, r *http.Request) {
+	fc := middleware.GetFiduciaryAuthClaims(r.Context())
+	if fc == nil {
+		response.Error(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	tenantID, _ := uuid.Parse(fc.TenantID)
+	reviewerID, _ := uuid.Parse(fc.FiduciaryID)
+	claimID, err := uuid.Parse(mux.Vars(r)["claimId"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid claim ID")
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.service.RevokeClaim(claimID, tenantID, reviewerID, req.Note); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	go h.auditService.Create(context.Background(), uuid.Nil, tenantID, uuid.Nil, "nominee_claim_revoked", "revoked", reviewerID.String(), getClientIP(r), "", "", map[string]interface{}{
+		"claimId": claimID.String(),
+		"note":    req.Note,
+	})
+
+	response.JSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// ==== Acting authority ====
+
+// ActingAuthority lets an approved nominee call data principal endpoints on behalf
+// of the principal named in the X-Acting-For-Principal header. The request's claims
+// are rewritten to the principal and the approved claim is stored in the context so
+// downstream handlers can record who actually acted. Requests without the header
+// pass through untouched.
+func (h *NomineeHandler) ActingAuthority(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actingFor := r.Header.Get(ActingForHeader)
+		if actingFor == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c := middleware.GetClaimsFromContext(r.Context())
+		if c == nil {
+			response.Error(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		principalID, err := uuid.Parse(actingFor)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid principal ID in "+ActingForHeader)
+			return
+		}
+		tenantID, err := uuid.Parse(c.TenantID)
+		if err != nil {
+			response.Error(w, http.StatusUnauthorized, "Invalid tenant ID in token")
+			return
+		}
+
+		claim, err := h.service.ResolveAuthority(c.Email, principalID, tenantID)
+		if err != nil {
+			go h.auditService.Create(context.Background(), principalID, tenantID, uuid.Nil, "nominee_authority_denied", "denied", "nominee", getClientIP(r), "", "", map[string]interface{}{
+				"nomineeAccountId": c.PrincipalID,
+				"method":           r.Method,
+				"path":             r.URL.Path,
+			})
+			response.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		acting := *c
+		acting.PrincipalID = principalID.String()
+		acting.Email = ""
+		acting.Phone = ""
+
+		go h.auditService.Create(context.Background(), principalID, tenantID, uuid.Nil, "nominee_authority_exercised", claim.ClaimType, "nominee", getClientIP(r), "", "", map[string]interface{}{
+			"nomineeId":        claim.NomineeID.String(),
+			"claimId":          claim.ID.String(),
+			"nomineeAccountId": c.PrincipalID,
+			"method":           r.Method,
+			"path":             r.URL.Path,
+		})
+
+		ctx := context.WithValue(r.Context(), contextkeys.UserClaimsKey, &acting)
+		ctx = context.WithValue(ctx, contextkeys.NomineeClaimKey, claim)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// nomineeClaimFromContext returns the approved claim when a nominee is acting, or nil.
+func nomineeClaimFromContext(ctx context.Context) *models.NomineeClaim {
+	claim, _ := ctx.Value(contextkeys.NomineeClaimKey).(*models.NomineeClaim)
+	return claim
+}
@@ -110,6 +110,9 @@ func (h *PublicAPIHandler) CreateDSR(w http.ResponseWriter, r *http.Request) {
 		UserID string `json:"userId"`
 		Type   string `json:"type"` // e.g., "Data Deletion", "Data Portability"
 		Note   string `json:"note,omitempty"`
+		Email  string `json:"email,omitempty"` // Status updates go here; defaults to the principal's email
+		// ConsentFormID is the form the request was raised from; it scopes routing
+		ConsentFormID string `json:"consentFormId,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
@@ -122,14 +125,6 @@ func (h *PublicAPIHandler) CreateDSR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get tenant-specific DB connection
-	schema := "tenant_" + apiKeyClaims.TenantID[:8]
-	tenantDB, err := db.GetTenantDB(schema)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Tenant database not found")
-		return
-	}
-
 	dsrRequest := models.DSRRequest{
 		ID:             uuid.New(),
 		UserID:         userID,
@@ -138,9 +133,24 @@ func (h *PublicAPIHandler) CreateDSR(w http.ResponseWriter, r *http.Request) {
 		Status:         "Pending",
 		RequestedAt:    time.Now(),
 		ResolutionNote: req.Note,
+		RequesterEmail: req.Email,
 	}
 
-	if err := tenantDB.Create(&dsrRequest).Error; err != nil {
+	if req.ConsentFormID != "" {
+		formID, err := uuid.Parse(req.ConsentFormID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid consent form ID")
+			return
+		}
+		if err := h.DSRService.ScopeToConsentForm(&dsrRequest, formID); err != nil {
+			writeError(w, http.StatusBadRequest, "Consent form not found")
+			return
+		}
+	}
+
+	// Created through the DSR service so the request is routed, tracked and
+	// visible in the fiduciary queue
+	if err := h.DSRService.CreateRequest(&dsrRequest); err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to create DSR request")
 		return
 	}
@@ -151,7 +161,17 @@ func (h *PublicAPIHandler) CreateDSR(w http.ResponseWriter, r *http.Request) {
 		"dsrType": dsrRequest.Type,
 	})
 
-	writeJSON(w, http.StatusCreated, dsrRequest)
+	resp := map[string]interface{}{
+		"id":          dsrRequest.ID,
+		"trackingRef": dsrRequest.TrackingRef,
+		"type":        dsrRequest.Type,
+		"status":      dsrRequest.Status,
+		"dueDate":     dsrRequest.DueDate,
+	}
+	if link, err := h.DSRService.StatusLink(&dsrRequest); err == nil {
+		resp["statusUrl"] = link
+	}
+	writeJSON(w, http.StatusCreated, resp)
 }
 
 // VerifyConsentsRequest is the request body for checking required consents.
@@ -5,6 +5,8 @@ import (
 	"pixpivot/arc/internal/contextkeys"
 	"pixpivot/arc/internal/dto"
 	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/pkg/log"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -17,10 +19,18 @@ type PublicConsentHandler struct {
 	userConsentService *services.UserConsentService
 	consentFormService *services.ConsentFormService
 	webhookService     *services.WebhookService
+	sdfService         *services.SDFService
 }
 
-func NewPublicConsentHandler(userConsentService *services.UserConsentService, consentFormService *services.ConsentFormService, webhookService *services.WebhookService) *PublicConsentHandler {
-	return &PublicConsentHandler{userConsentService: userConsentService, consentFormService: consentFormService, webhookService: webhookService}
+func NewPublicConsentHandler(userConsentService *services.UserConsentService, consentFormService *services.ConsentFormService, webhookService *services.WebhookService, sdfService *services.SDFService) *PublicConsentHandler {
+	return &PublicConsentHandler{userConsentService: userConsentService, consentFormService: consentFormService, webhookService: webhookService, sdfService: sdfService}
+}
+
+// consentNotice is a consent form as shown to Data Principals, with the
+// contact details of the fiduciary's Data Protection Officer
+type consentNotice struct {
+	*models.ConsentForm
+	DataProtectionOfficer *services.DPOContact `json:"dataProtectionOfficer,omitempty"`
 }
 
 func (h *PublicConsentHandler) GetConsentForm(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +47,13 @@ func (h *PublicConsentHandler) GetConsentForm(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	writeJSON(w, http.StatusOK, form)
+	// The notice is still served if the DPO cannot be looked up
+	dpo, err := h.sdfService.PublicDPO(form.TenantID)
+	if err != nil {
+		log.Logger.Error().Err(err).Str("tenant_id", form.TenantID.String()).Msg("failed to load DPO contact for consent notice")
+	}
+
+	writeJSON(w, http.StatusOK, consentNotice{ConsentForm: form, DataProtectionOfficer: dpo})
 }
 
 func (h *PublicConsentHandler) SubmitConsent(w http.ResponseWriter, r *http.Request) {
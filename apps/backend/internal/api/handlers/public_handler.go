@@ -10,6 +10,7 @@ import (
 	"pixpivot/arc/internal/core/services"
 	"pixpivot/arc/internal/dto"
 	"pixpivot/arc/internal/models"
+	"pixpivot/arc/pkg/log"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,6 +22,7 @@ type PublicHandler struct {
 	receiptService *services.ReceiptService
 	cookieService  *services.CookieService
 	auditService   *services.AuditService
+	sdfService     *services.SDFService
 }
 
 // NewPublicHandler creates a new public handler
@@ -28,11 +30,13 @@ func NewPublicHandler(
 	consentService *services.UserConsentService,
 	receiptService *services.ReceiptService,
 	cookieService *services.CookieService,
+	sdfService *services.SDFService,
 ) *PublicHandler {
 	return &PublicHandler{
 		consentService: consentService,
 		receiptService: receiptService,
 		cookieService:  cookieService,
+		sdfService:     sdfService,
 	}
 }
 
@@ -306,6 +310,16 @@ func (h *PublicHandler) GetPrivacyPolicy(w http.ResponseWriter, r *http.Request)
 		"version":      "1.0",
 	}
 
+	// Data Principals are told whom to contact about their data; the policy
+	// is still served if the DPO cannot be looked up
+	dpo, err := h.sdfService.PublicDPO(tenantID)
+	if err != nil {
+		log.Logger.Error().Err(err).Str("tenant_id", tenantID.String()).Msg("failed to load DPO contact for privacy policy")
+	}
+	if dpo != nil {
+		policy["data_protection_officer"] = dpo
+	}
+
 	writeJSON(w, http.StatusOK, policy)
 }
 
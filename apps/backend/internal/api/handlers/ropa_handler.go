@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// RopaHandler serves each organization entity's Record of Processing
+// Activities: the live register, hand-filled fields, versions, sign-off and
+// export
+type RopaHandler struct {
+	service      *services.RopaService
+	auditService *services.AuditService
+}
+
+func NewRopaHandler(service *services.RopaService, auditService *services.AuditService) *RopaHandler {
+	return &RopaHandler{service: service, auditService: auditService}
+}
+
+func (h *RopaHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/status", h.Status).Methods("GET")
+	r.HandleFunc("/entities/{entityId}/preview", h.Preview).Methods("GET")
+	r.HandleFunc("/entities/{entityId}/profile", h.GetProfile).Methods("GET")
+	r.HandleFunc("/entities/{entityId}/profile", h.SaveProfile).Methods("PUT")
+	r.HandleFunc("/entities/{entityId}/versions", h.ListVersions).Methods("GET")
+	r.HandleFunc("/entities/{entityId}/versions", h.Generate).Methods("POST")
+	r.HandleFunc("/versions/{id}", h.GetVersion).Methods("GET")
+	r.HandleFunc("/versions/{id}/sign-off", h.SignOff).Methods("POST")
+	r.HandleFunc("/versions/{id}/export", h.Export).Methods("GET")
+}
+
+func writeRopaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRopaEntityNotFound),
+		errors.Is(err, services.ErrRopaVersionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrRopaNotDraft):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidRopaProfile),
+		errors.Is(err, services.ErrUnsupportedRopaFormat):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ropaRequest returns the caller and the ID in the named route variable
+func ropaRequest(w http.ResponseWriter, r *http.Request, idVar string) (tenantID, userID, id uuid.UUID, ok bool) {
+	tenantID, userID, ok = fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)[idVar])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid "+idVar)
+		return tenantID, userID, id, false
+	}
+	return tenantID, userID, id, true
+}
+
+// Status lists every entity with its latest version and staleness warnings
+func (h *RopaHandler) Status(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	status, err := h.service.Status(tenantID, purposes)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// Preview assembles the register from the current sources without saving it
+func (h *RopaHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, entityID, ok := ropaRequest(w, r, "entityId")
+	if !ok {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	reg, err := h.service.Build(tenantID, entityID, purposes)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+func (h *RopaHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, entityID, ok := ropaRequest(w, r, "entityId")
+	if !ok {
+		return
+	}
+	p, err := h.service.Profile(tenantID, entityID)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// SaveProfile replaces the fields filled in by hand, such as the controller
+// contact and security measures
+func (h *RopaHandler) SaveProfile(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, entityID, ok := ropaRequest(w, r, "entityId")
+	if !ok {
+		return
+	}
+	var req services.RopaProfileInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	p, err := h.service.SaveProfile(tenantID, entityID, userID, req)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "ropa_profile_updated", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"organization_entity_id": entityID,
+	})
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *RopaHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, entityID, ok := ropaRequest(w, r, "entityId")
+	if !ok {
+		return
+	}
+	list, err := h.service.Versions(tenantID, entityID)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Generate freezes the current register as a new draft version
+func (h *RopaHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, entityID, ok := ropaRequest(w, r, "entityId")
+	if !ok {
+		return
+	}
+	var req struct {
+		ChangeNote string `json:"changeNote"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	v, err := h.service.Generate(tenantID, entityID, userID, purposes, req.ChangeNote)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "ropa_version_generated", v.Status, "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"organization_entity_id": entityID, "version_id": v.ID, "version": v.Version, "sha256": v.ContentSHA256,
+	})
+	writeJSON(w, http.StatusCreated, v)
+}
+
+// GetVersion returns a version with its register and whether the purposes,
+// vendors or hand-filled fields behind it changed since
+func (h *RopaHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, versionID, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	view, err := h.service.Version(tenantID, versionID, purposes)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *RopaHandler) SignOff(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, versionID, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	v, err := h.service.SignOff(tenantID, versionID, userID, req.Note)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "ropa_version_signed_off", v.Status, "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"organization_entity_id": v.OrganizationEntityID, "version_id": v.ID, "version": v.Version, "note": v.SignOffNote,
+	})
+	writeJSON(w, http.StatusOK, v)
+}
+
+// Export downloads a version with ?format=xlsx|csv|pdf
+func (h *RopaHandler) Export(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, versionID, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	doc, contentType, filename, err := h.service.Export(tenantID, versionID, format)
+	if err != nil {
+		writeRopaError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "ropa_exported", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"version_id": versionID, "format": format,
+	})
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	_, _ = w.Write(doc)
+}
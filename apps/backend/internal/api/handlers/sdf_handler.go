@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SDFHandler serves a Significant Data Fiduciary's designation, obligations
+// calendar, evidence and DPO registry
+type SDFHandler struct {
+	service      *services.SDFService
+	auditService *services.AuditService
+}
+
+func NewSDFHandler(service *services.SDFService, auditService *services.AuditService) *SDFHandler {
+	return &SDFHandler{service: service, auditService: auditService}
+}
+
+func (h *SDFHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("", h.Status).Methods("GET")
+	r.HandleFunc("/designation", h.Designate).Methods("PUT")
+	r.HandleFunc("/calendar", h.Calendar).Methods("GET")
+	r.HandleFunc("/obligations", h.ListObligations).Methods("GET")
+	r.HandleFunc("/obligations", h.CreateObligation).Methods("POST")
+	r.HandleFunc("/obligations/{id}", h.UpdateObligation).Methods("PUT")
+	r.HandleFunc("/obligations/{id}", h.DeleteObligation).Methods("DELETE")
+	r.HandleFunc("/obligations/{id}/complete", h.Complete).Methods("POST")
+	r.HandleFunc("/obligations/{id}/completions", h.ListCompletions).Methods("GET")
+	r.HandleFunc("/obligations/{id}/evidence", h.ListEvidence).Methods("GET")
+	r.HandleFunc("/obligations/{id}/evidence", h.UploadEvidence).Methods("POST")
+	r.HandleFunc("/evidence/{id}/download", h.DownloadEvidence).Methods("GET")
+	r.HandleFunc("/dpo", h.ListDPOs).Methods("GET")
+	r.HandleFunc("/dpo", h.AppointDPO).Methods("POST")
+	r.HandleFunc("/dpo/{id}", h.UpdateDPO).Methods("PUT")
+}
+
+func writeSDFError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTenantNotFound),
+		errors.Is(err, services.ErrSDFObligationNotFound),
+		errors.Is(err, services.ErrSDFEvidenceNotFound),
+		errors.Is(err, services.ErrDPONotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotSDF),
+		errors.Is(err, services.ErrSDFObligationClosed),
+		errors.Is(err, services.ErrSDFObligationHasHistory),
+		errors.Is(err, services.ErrDPOAppointmentEnded):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrSDFEvidenceRequired),
+		errors.Is(err, services.ErrDPONotInIndia):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrInvalidSDFObligation),
+		errors.Is(err, services.ErrInvalidDPO),
+		errors.Is(err, services.ErrInvalidSDFCalendarRange),
+		errors.Is(err, services.ErrUnknownCountry),
+		errors.Is(err, services.ErrEvidenceEmpty):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (h *SDFHandler) audit(r *http.Request, tenantID, userID uuid.UUID, action string, details map[string]interface{}) {
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, action, "", "fiduciary", getClientIP(r), "", "", details)
+}
+
+// Status returns the designation, the current DPO and what needs attention
+func (h *SDFHandler) Status(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	st, err := h.service.Status(tenantID)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// Designate sets or clears the tenant's SDF designation
+func (h *SDFHandler) Designate(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req services.SDFDesignationInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	st, err := h.service.Designate(tenantID, userID, req)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_designation_changed", map[string]interface{}{"designated": st.Designated, "designated_at": st.DesignatedAt})
+	writeJSON(w, http.StatusOK, st)
+}
+
+// Calendar lists due dates between from and to (YYYY-MM-DD), by default the
+// next twelve months
+func (h *SDFHandler) Calendar(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today, today.AddDate(1, 0, 0)
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+name+" date")
+			return
+		}
+		*dst = t
+	}
+	entries, err := h.service.Calendar(tenantID, from, to)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"from": from, "to": to, "entries": entries})
+}
+
+func (h *SDFHandler) ListObligations(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListObligations(tenantID)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *SDFHandler) CreateObligation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req services.SDFObligationInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	o, err := h.service.CreateObligation(tenantID, userID, req)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_obligation_created", map[string]interface{}{"obligation_id": o.ID, "title": o.Title})
+	writeJSON(w, http.StatusCreated, o)
+}
+
+func (h *SDFHandler) UpdateObligation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req services.SDFObligationInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	o, err := h.service.UpdateObligation(tenantID, id, req)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_obligation_updated", map[string]interface{}{"obligation_id": o.ID, "next_due_at": o.NextDueAt})
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (h *SDFHandler) DeleteObligation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteObligation(tenantID, id); err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_obligation_deleted", map[string]interface{}{"obligation_id": id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Complete records that the obligation's current due date was met
+func (h *SDFHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	note, ok := decodeDPIANote(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.service.Complete(tenantID, id, userID, note)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_obligation_completed", map[string]interface{}{"obligation_id": id, "due_at": c.DueAt, "late": c.Late})
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *SDFHandler) ListCompletions(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	list, err := h.service.ListCompletions(tenantID, id)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *SDFHandler) ListEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	list, err := h.service.ListEvidence(tenantID, id)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// UploadEvidence takes a multipart "file" with an optional "note"
+func (h *SDFHandler) UploadEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file not provided")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read file")
+		return
+	}
+	e, err := h.service.UploadEvidence(tenantID, id, userID, services.SDFEvidenceUpload{
+		FileName:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Note:        r.FormValue("note"),
+	}, data)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sdf_evidence_uploaded", map[string]interface{}{"obligation_id": id, "evidence_id": e.ID, "sha256": e.SHA256})
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *SDFHandler) DownloadEvidence(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	e, data, err := h.service.DownloadEvidence(tenantID, id)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", e.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.FileName))
+	w.Header().Set("X-Content-SHA256", e.SHA256)
+	_, _ = w.Write(data)
+}
+
+// ListDPOs returns the DPO registry, the current appointment first
+func (h *SDFHandler) ListDPOs(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListDPOs(tenantID)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// AppointDPO records a new DPO, ending the current appointment
+func (h *SDFHandler) AppointDPO(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req services.DPOInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	d, err := h.service.AppointDPO(tenantID, userID, req)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpo_appointed", map[string]interface{}{"dpo_id": d.ID, "name": d.Name, "country": d.Country})
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// UpdateDPO corrects the current DPO's contact details
+func (h *SDFHandler) UpdateDPO(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, id, ok := ropaRequest(w, r, "id")
+	if !ok {
+		return
+	}
+	var req services.DPOInput
+	if !decodeDPIABody(w, r, &req) {
+		return
+	}
+	d, err := h.service.UpdateDPO(tenantID, id, req)
+	if err != nil {
+		writeSDFError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "dpo_updated", map[string]interface{}{"dpo_id": d.ID})
+	writeJSON(w, http.StatusOK, d)
+}
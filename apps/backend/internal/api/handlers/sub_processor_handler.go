@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pixpivot/arc/internal/claims"
+	"pixpivot/arc/internal/contextkeys"
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/db"
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SubProcessorHandler serves the sub-processor register to fiduciaries,
+// vendors and data principals
+type SubProcessorHandler struct {
+	service      *services.SubProcessorService
+	auditService *services.AuditService
+}
+
+func NewSubProcessorHandler(service *services.SubProcessorService, auditService *services.AuditService) *SubProcessorHandler {
+	return &SubProcessorHandler{service: service, auditService: auditService}
+}
+
+// RegisterRoutes mounts the fiduciary routes on the TPRM router
+func (h *SubProcessorHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/vendors/{vendorId}/sub-processors", h.List).Methods("GET")
+	r.HandleFunc("/vendors/{vendorId}/sub-processors", h.Declare).Methods("POST")
+	r.HandleFunc("/vendors/{vendorId}/sub-processors/history", h.History).Methods("GET")
+	r.HandleFunc("/sub-processors/pending", h.Pending).Methods("GET")
+	r.HandleFunc("/sub-processors/{id}/approve", h.Approve).Methods("POST")
+	r.HandleFunc("/sub-processors/{id}/reject", h.Reject).Methods("POST")
+	r.HandleFunc("/sub-processors/{id}/remove", h.Remove).Methods("POST")
+	r.HandleFunc("/data-map", h.DataMap).Methods("GET")
+}
+
+// RegisterPortalRoutes mounts the vendor routes; the router must use the
+// vendor portal's Authenticate middleware
+func (h *SubProcessorHandler) RegisterPortalRoutes(r *mux.Router) {
+	r.HandleFunc("/sub-processors", h.PortalList).Methods("GET")
+	r.HandleFunc("/sub-processors", h.Propose).Methods("POST")
+	r.HandleFunc("/sub-processors/{id}/remove", h.PortalRemove).Methods("POST")
+}
+
+func writeSubProcessorError(w http.ResponseWriter, err error) {
+	var restricted *services.TransferRestrictedError
+	switch {
+	case errors.As(err, &restricted):
+		writeTransferError(w, err)
+	case errors.Is(err, services.ErrVendorScopeDenied):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrSubProcessorNotFound),
+		errors.Is(err, services.ErrVendorNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSubProcessorDecided),
+		errors.Is(err, services.ErrSubProcessorInactive):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidSubProcessor):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// tenantPurposes loads the tenant's purposes from its own schema
+func tenantPurposes(tenantID uuid.UUID) ([]models.Purpose, error) {
+	tenantDB, err := db.GetTenantDB("tenant_" + tenantID.String()[:8])
+	if err != nil {
+		return nil, err
+	}
+	var purposes []models.Purpose
+	err = tenantDB.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&purposes).Error
+	return purposes, err
+}
+
+func (h *SubProcessorHandler) audit(r *http.Request, tenantID, userID uuid.UUID, action string, sp *models.VendorSubProcessor, note string) {
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, action, sp.Status, "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"sub_processor_id": sp.ID, "vendor_id": sp.VendorID, "name": sp.Name, "location": sp.Location, "note": note,
+	})
+}
+
+// Fiduciary endpoints
+
+func (h *SubProcessorHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	list, err := h.service.List(tenantID, vendorID)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Declare records a sub-processor the tenant has already approved
+func (h *SubProcessorHandler) Declare(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	var req services.SubProcessorInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	sp, err := h.service.Declare(tenantID, vendorID, userID, req)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, "sub_processor_declared", sp, req.Note)
+	writeJSON(w, http.StatusCreated, sp)
+}
+
+func (h *SubProcessorHandler) History(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	events, err := h.service.History(tenantID, vendorID)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func (h *SubProcessorHandler) Pending(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.Pending(tenantID)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// decision handles approve, reject and remove, which share their shape
+func (h *SubProcessorHandler) decision(w http.ResponseWriter, r *http.Request, action string, apply func(tenantID, id, userID uuid.UUID, note string) (*models.VendorSubProcessor, error)) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid sub-processor ID")
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	sp, err := apply(tenantID, id, userID, req.Note)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	h.audit(r, tenantID, userID, action, sp, req.Note)
+	writeJSON(w, http.StatusOK, sp)
+}
+
+func (h *SubProcessorHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decision(w, r, "sub_processor_approved", h.service.Approve)
+}
+
+// Reject records the tenant's objection; a note giving the reason is required
+func (h *SubProcessorHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decision(w, r, "sub_processor_rejected", h.service.Reject)
+}
+
+func (h *SubProcessorHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	h.decision(w, r, "sub_processor_removed", h.service.Remove)
+}
+
+// DataMap shows each third-party purpose with its vendors and their approved
+// sub-processors
+func (h *SubProcessorHandler) DataMap(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	m, err := h.service.DataMap(tenantID, purposes)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// Vendor endpoints
+
+func (h *SubProcessorHandler) auditVendor(r *http.Request, sess *services.VendorSession, action string, sp *models.VendorSubProcessor) {
+	go h.auditService.Create(context.Background(), sess.Invitation.ID, sess.TenantID(), uuid.Nil, action, sp.Status, "vendor", getClientIP(r), "", "", map[string]interface{}{
+		"sub_processor_id": sp.ID, "vendor_id": sess.VendorID(), "invitation_id": sess.Invitation.ID,
+		"email": sess.Invitation.Email, "name": sp.Name, "location": sp.Location,
+	})
+}
+
+func (h *SubProcessorHandler) PortalList(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListForPortal(vendorSession(r))
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Propose submits a sub-processor for the tenant's approval
+func (h *SubProcessorHandler) Propose(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	var req services.SubProcessorInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	sp, err := h.service.Propose(sess, req)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_sub_processor_proposed", sp)
+	writeJSON(w, http.StatusCreated, sp)
+}
+
+func (h *SubProcessorHandler) PortalRemove(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid sub-processor ID")
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	sp, err := h.service.RemoveFromPortal(sess, id, req.Note)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_sub_processor_removed", sp)
+	writeJSON(w, http.StatusOK, sp)
+}
+
+// Data principal endpoint
+
+// ForPrincipal shows a data principal who processes their data on the
+// fiduciary's behalf, including sub-processors
+func (h *SubProcessorHandler) ForPrincipal(w http.ResponseWriter, r *http.Request) {
+	c, ok := r.Context().Value(contextkeys.UserClaimsKey).(*claims.DataPrincipalClaims)
+	if !ok {
+		writeError(w, http.StatusForbidden, "user access required")
+		return
+	}
+	tenantID, err := uuid.Parse(c.TenantID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tenant ID")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	list, err := h.service.ForPrincipal(tenantID, purposes)
+	if err != nil {
+		writeSubProcessorError(w, err)
+		return
+	}
+	principalID, _ := uuid.Parse(c.PrincipalID)
+	go h.auditService.Create(context.Background(), principalID, tenantID, uuid.Nil, "sub_processors_viewed", "", "data_principal", getClientIP(r), "", "", map[string]interface{}{
+		"purposes": len(list),
+	})
+	writeJSON(w, http.StatusOK, list)
+}
@@ -33,11 +33,6 @@ func (h *TPRMHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/assessments/{assessmentId}/compute-risk", h.ComputeRisk).Methods("POST")
 	r.HandleFunc("/evidence/{evidenceId}", h.GetEvidenceMeta).Methods("GET")
 	r.HandleFunc("/evidence/{evidenceId}/file", h.DownloadEvidenceFile).Methods("GET")
-
-	// DPA Routes
-	r.HandleFunc("/dpa/templates", h.CreateDPATemplate).Methods("POST")
-	r.HandleFunc("/vendors/{vendorId}/dpa/generate", h.GenerateDPA).Methods("POST")
-	r.HandleFunc("/vendors/{vendorId}/dpa/upload", h.UploadSignedDPA).Methods("POST")
 }
 
 type CreateAssessmentRequest struct {
@@ -364,49 +359,3 @@ func (h *TPRMHandler) SubmitAuditResponse(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(map[string]string{"status": "submitted"})
 }
 
-func (h *TPRMHandler) CreateDPATemplate(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		TenantID string `json:"tenantId"`
-		Name     string `json:"name"`
-		Content  string `json:"content"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
-		return
-	}
-	tenantID, _ := uuid.Parse(req.TenantID)
-
-	t, err := h.service.CreateDPATemplate(tenantID, req.Name, req.Content)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(t)
-}
-
-func (h *TPRMHandler) GenerateDPA(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	vendorID, _ := uuid.Parse(vars["vendorId"])
-
-	var req struct {
-		TenantID   string `json:"tenantId"`
-		TemplateID string `json:"templateId"`
-	}
-	json.NewDecoder(r.Body).Decode(&req)
-	tenantID, _ := uuid.Parse(req.TenantID)
-	templateID, _ := uuid.Parse(req.TemplateID)
-
-	dpa, err := h.service.GenerateDPA(tenantID, vendorID, templateID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	json.NewEncoder(w).Encode(dpa)
-}
-
-func (h *TPRMHandler) UploadSignedDPA(w http.ResponseWriter, r *http.Request) {
-	// Placeholder for file upload logic similar to evidence upload
-	w.WriteHeader(http.StatusNotImplemented)
-}
-
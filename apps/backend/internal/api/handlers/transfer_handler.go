@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/localization"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// TransferHandler serves the cross-border transfer register and the tenant's
+// restricted-country list
+type TransferHandler struct {
+	service      *services.TransferService
+	auditService *services.AuditService
+}
+
+func NewTransferHandler(service *services.TransferService, auditService *services.AuditService) *TransferHandler {
+	return &TransferHandler{service: service, auditService: auditService}
+}
+
+// RegisterRoutes mounts the transfer routes on the TPRM router
+func (h *TransferHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/transfers/register", h.Register).Methods("GET")
+	r.HandleFunc("/transfers/countries", h.Countries).Methods("GET")
+	r.HandleFunc("/transfers/restricted-countries", h.ListRestricted).Methods("GET")
+	r.HandleFunc("/transfers/restricted-countries", h.SetRestricted).Methods("PUT")
+	r.HandleFunc("/transfers/restricted-countries/{code}", h.RemoveRestricted).Methods("DELETE")
+}
+
+func writeTransferError(w http.ResponseWriter, err error) {
+	var restricted *services.TransferRestrictedError
+	switch {
+	case errors.As(err, &restricted):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":       true,
+			"message":     restricted.Error(),
+			"country":     restricted.Country,
+			"restriction": restricted.Rule,
+		})
+	case errors.Is(err, services.ErrRestrictedCountryNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUnknownCountry),
+		errors.Is(err, services.ErrInvalidTransferRestriction):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Register lists every purpose and data category flowing out of the
+// tenant's home country
+func (h *TransferHandler) Register(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	purposes, err := tenantPurposes(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load purposes")
+		return
+	}
+	reg, err := h.service.Register(tenantID, purposes)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+func (h *TransferHandler) Countries(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, localization.Countries())
+}
+
+func (h *TransferHandler) ListRestricted(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListRestricted(tenantID)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// SetRestricted adds a country to the restricted list or changes its entry
+func (h *TransferHandler) SetRestricted(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req services.RestrictionInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	rc, err := h.service.SetRestricted(tenantID, userID, req)
+	if err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "restricted_country_set", rc.Action, "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"country": rc.CountryCode, "reason": rc.Reason, "reference": rc.Reference,
+	})
+	writeJSON(w, http.StatusOK, rc)
+}
+
+func (h *TransferHandler) RemoveRestricted(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	code := mux.Vars(r)["code"]
+	if err := h.service.RemoveRestricted(tenantID, code); err != nil {
+		writeTransferError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "restricted_country_removed", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"country": code,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"message": "country removed from the restricted list"})
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"pixpivot/arc/internal/api/middleware"
+	"pixpivot/arc/internal/localization"
 	"pixpivot/arc/internal/models"
 	"pixpivot/arc/internal/core/services"
 
@@ -12,11 +15,50 @@ import (
 )
 
 type VendorHandler struct {
-	service services.VendorService
+	service   services.VendorService
+	transfers *services.TransferService
 }
 
-func NewVendorHandler(service services.VendorService) *VendorHandler {
-	return &VendorHandler{service: service}
+func NewVendorHandler(service services.VendorService, transfers *services.TransferService) *VendorHandler {
+	return &VendorHandler{service: service, transfers: transfers}
+}
+
+// vendorWithTransfer is a vendor response carrying a warning when the vendor
+// processes data in a country the tenant has flagged
+type vendorWithTransfer struct {
+	*models.Vendor
+	TransferWarning string `json:"transferWarning,omitempty"`
+}
+
+// checkVendorTransfer vets the vendor's processing country against the
+// calling tenant's restricted-country list. It writes the error response
+// and returns false when the country is blocked.
+func (h *VendorHandler) checkVendorTransfer(w http.ResponseWriter, r *http.Request, country localization.Country) (warning string, ok bool) {
+	c := middleware.GetFiduciaryAuthClaims(r.Context())
+	if h.transfers == nil || c == nil || country.Code == "" {
+		return "", true
+	}
+	tenantID, err := uuid.Parse(c.TenantID)
+	if err != nil {
+		return "", true
+	}
+	flagged, err := h.transfers.CheckTransfer(tenantID, country.Code)
+	if err != nil {
+		writeTransferError(w, err)
+		return "", false
+	}
+	if flagged {
+		warning = "the vendor processes data in " + country.Name + ", which is flagged on your restricted-country list"
+	}
+	return warning, true
+}
+
+func writeVendorError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrUnknownCountry) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
 }
 
 func (h *VendorHandler) CreateVendor(w http.ResponseWriter, r *http.Request) {
@@ -29,11 +71,20 @@ func (h *VendorHandler) CreateVendor(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "company and email required")
 		return
 	}
+	country, err := services.ResolveCountry(vendor.ProcessingCountry, vendor.ProcessingLocation)
+	if err != nil && vendor.ProcessingCountry != "" {
+		writeVendorError(w, err)
+		return
+	}
+	warning, ok := h.checkVendorTransfer(w, r, country)
+	if !ok {
+		return
+	}
 	if err := h.service.CreateVendor(&vendor); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeVendorError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, vendor)
+	writeJSON(w, http.StatusCreated, vendorWithTransfer{Vendor: &vendor, TransferWarning: warning})
 }
 
 func (h *VendorHandler) UpdateVendor(w http.ResponseWriter, r *http.Request) {
@@ -50,12 +101,25 @@ func (h *VendorHandler) UpdateVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	warning := ""
+	if updateData.ProcessingLocation != "" || updateData.ProcessingCountry != "" {
+		country, err := services.ResolveCountry(updateData.ProcessingCountry, updateData.ProcessingLocation)
+		if err != nil && updateData.ProcessingCountry != "" {
+			writeVendorError(w, err)
+			return
+		}
+		var ok bool
+		if warning, ok = h.checkVendorTransfer(w, r, country); !ok {
+			return
+		}
+	}
+
 	vendor, err := h.service.UpdateVendor(id, updateData)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeVendorError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, vendor)
+	writeJSON(w, http.StatusOK, vendorWithTransfer{Vendor: vendor, TransferWarning: warning})
 }
 
 func (h *VendorHandler) DeleteVendor(w http.ResponseWriter, r *http.Request) {
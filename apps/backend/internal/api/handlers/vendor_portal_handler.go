@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/contextkeys"
+	"pixpivot/arc/internal/core/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// VendorTokenHeader carries a vendor portal token when it is not sent as a
+// bearer token
+const VendorTokenHeader = "X-Vendor-Token"
+
+const vendorEvidenceMaxBytes = 25 << 20
+
+// VendorPortalHandler serves the vendor self-service portal and the
+// fiduciary endpoints that invite vendor contacts to it
+type VendorPortalHandler struct {
+	service      *services.VendorPortalService
+	auditService *services.AuditService
+}
+
+func NewVendorPortalHandler(service *services.VendorPortalService, auditService *services.AuditService) *VendorPortalHandler {
+	return &VendorPortalHandler{service: service, auditService: auditService}
+}
+
+// RegisterFiduciaryRoutes mounts invitation management on the TPRM router
+func (h *VendorPortalHandler) RegisterFiduciaryRoutes(r *mux.Router) {
+	r.HandleFunc("/vendors/{vendorId}/portal-invitations", h.Invite).Methods("POST")
+	r.HandleFunc("/vendors/{vendorId}/portal-invitations", h.ListInvitations).Methods("GET")
+	r.HandleFunc("/portal-invitations/{invitationId}/revoke", h.RevokeInvitation).Methods("POST")
+}
+
+// RegisterPortalRoutes mounts the vendor-facing routes; the router must use
+// h.Authenticate
+func (h *VendorPortalHandler) RegisterPortalRoutes(r *mux.Router) {
+	r.HandleFunc("/me", h.Overview).Methods("GET")
+	r.HandleFunc("/assessments", h.ListAssessments).Methods("GET")
+	r.HandleFunc("/assessments/{assessmentId}/questionnaire", h.GetQuestionnaire).Methods("GET")
+	r.HandleFunc("/assessments/{assessmentId}/answers", h.SubmitAnswers).Methods("PUT")
+	r.HandleFunc("/assessments/{assessmentId}/evidence", h.UploadEvidence).Methods("POST")
+	r.HandleFunc("/findings", h.ListFindings).Methods("GET")
+	r.HandleFunc("/findings/{findingId}/responses", h.RespondToFinding).Methods("POST")
+	r.HandleFunc("/dpas", h.ListDPAs).Methods("GET")
+	r.HandleFunc("/dpas/{dpaId}/acknowledge", h.AcknowledgeDPA).Methods("POST")
+}
+
+// Authenticate resolves the vendor portal token and stores the session in the
+// request context
+func (h *VendorPortalHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(VendorTokenHeader)
+		if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		sess, err := h.service.Authenticate(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), contextkeys.VendorSessionKey, sess)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func vendorSession(r *http.Request) *services.VendorSession {
+	sess, _ := r.Context().Value(contextkeys.VendorSessionKey).(*services.VendorSession)
+	return sess
+}
+
+// auditVendor records a vendor portal action. The invitation stands in for
+// the user, since vendor contacts have no account.
+func (h *VendorPortalHandler) auditVendor(r *http.Request, sess *services.VendorSession, action, status string, details map[string]interface{}) {
+	details["vendor_id"] = sess.VendorID()
+	details["invitation_id"] = sess.Invitation.ID
+	details["email"] = sess.Invitation.Email
+	go h.auditService.Create(context.Background(), sess.Invitation.ID, sess.TenantID(), uuid.Nil, action, status, "vendor", getClientIP(r), "", "", details)
+}
+
+func (h *VendorPortalHandler) writeVendorPortalError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrVendorScopeDenied):
+		if sess := vendorSession(r); sess != nil {
+			h.auditVendor(r, sess, "vendor_portal_access_denied", "denied", map[string]interface{}{
+				"method": r.Method, "path": r.URL.Path,
+			})
+		}
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrVendorNotFound),
+		errors.Is(err, services.ErrVendorInvitationNotFound),
+		errors.Is(err, services.ErrFindingNotFound),
+		errors.Is(err, services.ErrDPANotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidVendorInvitation),
+		errors.Is(err, services.ErrInvalidFindingResponse):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrFindingClosed),
+		errors.Is(err, services.ErrDPAAcknowledged):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeQuestionnaireError(w, err)
+	}
+}
+
+// Fiduciary endpoints
+
+// Invite emails a vendor contact a portal link. The token is only ever sent
+// in that email.
+func (h *VendorPortalHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	var req struct {
+		services.InviteInput
+		ValidDays int `json:"validDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.TTL = time.Duration(req.ValidDays) * 24 * time.Hour
+	inv, _, err := h.service.Invite(tenantID, vendorID, userID, req.InviteInput)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_portal_invited", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"invitation_id": inv.ID, "vendor_id": vendorID, "email": inv.Email, "expires_at": inv.ExpiresAt,
+	})
+	writeJSON(w, http.StatusCreated, inv)
+}
+
+func (h *VendorPortalHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	list, err := h.service.ListInvitations(tenantID, vendorID)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *VendorPortalHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["invitationId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid invitation ID")
+		return
+	}
+	inv, err := h.service.RevokeInvitation(tenantID, id)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_portal_invitation_revoked", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"invitation_id": inv.ID, "vendor_id": inv.VendorID,
+	})
+	writeJSON(w, http.StatusOK, inv)
+}
+
+// Vendor endpoints
+
+func (h *VendorPortalHandler) Overview(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	o, err := h.service.Overview(sess)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_opened", "", map[string]interface{}{})
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (h *VendorPortalHandler) ListAssessments(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	list, err := h.service.ListAssessments(sess)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_assessments_viewed", "", map[string]interface{}{"count": len(list)})
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *VendorPortalHandler) GetQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	view, err := h.service.GetQuestionnaire(sess, assessmentID)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_questionnaire_viewed", "", map[string]interface{}{"assessment_id": assessmentID})
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *VendorPortalHandler) SubmitAnswers(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	var req struct {
+		Answers []services.AnswerInput `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Answers) == 0 {
+		writeError(w, http.StatusBadRequest, "answers are required")
+		return
+	}
+	if err := h.service.SubmitAnswers(sess, assessmentID, req.Answers); err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	questions := make([]string, 0, len(req.Answers))
+	for _, a := range req.Answers {
+		questions = append(questions, a.QuestionID)
+	}
+	h.auditVendor(r, sess, "vendor_portal_answers_submitted", "", map[string]interface{}{
+		"assessment_id": assessmentID, "questions": questions,
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"saved": len(req.Answers)})
+}
+
+func (h *VendorPortalHandler) UploadEvidence(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, vendorEvidenceMaxBytes+1<<20)
+	if err := r.ParseMultipartForm(vendorEvidenceMaxBytes); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form data")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file not provided")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, vendorEvidenceMaxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+	if len(data) > vendorEvidenceMaxBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "evidence files are limited to 25MB")
+		return
+	}
+
+	e, err := h.service.UploadEvidence(sess, assessmentID, header.Filename, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_evidence_uploaded", "", map[string]interface{}{
+		"assessment_id": assessmentID, "evidence_id": e.ID, "file_name": header.Filename, "size_bytes": e.SizeBytes,
+	})
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *VendorPortalHandler) ListFindings(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	list, err := h.service.ListFindings(sess)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_findings_viewed", "", map[string]interface{}{"count": len(list)})
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *VendorPortalHandler) RespondToFinding(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	findingID, err := uuid.Parse(mux.Vars(r)["findingId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid finding ID")
+		return
+	}
+	var req services.FindingResponseInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	resp, err := h.service.RespondToFinding(sess, findingID, req)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_finding_responded", "", map[string]interface{}{
+		"finding_id": findingID, "response_id": resp.ID, "marked_resolved": resp.MarkedResolved,
+	})
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *VendorPortalHandler) ListDPAs(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	list, err := h.service.ListDPAs(sess)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_dpas_viewed", "", map[string]interface{}{"count": len(list)})
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *VendorPortalHandler) AcknowledgeDPA(w http.ResponseWriter, r *http.Request) {
+	sess := vendorSession(r)
+	dpaID, err := uuid.Parse(mux.Vars(r)["dpaId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid DPA ID")
+		return
+	}
+	a, err := h.service.AcknowledgeDPA(sess, dpaID)
+	if err != nil {
+		h.writeVendorPortalError(w, r, err)
+		return
+	}
+	h.auditVendor(r, sess, "vendor_portal_dpa_acknowledged", "", map[string]interface{}{
+		"dpa_id": a.ID,
+	})
+	writeJSON(w, http.StatusOK, a)
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pixpivot/arc/internal/core/services"
+	"pixpivot/arc/internal/questionnaire"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// VendorQuestionnaireHandler serves questionnaire authoring and
+// questionnaire-based vendor assessments
+type VendorQuestionnaireHandler struct {
+	service      *services.VendorQuestionnaireService
+	tprmService  *services.TPRMService
+	auditService *services.AuditService
+}
+
+func NewVendorQuestionnaireHandler(service *services.VendorQuestionnaireService, tprmService *services.TPRMService, auditService *services.AuditService) *VendorQuestionnaireHandler {
+	return &VendorQuestionnaireHandler{service: service, tprmService: tprmService, auditService: auditService}
+}
+
+func (h *VendorQuestionnaireHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/questionnaires", h.CreateQuestionnaire).Methods("POST")
+	r.HandleFunc("/questionnaires", h.ListQuestionnaires).Methods("GET")
+	r.HandleFunc("/questionnaires/import/dpdpa", h.ImportDPDPA).Methods("POST")
+	r.HandleFunc("/questionnaires/{questionnaireId}", h.GetQuestionnaire).Methods("GET")
+	r.HandleFunc("/questionnaires/{questionnaireId}/draft", h.SaveDraft).Methods("PUT")
+	r.HandleFunc("/questionnaires/{questionnaireId}/versions/{version}", h.GetVersion).Methods("GET")
+	r.HandleFunc("/questionnaires/{questionnaireId}/versions/{version}/publish", h.Publish).Methods("POST")
+
+	r.HandleFunc("/vendors/{vendorId}/questionnaire-assessments", h.StartAssessment).Methods("POST")
+	r.HandleFunc("/assessments/{assessmentId}/questionnaire", h.GetAssessmentQuestionnaire).Methods("GET")
+	r.HandleFunc("/assessments/{assessmentId}/answers", h.SubmitAnswers).Methods("PUT")
+	r.HandleFunc("/assessments/{assessmentId}/answers/reuse", h.ReuseAnswers).Methods("POST")
+	r.HandleFunc("/assessments/{assessmentId}/complete", h.CompleteAssessment).Methods("POST")
+}
+
+func writeQuestionnaireError(w http.ResponseWriter, err error) {
+	var invalid *services.QuestionnaireInvalidError
+	var rejected *services.AnswersRejectedError
+	var incomplete *services.QuestionnaireIncompleteError
+	switch {
+	case errors.As(err, &invalid):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": invalid.Error(),
+			"issues":  invalid.Issues,
+		})
+	case errors.As(err, &rejected):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": rejected.Error(),
+			"issues":  rejected.Issues,
+		})
+	case errors.As(err, &incomplete):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   true,
+			"message": incomplete.Error(),
+			"missing": incomplete.Missing,
+		})
+	case errors.Is(err, services.ErrQuestionnaireNotFound),
+		errors.Is(err, services.ErrQuestionnaireVersionNotFound),
+		errors.Is(err, services.ErrAssessmentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidQuestionnaire),
+		errors.Is(err, services.ErrAssessmentNoQuestionnaire):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrQuestionnaireNotPublished),
+		errors.Is(err, services.ErrQuestionnaireVersionLocked),
+		errors.Is(err, services.ErrAssessmentCompleted):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Logger.Error().Err(err).Msg("vendor questionnaire request failed")
+		writeError(w, http.StatusInternalServerError, "failed to process questionnaire request")
+	}
+}
+
+type questionnaireRequest struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Definition  questionnaire.Definition `json:"definition"`
+	Changelog   string                   `json:"changelog"`
+}
+
+func (h *VendorQuestionnaireHandler) CreateQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req questionnaireRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	detail, err := h.service.CreateQuestionnaire(tenantID, userID, req.Name, req.Description, req.Definition)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_created", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"questionnaire_id": detail.ID, "name": detail.Name,
+	})
+	writeJSON(w, http.StatusCreated, detail)
+}
+
+func (h *VendorQuestionnaireHandler) ListQuestionnaires(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	list, err := h.service.ListQuestionnaires(tenantID)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ImportDPDPA publishes the built-in DPDPA checklist as an editable questionnaire
+func (h *VendorQuestionnaireHandler) ImportDPDPA(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	detail, err := h.service.ImportChecklist(tenantID, userID, h.tprmService.GetDPDPAChecklist())
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_imported", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"questionnaire_id": detail.ID, "source": "dpdpa_checklist",
+	})
+	writeJSON(w, http.StatusCreated, detail)
+}
+
+func (h *VendorQuestionnaireHandler) GetQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["questionnaireId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid questionnaire ID")
+		return
+	}
+	detail, err := h.service.GetQuestionnaire(tenantID, id)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// SaveDraft edits the draft version, opening a new one if the latest is published
+func (h *VendorQuestionnaireHandler) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(r)["questionnaireId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid questionnaire ID")
+		return
+	}
+	var req questionnaireRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v, err := h.service.SaveDraft(tenantID, id, req.Definition, req.Changelog)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_draft_saved", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"questionnaire_id": id, "version": v.Version,
+	})
+	writeJSON(w, http.StatusOK, v)
+}
+
+func questionnaireVersionVars(r *http.Request) (uuid.UUID, int, bool) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["questionnaireId"])
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil || version < 1 {
+		return uuid.Nil, 0, false
+	}
+	return id, version, true
+}
+
+func (h *VendorQuestionnaireHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, version, ok := questionnaireVersionVars(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid questionnaire ID or version")
+		return
+	}
+	v, err := h.service.GetVersion(tenantID, id, version)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func (h *VendorQuestionnaireHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, version, ok := questionnaireVersionVars(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid questionnaire ID or version")
+		return
+	}
+	v, err := h.service.Publish(tenantID, id, version, userID)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_published", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"questionnaire_id": id, "version": v.Version,
+	})
+	writeJSON(w, http.StatusOK, v)
+}
+
+// StartAssessment assesses a vendor on a questionnaire's published version
+func (h *VendorQuestionnaireHandler) StartAssessment(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	vendorID, err := uuid.Parse(mux.Vars(r)["vendorId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid vendor ID")
+		return
+	}
+	var req struct {
+		QuestionnaireID uuid.UUID  `json:"questionnaireId"`
+		Title           string     `json:"title"`
+		DueDate         *time.Time `json:"dueDate"`
+		AssessorID      *uuid.UUID `json:"assessorId"`
+		ReuseAnswers    bool       `json:"reuseAnswers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	view, err := h.service.StartAssessment(tenantID, vendorID, req.QuestionnaireID, req.Title, req.DueDate, req.AssessorID, req.ReuseAnswers, userID)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_assessment_started", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"assessment_id": view.Assessment.ID, "vendor_id": vendorID, "questionnaire_id": view.QuestionnaireID, "version": view.Version,
+	})
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (h *VendorQuestionnaireHandler) GetAssessmentQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	tenantID, _, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	view, err := h.service.GetAssessmentQuestionnaire(tenantID, assessmentID)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (h *VendorQuestionnaireHandler) SubmitAnswers(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	var req struct {
+		Answers []services.AnswerInput `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Answers) == 0 {
+		writeError(w, http.StatusBadRequest, "answers are required")
+		return
+	}
+	result, err := h.service.SubmitAnswers(tenantID, assessmentID, userID, req.Answers)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ReuseAnswers fills unanswered questions from the vendor's recent answers.
+// ?maxAgeDays bounds how old a reused answer may be (default 365).
+func (h *VendorQuestionnaireHandler) ReuseAnswers(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	maxAgeDays := 365
+	if v := r.URL.Query().Get("maxAgeDays"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "maxAgeDays must be a positive number")
+			return
+		}
+		maxAgeDays = n
+	}
+	reused, err := h.service.ReuseAnswers(tenantID, assessmentID, userID, time.Duration(maxAgeDays)*24*time.Hour)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reused": reused})
+}
+
+func (h *VendorQuestionnaireHandler) CompleteAssessment(w http.ResponseWriter, r *http.Request) {
+	tenantID, userID, ok := fiduciaryCaller(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	assessmentID, err := uuid.Parse(mux.Vars(r)["assessmentId"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid assessment ID")
+		return
+	}
+	result, err := h.service.CompleteAssessment(tenantID, assessmentID)
+	if err != nil {
+		writeQuestionnaireError(w, err)
+		return
+	}
+	go h.auditService.Create(context.Background(), userID, tenantID, uuid.Nil, "vendor_questionnaire_assessment_completed", "", "fiduciary", getClientIP(r), "", "", map[string]interface{}{
+		"assessment_id": assessmentID, "risk_score": result.RiskScore, "passed": result.Passed,
+	})
+	writeJSON(w, http.StatusOK, result)
+}
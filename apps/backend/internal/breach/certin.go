@@ -0,0 +1,229 @@
+package breach
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CERTInReportingWindow is the time CERT-In's April 2022 directions under
+// section 70B(6) of the IT Act allow for reporting a cyber incident, counted
+// from when the incident is noticed or brought to notice
+const CERTInReportingWindow = 6 * time.Hour
+
+// CERTInIncidentType is one of the reportable incident types in Annexure I
+// of the CERT-In directions
+type CERTInIncidentType string
+
+const (
+	CERTInTargetedScanning         CERTInIncidentType = "targeted_scanning"
+	CERTInCriticalSystemCompromise CERTInIncidentType = "critical_system_compromise"
+	CERTInUnauthorisedAccess       CERTInIncidentType = "unauthorised_access"
+	CERTInWebsiteDefacement        CERTInIncidentType = "website_defacement"
+	CERTInMaliciousCode            CERTInIncidentType = "malicious_code"
+	CERTInServerAttack             CERTInIncidentType = "server_attack"
+	CERTInIdentityTheftPhishing    CERTInIncidentType = "identity_theft_phishing"
+	CERTInDenialOfService          CERTInIncidentType = "denial_of_service"
+	CERTInCriticalInfrastructure   CERTInIncidentType = "critical_infrastructure_attack"
+	CERTInApplicationAttack        CERTInIncidentType = "application_attack"
+	CERTInDataBreach               CERTInIncidentType = "data_breach"
+	CERTInDataLeak                 CERTInIncidentType = "data_leak"
+	CERTInIoTAttack                CERTInIncidentType = "iot_attack"
+	CERTInDigitalPayment           CERTInIncidentType = "digital_payment_attack"
+	CERTInMaliciousMobileApp       CERTInIncidentType = "malicious_mobile_app"
+	CERTInFakeMobileApp            CERTInIncidentType = "fake_mobile_app"
+	CERTInSocialMediaCompromise    CERTInIncidentType = "social_media_compromise"
+	CERTInCloudAttack              CERTInIncidentType = "cloud_attack"
+	CERTInEmergingTechAttack       CERTInIncidentType = "emerging_tech_attack"
+	CERTInAIMLAttack               CERTInIncidentType = "ai_ml_attack"
+)
+
+// CERTInIncidentTypeInfo describes an incident type as Annexure I lists it
+type CERTInIncidentTypeInfo struct {
+	Code   CERTInIncidentType `json:"code"`
+	Number int                `json:"number"`
+	Label  string             `json:"label"`
+}
+
+// CERTInIncidentTypes is the Annexure I taxonomy in the directions' order
+var CERTInIncidentTypes = []CERTInIncidentTypeInfo{
+	{CERTInTargetedScanning, 1, "Targeted scanning/probing of critical networks/systems"},
+	{CERTInCriticalSystemCompromise, 2, "Compromise of critical systems/information"},
+	{CERTInUnauthorisedAccess, 3, "Unauthorised access of IT systems/data"},
+	{CERTInWebsiteDefacement, 4, "Defacement of website or intrusion into a website and unauthorised changes such as inserting malicious code, links to external websites etc."},
+	{CERTInMaliciousCode, 5, "Malicious code attacks such as spreading of virus/worm/Trojan/Bots/Spyware/Ransomware/Cryptominers"},
+	{CERTInServerAttack, 6, "Attack on servers such as Database, Mail and DNS and network devices such as Routers"},
+	{CERTInIdentityTheftPhishing, 7, "Identity Theft, spoofing and phishing attacks"},
+	{CERTInDenialOfService, 8, "Denial of Service (DoS) and Distributed Denial of Service (DDoS) attacks"},
+	{CERTInCriticalInfrastructure, 9, "Attacks on Critical infrastructure, SCADA and operational technology systems and Wireless networks"},
+	{CERTInApplicationAttack, 10, "Attacks on Application such as E-Governance, E-Commerce etc."},
+	{CERTInDataBreach, 11, "Data Breach"},
+	{CERTInDataLeak, 12, "Data Leak"},
+	{CERTInIoTAttack, 13, "Attacks on Internet of Things (IoT) devices and associated systems, networks, software, servers"},
+	{CERTInDigitalPayment, 14, "Attacks or incident affecting Digital Payment systems"},
+	{CERTInMaliciousMobileApp, 15, "Attacks through Malicious mobile Apps"},
+	{CERTInFakeMobileApp, 16, "Fake mobile Apps"},
+	{CERTInSocialMediaCompromise, 17, "Unauthorised access to social media accounts"},
+	{CERTInCloudAttack, 18, "Attacks or malicious/suspicious activities affecting Cloud computing systems/servers/software/applications"},
+	{CERTInEmergingTechAttack, 19, "Attacks or malicious/suspicious activities affecting systems/servers/networks/software/applications related to Big Data, Block chain, virtual assets, virtual asset exchanges, custodian wallets, Robotics, 3D and 4D Printing, additive manufacturing, Drones"},
+	{CERTInAIMLAttack, 20, "Attacks or malicious/suspicious activities affecting systems/servers/software/applications related to Artificial Intelligence and Machine Learning"},
+}
+
+// LookupCERTInIncidentType returns the Annexure I entry for a code
+func LookupCERTInIncidentType(code CERTInIncidentType) (CERTInIncidentTypeInfo, bool) {
+	for _, t := range CERTInIncidentTypes {
+		if t.Code == code {
+			return t, true
+		}
+	}
+	return CERTInIncidentTypeInfo{}, false
+}
+
+// SuggestCERTInIncidentType maps a breach type to the closest CERT-In
+// incident type. Breaches that are not cyber incidents, such as a lost paper
+// file, have no suggestion.
+func SuggestCERTInIncidentType(breachType string) CERTInIncidentType {
+	switch breachType {
+	case "unauthorized_access", "insider_threat":
+		return CERTInUnauthorisedAccess
+	case "data_theft":
+		return CERTInDataBreach
+	case "data_loss", "lost_device":
+		return CERTInDataLeak
+	case "ransomware", "malware_attack":
+		return CERTInMaliciousCode
+	case "social_engineering", "phishing":
+		return CERTInIdentityTheftPhishing
+	case "system_vulnerability":
+		return CERTInCriticalSystemCompromise
+	}
+	return ""
+}
+
+// CERT-In track statuses
+const (
+	CERTInStatusNotRequired  = "not_required"
+	CERTInStatusPending      = "pending"
+	CERTInStatusOverdue      = "overdue"
+	CERTInStatusReported     = "reported"
+	CERTInStatusReportedLate = "reported_late"
+)
+
+// CERTInStatus works out where a breach's CERT-In track stands at now
+func CERTInStatus(required bool, deadline, reportedAt *time.Time, now time.Time) string {
+	switch {
+	case !required:
+		return CERTInStatusNotRequired
+	case reportedAt != nil && deadline != nil && reportedAt.After(*deadline):
+		return CERTInStatusReportedLate
+	case reportedAt != nil:
+		return CERTInStatusReported
+	case deadline != nil && now.After(*deadline):
+		return CERTInStatusOverdue
+	}
+	return CERTInStatusPending
+}
+
+// CERTInIncidentDetails holds what the CERT-In form asks for that the breach
+// record does not otherwise capture
+type CERTInIncidentDetails struct {
+	AffectedSystems   []CERTInAffectedSystem `json:"affectedSystems,omitempty"`
+	Symptoms          string                 `json:"symptoms,omitempty"` // Unusual behaviour observed
+	CriticalSystem    bool                   `json:"criticalSystem"`     // Affected system is critical to the organisation's mission
+	ReportedElsewhere []string               `json:"reportedElsewhere,omitempty"`
+}
+
+// CERTInAffectedSystem identifies an affected system or network
+type CERTInAffectedSystem struct {
+	Name            string `json:"name"`
+	IPAddress       string `json:"ipAddress,omitempty"`
+	Hostname        string `json:"hostname,omitempty"`
+	OperatingSystem string `json:"operatingSystem,omitempty"`
+	Location        string `json:"location,omitempty"`
+}
+
+// CERTInContact is the organisation's designated Point of Contact for CERT-In
+type CERTInContact struct {
+	Name        string `json:"name"`
+	Designation string `json:"designation,omitempty"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone,omitempty"`
+}
+
+// CERTInReport is an incident report laid out in the fields of CERT-In's
+// incident reporting form
+type CERTInReport struct {
+	BreachID    uuid.UUID  `json:"breachId"`
+	GeneratedAt time.Time  `json:"generatedAt"`
+	DueBy       *time.Time `json:"dueBy,omitempty"`
+	Simulation  string     `json:"simulation,omitempty"` // SimulationBanner on reports of a tabletop exercise
+
+	// Reporting organisation and its Point of Contact
+	Organisation        string        `json:"organisation"`
+	Sector              string        `json:"sector,omitempty"`
+	OrganisationAddress string        `json:"organisationAddress,omitempty"`
+	PointOfContact      CERTInContact `json:"pointOfContact"`
+
+	// Incident
+	IncidentType       CERTInIncidentType `json:"incidentType"`
+	IncidentTypeNumber int                `json:"incidentTypeNumber,omitempty"`
+	IncidentTypeLabel  string             `json:"incidentTypeLabel,omitempty"`
+	OccurredAt         *time.Time         `json:"occurredAt,omitempty"`
+	DetectedAt         *time.Time         `json:"detectedAt,omitempty"`
+	Description        string             `json:"description"`
+	Symptoms           string             `json:"symptoms,omitempty"`
+	Location           []string           `json:"location"`
+
+	// Affected systems and data
+	AffectedSystems []CERTInAffectedSystem `json:"affectedSystems"`
+	CriticalSystem  bool                   `json:"criticalSystem"`
+	DataCategories  []string               `json:"dataCategories,omitempty"`
+	AffectedRecords int                    `json:"affectedRecords,omitempty"`
+
+	// Response
+	ActionsTaken      []string `json:"actionsTaken"`
+	ReportedElsewhere []string `json:"reportedElsewhere,omitempty"`
+}
+
+// Validate checks the form's mandatory fields
+func (r *CERTInReport) Validate() []DPBValidationIssue {
+	issues := []DPBValidationIssue{}
+	fail := func(field, format string, args ...interface{}) {
+		issues = append(issues, DPBValidationIssue{Field: field, Severity: "error", Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(field, format string, args ...interface{}) {
+		issues = append(issues, DPBValidationIssue{Field: field, Severity: "warning", Message: fmt.Sprintf(format, args...)})
+	}
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+
+	if blank(r.Organisation) {
+		fail("organisation", "name of the reporting organisation is required")
+	}
+	if blank(r.PointOfContact.Name) || blank(r.PointOfContact.Email) {
+		fail("pointOfContact", "name and email of the designated Point of Contact are required; set certInPocName and certInPocEmail in the tenant settings")
+	}
+	if _, ok := LookupCERTInIncidentType(r.IncidentType); !ok {
+		fail("incidentType", "incident type must be one of the CERT-In Annexure I types")
+	}
+	if r.DetectedAt == nil {
+		fail("detectedAt", "time the incident was noticed is required")
+	}
+	if r.OccurredAt != nil && r.DetectedAt != nil && r.DetectedAt.Before(*r.OccurredAt) {
+		fail("detectedAt", "detection cannot precede occurrence")
+	}
+	if blank(r.Description) {
+		fail("description", "description of the incident is required")
+	}
+	if len(r.AffectedSystems) == 0 {
+		warn("affectedSystems", "no affected systems recorded; CERT-In expects IP addresses or hostnames where known")
+	}
+	if len(r.ActionsTaken) == 0 {
+		warn("actionsTaken", "no actions taken recorded")
+	}
+	if r.DueBy != nil && r.GeneratedAt.After(*r.DueBy) {
+		warn("dueBy", "report is past the 6-hour deadline of %s", r.DueBy.Format(time.RFC3339))
+	}
+	return issues
+}
@@ -0,0 +1,187 @@
+package breach
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DPBReportKind distinguishes the two intimations the DPDP Rules require
+type DPBReportKind string
+
+const (
+	// DPBReportInitial is the intimation sent without delay on becoming aware of the breach
+	DPBReportInitial DPBReportKind = "initial"
+	// DPBReportDetailed is the fuller report due within 72 hours of becoming aware
+	DPBReportDetailed DPBReportKind = "detailed"
+)
+
+// DPBDetailedReportWindow is the time allowed for the detailed report, counted from detection
+const DPBDetailedReportWindow = 72 * time.Hour
+
+// DPBIntimation is a breach intimation to the Data Protection Board in the
+// structure prescribed by the DPDP Rules. The initial intimation covers the
+// nature, extent, timing, location and likely impact of the breach; the
+// detailed report adds circumstances, mitigation, findings on the cause,
+// remedial measures and the intimations sent to affected Data Principals.
+type DPBIntimation struct {
+	Kind        DPBReportKind `json:"kind"`
+	BreachID    uuid.UUID     `json:"breachId"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+	DueBy       *time.Time    `json:"dueBy,omitempty"` // Detailed report deadline
+
+	Fiduciary DPBFiduciaryDetails `json:"dataFiduciary"`
+
+	// Description of the breach
+	Nature       string     `json:"nature"`
+	Title        string     `json:"title,omitempty"`
+	Description  string     `json:"description"`
+	Severity     string     `json:"severity,omitempty"`
+	OccurredAt   *time.Time `json:"occurredAt,omitempty"`
+	DetectedAt   *time.Time `json:"detectedAt,omitempty"`
+	ContainedAt  *time.Time `json:"containedAt,omitempty"`
+	Location     []string   `json:"location"`
+	Extent       DPBExtent  `json:"extent"`
+	LikelyImpact string     `json:"likelyImpact"`
+
+	// Detailed report only
+	Circumstances       string                  `json:"circumstances,omitempty"`
+	MitigationMeasures  []string                `json:"mitigationMeasures,omitempty"`
+	CauseFindings       string                  `json:"causeFindings,omitempty"`
+	RemedialMeasures    []string                `json:"remedialMeasures,omitempty"`
+	PrincipalIntimation *DPBPrincipalIntimation `json:"principalIntimation,omitempty"`
+	Timeline            []DPBTimelineEntry      `json:"timeline,omitempty"`
+	Evidence            []DPBEvidenceEntry      `json:"evidence,omitempty"`
+}
+
+// DPBFiduciaryDetails identifies the reporting Data Fiduciary
+type DPBFiduciaryDetails struct {
+	TenantID     uuid.UUID `json:"tenantId"`
+	Name         string    `json:"name"`
+	ContactEmail string    `json:"contactEmail,omitempty"`
+}
+
+// DPBExtent describes how far the breach reaches. When a recipient list has
+// been frozen, the counts come from it and its version is recorded.
+type DPBExtent struct {
+	AffectedPrincipals   int            `json:"affectedPrincipals"`
+	DataCategories       []string       `json:"dataCategories"`
+	ByCategory           map[string]int `json:"byCategory,omitempty"`
+	RecipientListVersion int            `json:"recipientListVersion,omitempty"`
+}
+
+// DPBPrincipalIntimation reports on notices sent to affected Data Principals
+type DPBPrincipalIntimation struct {
+	Required      bool       `json:"required"`
+	NotifiedCount int        `json:"notifiedCount"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	Methods       []string   `json:"methods,omitempty"`
+}
+
+// DPBTimelineEntry is one event in the breach's lifecycle
+type DPBTimelineEntry struct {
+	At          time.Time `json:"at"`
+	Event       string    `json:"event"`
+	Description string    `json:"description,omitempty"`
+}
+
+// DPBEvidenceEntry lists collected evidence by reference and hash; the
+// evidence itself is not sent with the report.
+type DPBEvidenceEntry struct {
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	SHA256      string    `json:"sha256,omitempty"`
+	CollectedAt time.Time `json:"collectedAt"`
+}
+
+// DPBValidationIssue flags a field that is missing or doubtful. Errors block
+// generation; warnings are reported but do not.
+type DPBValidationIssue struct {
+	Field    string `json:"field"`
+	Severity string `json:"severity"` // error, warning
+	Message  string `json:"message"`
+}
+
+// Validate checks the mandatory fields for the intimation's kind.
+func (r *DPBIntimation) Validate() []DPBValidationIssue {
+	issues := []DPBValidationIssue{}
+	fail := func(field, format string, args ...interface{}) {
+		issues = append(issues, DPBValidationIssue{Field: field, Severity: "error", Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(field, format string, args ...interface{}) {
+		issues = append(issues, DPBValidationIssue{Field: field, Severity: "warning", Message: fmt.Sprintf(format, args...)})
+	}
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+
+	if blank(r.Fiduciary.Name) {
+		fail("dataFiduciary.name", "name of the Data Fiduciary is required")
+	}
+	if blank(r.Nature) {
+		fail("nature", "nature of the breach (breach type) is required")
+	}
+	if blank(r.Description) {
+		fail("description", "description of the breach is required")
+	}
+	if r.OccurredAt == nil {
+		fail("occurredAt", "time of occurrence is required; give a best estimate if unknown")
+	}
+	if r.DetectedAt == nil {
+		fail("detectedAt", "time the breach was detected is required")
+	}
+	if r.OccurredAt != nil && r.DetectedAt != nil && r.DetectedAt.Before(*r.OccurredAt) {
+		fail("detectedAt", "detection cannot precede occurrence")
+	}
+	if len(r.Location) == 0 {
+		fail("location", "location of occurrence is required; record the geographic scope in the impact assessment")
+	}
+	if r.Extent.AffectedPrincipals <= 0 {
+		fail("extent.affectedPrincipals", "number of affected Data Principals is required; give a best estimate")
+	}
+	if len(r.Extent.DataCategories) == 0 {
+		fail("extent.dataCategories", "categories of personal data affected are required")
+	}
+	if blank(r.LikelyImpact) {
+		fail("likelyImpact", "likely impact on Data Principals is required; complete the impact assessment")
+	}
+
+	if r.Kind != DPBReportDetailed {
+		return issues
+	}
+
+	if blank(r.Circumstances) {
+		fail("circumstances", "facts on the events, circumstances and reasons leading to the breach are required")
+	}
+	if len(r.MitigationMeasures) == 0 {
+		fail("mitigationMeasures", "measures implemented or proposed to mitigate risk are required")
+	}
+	if blank(r.CauseFindings) {
+		fail("causeFindings", "findings regarding the person who caused the breach are required; state if none yet")
+	}
+	if len(r.RemedialMeasures) == 0 {
+		fail("remedialMeasures", "remedial measures to prevent recurrence are required")
+	}
+	if r.PrincipalIntimation == nil {
+		fail("principalIntimation", "report on intimations to affected Data Principals is required")
+	} else if r.PrincipalIntimation.Required && r.PrincipalIntimation.NotifiedCount == 0 {
+		fail("principalIntimation.notifiedCount", "affected Data Principals must be intimated before the detailed report")
+	}
+	if r.ContainedAt == nil {
+		warn("containedAt", "breach is not recorded as contained")
+	}
+	if r.DueBy != nil && r.GeneratedAt.After(*r.DueBy) {
+		warn("dueBy", "detailed report is past the 72-hour deadline of %s", r.DueBy.Format(time.RFC3339))
+	}
+	return issues
+}
+
+// HasErrors reports whether any issue blocks generation
+func HasErrors(issues []DPBValidationIssue) bool {
+	for _, i := range issues {
+		if i.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
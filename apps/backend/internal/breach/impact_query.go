@@ -0,0 +1,93 @@
+package breach
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpactQuery describes what a breach exposed: the systems (discovery data
+// sources) that were breached, the window during which they were exposed and
+// the categories of personal data affected. It is resolved against consents,
+// purpose data objects and discovery findings to find the affected Data
+// Principals.
+type ImpactQuery struct {
+	SystemIDs      []uuid.UUID `json:"systemIds"`
+	WindowStart    *time.Time  `json:"windowStart,omitempty"`
+	WindowEnd      *time.Time  `json:"windowEnd,omitempty"`
+	DataCategories []string    `json:"dataCategories"`
+}
+
+// ErrInvalidImpactQuery wraps every error returned by ImpactQuery.Validate
+var ErrInvalidImpactQuery = errors.New("invalid impact query")
+
+// Validate checks the query is resolvable
+func (q *ImpactQuery) Validate() error {
+	if len(q.SystemIDs) == 0 && len(q.DataCategories) == 0 {
+		return fmt.Errorf("%w: at least one breached system or data category is required", ErrInvalidImpactQuery)
+	}
+	if q.WindowStart != nil && q.WindowEnd != nil && q.WindowEnd.Before(*q.WindowStart) {
+		return fmt.Errorf("%w: window end cannot precede window start", ErrInvalidImpactQuery)
+	}
+	return nil
+}
+
+// dataCategoryMembers maps a breach data category to the discovery PII types
+// and consent data objects that belong to it. Items not listed here only match
+// a category of the same name.
+var dataCategoryMembers = map[string][]string{
+	"identity":  {"name", "full_name", "dob", "date_of_birth", "aadhaar", "pan", "passport", "voter_id", "driving_license"},
+	"contact":   {"email", "phone", "phone_in", "mobile", "address", "postal_address"},
+	"financial": {"credit_card", "debit_card", "bank_account", "account_number", "ifsc", "upi", "gstin"},
+	"health":    {"medical", "medical_records", "diagnosis", "prescription", "health_records"},
+	"biometric": {"fingerprint", "face", "iris", "voice"},
+	"location":  {"gps", "geolocation", "ip_address"},
+}
+
+// NormalizeDataItem lower-cases a category, PII type or data object name
+// and joins its words with underscores, so "Phone Number" and "phone_number"
+// compare equal.
+func NormalizeDataItem(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '.'
+	}), "_")
+}
+
+// CategoriesOf returns which of the given categories a PII type or data
+// object falls into.
+func CategoriesOf(item string, categories []string) []string {
+	item = NormalizeDataItem(item)
+	var out []string
+	for _, c := range categories {
+		c = NormalizeDataItem(c)
+		if item == c {
+			out = append(out, c)
+			continue
+		}
+		for _, m := range dataCategoryMembers[c] {
+			if item == m {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// CategoryForItem returns the category a PII type or data object belongs to,
+// or the item itself when it is not in the taxonomy.
+func CategoryForItem(item string) string {
+	item = NormalizeDataItem(item)
+	for c, members := range dataCategoryMembers {
+		for _, m := range members {
+			if item == m {
+				return c
+			}
+		}
+	}
+	return item
+}
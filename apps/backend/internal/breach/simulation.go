@@ -0,0 +1,98 @@
+package breach
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SimulationBanner heads every regulator document rendered for a simulated
+// breach, so an exported copy cannot be mistaken for a real submission
+const SimulationBanner = "*** SIMULATION - TABLETOP EXERCISE - DO NOT SUBMIT ***"
+
+// AfterActionReport reviews a tabletop exercise: how long the team took to
+// reach each milestone of the breach workflow, measured from detection, and
+// whether each regulatory deadline would have been met.
+type AfterActionReport struct {
+	BreachID    uuid.UUID  `json:"breachId"`
+	TenantID    uuid.UUID  `json:"tenantId"`
+	Title       string     `json:"title"`
+	Severity    string     `json:"severity"`
+	DetectedAt  time.Time  `json:"detectedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"` // Nil while the exercise is still running
+	GeneratedAt time.Time  `json:"generatedAt"`
+
+	Milestones    []AfterActionMilestone `json:"milestones"`
+	Stages        []AfterActionStage     `json:"stages"`
+	Notifications map[string]int         `json:"notifications"` // Notices captured by the sink, by recipient type and channel
+	Escalations   int                    `json:"escalations"`
+	Findings      []string               `json:"findings"`
+}
+
+// AfterActionMilestone is one step of the workflow. Deadline and MetDeadline
+// are set for steps with a regulatory clock.
+type AfterActionMilestone struct {
+	Name                 string     `json:"name"`
+	At                   *time.Time `json:"at,omitempty"`
+	MinutesFromDetection *float64   `json:"minutesFromDetection,omitempty"`
+	MinutesFromPrevious  *float64   `json:"minutesFromPrevious,omitempty"`
+	Deadline             *time.Time `json:"deadline,omitempty"`
+	MetDeadline          *bool      `json:"metDeadline,omitempty"`
+}
+
+// AfterActionStage is where a workflow stage ended up
+type AfterActionStage struct {
+	Stage                string     `json:"stage"`
+	Status               string     `json:"status"`
+	CompletedAt          *time.Time `json:"completedAt,omitempty"`
+	MinutesFromDetection *float64   `json:"minutesFromDetection,omitempty"`
+}
+
+func minutesBetween(from, to time.Time) *float64 {
+	m := to.Sub(from).Minutes()
+	m = float64(int64(m*10+0.5)) / 10
+	return &m
+}
+
+// AddMilestone appends a milestone, timing it from detection and from the
+// previous milestone that was reached, and judging it against its deadline
+// as of now. A nil at means the step was not reached.
+func (r *AfterActionReport) AddMilestone(name string, at, deadline *time.Time, now time.Time) {
+	m := AfterActionMilestone{Name: name, At: at, Deadline: deadline}
+	if at != nil {
+		m.MinutesFromDetection = minutesBetween(r.DetectedAt, *at)
+		for i := len(r.Milestones) - 1; i >= 0; i-- {
+			if prev := r.Milestones[i].At; prev != nil {
+				m.MinutesFromPrevious = minutesBetween(*prev, *at)
+				break
+			}
+		}
+	}
+	if deadline != nil {
+		switch {
+		case at != nil:
+			met := !at.After(*deadline)
+			m.MetDeadline = &met
+			if !met {
+				r.Findings = append(r.Findings, fmt.Sprintf("%s was %s past its deadline", name, at.Sub(*deadline).Round(time.Minute)))
+			}
+		case now.After(*deadline):
+			met := false
+			m.MetDeadline = &met
+			r.Findings = append(r.Findings, fmt.Sprintf("%s was not reached before its deadline", name))
+		}
+	} else if at == nil {
+		r.Findings = append(r.Findings, fmt.Sprintf("%s was not reached", name))
+	}
+	r.Milestones = append(r.Milestones, m)
+}
+
+// AddStage records a workflow stage's outcome
+func (r *AfterActionReport) AddStage(stage, status string, completedAt *time.Time) {
+	s := AfterActionStage{Stage: stage, Status: status, CompletedAt: completedAt}
+	if completedAt != nil {
+		s.MinutesFromDetection = minutesBetween(r.DetectedAt, *completedAt)
+	}
+	r.Stages = append(r.Stages, s)
+}
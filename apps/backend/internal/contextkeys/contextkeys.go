@@ -8,5 +8,8 @@ const (
 	UserClaimsKey      contextKey = "userClaims"
 	APIKeyClaimsKey    contextKey = "apiKeyClaims"
 	TenantIDKey        contextKey = "tenantID"
+	NomineeClaimKey    contextKey = "nomineeClaim"
+	VendorSessionKey   contextKey = "vendorSession"
+	DPASignerKey       contextKey = "dpaSigner"
 )
 
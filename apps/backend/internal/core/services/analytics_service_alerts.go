@@ -83,6 +83,34 @@ func (s *AnalyticsService) GenerateAlertsComplete(dashboard *models.AnalyticsDas
 		})
 	}
 
+	// Check Significant Data Fiduciary obligations
+	if sdf := dashboard.SDFAnalytics; sdf != nil && sdf.Designated {
+		if sdf.OverdueObligations > 0 {
+			alerts = append(alerts, models.AnalyticsAlert{
+				ID:          uuid.New(),
+				Type:        "critical",
+				Category:    "sdf",
+				Message:     fmt.Sprintf("%d Significant Data Fiduciary obligation(s) overdue", sdf.OverdueObligations),
+				Metric:      "sdf_overdue_obligations",
+				Threshold:   0,
+				ActualValue: float64(sdf.OverdueObligations),
+				CreatedAt:   time.Now(),
+			})
+		}
+		if !sdf.DPOAppointed || !sdf.DPOInIndia {
+			alerts = append(alerts, models.AnalyticsAlert{
+				ID:          uuid.New(),
+				Type:        "critical",
+				Category:    "sdf",
+				Message:     "No India-based Data Protection Officer is appointed",
+				Metric:      "sdf_dpo_in_india",
+				Threshold:   1,
+				ActualValue: 0,
+				CreatedAt:   time.Now(),
+			})
+		}
+	}
+
 	return alerts
 }
 
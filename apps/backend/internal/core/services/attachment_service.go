@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/db"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AttachmentScopeGrievance = "grievance"
+	AttachmentScopeDSR       = "dsr"
+
+	maxAttachmentSize      = 10 << 20
+	maxAttachmentsPerScope = 20
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentForbidden = errors.New("not allowed to access this attachment")
+	ErrInvalidAttachment   = errors.New("invalid attachment")
+)
+
+func invalidAttachment(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidAttachment}, args...)...)
+}
+
+// attachmentTypes is the upload allowlist, mapping each accepted content type
+// to what http.DetectContentType must report for the file's actual bytes.
+var attachmentTypes = map[string]string{
+	"application/pdf": "application/pdf",
+	"image/png":       "image/png",
+	"image/jpeg":      "image/jpeg",
+	"image/gif":       "image/gif",
+	"image/webp":      "image/webp",
+	"text/plain":      "text/plain",
+	"text/csv":        "text/plain",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "application/zip",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       "application/zip",
+}
+
+// MalwareScanner is the hook for scanning uploads before they are stored, e.g.
+// a ClamAV daemon. Infected files are rejected; a scanner error rejects the
+// upload so nothing unscanned slips through while the scanner is down.
+type MalwareScanner interface {
+	Scan(ctx context.Context, fileName string, data []byte) (clean bool, detail string, err error)
+}
+
+// attachmentManagePermissions lets staff holding the permission reach items in
+// that scope that are not assigned to them.
+var attachmentManagePermissions = map[string]string{
+	AttachmentScopeGrievance: "grievances:manage",
+	AttachmentScopeDSR:       "consents:update",
+}
+
+// AttachmentViewer identifies who is uploading or downloading. Exactly one of
+// PrincipalID and StaffID is set.
+type AttachmentViewer struct {
+	TenantID     uuid.UUID
+	PrincipalID  *uuid.UUID
+	StaffID      *uuid.UUID
+	Permissions  map[string]bool
+	IsSuperAdmin bool
+}
+
+// AttachmentUpload is a file to attach to a grievance, DSR or one of their comments.
+type AttachmentUpload struct {
+	Scope       string
+	ParentID    uuid.UUID
+	CommentID   *uuid.UUID
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// AttachmentService stores evidence files on grievances and DSRs and enforces
+// who may see them: the principal who owns the item and the staff assigned to it.
+type AttachmentService struct {
+	repo    *repository.AttachmentRepository
+	store   *blob.Store
+	scanner MalwareScanner
+
+	// tenantDB resolves a tenant's database for grievance lookups; replaced in tests
+	tenantDB func(tenantID uuid.UUID) (*gorm.DB, error)
+}
+
+func NewAttachmentService(repo *repository.AttachmentRepository, store *blob.Store, scanner MalwareScanner) *AttachmentService {
+	return &AttachmentService{
+		repo:    repo,
+		store:   store,
+		scanner: scanner,
+		tenantDB: func(tenantID uuid.UUID) (*gorm.DB, error) {
+			return db.GetTenantDB("tenant_" + tenantID.String()[:8])
+		},
+	}
+}
+
+// checkAttachmentFile validates size and type and returns the content type to
+// store. The declared type must be on the allowlist and match the file's bytes,
+// so an executable renamed to .pdf is refused.
+func checkAttachmentFile(fileName, contentType string, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", invalidAttachment("file is empty")
+	}
+	if len(data) > maxAttachmentSize {
+		return "", invalidAttachment("file exceeds the 10MB limit")
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))))
+	}
+	expected, ok := attachmentTypes[mediaType]
+	if !ok {
+		return "", invalidAttachment("file type %q is not allowed", mediaType)
+	}
+	if sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data)); sniffed != expected {
+		return "", invalidAttachment("file content does not match type %q", mediaType)
+	}
+	return mediaType, nil
+}
+
+// attachmentParent is the access-relevant part of a grievance or DSR.
+type attachmentParent struct {
+	tenantID    uuid.UUID
+	scope       string
+	principalID uuid.UUID
+	assignedTo  *uuid.UUID
+}
+
+func (s *AttachmentService) loadParent(ctx context.Context, scope string, parentID, tenantID uuid.UUID) (*attachmentParent, error) {
+	switch scope {
+	case AttachmentScopeDSR:
+		req, err := s.repo.GetDSRRequest(parentID)
+		if err != nil {
+			return nil, ErrAttachmentNotFound
+		}
+		return &attachmentParent{scope: scope, tenantID: req.TenantID, principalID: req.UserID, assignedTo: req.AssignedTo}, nil
+	case AttachmentScopeGrievance:
+		tdb, err := s.tenantDB(tenantID)
+		if err != nil {
+			return nil, err
+		}
+		g, err := repository.NewGrievanceRepo(tdb).GetByID(ctx, parentID.String())
+		if err != nil {
+			return nil, ErrAttachmentNotFound
+		}
+		return &attachmentParent{scope: scope, tenantID: g.TenantID, principalID: g.UserID, assignedTo: g.AssignedTo}, nil
+	}
+	return nil, invalidAttachment("unknown scope %q", scope)
+}
+
+func (p *attachmentParent) allows(v AttachmentViewer) bool {
+	if p.tenantID != v.TenantID {
+		return false
+	}
+	if v.PrincipalID != nil {
+		return *v.PrincipalID == p.principalID
+	}
+	if v.StaffID != nil {
+		if p.assignedTo != nil && *p.assignedTo == *v.StaffID {
+			return true
+		}
+		return v.IsSuperAdmin || v.Permissions[attachmentManagePermissions[p.scope]]
+	}
+	return false
+}
+
+// checkComment verifies the comment belongs to the parent and reports whether
+// it is internal. Principals cannot attach to internal comments.
+func (s *AttachmentService) checkComment(ctx context.Context, up AttachmentUpload, v AttachmentViewer) (bool, error) {
+	if up.CommentID == nil {
+		return false, nil
+	}
+	switch up.Scope {
+	case AttachmentScopeDSR:
+		c, err := s.repo.GetDSRComment(*up.CommentID)
+		if err != nil || c.RequestID != up.ParentID {
+			return false, invalidAttachment("comment not found on this request")
+		}
+		if c.IsInternal && v.PrincipalID != nil {
+			return false, ErrAttachmentForbidden
+		}
+		return c.IsInternal, nil
+	default:
+		tdb, err := s.tenantDB(v.TenantID)
+		if err != nil {
+			return false, err
+		}
+		c, err := repository.NewGrievanceRepo(tdb).GetComment(ctx, up.CommentID.String())
+		if err != nil || c.GrievanceID != up.ParentID {
+			return false, invalidAttachment("comment not found on this grievance")
+		}
+		return false, nil
+	}
+}
+
+// Upload validates, scans and stores a file against a grievance or DSR.
+func (s *AttachmentService) Upload(ctx context.Context, up AttachmentUpload, v AttachmentViewer) (*models.Attachment, error) {
+	if s.store == nil {
+		return nil, errors.New("file uploads are not configured")
+	}
+	parent, err := s.loadParent(ctx, up.Scope, up.ParentID, v.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.allows(v) {
+		return nil, ErrAttachmentForbidden
+	}
+	internal, err := s.checkComment(ctx, up, v)
+	if err != nil {
+		return nil, err
+	}
+	contentType, err := checkAttachmentFile(up.FileName, up.ContentType, up.Data)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := s.repo.CountByParent(up.Scope, up.ParentID); err != nil {
+		return nil, err
+	} else if n >= maxAttachmentsPerScope {
+		return nil, invalidAttachment("no more than %d files can be attached", maxAttachmentsPerScope)
+	}
+
+	sum := sha256.Sum256(up.Data)
+	a := &models.Attachment{
+		ID:          uuid.New(),
+		TenantID:    parent.tenantID,
+		Scope:       up.Scope,
+		ParentID:    up.ParentID,
+		CommentID:   up.CommentID,
+		IsInternal:  internal,
+		FileName:    blob.SafeName(up.FileName),
+		ContentType: contentType,
+		SizeBytes:   int64(len(up.Data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		ScanStatus:  "not_scanned",
+	}
+	if v.PrincipalID != nil {
+		a.UploadedBy, a.UploaderType = *v.PrincipalID, "principal"
+	} else {
+		a.UploadedBy, a.UploaderType = *v.StaffID, "staff"
+	}
+
+	if s.scanner != nil {
+		clean, detail, err := s.scanner.Scan(ctx, a.FileName, up.Data)
+		if err != nil {
+			log.Logger.Error().Err(err).Str("file", a.FileName).Msg("attachment scan failed")
+			return nil, errors.New("file could not be scanned, please try again later")
+		}
+		if !clean {
+			log.Logger.Warn().Str("file", a.FileName).Str("sha256", a.SHA256).Str("detail", detail).Msg("rejected infected attachment")
+			return nil, invalidAttachment("file failed the malware scan")
+		}
+		now := time.Now()
+		a.ScanStatus, a.ScannedAt = "clean", &now
+	}
+
+	key := fmt.Sprintf("attachments/%s/%s/%s/%s_%s", a.TenantID, a.Scope, a.ParentID, a.ID, a.FileName)
+	path, err := s.store.Put(key, contentType, up.Data)
+	if err != nil {
+		return nil, err
+	}
+	a.FilePath = path
+	if err := s.repo.Create(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// List returns the attachments on a grievance or DSR visible to the viewer.
+func (s *AttachmentService) List(ctx context.Context, scope string, parentID uuid.UUID, v AttachmentViewer) ([]models.Attachment, error) {
+	parent, err := s.loadParent(ctx, scope, parentID, v.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.allows(v) {
+		return nil, ErrAttachmentForbidden
+	}
+	return s.repo.ListByParent(scope, parentID, v.StaffID != nil)
+}
+
+// Download returns an attachment's metadata and content after the access check.
+func (s *AttachmentService) Download(ctx context.Context, id uuid.UUID, v AttachmentViewer) (*models.Attachment, []byte, error) {
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	parent, err := s.loadParent(ctx, a.Scope, a.ParentID, a.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parent.allows(v) || (a.IsInternal && v.PrincipalID != nil) {
+		return nil, nil, ErrAttachmentForbidden
+	}
+	data, err := s.store.Get(a.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, data, nil
+}
+
+// ForExport returns the principal-visible attachments on a DSR with their
+// content, for inclusion in the result package.
+func (s *AttachmentService) ForExport(requestID uuid.UUID) (map[string][]byte, error) {
+	list, err := s.repo.ListByParent(AttachmentScopeDSR, requestID, false)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(list))
+	for _, a := range list {
+		data, err := s.store.Get(a.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", a.ID, err)
+		}
+		files[a.ID.String()[:8]+"_"+a.FileName] = data
+	}
+	return files, nil
+}
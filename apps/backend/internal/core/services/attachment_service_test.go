@@ -0,0 +1,188 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/jwtlink"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type stubScanner struct{ infected bool }
+
+func (s stubScanner) Scan(_ context.Context, _ string, _ []byte) (bool, string, error) {
+	if s.infected {
+		return false, "Eicar-Test-Signature", nil
+	}
+	return true, "", nil
+}
+
+func setupAttachments(t *testing.T, scanner MalwareScanner) (*AttachmentService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DataPrincipal{}, &models.DSRRequest{}, &models.DSRComment{}, &models.DSREvent{},
+		&models.Attachment{}, &models.Grievance{}, &models.GrievanceComment{}))
+	store := blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false)
+	svc := NewAttachmentService(repository.NewAttachmentRepository(db), store, scanner)
+	svc.tenantDB = func(uuid.UUID) (*gorm.DB, error) { return db, nil }
+	return svc, db
+}
+
+var pdfBytes = []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n")
+
+func TestCheckAttachmentFile_AllowlistAndSniffing(t *testing.T) {
+	ct, err := checkAttachmentFile("id.pdf", "application/pdf", pdfBytes)
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", ct)
+
+	// Browsers often send octet-stream; the extension decides
+	ct, err = checkAttachmentFile("notes.txt", "application/octet-stream", []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", ct)
+
+	_, err = checkAttachmentFile("setup.exe", "application/x-msdownload", []byte("MZ\x90\x00"))
+	assert.ErrorIs(t, err, ErrInvalidAttachment)
+
+	_, err = checkAttachmentFile("invoice.pdf", "application/pdf", []byte("MZ\x90\x00\x03\x00\x00\x00"))
+	assert.ErrorIs(t, err, ErrInvalidAttachment, "an executable renamed to .pdf is refused")
+
+	_, err = checkAttachmentFile("big.txt", "text/plain", bytes.Repeat([]byte("a"), maxAttachmentSize+1))
+	assert.ErrorIs(t, err, ErrInvalidAttachment)
+}
+
+func TestAttachments_DSRAccessChecks(t *testing.T) {
+	svc, db := setupAttachments(t, stubScanner{})
+	ctx := context.Background()
+	tenantID, principalID, assignee, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	req := models.DSRRequest{ID: uuid.New(), UserID: principalID, TenantID: tenantID, Type: "access", Status: "Pending", AssignedTo: &assignee, RequestedAt: time.Now()}
+	require.NoError(t, db.Create(&req).Error)
+
+	owner := AttachmentViewer{TenantID: tenantID, PrincipalID: &principalID}
+	a, err := svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeDSR, ParentID: req.ID, FileName: "id.pdf", ContentType: "application/pdf", Data: pdfBytes}, owner)
+	require.NoError(t, err)
+	assert.Len(t, a.SHA256, 64)
+	assert.Equal(t, "clean", a.ScanStatus)
+	assert.Equal(t, "principal", a.UploaderType)
+
+	_, data, err := svc.Download(ctx, a.ID, owner)
+	require.NoError(t, err)
+	assert.Equal(t, pdfBytes, data)
+
+	stranger := AttachmentViewer{TenantID: tenantID, PrincipalID: &other}
+	_, _, err = svc.Download(ctx, a.ID, stranger)
+	assert.ErrorIs(t, err, ErrAttachmentForbidden)
+
+	_, _, err = svc.Download(ctx, a.ID, AttachmentViewer{TenantID: tenantID, StaffID: &assignee})
+	assert.NoError(t, err, "assigned staff can download")
+
+	_, _, err = svc.Download(ctx, a.ID, AttachmentViewer{TenantID: tenantID, StaffID: &other})
+	assert.ErrorIs(t, err, ErrAttachmentForbidden, "unassigned staff cannot download")
+
+	_, _, err = svc.Download(ctx, a.ID, AttachmentViewer{TenantID: tenantID, StaffID: &other, Permissions: map[string]bool{"consents:update": true}})
+	assert.NoError(t, err, "DSR managers can download")
+
+	_, _, err = svc.Download(ctx, a.ID, AttachmentViewer{TenantID: tenantID, StaffID: &other, Permissions: map[string]bool{"roles:manage": true}})
+	assert.ErrorIs(t, err, ErrAttachmentForbidden, "role administrators do not handle DSRs")
+
+	_, _, err = svc.Download(ctx, a.ID, AttachmentViewer{TenantID: uuid.New(), StaffID: &assignee, IsSuperAdmin: true})
+	assert.ErrorIs(t, err, ErrAttachmentForbidden, "other tenants never see the file")
+}
+
+func TestAttachments_InternalCommentsAndExport(t *testing.T) {
+	jwtlink.Init("test-secret")
+	svc, db := setupAttachments(t, nil)
+	ctx := context.Background()
+	tenantID, principalID, staffID := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.DataPrincipal{ID: principalID, TenantID: tenantID, Email: "asha@example.com"}).Error)
+	req := models.DSRRequest{ID: uuid.New(), UserID: principalID, TenantID: tenantID, Type: "access", Status: "Pending", AssignedTo: &staffID, RequestedAt: time.Now()}
+	require.NoError(t, db.Create(&req).Error)
+
+	dsrRepo := repository.NewDSRRepository(db, nil)
+	dsrService := NewDSRService(dsrRepo, nil, nil, nil)
+	note := &models.DSRComment{RequestID: req.ID, AuthorID: staffID, Content: "Checked ID against CRM", IsInternal: true}
+	reply := &models.DSRComment{RequestID: req.ID, AuthorID: principalID, AuthorType: "principal", Content: "Here is my ID", IsInternal: false}
+	require.NoError(t, dsrService.AddComment(note))
+	require.NoError(t, dsrService.AddComment(reply))
+
+	visible, err := dsrService.GetComments(req.ID, false)
+	require.NoError(t, err)
+	require.Len(t, visible, 1)
+	assert.Equal(t, reply.ID, visible[0].ID)
+
+	staff := AttachmentViewer{TenantID: tenantID, StaffID: &staffID}
+	owner := AttachmentViewer{TenantID: tenantID, PrincipalID: &principalID}
+	internal, err := svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeDSR, ParentID: req.ID, CommentID: &note.ID, FileName: "crm.txt", ContentType: "text/plain", Data: []byte("crm match")}, staff)
+	require.NoError(t, err)
+	assert.True(t, internal.IsInternal)
+	assert.Equal(t, "not_scanned", internal.ScanStatus)
+
+	_, err = svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeDSR, ParentID: req.ID, CommentID: &note.ID, FileName: "x.txt", ContentType: "text/plain", Data: []byte("x")}, owner)
+	assert.ErrorIs(t, err, ErrAttachmentForbidden, "principals cannot attach to internal notes")
+
+	_, err = svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeDSR, ParentID: req.ID, CommentID: &reply.ID, FileName: "id.pdf", ContentType: "application/pdf", Data: pdfBytes}, owner)
+	require.NoError(t, err)
+
+	list, err := svc.List(ctx, AttachmentScopeDSR, req.ID, owner)
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "internal attachments are hidden from the principal")
+	_, _, err = svc.Download(ctx, internal.ID, owner)
+	assert.ErrorIs(t, err, ErrAttachmentForbidden)
+
+	list, err = svc.List(ctx, AttachmentScopeDSR, req.ID, staff)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// The result package carries the principal-visible files only
+	tracker := NewDSRTrackingService(dsrRepo, nil, nil, svc, "https://portal.example.com")
+	req.Status = "Completed"
+	pkg, err := tracker.ResultPackage(&req)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	require.NoError(t, err)
+	var files []string
+	for _, f := range zr.File {
+		if len(f.Name) > 12 && f.Name[:12] == "attachments/" {
+			files = append(files, f.Name)
+		}
+	}
+	require.Len(t, files, 1)
+	assert.Contains(t, files[0], "id.pdf")
+}
+
+func TestAttachments_GrievanceScopeAndScanner(t *testing.T) {
+	svc, db := setupAttachments(t, stubScanner{infected: true})
+	ctx := context.Background()
+	tenantID, principalID := uuid.New(), uuid.New()
+	g := models.Grievance{ID: uuid.New(), UserID: principalID, TenantID: tenantID, Status: "open", CreatedAt: time.Now()}
+	require.NoError(t, db.Create(&g).Error)
+	owner := AttachmentViewer{TenantID: tenantID, PrincipalID: &principalID}
+
+	_, err := svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeGrievance, ParentID: g.ID, FileName: "shot.txt", ContentType: "text/plain", Data: []byte("screenshot")}, owner)
+	assert.True(t, errors.Is(err, ErrInvalidAttachment), "infected files are rejected")
+
+	svc.scanner = stubScanner{}
+	comment := models.GrievanceComment{ID: uuid.New(), GrievanceID: g.ID, UserID: principalID, Comment: "see attached"}
+	require.NoError(t, db.Create(&comment).Error)
+	a, err := svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeGrievance, ParentID: g.ID, CommentID: &comment.ID, FileName: "../../etc/shot.txt", ContentType: "text/plain", Data: []byte("screenshot")}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, "shot.txt", a.FileName)
+
+	wrong := uuid.New()
+	_, err = svc.Upload(ctx, AttachmentUpload{Scope: AttachmentScopeGrievance, ParentID: g.ID, CommentID: &wrong, FileName: "a.txt", ContentType: "text/plain", Data: []byte("a")}, owner)
+	assert.ErrorIs(t, err, ErrInvalidAttachment)
+
+	staffID := uuid.New()
+	_, err = svc.List(ctx, AttachmentScopeGrievance, g.ID, AttachmentViewer{TenantID: tenantID, StaffID: &staffID, Permissions: map[string]bool{"consents:update": true}})
+	assert.ErrorIs(t, err, ErrAttachmentForbidden, "DSR managers have no say over grievances")
+}
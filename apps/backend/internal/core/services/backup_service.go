@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,20 +11,19 @@ import (
 	"time"
 
 	"pixpivot/arc/config"
+	"pixpivot/arc/internal/models"
 	"pixpivot/arc/pkg/log"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
 type BackupService struct {
 	MasterDB *gorm.DB
 	Cfg      config.Config
-	Cron     *cron.Cron
 	S3Client *s3.S3
 }
 
@@ -43,50 +44,37 @@ func NewBackupService(masterDB *gorm.DB, cfg config.Config) *BackupService {
 	return &BackupService{
 		MasterDB: masterDB,
 		Cfg:      cfg,
-		Cron:     cron.New(),
 		S3Client: s3.New(sess),
 	}
 }
 
-func (s *BackupService) Start() {
-	// Schedule backups based on config or default
-	// Default: Daily at 2 AM
-	_, err := s.Cron.AddFunc("0 2 * * *", func() {
-		s.PerformBackup("daily")
+// RegisterJobs schedules the backups on the job runner
+func (s *BackupService) RegisterJobs(r *JobRunner) {
+	r.Register("backup", func(ctx context.Context, job *models.Job) error {
+		var p struct {
+			Tag string `json:"tag"`
+		}
+		if err := DecodeJobPayload(job, &p); err != nil {
+			return err
+		}
+		return s.PerformBackup(p.Tag)
 	})
-	if err != nil {
-		log.Logger.Error().Err(err).Msg("Failed to schedule daily backup")
-	}
-
-	// Weekly: Sunday at 3 AM
-	_, err = s.Cron.AddFunc("0 3 * * 0", func() {
-		s.PerformBackup("weekly")
-	})
-	if err != nil {
-		log.Logger.Error().Err(err).Msg("Failed to schedule weekly backup")
-	}
-
-	// Monthly: 1st of month at 4 AM
-	_, err = s.Cron.AddFunc("0 4 1 * *", func() {
-		s.PerformBackup("monthly")
-	})
-	if err != nil {
-		log.Logger.Error().Err(err).Msg("Failed to schedule monthly backup")
-	}
-
-	s.Cron.Start()
-	log.Logger.Info().Msg("Backup service started")
+	// Daily at 2 AM, weekly on Sunday at 3 AM, monthly on the 1st at 4 AM
+	r.Schedule("backup.daily", "0 2 * * *", "backup", map[string]string{"tag": "daily"})
+	r.Schedule("backup.weekly", "0 3 * * 0", "backup", map[string]string{"tag": "weekly"})
+	r.Schedule("backup.monthly", "0 4 1 * *", "backup", map[string]string{"tag": "monthly"})
 }
 
-func (s *BackupService) Stop() {
-	s.Cron.Stop()
-}
-
-func (s *BackupService) PerformBackup(tag string) {
+// PerformBackup dumps the master and every tenant database to S3. A failed
+// database does not stop the others; the failures are returned together.
+func (s *BackupService) PerformBackup(tag string) error {
 	log.Logger.Info().Str("type", tag).Msg("Starting backup sequence")
 
 	// 1. Backup Global DB (Master)
-	s.backupDatabase(s.Cfg.DBName, tag)
+	var errs []error
+	if err := s.backupDatabase(s.Cfg.DBName, tag); err != nil {
+		errs = append(errs, err)
+	}
 
 	// 2. Get list of Tenant DBs
 	var tenants []struct {
@@ -95,16 +83,19 @@ func (s *BackupService) PerformBackup(tag string) {
 	// Assuming "tenants" table exists in MasterDB and has "tenant_id" column
 	if err := s.MasterDB.Table("tenants").Select("tenant_id").Scan(&tenants).Error; err != nil {
 		log.Logger.Error().Err(err).Msg("Failed to fetch tenants for backup")
-		return
+		return errors.Join(append(errs, fmt.Errorf("failed to fetch tenants: %w", err))...)
 	}
 
 	for _, t := range tenants {
 		dbName := "tenant_" + strings.ReplaceAll(t.TenantID, "-", "")
-		s.backupDatabase(dbName, tag)
+		if err := s.backupDatabase(dbName, tag); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
 }
 
-func (s *BackupService) backupDatabase(dbName, tag string) {
+func (s *BackupService) backupDatabase(dbName, tag string) error {
 	log.Logger.Info().Str("db", dbName).Msg("Backing up database")
 	
 	timestamp := time.Now().Format("20060102_150405")
@@ -122,14 +113,14 @@ func (s *BackupService) backupDatabase(dbName, tag string) {
 
 	if output, err := cmd.CombinedOutput(); err != nil {
 		log.Logger.Error().Err(err).Str("db", dbName).Str("output", string(output)).Msg("pg_dump failed")
-		return
+		return fmt.Errorf("pg_dump of %s failed: %w", dbName, err)
 	}
 
 	// Upload to S3
 	file, err := os.Open(filepath)
 	if err != nil {
 		log.Logger.Error().Err(err).Msg("Failed to open backup file")
-		return
+		return fmt.Errorf("failed to open backup of %s: %w", dbName, err)
 	}
 	defer file.Close()
 	defer os.Remove(filepath)
@@ -142,13 +133,18 @@ func (s *BackupService) backupDatabase(dbName, tag string) {
 	})
 	if err != nil {
 		log.Logger.Error().Err(err).Msg("Failed to upload backup to S3")
-		return
+		return fmt.Errorf("failed to upload backup of %s: %w", dbName, err)
 	}
 	log.Logger.Info().Str("db", dbName).Msg("Backup uploaded")
+	return nil
 }
 
 // ManualBackup triggers a backup immediately
 func (s *BackupService) ManualBackup() {
-	go s.PerformBackup("manual")
+	go func() {
+		if err := s.PerformBackup("manual"); err != nil {
+			log.Logger.Error().Err(err).Msg("Manual backup failed")
+		}
+	}()
 }
 
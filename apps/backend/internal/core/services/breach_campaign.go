@@ -0,0 +1,729 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/realtime"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCampaignNotFound        = errors.New("campaign not found")
+	ErrInvalidCampaign         = errors.New("invalid campaign")
+	ErrNotificationNotApproved = errors.New("data principal notification must be approved first")
+)
+
+// Campaign channels
+const (
+	CampaignChannelEmail = "email"
+	CampaignChannelSMS   = "sms"
+	CampaignChannelInApp = "in_app"
+)
+
+// campaignRetryBackoff is the wait before each retry of a failed send; a
+// message that fails once more after the last wait is marked failed
+var campaignRetryBackoff = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// campaignStaleAfter is how long a message may sit in sending before it is
+// assumed lost with a crashed worker and queued again
+const campaignStaleAfter = 10 * time.Minute
+
+// BreachCampaignLimits caps how many messages each channel sends per minute
+type BreachCampaignLimits map[string]int
+
+// CampaignContent is the rendered notice for one channel
+type CampaignContent struct {
+	TemplateName string `json:"templateName"`
+	Subject      string `json:"subject,omitempty"`
+	Body         string `json:"body"`
+	SHA256       string `json:"sha256"`
+}
+
+type campaignEmailSender interface {
+	SendTracked(to, subject, body, messageID string) error
+}
+
+type campaignSMSSender interface {
+	Send(to, body string) (string, error)
+}
+
+// BreachCampaignService runs breach notification campaigns: it queues one
+// message per recipient and channel, sends them at each channel's rate limit
+// with retries, tracks delivery and bounce reports, keeps the breach's
+// notified count current and produces a signed delivery proof.
+type BreachCampaignService struct {
+	repo         *repository.BreachCampaignRepository
+	breachRepo   *repository.BreachNotificationRepository
+	impact       *BreachImpactService
+	templateRepo *repository.BreachNotificationTemplateRepository
+	tenantRepo   *repository.TenantRepository
+	timelineRepo *repository.BreachTimelineRepository
+	notifRepo    *repository.NotificationRepo
+	sink         *repository.BreachSimulationRepository
+	hub          *realtime.Hub
+	email        campaignEmailSender
+	sms          campaignSMSSender
+	signer       *DocumentSigner
+	limits       BreachCampaignLimits
+	running      sync.Mutex
+}
+
+func NewBreachCampaignService(
+	repo *repository.BreachCampaignRepository,
+	breachRepo *repository.BreachNotificationRepository,
+	impact *BreachImpactService,
+	templateRepo *repository.BreachNotificationTemplateRepository,
+	tenantRepo *repository.TenantRepository,
+	timelineRepo *repository.BreachTimelineRepository,
+	notifRepo *repository.NotificationRepo,
+	sink *repository.BreachSimulationRepository,
+	hub *realtime.Hub,
+	email *EmailService,
+	sms *SMSService,
+	signer *DocumentSigner,
+	limits BreachCampaignLimits,
+) *BreachCampaignService {
+	return &BreachCampaignService{
+		repo:         repo,
+		breachRepo:   breachRepo,
+		impact:       impact,
+		templateRepo: templateRepo,
+		tenantRepo:   tenantRepo,
+		timelineRepo: timelineRepo,
+		notifRepo:    notifRepo,
+		sink:         sink,
+		hub:          hub,
+		email:        email,
+		sms:          sms,
+		signer:       signer,
+		limits:       limits,
+	}
+}
+
+// RegisterJobs runs the send queue every minute; each run sends up to each
+// channel's per-minute limit.
+func (s *BreachCampaignService) RegisterJobs(r *JobRunner) {
+	r.Register("breach.campaigns", func(ctx context.Context, job *models.Job) error {
+		if n := s.Run(time.Now()); n > 0 {
+			log.Logger.Info().Int("messages", n).Msg("Breach notification campaign run complete")
+		}
+		return nil
+	})
+	r.Schedule("breach.campaigns", "* * * * *", "breach.campaigns", nil)
+}
+
+func (s *BreachCampaignService) breachForTenant(tenantID, breachID uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return b, nil
+}
+
+// templateValues fills the placeholders the breach alone cannot: who the
+// fiduciary is and how to reach its DPO
+func (s *BreachCampaignService) templateValues(b *models.BreachNotification) map[string]string {
+	values := map[string]string{
+		"breach_id":          b.ID.String(),
+		"data_categories":    strings.Join(jsonStrings(b.DataCategoriesAffected), ", "),
+		"impact_description": b.ImpactOnDataPrincipals,
+		"remedial_actions":   strings.Join(jsonStrings(b.RemedialActions), "; "),
+	}
+	if b.ContainmentDate != nil {
+		values["containment_date"] = b.ContainmentDate.Format("2006-01-02")
+	}
+	if tenant, err := s.tenantRepo.GetByID(b.TenantID); err == nil {
+		values["company_name"] = tenant.Name
+		values["organization_name"] = tenant.Name
+		var cfg map[string]interface{}
+		if json.Unmarshal(tenant.Config, &cfg) == nil {
+			for placeholder, key := range map[string]string{"dpo_name": "dpoName", "dpo_email": "dpoEmail", "dpo_phone": "dpoPhone"} {
+				if v, ok := cfg[key].(string); ok {
+					values[placeholder] = v
+				}
+			}
+			if values["dpo_email"] == "" {
+				if v, ok := cfg["contactEmail"].(string); ok {
+					values["dpo_email"] = v
+				}
+			}
+		}
+	}
+	return values
+}
+
+// render builds each channel's notice from the data principal templates. In-app
+// notices reuse the email subject with the short SMS text.
+func (s *BreachCampaignService) render(b *models.BreachNotification, channels []string) (map[string]CampaignContent, error) {
+	values := s.templateValues(b)
+	emailTpl, err := s.templateRepo.GetTemplate("data_principal_notification_template", "data_principal")
+	if err != nil {
+		return nil, fmt.Errorf("data principal notification template not found: %w", err)
+	}
+	smsTpl, err := s.templateRepo.GetTemplate("data_principal_notification_sms", "data_principal")
+	if err != nil {
+		return nil, fmt.Errorf("data principal SMS template not found: %w", err)
+	}
+
+	content := map[string]CampaignContent{}
+	for _, ch := range channels {
+		var c CampaignContent
+		switch ch {
+		case CampaignChannelEmail:
+			c = CampaignContent{TemplateName: emailTpl.TemplateName, Subject: renderBreachTemplate(emailTpl.Subject, b, values), Body: renderBreachTemplate(emailTpl.Body, b, values)}
+		case CampaignChannelSMS:
+			c = CampaignContent{TemplateName: smsTpl.TemplateName, Body: renderBreachTemplate(smsTpl.Body, b, values)}
+		case CampaignChannelInApp:
+			c = CampaignContent{TemplateName: smsTpl.TemplateName, Subject: renderBreachTemplate(emailTpl.Subject, b, values), Body: renderBreachTemplate(smsTpl.Body, b, values)}
+		}
+		sum := sha256.Sum256([]byte(c.Subject + "\n" + c.Body))
+		c.SHA256 = hex.EncodeToString(sum[:])
+		content[ch] = c
+	}
+	return content, nil
+}
+
+// Launch queues a campaign to everyone on a recipient list; a nil listID
+// uses the breach's latest list. Notification must have been approved.
+func (s *BreachCampaignService) Launch(tenantID, breachID, listID uuid.UUID, channels []string, createdBy uuid.UUID) (*models.BreachNotificationCampaign, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if !b.DataPrincipalNotificationApproved {
+		return nil, ErrNotificationNotApproved
+	}
+
+	seen := map[string]bool{}
+	var chans []string
+	for _, ch := range channels {
+		switch ch {
+		case CampaignChannelEmail, CampaignChannelSMS, CampaignChannelInApp:
+		default:
+			return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidCampaign, ch)
+		}
+		if !seen[ch] {
+			seen[ch] = true
+			chans = append(chans, ch)
+		}
+	}
+	if len(chans) == 0 {
+		return nil, fmt.Errorf("%w: at least one channel is required", ErrInvalidCampaign)
+	}
+
+	list, contacts, err := s.impact.RecipientContacts(tenantID, breachID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return nil, fmt.Errorf("%w: recipient list v%d is empty", ErrInvalidCampaign, list.Version)
+	}
+	content, err := s.render(b, chans)
+	if err != nil {
+		return nil, err
+	}
+	contentJSON, _ := json.Marshal(content)
+	chansJSON, _ := json.Marshal(chans)
+
+	now := time.Now()
+	campaign := &models.BreachNotificationCampaign{
+		ID:                   uuid.New(),
+		BreachID:             b.ID,
+		TenantID:             tenantID,
+		RecipientListID:      list.ID,
+		RecipientListVersion: list.Version,
+		Channels:             chansJSON,
+		Content:              contentJSON,
+		Status:               "queued",
+		CreatedBy:            createdBy,
+		IsSimulation:         b.IsSimulation,
+	}
+	var messages []models.BreachCommunication
+	for _, c := range contacts {
+		principalID := c.DataPrincipalID
+		for _, ch := range chans {
+			to := c.Email
+			switch ch {
+			case CampaignChannelSMS:
+				to = c.Phone
+			case CampaignChannelInApp:
+				to = principalID.String()
+			}
+			if to == "" {
+				campaign.Skipped++
+				continue
+			}
+			messages = append(messages, models.BreachCommunication{
+				ID:                uuid.New(),
+				BreachID:          b.ID,
+				TenantID:          tenantID,
+				CommunicationType: "initial_notification",
+				Recipient:         to,
+				RecipientType:     "data_principal",
+				Subject:           content[ch].Subject,
+				TemplateName:      content[ch].TemplateName,
+				SendMethod:        ch,
+				Status:            "queued",
+				CreatedBy:         createdBy,
+				CampaignID:        &campaign.ID,
+				DataPrincipalID:   &principalID,
+				NextAttemptAt:     &now,
+				ContentSHA256:     content[ch].SHA256,
+			})
+		}
+	}
+	campaign.TotalMessages = len(messages)
+	campaign.Queued = len(messages)
+	if err := s.repo.Create(campaign, messages); err != nil {
+		return nil, err
+	}
+
+	b.Status = "notifying"
+	if err := s.breachRepo.UpdateBreachNotification(b); err != nil {
+		log.Logger.Error().Err(err).Str("breach_id", b.ID.String()).Msg("Failed to mark breach as notifying")
+	}
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    tenantID,
+		EventType:   "notification_campaign_started",
+		Description: fmt.Sprintf("Notification campaign queued: %d messages over %s to recipient list v%d (%d without contact details)", campaign.TotalMessages, strings.Join(chans, ", "), list.Version, campaign.Skipped),
+		PerformedBy: &createdBy,
+	})
+	return campaign, nil
+}
+
+// Run sends what is due on every channel, up to each channel's limit, and
+// returns the number of messages attempted. Overlapping runs are skipped.
+func (s *BreachCampaignService) Run(now time.Time) int {
+	if !s.running.TryLock() {
+		return 0
+	}
+	defer s.running.Unlock()
+
+	if n, err := s.repo.RequeueStale(now.Add(-campaignStaleAfter)); err != nil {
+		log.Logger.Error().Err(err).Msg("Failed to requeue stale breach notifications")
+	} else if n > 0 {
+		log.Logger.Warn().Int64("messages", n).Msg("Requeued breach notifications left in sending")
+	}
+
+	contents := map[uuid.UUID]map[string]CampaignContent{}
+	simulated := map[uuid.UUID]bool{}
+	touched := map[uuid.UUID]bool{}
+	total := 0
+	for _, ch := range []string{CampaignChannelEmail, CampaignChannelSMS, CampaignChannelInApp} {
+		limit := s.limits[ch]
+		if limit <= 0 {
+			continue
+		}
+		due, err := s.repo.DueMessages(ch, now, limit)
+		if err != nil {
+			log.Logger.Error().Err(err).Str("channel", ch).Msg("Failed to load due breach notifications")
+			continue
+		}
+		for i := range due {
+			m := &due[i]
+			if ok, err := s.repo.Claim(m.ID); err != nil || !ok {
+				continue
+			}
+			content, ok := contents[*m.CampaignID]
+			if !ok {
+				campaign, err := s.repo.GetByID(*m.CampaignID)
+				if err != nil {
+					log.Logger.Error().Err(err).Str("campaign_id", m.CampaignID.String()).Msg("Campaign missing for queued message")
+					continue
+				}
+				_ = json.Unmarshal(campaign.Content, &content)
+				contents[*m.CampaignID] = content
+				simulated[*m.CampaignID] = campaign.IsSimulation
+			}
+			if simulated[*m.CampaignID] {
+				s.capture(m, content[ch], now)
+			} else {
+				s.deliver(m, content[ch], now)
+			}
+			if err := s.repo.SaveMessage(m); err != nil {
+				log.Logger.Error().Err(err).Str("message_id", m.ID.String()).Msg("Failed to record breach notification send")
+			}
+			touched[*m.CampaignID] = true
+			total++
+		}
+	}
+	for id := range touched {
+		if err := s.refresh(id, now); err != nil {
+			log.Logger.Error().Err(err).Str("campaign_id", id.String()).Msg("Failed to refresh campaign")
+		}
+	}
+	return total
+}
+
+// deliver attempts one send and records the outcome on the message
+func (s *BreachCampaignService) deliver(m *models.BreachCommunication, content CampaignContent, now time.Time) {
+	m.Attempts++
+	var err error
+	switch m.SendMethod {
+	case CampaignChannelEmail:
+		m.ProviderMessageID = fmt.Sprintf("<%s@breach-notices>", m.ID)
+		err = s.email.SendTracked(m.Recipient, content.Subject, content.Body, m.ProviderMessageID)
+	case CampaignChannelSMS:
+		m.ProviderMessageID, err = s.sms.Send(m.Recipient, content.Body)
+	case CampaignChannelInApp:
+		n := &models.Notification{
+			ID:        uuid.New(),
+			UserID:    *m.DataPrincipalID,
+			Title:     content.Subject,
+			Body:      content.Body,
+			Icon:      "shield-alert",
+			Unread:    true,
+			CreatedAt: now,
+		}
+		if err = s.notifRepo.Create(n); err == nil {
+			m.ProviderMessageID = n.ID.String()
+			if s.hub != nil {
+				s.hub.Publish(n.UserID, n)
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown channel %q", m.SendMethod)
+	}
+
+	if err != nil {
+		msg := err.Error()
+		m.ErrorMessage = &msg
+		if m.Attempts > len(campaignRetryBackoff) {
+			m.Status = "failed"
+			m.NextAttemptAt = nil
+			return
+		}
+		next := now.Add(campaignRetryBackoff[m.Attempts-1])
+		m.Status = "queued"
+		m.NextAttemptAt = &next
+		return
+	}
+
+	sentAt := now
+	m.SentAt = &sentAt
+	m.ErrorMessage = nil
+	m.NextAttemptAt = nil
+	m.Status = "sent"
+	if m.SendMethod == CampaignChannelInApp {
+		// Stored in the principal's inbox, so delivered on sending
+		m.DeliveredAt = &sentAt
+		m.Status = "delivered"
+	}
+}
+
+// capture hands a simulated campaign's message to the simulation sink in
+// place of sending it; it counts as delivered
+func (s *BreachCampaignService) capture(m *models.BreachCommunication, content CampaignContent, now time.Time) {
+	m.Attempts++
+	msgID := m.ID
+	err := s.sink.Capture(&models.BreachSimulationMessage{
+		BreachID:        m.BreachID,
+		TenantID:        m.TenantID,
+		CommunicationID: &msgID,
+		RecipientType:   m.RecipientType,
+		Channel:         m.SendMethod,
+		Recipient:       m.Recipient,
+		Subject:         content.Subject,
+		Body:            content.Body,
+		CapturedAt:      now,
+	})
+	if err != nil {
+		msg := err.Error()
+		m.ErrorMessage = &msg
+		m.Status = "failed"
+		m.NextAttemptAt = nil
+		return
+	}
+	at := now
+	m.ProviderMessageID = "simulated:" + m.ID.String()
+	m.SentAt = &at
+	m.DeliveredAt = &at
+	m.ErrorMessage = nil
+	m.NextAttemptAt = nil
+	m.Status = "delivered"
+}
+
+// refresh recounts a campaign's messages, settles its status and updates
+// the breach's notified count
+func (s *BreachCampaignService) refresh(campaignID uuid.UUID, now time.Time) error {
+	campaign, err := s.repo.GetByID(campaignID)
+	if err != nil {
+		return err
+	}
+	counts, err := s.repo.StatusCounts(campaignID)
+	if err != nil {
+		return err
+	}
+	campaign.Queued = counts["queued"] + counts["sending"]
+	campaign.Sent = counts["sent"]
+	campaign.Delivered = counts["delivered"]
+	campaign.Bounced = counts["bounced"]
+	campaign.Failed = counts["failed"]
+	campaign.Cancelled = counts["cancelled"]
+
+	completed := false
+	if campaign.Status != "cancelled" {
+		if campaign.StartedAt == nil && campaign.Queued < campaign.TotalMessages {
+			campaign.StartedAt = &now
+		}
+		if campaign.Queued == 0 {
+			if campaign.Status != "completed" {
+				completed = true
+				campaign.CompletedAt = &now
+			}
+			campaign.Status = "completed"
+		} else if campaign.StartedAt != nil {
+			campaign.Status = "running"
+		}
+	}
+	if err := s.repo.Update(campaign); err != nil {
+		return err
+	}
+
+	b, err := s.breachRepo.GetBreachNotificationByID(campaign.BreachID)
+	if err != nil {
+		return err
+	}
+	notified, err := s.repo.NotifiedPrincipals(b.ID)
+	if err != nil {
+		return err
+	}
+	b.NotifiedUsersCount = int(notified)
+	if notified > 0 && b.DataPrincipalNotificationSentAt == nil {
+		b.DataPrincipalNotificationSentAt = &now
+	}
+	if completed && b.Status == "notifying" {
+		b.Status = "notified"
+	}
+	if err := s.breachRepo.UpdateBreachNotification(b); err != nil {
+		return err
+	}
+	if completed {
+		_ = s.timelineRepo.Create(&models.BreachTimeline{
+			BreachID:    b.ID,
+			TenantID:    b.TenantID,
+			EventType:   "data_principals_notified",
+			Description: fmt.Sprintf("Notification campaign finished: %d sent, %d delivered, %d bounced, %d failed; %d Data Principals reached", campaign.Sent, campaign.Delivered, campaign.Bounced, campaign.Failed, notified),
+			PerformedBy: &campaign.CreatedBy,
+		})
+	}
+	return nil
+}
+
+// messageRef reduces an email Message-ID such as <id@breach-notices> to the
+// message ID; other references are returned unchanged
+func messageRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "<") && strings.HasSuffix(ref, ">") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(ref, "<"), ">")
+		if at := strings.Index(inner, "@"); at > 0 {
+			if _, err := uuid.Parse(inner[:at]); err == nil {
+				return inner[:at]
+			}
+		}
+	}
+	return ref
+}
+
+// RecordDelivery applies a delivery, bounce or failure report from a mail or
+// SMS provider. ref is the message ID, email Message-ID or gateway ID.
+func (s *BreachCampaignService) RecordDelivery(ref, status, reason string, at time.Time) (*models.BreachCommunication, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	m, err := s.repo.FindMessage(messageRef(ref))
+	if err != nil {
+		return nil, ErrCampaignNotFound
+	}
+	switch status {
+	case "delivered":
+		if m.Status != "sent" {
+			return m, nil // Late or duplicate report
+		}
+		m.DeliveredAt = &at
+	case "bounced":
+		if m.Status != "sent" && m.Status != "delivered" {
+			return m, nil
+		}
+		m.BouncedAt = &at
+	case "failed":
+		if m.Status != "sent" {
+			return m, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown delivery status %q", ErrInvalidCampaign, status)
+	}
+	m.Status = status
+	if reason != "" {
+		m.ErrorMessage = &reason
+	}
+	if err := s.repo.SaveMessage(m); err != nil {
+		return nil, err
+	}
+	return m, s.refresh(*m.CampaignID, time.Now())
+}
+
+// Cancel stops a campaign; messages already sent are unaffected
+func (s *BreachCampaignService) Cancel(tenantID, campaignID uuid.UUID) (*models.BreachNotificationCampaign, error) {
+	campaign, err := s.Get(tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status == "completed" || campaign.Status == "cancelled" {
+		return campaign, nil
+	}
+	if _, err := s.repo.CancelQueued(campaignID); err != nil {
+		return nil, err
+	}
+	campaign.Status = "cancelled"
+	now := time.Now()
+	campaign.CompletedAt = &now
+	if err := s.repo.Update(campaign); err != nil {
+		return nil, err
+	}
+	if err := s.refresh(campaignID, now); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(campaignID)
+}
+
+// List returns a breach's campaigns, newest first
+func (s *BreachCampaignService) List(tenantID, breachID uuid.UUID) ([]models.BreachNotificationCampaign, error) {
+	if _, err := s.breachForTenant(tenantID, breachID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByBreachID(breachID)
+}
+
+// Get returns a campaign belonging to the tenant
+func (s *BreachCampaignService) Get(tenantID, campaignID uuid.UUID) (*models.BreachNotificationCampaign, error) {
+	campaign, err := s.repo.GetByID(campaignID)
+	if err != nil || campaign.TenantID != tenantID {
+		return nil, ErrCampaignNotFound
+	}
+	return campaign, nil
+}
+
+// Messages pages through a campaign's messages, optionally by status
+func (s *BreachCampaignService) Messages(tenantID, campaignID uuid.UUID, status string, limit, offset int) ([]models.BreachCommunication, error) {
+	if _, err := s.Get(tenantID, campaignID); err != nil {
+		return nil, err
+	}
+	return s.repo.Messages(campaignID, status, limit, offset)
+}
+
+// DeliveryProof is the record of who was sent what, when, and what became
+// of each message
+type DeliveryProof struct {
+	CampaignID           uuid.UUID                  `json:"campaignId"`
+	BreachID             uuid.UUID                  `json:"breachId"`
+	TenantID             uuid.UUID                  `json:"tenantId"`
+	GeneratedAt          time.Time                  `json:"generatedAt"`
+	RecipientListID      uuid.UUID                  `json:"recipientListId"`
+	RecipientListVersion int                        `json:"recipientListVersion"`
+	RecipientListSHA256  string                     `json:"recipientListSha256,omitempty"`
+	Status               string                     `json:"status"`
+	Content              map[string]CampaignContent `json:"content"`
+	Summary              map[string]int             `json:"summary"`
+	Messages             []DeliveryProofEntry       `json:"messages"`
+}
+
+// DeliveryProofEntry is one message in a delivery proof. Addresses are masked.
+type DeliveryProofEntry struct {
+	MessageID         uuid.UUID  `json:"messageId"`
+	DataPrincipalID   uuid.UUID  `json:"dataPrincipalId"`
+	Channel           string     `json:"channel"`
+	Recipient         string     `json:"recipient"`
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempts"`
+	SentAt            *time.Time `json:"sentAt,omitempty"`
+	DeliveredAt       *time.Time `json:"deliveredAt,omitempty"`
+	BouncedAt         *time.Time `json:"bouncedAt,omitempty"`
+	ProviderMessageID string     `json:"providerMessageId,omitempty"`
+	ContentSHA256     string     `json:"contentSha256"`
+	Error             string     `json:"error,omitempty"`
+}
+
+// Proof builds the campaign's delivery proof as signed JSON. It returns the
+// document and its signature.
+func (s *BreachCampaignService) Proof(tenantID, campaignID uuid.UUID) ([]byte, string, error) {
+	campaign, err := s.Get(tenantID, campaignID)
+	if err != nil {
+		return nil, "", err
+	}
+	messages, err := s.repo.Messages(campaignID, "", 0, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	proof := DeliveryProof{
+		CampaignID:           campaign.ID,
+		BreachID:             campaign.BreachID,
+		TenantID:             campaign.TenantID,
+		GeneratedAt:          time.Now().UTC(),
+		RecipientListID:      campaign.RecipientListID,
+		RecipientListVersion: campaign.RecipientListVersion,
+		Status:               campaign.Status,
+		Content:              map[string]CampaignContent{},
+		Summary: map[string]int{
+			"total": campaign.TotalMessages, "skipped": campaign.Skipped, "queued": campaign.Queued, "sent": campaign.Sent,
+			"delivered": campaign.Delivered, "bounced": campaign.Bounced, "failed": campaign.Failed, "cancelled": campaign.Cancelled,
+		},
+		Messages: make([]DeliveryProofEntry, 0, len(messages)),
+	}
+	if list, err := s.impact.Get(tenantID, campaign.BreachID, campaign.RecipientListID); err == nil {
+		proof.RecipientListSHA256 = list.ContentSHA256
+	}
+	_ = json.Unmarshal(campaign.Content, &proof.Content)
+	for _, m := range messages {
+		e := DeliveryProofEntry{
+			MessageID:         m.ID,
+			Channel:           m.SendMethod,
+			Recipient:         m.Recipient,
+			Status:            m.Status,
+			Attempts:          m.Attempts,
+			SentAt:            m.SentAt,
+			DeliveredAt:       m.DeliveredAt,
+			BouncedAt:         m.BouncedAt,
+			ProviderMessageID: m.ProviderMessageID,
+			ContentSHA256:     m.ContentSHA256,
+		}
+		if m.SendMethod != CampaignChannelInApp {
+			e.Recipient = maskData(m.Recipient)
+		}
+		if m.DataPrincipalID != nil {
+			e.DataPrincipalID = *m.DataPrincipalID
+		}
+		if m.ErrorMessage != nil {
+			e.Error = *m.ErrorMessage
+		}
+		proof.Messages = append(proof.Messages, e)
+	}
+
+	doc, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	sig, err := s.signer.Sign(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	return doc, sig, nil
+}
+
+// SigningKeyID identifies the key delivery proofs are signed with
+func (s *BreachCampaignService) SigningKeyID() string {
+	return s.signer.KeyID()
+}
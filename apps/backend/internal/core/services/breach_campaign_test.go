@@ -0,0 +1,195 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+// flakyEmail fails its first send, then records what it sends
+type flakyEmail struct {
+	failures int
+	sent     map[string]string // Message-ID -> recipient
+}
+
+func (e *flakyEmail) SendTracked(to, subject, body, messageID string) error {
+	if e.failures > 0 {
+		e.failures--
+		return errors.New("421 try again later")
+	}
+	e.sent[messageID] = to
+	return nil
+}
+
+func TestBreachCampaign_ThrottlesRetriesAndTracksDelivery(t *testing.T) {
+	f := setupBreachImpact(t)
+	db := f.db
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}, &models.BreachNotificationTemplate{}, &models.BreachCommunication{},
+		&models.BreachNotificationCampaign{}, &models.Notification{}))
+	require.NoError(t, db.Create(&models.Tenant{TenantID: f.tenantID, Name: "Acme Retail", Config: datatypes.JSON(`{"dpoEmail":"dpo@acme.example"}`)}).Error)
+	require.NoError(t, db.Create(&[]models.BreachNotificationTemplate{
+		{ID: uuid.New(), TemplateName: "data_principal_notification_template", RecipientType: "data_principal", TemplateType: "email",
+			Subject: "Important notice from {{company_name}}", Body: "Contact {{dpo_email}} about breach {{breach_id}}", IsActive: true},
+		{ID: uuid.New(), TemplateName: "data_principal_notification_sms", RecipientType: "data_principal", TemplateType: "sms",
+			Body: "{{company_name}}: your data may be affected. Contact {{dpo_email}}", IsActive: true},
+	}).Error)
+	require.NoError(t, db.Model(&models.DataPrincipal{}).Where("id = ?", f.alice).Update("phone", "+919800000001").Error)
+
+	staff := uuid.New()
+	end := time.Now().Add(-time.Hour)
+	_, _, err := f.svc.Freeze(f.tenantID, f.breach.ID, breach.ImpactQuery{SystemIDs: []uuid.UUID{f.system}, WindowEnd: &end, DataCategories: []string{"contact"}}, staff)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer := NewDocumentSigner(key)
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	svc := NewBreachCampaignService(repository.NewBreachCampaignRepository(db), breachRepo, f.svc,
+		repository.NewBreachNotificationTemplateRepository(db), repository.NewTenantRepository(db), repository.NewBreachTimelineRepository(db),
+		repository.NewNotificationRepo(db), repository.NewBreachSimulationRepository(db), nil, NewEmailService("", 0, "", "", ""), NewSMSService("", "", "ARC"), signer,
+		BreachCampaignLimits{CampaignChannelEmail: 1, CampaignChannelSMS: 10, CampaignChannelInApp: 10})
+	mail := &flakyEmail{failures: 1, sent: map[string]string{}}
+	svc.email = mail
+
+	_, err = svc.Launch(f.tenantID, f.breach.ID, uuid.Nil, []string{CampaignChannelEmail}, staff)
+	assert.ErrorIs(t, err, ErrNotificationNotApproved)
+	b, err := breachRepo.GetBreachNotificationByID(f.breach.ID)
+	require.NoError(t, err)
+	b.DataPrincipalNotificationApproved = true
+	require.NoError(t, breachRepo.UpdateBreachNotification(b))
+	_, err = svc.Launch(f.tenantID, f.breach.ID, uuid.Nil, []string{"fax"}, staff)
+	assert.ErrorIs(t, err, ErrInvalidCampaign)
+
+	campaign, err := svc.Launch(f.tenantID, f.breach.ID, uuid.Nil, []string{CampaignChannelEmail, CampaignChannelSMS}, staff)
+	require.NoError(t, err)
+	assert.Equal(t, 3, campaign.TotalMessages)
+	assert.Equal(t, 1, campaign.Skipped, "bob has no phone number")
+	var content map[string]CampaignContent
+	require.NoError(t, json.Unmarshal(campaign.Content, &content))
+	assert.Equal(t, "Important notice from Acme Retail", content[CampaignChannelEmail].Subject)
+	assert.Contains(t, content[CampaignChannelSMS].Body, "dpo@acme.example")
+
+	// One email per minute: the first attempt fails and is retried after the backoff
+	now := time.Now()
+	assert.Equal(t, 2, svc.Run(now))
+	assert.Empty(t, mail.sent)
+	assert.Equal(t, 1, svc.Run(now.Add(30*time.Second)), "only the untried email is due")
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, 1, svc.Run(now.Add(2*time.Minute)))
+	assert.Equal(t, 0, svc.Run(now.Add(3*time.Minute)))
+	assert.Len(t, mail.sent, 2)
+
+	campaign, err = svc.Get(f.tenantID, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", campaign.Status)
+	assert.Equal(t, 3, campaign.Sent)
+	b, err = breachRepo.GetBreachNotificationByID(f.breach.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.NotifiedUsersCount)
+	assert.Equal(t, "notified", b.Status)
+	assert.NotNil(t, b.DataPrincipalNotificationSentAt)
+
+	// Delivery and bounce reports quote the Message-ID back
+	for messageID, to := range mail.sent {
+		status := "delivered"
+		if to == "bob@example.com" {
+			status = "bounced"
+		}
+		_, err := svc.RecordDelivery(messageID, status, "", time.Time{})
+		require.NoError(t, err)
+	}
+	campaign, err = svc.Get(f.tenantID, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, campaign.Delivered)
+	assert.Equal(t, 1, campaign.Bounced)
+	b, err = breachRepo.GetBreachNotificationByID(f.breach.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, b.NotifiedUsersCount, "a bounce means bob was not reached")
+
+	doc, sig, err := svc.Proof(f.tenantID, campaign.ID)
+	require.NoError(t, err)
+	assert.True(t, signer.Verify(doc, sig))
+	var proof DeliveryProof
+	require.NoError(t, json.Unmarshal(doc, &proof))
+	assert.Len(t, proof.Messages, 3)
+	assert.NotEmpty(t, proof.RecipientListSHA256)
+	for _, m := range proof.Messages {
+		assert.NotContains(t, m.Recipient, "example.com", fmt.Sprintf("recipient %s should be masked", m.Recipient))
+	}
+
+	_, _, err = svc.Proof(uuid.New(), campaign.ID)
+	assert.ErrorIs(t, err, ErrCampaignNotFound)
+}
+
+func TestBreachCampaign_FailsAfterRetriesAndCancels(t *testing.T) {
+	f := setupBreachImpact(t)
+	db := f.db
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}, &models.BreachNotificationTemplate{}, &models.BreachCommunication{},
+		&models.BreachNotificationCampaign{}, &models.Notification{}))
+	require.NoError(t, db.Create(&[]models.BreachNotificationTemplate{
+		{ID: uuid.New(), TemplateName: "data_principal_notification_template", RecipientType: "data_principal", Subject: "Notice", Body: "Body", IsActive: true},
+		{ID: uuid.New(), TemplateName: "data_principal_notification_sms", RecipientType: "data_principal", Body: "Short", IsActive: true},
+	}).Error)
+	staff := uuid.New()
+	end := time.Now().Add(-time.Hour)
+	_, _, err := f.svc.Freeze(f.tenantID, f.breach.ID, breach.ImpactQuery{SystemIDs: []uuid.UUID{f.system}, WindowEnd: &end, DataCategories: []string{"contact"}}, staff)
+	require.NoError(t, err)
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	b, err := breachRepo.GetBreachNotificationByID(f.breach.ID)
+	require.NoError(t, err)
+	b.DataPrincipalNotificationApproved = true
+	require.NoError(t, breachRepo.UpdateBreachNotification(b))
+
+	svc := NewBreachCampaignService(repository.NewBreachCampaignRepository(db), breachRepo, f.svc,
+		repository.NewBreachNotificationTemplateRepository(db), repository.NewTenantRepository(db), repository.NewBreachTimelineRepository(db),
+		repository.NewNotificationRepo(db), repository.NewBreachSimulationRepository(db), nil, NewEmailService("", 0, "", "", ""), NewSMSService("", "", ""), nil,
+		BreachCampaignLimits{CampaignChannelEmail: 10, CampaignChannelInApp: 10})
+	svc.email = &flakyEmail{failures: 100, sent: map[string]string{}}
+
+	campaign, err := svc.Launch(f.tenantID, f.breach.ID, uuid.Nil, []string{CampaignChannelEmail, CampaignChannelInApp}, staff)
+	require.NoError(t, err)
+	assert.Equal(t, 4, campaign.TotalMessages)
+
+	// In-app notices land in the principals' inboxes straight away
+	now := time.Now()
+	svc.Run(now)
+	var inbox int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("user_id IN ?", []uuid.UUID{f.alice, f.bob}).Count(&inbox).Error)
+	assert.Equal(t, int64(2), inbox)
+
+	// Emails that keep failing give up after the last backoff
+	for i := 1; i <= len(campaignRetryBackoff); i++ {
+		svc.Run(now.Add(time.Duration(i) * 2 * time.Hour))
+	}
+	campaign, err = svc.Get(f.tenantID, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", campaign.Status)
+	assert.Equal(t, 2, campaign.Delivered)
+	assert.Equal(t, 2, campaign.Failed)
+	failed, err := svc.Messages(f.tenantID, campaign.ID, "failed", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	assert.Equal(t, len(campaignRetryBackoff)+1, failed[0].Attempts)
+	assert.NotNil(t, failed[0].ErrorMessage)
+
+	// Cancelling stops whatever is still queued
+	second, err := svc.Launch(f.tenantID, f.breach.ID, uuid.Nil, []string{CampaignChannelEmail}, staff)
+	require.NoError(t, err)
+	second, err = svc.Cancel(f.tenantID, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", second.Status)
+	assert.Equal(t, 2, second.Cancelled)
+	assert.Equal(t, 0, svc.Run(now.Add(24*time.Hour)), "cancelled messages are never sent")
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/internal/templates"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrCERTInNotRequired       = errors.New("breach is not on the CERT-In reporting track")
+	ErrInvalidCERTInIncident   = errors.New("invalid CERT-In incident type")
+	ErrUnsupportedCERTInFormat = errors.New("unsupported CERT-In export format")
+)
+
+// istLocation is Indian Standard Time, which CERT-In reports use
+var istLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// CERTInReportService runs the CERT-In track of a breach: it keeps the
+// 6-hour deadline, builds the incident report in CERT-In's form fields and
+// records when the report was made.
+type CERTInReportService struct {
+	breachRepo   *repository.BreachNotificationRepository
+	impactRepo   *repository.BreachImpactAssessmentRepository
+	timelineRepo *repository.BreachTimelineRepository
+	tenantRepo   *repository.TenantRepository
+	templateRepo *repository.BreachNotificationTemplateRepository
+}
+
+func NewCERTInReportService(
+	breachRepo *repository.BreachNotificationRepository,
+	impactRepo *repository.BreachImpactAssessmentRepository,
+	timelineRepo *repository.BreachTimelineRepository,
+	tenantRepo *repository.TenantRepository,
+	templateRepo *repository.BreachNotificationTemplateRepository,
+) *CERTInReportService {
+	return &CERTInReportService{
+		breachRepo:   breachRepo,
+		impactRepo:   impactRepo,
+		timelineRepo: timelineRepo,
+		tenantRepo:   tenantRepo,
+		templateRepo: templateRepo,
+	}
+}
+
+func (s *CERTInReportService) breachForTenant(tenantID, breachID uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return b, nil
+}
+
+// CERTInTrackUpdate changes a breach's CERT-In track. Nil fields are left as they are.
+type CERTInTrackUpdate struct {
+	Required     *bool                         `json:"required"`
+	IncidentType *breach.CERTInIncidentType    `json:"incidentType"`
+	Details      *breach.CERTInIncidentDetails `json:"details"`
+}
+
+// UpdateTrack puts a breach on or off the CERT-In track, sets its incident
+// type and records the form details the breach does not hold
+func (s *CERTInReportService) UpdateTrack(tenantID, breachID uuid.UUID, req CERTInTrackUpdate, updatedBy uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if req.IncidentType != nil && *req.IncidentType != "" {
+		if _, ok := breach.LookupCERTInIncidentType(*req.IncidentType); !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCERTInIncident, *req.IncidentType)
+		}
+		b.CERTInIncidentType = string(*req.IncidentType)
+	}
+	if req.Required != nil {
+		b.RequiresCERTInReporting = *req.Required
+		if !*req.Required {
+			b.CERTInIncidentType = ""
+			b.CERTInDeadline = nil
+		}
+	}
+	if req.Details != nil {
+		raw, err := json.Marshal(req.Details)
+		if err != nil {
+			return nil, err
+		}
+		b.CERTInDetails = datatypes.JSON(raw)
+	}
+
+	detection := b.DetectionDate
+	if detection.IsZero() {
+		detection = b.CreatedAt
+	}
+	calculateCERTInDeadline(b, detection, time.Now())
+	if err := s.breachRepo.UpdateBreachNotification(b); err != nil {
+		return nil, err
+	}
+
+	desc := "Removed from the CERT-In reporting track"
+	if b.RequiresCERTInReporting {
+		desc = fmt.Sprintf("CERT-In reporting track: incident type %q, due %s", b.CERTInIncidentType, b.CERTInDeadline.Format(time.RFC3339))
+	}
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    b.TenantID,
+		EventType:   "cert_in_track_updated",
+		Description: desc,
+		PerformedBy: &updatedBy,
+	})
+	return b, nil
+}
+
+// Build assembles the CERT-In incident report from the breach records
+func (s *CERTInReportService) Build(tenantID, breachID uuid.UUID) (*breach.CERTInReport, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if !b.RequiresCERTInReporting {
+		return nil, ErrCERTInNotRequired
+	}
+
+	r := &breach.CERTInReport{
+		BreachID:        b.ID,
+		GeneratedAt:     time.Now().UTC(),
+		DueBy:           b.CERTInDeadline,
+		IncidentType:    breach.CERTInIncidentType(b.CERTInIncidentType),
+		OccurredAt:      nonZeroTime(b.BreachDate),
+		DetectedAt:      nonZeroTime(b.DetectionDate),
+		Description:     b.Description,
+		Location:        []string{},
+		AffectedSystems: []breach.CERTInAffectedSystem{},
+		DataCategories:  jsonStrings(b.DataCategoriesAffected),
+		AffectedRecords: b.AffectedUsersCount,
+		ActionsTaken:    jsonStrings(b.RemedialActions),
+	}
+	if b.IsSimulation {
+		r.Simulation = breach.SimulationBanner
+	}
+	if info, ok := breach.LookupCERTInIncidentType(r.IncidentType); ok {
+		r.IncidentTypeNumber = info.Number
+		r.IncidentTypeLabel = info.Label
+	}
+	if r.ActionsTaken == nil {
+		r.ActionsTaken = []string{}
+	}
+
+	if tenant, err := s.tenantRepo.GetByID(b.TenantID); err == nil {
+		r.Organisation = tenant.Name
+		var cfg map[string]interface{}
+		if json.Unmarshal(tenant.Config, &cfg) == nil {
+			first := func(keys ...string) string {
+				for _, k := range keys {
+					if v, ok := cfg[k].(string); ok && v != "" {
+						return v
+					}
+				}
+				return ""
+			}
+			r.Sector = first("sector", "industry")
+			r.OrganisationAddress = first("address")
+			// CERT-In wants the designated Point of Contact; the DPO stands in if none is set
+			r.PointOfContact = breach.CERTInContact{
+				Name:        first("certInPocName", "dpoName"),
+				Designation: first("certInPocDesignation"),
+				Email:       first("certInPocEmail", "dpoEmail", "contactEmail"),
+				Phone:       first("certInPocPhone", "dpoPhone"),
+			}
+			if r.PointOfContact.Designation == "" && r.PointOfContact.Name != "" && first("certInPocName") == "" {
+				r.PointOfContact.Designation = "Data Protection Officer"
+			}
+		}
+	}
+
+	if impact, err := s.impactRepo.GetByBreachID(b.ID); err == nil {
+		if loc := jsonStrings(impact.GeographicScope); len(loc) > 0 {
+			r.Location = loc
+		}
+	}
+
+	var details breach.CERTInIncidentDetails
+	if len(b.CERTInDetails) > 0 {
+		_ = json.Unmarshal(b.CERTInDetails, &details)
+	}
+	if len(details.AffectedSystems) > 0 {
+		r.AffectedSystems = details.AffectedSystems
+	}
+	r.Symptoms = details.Symptoms
+	r.CriticalSystem = details.CriticalSystem
+	r.ReportedElsewhere = append(r.ReportedElsewhere, details.ReportedElsewhere...)
+	if b.DPBReported {
+		r.ReportedElsewhere = append(r.ReportedElsewhere, "Data Protection Board of India")
+	}
+	return r, nil
+}
+
+// Validate builds the report and returns it with its validation issues
+func (s *CERTInReportService) Validate(tenantID, breachID uuid.UUID) (*breach.CERTInReport, []breach.DPBValidationIssue, error) {
+	r, err := s.Build(tenantID, breachID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, r.Validate(), nil
+}
+
+// Export renders the report as "json" or as "text", the filled-in form ready
+// to email to CERT-In. It returns the document, its content type and a file name.
+// Reports of a simulated breach carry the simulation banner.
+func (s *CERTInReportService) Export(tenantID, breachID uuid.UUID, format string) ([]byte, string, string, error) {
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" {
+		return nil, "", "", fmt.Errorf("%w: %q", ErrUnsupportedCERTInFormat, format)
+	}
+	r, issues, err := s.Validate(tenantID, breachID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if breach.HasErrors(issues) {
+		return nil, "", "", &DPBReportIncompleteError{Issues: issues}
+	}
+	name := "cert-in-incident-" + breachID.String()[:8]
+
+	if format == "json" {
+		doc, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, "", "", err
+		}
+		return doc, "application/json", name + ".json", nil
+	}
+
+	subject := "Cyber Security Incident Report - {{certin_incident_type}} - {{company_name}}"
+	body := templates.CERTInIncidentReportTemplate
+	if tpl, err := s.templateRepo.GetTemplate("certin_incident_report", "cert_in"); err == nil {
+		subject, body = tpl.Subject, tpl.Body
+	}
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	values := certInTemplateValues(r)
+	text := "Subject: " + renderBreachTemplate(subject, b, values) + "\n\n" + renderBreachTemplate(body, b, values)
+	if b.IsSimulation {
+		text = breach.SimulationBanner + "\n\n" + text
+	}
+	return []byte(text), "text/plain; charset=utf-8", name + ".txt", nil
+}
+
+// certInTemplateValues fills the report template's placeholders
+func certInTemplateValues(r *breach.CERTInReport) map[string]string {
+	orDash := func(s string) string {
+		if strings.TrimSpace(s) == "" {
+			return "-"
+		}
+		return s
+	}
+	at := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.In(istLocation).Format("02 Jan 2006 15:04 MST")
+	}
+	list := func(items []string) string {
+		if len(items) == 0 {
+			return "-"
+		}
+		return "- " + strings.Join(items, "\n- ")
+	}
+
+	systems := make([]string, 0, len(r.AffectedSystems))
+	for _, sys := range r.AffectedSystems {
+		parts := []string{sys.Name}
+		for _, p := range []string{sys.IPAddress, sys.Hostname, sys.OperatingSystem, sys.Location} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		systems = append(systems, strings.Join(parts, ", "))
+	}
+	critical := "No"
+	if r.CriticalSystem {
+		critical = "Yes"
+	}
+	return map[string]string{
+		"breach_id":              r.BreachID.String(),
+		"company_name":           orDash(r.Organisation),
+		"sector":                 orDash(r.Sector),
+		"organisation_address":   orDash(r.OrganisationAddress),
+		"poc_name":               orDash(r.PointOfContact.Name),
+		"poc_designation":        orDash(r.PointOfContact.Designation),
+		"poc_email":              orDash(r.PointOfContact.Email),
+		"poc_phone":              orDash(r.PointOfContact.Phone),
+		"certin_incident_number": fmt.Sprintf("%d", r.IncidentTypeNumber),
+		"certin_incident_type":   orDash(r.IncidentTypeLabel),
+		"occurred_at":            at(r.OccurredAt),
+		"detected_at":            at(r.DetectedAt),
+		"location":               orDash(strings.Join(r.Location, ", ")),
+		"affected_systems":       list(systems),
+		"critical_system":        critical,
+		"breach_description":     orDash(r.Description),
+		"symptoms":               orDash(r.Symptoms),
+		"data_categories":        orDash(strings.Join(r.DataCategories, ", ")),
+		"affected_count":         fmt.Sprintf("%d", r.AffectedRecords),
+		"remedial_actions":       list(r.ActionsTaken),
+		"reported_elsewhere":     orDash(strings.Join(r.ReportedElsewhere, ", ")),
+	}
+}
+
+// MarkReported records that the report reached CERT-In, with CERT-In's
+// acknowledgement reference if one was given
+func (s *CERTInReportService) MarkReported(tenantID, breachID uuid.UUID, reference string, reportedAt time.Time, reportedBy uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if !b.RequiresCERTInReporting {
+		return nil, ErrCERTInNotRequired
+	}
+	if reportedAt.IsZero() {
+		reportedAt = time.Now()
+	}
+	b.CERTInReportedAt = &reportedAt
+	if reference != "" {
+		b.CERTInReference = &reference
+	}
+	b.CERTInStatus = certInStatus(b, time.Now())
+	if err := s.breachRepo.UpdateBreachNotification(b); err != nil {
+		return nil, err
+	}
+
+	desc := "Incident reported to CERT-In"
+	if reference != "" {
+		desc += " (ref " + reference + ")"
+	}
+	if b.CERTInStatus == breach.CERTInStatusReportedLate {
+		desc += fmt.Sprintf(", %s after the 6-hour deadline", reportedAt.Sub(*b.CERTInDeadline).Round(time.Minute))
+	}
+	if b.IsSimulation {
+		desc += " (simulation: nothing was submitted)"
+	}
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    b.TenantID,
+		EventType:   "cert_in_reported",
+		Description: desc,
+		PerformedBy: &reportedBy,
+	})
+	return b, nil
+}
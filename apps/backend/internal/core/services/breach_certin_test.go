@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCERTIn_ParallelTrackDeadlineAndReport(t *testing.T) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}, &models.EncryptedBreachNotification{}, &models.BreachTimeline{},
+		&models.BreachWorkflowStage{}, &models.BreachImpactAssessment{}, &models.BreachNotificationTemplate{}))
+
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	timelineRepo := repository.NewBreachTimelineRepository(db)
+	enhanced := NewEnhancedBreachNotificationService(breachRepo, repository.NewBreachImpactAssessmentRepository(db), nil,
+		repository.NewBreachWorkflowStageRepository(db), nil, nil, timelineRepo, nil, nil, nil)
+	svc := NewCERTInReportService(breachRepo, repository.NewBreachImpactAssessmentRepository(db), timelineRepo,
+		repository.NewTenantRepository(db), repository.NewBreachNotificationTemplateRepository(db))
+
+	tenantID := uuid.New()
+	detected := time.Now().Add(-7 * time.Hour)
+	b := &models.BreachNotification{TenantID: tenantID, Title: "File server encrypted", Description: "Ransomware encrypted the HR file share",
+		BreachType: "ransomware", Severity: "high", BreachDate: detected.Add(-time.Hour), DetectionDate: detected,
+		RemedialActions: datatypes.JSON(`["Isolated the file server"]`), RequiresCERTInReporting: true}
+	require.NoError(t, enhanced.CreateBreachWithWorkflow(b, uuid.New()))
+
+	// The type is suggested from the breach type and the 6-hour clock runs from detection
+	assert.Equal(t, string(breach.CERTInMaliciousCode), b.CERTInIncidentType)
+	require.NotNil(t, b.CERTInDeadline)
+	assert.WithinDuration(t, detected.Add(6*time.Hour), *b.CERTInDeadline, time.Second)
+	sla, err := enhanced.CheckSLACompliance(b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, breach.CERTInStatusOverdue, sla["cert_in_status"])
+	assert.Equal(t, true, sla["cert_in_overdue"])
+	assert.Equal(t, false, sla["dpb_overdue"], "the DPB track has its own 72-hour clock")
+	register, err := enhanced.GetBreachRegister(tenantID)
+	require.NoError(t, err)
+	require.Len(t, register, 1)
+	assert.Equal(t, breach.CERTInStatusOverdue, register[0].CERTInStatus)
+
+	// Without a Point of Contact the report cannot be exported
+	_, _, _, err = svc.Export(tenantID, b.ID, "json")
+	var incomplete *DPBReportIncompleteError
+	require.ErrorAs(t, err, &incomplete)
+	assert.Contains(t, incomplete.Error(), "pointOfContact")
+
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme Retail",
+		Config: datatypes.JSON(`{"dpoName":"Priya Rao","dpoEmail":"dpo@acme.example","sector":"Retail"}`)}).Error)
+	_, err = svc.UpdateTrack(tenantID, b.ID, CERTInTrackUpdate{Details: &breach.CERTInIncidentDetails{
+		AffectedSystems: []breach.CERTInAffectedSystem{{Name: "HR file share", IPAddress: "10.0.4.12", OperatingSystem: "Windows Server 2019"}},
+		Symptoms:        "Files renamed with .lock extension",
+	}}, uuid.New())
+	require.NoError(t, err)
+
+	doc, contentType, _, err := svc.Export(tenantID, b.ID, "json")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	var report breach.CERTInReport
+	require.NoError(t, json.Unmarshal(doc, &report))
+	assert.Equal(t, 5, report.IncidentTypeNumber)
+	assert.Equal(t, "Data Protection Officer", report.PointOfContact.Designation)
+	assert.Equal(t, "10.0.4.12", report.AffectedSystems[0].IPAddress)
+
+	text, _, _, err := svc.Export(tenantID, b.ID, "text")
+	require.NoError(t, err)
+	assert.Contains(t, string(text), "Subject: Cyber Security Incident Report - Malicious code attacks")
+	assert.Contains(t, string(text), "3. Type of incident: 5. Malicious code")
+	assert.Contains(t, string(text), "HR file share, 10.0.4.12, Windows Server 2019")
+	assert.Contains(t, string(text), "- Isolated the file server")
+	assert.NotContains(t, string(text), "{{")
+
+	// Reporting after the deadline is recorded as late
+	reported, err := svc.MarkReported(tenantID, b.ID, "CERTIn-2026-1234", time.Now(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, breach.CERTInStatusReportedLate, reported.CERTInStatus)
+	sla, err = enhanced.CheckSLACompliance(b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, false, sla["cert_in_within_sla"])
+	assert.Equal(t, true, sla["cert_in_notified"])
+
+	_, err = svc.UpdateTrack(tenantID, b.ID, CERTInTrackUpdate{IncidentType: ptrCERTInType("tornado")}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidCERTInIncident)
+	off := false
+	_, err = svc.UpdateTrack(tenantID, b.ID, CERTInTrackUpdate{Required: &off}, uuid.New())
+	require.NoError(t, err)
+	_, err = svc.Build(tenantID, b.ID)
+	assert.ErrorIs(t, err, ErrCERTInNotRequired)
+	_, err = svc.Build(uuid.New(), b.ID)
+	assert.ErrorIs(t, err, ErrBreachNotFound)
+}
+
+func ptrCERTInType(s string) *breach.CERTInIncidentType {
+	t := breach.CERTInIncidentType(s)
+	return &t
+}
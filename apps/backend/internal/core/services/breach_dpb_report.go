@@ -0,0 +1,464 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+var ErrBreachNotFound = errors.New("breach not found")
+
+// DPBReportIncompleteError lists the mandatory fields still missing from a report
+type DPBReportIncompleteError struct {
+	Issues []breach.DPBValidationIssue
+}
+
+func (e *DPBReportIncompleteError) Error() string {
+	fields := make([]string, 0, len(e.Issues))
+	for _, i := range e.Issues {
+		if i.Severity == "error" {
+			fields = append(fields, i.Field)
+		}
+	}
+	return "breach report is missing mandatory fields: " + strings.Join(fields, ", ")
+}
+
+// DPBReportService builds breach intimations to the Data Protection Board from
+// a breach and its impact assessment, timeline and evidence, and renders them
+// as signed JSON and PDF.
+type DPBReportService struct {
+	breachRepo        *repository.BreachNotificationRepository
+	impactRepo        *repository.BreachImpactAssessmentRepository
+	timelineRepo      *repository.BreachTimelineRepository
+	evidenceRepo      *repository.BreachEvidenceRepository
+	communicationRepo *repository.BreachCommunicationRepository
+	tenantRepo        *repository.TenantRepository
+	reportRepo        *repository.BreachDPBReportRepository
+	recipientRepo     *repository.BreachRecipientListRepository
+	store             *blob.Store
+	signer            *DocumentSigner
+}
+
+func NewDPBReportService(
+	breachRepo *repository.BreachNotificationRepository,
+	impactRepo *repository.BreachImpactAssessmentRepository,
+	timelineRepo *repository.BreachTimelineRepository,
+	evidenceRepo *repository.BreachEvidenceRepository,
+	communicationRepo *repository.BreachCommunicationRepository,
+	tenantRepo *repository.TenantRepository,
+	reportRepo *repository.BreachDPBReportRepository,
+	recipientRepo *repository.BreachRecipientListRepository,
+	store *blob.Store,
+	signer *DocumentSigner,
+) *DPBReportService {
+	return &DPBReportService{
+		breachRepo:        breachRepo,
+		impactRepo:        impactRepo,
+		timelineRepo:      timelineRepo,
+		evidenceRepo:      evidenceRepo,
+		communicationRepo: communicationRepo,
+		tenantRepo:        tenantRepo,
+		reportRepo:        reportRepo,
+		recipientRepo:     recipientRepo,
+		store:             store,
+		signer:            signer,
+	}
+}
+
+func jsonStrings(raw []byte) []string {
+	var out []string
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &out)
+	}
+	return out
+}
+
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Build assembles the intimation from the breach records without saving it.
+func (s *DPBReportService) Build(tenantID, breachID uuid.UUID, kind breach.DPBReportKind) (*breach.DPBIntimation, error) {
+	if kind != breach.DPBReportInitial && kind != breach.DPBReportDetailed {
+		return nil, fmt.Errorf("report kind must be %q or %q", breach.DPBReportInitial, breach.DPBReportDetailed)
+	}
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+
+	r := &breach.DPBIntimation{
+		Kind:        kind,
+		BreachID:    b.ID,
+		GeneratedAt: time.Now().UTC(),
+		Fiduciary:   breach.DPBFiduciaryDetails{TenantID: b.TenantID},
+		Nature:      strings.ReplaceAll(b.BreachType, "_", " "),
+		Title:       b.Title,
+		Description: b.Description,
+		Severity:    b.Severity,
+		OccurredAt:  nonZeroTime(b.BreachDate),
+		DetectedAt:  nonZeroTime(b.DetectionDate),
+		ContainedAt: b.ContainmentDate,
+		Location:    []string{},
+		Extent: breach.DPBExtent{
+			AffectedPrincipals: b.AffectedUsersCount,
+			DataCategories:     jsonStrings(b.DataCategoriesAffected),
+		},
+		LikelyImpact: b.ImpactOnDataPrincipals,
+	}
+	if tenant, err := s.tenantRepo.GetByID(b.TenantID); err == nil {
+		r.Fiduciary.Name = tenant.Name
+		var cfg map[string]interface{}
+		if json.Unmarshal(tenant.Config, &cfg) == nil {
+			for _, key := range []string{"dpoEmail", "contactEmail"} {
+				if v, ok := cfg[key].(string); ok && v != "" {
+					r.Fiduciary.ContactEmail = v
+					break
+				}
+			}
+		}
+	}
+
+	// A frozen recipient list is the authoritative count of affected principals
+	if list, err := s.recipientRepo.GetLatest(b.ID); err == nil {
+		r.Extent.AffectedPrincipals = list.TotalRecipients
+		r.Extent.RecipientListVersion = list.Version
+		if cats := jsonStrings(list.DataCategories); len(cats) > 0 {
+			r.Extent.DataCategories = cats
+		}
+		_ = json.Unmarshal(list.CategoryCounts, &r.Extent.ByCategory)
+	}
+
+	// The impact assessment fills in what the breach record leaves open
+	if impact, err := s.impactRepo.GetByBreachID(b.ID); err == nil {
+		if loc := jsonStrings(impact.GeographicScope); len(loc) > 0 {
+			r.Location = loc
+		}
+		if len(r.Extent.DataCategories) == 0 {
+			r.Extent.DataCategories = jsonStrings(impact.DataCategoriesAffected)
+		}
+		if r.Extent.AffectedPrincipals == 0 {
+			r.Extent.AffectedPrincipals = impact.NumberOfAffected
+		}
+		if r.LikelyImpact == "" && (impact.LikelihoodOfHarm != "" || impact.ImpactLevel != "") {
+			parts := []string{}
+			if impact.LikelihoodOfHarm != "" {
+				parts = append(parts, "Likelihood of harm: "+impact.LikelihoodOfHarm)
+			}
+			if impact.ImpactLevel != "" {
+				parts = append(parts, "Impact level: "+impact.ImpactLevel)
+			}
+			if impact.RiskToRightsLevel != "" {
+				parts = append(parts, "Risk to rights of Data Principals: "+impact.RiskToRightsLevel)
+			}
+			r.LikelyImpact = strings.Join(parts, ". ") + "."
+			if impact.AssessmentNotes != "" {
+				r.LikelyImpact += " " + impact.AssessmentNotes
+			}
+		}
+	}
+	if r.LikelyImpact == "" && b.LikelihoodOfHarm != "" {
+		r.LikelyImpact = "Likelihood of harm: " + b.LikelihoodOfHarm + "."
+	}
+	if r.Extent.DataCategories == nil {
+		r.Extent.DataCategories = []string{}
+	}
+
+	timeline, err := s.timelineRepo.GetByBreachID(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range timeline {
+		if r.ContainedAt == nil && (t.EventType == "containment" || t.EventType == "contained") {
+			at := t.OccurredAt
+			r.ContainedAt = &at
+		}
+	}
+
+	if kind == breach.DPBReportInitial {
+		return r, nil
+	}
+
+	if r.DetectedAt != nil {
+		due := r.DetectedAt.Add(breach.DPBDetailedReportWindow)
+		r.DueBy = &due
+	}
+	if b.InvestigationSummary != nil {
+		r.Circumstances = *b.InvestigationSummary
+	}
+	if b.RootCause != nil {
+		r.CauseFindings = *b.RootCause
+	}
+	r.MitigationMeasures = jsonStrings(b.RemedialActions)
+	r.RemedialMeasures = jsonStrings(b.PreventiveMeasures)
+
+	intimation := &breach.DPBPrincipalIntimation{
+		Required:      b.RequiresDataPrincipalNotification,
+		NotifiedCount: b.NotifiedUsersCount,
+		SentAt:        b.DataPrincipalNotificationSentAt,
+		Methods:       []string{},
+	}
+	if comms, err := s.communicationRepo.GetByBreachID(b.ID); err == nil {
+		seen := map[string]bool{}
+		for _, c := range comms {
+			if c.RecipientType != "data_principal" || c.SentAt == nil {
+				continue
+			}
+			if !seen[c.SendMethod] {
+				seen[c.SendMethod] = true
+				intimation.Methods = append(intimation.Methods, c.SendMethod)
+			}
+			if intimation.SentAt == nil || c.SentAt.Before(*intimation.SentAt) {
+				intimation.SentAt = c.SentAt
+			}
+		}
+	}
+	r.PrincipalIntimation = intimation
+
+	r.Timeline = make([]breach.DPBTimelineEntry, 0, len(timeline))
+	for _, t := range timeline {
+		r.Timeline = append(r.Timeline, breach.DPBTimelineEntry{At: t.OccurredAt, Event: t.EventType, Description: t.Description})
+	}
+	evidence, err := s.evidenceRepo.GetByBreachID(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	r.Evidence = make([]breach.DPBEvidenceEntry, 0, len(evidence))
+	for _, e := range evidence {
+		r.Evidence = append(r.Evidence, breach.DPBEvidenceEntry{Type: e.EvidenceType, Title: e.Title, SHA256: e.FileHash, CollectedAt: e.CollectedAt})
+	}
+	return r, nil
+}
+
+// Validate builds the intimation and returns it with its validation issues.
+func (s *DPBReportService) Validate(tenantID, breachID uuid.UUID, kind breach.DPBReportKind) (*breach.DPBIntimation, []breach.DPBValidationIssue, error) {
+	r, err := s.Build(tenantID, breachID, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, r.Validate(), nil
+}
+
+// Generate validates the intimation and, if nothing mandatory is missing,
+// stores it as a new signed version with its PDF rendering.
+func (s *DPBReportService) Generate(tenantID, breachID uuid.UUID, kind breach.DPBReportKind, generatedBy uuid.UUID) (*models.BreachDPBReport, error) {
+	// Exercises may dry-run the report with Validate but never produce a signed one
+	if b, err := s.breachRepo.GetBreachNotificationByID(breachID); err == nil && b.TenantID == tenantID && b.IsSimulation {
+		return nil, ErrBreachSimulated
+	}
+	r, issues, err := s.Validate(tenantID, breachID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if breach.HasErrors(issues) {
+		return nil, &DPBReportIncompleteError{Issues: issues}
+	}
+
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	warnings, _ := json.Marshal(issues)
+	payloadSum := sha256.Sum256(payload)
+	payloadSig, err := s.signer.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BreachDPBReport{
+		ID:               uuid.New(),
+		BreachID:         r.BreachID,
+		TenantID:         tenantID,
+		Kind:             string(kind),
+		Payload:          payload,
+		Warnings:         warnings,
+		PayloadSHA256:    hex.EncodeToString(payloadSum[:]),
+		PayloadSignature: payloadSig,
+		SigningKeyID:     s.signer.KeyID(),
+		GeneratedBy:      generatedBy,
+	}
+	pdf, err := renderDPBReportPDF(r, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report PDF: %w", err)
+	}
+	pdfSum := sha256.Sum256(pdf)
+	report.PDFSHA256 = hex.EncodeToString(pdfSum[:])
+	if report.PDFSignature, err = s.signer.Sign(pdf); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("breach-reports/%s/%s/%s_%s.pdf", tenantID, r.BreachID, kind, report.ID)
+	if report.PDFPath, err = s.store.Put(key, "application/pdf", pdf); err != nil {
+		return nil, err
+	}
+	if err := s.reportRepo.Create(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// List returns the generated reports for a breach, newest version first
+func (s *DPBReportService) List(tenantID, breachID uuid.UUID) ([]models.BreachDPBReport, error) {
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return s.reportRepo.GetByBreachID(breachID)
+}
+
+// Get returns a generated report belonging to the tenant
+func (s *DPBReportService) Get(tenantID, reportID uuid.UUID) (*models.BreachDPBReport, error) {
+	report, err := s.reportRepo.GetByID(reportID)
+	if err != nil || report.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return report, nil
+}
+
+// PDF returns the stored PDF after checking it still matches its signature
+func (s *DPBReportService) PDF(tenantID, reportID uuid.UUID) (*models.BreachDPBReport, []byte, error) {
+	report, err := s.Get(tenantID, reportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.store.Get(report.PDFPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.signer.Verify(data, report.PDFSignature) {
+		return nil, nil, fmt.Errorf("stored PDF for report %s fails signature check", report.ID)
+	}
+	return report, data, nil
+}
+
+// Verify checks a report's payload and PDF against their signatures
+func (s *DPBReportService) Verify(tenantID, reportID uuid.UUID) (bool, error) {
+	report, err := s.Get(tenantID, reportID)
+	if err != nil {
+		return false, err
+	}
+	data, err := s.store.Get(report.PDFPath)
+	if err != nil {
+		return false, err
+	}
+	return s.signer.Verify(report.Payload, report.PayloadSignature) && s.signer.Verify(data, report.PDFSignature), nil
+}
+
+func renderDPBReportPDF(r *breach.DPBIntimation, report *models.BreachDPBReport) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-18)
+		pdf.SetFont("Arial", "", 7)
+		pdf.MultiCell(0, 3.5, tr(fmt.Sprintf("Report %s | content SHA-256 %s | signed with key %s",
+			report.ID, report.PayloadSHA256, report.SigningKeyID)), "", "L", false)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	title := "Intimation of Personal Data Breach"
+	if r.Kind == breach.DPBReportDetailed {
+		title = "Detailed Report on Personal Data Breach"
+	}
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(0, 10, title)
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 10)
+	pdf.Cell(0, 6, "To: Data Protection Board of India, under the Digital Personal Data Protection Act, 2023")
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Generated: "+r.GeneratedAt.Format("02 Jan 2006 15:04 MST"))
+	pdf.Ln(10)
+
+	section := func(name string) {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 8, name)
+		pdf.Ln(8)
+	}
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(55, 6, tr(label))
+		pdf.SetFont("Arial", "", 10)
+		pdf.MultiCell(0, 6, tr(value), "", "L", false)
+	}
+	list := func(label string, items []string) {
+		field(label, strings.Join(items, "; "))
+	}
+	when := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format("02 Jan 2006 15:04 UTC")
+	}
+
+	section("Data Fiduciary")
+	field("Name", r.Fiduciary.Name)
+	field("Contact", r.Fiduciary.ContactEmail)
+	pdf.Ln(4)
+
+	section("Description of the breach")
+	field("Breach reference", r.BreachID.String())
+	field("Nature", r.Nature)
+	field("Title", r.Title)
+	field("Description", r.Description)
+	field("Severity", r.Severity)
+	field("Occurred", when(r.OccurredAt))
+	field("Detected", when(r.DetectedAt))
+	field("Contained", when(r.ContainedAt))
+	list("Location", r.Location)
+	field("Affected Data Principals", fmt.Sprintf("%d", r.Extent.AffectedPrincipals))
+	list("Data categories", r.Extent.DataCategories)
+	field("Likely impact", r.LikelyImpact)
+
+	if r.Kind == breach.DPBReportDetailed {
+		pdf.Ln(4)
+		section("Detailed report")
+		field("Circumstances", r.Circumstances)
+		list("Mitigation measures", r.MitigationMeasures)
+		field("Findings on the cause", r.CauseFindings)
+		list("Remedial measures", r.RemedialMeasures)
+		if pi := r.PrincipalIntimation; pi != nil {
+			field("Principals intimated", fmt.Sprintf("%d", pi.NotifiedCount))
+			field("Intimation sent", when(pi.SentAt))
+			list("Intimation channels", pi.Methods)
+		}
+		if len(r.Timeline) > 0 {
+			pdf.Ln(4)
+			section("Timeline")
+			for _, t := range r.Timeline {
+				at := t.At
+				field(when(&at), t.Event+": "+t.Description)
+			}
+		}
+		if len(r.Evidence) > 0 {
+			pdf.Ln(4)
+			section("Evidence on file")
+			for _, e := range r.Evidence {
+				field(e.Type, e.Title+" (SHA-256 "+e.SHA256+")")
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDPBReports(t *testing.T) (*DPBReportService, *gorm.DB) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}, &models.EncryptedBreachNotification{}, &models.BreachImpactAssessment{},
+		&models.BreachTimeline{}, &models.BreachEvidence{}, &models.BreachCommunication{}, &models.BreachDPBReport{},
+		&models.BreachRecipientList{}, &models.BreachRecipient{}))
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	svc := NewDPBReportService(
+		repository.NewBreachNotificationRepository(db),
+		repository.NewBreachImpactAssessmentRepository(db),
+		repository.NewBreachTimelineRepository(db),
+		repository.NewBreachEvidenceRepository(db),
+		repository.NewBreachCommunicationRepository(db),
+		repository.NewTenantRepository(db),
+		repository.NewBreachDPBReportRepository(db),
+		repository.NewBreachRecipientListRepository(db),
+		blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false),
+		NewDocumentSigner(key),
+	)
+	return svc, db
+}
+
+func TestDPBReport_ValidationFlagsMissingFields(t *testing.T) {
+	svc, db := setupDPBReports(t)
+	tenantID := uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme Retail"}).Error)
+	b := &models.BreachNotification{ID: uuid.New(), TenantID: tenantID, Description: "Exposed S3 bucket", BreachType: "unauthorized_access", DetectionDate: time.Now()}
+	require.NoError(t, repository.NewBreachNotificationRepository(db).CreateBreachNotification(b))
+
+	_, issues, err := svc.Validate(tenantID, b.ID, breach.DPBReportInitial)
+	require.NoError(t, err)
+	fields := map[string]bool{}
+	for _, i := range issues {
+		fields[i.Field] = true
+	}
+	for _, f := range []string{"occurredAt", "location", "extent.affectedPrincipals", "extent.dataCategories", "likelyImpact"} {
+		assert.True(t, fields[f], "expected %s to be flagged", f)
+	}
+	assert.False(t, fields["description"])
+	assert.False(t, fields["circumstances"], "detailed-only fields are not required initially")
+
+	_, err = svc.Generate(tenantID, b.ID, breach.DPBReportInitial, uuid.New())
+	var incomplete *DPBReportIncompleteError
+	require.True(t, errors.As(err, &incomplete))
+	assert.Contains(t, incomplete.Error(), "location")
+
+	_, _, err = svc.Validate(uuid.New(), b.ID, breach.DPBReportInitial)
+	assert.ErrorIs(t, err, ErrBreachNotFound, "other tenants cannot report on the breach")
+}
+
+func TestDPBReport_GeneratesSignedInitialAndDetailedReports(t *testing.T) {
+	svc, db := setupDPBReports(t)
+	tenantID, staffID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme Retail", Config: datatypes.JSON(`{"dpoEmail":"dpo@acme.example"}`)}).Error)
+
+	detected := time.Now().Add(-10 * time.Hour)
+	summary := "Misconfigured bucket policy after a deployment"
+	rootCause := "Deployment script applied a public-read policy; no external actor identified"
+	b := &models.BreachNotification{
+		ID: uuid.New(), TenantID: tenantID, Title: "Order export exposed", Description: "Order exports were publicly readable",
+		BreachType: "unauthorized_access", Severity: "high", BreachDate: detected.Add(-48 * time.Hour), DetectionDate: detected,
+		AffectedUsersCount: 1200, InvestigationSummary: &summary, RootCause: &rootCause,
+		RemedialActions:                   datatypes.JSON(`["Bucket made private","Access keys rotated"]`),
+		PreventiveMeasures:                datatypes.JSON(`["Policy linting in CI"]`),
+		RequiresDataPrincipalNotification: true, NotifiedUsersCount: 1180,
+	}
+	require.NoError(t, repository.NewBreachNotificationRepository(db).CreateBreachNotification(b))
+	require.NoError(t, db.Create(&models.BreachImpactAssessment{ID: uuid.New(), BreachID: b.ID, TenantID: tenantID,
+		LikelihoodOfHarm: "medium", ImpactLevel: "significant", DataCategoriesAffected: datatypes.JSON(`["contact","order_history"]`),
+		GeographicScope: datatypes.JSON(`["India"]`)}).Error)
+	require.NoError(t, repository.NewBreachTimelineRepository(db).Create(&models.BreachTimeline{BreachID: b.ID, TenantID: tenantID, EventType: "containment", Description: "Bucket locked down"}))
+	require.NoError(t, repository.NewBreachEvidenceRepository(db).Create(&models.BreachEvidence{BreachID: b.ID, TenantID: tenantID, EvidenceType: "logs", Title: "S3 access logs", FileHash: "abc123", CollectedAt: time.Now()}))
+
+	initial, err := svc.Generate(tenantID, b.ID, breach.DPBReportInitial, staffID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, initial.Version)
+	assert.Len(t, initial.PDFSHA256, 64)
+
+	var payload breach.DPBIntimation
+	require.NoError(t, json.Unmarshal(initial.Payload, &payload))
+	assert.Equal(t, "Acme Retail", payload.Fiduciary.Name)
+	assert.Equal(t, "dpo@acme.example", payload.Fiduciary.ContactEmail)
+	assert.Equal(t, "unauthorized access", payload.Nature)
+	assert.Equal(t, []string{"India"}, payload.Location)
+	assert.Equal(t, []string{"contact", "order_history"}, payload.Extent.DataCategories)
+	assert.Contains(t, payload.LikelyImpact, "Likelihood of harm: medium")
+	assert.NotNil(t, payload.ContainedAt)
+	assert.Empty(t, payload.Timeline, "the initial intimation stays brief")
+
+	_, pdf, err := svc.PDF(tenantID, initial.ID)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF")))
+	ok, err := svc.Verify(tenantID, initial.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	detailed, err := svc.Generate(tenantID, b.ID, breach.DPBReportDetailed, staffID)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(detailed.Payload, &payload))
+	assert.Equal(t, rootCause, payload.CauseFindings)
+	assert.Equal(t, []string{"Bucket made private", "Access keys rotated"}, payload.MitigationMeasures)
+	assert.Equal(t, 1180, payload.PrincipalIntimation.NotifiedCount)
+	require.Len(t, payload.Evidence, 1)
+	assert.Equal(t, "abc123", payload.Evidence[0].SHA256)
+
+	again, err := svc.Generate(tenantID, b.ID, breach.DPBReportInitial, staffID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, again.Version, "regenerating adds a version")
+
+	// Tampering with the stored payload breaks verification
+	require.NoError(t, db.Model(&models.BreachDPBReport{}).Where("id = ?", initial.ID).
+		Update("payload", datatypes.JSON(`{"kind":"initial"}`)).Error)
+	ok, err = svc.Verify(tenantID, initial.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
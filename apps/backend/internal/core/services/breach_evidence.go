@@ -0,0 +1,469 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+)
+
+// Custody log actions
+const (
+	CustodyUploaded        = "uploaded"
+	CustodyListed          = "listed" // The record appeared in a breach's evidence listing
+	CustodyViewed          = "viewed"
+	CustodyDownloaded      = "downloaded"
+	CustodyVerified        = "verified"
+	CustodyIntegrityFailed = "integrity_failed"
+	CustodyExported        = "exported"
+)
+
+var (
+	ErrEvidenceNotFound  = errors.New("evidence not found")
+	ErrEvidenceNoFile    = errors.New("evidence has no stored file")
+	ErrEvidenceEmpty     = errors.New("evidence file is empty")
+	ErrEvidenceTampered  = errors.New("evidence file does not match the digest taken at upload")
+	ErrEvidenceNotSigned = errors.New("evidence export requires document signing")
+)
+
+// CustodyActor identifies who touched an evidence item and from where
+type CustodyActor struct {
+	ID        uuid.UUID
+	IPAddress string
+	UserAgent string
+}
+
+// EvidenceUpload describes a file added to a breach's evidence
+type EvidenceUpload struct {
+	EvidenceType   string
+	Title          string
+	Description    string
+	FileName       string
+	ContentType    string
+	IsConfidential bool
+}
+
+// EvidenceVerification is the result of re-reading an item against its digest
+// and checking its custody log
+type EvidenceVerification struct {
+	EvidenceID     uuid.UUID `json:"evidenceId"`
+	ExpectedSHA256 string    `json:"expectedSha256"`
+	ActualSHA256   string    `json:"actualSha256,omitempty"`
+	FileIntact     bool      `json:"fileIntact"`
+	ChainIntact    bool      `json:"chainIntact"`
+	ChainBrokenAt  int       `json:"chainBrokenAt,omitempty"` // Sequence of the first entry that fails
+	CustodyEvents  int       `json:"custodyEvents"`
+}
+
+// EvidenceManifest is the signed index of an evidence export
+type EvidenceManifest struct {
+	BreachID     uuid.UUID               `json:"breachId"`
+	TenantID     uuid.UUID               `json:"tenantId"`
+	GeneratedAt  time.Time               `json:"generatedAt"`
+	GeneratedBy  uuid.UUID               `json:"generatedBy"`
+	SigningKeyID string                  `json:"signingKeyId"`
+	Items        []EvidenceManifestEntry `json:"items"`
+}
+
+// EvidenceManifestEntry describes one item in an export. Path is empty for
+// items recorded without a file.
+type EvidenceManifestEntry struct {
+	EvidenceID   uuid.UUID                           `json:"evidenceId"`
+	EvidenceType string                              `json:"evidenceType"`
+	Title        string                              `json:"title"`
+	Description  string                              `json:"description,omitempty"`
+	Path         string                              `json:"path,omitempty"`
+	FileName     string                              `json:"fileName,omitempty"`
+	ContentType  string                              `json:"contentType,omitempty"`
+	FileSize     int64                               `json:"fileSize"`
+	SHA256       string                              `json:"sha256,omitempty"`
+	UploadedBy   uuid.UUID                           `json:"uploadedBy"`
+	UploadedAt   time.Time                           `json:"uploadedAt"`
+	RetainUntil  *time.Time                          `json:"retainUntil,omitempty"`
+	FileIntact   bool                                `json:"fileIntact"`
+	ChainIntact  bool                                `json:"chainIntact"`
+	CustodyLog   []models.BreachEvidenceCustodyEvent `json:"custodyLog"`
+}
+
+// BreachEvidenceService stores breach evidence write-once, logs every access
+// to it in a hash-chained custody log and exports it with a signed manifest.
+type BreachEvidenceService struct {
+	breachRepo    *repository.BreachNotificationRepository
+	evidenceRepo  *repository.BreachEvidenceRepository
+	custodyRepo   *repository.BreachEvidenceCustodyRepository
+	timelineRepo  *repository.BreachTimelineRepository
+	store         *blob.Store
+	signer        *DocumentSigner
+	retentionDays int
+}
+
+func NewBreachEvidenceService(
+	breachRepo *repository.BreachNotificationRepository,
+	evidenceRepo *repository.BreachEvidenceRepository,
+	custodyRepo *repository.BreachEvidenceCustodyRepository,
+	timelineRepo *repository.BreachTimelineRepository,
+	store *blob.Store,
+	signer *DocumentSigner,
+	retentionDays int,
+) *BreachEvidenceService {
+	return &BreachEvidenceService{
+		breachRepo:    breachRepo,
+		evidenceRepo:  evidenceRepo,
+		custodyRepo:   custodyRepo,
+		timelineRepo:  timelineRepo,
+		store:         store,
+		signer:        signer,
+		retentionDays: retentionDays,
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *BreachEvidenceService) breachForTenant(tenantID, breachID uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return b, nil
+}
+
+func (s *BreachEvidenceService) evidenceForTenant(tenantID, evidenceID uuid.UUID) (*models.BreachEvidence, error) {
+	e, err := s.evidenceRepo.GetByID(evidenceID)
+	if err != nil || e.TenantID != tenantID {
+		return nil, ErrEvidenceNotFound
+	}
+	return e, nil
+}
+
+func (s *BreachEvidenceService) logCustody(e *models.BreachEvidence, action string, actor CustodyActor, digest, note string) error {
+	event := &models.BreachEvidenceCustodyEvent{
+		EvidenceID: e.ID,
+		BreachID:   e.BreachID,
+		TenantID:   e.TenantID,
+		Action:     action,
+		IPAddress:  actor.IPAddress,
+		UserAgent:  actor.UserAgent,
+		SHA256:     digest,
+		Note:       note,
+	}
+	if actor.ID != uuid.Nil {
+		id := actor.ID
+		event.ActorID = &id
+	}
+	return s.custodyRepo.Append(event)
+}
+
+// Upload stores a file as evidence. The digest is taken before the write and
+// the file goes to a key that is never reused, so it cannot be overwritten.
+func (s *BreachEvidenceService) Upload(tenantID, breachID uuid.UUID, in EvidenceUpload, data []byte, actor CustodyActor) (*models.BreachEvidence, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrEvidenceEmpty
+	}
+	now := time.Now().UTC()
+	e := &models.BreachEvidence{
+		ID:             uuid.New(),
+		BreachID:       b.ID,
+		TenantID:       b.TenantID,
+		EvidenceType:   in.EvidenceType,
+		Title:          in.Title,
+		Description:    in.Description,
+		FileName:       filepath.Base(strings.ReplaceAll(in.FileName, "..", "")),
+		ContentType:    in.ContentType,
+		FileSize:       int64(len(data)),
+		FileHash:       sha256Hex(data),
+		CollectedBy:    actor.ID,
+		CollectedAt:    now,
+		IsConfidential: in.IsConfidential,
+	}
+	if e.Title == "" {
+		e.Title = e.FileName
+	}
+	if e.ContentType == "" {
+		e.ContentType = "application/octet-stream"
+	}
+	var retainUntil time.Time
+	if s.retentionDays > 0 {
+		retainUntil = now.AddDate(0, 0, s.retentionDays)
+		e.RetainUntil = &retainUntil
+	}
+
+	key := fmt.Sprintf("breach-evidence/%s/%s/%s/%s", e.TenantID, e.BreachID, e.ID, e.FileName)
+	e.FilePath, err = s.store.PutOnce(key, e.ContentType, data, retainUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store evidence: %w", err)
+	}
+	if err := s.evidenceRepo.Create(e); err != nil {
+		return nil, err
+	}
+	if err := s.logCustody(e, CustodyUploaded, actor, e.FileHash, ""); err != nil {
+		return nil, err
+	}
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    e.BreachID,
+		TenantID:    e.TenantID,
+		EventType:   "evidence_added",
+		Description: fmt.Sprintf("Evidence %q added (SHA-256 %s)", e.Title, e.FileHash),
+		PerformedBy: &actor.ID,
+	})
+	return e, nil
+}
+
+// List returns the evidence recorded against a breach and logs the listing
+// against each item
+func (s *BreachEvidenceService) List(tenantID, breachID uuid.UUID, actor CustodyActor) ([]models.BreachEvidence, error) {
+	if _, err := s.breachForTenant(tenantID, breachID); err != nil {
+		return nil, err
+	}
+	items, err := s.evidenceRepo.GetByBreachID(breachID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if err := s.logCustody(&items[i], CustodyListed, actor, "", ""); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// View returns an item's record and logs the view
+func (s *BreachEvidenceService) View(tenantID, evidenceID uuid.UUID, actor CustodyActor) (*models.BreachEvidence, error) {
+	e, err := s.evidenceForTenant(tenantID, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.logCustody(e, CustodyViewed, actor, "", ""); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Download reads an item's file and logs the download with the digest of
+// what was read. A file that no longer matches its upload digest is not
+// served; the mismatch is logged instead.
+func (s *BreachEvidenceService) Download(tenantID, evidenceID uuid.UUID, actor CustodyActor) (*models.BreachEvidence, []byte, error) {
+	e, err := s.evidenceForTenant(tenantID, evidenceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.FilePath == "" {
+		return nil, nil, ErrEvidenceNoFile
+	}
+	data, err := s.store.Get(e.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest := sha256Hex(data)
+	if digest != e.FileHash {
+		if err := s.logCustody(e, CustodyIntegrityFailed, actor, digest, "download refused"); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrEvidenceTampered
+	}
+	if err := s.logCustody(e, CustodyDownloaded, actor, digest, ""); err != nil {
+		return nil, nil, err
+	}
+	return e, data, nil
+}
+
+// CustodyLog returns an item's custody log in order
+func (s *BreachEvidenceService) CustodyLog(tenantID, evidenceID uuid.UUID) ([]models.BreachEvidenceCustodyEvent, error) {
+	if _, err := s.evidenceForTenant(tenantID, evidenceID); err != nil {
+		return nil, err
+	}
+	return s.custodyRepo.ByEvidence(evidenceID)
+}
+
+// VerifyCustodyChain recomputes each entry's hash and link to its
+// predecessor. It returns the sequence of the first bad entry, or 0.
+func VerifyCustodyChain(events []models.BreachEvidenceCustodyEvent) int {
+	prev := ""
+	for i := range events {
+		e := &events[i]
+		if e.Sequence != i+1 || e.PreviousHash != prev || repository.CustodyEventHash(e) != e.EventHash {
+			if e.Sequence > 0 {
+				return e.Sequence
+			}
+			return i + 1
+		}
+		prev = e.EventHash
+	}
+	return 0
+}
+
+// Verify re-reads an item's file against its upload digest, checks the
+// custody log and records the outcome in the log
+func (s *BreachEvidenceService) Verify(tenantID, evidenceID uuid.UUID, actor CustodyActor) (*EvidenceVerification, error) {
+	e, err := s.evidenceForTenant(tenantID, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.check(e)
+	if err != nil {
+		return nil, err
+	}
+	action := CustodyVerified
+	if !v.FileIntact || !v.ChainIntact {
+		action = CustodyIntegrityFailed
+	}
+	if err := s.logCustody(e, action, actor, v.ActualSHA256, ""); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *BreachEvidenceService) check(e *models.BreachEvidence) (*EvidenceVerification, error) {
+	v := &EvidenceVerification{EvidenceID: e.ID, ExpectedSHA256: e.FileHash}
+	if e.FilePath != "" {
+		data, err := s.store.Get(e.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		v.ActualSHA256 = sha256Hex(data)
+		v.FileIntact = v.ActualSHA256 == e.FileHash
+	}
+	events, err := s.custodyRepo.ByEvidence(e.ID)
+	if err != nil {
+		return nil, err
+	}
+	v.CustodyEvents = len(events)
+	v.ChainBrokenAt = VerifyCustodyChain(events)
+	v.ChainIntact = v.ChainBrokenAt == 0
+	return v, nil
+}
+
+// Export bundles a breach's evidence files into a zip with manifest.json,
+// listing digests, uploader, timestamps and custody log of each item, and
+// manifest.sig, the detached signature over manifest.json. The export is
+// logged on every item before the manifest is built, so the manifest's logs
+// include it. It returns the archive and the manifest signature.
+func (s *BreachEvidenceService) Export(tenantID, breachID uuid.UUID, actor CustodyActor) ([]byte, string, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.signer == nil || s.signer.KeyID() == "" {
+		return nil, "", ErrEvidenceNotSigned
+	}
+	items, err := s.evidenceRepo.GetByBreachID(b.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manifest := EvidenceManifest{
+		BreachID:     b.ID,
+		TenantID:     b.TenantID,
+		GeneratedAt:  time.Now().UTC(),
+		GeneratedBy:  actor.ID,
+		SigningKeyID: s.signer.KeyID(),
+		Items:        make([]EvidenceManifestEntry, 0, len(items)),
+	}
+	files := map[string][]byte{}
+	for i := range items {
+		e := &items[i]
+		entry := EvidenceManifestEntry{
+			EvidenceID:   e.ID,
+			EvidenceType: e.EvidenceType,
+			Title:        e.Title,
+			Description:  e.Description,
+			FileName:     e.FileName,
+			ContentType:  e.ContentType,
+			FileSize:     e.FileSize,
+			SHA256:       e.FileHash,
+			UploadedBy:   e.CollectedBy,
+			UploadedAt:   e.CollectedAt,
+			RetainUntil:  e.RetainUntil,
+		}
+		digest := ""
+		if e.FilePath != "" {
+			data, err := s.store.Get(e.FilePath)
+			if err != nil {
+				return nil, "", err
+			}
+			digest = sha256Hex(data)
+			entry.FileIntact = digest == e.FileHash
+			entry.Path = fmt.Sprintf("evidence/%s/%s", e.ID, e.FileName)
+			files[entry.Path] = data
+		}
+		if err := s.logCustody(e, CustodyExported, actor, digest, ""); err != nil {
+			return nil, "", err
+		}
+		events, err := s.custodyRepo.ByEvidence(e.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		entry.CustodyLog = events
+		entry.ChainIntact = VerifyCustodyChain(events) == 0
+		manifest.Items = append(manifest.Items, entry)
+	}
+
+	doc, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+	sig, err := s.signer.Sign(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, data []byte) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	if err := write("manifest.json", doc); err != nil {
+		return nil, "", err
+	}
+	if err := write("manifest.sig", []byte(sig)); err != nil {
+		return nil, "", err
+	}
+	for _, entry := range manifest.Items {
+		if entry.Path == "" {
+			continue
+		}
+		if err := write(entry.Path, files[entry.Path]); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    b.TenantID,
+		EventType:   "evidence_exported",
+		Description: fmt.Sprintf("Evidence bundle of %d item(s) exported (manifest SHA-256 %s)", len(manifest.Items), sha256Hex(doc)),
+		PerformedBy: &actor.ID,
+	})
+	return buf.Bytes(), sig, nil
+}
+
+// SigningKeyID identifies the key evidence manifests are signed with
+func (s *BreachEvidenceService) SigningKeyID() string {
+	return s.signer.KeyID()
+}
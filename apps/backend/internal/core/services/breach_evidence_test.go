@@ -0,0 +1,113 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBreachEvidence_CustodyChainAndSignedExport(t *testing.T) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.EncryptedBreachNotification{}, &models.BreachEvidence{},
+		&models.BreachEvidenceCustodyEvent{}, &models.BreachTimeline{}))
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer := NewDocumentSigner(key)
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	svc := NewBreachEvidenceService(breachRepo, repository.NewBreachEvidenceRepository(db), repository.NewBreachEvidenceCustodyRepository(db),
+		repository.NewBreachTimelineRepository(db), blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false), signer, 0)
+
+	tenantID := uuid.New()
+	b := &models.BreachNotification{ID: uuid.New(), TenantID: tenantID, Description: "Exposed S3 bucket", BreachType: "unauthorized_access"}
+	require.NoError(t, breachRepo.CreateBreachNotification(b))
+	actor := CustodyActor{ID: uuid.New(), IPAddress: "10.0.0.5", UserAgent: "curl/8"}
+
+	content := []byte("GET /bucket/customers.csv 200")
+	e, err := svc.Upload(tenantID, b.ID, EvidenceUpload{EvidenceType: "logs", FileName: "../access.log", ContentType: "text/plain"}, content, actor)
+	require.NoError(t, err)
+	assert.Equal(t, sha256Hex(content), e.FileHash)
+	assert.Equal(t, "access.log", e.FileName)
+
+	// Other tenants cannot see the item
+	_, err = svc.View(uuid.New(), e.ID, actor)
+	assert.ErrorIs(t, err, ErrEvidenceNotFound)
+
+	listed, err := svc.List(tenantID, b.ID, actor)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	_, err = svc.View(tenantID, e.ID, actor)
+	require.NoError(t, err)
+	_, data, err := svc.Download(tenantID, e.ID, actor)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	events, err := svc.CustodyLog(tenantID, e.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, []string{CustodyUploaded, CustodyListed, CustodyViewed, CustodyDownloaded},
+		[]string{events[0].Action, events[1].Action, events[2].Action, events[3].Action})
+	assert.Equal(t, "10.0.0.5", events[3].IPAddress)
+	assert.Equal(t, e.FileHash, events[3].SHA256)
+	assert.Zero(t, VerifyCustodyChain(events))
+
+	// Export bundles the file with a manifest signed over its exact bytes
+	bundle, sig, err := svc.Export(tenantID, b.ID, actor)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	assert.True(t, signer.Verify(files["manifest.json"], sig))
+	assert.Equal(t, sig, string(files["manifest.sig"]))
+	var manifest EvidenceManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Len(t, manifest.Items, 1)
+	item := manifest.Items[0]
+	assert.Equal(t, e.FileHash, item.SHA256)
+	assert.Equal(t, actor.ID, item.UploadedBy)
+	assert.True(t, item.FileIntact)
+	assert.True(t, item.ChainIntact)
+	require.Len(t, item.CustodyLog, 5)
+	assert.Equal(t, CustodyExported, item.CustodyLog[4].Action)
+	assert.Equal(t, content, files[item.Path])
+
+	// Editing a logged entry breaks the chain from that entry on
+	require.NoError(t, db.Model(&models.BreachEvidenceCustodyEvent{}).Where("id = ?", events[1].ID).Update("ip_address", "192.168.1.1").Error)
+	v, err := svc.Verify(tenantID, e.ID, actor)
+	require.NoError(t, err)
+	assert.True(t, v.FileIntact)
+	assert.False(t, v.ChainIntact)
+	assert.Equal(t, 2, v.ChainBrokenAt)
+
+	// A swapped file is detected and not served
+	require.NoError(t, os.Chmod(e.FilePath, 0o644))
+	require.NoError(t, os.WriteFile(e.FilePath, []byte("GET /bucket/nothing 404"), 0o644))
+	_, _, err = svc.Download(tenantID, e.ID, actor)
+	assert.ErrorIs(t, err, ErrEvidenceTampered)
+	events, err = svc.CustodyLog(tenantID, e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CustodyIntegrityFailed, events[len(events)-1].Action)
+}
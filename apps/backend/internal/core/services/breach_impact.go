@@ -0,0 +1,449 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRecipientListNotFound is returned when a breach has no such recipient list
+var ErrRecipientListNotFound = errors.New("recipient list not found")
+
+// BreachImpactResolution is the outcome of resolving an impact query: the
+// affected Data Principals and how many fall under each data category.
+type BreachImpactResolution struct {
+	Query                 breach.ImpactQuery       `json:"query"`
+	DataCategories        []string                 `json:"dataCategories"`
+	UnconfirmedCategories []string                 `json:"unconfirmedCategories"`
+	DiscoveredCategories  []string                 `json:"discoveredCategories"`
+	CategoryCounts        map[string]int           `json:"categoryCounts"`
+	TotalRecipients       int                      `json:"totalRecipients"`
+	Warnings              []string                 `json:"warnings"`
+	Recipients            []models.BreachRecipient `json:"-"`
+}
+
+// BreachImpactService works out which Data Principals a breach affects. A
+// principal is affected when they held an active consent, during the exposure
+// window, to a purpose whose data objects fall in an affected category.
+// Discovery findings for the breached systems confirm or extend the
+// categories. Resolved lists are frozen as versions.
+type BreachImpactService struct {
+	db           *gorm.DB
+	breachRepo   *repository.BreachNotificationRepository
+	listRepo     *repository.BreachRecipientListRepository
+	timelineRepo *repository.BreachTimelineRepository
+}
+
+func NewBreachImpactService(
+	db *gorm.DB,
+	breachRepo *repository.BreachNotificationRepository,
+	listRepo *repository.BreachRecipientListRepository,
+	timelineRepo *repository.BreachTimelineRepository,
+) *BreachImpactService {
+	return &BreachImpactService{db: db, breachRepo: breachRepo, listRepo: listRepo, timelineRepo: timelineRepo}
+}
+
+func (s *BreachImpactService) breachForTenant(tenantID, breachID uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.breachRepo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	return b, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// inChunks runs fn over ids in slices small enough for an IN clause
+func inChunks(ids []uuid.UUID, fn func([]uuid.UUID) error) error {
+	const size = 1000
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Preview resolves the query without saving anything. Blank query fields
+// default from the breach: the window runs from occurrence to containment
+// (or now) and categories come from the breach record.
+func (s *BreachImpactService) Preview(tenantID, breachID uuid.UUID, q breach.ImpactQuery) (*BreachImpactResolution, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(b, q)
+}
+
+func (s *BreachImpactService) resolve(b *models.BreachNotification, q breach.ImpactQuery) (*BreachImpactResolution, error) {
+	if q.WindowStart == nil {
+		q.WindowStart = nonZeroTime(b.BreachDate)
+	}
+	if q.WindowEnd == nil {
+		end := time.Now().UTC()
+		if b.ContainmentDate != nil {
+			end = *b.ContainmentDate
+		}
+		q.WindowEnd = &end
+	}
+	if len(q.DataCategories) == 0 {
+		q.DataCategories = jsonStrings(b.DataCategoriesAffected)
+	}
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	res := &BreachImpactResolution{
+		Query:                 q,
+		UnconfirmedCategories: []string{},
+		DiscoveredCategories:  []string{},
+		CategoryCounts:        map[string]int{},
+		Warnings:              []string{},
+	}
+
+	requested := map[string]bool{}
+	for _, c := range q.DataCategories {
+		if c = breach.NormalizeDataItem(c); c != "" {
+			requested[c] = true
+		}
+	}
+
+	// Discovery findings show what personal data the breached systems hold.
+	// Requested categories they don't show are kept, since the scan may be
+	// incomplete, but flagged; categories they show beyond the request are
+	// added, since the data was exposed whether or not it was reported.
+	categories := map[string]bool{}
+	for c := range requested {
+		categories[c] = true
+	}
+	if len(q.SystemIDs) > 0 {
+		var findings []models.DiscoveryResult
+		if err := s.db.Where("tenant_id = ? AND data_source_id IN ?", b.TenantID, q.SystemIDs).
+			Find(&findings).Error; err != nil {
+			return nil, err
+		}
+		discovered := map[string]bool{}
+		for _, f := range findings {
+			discovered[breach.CategoryForItem(f.PIIType)] = true
+		}
+		res.DiscoveredCategories = sortedKeys(discovered)
+		if len(findings) == 0 {
+			res.Warnings = append(res.Warnings, "no discovery findings for the breached systems; data categories are unconfirmed")
+		}
+		for c := range requested {
+			if len(findings) > 0 && !discovered[c] {
+				res.UnconfirmedCategories = append(res.UnconfirmedCategories, c)
+			}
+		}
+		sort.Strings(res.UnconfirmedCategories)
+		for c := range discovered {
+			if !categories[c] {
+				categories[c] = true
+				res.Warnings = append(res.Warnings, fmt.Sprintf("discovery found %q data in the breached systems that was not listed as affected", c))
+			}
+		}
+	}
+	res.DataCategories = sortedKeys(categories)
+	if len(res.DataCategories) == 0 {
+		res.Warnings = append(res.Warnings, "no data categories to resolve")
+		return res, nil
+	}
+
+	// Purposes in scope, per consent form, with the categories their data objects cover
+	var formPurposes []models.ConsentFormPurpose
+	if err := s.db.Joins("JOIN consent_forms ON consent_forms.id = consent_form_purposes.consent_form_id").
+		Where("consent_forms.tenant_id = ?", b.TenantID).
+		Find(&formPurposes).Error; err != nil {
+		return nil, err
+	}
+	type scopeKey struct{ form, purpose uuid.UUID }
+	scope := map[scopeKey][]string{}
+	byPurpose := map[uuid.UUID]map[string]bool{}
+	for _, fp := range formPurposes {
+		cats := map[string]bool{}
+		for _, obj := range fp.DataObjects {
+			for _, c := range breach.CategoriesOf(obj, res.DataCategories) {
+				cats[c] = true
+			}
+		}
+		if len(cats) == 0 {
+			continue
+		}
+		scope[scopeKey{fp.ConsentFormID, fp.PurposeID}] = sortedKeys(cats)
+		if byPurpose[fp.PurposeID] == nil {
+			byPurpose[fp.PurposeID] = map[string]bool{}
+		}
+		for c := range cats {
+			byPurpose[fp.PurposeID][c] = true
+		}
+	}
+	if len(byPurpose) == 0 {
+		res.Warnings = append(res.Warnings, "no consented purpose collects data in the affected categories")
+		return res, nil
+	}
+	purposeIDs := make([]uuid.UUID, 0, len(byPurpose))
+	for id := range byPurpose {
+		purposeIDs = append(purposeIDs, id)
+	}
+
+	// Consents live during the window: granted before it ended, not withdrawn
+	// or expired before it began
+	var consents []models.UserConsent
+	if err := s.db.Where("tenant_id = ? AND purpose_id IN ? AND created_at <= ?", b.TenantID, purposeIDs, *q.WindowEnd).
+		Find(&consents).Error; err != nil {
+		return nil, err
+	}
+	type hit struct {
+		categories map[string]bool
+		purposes   map[uuid.UUID]bool
+	}
+	hits := map[uuid.UUID]*hit{}
+	for _, c := range consents {
+		if q.WindowStart != nil {
+			if !c.Status && c.UpdatedAt.Before(*q.WindowStart) {
+				continue
+			}
+			if c.ExpiresAt != nil && c.ExpiresAt.Before(*q.WindowStart) {
+				continue
+			}
+		}
+		cats, ok := scope[scopeKey{c.ConsentFormID, c.PurposeID}]
+		if !ok {
+			// Consents given outside a form carry every category the purpose covers
+			if c.ConsentFormID != uuid.Nil {
+				continue
+			}
+			cats = sortedKeys(byPurpose[c.PurposeID])
+		}
+		h := hits[c.UserID]
+		if h == nil {
+			h = &hit{categories: map[string]bool{}, purposes: map[uuid.UUID]bool{}}
+			hits[c.UserID] = h
+		}
+		for _, cat := range cats {
+			h.categories[cat] = true
+		}
+		h.purposes[c.PurposeID] = true
+	}
+	if len(hits) == 0 {
+		return res, nil
+	}
+
+	// Only principals that still exist can be notified
+	userIDs := make([]uuid.UUID, 0, len(hits))
+	for id := range hits {
+		userIDs = append(userIDs, id)
+	}
+	var known []uuid.UUID
+	if err := inChunks(userIDs, func(chunk []uuid.UUID) error {
+		var found []uuid.UUID
+		if err := s.db.Model(&models.DataPrincipal{}).Where("id IN ?", chunk).Pluck("id", &found).Error; err != nil {
+			return err
+		}
+		known = append(known, found...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if missing := len(userIDs) - len(known); missing > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%d consenting principals no longer have a record and were left out", missing))
+	}
+	sort.Slice(known, func(i, j int) bool { return known[i].String() < known[j].String() })
+
+	for _, id := range known {
+		h := hits[id]
+		cats := sortedKeys(h.categories)
+		purposes := make([]string, 0, len(h.purposes))
+		for p := range h.purposes {
+			purposes = append(purposes, p.String())
+		}
+		sort.Strings(purposes)
+		catsJSON, _ := json.Marshal(cats)
+		purposesJSON, _ := json.Marshal(purposes)
+		res.Recipients = append(res.Recipients, models.BreachRecipient{
+			ID:              uuid.New(),
+			BreachID:        b.ID,
+			TenantID:        b.TenantID,
+			DataPrincipalID: id,
+			Categories:      catsJSON,
+			PurposeIDs:      purposesJSON,
+		})
+		for _, c := range cats {
+			res.CategoryCounts[c]++
+		}
+	}
+	res.TotalRecipients = len(res.Recipients)
+	return res, nil
+}
+
+// recipientsDigest fingerprints a list's content so a frozen list can be
+// shown to be unchanged
+func recipientsDigest(recipients []models.BreachRecipient) string {
+	h := sha256.New()
+	for _, r := range recipients {
+		fmt.Fprintf(h, "%s:%s\n", r.DataPrincipalID, r.Categories)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Freeze resolves the query and stores the result as the breach's next
+// recipient list version. The breach's affected count and categories are
+// updated to match.
+func (s *BreachImpactService) Freeze(tenantID, breachID uuid.UUID, q breach.ImpactQuery, createdBy uuid.UUID) (*models.BreachRecipientList, *BreachImpactResolution, error) {
+	b, err := s.breachForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := s.resolve(b, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query, _ := json.Marshal(res.Query)
+	categories, _ := json.Marshal(res.DataCategories)
+	unconfirmed, _ := json.Marshal(res.UnconfirmedCategories)
+	counts, _ := json.Marshal(res.CategoryCounts)
+	list := &models.BreachRecipientList{
+		ID:                    uuid.New(),
+		BreachID:              b.ID,
+		TenantID:              tenantID,
+		Query:                 query,
+		DataCategories:        categories,
+		UnconfirmedCategories: unconfirmed,
+		CategoryCounts:        counts,
+		TotalRecipients:       res.TotalRecipients,
+		ContentSHA256:         recipientsDigest(res.Recipients),
+		CreatedBy:             createdBy,
+	}
+	if err := s.listRepo.Create(list, res.Recipients); err != nil {
+		return nil, nil, err
+	}
+
+	b.AffectedUsersCount = list.TotalRecipients
+	b.DataCategoriesAffected = categories
+	if err := s.breachRepo.UpdateBreachNotification(b); err != nil {
+		return nil, nil, err
+	}
+	_ = s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    tenantID,
+		EventType:   "recipient_list_frozen",
+		Description: fmt.Sprintf("Recipient list v%d frozen with %d affected Data Principals (%s)", list.Version, list.TotalRecipients, strings.Join(res.DataCategories, ", ")),
+		PerformedBy: &createdBy,
+		EventData:   counts,
+	})
+	return list, res, nil
+}
+
+// Lists returns the breach's recipient lists, newest version first
+func (s *BreachImpactService) Lists(tenantID, breachID uuid.UUID) ([]models.BreachRecipientList, error) {
+	if _, err := s.breachForTenant(tenantID, breachID); err != nil {
+		return nil, err
+	}
+	return s.listRepo.GetByBreachID(breachID)
+}
+
+// Get returns a recipient list; a nil listID means the breach's latest
+func (s *BreachImpactService) Get(tenantID, breachID, listID uuid.UUID) (*models.BreachRecipientList, error) {
+	if _, err := s.breachForTenant(tenantID, breachID); err != nil {
+		return nil, err
+	}
+	var list *models.BreachRecipientList
+	var err error
+	if listID == uuid.Nil {
+		list, err = s.listRepo.GetLatest(breachID)
+	} else {
+		list, err = s.listRepo.GetByID(listID)
+	}
+	if err != nil || list.BreachID != breachID {
+		return nil, ErrRecipientListNotFound
+	}
+	return list, nil
+}
+
+// Recipients pages through a list's recipients
+func (s *BreachImpactService) Recipients(tenantID, breachID, listID uuid.UUID, limit, offset int) ([]models.BreachRecipient, error) {
+	list, err := s.Get(tenantID, breachID, listID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listRepo.GetRecipients(list.ID, limit, offset)
+}
+
+// VerifyList recomputes the digest of a frozen list's recipients
+func (s *BreachImpactService) VerifyList(tenantID, breachID, listID uuid.UUID) (bool, error) {
+	list, err := s.Get(tenantID, breachID, listID)
+	if err != nil {
+		return false, err
+	}
+	recipients, err := s.listRepo.GetRecipients(list.ID, 0, 0)
+	if err != nil {
+		return false, err
+	}
+	return len(recipients) == list.TotalRecipients && recipientsDigest(recipients) == list.ContentSHA256, nil
+}
+
+// BreachRecipientContact is how to reach one Data Principal on a list
+type BreachRecipientContact struct {
+	DataPrincipalID uuid.UUID
+	Email           string
+	Phone           string
+}
+
+// RecipientContacts looks up the contact details of everyone on a list.
+// Recipients whose principal record has since been deleted are left out.
+func (s *BreachImpactService) RecipientContacts(tenantID, breachID, listID uuid.UUID) (*models.BreachRecipientList, []BreachRecipientContact, error) {
+	list, err := s.Get(tenantID, breachID, listID)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipients, err := s.listRepo.GetRecipients(list.ID, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(recipients))
+	for _, r := range recipients {
+		ids = append(ids, r.DataPrincipalID)
+	}
+	contacts := make([]BreachRecipientContact, 0, len(ids))
+	if err := inChunks(ids, func(chunk []uuid.UUID) error {
+		var found []models.DataPrincipal
+		if err := s.db.Select("id", "email", "phone").Where("id IN ?", chunk).Order("id").Find(&found).Error; err != nil {
+			return err
+		}
+		for _, p := range found {
+			contacts = append(contacts, BreachRecipientContact{
+				DataPrincipalID: p.ID,
+				Email:           strings.TrimSpace(p.Email),
+				Phone:           strings.TrimSpace(p.Phone),
+			})
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return list, contacts, nil
+}
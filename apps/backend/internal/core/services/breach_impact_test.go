@@ -0,0 +1,167 @@
+package services
+
+import (
+	"encoding/json"
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type breachImpactFixture struct {
+	svc      *BreachImpactService
+	db       *gorm.DB
+	tenantID uuid.UUID
+	breach   *models.BreachNotification
+	system   uuid.UUID
+	alice    uuid.UUID // contact data, consent active
+	bob      uuid.UUID // identity data only
+}
+
+func setupBreachImpact(t *testing.T) *breachImpactFixture {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.EncryptedBreachNotification{}, &models.BreachTimeline{},
+		&models.BreachRecipientList{}, &models.BreachRecipient{}, &models.DataPrincipal{}, &models.UserConsent{},
+		&models.ConsentForm{}, &models.ConsentFormPurpose{}, &models.DiscoveryResult{}))
+
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	f := &breachImpactFixture{
+		svc:      NewBreachImpactService(db, breachRepo, repository.NewBreachRecipientListRepository(db), repository.NewBreachTimelineRepository(db)),
+		db:       db,
+		tenantID: uuid.New(),
+		system:   uuid.New(),
+	}
+	now := time.Now()
+	f.breach = &models.BreachNotification{ID: uuid.New(), TenantID: f.tenantID, Description: "Customer DB snapshot leaked",
+		BreachType: "data_theft", BreachDate: now.Add(-48 * time.Hour), DetectionDate: now.Add(-24 * time.Hour),
+		DataCategoriesAffected: datatypes.JSON(`["contact"]`)}
+	require.NoError(t, breachRepo.CreateBreachNotification(f.breach))
+
+	form := models.ConsentForm{ID: uuid.New(), TenantID: f.tenantID, Name: "Signup", FormLink: "signup"}
+	require.NoError(t, db.Create(&form).Error)
+	newsletter, kyc, prefs := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&[]models.ConsentFormPurpose{
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: newsletter, DataObjects: pq.StringArray{"Email", "Phone Number"}},
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: kyc, DataObjects: pq.StringArray{"pan"}},
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: prefs, DataObjects: pq.StringArray{"theme"}},
+	}).Error)
+
+	principal := func(email string) uuid.UUID {
+		p := models.DataPrincipal{ID: uuid.New(), TenantID: f.tenantID, Email: email}
+		require.NoError(t, db.Create(&p).Error)
+		return p.ID
+	}
+	consent := func(user, purpose uuid.UUID, granted bool, created, updated time.Time) {
+		require.NoError(t, db.Create(&models.UserConsent{ID: uuid.New(), UserID: user, PurposeID: purpose, TenantID: f.tenantID,
+			ConsentFormID: form.ID, Status: granted, CreatedAt: created, UpdatedAt: updated}).Error)
+	}
+	f.alice, f.bob = principal("alice@example.com"), principal("bob@example.com")
+	withdrawn, late, unrelated := principal("carol@example.com"), principal("dan@example.com"), principal("erin@example.com")
+	consent(f.alice, newsletter, true, now.Add(-30*24*time.Hour), now.Add(-30*24*time.Hour))
+	consent(f.bob, kyc, true, now.Add(-30*24*time.Hour), now.Add(-30*24*time.Hour))
+	consent(withdrawn, newsletter, false, now.Add(-30*24*time.Hour), now.Add(-72*time.Hour))
+	consent(late, newsletter, true, now, now)
+	consent(unrelated, prefs, true, now.Add(-30*24*time.Hour), now.Add(-30*24*time.Hour))
+
+	require.NoError(t, db.Create(&[]models.DiscoveryResult{
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.system, TableName: "users", ColumnName: "email", PIIType: "email"},
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.system, TableName: "users", ColumnName: "pan_no", PIIType: "pan"},
+	}).Error)
+	return f
+}
+
+func TestBreachImpact_ResolvesAffectedPrincipals(t *testing.T) {
+	f := setupBreachImpact(t)
+	end := time.Now().Add(-time.Hour)
+
+	res, err := f.svc.Preview(f.tenantID, f.breach.ID, breach.ImpactQuery{WindowEnd: &end})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.TotalRecipients, "categories default from the breach; withdrawn, late and unrelated consents are excluded")
+	assert.Equal(t, f.alice, res.Recipients[0].DataPrincipalID)
+	assert.Equal(t, map[string]int{"contact": 1}, res.CategoryCounts)
+
+	// Discovery in the breached system also finds identity data, which widens the scope
+	res, err = f.svc.Preview(f.tenantID, f.breach.ID, breach.ImpactQuery{SystemIDs: []uuid.UUID{f.system}, WindowEnd: &end, DataCategories: []string{"contact", "financial"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"contact", "financial", "identity"}, res.DataCategories)
+	assert.Equal(t, []string{"financial"}, res.UnconfirmedCategories)
+	assert.Equal(t, map[string]int{"contact": 1, "identity": 1}, res.CategoryCounts)
+	assert.Equal(t, 2, res.TotalRecipients)
+	assert.NotEmpty(t, res.Warnings)
+
+	_, err = f.svc.Preview(uuid.New(), f.breach.ID, breach.ImpactQuery{DataCategories: []string{"contact"}})
+	assert.ErrorIs(t, err, ErrBreachNotFound)
+	start := end.Add(time.Hour)
+	_, err = f.svc.Preview(f.tenantID, f.breach.ID, breach.ImpactQuery{WindowStart: &start, WindowEnd: &end})
+	assert.ErrorIs(t, err, breach.ErrInvalidImpactQuery)
+}
+
+func TestBreachImpact_FreezesVersionedRecipientLists(t *testing.T) {
+	f := setupBreachImpact(t)
+	staff := uuid.New()
+	end := time.Now().Add(-time.Hour)
+	q := breach.ImpactQuery{SystemIDs: []uuid.UUID{f.system}, WindowEnd: &end, DataCategories: []string{"contact"}}
+
+	list, _, err := f.svc.Freeze(f.tenantID, f.breach.ID, q, staff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Version)
+	assert.Equal(t, 2, list.TotalRecipients)
+	var counts map[string]int
+	require.NoError(t, json.Unmarshal(list.CategoryCounts, &counts))
+	assert.Equal(t, map[string]int{"contact": 1, "identity": 1}, counts)
+
+	b, err := repository.NewBreachNotificationRepository(f.db).GetBreachNotificationByID(f.breach.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.AffectedUsersCount, "the breach's affected count follows the frozen list")
+
+	_, contacts, err := f.svc.RecipientContacts(f.tenantID, f.breach.ID, uuid.Nil)
+	require.NoError(t, err)
+	emails := []string{}
+	for _, c := range contacts {
+		emails = append(emails, c.Email)
+	}
+	assert.ElementsMatch(t, []string{"alice@example.com", "bob@example.com"}, emails)
+
+	again, _, err := f.svc.Freeze(f.tenantID, f.breach.ID, breach.ImpactQuery{WindowEnd: &end, DataCategories: []string{"contact"}}, staff)
+	require.NoError(t, err)
+	assert.Equal(t, 2, again.Version)
+	assert.Equal(t, 1, again.TotalRecipients)
+	latest, err := f.svc.Get(f.tenantID, f.breach.ID, uuid.Nil)
+	require.NoError(t, err)
+	assert.Equal(t, again.ID, latest.ID)
+
+	// The DPB report takes its extent from the latest list
+	require.NoError(t, f.db.AutoMigrate(&models.Tenant{}, &models.BreachImpactAssessment{}, &models.BreachEvidence{}, &models.BreachCommunication{}))
+	reports := NewDPBReportService(repository.NewBreachNotificationRepository(f.db), repository.NewBreachImpactAssessmentRepository(f.db),
+		repository.NewBreachTimelineRepository(f.db), repository.NewBreachEvidenceRepository(f.db), repository.NewBreachCommunicationRepository(f.db),
+		repository.NewTenantRepository(f.db), repository.NewBreachDPBReportRepository(f.db), repository.NewBreachRecipientListRepository(f.db), nil, nil)
+	intimation, err := reports.Build(f.tenantID, f.breach.ID, breach.DPBReportInitial)
+	require.NoError(t, err)
+	assert.Equal(t, 1, intimation.Extent.AffectedPrincipals)
+	assert.Equal(t, 2, intimation.Extent.RecipientListVersion)
+	assert.Equal(t, map[string]int{"contact": 1}, intimation.Extent.ByCategory)
+
+	// Earlier versions stay intact and verifiable; tampering is detected
+	ok, err := f.svc.VerifyList(f.tenantID, f.breach.ID, list.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, f.db.Where("list_id = ? AND data_principal_id = ?", list.ID, f.bob).Delete(&models.BreachRecipient{}).Error)
+	ok, err = f.svc.VerifyList(f.tenantID, f.breach.ID, list.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = f.svc.Get(uuid.New(), f.breach.ID, list.ID)
+	assert.ErrorIs(t, err, ErrBreachNotFound)
+}
@@ -1,13 +1,16 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"pixpivot/arc/internal/breach"
 	"pixpivot/arc/internal/models"
 	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
 
 	"github.com/google/uuid"
 )
@@ -21,6 +24,7 @@ type EnhancedBreachNotificationService struct {
 	evidenceRepo         *repository.BreachEvidenceRepository
 	timelineRepo         *repository.BreachTimelineRepository
 	templateRepo         *repository.BreachNotificationTemplateRepository
+	simulationRepo       *repository.BreachSimulationRepository
 	emailService         *EmailService
 }
 
@@ -33,6 +37,7 @@ func NewEnhancedBreachNotificationService(
 	evidenceRepo *repository.BreachEvidenceRepository,
 	timelineRepo *repository.BreachTimelineRepository,
 	templateRepo *repository.BreachNotificationTemplateRepository,
+	simulationRepo *repository.BreachSimulationRepository,
 	emailService *EmailService,
 ) *EnhancedBreachNotificationService {
 	return &EnhancedBreachNotificationService{
@@ -44,6 +49,7 @@ func NewEnhancedBreachNotificationService(
 		evidenceRepo:         evidenceRepo,
 		timelineRepo:         timelineRepo,
 		templateRepo:         templateRepo,
+		simulationRepo:       simulationRepo,
 		emailService:         emailService,
 	}
 }
@@ -77,6 +83,30 @@ func (s *EnhancedBreachNotificationService) CalculateDPDPDeadlines(breach *model
 	if breach.DPBNotificationDeadline != nil && now.After(*breach.DPBNotificationDeadline) && !breach.DPBReported {
 		breach.IsOverdue = true
 	}
+
+	calculateCERTInDeadline(breach, detectionDate, now)
+}
+
+// calculateCERTInDeadline sets up the CERT-In track, which runs alongside the
+// DPB one: cyber incidents must reach CERT-In within 6 hours of being noticed.
+// Naming an incident type opts the breach in.
+func calculateCERTInDeadline(b *models.BreachNotification, detectionDate, now time.Time) {
+	if b.CERTInIncidentType != "" {
+		b.RequiresCERTInReporting = true
+	}
+	if b.RequiresCERTInReporting {
+		if b.CERTInIncidentType == "" {
+			b.CERTInIncidentType = string(breach.SuggestCERTInIncidentType(b.BreachType))
+		}
+		deadline := detectionDate.Add(breach.CERTInReportingWindow)
+		b.CERTInDeadline = &deadline
+	}
+	b.CERTInStatus = certInStatus(b, now)
+}
+
+// certInStatus is where the breach's CERT-In track stands at now
+func certInStatus(b *models.BreachNotification, now time.Time) string {
+	return breach.CERTInStatus(b.RequiresCERTInReporting, b.CERTInDeadline, b.CERTInReportedAt, now)
 }
 
 // CreateBreachWithWorkflow creates a breach and initializes workflow
@@ -294,7 +324,7 @@ func (s *EnhancedBreachNotificationService) SendDPBNotification(
 
 	// Send email
 	subject := fmt.Sprintf("Data Breach Notification - %s", breach.Title)
-	if err := s.emailService.Send(dpbStakeholder.ContactEmail, subject, content); err != nil {
+	if err := s.sendEmail(breach, "dpb", dpbStakeholder.ContactEmail, subject, content); err != nil {
 		dpbStakeholder.NotificationStatus = "failed"
 		s.stakeholderRepo.Update(dpbStakeholder)
 		return fmt.Errorf("failed to send DPB notification: %w", err)
@@ -304,7 +334,7 @@ func (s *EnhancedBreachNotificationService) SendDPBNotification(
 	now := time.Now()
 	dpbStakeholder.NotificationSent = true
 	dpbStakeholder.NotifiedAt = &now
-	dpbStakeholder.NotificationStatus = "sent"
+	dpbStakeholder.NotificationStatus = sentStatus(breach)
 
 	if err := s.stakeholderRepo.Update(dpbStakeholder); err != nil {
 		return err
@@ -332,7 +362,7 @@ func (s *EnhancedBreachNotificationService) SendDPBNotification(
 		TemplateName:      template.TemplateName,
 		SendMethod:        "email",
 		SentAt:            &now,
-		Status:            "sent",
+		Status:            sentStatus(breach),
 		CreatedBy:         sentBy,
 	}
 
@@ -346,7 +376,7 @@ func (s *EnhancedBreachNotificationService) SendDPBNotification(
 		BreachID:    breachID,
 		TenantID:    breach.TenantID,
 		EventType:   "dpb_notified",
-		Description: "Data Protection Board notified",
+		Description: "Data Protection Board notified" + simulationNote(breach),
 		PerformedBy: &sentBy,
 	}
 	return s.timelineRepo.Create(timelineEntry)
@@ -397,7 +427,7 @@ func (s *EnhancedBreachNotificationService) SendDataPrincipalNotifications(
 			continue
 		}
 
-		if err := s.emailService.Send(email, subject, content); err != nil {
+		if err := s.sendEmail(breach, "data_principal", email, subject, content); err != nil {
 			stakeholder.NotificationStatus = "failed"
 			s.stakeholderRepo.Update(stakeholder)
 			failCount++
@@ -407,7 +437,7 @@ func (s *EnhancedBreachNotificationService) SendDataPrincipalNotifications(
 		now := time.Now()
 		stakeholder.NotificationSent = true
 		stakeholder.NotifiedAt = &now
-		stakeholder.NotificationStatus = "sent"
+		stakeholder.NotificationStatus = sentStatus(breach)
 		s.stakeholderRepo.Update(stakeholder)
 		successCount++
 	}
@@ -428,12 +458,45 @@ func (s *EnhancedBreachNotificationService) SendDataPrincipalNotifications(
 		BreachID:    breachID,
 		TenantID:    breach.TenantID,
 		EventType:   "data_principals_notified",
-		Description: fmt.Sprintf("Notified %d affected individuals (%d failed)", successCount, failCount),
+		Description: fmt.Sprintf("Notified %d affected individuals (%d failed)", successCount, failCount) + simulationNote(breach),
 		PerformedBy: &sentBy,
 	}
 	return s.timelineRepo.Create(timelineEntry)
 }
 
+// sendEmail sends a notice, or for a simulated breach hands it to the
+// simulation sink instead
+func (s *EnhancedBreachNotificationService) sendEmail(b *models.BreachNotification, recipientType, to, subject, body string) error {
+	if b.IsSimulation {
+		return s.simulationRepo.Capture(&models.BreachSimulationMessage{
+			BreachID:      b.ID,
+			TenantID:      b.TenantID,
+			RecipientType: recipientType,
+			Channel:       "email",
+			Recipient:     to,
+			Subject:       subject,
+			Body:          body,
+		})
+	}
+	return s.emailService.Send(to, subject, body)
+}
+
+// sentStatus is the status recorded for a notice once sent
+func sentStatus(b *models.BreachNotification) string {
+	if b.IsSimulation {
+		return "simulated"
+	}
+	return "sent"
+}
+
+// simulationNote marks timeline entries of a simulated breach
+func simulationNote(b *models.BreachNotification) string {
+	if b.IsSimulation {
+		return " (simulation: captured by the sink, nothing sent)"
+	}
+	return ""
+}
+
 // CheckSLACompliance checks if breach notifications are within SLA
 func (s *EnhancedBreachNotificationService) CheckSLACompliance(breachID uuid.UUID) (map[string]interface{}, error) {
 	breach, err := s.repo.GetBreachNotificationByID(breachID)
@@ -472,6 +535,22 @@ func (s *EnhancedBreachNotificationService) CheckSLACompliance(breachID uuid.UUI
 		}
 	}
 
+	// CERT-In SLA
+	slaStatus["cert_in_required"] = breach.RequiresCERTInReporting
+	slaStatus["cert_in_status"] = certInStatus(breach, now)
+	if breach.RequiresCERTInReporting && breach.CERTInDeadline != nil {
+		slaStatus["cert_in_deadline"] = breach.CERTInDeadline.Format(time.RFC3339)
+		slaStatus["cert_in_notified"] = breach.CERTInReportedAt != nil
+
+		if breach.CERTInReportedAt != nil {
+			slaStatus["cert_in_notified_at"] = breach.CERTInReportedAt.Format(time.RFC3339)
+			slaStatus["cert_in_within_sla"] = !breach.CERTInReportedAt.After(*breach.CERTInDeadline)
+		} else {
+			slaStatus["cert_in_within_sla"] = now.Before(*breach.CERTInDeadline)
+			slaStatus["cert_in_overdue"] = now.After(*breach.CERTInDeadline)
+		}
+	}
+
 	slaStatus["is_overdue"] = breach.IsOverdue
 
 	return slaStatus, nil
@@ -479,6 +558,12 @@ func (s *EnhancedBreachNotificationService) CheckSLACompliance(breachID uuid.UUI
 
 // renderTemplate renders a notification template with breach data
 func (s *EnhancedBreachNotificationService) renderTemplate(template string, breach *models.BreachNotification) string {
+	return renderBreachTemplate(template, breach, nil)
+}
+
+// renderBreachTemplate fills a template's {{placeholders}} from the breach,
+// plus any extra values the caller supplies
+func renderBreachTemplate(template string, breach *models.BreachNotification, extra map[string]string) string {
 	result := template
 
 	replacements := map[string]string{
@@ -490,6 +575,9 @@ func (s *EnhancedBreachNotificationService) renderTemplate(template string, brea
 		"{{severity}}":           breach.Severity,
 		"{{breach_type}}":        breach.BreachType,
 	}
+	for k, v := range extra {
+		replacements["{{"+k+"}}"] = v
+	}
 
 	for placeholder, value := range replacements {
 		result = strings.ReplaceAll(result, placeholder, value)
@@ -498,22 +586,44 @@ func (s *EnhancedBreachNotificationService) renderTemplate(template string, brea
 	return result
 }
 
-// GetBreachRegister returns all breaches for compliance register
+// GetBreachRegister returns all breaches for compliance register; simulated
+// breaches are not listed
 func (s *EnhancedBreachNotificationService) GetBreachRegister(tenantID uuid.UUID) ([]models.BreachNotification, error) {
-	return s.repo.ListBreachNotifications(tenantID)
+	breaches, err := s.repo.ListBreachNotifications(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	// The CERT-In clock is short enough that a stored status goes stale
+	now := time.Now()
+	for i := range breaches {
+		breaches[i].CERTInStatus = certInStatus(&breaches[i], now)
+	}
+	return breaches, nil
 }
 
-// EscalateOverdueBreaches identifies and escalates overdue breach notifications
+// EscalateOverdueBreaches identifies and escalates overdue breach notifications.
+// Running exercises are escalated too, so their SLA timers behave as real ones.
 func (s *EnhancedBreachNotificationService) EscalateOverdueBreaches(tenantID uuid.UUID) error {
 	breaches, err := s.repo.ListBreachNotifications(tenantID)
 	if err != nil {
 		return err
 	}
+	simulations, err := s.repo.ListSimulatedBreachNotifications(tenantID)
+	if err != nil {
+		return err
+	}
+	for _, b := range simulations {
+		if b.SimulationCompletedAt == nil {
+			breaches = append(breaches, b)
+		}
+	}
 
 	now := time.Now()
 	for _, breach := range breaches {
-		// Check if DPB notification is overdue
-		if !breach.DPBReported && breach.DPBNotificationDeadline != nil && now.After(*breach.DPBNotificationDeadline) {
+		// Check if DPB notification is overdue. The sweep runs on a schedule,
+		// so a breach is escalated once.
+		if !breach.DPBReported && breach.DPBNotificationDeadline != nil && now.After(*breach.DPBNotificationDeadline) &&
+			!s.hasTimelineEntry(breach.ID, "escalation", dpbEscalationNote) {
 			breach.IsOverdue = true
 			s.repo.UpdateBreachNotification(&breach)
 
@@ -523,13 +633,65 @@ func (s *EnhancedBreachNotificationService) EscalateOverdueBreaches(tenantID uui
 				BreachID:    breach.ID,
 				TenantID:    breach.TenantID,
 				EventType:   "escalation",
-				Description: "DPB notification deadline exceeded - escalated",
+				Description: dpbEscalationNote,
 			}
 			s.timelineRepo.Create(timelineEntry)
 
 			// TODO: Send escalation notification to admins
 		}
+
+		// CERT-In deadline: escalate once, when the track first goes overdue
+		if status := certInStatus(&breach, now); status != breach.CERTInStatus {
+			breach.CERTInStatus = status
+			s.repo.UpdateBreachNotification(&breach)
+			if status == "overdue" {
+				s.timelineRepo.Create(&models.BreachTimeline{
+					ID:          uuid.New(),
+					BreachID:    breach.ID,
+					TenantID:    breach.TenantID,
+					EventType:   "escalation",
+					Description: "CERT-In 6-hour reporting deadline exceeded - escalated",
+				})
+			}
+		}
 	}
 
 	return nil
 }
+
+const dpbEscalationNote = "DPB notification deadline exceeded - escalated"
+
+func (s *EnhancedBreachNotificationService) hasTimelineEntry(breachID uuid.UUID, eventType, description string) bool {
+	entries, err := s.timelineRepo.GetByBreachID(breachID)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.EventType == eventType && e.Description == description {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterJobs sweeps every tenant for overdue breaches every 15 minutes
+func (s *EnhancedBreachNotificationService) RegisterJobs(r *JobRunner) {
+	r.Register("breach.escalate", func(ctx context.Context, job *models.Job) error {
+		tenantIDs, err := s.repo.ListBreachTenantIDs()
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, tenantID := range tenantIDs {
+			if err := s.EscalateOverdueBreaches(tenantID); err != nil {
+				log.Logger.Error().Err(err).Str("tenant", tenantID.String()).Msg("Breach escalation sweep failed")
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("breach escalation failed for %d of %d tenants", failed, len(tenantIDs))
+		}
+		return nil
+	})
+	r.Schedule("breach.escalate", "*/15 * * * *", "breach.escalate", nil)
+}
@@ -112,9 +112,10 @@ func (s *BreachReportingService) ReportBreachToDPB(ctx context.Context, breach *
 
 // ProcessPendingBreachReports processes all pending breach reports that need to be sent to the DPB
 func (s *BreachReportingService) ProcessPendingBreachReports(ctx context.Context) error {
-	// Find all breaches that require DPB reporting but haven't been reported yet
+	// Find all breaches that require DPB reporting but haven't been reported yet.
+	// Tabletop exercises are never reported.
 	var breaches []models.BreachNotification
-	if err := s.DB.Where("requires_dpb_reporting = ? AND dpb_reported = ?", true, false).Find(&breaches).Error; err != nil {
+	if err := s.DB.Where("requires_dpb_reporting = ? AND dpb_reported = ? AND is_simulation = ?", true, false, false).Find(&breaches).Error; err != nil {
 		return fmt.Errorf("failed to retrieve pending breach reports: %w", err)
 	}
 	
@@ -160,3 +161,10 @@ func (s *BreachReportingService) ProcessPendingBreachReports(ctx context.Context
 	return nil
 }
 
+// RegisterJobs processes pending DPB reports every hour
+func (s *BreachReportingService) RegisterJobs(r *JobRunner) {
+	r.Register("breach.dpb_pending", func(ctx context.Context, job *models.Job) error {
+		return s.ProcessPendingBreachReports(ctx)
+	})
+	r.Schedule("breach.dpb_pending", "0 * * * *", "breach.dpb_pending", nil)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotSimulation       = errors.New("breach is not a simulation")
+	ErrSimulationCompleted = errors.New("simulation has already been completed")
+	// ErrBreachSimulated is returned where a simulated breach would otherwise
+	// produce a regulator submission
+	ErrBreachSimulated = errors.New("simulated breaches are not submitted to regulators")
+)
+
+func (s *EnhancedBreachNotificationService) simulationForTenant(tenantID, breachID uuid.UUID) (*models.BreachNotification, error) {
+	b, err := s.repo.GetBreachNotificationByID(breachID)
+	if err != nil || b.TenantID != tenantID {
+		return nil, ErrBreachNotFound
+	}
+	if !b.IsSimulation {
+		return nil, ErrNotSimulation
+	}
+	return b, nil
+}
+
+// ListSimulations returns the tenant's tabletop exercises
+func (s *EnhancedBreachNotificationService) ListSimulations(tenantID uuid.UUID) ([]models.BreachNotification, error) {
+	return s.repo.ListSimulatedBreachNotifications(tenantID)
+}
+
+// SimulationMessages returns the notices the sink captured for an exercise
+func (s *EnhancedBreachNotificationService) SimulationMessages(tenantID, breachID uuid.UUID) ([]models.BreachSimulationMessage, error) {
+	if _, err := s.simulationForTenant(tenantID, breachID); err != nil {
+		return nil, err
+	}
+	return s.simulationRepo.ByBreach(breachID)
+}
+
+// CompleteSimulation ends an exercise: the breach is closed, its resolution
+// stage completed, and the after-action report is returned
+func (s *EnhancedBreachNotificationService) CompleteSimulation(tenantID, breachID, completedBy uuid.UUID) (*breach.AfterActionReport, error) {
+	b, err := s.simulationForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	if b.SimulationCompletedAt != nil {
+		return nil, ErrSimulationCompleted
+	}
+
+	now := time.Now()
+	b.SimulationCompletedAt = &now
+	b.Status = "closed"
+	b.CurrentWorkflowStage = "resolution"
+	if err := s.repo.UpdateBreachNotification(b); err != nil {
+		return nil, err
+	}
+	if err := s.workflowRepo.UpdateStageStatus(b.ID, "resolution", "completed"); err != nil {
+		return nil, err
+	}
+	if err := s.timelineRepo.Create(&models.BreachTimeline{
+		BreachID:    b.ID,
+		TenantID:    b.TenantID,
+		EventType:   "simulation_completed",
+		Description: "Tabletop exercise completed",
+		PerformedBy: &completedBy,
+	}); err != nil {
+		return nil, err
+	}
+	return s.buildAfterActionReport(b, now)
+}
+
+// AfterActionReport reviews an exercise, completed or still running
+func (s *EnhancedBreachNotificationService) AfterActionReport(tenantID, breachID uuid.UUID) (*breach.AfterActionReport, error) {
+	b, err := s.simulationForTenant(tenantID, breachID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildAfterActionReport(b, time.Now())
+}
+
+func (s *EnhancedBreachNotificationService) buildAfterActionReport(b *models.BreachNotification, now time.Time) (*breach.AfterActionReport, error) {
+	timeline, err := s.timelineRepo.GetByBreachID(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := s.workflowRepo.GetByBreachID(b.ID)
+	if err != nil {
+		return nil, err
+	}
+	captured, err := s.simulationRepo.ByBreach(b.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The deadlines are judged as of the end of the exercise
+	asOf := now
+	if b.SimulationCompletedAt != nil {
+		asOf = *b.SimulationCompletedAt
+	}
+	r := &breach.AfterActionReport{
+		BreachID:      b.ID,
+		TenantID:      b.TenantID,
+		Title:         b.Title,
+		Severity:      b.Severity,
+		DetectedAt:    b.DetectionDate,
+		CompletedAt:   b.SimulationCompletedAt,
+		GeneratedAt:   now.UTC(),
+		Milestones:    []breach.AfterActionMilestone{},
+		Stages:        []breach.AfterActionStage{},
+		Notifications: map[string]int{},
+		Findings:      []string{},
+	}
+	if r.DetectedAt.IsZero() {
+		r.DetectedAt = b.CreatedAt
+	}
+
+	// First occurrence of each timeline event
+	first := map[string]*time.Time{}
+	for i := range timeline {
+		t := &timeline[i]
+		if t.EventType == "escalation" {
+			r.Escalations++
+		}
+		if _, ok := first[t.EventType]; !ok {
+			at := t.OccurredAt
+			first[t.EventType] = &at
+		}
+	}
+	either := func(x, y *time.Time) *time.Time {
+		if x != nil {
+			return x
+		}
+		return y
+	}
+
+	r.AddMilestone("breach_recorded", first["breach_created"], nil, asOf)
+	r.AddMilestone("submitted_for_verification", first["submitted_for_verification"], nil, asOf)
+	r.AddMilestone("verified", either(b.VerifiedAt, first["breach_verified"]), nil, asOf)
+	r.AddMilestone("contained", either(b.ContainmentDate, either(first["containment"], first["contained"])), nil, asOf)
+	if b.RequiresCERTInReporting {
+		r.AddMilestone("cert_in_reported", b.CERTInReportedAt, b.CERTInDeadline, asOf)
+	}
+	r.AddMilestone("dpb_notified", either(b.DPBReportedDate, first["dpb_notified"]), b.DPBNotificationDeadline, asOf)
+	r.AddMilestone("data_principal_notification_approved", b.DataPrincipalNotificationApprovedAt, nil, asOf)
+	r.AddMilestone("data_principals_notified",
+		either(b.DataPrincipalNotificationSentAt, either(first["data_principals_notified"], first["notification_campaign_started"])),
+		b.DataPrincipalNotificationDeadline, asOf)
+	if b.SimulationCompletedAt != nil {
+		r.AddMilestone("exercise_completed", b.SimulationCompletedAt, nil, asOf)
+	}
+
+	for _, st := range stages {
+		r.AddStage(st.Stage, st.Status, st.CompletedAt)
+	}
+	for _, m := range captured {
+		r.Notifications[m.RecipientType+"."+m.Channel]++
+	}
+	if r.Escalations > 0 {
+		r.Findings = append(r.Findings, fmt.Sprintf("%d escalation(s) were raised for missed deadlines", r.Escalations))
+	}
+	return r, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBreachSimulation_SinksNoticesAndReportsStageTimes(t *testing.T) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.EncryptedBreachNotification{}, &models.BreachTimeline{}, &models.BreachWorkflowStage{},
+		&models.BreachStakeholder{}, &models.BreachCommunication{}, &models.BreachNotificationTemplate{}, &models.BreachSimulationMessage{}))
+	require.NoError(t, db.Create(&models.BreachNotificationTemplate{ID: uuid.New(), TemplateName: "dpb_notification_template",
+		RecipientType: "dpb", Body: "Breach: {{breach_title}}", IsActive: true}).Error)
+
+	breachRepo := repository.NewBreachNotificationRepository(db)
+	// No email service: any attempt to really send would panic
+	svc := NewEnhancedBreachNotificationService(breachRepo, nil, repository.NewBreachStakeholderRepository(db),
+		repository.NewBreachWorkflowStageRepository(db), repository.NewBreachCommunicationRepository(db), nil,
+		repository.NewBreachTimelineRepository(db), repository.NewBreachNotificationTemplateRepository(db),
+		repository.NewBreachSimulationRepository(db), nil)
+
+	tenantID, userID := uuid.New(), uuid.New()
+	detected := time.Now().Add(-2 * time.Hour)
+	b := &models.BreachNotification{TenantID: tenantID, Title: "Exercise: leaked CRM export", Severity: "high",
+		BreachType: "unauthorized_access", DetectionDate: detected, IsSimulation: true}
+	require.NoError(t, svc.CreateBreachWithWorkflow(b, userID))
+	require.NoError(t, breachRepo.CreateBreachNotification(&models.BreachNotification{ID: uuid.New(), TenantID: tenantID, Title: "Real"}))
+
+	// Kept out of the register, listed with the exercises
+	register, err := svc.GetBreachRegister(tenantID)
+	require.NoError(t, err)
+	require.Len(t, register, 1)
+	assert.Equal(t, "Real", register[0].Title)
+	sims, err := svc.ListSimulations(tenantID)
+	require.NoError(t, err)
+	require.Len(t, sims, 1)
+
+	require.NoError(t, svc.SubmitForVerification(b.ID, userID))
+	stored, err := breachRepo.GetBreachNotificationByID(b.ID)
+	require.NoError(t, err)
+	verifiedAt := time.Now()
+	stored.Status, stored.VerifiedAt = "verified", &verifiedAt
+	require.NoError(t, breachRepo.UpdateBreachNotification(stored))
+
+	// The DPB notice is rendered and captured by the sink, not sent
+	require.NoError(t, svc.SendDPBNotification(b.ID, userID))
+	msgs, err := svc.SimulationMessages(tenantID, b.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "dpb@meity.gov.in", msgs[0].Recipient)
+	assert.Equal(t, "Breach: Exercise: leaked CRM export", msgs[0].Body)
+	comms, err := repository.NewBreachCommunicationRepository(db).GetByBreachID(b.ID)
+	require.NoError(t, err)
+	require.Len(t, comms, 1)
+	assert.Equal(t, "simulated", comms[0].Status)
+
+	// Regulator-facing endpoints refuse exercises
+	_, err = svc.AfterActionReport(tenantID, register[0].ID)
+	assert.ErrorIs(t, err, ErrNotSimulation)
+	_, err = svc.AfterActionReport(uuid.New(), b.ID)
+	assert.ErrorIs(t, err, ErrBreachNotFound)
+
+	report, err := svc.CompleteSimulation(tenantID, b.ID, userID)
+	require.NoError(t, err)
+	require.NotNil(t, report.CompletedAt)
+	assert.Equal(t, 1, report.Notifications["dpb.email"])
+	milestones := map[string]breach.AfterActionMilestone{}
+	for _, m := range report.Milestones {
+		milestones[m.Name] = m
+	}
+	dpb := milestones["dpb_notified"]
+	require.NotNil(t, dpb.MinutesFromDetection)
+	assert.InDelta(t, 120, *dpb.MinutesFromDetection, 1)
+	require.NotNil(t, dpb.MetDeadline)
+	assert.True(t, *dpb.MetDeadline)
+	assert.NotNil(t, milestones["verified"].At)
+	// High severity gives principals 24 hours, so that clock is still running
+	assert.Nil(t, milestones["data_principals_notified"].MetDeadline)
+	assert.Contains(t, report.Findings, "contained was not reached")
+
+	_, err = svc.CompleteSimulation(tenantID, b.ID, userID)
+	assert.ErrorIs(t, err, ErrSimulationCompleted)
+}
+
+func TestDPBReport_RefusesSimulatedBreach(t *testing.T) {
+	svc, db := setupDPBReports(t)
+	tenantID := uuid.New()
+	b := &models.BreachNotification{ID: uuid.New(), TenantID: tenantID, Description: "Exercise", DetectionDate: time.Now(), IsSimulation: true}
+	require.NoError(t, repository.NewBreachNotificationRepository(db).CreateBreachNotification(b))
+
+	_, err := svc.Generate(tenantID, b.ID, breach.DPBReportInitial, uuid.New())
+	assert.ErrorIs(t, err, ErrBreachSimulated)
+}
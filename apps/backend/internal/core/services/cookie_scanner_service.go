@@ -21,6 +21,7 @@ import (
 
 type CookieScannerService struct {
 	repo *repository.CookieRepository
+	jobs *JobRunner
 }
 
 func NewCookieScannerService(repo *repository.CookieRepository) *CookieScannerService {
@@ -48,12 +49,44 @@ func (s *CookieScannerService) ScanWebsite(tenantID uuid.UUID, targetURL string)
 		return nil, fmt.Errorf("failed to create scan record: %w", err)
 	}
 
-	// Start scanning in background
-	go s.performScan(scan)
+	// Start scanning in background; on the job queue the scan survives a restart
+	if s.jobs == nil {
+		go s.performScan(scan)
+		return scan, nil
+	}
+	if _, err := s.jobs.Enqueue("cookie.scan", map[string]uuid.UUID{"scanId": scan.ID}, JobOptions{TenantID: &tenantID, MaxAttempts: 2}); err != nil {
+		scan.Status = models.CookieScanStatusFailed
+		scan.ErrorMessage = "Failed to queue scan"
+		s.repo.UpdateScan(scan)
+		return nil, fmt.Errorf("failed to queue scan: %w", err)
+	}
 
 	return scan, nil
 }
 
+// RegisterJobs moves website scans onto the job runner
+func (s *CookieScannerService) RegisterJobs(r *JobRunner) {
+	s.jobs = r
+	r.Register("cookie.scan", func(ctx context.Context, job *models.Job) error {
+		var p struct {
+			ScanID uuid.UUID `json:"scanId"`
+		}
+		if err := DecodeJobPayload(job, &p); err != nil {
+			return err
+		}
+		if job.TenantID == nil {
+			return fmt.Errorf("cookie scan job has no tenant")
+		}
+		scan, err := s.repo.GetScanByID(p.ScanID, *job.TenantID)
+		if err != nil {
+			return fmt.Errorf("failed to load scan: %w", err)
+		}
+		// A scan that fails records its error on the scan itself
+		s.performScan(scan)
+		return nil
+	})
+}
+
 func (s *CookieScannerService) performScan(scan *models.CookieScan) {
 	startTime := time.Now()
 
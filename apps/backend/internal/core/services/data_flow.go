@@ -0,0 +1,671 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/breach"
+	"pixpivot/arc/internal/localization"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Data flow map node types
+const (
+	FlowNodePurpose      = "purpose"
+	FlowNodeDataObject   = "data_object"
+	FlowNodeSystem       = "system"
+	FlowNodeVendor       = "vendor"
+	FlowNodeSubProcessor = "sub_processor"
+	FlowNodeLocation     = "location"
+)
+
+// Data flow map edge relations
+const (
+	FlowEdgeCollects       = "collects"         // purpose -> data object
+	FlowEdgeProcessedIn    = "processed_in"     // purpose -> system
+	FlowEdgeStoredIn       = "stored_in"        // data object -> system, from discovery
+	FlowEdgeSharedWith     = "shared_with"      // purpose -> vendor
+	FlowEdgeSubProcessedBy = "sub_processed_by" // vendor -> sub-processor
+	FlowEdgeLocatedIn      = "located_in"       // vendor or sub-processor -> location
+)
+
+var (
+	ErrFlowPurposeNotFound       = errors.New("purpose not found")
+	ErrFlowDataSourceNotFound    = errors.New("data source not found")
+	ErrPurposeSystemNotFound     = errors.New("system is not declared for the purpose")
+	ErrFlowNodeNotFound          = errors.New("not on the data flow map")
+	ErrUnsupportedDataFlowFormat = errors.New("unsupported data flow export format")
+)
+
+// dataObjectAliases maps names consent forms commonly give data objects to
+// the PII types discovery reports, so both end up on the same node
+var dataObjectAliases = map[string]string{
+	"aadhar":             "aadhaar",
+	"aadhaar_number":     "aadhaar",
+	"aadhaar_card":       "aadhaar",
+	"pan_number":         "pan",
+	"pan_card":           "pan",
+	"passport_number":    "passport",
+	"voter_id_card":      "voter_id",
+	"epic":               "voter_id",
+	"gst":                "gstin",
+	"gst_number":         "gstin",
+	"email_address":      "email",
+	"email_id":           "email",
+	"phone":              "phone_in",
+	"phone_number":       "phone_in",
+	"mobile":             "phone_in",
+	"mobile_number":      "phone_in",
+	"card_number":        "credit_card",
+	"credit_card_number": "credit_card",
+}
+
+// DataObjectKey returns the key a data object or PII type is mapped under:
+// "PAN Number", "pan" and "pan_card" are all "pan"
+func DataObjectKey(name string) string {
+	key := breach.NormalizeDataItem(name)
+	if alias, ok := dataObjectAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// DataFlowNode is a purpose, data object, system, vendor, sub-processor or
+// location on the data flow map
+type DataFlowNode struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Label      string            `json:"label"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// DataFlowEdge is a directed link between two nodes. Label carries detail
+// such as the columns a data object was discovered in.
+type DataFlowEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+	Label    string `json:"label,omitempty"`
+}
+
+// DataFlowGraph is the tenant's data flow map, or the part of it a query
+// selected
+type DataFlowGraph struct {
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Nodes       []DataFlowNode `json:"nodes"`
+	Edges       []DataFlowEdge `json:"edges"`
+
+	nodes map[string]int
+	edges map[string]int
+}
+
+func newDataFlowGraph(now time.Time) *DataFlowGraph {
+	return &DataFlowGraph{
+		GeneratedAt: now,
+		Nodes:       []DataFlowNode{},
+		Edges:       []DataFlowEdge{},
+		nodes:       map[string]int{},
+		edges:       map[string]int{},
+	}
+}
+
+func (g *DataFlowGraph) addNode(n DataFlowNode) {
+	if _, ok := g.nodes[n.ID]; ok {
+		return
+	}
+	g.nodes[n.ID] = len(g.Nodes)
+	g.Nodes = append(g.Nodes, n)
+}
+
+// addEdge links two nodes once; a label given again is appended, so an edge
+// found through several columns lists them all
+func (g *DataFlowGraph) addEdge(from, to, relation, label string) {
+	key := from + "\x00" + to + "\x00" + relation
+	if i, ok := g.edges[key]; ok {
+		if label != "" && !strings.Contains(", "+g.Edges[i].Label+", ", ", "+label+", ") {
+			if g.Edges[i].Label != "" {
+				label = g.Edges[i].Label + ", " + label
+			}
+			g.Edges[i].Label = label
+		}
+		return
+	}
+	g.edges[key] = len(g.Edges)
+	g.Edges = append(g.Edges, DataFlowEdge{From: from, To: to, Relation: relation, Label: label})
+}
+
+// Node returns the node with the given ID
+func (g *DataFlowGraph) Node(id string) (DataFlowNode, bool) {
+	i, ok := g.nodes[id]
+	if !ok {
+		return DataFlowNode{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// NodesOf returns the nodes of one type
+func (g *DataFlowGraph) NodesOf(nodeType string) []DataFlowNode {
+	var out []DataFlowNode
+	for _, n := range g.Nodes {
+		if n.Type == nodeType {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// downstream adds to keep every node reachable from id, without passing
+// through nodes of the skipped type
+func (g *DataFlowGraph) downstream(id, skip string, keep map[string]bool) {
+	queue := []string{id}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, e := range g.Edges {
+			if e.From != cur || keep[e.To] {
+				continue
+			}
+			if n, _ := g.Node(e.To); n.Type == skip {
+				continue
+			}
+			keep[e.To] = true
+			queue = append(queue, e.To)
+		}
+	}
+}
+
+// subgraph keeps the given nodes and the edges between them
+func (g *DataFlowGraph) subgraph(keep map[string]bool) *DataFlowGraph {
+	sub := newDataFlowGraph(g.GeneratedAt)
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			sub.addNode(n)
+		}
+	}
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] {
+			sub.addEdge(e.From, e.To, e.Relation, e.Label)
+		}
+	}
+	return sub
+}
+
+// DataObjectFlow answers "where does this data flow?": the systems the data
+// object was discovered in, the purposes collecting it, and the systems,
+// vendors, sub-processors and locations those purposes pass it on to
+func (g *DataFlowGraph) DataObjectFlow(object string) (*DataFlowGraph, error) {
+	root := dataObjectNodeID(DataObjectKey(object))
+	if _, ok := g.Node(root); !ok {
+		return nil, fmt.Errorf("data object %q: %w", object, ErrFlowNodeNotFound)
+	}
+	keep := map[string]bool{root: true}
+	for _, e := range g.Edges {
+		switch {
+		case e.From == root && e.Relation == FlowEdgeStoredIn:
+			keep[e.To] = true
+		case e.To == root && e.Relation == FlowEdgeCollects:
+			keep[e.From] = true
+			g.downstream(e.From, FlowNodeDataObject, keep)
+		}
+	}
+	return g.subgraph(keep), nil
+}
+
+// VendorFeeds answers "which purposes feed this vendor?": the purposes
+// sharing data with the vendor, the data objects they collect, and the
+// vendor's sub-processors and locations. The vendor is given by ID, or by
+// name when the purposes name a vendor that is not in the register.
+func (g *DataFlowGraph) VendorFeeds(vendor string) (*DataFlowGraph, error) {
+	root := vendorNodeID(vendor)
+	if _, ok := g.Node(root); !ok {
+		return nil, fmt.Errorf("vendor %q: %w", vendor, ErrFlowNodeNotFound)
+	}
+	keep := map[string]bool{root: true}
+	g.downstream(root, "", keep)
+	for _, e := range g.Edges {
+		if e.To == root && e.Relation == FlowEdgeSharedWith {
+			keep[e.From] = true
+			for _, c := range g.Edges {
+				if c.From == e.From && c.Relation == FlowEdgeCollects {
+					keep[c.To] = true
+				}
+			}
+		}
+	}
+	return g.subgraph(keep), nil
+}
+
+func purposeNodeID(id uuid.UUID) string      { return "purpose:" + id.String() }
+func dataObjectNodeID(key string) string     { return "data:" + key }
+func systemNodeID(id uuid.UUID) string       { return "system:" + id.String() }
+func subProcessorNodeID(id uuid.UUID) string { return "sub_processor:" + id.String() }
+func locationNodeID(code string) string      { return "location:" + code }
+
+// vendorNodeID identifies registered vendors by ID and others by their
+// normalized name
+func vendorNodeID(ref string) string {
+	if id, err := uuid.Parse(strings.TrimSpace(ref)); err == nil {
+		return "vendor:" + id.String()
+	}
+	return "vendor:" + breach.NormalizeDataItem(ref)
+}
+
+// UnmappedFinding is a discovered PII column whose data no active purpose
+// collects
+type UnmappedFinding struct {
+	DataSourceID   uuid.UUID `json:"dataSourceId"`
+	System         string    `json:"system"`
+	TableName      string    `json:"tableName"`
+	ColumnName     string    `json:"columnName"`
+	PIIType        string    `json:"piiType"`
+	Classification string    `json:"classification,omitempty"`
+}
+
+// UnmappedPurpose is an active purpose with no declared system
+type UnmappedPurpose struct {
+	PurposeID   uuid.UUID `json:"purposeId"`
+	Name        string    `json:"name"`
+	DataObjects []string  `json:"dataObjects"`
+}
+
+// DataFlowGaps lists what privacy reviewers need to map: personal data found
+// by discovery that no purpose accounts for, and purposes whose systems are
+// unknown
+type DataFlowGaps struct {
+	GeneratedAt            time.Time         `json:"generatedAt"`
+	UnmappedFindings       []UnmappedFinding `json:"unmappedFindings"`
+	PurposesWithoutSystems []UnmappedPurpose `json:"purposesWithoutSystems"`
+}
+
+// DataFlowService joins consent form data objects and vendors, discovery
+// findings, declared purpose systems, sub-processors and processing
+// locations into the tenant's data flow map. Purposes live in the tenant
+// schema, so callers load and pass them in.
+type DataFlowService struct {
+	repo       *repository.DataFlowRepository
+	vendorRepo repository.VendorRepository
+	subRepo    *repository.SubProcessorRepository
+}
+
+func NewDataFlowService(repo *repository.DataFlowRepository, vendorRepo repository.VendorRepository, subRepo *repository.SubProcessorRepository) *DataFlowService {
+	return &DataFlowService{repo: repo, vendorRepo: vendorRepo, subRepo: subRepo}
+}
+
+func findPurpose(purposes []models.Purpose, id uuid.UUID) (*models.Purpose, bool) {
+	for i := range purposes {
+		if purposes[i].ID == id {
+			return &purposes[i], true
+		}
+	}
+	return nil, false
+}
+
+// DeclareSystem records that a purpose's data is processed in a discovery
+// data source
+func (s *DataFlowService) DeclareSystem(tenantID, userID uuid.UUID, purposes []models.Purpose, purposeID, dataSourceID uuid.UUID, note string) (*models.PurposeSystem, error) {
+	if _, ok := findPurpose(purposes, purposeID); !ok {
+		return nil, ErrFlowPurposeNotFound
+	}
+	if _, err := s.repo.GetDataSource(tenantID, dataSourceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFlowDataSourceNotFound
+		}
+		return nil, err
+	}
+	ps := &models.PurposeSystem{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		PurposeID:    purposeID,
+		DataSourceID: dataSourceID,
+		Note:         strings.TrimSpace(note),
+		DeclaredBy:   userID,
+	}
+	if err := s.repo.DeclareSystem(ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// RemoveSystem withdraws a system declaration
+func (s *DataFlowService) RemoveSystem(tenantID, purposeID, dataSourceID uuid.UUID) error {
+	removed, err := s.repo.RemoveSystem(tenantID, purposeID, dataSourceID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrPurposeSystemNotFound
+	}
+	return nil
+}
+
+func (s *DataFlowService) ListSystems(tenantID uuid.UUID) ([]models.PurposeSystem, error) {
+	return s.repo.ListPurposeSystems(tenantID)
+}
+
+// dataFlowInputs is everything the map is built from
+type dataFlowInputs struct {
+	purposes     []models.Purpose // active, with consent form vendors merged in
+	objects      map[uuid.UUID][]string
+	systems      []models.DataSource
+	declarations []models.PurposeSystem
+	findings     []models.DiscoveryResult
+}
+
+func (s *DataFlowService) load(tenantID uuid.UUID, purposes []models.Purpose) (*dataFlowInputs, error) {
+	in := &dataFlowInputs{objects: map[uuid.UUID][]string{}}
+	formPurposes, err := s.repo.ListFormPurposes(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	formVendors := map[uuid.UUID][]string{}
+	for _, fp := range formPurposes {
+		in.objects[fp.PurposeID] = append(in.objects[fp.PurposeID], fp.DataObjects...)
+		formVendors[fp.PurposeID] = append(formVendors[fp.PurposeID], fp.VendorIDs...)
+	}
+	for _, p := range purposes {
+		if !p.Active {
+			continue
+		}
+		vendors := append(append([]string{}, p.Vendors...), formVendors[p.ID]...)
+		p.Vendors = vendors
+		in.purposes = append(in.purposes, p)
+	}
+	if in.systems, err = s.repo.ListDataSources(tenantID); err != nil {
+		return nil, err
+	}
+	if in.declarations, err = s.repo.ListPurposeSystems(tenantID); err != nil {
+		return nil, err
+	}
+	findings, err := s.repo.ListFindings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	// Rescans report the same column again; keep one finding per column
+	seen := map[string]bool{}
+	for _, f := range findings {
+		key := f.DataSourceID.String() + "." + f.TableName + "." + f.ColumnName + "." + DataObjectKey(f.PIIType)
+		if !seen[key] {
+			seen[key] = true
+			in.findings = append(in.findings, f)
+		}
+	}
+	return in, nil
+}
+
+// Graph builds the tenant's data flow map from its active purposes
+func (s *DataFlowService) Graph(tenantID uuid.UUID, purposes []models.Purpose) (*DataFlowGraph, error) {
+	in, err := s.load(tenantID, purposes)
+	if err != nil {
+		return nil, err
+	}
+	g := newDataFlowGraph(time.Now().UTC())
+
+	for _, p := range in.purposes {
+		attrs := map[string]string{}
+		if p.LegalBasis != "" {
+			attrs["legalBasis"] = p.LegalBasis
+		}
+		g.addNode(DataFlowNode{ID: purposeNodeID(p.ID), Type: FlowNodePurpose, Label: p.Name, Attributes: attrs})
+		for _, obj := range in.objects[p.ID] {
+			key := DataObjectKey(obj)
+			if key == "" {
+				continue
+			}
+			g.addNode(DataFlowNode{ID: dataObjectNodeID(key), Type: FlowNodeDataObject, Label: obj})
+			g.addEdge(purposeNodeID(p.ID), dataObjectNodeID(key), FlowEdgeCollects, "")
+		}
+	}
+
+	systems := map[uuid.UUID]bool{}
+	for _, ds := range in.systems {
+		systems[ds.ID] = true
+		g.addNode(DataFlowNode{ID: systemNodeID(ds.ID), Type: FlowNodeSystem, Label: ds.Name,
+			Attributes: map[string]string{"type": ds.Type}})
+	}
+	for _, d := range in.declarations {
+		if _, ok := g.Node(purposeNodeID(d.PurposeID)); ok && systems[d.DataSourceID] {
+			g.addEdge(purposeNodeID(d.PurposeID), systemNodeID(d.DataSourceID), FlowEdgeProcessedIn, d.Note)
+		}
+	}
+	for _, f := range in.findings {
+		key := DataObjectKey(f.PIIType)
+		if key == "" || !systems[f.DataSourceID] {
+			continue
+		}
+		g.addNode(DataFlowNode{ID: dataObjectNodeID(key), Type: FlowNodeDataObject, Label: f.PIIType})
+		g.addEdge(dataObjectNodeID(key), systemNodeID(f.DataSourceID), FlowEdgeStoredIn, f.TableName+"."+f.ColumnName)
+	}
+
+	located := func(from, code string) {
+		if code == "" {
+			return
+		}
+		label := code
+		if c, ok := localization.LookupCountry(code); ok {
+			label = c.Name
+		}
+		g.addNode(DataFlowNode{ID: locationNodeID(code), Type: FlowNodeLocation, Label: label})
+		g.addEdge(from, locationNodeID(code), FlowEdgeLocatedIn, "")
+	}
+	vendors, ids := resolvePurposeVendors(s.vendorRepo, in.purposes)
+	for _, p := range in.purposes {
+		for _, ref := range p.Vendors {
+			id := vendorNodeID(ref)
+			if _, ok := g.Node(id); !ok {
+				node := DataFlowNode{ID: id, Type: FlowNodeVendor, Label: ref, Attributes: map[string]string{}}
+				if v := vendors[ref]; v != nil {
+					node.Label = v.Company
+					node.Attributes["processingLocation"] = v.ProcessingLocation
+				} else {
+					node.Attributes["registered"] = "false"
+				}
+				g.addNode(node)
+				if v := vendors[ref]; v != nil {
+					located(id, vendorCountry(v))
+				}
+			}
+			g.addEdge(purposeNodeID(p.ID), id, FlowEdgeSharedWith, "")
+		}
+	}
+	subs, err := s.subRepo.ListApproved(tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		sp := &subs[i]
+		id := subProcessorNodeID(sp.ID)
+		g.addNode(DataFlowNode{ID: id, Type: FlowNodeSubProcessor, Label: sp.Name,
+			Attributes: map[string]string{"services": sp.Services, "location": sp.Location}})
+		g.addEdge(vendorNodeID(sp.VendorID.String()), id, FlowEdgeSubProcessedBy, "")
+		located(id, subProcessorCountry(sp))
+	}
+	return g, nil
+}
+
+// Gaps lists discovered PII that no active purpose collects and active
+// purposes with no declared system
+func (s *DataFlowService) Gaps(tenantID uuid.UUID, purposes []models.Purpose) (*DataFlowGaps, error) {
+	in, err := s.load(tenantID, purposes)
+	if err != nil {
+		return nil, err
+	}
+	gaps := &DataFlowGaps{
+		GeneratedAt:            time.Now().UTC(),
+		UnmappedFindings:       []UnmappedFinding{},
+		PurposesWithoutSystems: []UnmappedPurpose{},
+	}
+	collected := map[string]bool{}
+	for _, p := range in.purposes {
+		for _, obj := range in.objects[p.ID] {
+			collected[DataObjectKey(obj)] = true
+		}
+	}
+	names := map[uuid.UUID]string{}
+	for _, ds := range in.systems {
+		names[ds.ID] = ds.Name
+	}
+	for _, f := range in.findings {
+		if collected[DataObjectKey(f.PIIType)] {
+			continue
+		}
+		gaps.UnmappedFindings = append(gaps.UnmappedFindings, UnmappedFinding{
+			DataSourceID: f.DataSourceID, System: names[f.DataSourceID], TableName: f.TableName,
+			ColumnName: f.ColumnName, PIIType: f.PIIType, Classification: f.Classification,
+		})
+	}
+	declared := map[uuid.UUID]bool{}
+	for _, d := range in.declarations {
+		declared[d.PurposeID] = true
+	}
+	for _, p := range in.purposes {
+		if declared[p.ID] {
+			continue
+		}
+		objects := map[string]bool{}
+		for _, obj := range in.objects[p.ID] {
+			objects[obj] = true
+		}
+		gaps.PurposesWithoutSystems = append(gaps.PurposesWithoutSystems, UnmappedPurpose{
+			PurposeID: p.ID, Name: p.Name, DataObjects: sortedKeys(objects),
+		})
+	}
+	return gaps, nil
+}
+
+// Export renders the graph as "json", "graphml" or "dot". It returns the
+// document, its content type and a file name.
+func (g *DataFlowGraph) Export(format string) ([]byte, string, string, error) {
+	switch format {
+	case "", "json":
+		doc, err := json.MarshalIndent(g, "", "  ")
+		return doc, "application/json", "data-flow.json", err
+	case "graphml":
+		doc, err := g.graphML()
+		return doc, "application/graphml+xml", "data-flow.graphml", err
+	case "dot":
+		return g.dot(), "text/vnd.graphviz", "data-flow.dot", nil
+	default:
+		return nil, "", "", fmt.Errorf("%w: %q", ErrUnsupportedDataFlowFormat, format)
+	}
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func (g *DataFlowGraph) graphML() ([]byte, error) {
+	doc := graphMLDoc{XMLNS: "http://graphml.graphdrawing.org/xmlns"}
+	doc.Keys = []graphMLKey{
+		{ID: "type", For: "node", Name: "type", Type: "string"},
+		{ID: "label", For: "node", Name: "label", Type: "string"},
+		{ID: "relation", For: "edge", Name: "relation", Type: "string"},
+		{ID: "detail", For: "edge", Name: "label", Type: "string"},
+	}
+	attrs := map[string]bool{}
+	for _, n := range g.Nodes {
+		for k := range n.Attributes {
+			attrs[k] = true
+		}
+	}
+	for _, k := range sortedKeys(attrs) {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "attr_" + k, For: "node", Name: k, Type: "string"})
+	}
+	doc.Graph.ID = "data-flow"
+	doc.Graph.EdgeDefault = "directed"
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: n.ID, Data: []graphMLData{{Key: "type", Value: n.Type}, {Key: "label", Value: n.Label}}}
+		keys := make([]string, 0, len(n.Attributes))
+		for k := range n.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			node.Data = append(node.Data, graphMLData{Key: "attr_" + k, Value: n.Attributes[k]})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		edge := graphMLEdge{Source: e.From, Target: e.To, Data: []graphMLData{{Key: "relation", Value: e.Relation}}}
+		if e.Label != "" {
+			edge.Data = append(edge.Data, graphMLData{Key: "detail", Value: e.Label})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// dotShapes draws each node type differently in Graphviz
+var dotShapes = map[string]string{
+	FlowNodePurpose:      "box",
+	FlowNodeDataObject:   "ellipse",
+	FlowNodeSystem:       "cylinder",
+	FlowNodeVendor:       "component",
+	FlowNodeSubProcessor: "component",
+	FlowNodeLocation:     "octagon",
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func (g *DataFlowGraph) dot() []byte {
+	var b bytes.Buffer
+	b.WriteString("digraph data_flow {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(n.Label), dotShapes[n.Type])
+	}
+	for _, e := range g.Edges {
+		label := e.Relation
+		if e.Label != "" {
+			label += "\n" + e.Label
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(label))
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
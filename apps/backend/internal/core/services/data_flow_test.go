@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dataFlowFixture struct {
+	svc        *DataFlowService
+	tenantID   uuid.UUID
+	purposes   []models.Purpose
+	kyc        uuid.UUID
+	marketing  uuid.UUID
+	crm        models.Vendor
+	usersDB    uuid.UUID
+	analytics  uuid.UUID
+	supportSub uuid.UUID
+}
+
+func setupDataFlow(t *testing.T) *dataFlowFixture {
+	_, subs, _, db := setupTransfers(t)
+	require.NoError(t, db.AutoMigrate(&models.ConsentForm{}, &models.ConsentFormPurpose{},
+		&models.DataSource{}, &models.DiscoveryResult{}, &models.PurposeSystem{}))
+	vendorRepo := repository.NewVendorRepository(db)
+	f := &dataFlowFixture{
+		svc:       NewDataFlowService(repository.NewDataFlowRepository(db), vendorRepo, repository.NewSubProcessorRepository(db)),
+		tenantID:  uuid.New(),
+		kyc:       uuid.New(),
+		marketing: uuid.New(),
+		usersDB:   uuid.New(),
+		analytics: uuid.New(),
+	}
+	f.crm = models.Vendor{VendorID: uuid.New(), Company: "CRMCo", Email: "dpo@crm.example", ProcessingLocation: "Frankfurt, Germany"}
+	require.NoError(t, db.Create(&f.crm).Error)
+	sp, err := subs.Declare(f.tenantID, f.crm.VendorID, uuid.New(), SubProcessorInput{Name: "SupportCo", Location: "Manila", Services: "Support", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	f.supportSub = sp.ID
+
+	f.purposes = []models.Purpose{
+		{ID: f.kyc, Name: "KYC", Active: true, LegalBasis: "legal_obligation"},
+		{ID: f.marketing, Name: "Marketing", Active: true, IsThirdParty: true, Vendors: pq.StringArray{"Legacy Mailer"}},
+		{ID: uuid.New(), Name: "Retired", Active: false},
+	}
+	form := models.ConsentForm{ID: uuid.New(), TenantID: f.tenantID, Name: "Signup", FormLink: "signup"}
+	require.NoError(t, db.Create(&form).Error)
+	require.NoError(t, db.Create(&[]models.ConsentFormPurpose{
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: f.kyc, DataObjects: pq.StringArray{"PAN Number", "Email"}},
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: f.marketing, DataObjects: pq.StringArray{"email", "Mobile"},
+			VendorIDs: pq.StringArray{f.crm.VendorID.String()}},
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: f.purposes[2].ID, DataObjects: pq.StringArray{"aadhaar"}},
+	}).Error)
+
+	require.NoError(t, db.Create(&[]models.DataSource{
+		{ID: f.usersDB, TenantID: f.tenantID, Name: "users-db", Type: "postgres"},
+		{ID: f.analytics, TenantID: f.tenantID, Name: "analytics", Type: "mysql"},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.DiscoveryResult{
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.usersDB, TableName: "users", ColumnName: "pan_no", PIIType: "pan"},
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.usersDB, TableName: "users", ColumnName: "pan_no", PIIType: "pan"}, // rescan
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.usersDB, TableName: "kyc", ColumnName: "pan", PIIType: "pan"},
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.usersDB, TableName: "users", ColumnName: "email", PIIType: "email"},
+		{ID: uuid.New(), TenantID: f.tenantID, DataSourceID: f.analytics, TableName: "events", ColumnName: "aadhaar_no", PIIType: "aadhaar", Classification: "restricted"},
+	}).Error)
+	return f
+}
+
+func TestDataObjectKey(t *testing.T) {
+	for name, want := range map[string]string{
+		"PAN Number": "pan", "pan": "pan", "Mobile": "phone_in", "phone_in": "phone_in",
+		"Email Address": "email", "Theme": "theme",
+	} {
+		assert.Equal(t, want, DataObjectKey(name), name)
+	}
+}
+
+func TestDataFlow_DeclareSystems(t *testing.T) {
+	f := setupDataFlow(t)
+	userID := uuid.New()
+
+	_, err := f.svc.DeclareSystem(f.tenantID, userID, f.purposes, uuid.New(), f.usersDB, "")
+	assert.ErrorIs(t, err, ErrFlowPurposeNotFound)
+	_, err = f.svc.DeclareSystem(f.tenantID, userID, f.purposes, f.kyc, uuid.New(), "")
+	assert.ErrorIs(t, err, ErrFlowDataSourceNotFound)
+	_, err = f.svc.DeclareSystem(uuid.New(), userID, f.purposes, f.kyc, f.usersDB, "")
+	assert.ErrorIs(t, err, ErrFlowDataSourceNotFound) // Another tenant's source
+
+	_, err = f.svc.DeclareSystem(f.tenantID, userID, f.purposes, f.kyc, f.usersDB, "")
+	require.NoError(t, err)
+	// Declaring again updates the note rather than adding a row
+	_, err = f.svc.DeclareSystem(f.tenantID, userID, f.purposes, f.kyc, f.usersDB, "KYC records")
+	require.NoError(t, err)
+	list, err := f.svc.ListSystems(f.tenantID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "KYC records", list[0].Note)
+
+	require.NoError(t, f.svc.RemoveSystem(f.tenantID, f.kyc, f.usersDB))
+	assert.ErrorIs(t, f.svc.RemoveSystem(f.tenantID, f.kyc, f.usersDB), ErrPurposeSystemNotFound)
+}
+
+func TestDataFlow_GraphAndQueries(t *testing.T) {
+	f := setupDataFlow(t)
+	_, err := f.svc.DeclareSystem(f.tenantID, uuid.New(), f.purposes, f.kyc, f.usersDB, "")
+	require.NoError(t, err)
+
+	g, err := f.svc.Graph(f.tenantID, f.purposes)
+	require.NoError(t, err)
+	assert.Len(t, g.NodesOf(FlowNodePurpose), 2) // Retired is inactive
+	// Form and discovery names for the same data share a node
+	assert.Len(t, g.NodesOf(FlowNodeDataObject), 4) // pan, email, phone_in, aadhaar
+	assert.Len(t, g.NodesOf(FlowNodeSystem), 2)
+	assert.Len(t, g.NodesOf(FlowNodeVendor), 2)
+	legacy, ok := g.Node(vendorNodeID("Legacy Mailer"))
+	require.True(t, ok)
+	assert.Equal(t, "false", legacy.Attributes["registered"])
+
+	// Where does PAN flow?
+	pan, err := g.DataObjectFlow("PAN")
+	require.NoError(t, err)
+	_, ok = pan.Node(purposeNodeID(f.kyc))
+	assert.True(t, ok)
+	_, ok = pan.Node(purposeNodeID(f.marketing))
+	assert.False(t, ok)
+	_, ok = pan.Node(dataObjectNodeID("email"))
+	assert.False(t, ok, "other data objects of the purpose are left out")
+	var stored *DataFlowEdge
+	for i, e := range pan.Edges {
+		if e.Relation == FlowEdgeStoredIn {
+			stored = &pan.Edges[i]
+		}
+	}
+	require.NotNil(t, stored)
+	assert.Equal(t, systemNodeID(f.usersDB), stored.To)
+	assert.Equal(t, "kyc.pan, users.pan_no", stored.Label)
+
+	// Email is shared with CRMCo through Marketing, and on to its sub-processor in the Philippines
+	email, err := g.DataObjectFlow("email_address")
+	require.NoError(t, err)
+	for _, id := range []string{vendorNodeID(f.crm.VendorID.String()), subProcessorNodeID(f.supportSub), locationNodeID("DE"), locationNodeID("PH")} {
+		_, ok := email.Node(id)
+		assert.True(t, ok, id)
+	}
+
+	// Which purposes feed CRMCo?
+	feeds, err := g.VendorFeeds(f.crm.VendorID.String())
+	require.NoError(t, err)
+	require.Len(t, feeds.NodesOf(FlowNodePurpose), 1)
+	assert.Equal(t, "Marketing", feeds.NodesOf(FlowNodePurpose)[0].Label)
+	assert.Len(t, feeds.NodesOf(FlowNodeDataObject), 2)
+	assert.Empty(t, feeds.NodesOf(FlowNodeSystem))
+	legacyFeeds, err := g.VendorFeeds("legacy mailer")
+	require.NoError(t, err)
+	assert.Len(t, legacyFeeds.NodesOf(FlowNodePurpose), 1)
+
+	_, err = g.DataObjectFlow("passport")
+	assert.ErrorIs(t, err, ErrFlowNodeNotFound)
+	_, err = g.VendorFeeds(uuid.New().String())
+	assert.ErrorIs(t, err, ErrFlowNodeNotFound)
+}
+
+func TestDataFlow_Export(t *testing.T) {
+	f := setupDataFlow(t)
+	g, err := f.svc.Graph(f.tenantID, f.purposes)
+	require.NoError(t, err)
+
+	doc, contentType, filename, err := g.Export("graphml")
+	require.NoError(t, err)
+	assert.Equal(t, "application/graphml+xml", contentType)
+	assert.Equal(t, "data-flow.graphml", filename)
+	var parsed graphMLDoc
+	require.NoError(t, xml.Unmarshal(doc, &parsed))
+	assert.Len(t, parsed.Graph.Nodes, len(g.Nodes))
+	assert.Len(t, parsed.Graph.Edges, len(g.Edges))
+
+	doc, _, _, err = g.Export("dot")
+	require.NoError(t, err)
+	dot := string(doc)
+	assert.True(t, strings.HasPrefix(dot, "digraph data_flow {"))
+	assert.Contains(t, dot, `"purpose:`+f.kyc.String()+`" -> "data:pan" [label="collects"];`)
+	assert.Contains(t, dot, `[label="CRMCo", shape=component];`)
+
+	doc, contentType, _, err = g.Export("json")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.Contains(t, string(doc), `"relation": "shared_with"`)
+
+	_, _, _, err = g.Export("svg")
+	assert.ErrorIs(t, err, ErrUnsupportedDataFlowFormat)
+}
+
+func TestDataFlow_Gaps(t *testing.T) {
+	f := setupDataFlow(t)
+	_, err := f.svc.DeclareSystem(f.tenantID, uuid.New(), f.purposes, f.marketing, f.analytics, "")
+	require.NoError(t, err)
+
+	gaps, err := f.svc.Gaps(f.tenantID, f.purposes)
+	require.NoError(t, err)
+	// Aadhaar is only collected by an inactive purpose
+	require.Len(t, gaps.UnmappedFindings, 1)
+	assert.Equal(t, "analytics", gaps.UnmappedFindings[0].System)
+	assert.Equal(t, "aadhaar_no", gaps.UnmappedFindings[0].ColumnName)
+	assert.Equal(t, "restricted", gaps.UnmappedFindings[0].Classification)
+
+	require.Len(t, gaps.PurposesWithoutSystems, 1)
+	assert.Equal(t, "KYC", gaps.PurposesWithoutSystems[0].Name)
+	assert.Equal(t, []string{"Email", "PAN Number"}, gaps.PurposesWithoutSystems[0].DataObjects)
+}
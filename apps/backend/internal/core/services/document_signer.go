@@ -0,0 +1,62 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// DocumentSigner signs generated documents (regulator reports, manifests)
+// with the server's RSA key so recipients can check them against the
+// published public key.
+type DocumentSigner struct {
+	key   *rsa.PrivateKey
+	keyID string
+}
+
+func NewDocumentSigner(key *rsa.PrivateKey) *DocumentSigner {
+	s := &DocumentSigner{key: key}
+	if key != nil {
+		if der, err := x509.MarshalPKIXPublicKey(&key.PublicKey); err == nil {
+			sum := sha256.Sum256(der)
+			s.keyID = hex.EncodeToString(sum[:8])
+		}
+	}
+	return s
+}
+
+// KeyID is a short fingerprint of the public key, recorded with each signature
+func (s *DocumentSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign returns a base64 RSA PKCS#1 v1.5 SHA-256 signature over data
+func (s *DocumentSigner) Sign(data []byte) (string, error) {
+	if s == nil || s.key == nil {
+		return "", errors.New("document signing is not configured")
+	}
+	sum := sha256.Sum256(data)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Verify checks a signature produced by Sign
+func (s *DocumentSigner) Verify(data []byte, signature string) bool {
+	if s == nil || s.key == nil {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, sum[:], sig) == nil
+}
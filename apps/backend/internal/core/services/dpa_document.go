@@ -0,0 +1,509 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/dpa"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	ErrDPATemplateNotFound        = errors.New("DPA template not found")
+	ErrDPATemplateVersionNotFound = errors.New("DPA template version not found")
+	ErrDPAClauseNotFound          = errors.New("DPA clause not found")
+	ErrInvalidDPAClause           = errors.New("invalid DPA clause")
+	ErrDPAAlreadySigned           = errors.New("a signed copy has already been stored for this agreement")
+	ErrDPADocumentTampered        = errors.New("stored DPA document does not match its recorded hash")
+)
+
+// DPATemplateInvalidError lists why a template revision was refused
+type DPATemplateInvalidError struct {
+	Issues []string
+}
+
+func (e *DPATemplateInvalidError) Error() string {
+	return "DPA template is not valid: " + strings.Join(e.Issues, "; ")
+}
+
+// DPAValuesInvalidError lists merge field values that are missing or of the
+// wrong type
+type DPAValuesInvalidError struct {
+	Issues []string
+}
+
+func (e *DPAValuesInvalidError) Error() string {
+	return "DPA field values are not valid: " + strings.Join(e.Issues, "; ")
+}
+
+// DPADocumentService keeps versioned DPA templates and the tenant's clause
+// library, and generates agreements from them as PDFs. Generated documents
+// and signed copies are kept in object storage with their SHA-256, and
+// checked against it whenever they are read back.
+type DPADocumentService struct {
+	repo       *repository.TPRMRepository
+	vendorRepo repository.VendorRepository
+	tenantRepo *repository.TenantRepository
+	store      *blob.Store
+}
+
+func NewDPADocumentService(repo *repository.TPRMRepository, vendorRepo repository.VendorRepository, tenantRepo *repository.TenantRepository, store *blob.Store) *DPADocumentService {
+	return &DPADocumentService{repo: repo, vendorRepo: vendorRepo, tenantRepo: tenantRepo, store: store}
+}
+
+// DPATemplateDetail is a template with its versions, newest first
+type DPATemplateDetail struct {
+	Template models.DPATemplate          `json:"template"`
+	Versions []models.DPATemplateVersion `json:"versions"`
+}
+
+// clauseLookup resolves clause keys against the tenant's current clauses
+func (s *DPADocumentService) clauseLookup(tenantID uuid.UUID) (dpa.ClauseLookup, error) {
+	current, err := s.repo.ListCurrentDPAClauses(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]*dpa.Clause{}
+	for _, c := range current {
+		byKey[c.Key] = &dpa.Clause{Key: c.Key, Version: c.Version, Title: c.Title, Body: c.Body}
+	}
+	return func(key string) (*dpa.Clause, bool) {
+		c, ok := byKey[key]
+		return c, ok
+	}, nil
+}
+
+func (s *DPADocumentService) checkRevision(tenantID uuid.UUID, body string, fields []dpa.Field) error {
+	issues := dpa.ValidateFields(fields)
+	if len(issues) == 0 {
+		lookup, err := s.clauseLookup(tenantID)
+		if err != nil {
+			return err
+		}
+		issues = dpa.CheckTemplate(body, dpa.Fields(fields), lookup)
+	}
+	if len(issues) > 0 {
+		return &DPATemplateInvalidError{Issues: issues}
+	}
+	return nil
+}
+
+// CreateTemplate stores a new template as version 1
+func (s *DPADocumentService) CreateTemplate(tenantID, createdBy uuid.UUID, name, description, body string, fields []dpa.Field) (*DPATemplateDetail, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &DPATemplateInvalidError{Issues: []string{"name is required"}}
+	}
+	t := &models.DPATemplate{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		IsActive:    true,
+		CreatedBy:   &createdBy,
+	}
+	if _, err := s.addVersion(t, createdBy, body, fields, ""); err != nil {
+		return nil, err
+	}
+	return s.GetTemplate(tenantID, t.ID)
+}
+
+// ReviseTemplate adds a version to a template. Earlier versions, and the
+// agreements generated from them, are untouched.
+func (s *DPADocumentService) ReviseTemplate(tenantID, templateID, revisedBy uuid.UUID, body string, fields []dpa.Field, changelog string) (*models.DPATemplateVersion, error) {
+	t, err := s.template(tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return s.addVersion(t, revisedBy, body, fields, changelog)
+}
+
+func (s *DPADocumentService) addVersion(t *models.DPATemplate, by uuid.UUID, body string, fields []dpa.Field, changelog string) (*models.DPATemplateVersion, error) {
+	if err := s.checkRevision(t.TenantID, body, fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = []dpa.Field{}
+	}
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	t.LatestVersion++
+	t.Version = fmt.Sprintf("%d", t.LatestVersion)
+	t.Content = body
+	v := &models.DPATemplateVersion{
+		ID:         uuid.New(),
+		TemplateID: t.ID,
+		TenantID:   t.TenantID,
+		Version:    t.LatestVersion,
+		Body:       body,
+		Fields:     fieldsJSON,
+		Changelog:  strings.TrimSpace(changelog),
+		CreatedBy:  by,
+	}
+	if err := s.repo.AddDPATemplateVersion(t, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *DPADocumentService) template(tenantID, id uuid.UUID) (*models.DPATemplate, error) {
+	t, err := s.repo.GetDPATemplate(id)
+	if err != nil || t.TenantID != tenantID {
+		return nil, ErrDPATemplateNotFound
+	}
+	return t, nil
+}
+
+func (s *DPADocumentService) ListTemplates(tenantID uuid.UUID) ([]models.DPATemplate, error) {
+	return s.repo.ListDPATemplates(tenantID)
+}
+
+func (s *DPADocumentService) GetTemplate(tenantID, id uuid.UUID) (*DPATemplateDetail, error) {
+	t, err := s.template(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.ListDPATemplateVersions(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &DPATemplateDetail{Template: *t, Versions: versions}, nil
+}
+
+// templateVersion loads a version of the template; 0 means the latest
+func (s *DPADocumentService) templateVersion(tenantID, templateID uuid.UUID, version int) (*models.DPATemplate, *models.DPATemplateVersion, []dpa.Field, error) {
+	t, err := s.template(tenantID, templateID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if version == 0 {
+		version = t.LatestVersion
+	}
+	v, err := s.repo.GetDPATemplateVersion(t.ID, version)
+	if err != nil {
+		return nil, nil, nil, ErrDPATemplateVersionNotFound
+	}
+	var custom []dpa.Field
+	if err := json.Unmarshal(v.Fields, &custom); err != nil && len(v.Fields) > 0 {
+		return nil, nil, nil, fmt.Errorf("failed to read fields of template version %d: %w", v.Version, err)
+	}
+	return t, v, dpa.Fields(custom), nil
+}
+
+// Clause library
+
+// SaveClause adds a new version of the clause with this key
+func (s *DPADocumentService) SaveClause(tenantID, by uuid.UUID, key, title, category, body string) (*models.DPAClause, error) {
+	key = strings.TrimSpace(key)
+	if !dpaClauseKey(key) {
+		return nil, fmt.Errorf("%w: key must be lower case letters, digits and underscores", ErrInvalidDPAClause)
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidDPAClause)
+	}
+	// Clause bodies may use any standard field but no other clause
+	noClauses := func(string) (*dpa.Clause, bool) { return nil, false }
+	if issues := dpa.CheckTemplate(body, dpa.StandardFields, noClauses); len(issues) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDPAClause, strings.Join(issues, "; "))
+	}
+	history, err := s.repo.ListDPAClauseVersions(tenantID, key)
+	if err != nil {
+		return nil, err
+	}
+	c := &models.DPAClause{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Key:       key,
+		Version:   1,
+		Title:     strings.TrimSpace(title),
+		Category:  strings.TrimSpace(category),
+		Body:      body,
+		IsCurrent: true,
+		CreatedBy: by,
+	}
+	if len(history) > 0 {
+		c.Version = history[0].Version + 1
+	}
+	if err := s.repo.AddDPAClauseVersion(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func dpaClauseKey(key string) bool {
+	if key == "" || len(key) > 64 {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *DPADocumentService) ListClauses(tenantID uuid.UUID) ([]models.DPAClause, error) {
+	return s.repo.ListCurrentDPAClauses(tenantID)
+}
+
+func (s *DPADocumentService) ClauseHistory(tenantID uuid.UUID, key string) ([]models.DPAClause, error) {
+	list, err := s.repo.ListDPAClauseVersions(tenantID, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrDPAClauseNotFound
+	}
+	return list, nil
+}
+
+// Generation
+
+// GenerateDPAInput selects the template version (0 for latest) and supplies
+// merge field values. Vendor and fiduciary fields are filled in from their
+// records when not given.
+type GenerateDPAInput struct {
+	TemplateID uuid.UUID              `json:"templateId"`
+	Version    int                    `json:"version"`
+	Values     map[string]interface{} `json:"values"`
+	ValidFrom  *time.Time             `json:"validFrom"`
+	ValidUntil *time.Time             `json:"validUntil"`
+}
+
+// DPAPreview is a rendered agreement that has not been stored
+type DPAPreview struct {
+	TemplateVersion int                    `json:"templateVersion"`
+	Values          map[string]interface{} `json:"values"`
+	Document        *dpa.Document          `json:"document"`
+}
+
+func (s *DPADocumentService) render(tenantID, vendorID uuid.UUID, in GenerateDPAInput) (*models.DPATemplate, *DPAPreview, error) {
+	vendor, err := s.vendorRepo.GetByID(vendorID)
+	if err != nil {
+		return nil, nil, ErrVendorNotFound
+	}
+	t, v, fields, err := s.templateVersion(tenantID, in.TemplateID, in.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := map[string]interface{}{}
+	defaults := map[string]string{
+		"vendor_name":         vendor.Company,
+		"vendor_email":        vendor.Email,
+		"vendor_address":      vendor.Address,
+		"processing_location": vendor.ProcessingLocation,
+	}
+	if tenant, err := s.tenantRepo.GetByID(tenantID); err == nil {
+		defaults["fiduciary_name"] = tenant.Name
+	}
+	for k, d := range defaults {
+		if d != "" {
+			values[k] = d
+		}
+	}
+	for k, val := range in.Values {
+		values[k] = val
+	}
+
+	checked, issues := dpa.CheckValues(fields, values)
+	if len(issues) > 0 {
+		return nil, nil, &DPAValuesInvalidError{Issues: issues}
+	}
+	lookup, err := s.clauseLookup(tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := dpa.Render(v.Body, fields, checked, lookup)
+	if err != nil {
+		// The template was checked when saved, so this is a clause that has
+		// since been changed in a way the template cannot use
+		return nil, nil, &DPATemplateInvalidError{Issues: []string{err.Error()}}
+	}
+	return t, &DPAPreview{TemplateVersion: v.Version, Values: values, Document: doc}, nil
+}
+
+// Preview renders an agreement without storing anything
+func (s *DPADocumentService) Preview(tenantID, vendorID uuid.UUID, in GenerateDPAInput) (*DPAPreview, error) {
+	_, p, err := s.render(tenantID, vendorID, in)
+	return p, err
+}
+
+// Generate renders an agreement to PDF, stores it and records the template
+// version, field values and clause versions it was built from
+func (s *DPADocumentService) Generate(tenantID, vendorID, generatedBy uuid.UUID, in GenerateDPAInput) (*models.DPAAgreement, error) {
+	t, p, err := s.render(tenantID, vendorID, in)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := json.Marshal(p.Values)
+	if err != nil {
+		return nil, err
+	}
+	clauses, err := json.Marshal(p.Document.Clauses)
+	if err != nil {
+		return nil, err
+	}
+	a := &models.DPAAgreement{
+		ID:              uuid.New(),
+		TenantID:        tenantID,
+		VendorID:        vendorID,
+		TemplateID:      &t.ID,
+		Status:          "generated",
+		ValidFrom:       in.ValidFrom,
+		ValidUntil:      in.ValidUntil,
+		TemplateVersion: p.TemplateVersion,
+		Variables:       variables,
+		ClauseVersions:  clauses,
+		GeneratedBy:     &generatedBy,
+	}
+	pdf, err := renderDPAPDF(t.Name, p.Document, a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render DPA PDF: %w", err)
+	}
+	a.DocumentSHA256 = sha256Hex(pdf)
+	key := fmt.Sprintf("dpa/%s/%s/%s.pdf", tenantID, vendorID, a.ID)
+	if a.DocumentPath, err = s.store.Put(key, "application/pdf", pdf); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateDPAAgreement(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *DPADocumentService) GetAgreement(tenantID, id uuid.UUID) (*models.DPAAgreement, error) {
+	a, err := s.repo.GetDPAAgreement(id)
+	if err != nil || a.TenantID != tenantID {
+		return nil, ErrDPANotFound
+	}
+	return a, nil
+}
+
+func (s *DPADocumentService) ListAgreements(tenantID, vendorID uuid.UUID) ([]models.DPAAgreement, error) {
+	return s.repo.ListDPAAgreementsByVendor(tenantID, vendorID)
+}
+
+func (s *DPADocumentService) readVerified(location, want string) ([]byte, error) {
+	data, err := s.store.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != want {
+		return nil, ErrDPADocumentTampered
+	}
+	return data, nil
+}
+
+// Document returns the generated PDF after checking its hash
+func (s *DPADocumentService) Document(tenantID, id uuid.UUID) (*models.DPAAgreement, []byte, error) {
+	a, err := s.GetAgreement(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.DocumentPath == "" {
+		return nil, nil, ErrDPANotFound
+	}
+	data, err := s.readVerified(a.DocumentPath, a.DocumentSHA256)
+	return a, data, err
+}
+
+// UploadSigned stores the countersigned copy of an agreement. A signed copy
+// is written once; it is never replaced.
+func (s *DPADocumentService) UploadSigned(tenantID, id, uploadedBy uuid.UUID, contentType string, data []byte) (*models.DPAAgreement, error) {
+	a, err := s.GetAgreement(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.SignedURL != "" {
+		return nil, ErrDPAAlreadySigned
+	}
+	ext := ".pdf"
+	if contentType != "" && contentType != "application/pdf" {
+		ext = ""
+	}
+	a.SignedSHA256 = sha256Hex(data)
+	key := fmt.Sprintf("dpa/%s/%s/%s-signed%s", tenantID, a.VendorID, a.ID, ext)
+	location, err := s.store.PutOnce(key, contentType, data, time.Time{})
+	if err != nil {
+		if errors.Is(err, blob.ErrObjectExists) {
+			return nil, ErrDPAAlreadySigned
+		}
+		return nil, err
+	}
+	now := time.Now()
+	a.SignedURL = location
+	a.SignedUploadedAt = &now
+	a.SignedUploadedBy = &uploadedBy
+	a.Status = "signed"
+	if err := s.repo.UpdateDPAAgreement(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SignedCopy returns the stored signed copy after checking its hash
+func (s *DPADocumentService) SignedCopy(tenantID, id uuid.UUID) (*models.DPAAgreement, []byte, error) {
+	a, err := s.GetAgreement(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.SignedURL == "" {
+		return nil, nil, ErrDPANotFound
+	}
+	data, err := s.readVerified(a.SignedURL, a.SignedSHA256)
+	return a, data, err
+}
+
+// renderDPAPDF lays out a rendered agreement. Lines starting "# " and "## "
+// are headings; blank lines separate paragraphs.
+func renderDPAPDF(title string, doc *dpa.Document, a *models.DPAAgreement) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("Agreement %s | template version %d", a.ID, a.TemplateVersion)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.MultiCell(0, 8, tr(title), "", "L", false)
+	pdf.Ln(4)
+
+	for _, para := range strings.Split(strings.ReplaceAll(doc.Text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(para, "## "):
+			pdf.SetFont("Arial", "B", 11)
+			pdf.MultiCell(0, 6, tr(strings.TrimPrefix(para, "## ")), "", "L", false)
+		case strings.HasPrefix(para, "# "):
+			pdf.SetFont("Arial", "B", 13)
+			pdf.MultiCell(0, 7, tr(strings.TrimPrefix(para, "# ")), "", "L", false)
+		default:
+			pdf.SetFont("Arial", "", 10)
+			pdf.MultiCell(0, 5, tr(para), "", "J", false)
+		}
+		pdf.Ln(3)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
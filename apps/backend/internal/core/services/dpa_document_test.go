@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pixpivot/arc/internal/dpa"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testDPABody = `# Data Processing Agreement
+
+This agreement is between {{field "fiduciary_name"}} and {{field "vendor_name"}}, effective {{field "effective_date"}}.
+
+The processor will process {{field "data_categories"}} for {{field "processing_purposes"}}.
+
+{{if flag "sub_processing_allowed"}}{{clause "sub_processing"}}{{else}}Sub-processing is not permitted.{{end}}
+
+Liability is capped at {{field "liability_cap"}}.`
+
+func setupDPADocuments(t *testing.T) (*DPADocumentService, *gorm.DB, string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Tenant{}, &models.Vendor{}, &models.DPATemplate{}, &models.DPATemplateVersion{},
+		&models.DPAClause{}, &models.DPAAgreement{},
+	))
+	dir := t.TempDir()
+	svc := NewDPADocumentService(repository.NewTPRMRepository(db), repository.NewVendorRepository(db), repository.NewTenantRepository(db),
+		blob.NewStore("local", dir, "", "", "", "", "", false, false))
+	return svc, db, dir
+}
+
+func TestDPADocument_TemplateValidation(t *testing.T) {
+	svc, _, _ := setupDPADocuments(t)
+	tenantID, userID := uuid.New(), uuid.New()
+
+	_, err := svc.CreateTemplate(tenantID, userID, "Standard DPA", "", `{{field "vendor_nmae"}}`, nil)
+	var invalid *DPATemplateInvalidError
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Issues[0], "vendor_nmae")
+
+	_, err = svc.CreateTemplate(tenantID, userID, "Standard DPA", "", testDPABody, nil)
+	require.ErrorAs(t, err, &invalid) // sub_processing is not in the library yet
+	assert.Contains(t, invalid.Issues[0], "sub_processing")
+
+	_, err = svc.CreateTemplate(tenantID, userID, "Standard DPA", "", `{{field "retention"}}`, []dpa.Field{
+		{Key: "retention", Type: "duration"}, {Key: "vendor_name", Type: dpa.FieldText},
+	})
+	require.ErrorAs(t, err, &invalid)
+	assert.Len(t, invalid.Issues, 2)
+
+	detail, err := svc.CreateTemplate(tenantID, userID, "Short DPA", "", `{{field "vendor_name"}} retains data for {{field "retention_days"}} days`, []dpa.Field{
+		{Key: "retention_days", Label: "Retention (days)", Type: dpa.FieldNumber, Required: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, detail.Template.LatestVersion)
+	require.Len(t, detail.Versions, 1)
+
+	_, err = svc.ReviseTemplate(tenantID, detail.Template.ID, userID, "{{field", nil, "broken")
+	require.ErrorAs(t, err, &invalid)
+	_, err = svc.ReviseTemplate(uuid.New(), detail.Template.ID, userID, testDPABody, nil, "")
+	assert.ErrorIs(t, err, ErrDPATemplateNotFound)
+}
+
+func TestDPADocument_GenerateRecordsVersionsAndHash(t *testing.T) {
+	svc, db, dir := setupDPADocuments(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Arc Bank"}).Error)
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme Cloud", Email: "legal@acme.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+
+	_, err := svc.SaveClause(tenantID, userID, "Sub Processing", "Sub-processing", "", "text")
+	assert.ErrorIs(t, err, ErrInvalidDPAClause)
+	_, err = svc.SaveClause(tenantID, userID, "sub_processing", "Sub-processing", "", `{{clause "other"}}`)
+	assert.ErrorIs(t, err, ErrInvalidDPAClause)
+	c1, err := svc.SaveClause(tenantID, userID, "sub_processing", "Sub-processing", "processors", `{{field "vendor_name"}} may engage sub-processors: {{field "sub_processing_terms"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c1.Version)
+
+	detail, err := svc.CreateTemplate(tenantID, userID, "Standard DPA", "Default processor terms", testDPABody, nil)
+	require.NoError(t, err)
+	templateID := detail.Template.ID
+
+	in := GenerateDPAInput{TemplateID: templateID, Values: map[string]interface{}{
+		"effective_date":         "2025-04-01",
+		"processing_purposes":    []interface{}{"payroll", "support"},
+		"data_categories":        []interface{}{"contact details"},
+		"sub_processing_allowed": true,
+		"sub_processing_terms":   "with 30 days notice",
+		"liability_cap":          "INR 1 crore",
+	}}
+
+	// Values are checked against their field types
+	bad := GenerateDPAInput{TemplateID: templateID, Values: map[string]interface{}{
+		"effective_date": "1 April 2025", "processing_purposes": "payroll", "unknown": "x",
+	}}
+	_, err = svc.Preview(tenantID, vendor.VendorID, bad)
+	var values *DPAValuesInvalidError
+	require.ErrorAs(t, err, &values)
+	joined := strings.Join(values.Issues, "\n")
+	assert.Contains(t, joined, "effective_date")
+	assert.Contains(t, joined, "processing_purposes")
+	assert.Contains(t, joined, "data_categories is required")
+	assert.Contains(t, joined, "unknown is not a field")
+
+	p, err := svc.Preview(tenantID, vendor.VendorID, in)
+	require.NoError(t, err)
+	assert.Contains(t, p.Document.Text, "between Arc Bank and Acme Cloud, effective 1 April 2025")
+	assert.Contains(t, p.Document.Text, "for payroll and support")
+	assert.Contains(t, p.Document.Text, "## Sub-processing\n\nAcme Cloud may engage sub-processors: with 30 days notice")
+
+	// A new clause version is picked up by later agreements only
+	first, err := svc.Generate(tenantID, vendor.VendorID, userID, in)
+	require.NoError(t, err)
+	_, err = svc.SaveClause(tenantID, userID, "sub_processing", "Sub-processing", "processors", `Sub-processors need prior written approval.`)
+	require.NoError(t, err)
+	second, err := svc.Generate(tenantID, vendor.VendorID, userID, in)
+	require.NoError(t, err)
+
+	var refs []dpa.ClauseRef
+	require.NoError(t, json.Unmarshal(first.ClauseVersions, &refs))
+	assert.Equal(t, []dpa.ClauseRef{{Key: "sub_processing", Version: 1}}, refs)
+	require.NoError(t, json.Unmarshal(second.ClauseVersions, &refs))
+	assert.Equal(t, []dpa.ClauseRef{{Key: "sub_processing", Version: 2}}, refs)
+	assert.Equal(t, 1, first.TemplateVersion)
+
+	history, err := svc.ClauseHistory(tenantID, "sub_processing")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.True(t, history[0].IsCurrent)
+	assert.False(t, history[1].IsCurrent)
+
+	// The stored PDF is checked against its hash when read back
+	_, pdf, err := svc.Document(tenantID, first.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(pdf), "%PDF"))
+	assert.Equal(t, sha256Hex(pdf), first.DocumentSHA256)
+
+	_, err = svc.GetAgreement(uuid.New(), first.ID)
+	assert.ErrorIs(t, err, ErrDPANotFound)
+
+	path := filepath.Join(dir, "dpa", tenantID.String(), vendor.VendorID.String(), first.ID.String()+".pdf")
+	require.NoError(t, os.WriteFile(path, append(pdf, ' '), 0o644))
+	_, _, err = svc.Document(tenantID, first.ID)
+	assert.ErrorIs(t, err, ErrDPADocumentTampered)
+
+	list, err := svc.ListAgreements(tenantID, vendor.VendorID)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestDPADocument_SignedCopyIsWrittenOnce(t *testing.T) {
+	svc, db, _ := setupDPADocuments(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme Cloud"}
+	require.NoError(t, db.Create(&vendor).Error)
+	detail, err := svc.CreateTemplate(tenantID, userID, "Minimal", "", `{{field "vendor_name"}} agrees.`, nil)
+	require.NoError(t, err)
+	a, err := svc.Generate(tenantID, vendor.VendorID, userID, GenerateDPAInput{TemplateID: detail.Template.ID, Values: map[string]interface{}{
+		"fiduciary_name": "Arc Bank", "effective_date": "2025-04-01",
+		"processing_purposes": []string{"hosting"}, "data_categories": []string{"logs"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "generated", a.Status)
+
+	_, _, err = svc.SignedCopy(tenantID, a.ID)
+	assert.ErrorIs(t, err, ErrDPANotFound)
+
+	signed := []byte("%PDF-1.4 countersigned")
+	a, err = svc.UploadSigned(tenantID, a.ID, userID, "application/pdf", signed)
+	require.NoError(t, err)
+	assert.Equal(t, "signed", a.Status)
+	assert.Equal(t, sha256Hex(signed), a.SignedSHA256)
+
+	_, err = svc.UploadSigned(tenantID, a.ID, userID, "application/pdf", []byte("%PDF-1.4 replacement"))
+	assert.ErrorIs(t, err, ErrDPAAlreadySigned)
+
+	_, data, err := svc.SignedCopy(tenantID, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, signed, data)
+}
@@ -0,0 +1,819 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/auth"
+	"pixpivot/arc/internal/dpa"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	ErrDPASigningNotFound     = errors.New("signing request not found")
+	ErrDPASigningTokenInvalid = errors.New("signing link is invalid or has expired")
+	ErrInvalidDPASigning      = errors.New("invalid signing request")
+	ErrDPANotSignable         = errors.New("only pending agreements can be sent for signature")
+	ErrDPASigningInProgress   = errors.New("agreement already has a signing request in progress")
+	ErrDPASigningClosed       = errors.New("signing request is no longer open")
+	ErrDPASignerAlreadySigned = errors.New("signer has already signed")
+	ErrDPASigningDocChanged   = errors.New("the document you reviewed is not the document under signature")
+	ErrSigningOTPInvalid      = errors.New("passcode is incorrect or has expired")
+	ErrSigningOTPLocked       = errors.New("too many incorrect passcodes; request a new one")
+	ErrSigningOTPTooSoon      = errors.New("a passcode was sent recently; wait before requesting another")
+	ErrDPASigningNotSealable  = errors.New("signing request cannot be sealed until every party has signed")
+	ErrDPASigningUnavailable  = errors.New("document signing key is not configured")
+)
+
+const (
+	dpaSigningDefaultDays = 14
+	dpaSigningOTPTTL      = 10 * time.Minute
+	dpaSigningOTPResend   = time.Minute
+	dpaSigningOTPAttempts = 5
+)
+
+type signingEmailSender interface {
+	Send(to, subject, body string) error
+	SendEmailWithAttachment(to, subject, body string, attachment []byte, filename string) error
+}
+
+// DPASigningService runs signing ceremonies for data processing agreements.
+// Each signatory gets a personal link, reviews the rendered agreement and
+// adopts a signature after confirming an emailed one-time passcode. When the
+// last party signs, the platform seals the agreement: it renders the final
+// PDF with a certificate of completion, signs it with the document signing
+// key and activates the agreement.
+type DPASigningService struct {
+	repo       *repository.DPASigningRepository
+	dpaRepo    *repository.EncryptedDPARepository
+	vendorRepo repository.VendorRepository
+	tenantRepo *repository.TenantRepository
+	store      *blob.Store
+	signer     *DocumentSigner
+	email      signingEmailSender
+	signingURL string
+}
+
+func NewDPASigningService(
+	repo *repository.DPASigningRepository,
+	dpaRepo *repository.EncryptedDPARepository,
+	vendorRepo repository.VendorRepository,
+	tenantRepo *repository.TenantRepository,
+	store *blob.Store,
+	signer *DocumentSigner,
+	emailService *EmailService,
+	signingURL string,
+) *DPASigningService {
+	s := &DPASigningService{
+		repo:       repo,
+		dpaRepo:    dpaRepo,
+		vendorRepo: vendorRepo,
+		tenantRepo: tenantRepo,
+		store:      store,
+		signer:     signer,
+		signingURL: strings.TrimRight(signingURL, "/"),
+	}
+	if emailService != nil {
+		s.email = emailService
+	}
+	return s
+}
+
+// SignerInput names a signatory for one of the parties
+type SignerInput struct {
+	Party string `json:"party"` // models.DPASignerTenant or models.DPASignerVendor
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Email string `json:"email"`
+}
+
+// StartSigningInput lists the signatories; ValidDays defaults to 14
+type StartSigningInput struct {
+	Signers   []SignerInput `json:"signers"`
+	ValidDays int           `json:"validDays"`
+}
+
+func (s *DPASigningService) agreement(tenantID, dpaID uuid.UUID) (*models.DataProcessingAgreement, error) {
+	a, err := s.dpaRepo.GetDPAByID(dpaID)
+	if err != nil || a.TenantID != tenantID {
+		return nil, ErrDPANotFound
+	}
+	return a, nil
+}
+
+func (s *DPASigningService) event(requestID uuid.UUID, signerID *uuid.UUID, event, ip, userAgent, docHash string) {
+	e := &models.DPASigningEvent{
+		ID:             uuid.New(),
+		RequestID:      requestID,
+		SignerID:       signerID,
+		Event:          event,
+		IP:             ip,
+		UserAgent:      userAgent,
+		DocumentSHA256: docHash,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.repo.AddEvent(e); err != nil {
+		log.Logger.Error().Err(err).Str("request_id", requestID.String()).Str("event", event).Msg("failed to record DPA signing event")
+	}
+}
+
+func checkSigners(in []SignerInput) ([]SignerInput, error) {
+	parties := map[string]bool{}
+	emails := map[string]bool{}
+	out := make([]SignerInput, 0, len(in))
+	for _, sg := range in {
+		sg.Name = strings.TrimSpace(sg.Name)
+		sg.Title = strings.TrimSpace(sg.Title)
+		sg.Email = strings.ToLower(strings.TrimSpace(sg.Email))
+		if sg.Party != models.DPASignerTenant && sg.Party != models.DPASignerVendor {
+			return nil, fmt.Errorf("%w: party must be %q or %q", ErrInvalidDPASigning, models.DPASignerTenant, models.DPASignerVendor)
+		}
+		if sg.Name == "" {
+			return nil, fmt.Errorf("%w: every signer needs a name", ErrInvalidDPASigning)
+		}
+		if _, err := mail.ParseAddress(sg.Email); err != nil {
+			return nil, fmt.Errorf("%w: %q is not a valid email address", ErrInvalidDPASigning, sg.Email)
+		}
+		if emails[sg.Email] {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrInvalidDPASigning, sg.Email)
+		}
+		emails[sg.Email] = true
+		parties[sg.Party] = true
+		out = append(out, sg)
+	}
+	if !parties[models.DPASignerTenant] || !parties[models.DPASignerVendor] {
+		return nil, fmt.Errorf("%w: both the tenant and the vendor need at least one signer", ErrInvalidDPASigning)
+	}
+	return out, nil
+}
+
+// Start renders the agreement, stores it and emails each signer a personal
+// signing link. It returns the request and each signer's link token; tokens
+// are only stored as hashes.
+func (s *DPASigningService) Start(tenantID, dpaID, startedBy uuid.UUID, in StartSigningInput) (*models.DPASigningRequest, map[uuid.UUID]string, error) {
+	if s.signer == nil || s.signer.KeyID() == "" {
+		return nil, nil, ErrDPASigningUnavailable
+	}
+	a, err := s.agreement(tenantID, dpaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.Status != string(dpa.DPAStatusPending) {
+		return nil, nil, ErrDPANotSignable
+	}
+	signers, err := checkSigners(in.Signers)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	open, err := s.repo.HasOpenRequest(a.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if open {
+		return nil, nil, ErrDPASigningInProgress
+	}
+	days := in.ValidDays
+	if days <= 0 {
+		days = dpaSigningDefaultDays
+	}
+
+	req := &models.DPASigningRequest{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		DPAID:     a.ID,
+		Status:    "pending",
+		ExpiresAt: now.AddDate(0, 0, days),
+		CreatedBy: startedBy,
+		CreatedAt: now,
+	}
+	parties := s.parties(a)
+	doc, err := renderDPASigningPDF(a, parties, req, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render agreement PDF: %w", err)
+	}
+	req.DocumentSHA256 = sha256Hex(doc)
+	key := fmt.Sprintf("dpa-signing/%s/%s/%s/document.pdf", tenantID, a.ID, req.ID)
+	if req.DocumentPath, err = s.store.PutOnce(key, "application/pdf", doc, time.Time{}); err != nil {
+		return nil, nil, err
+	}
+
+	tokens := map[uuid.UUID]string{}
+	events := []models.DPASigningEvent{{ID: uuid.New(), RequestID: req.ID, Event: "created", DocumentSHA256: req.DocumentSHA256, CreatedAt: now}}
+	for _, in := range signers {
+		token := auth.GenerateSecureToken()
+		sg := models.DPASigner{
+			ID:        uuid.New(),
+			RequestID: req.ID,
+			TenantID:  tenantID,
+			Party:     in.Party,
+			Name:      in.Name,
+			Title:     in.Title,
+			Email:     in.Email,
+			TokenHash: hashVendorToken(token),
+		}
+		tokens[sg.ID] = token
+		req.Signers = append(req.Signers, sg)
+		id := sg.ID
+		events = append(events, models.DPASigningEvent{ID: uuid.New(), RequestID: req.ID, SignerID: &id, Event: "link_sent", CreatedAt: now})
+	}
+	if err := s.repo.CreateRequest(req, events); err != nil {
+		return nil, nil, err
+	}
+
+	for _, sg := range req.Signers {
+		link := fmt.Sprintf("%s/dpa-signing?token=%s", s.signingURL, tokens[sg.ID])
+		body := fmt.Sprintf("Hello %s,<br><br>You have been asked to sign <b>%s</b> between %s and %s.<br><br>"+
+			"<a href=\"%s\">Review and sign the agreement</a><br><br>This link is personal to you and expires on %s. "+
+			"You will be sent a one-time passcode by email before signing.",
+			html.EscapeString(sg.Name), html.EscapeString(a.AgreementTitle), html.EscapeString(parties.Fiduciary),
+			html.EscapeString(parties.Vendor), link, req.ExpiresAt.Format("2 Jan 2006"))
+		s.send(sg.Email, "Signature requested: "+a.AgreementTitle, body, req.ID)
+	}
+	return req, tokens, nil
+}
+
+func (s *DPASigningService) send(to, subject, body string, requestID uuid.UUID) {
+	if s.email == nil {
+		return
+	}
+	if err := s.email.Send(to, subject, body); err != nil {
+		log.Logger.Error().Err(err).Str("request_id", requestID.String()).Msg("failed to send DPA signing email")
+	}
+}
+
+// dpaParties are the names printed on the agreement
+type dpaParties struct {
+	Fiduciary string
+	Vendor    string
+}
+
+func (s *DPASigningService) parties(a *models.DataProcessingAgreement) dpaParties {
+	p := dpaParties{Fiduciary: "Data Fiduciary", Vendor: "Data Processor"}
+	if t, err := s.tenantRepo.GetByID(a.TenantID); err == nil && t.Name != "" {
+		p.Fiduciary = t.Name
+	}
+	if v, err := s.vendorRepo.GetByID(a.VendorID); err == nil && v.Company != "" {
+		p.Vendor = v.Company
+	}
+	return p
+}
+
+func (s *DPASigningService) Get(tenantID, id uuid.UUID) (*models.DPASigningRequest, error) {
+	req, err := s.repo.GetRequest(tenantID, id)
+	if err != nil {
+		return nil, ErrDPASigningNotFound
+	}
+	return req, nil
+}
+
+func (s *DPASigningService) ListForDPA(tenantID, dpaID uuid.UUID) ([]models.DPASigningRequest, error) {
+	if _, err := s.agreement(tenantID, dpaID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRequestsByDPA(tenantID, dpaID)
+}
+
+// Events returns the ceremony's audit trail
+func (s *DPASigningService) Events(tenantID, id uuid.UUID) ([]models.DPASigningEvent, error) {
+	req, err := s.Get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(req.ID)
+}
+
+// Cancel closes a pending ceremony; its links stop working
+func (s *DPASigningService) Cancel(tenantID, id uuid.UUID) (*models.DPASigningRequest, error) {
+	req, err := s.Get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.SetRequestStatus(req.ID, "pending", "cancelled")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDPASigningClosed
+	}
+	now := time.Now()
+	req.Status = "cancelled"
+	req.CancelledAt = &now
+	if err := s.repo.UpdateRequest(req); err != nil {
+		return nil, err
+	}
+	s.event(req.ID, nil, "cancelled", "", "", "")
+	return req, nil
+}
+
+// Signer-facing operations
+
+// DPASigningSession is a signer authenticated by their link token
+type DPASigningSession struct {
+	Signer  *models.DPASigner
+	Request *models.DPASigningRequest
+}
+
+// Authenticate resolves a signing link. Links stay usable after completion
+// so signers can fetch the sealed copy, but not once cancelled or, while
+// still pending, expired.
+func (s *DPASigningService) Authenticate(token string) (*DPASigningSession, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrDPASigningTokenInvalid
+	}
+	sg, err := s.repo.GetSignerByTokenHash(hashVendorToken(token))
+	if err != nil {
+		return nil, ErrDPASigningTokenInvalid
+	}
+	req, err := s.repo.GetRequest(sg.TenantID, sg.RequestID)
+	if err != nil {
+		return nil, ErrDPASigningTokenInvalid
+	}
+	if req.Status == "cancelled" || (req.Status == "pending" && !time.Now().Before(req.ExpiresAt)) {
+		return nil, ErrDPASigningTokenInvalid
+	}
+	return &DPASigningSession{Signer: sg, Request: req}, nil
+}
+
+// DPASigningParty is another signer as shown to a signer
+type DPASigningParty struct {
+	Party    string     `json:"party"`
+	Name     string     `json:"name"`
+	Title    string     `json:"title,omitempty"`
+	SignedAt *time.Time `json:"signedAt,omitempty"`
+}
+
+// DPASigningView is what a signer sees on opening their link
+type DPASigningView struct {
+	RequestID       uuid.UUID         `json:"requestId"`
+	Status          string            `json:"status"`
+	AgreementTitle  string            `json:"agreementTitle"`
+	AgreementNumber string            `json:"agreementNumber"`
+	Fiduciary       string            `json:"fiduciary"`
+	Vendor          string            `json:"vendor"`
+	DocumentSHA256  string            `json:"documentSha256"`
+	ExpiresAt       time.Time         `json:"expiresAt"`
+	Signer          *models.DPASigner `json:"signer"`
+	Parties         []DPASigningParty `json:"parties"`
+}
+
+// Open returns the signer's view, recording the first time they open it
+func (s *DPASigningService) Open(sess *DPASigningSession, ip, userAgent string) (*DPASigningView, error) {
+	a, err := s.dpaRepo.GetDPAByID(sess.Request.DPAID)
+	if err != nil {
+		return nil, ErrDPANotFound
+	}
+	if sess.Signer.ViewedAt == nil {
+		now := time.Now()
+		sess.Signer.ViewedAt = &now
+		if err := s.repo.UpdateSigner(sess.Signer); err != nil {
+			return nil, err
+		}
+		s.event(sess.Request.ID, &sess.Signer.ID, "viewed", ip, userAgent, sess.Request.DocumentSHA256)
+	}
+	parties := s.parties(a)
+	view := &DPASigningView{
+		RequestID:       sess.Request.ID,
+		Status:          sess.Request.Status,
+		AgreementTitle:  a.AgreementTitle,
+		AgreementNumber: a.AgreementNumber,
+		Fiduciary:       parties.Fiduciary,
+		Vendor:          parties.Vendor,
+		DocumentSHA256:  sess.Request.DocumentSHA256,
+		ExpiresAt:       sess.Request.ExpiresAt,
+		Signer:          sess.Signer,
+	}
+	for _, sg := range sess.Request.Signers {
+		view.Parties = append(view.Parties, DPASigningParty{Party: sg.Party, Name: sg.Name, Title: sg.Title, SignedAt: sg.SignedAt})
+	}
+	return view, nil
+}
+
+// Document returns the agreement under signature after checking its hash
+func (s *DPASigningService) Document(sess *DPASigningSession) ([]byte, error) {
+	return s.verified(sess.Request.DocumentPath, sess.Request.DocumentSHA256)
+}
+
+func (s *DPASigningService) verified(location, want string) ([]byte, error) {
+	data, err := s.store.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != want {
+		return nil, ErrDPADocumentTampered
+	}
+	return data, nil
+}
+
+func hashSigningOTP(signerID uuid.UUID, code string) string {
+	return hashVendorToken(signerID.String() + ":" + code)
+}
+
+// SendOTP emails the signer a one-time passcode to confirm their signature
+func (s *DPASigningService) SendOTP(sess *DPASigningSession, ip, userAgent string) (time.Time, error) {
+	code, err := s.issueOTP(sess, ip, userAgent)
+	if err != nil {
+		return time.Time{}, err
+	}
+	body := fmt.Sprintf("Your passcode to sign the data processing agreement is <b>%s</b>.<br><br>"+
+		"It expires in %d minutes. If you did not request it, ignore this email.", code, int(dpaSigningOTPTTL.Minutes()))
+	s.send(sess.Signer.Email, "Your signing passcode", body, sess.Request.ID)
+	return *sess.Signer.OTPExpiresAt, nil
+}
+
+func (s *DPASigningService) issueOTP(sess *DPASigningSession, ip, userAgent string) (string, error) {
+	sg := sess.Signer
+	if sess.Request.Status != "pending" {
+		return "", ErrDPASigningClosed
+	}
+	if sg.SignedAt != nil {
+		return "", ErrDPASignerAlreadySigned
+	}
+	now := time.Now()
+	if sg.OTPSentAt != nil && now.Sub(*sg.OTPSentAt) < dpaSigningOTPResend {
+		return "", ErrSigningOTPTooSoon
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	expires := now.Add(dpaSigningOTPTTL)
+	sg.OTPHash = hashSigningOTP(sg.ID, code)
+	sg.OTPSentAt = &now
+	sg.OTPExpiresAt = &expires
+	sg.OTPAttempts = 0
+	if err := s.repo.UpdateSigner(sg); err != nil {
+		return "", err
+	}
+	s.event(sess.Request.ID, &sg.ID, "otp_sent", ip, userAgent, "")
+	return code, nil
+}
+
+// SignInput is a signer's adoption of their signature. DocumentSHA256 is
+// the hash of the document they reviewed; it must be the one under signature.
+type SignInput struct {
+	Passcode         string `json:"passcode"`
+	AdoptedSignature string `json:"adoptedSignature"`
+	DocumentSHA256   string `json:"documentSha256"`
+	Consent          bool   `json:"consent"`
+}
+
+// Sign records the signer's signature after checking their passcode. The
+// last signature seals the agreement.
+func (s *DPASigningService) Sign(sess *DPASigningSession, in SignInput, ip, userAgent string) (*models.DPASigningRequest, error) {
+	sg, req := sess.Signer, sess.Request
+	if req.Status != "pending" {
+		return nil, ErrDPASigningClosed
+	}
+	if sg.SignedAt != nil {
+		return nil, ErrDPASignerAlreadySigned
+	}
+	adopted := strings.TrimSpace(in.AdoptedSignature)
+	if adopted == "" || !in.Consent {
+		return nil, fmt.Errorf("%w: adopt a signature and consent to sign electronically", ErrInvalidDPASigning)
+	}
+	if !strings.EqualFold(strings.TrimSpace(in.DocumentSHA256), req.DocumentSHA256) {
+		return nil, ErrDPASigningDocChanged
+	}
+
+	now := time.Now()
+	if sg.OTPHash == "" || sg.OTPExpiresAt == nil || !now.Before(*sg.OTPExpiresAt) {
+		return nil, ErrSigningOTPInvalid
+	}
+	if sg.OTPAttempts >= dpaSigningOTPAttempts {
+		return nil, ErrSigningOTPLocked
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSigningOTP(sg.ID, strings.TrimSpace(in.Passcode))), []byte(sg.OTPHash)) != 1 {
+		sg.OTPAttempts++
+		if err := s.repo.UpdateSigner(sg); err != nil {
+			return nil, err
+		}
+		s.event(req.ID, &sg.ID, "otp_failed", ip, userAgent, "")
+		return nil, ErrSigningOTPInvalid
+	}
+
+	sg.OTPVerifiedAt = &now
+	sg.SignedAt = &now
+	sg.AdoptedSignature = adopted
+	sg.SignedIP = ip
+	sg.SignedUserAgent = userAgent
+	sg.SignedSHA256 = req.DocumentSHA256
+	stored, err := s.repo.RecordSignature(sg)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, ErrDPASignerAlreadySigned
+	}
+	s.event(req.ID, &sg.ID, "signed", ip, userAgent, req.DocumentSHA256)
+
+	remaining, err := s.repo.CountUnsigned(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining == 0 {
+		if err := s.seal(req.TenantID, req.ID); err != nil {
+			// The signature stands; the fiduciary can retry the seal
+			log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to seal signed DPA")
+		}
+	}
+	return s.repo.GetRequest(req.TenantID, req.ID)
+}
+
+// Seal retries sealing a request every party has signed
+func (s *DPASigningService) Seal(tenantID, id uuid.UUID) (*models.DPASigningRequest, error) {
+	req, err := s.Get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != "pending" {
+		return nil, ErrDPASigningClosed
+	}
+	remaining, err := s.repo.CountUnsigned(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		return nil, ErrDPASigningNotSealable
+	}
+	if err := s.seal(tenantID, req.ID); err != nil {
+		return nil, err
+	}
+	return s.Get(tenantID, id)
+}
+
+// seal renders the final PDF with the certificate of completion, signs it
+// and activates the agreement. Only one caller can seal a request.
+func (s *DPASigningService) seal(tenantID, id uuid.UUID) (err error) {
+	claimed, err := s.repo.SetRequestStatus(id, "pending", "sealing")
+	if err != nil || !claimed {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if _, rerr := s.repo.SetRequestStatus(id, "sealing", "pending"); rerr != nil {
+				log.Logger.Error().Err(rerr).Str("request_id", id.String()).Msg("failed to release DPA seal")
+			}
+		}
+	}()
+
+	req, err := s.repo.GetRequest(tenantID, id)
+	if err != nil {
+		return err
+	}
+	a, err := s.agreement(tenantID, req.DPAID)
+	if err != nil {
+		return err
+	}
+	events, err := s.repo.ListEvents(req.ID)
+	if err != nil {
+		return err
+	}
+	// The sealed copy is rendered from the agreement as it stands now, so
+	// check the terms still produce the document everyone signed
+	parties := s.parties(a)
+	unsigned, err := renderDPASigningPDF(a, parties, req, nil, nil)
+	if err != nil {
+		return err
+	}
+	if sha256Hex(unsigned) != req.DocumentSHA256 {
+		return ErrDPASigningDocChanged
+	}
+
+	now := time.Now()
+	req.CompletedAt = &now
+	req.SigningKeyID = s.signer.KeyID()
+	sealed, err := renderDPASigningPDF(a, parties, req, req.Signers, events)
+	if err != nil {
+		return fmt.Errorf("failed to render sealed PDF: %w", err)
+	}
+	req.SealedSHA256 = sha256Hex(sealed)
+	if req.SealSignature, err = s.signer.Sign(sealed); err != nil {
+		return err
+	}
+	// Each attempt writes its own object, so a seal that failed after the
+	// upload can be retried; only the copy the request records is served
+	key := fmt.Sprintf("dpa-signing/%s/%s/%s/sealed-%d.pdf", tenantID, a.ID, req.ID, now.UnixNano())
+	if req.SealedPath, err = s.store.PutOnce(key, "application/pdf", sealed, time.Time{}); err != nil {
+		return err
+	}
+
+	a.Status = string(dpa.DPAStatusActive)
+	a.SignedDate = &now
+	a.Signature = &req.SealSignature
+	for _, sg := range req.Signers {
+		if sg.Party == models.DPASignerTenant {
+			a.SignatoryName, a.SignatoryTitle = sg.Name, sg.Title
+			break
+		}
+	}
+	a.UpdatedAt = now
+	if err = s.dpaRepo.UpdateDPA(a); err != nil {
+		return err
+	}
+	req.Status = "completed"
+	if err = s.repo.UpdateRequest(req); err != nil {
+		return err
+	}
+	s.event(req.ID, nil, "sealed", "", "", req.SealedSHA256)
+
+	if s.email != nil {
+		body := fmt.Sprintf("All parties have signed <b>%s</b>. The sealed agreement with its certificate of completion is attached.<br><br>"+
+			"Document SHA-256: %s", html.EscapeString(a.AgreementTitle), req.SealedSHA256)
+		for _, sg := range req.Signers {
+			if err := s.email.SendEmailWithAttachment(sg.Email, "Completed: "+a.AgreementTitle, body, sealed, "dpa-"+a.ID.String()+"-sealed.pdf"); err != nil {
+				log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to send sealed DPA")
+			}
+		}
+	}
+	return nil
+}
+
+// Sealed returns the sealed agreement after checking the platform signature
+func (s *DPASigningService) Sealed(req *models.DPASigningRequest) ([]byte, error) {
+	if req.Status != "completed" || req.SealedPath == "" {
+		return nil, ErrDPASigningNotFound
+	}
+	data, err := s.store.Get(req.SealedPath)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != req.SealedSHA256 || !s.signer.Verify(data, req.SealSignature) {
+		return nil, ErrDPADocumentTampered
+	}
+	return data, nil
+}
+
+// renderDPASigningPDF renders the agreement terms. With signers it is the
+// sealed copy: signature blocks and a certificate of completion follow.
+func renderDPASigningPDF(a *models.DataProcessingAgreement, parties dpaParties, req *models.DPASigningRequest, signers []models.DPASigner, events []models.DPASigningEvent) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// Fixed dates and catalog order make the unsigned document reproducible
+	// from the agreement terms, so sealing can check they have not changed
+	created := req.CreatedAt
+	if signers != nil && req.CompletedAt != nil {
+		created = *req.CompletedAt
+	}
+	pdf.SetCreationDate(created.UTC().Truncate(time.Second))
+	pdf.SetModificationDate(created.UTC().Truncate(time.Second))
+	pdf.SetCatalogSort(true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("Agreement %s | signing request %s", a.ID, req.ID)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	section := func(name string) {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 8, tr(name))
+		pdf.Ln(8)
+	}
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(55, 6, tr(label))
+		pdf.SetFont("Arial", "", 10)
+		pdf.MultiCell(0, 6, tr(value), "", "L", false)
+	}
+	yesNo := func(b bool) string {
+		if b {
+			return "Yes"
+		}
+		return "No"
+	}
+	when := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format("02 Jan 2006 15:04:05 UTC")
+	}
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.MultiCell(0, 8, tr(a.AgreementTitle), "", "L", false)
+	pdf.Ln(2)
+	pdf.SetFont("Arial", "", 10)
+	pdf.MultiCell(0, 5, tr(fmt.Sprintf("Between %s (Data Fiduciary) and %s (Data Processor)", parties.Fiduciary, parties.Vendor)), "", "L", false)
+	pdf.Ln(4)
+
+	section("Agreement")
+	field("Agreement number", a.AgreementNumber)
+	field("Version", a.Version)
+	field("Effective date", a.EffectiveDate.Format("2 January 2006"))
+	if a.ExpiryDate != nil {
+		field("Expiry date", a.ExpiryDate.Format("2 January 2006"))
+	}
+	field("Governing law", a.GoverningLaw)
+	pdf.Ln(3)
+
+	section("Processing")
+	field("Purposes", strings.Join(jsonStrings(a.ProcessingPurposes), "; "))
+	field("Data categories", strings.Join(jsonStrings(a.DataCategories), "; "))
+	field("Location", a.ProcessingLocation)
+	field("Retention period", a.DataRetentionPeriod)
+	field("Sub-processing allowed", yesNo(a.SubProcessingAllowed))
+	pdf.Ln(3)
+
+	section("Safeguards and obligations")
+	field("Security measures", strings.Join(jsonStrings(a.SecurityMeasures), "; "))
+	field("Data principal rights", strings.Join(jsonStrings(a.DataSubjectRights), "; "))
+	field("Breach notification", yesNo(a.BreachNotification))
+	field("Audit rights", yesNo(a.AuditRights))
+	field("Liability cap", a.LiabilityCap)
+	field("Insurance", a.InsuranceCoverage)
+
+	if signers == nil {
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	pdf.Ln(6)
+	section("Signatures")
+	for _, sg := range signers {
+		party := parties.Vendor
+		if sg.Party == models.DPASignerTenant {
+			party = parties.Fiduciary
+		}
+		pdf.SetFont("Arial", "I", 14)
+		pdf.Cell(0, 8, tr(sg.AdoptedSignature))
+		pdf.Ln(8)
+		pdf.SetFont("Arial", "", 9)
+		pdf.MultiCell(0, 4.5, tr(fmt.Sprintf("%s, %s, for %s. Signed %s", sg.Name, sg.Title, party, when(sg.SignedAt))), "", "L", false)
+		pdf.Ln(3)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(0, 10, "Certificate of Completion")
+	pdf.Ln(12)
+	field("Signing request", req.ID.String())
+	field("Agreement", a.ID.String())
+	field("Document SHA-256", req.DocumentSHA256)
+	field("Sent", when(&req.CreatedAt))
+	field("Completed", when(req.CompletedAt))
+	field("Sealed with key", req.SigningKeyID)
+	pdf.Ln(4)
+
+	for _, sg := range signers {
+		section(fmt.Sprintf("%s (%s)", sg.Name, sg.Party))
+		field("Email", sg.Email)
+		field("Viewed", when(sg.ViewedAt))
+		field("Passcode verified", when(sg.OTPVerifiedAt))
+		field("Signed", when(sg.SignedAt))
+		field("IP address", sg.SignedIP)
+		field("User agent", sg.SignedUserAgent)
+		field("Document SHA-256", sg.SignedSHA256)
+		pdf.Ln(3)
+	}
+
+	section("Audit trail")
+	names := map[uuid.UUID]string{}
+	for _, sg := range signers {
+		names[sg.ID] = sg.Email
+	}
+	pdf.SetFont("Arial", "", 8)
+	for _, e := range events {
+		who := "platform"
+		if e.SignerID != nil {
+			who = names[*e.SignerID]
+		}
+		line := fmt.Sprintf("%s  %-10s  %s", e.CreatedAt.UTC().Format("2006-01-02 15:04:05 UTC"), e.Event, who)
+		if e.IP != "" {
+			line += "  from " + e.IP
+		}
+		pdf.MultiCell(0, 4, tr(line), "", "L", false)
+	}
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "", 8)
+	pdf.MultiCell(0, 4, "This document is sealed with the platform's document signing key. The detached signature and the "+
+		"SHA-256 of this file are recorded with the signing request and can be checked against the published public key.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// capturedMail keeps the last message sent to each address
+type capturedMail struct {
+	last     map[string]string
+	attached map[string]int
+}
+
+func (m *capturedMail) Send(to, subject, body string) error {
+	m.last[to] = body
+	return nil
+}
+
+func (m *capturedMail) SendEmailWithAttachment(to, subject, body string, attachment []byte, filename string) error {
+	m.last[to] = body
+	m.attached[to]++
+	return nil
+}
+
+var passcodePattern = regexp.MustCompile(`<b>(\d{6})</b>`)
+
+func (m *capturedMail) passcode(t *testing.T, to string) string {
+	match := passcodePattern.FindStringSubmatch(m.last[to])
+	require.Len(t, match, 2, "no passcode sent to %s", to)
+	return match[1]
+}
+
+func setupDPASigning(t *testing.T) (*DPASigningService, *repository.EncryptedDPARepository, *capturedMail, *gorm.DB) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Tenant{}, &models.Vendor{}, &models.EncryptedDataProcessingAgreement{},
+		&models.DPASigningRequest{}, &models.DPASigner{}, &models.DPASigningEvent{}))
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	dpaRepo := repository.NewEncryptedDPARepository(db)
+	svc := NewDPASigningService(repository.NewDPASigningRepository(db), dpaRepo, repository.NewVendorRepository(db),
+		repository.NewTenantRepository(db), blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false),
+		NewDocumentSigner(key), nil, "https://app.example.com")
+	mail := &capturedMail{last: map[string]string{}, attached: map[string]int{}}
+	svc.email = mail
+	return svc, dpaRepo, mail, db
+}
+
+func pendingDPA(t *testing.T, db *gorm.DB, repo *repository.EncryptedDPARepository, tenantID uuid.UUID) *models.DataProcessingAgreement {
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme Cloud"}
+	require.NoError(t, db.Create(&vendor).Error)
+	a := &models.DataProcessingAgreement{
+		ID:                 uuid.New(),
+		TenantID:           tenantID,
+		VendorID:           vendor.VendorID,
+		AgreementTitle:     "Hosting DPA",
+		AgreementNumber:    "DPA-" + uuid.NewString()[:8],
+		Status:             "pending",
+		EffectiveDate:      time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		ProcessingPurposes: []byte(`["hosting"]`),
+		DataCategories:     []byte(`["contact details"]`),
+		ProcessingLocation: "India",
+		LiabilityCap:       "INR 1 crore",
+	}
+	require.NoError(t, repo.CreateDPA(a))
+	return a
+}
+
+func signersFor() StartSigningInput {
+	return StartSigningInput{Signers: []SignerInput{
+		{Party: models.DPASignerTenant, Name: "Asha Rao", Title: "DPO", Email: "asha@arc.example"},
+		{Party: models.DPASignerVendor, Name: "Sam Lee", Title: "CISO", Email: "Sam@Acme.example"},
+	}}
+}
+
+func signerSession(t *testing.T, svc *DPASigningService, tokens map[uuid.UUID]string, req *models.DPASigningRequest, party string) *DPASigningSession {
+	for _, sg := range req.Signers {
+		if sg.Party == party {
+			sess, err := svc.Authenticate(tokens[sg.ID])
+			require.NoError(t, err)
+			return sess
+		}
+	}
+	t.Fatalf("no %s signer", party)
+	return nil
+}
+
+func TestDPASigning_AllPartiesSignThenSealed(t *testing.T) {
+	svc, dpaRepo, mail, db := setupDPASigning(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Arc Bank"}).Error)
+	a := pendingDPA(t, db, dpaRepo, tenantID)
+
+	_, _, err := svc.Start(tenantID, a.ID, userID, StartSigningInput{Signers: signersFor().Signers[:1]})
+	assert.ErrorIs(t, err, ErrInvalidDPASigning)
+	_, _, err = svc.Start(uuid.New(), a.ID, userID, signersFor())
+	assert.ErrorIs(t, err, ErrDPANotFound)
+
+	req, tokens, err := svc.Start(tenantID, a.ID, userID, signersFor())
+	require.NoError(t, err)
+	assert.Contains(t, mail.last["sam@acme.example"], "/dpa-signing?token=")
+	_, _, err = svc.Start(tenantID, a.ID, userID, signersFor())
+	assert.ErrorIs(t, err, ErrDPASigningInProgress)
+
+	_, err = svc.Authenticate("not-a-token")
+	assert.ErrorIs(t, err, ErrDPASigningTokenInvalid)
+	tenantSess := signerSession(t, svc, tokens, req, models.DPASignerTenant)
+	vendorSess := signerSession(t, svc, tokens, req, models.DPASignerVendor)
+
+	view, err := svc.Open(tenantSess, "10.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.Equal(t, "Arc Bank", view.Fiduciary)
+	assert.Equal(t, "Acme Cloud", view.Vendor)
+	doc, err := svc.Document(tenantSess)
+	require.NoError(t, err)
+	assert.Equal(t, view.DocumentSHA256, sha256Hex(doc))
+
+	// A passcode is required, and must be the one just sent
+	sign := SignInput{AdoptedSignature: "Asha Rao", DocumentSHA256: view.DocumentSHA256, Consent: true}
+	_, err = svc.Sign(tenantSess, sign, "10.0.0.1", "test-agent")
+	assert.ErrorIs(t, err, ErrSigningOTPInvalid)
+	_, err = svc.SendOTP(tenantSess, "10.0.0.1", "test-agent")
+	require.NoError(t, err)
+	_, err = svc.SendOTP(tenantSess, "10.0.0.1", "test-agent")
+	assert.ErrorIs(t, err, ErrSigningOTPTooSoon)
+	code := mail.passcode(t, "asha@arc.example")
+
+	sign.Passcode = code
+	_, err = svc.Sign(tenantSess, SignInput{AdoptedSignature: "Asha Rao", DocumentSHA256: strings.Repeat("0", 64), Consent: true, Passcode: code}, "10.0.0.1", "test-agent")
+	assert.ErrorIs(t, err, ErrDPASigningDocChanged)
+	sign.Passcode = "000000"
+	if code == "000000" {
+		sign.Passcode = "111111"
+	}
+	_, err = svc.Sign(tenantSess, sign, "10.0.0.1", "test-agent")
+	assert.ErrorIs(t, err, ErrSigningOTPInvalid)
+	sign.Passcode = code
+	got, err := svc.Sign(tenantSess, sign, "10.0.0.1", "test-agent")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.Status)
+	stored, err := dpaRepo.GetDPAByID(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", stored.Status)
+
+	_, err = svc.Sign(tenantSess, sign, "10.0.0.1", "test-agent")
+	assert.ErrorIs(t, err, ErrDPASignerAlreadySigned)
+
+	// The last signature seals the agreement and activates it
+	_, err = svc.SendOTP(vendorSess, "10.0.0.2", "vendor-agent")
+	require.NoError(t, err)
+	got, err = svc.Sign(vendorSess, SignInput{AdoptedSignature: "S. Lee", DocumentSHA256: view.DocumentSHA256, Consent: true,
+		Passcode: mail.passcode(t, "sam@acme.example")}, "10.0.0.2", "vendor-agent")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", got.Status)
+	assert.NotEmpty(t, got.SealSignature)
+	assert.Equal(t, 1, mail.attached["asha@arc.example"])
+
+	stored, err = dpaRepo.GetDPAByID(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", stored.Status)
+	require.NotNil(t, stored.SignedDate)
+	require.NotNil(t, stored.Signature)
+	assert.Equal(t, got.SealSignature, *stored.Signature)
+	assert.Equal(t, "Asha Rao", stored.SignatoryName)
+
+	sealed, err := svc.Sealed(got)
+	require.NoError(t, err)
+	assert.Equal(t, got.SealedSHA256, sha256Hex(sealed))
+	assert.True(t, svc.signer.Verify(sealed, got.SealSignature))
+
+	events, err := svc.Events(tenantID, req.ID)
+	require.NoError(t, err)
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, e.Event)
+	}
+	assert.Equal(t, []string{"created", "link_sent", "link_sent", "viewed", "otp_sent", "otp_failed", "signed", "otp_sent", "signed", "sealed"}, kinds)
+	for _, sg := range got.Signers {
+		assert.Equal(t, view.DocumentSHA256, sg.SignedSHA256)
+		assert.NotEmpty(t, sg.SignedIP)
+	}
+}
+
+func TestDPASigning_PasscodeLocksAfterFailedAttempts(t *testing.T) {
+	svc, dpaRepo, mail, db := setupDPASigning(t)
+	tenantID := uuid.New()
+	a := pendingDPA(t, db, dpaRepo, tenantID)
+	req, tokens, err := svc.Start(tenantID, a.ID, uuid.New(), signersFor())
+	require.NoError(t, err)
+	sess := signerSession(t, svc, tokens, req, models.DPASignerVendor)
+	_, err = svc.SendOTP(sess, "", "")
+	require.NoError(t, err)
+	code := mail.passcode(t, "sam@acme.example")
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	in := SignInput{AdoptedSignature: "Sam Lee", DocumentSHA256: req.DocumentSHA256, Consent: true, Passcode: wrong}
+	for i := 0; i < dpaSigningOTPAttempts; i++ {
+		_, err = svc.Sign(sess, in, "", "")
+		assert.ErrorIs(t, err, ErrSigningOTPInvalid)
+	}
+	in.Passcode = code
+	_, err = svc.Sign(sess, in, "", "")
+	assert.ErrorIs(t, err, ErrSigningOTPLocked)
+}
+
+func TestDPASigning_ChangedTermsBlockSeal(t *testing.T) {
+	svc, dpaRepo, mail, db := setupDPASigning(t)
+	tenantID := uuid.New()
+	a := pendingDPA(t, db, dpaRepo, tenantID)
+	req, tokens, err := svc.Start(tenantID, a.ID, uuid.New(), signersFor())
+	require.NoError(t, err)
+
+	// The terms are edited after the document went out
+	a.LiabilityCap = "INR 10 lakh"
+	require.NoError(t, dpaRepo.UpdateDPA(a))
+
+	for _, party := range []string{models.DPASignerTenant, models.DPASignerVendor} {
+		sess := signerSession(t, svc, tokens, req, party)
+		_, err = svc.SendOTP(sess, "", "")
+		require.NoError(t, err)
+		_, err = svc.Sign(sess, SignInput{AdoptedSignature: sess.Signer.Name, DocumentSHA256: req.DocumentSHA256, Consent: true,
+			Passcode: mail.passcode(t, sess.Signer.Email)}, "", "")
+		require.NoError(t, err)
+	}
+
+	got, err := svc.Get(tenantID, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.Status)
+	_, err = svc.Seal(tenantID, req.ID)
+	assert.ErrorIs(t, err, ErrDPASigningDocChanged)
+	stored, err := dpaRepo.GetDPAByID(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", stored.Status)
+
+	_, err = svc.Cancel(tenantID, req.ID)
+	require.NoError(t, err)
+	for _, token := range tokens {
+		_, err = svc.Authenticate(token)
+		assert.ErrorIs(t, err, ErrDPASigningTokenInvalid)
+	}
+	_, _, err = svc.Start(tenantID, a.ID, uuid.New(), signersFor())
+	require.NoError(t, err)
+}
+
+func TestDPASigning_SealRetriesAfterFailure(t *testing.T) {
+	svc, dpaRepo, mail, db := setupDPASigning(t)
+	tenantID := uuid.New()
+	a := pendingDPA(t, db, dpaRepo, tenantID)
+	req, tokens, err := svc.Start(tenantID, a.ID, uuid.New(), signersFor())
+	require.NoError(t, err)
+
+	// Activating the agreement fails once, after the sealed copy is stored
+	failures := 0
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_dpa_update", func(tx *gorm.DB) {
+		if tx.Statement.Table == "encrypted_data_processing_agreements" && failures == 0 {
+			failures++
+			tx.AddError(errors.New("connection reset"))
+		}
+	}))
+
+	for _, party := range []string{models.DPASignerTenant, models.DPASignerVendor} {
+		sess := signerSession(t, svc, tokens, req, party)
+		_, err = svc.SendOTP(sess, "", "")
+		require.NoError(t, err)
+		_, err = svc.Sign(sess, SignInput{AdoptedSignature: sess.Signer.Name, DocumentSHA256: req.DocumentSHA256, Consent: true,
+			Passcode: mail.passcode(t, sess.Signer.Email)}, "", "")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, failures)
+	got, err := svc.Get(tenantID, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.Status)
+
+	got, err = svc.Seal(tenantID, req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", got.Status)
+	sealed, err := svc.Sealed(got)
+	require.NoError(t, err)
+	assert.Equal(t, got.SealedSHA256, sha256Hex(sealed))
+	stored, err := dpaRepo.GetDPAByID(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", stored.Status)
+}
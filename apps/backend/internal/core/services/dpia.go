@@ -0,0 +1,1163 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/dpia"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/questionnaire"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDPIANotFound           = errors.New("DPIA not found")
+	ErrDPIATemplateNotFound   = errors.New("DPIA template not found")
+	ErrDPIARiskNotFound       = errors.New("DPIA risk not found")
+	ErrDPIAMitigationNotFound = errors.New("DPIA mitigation not found")
+	ErrInvalidDPIA            = errors.New("invalid DPIA")
+	ErrDPIANotEditable        = errors.New("a DPIA can only be changed while in draft or returned for changes")
+	ErrDPIAWrongStatus        = errors.New("the DPIA's status does not allow this")
+	ErrDPIASelfApproval       = errors.New("a DPIA cannot be approved by the person who submitted it")
+	ErrUnsupportedDPIAFormat  = errors.New("unsupported DPIA export format")
+)
+
+// DPIAIncompleteError lists what must be done before a DPIA can be submitted
+type DPIAIncompleteError struct {
+	Issues []string
+}
+
+func (e *DPIAIncompleteError) Error() string {
+	return "DPIA cannot be submitted: " + strings.Join(e.Issues, "; ")
+}
+
+// dpiaEditable are the statuses in which answers, risks and scope can change
+var dpiaEditable = []string{models.DPIADraft, models.DPIAChangesRequested}
+
+// DPIAScope links a DPIA or mitigation to purposes, data sources and vendors
+type DPIAScope struct {
+	PurposeIDs []uuid.UUID `json:"purposeIds"`
+	SystemIDs  []uuid.UUID `json:"systemIds"`
+	VendorIDs  []uuid.UUID `json:"vendorIds"`
+}
+
+// DPIAInput creates or edits a DPIA. The template and review interval are
+// only read on creation; a zero review interval takes the template's.
+type DPIAInput struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	TemplateID   *uuid.UUID `json:"templateId,omitempty"`
+	ReviewMonths int        `json:"reviewMonths"`
+	DPIAScope
+}
+
+// DPIAAnswer is the answer to one template question
+type DPIAAnswer struct {
+	Value   string `json:"value"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// DPIARiskInput adds or rates a risk. Residual ratings are given once the
+// risk's mitigations are known.
+type DPIARiskInput struct {
+	Title              string `json:"title"`
+	Category           string `json:"category"`
+	Description        string `json:"description"`
+	Likelihood         int    `json:"likelihood"`
+	Impact             int    `json:"impact"`
+	ResidualLikelihood *int   `json:"residualLikelihood,omitempty"`
+	ResidualImpact     *int   `json:"residualImpact,omitempty"`
+	Accepted           bool   `json:"accepted"`
+}
+
+// DPIAMitigationInput adds or updates a mitigation
+type DPIAMitigationInput struct {
+	RiskID      uuid.UUID  `json:"riskId"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	DueDate     *time.Time `json:"dueDate,omitempty"`
+	Status      string     `json:"status"`
+	DPIAScope
+}
+
+// DPIALink is a purpose, system or vendor in scope, by name
+type DPIALink struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// DPIAScopeLinks names what a DPIA or mitigation is linked to
+type DPIAScopeLinks struct {
+	Purposes []DPIALink `json:"purposes"`
+	Systems  []DPIALink `json:"systems"`
+	Vendors  []DPIALink `json:"vendors"`
+}
+
+// DPIAMitigationView is a mitigation with what it is linked to
+type DPIAMitigationView struct {
+	models.DPIAMitigation
+	Links   DPIAScopeLinks `json:"links"`
+	Overdue bool           `json:"overdue"`
+}
+
+// DPIAAnswersResult is the score of a DPIA's answers and any risks they
+// raised
+type DPIAAnswersResult struct {
+	Result *questionnaire.Result `json:"result"`
+	Raised []models.DPIARisk     `json:"raised"`
+}
+
+// DPIADetail is a DPIA with everything needed to work on or audit it
+type DPIADetail struct {
+	DPIA        *models.DPIA          `json:"dpia"`
+	Template    dpia.Template         `json:"template"`
+	Answers     map[string]DPIAAnswer `json:"answers"`
+	Result      *questionnaire.Result `json:"result"`
+	Scope       DPIAScopeLinks        `json:"scope"`
+	Risks       []models.DPIARisk     `json:"risks"`
+	Mitigations []DPIAMitigationView  `json:"mitigations"`
+	Events      []models.DPIAEvent    `json:"events"`
+	// Issues are what must be resolved before the DPIA can be submitted
+	Issues []string `json:"issues"`
+	// People maps the user IDs on the DPIA and its events to names
+	People map[uuid.UUID]string `json:"people"`
+}
+
+// DPIAService runs Data Protection Impact Assessments: a template's
+// questions are answered, poor answers raise likelihood × impact rated
+// risks, and mitigations are tracked against them. A submitted DPIA is
+// signed off by a DPO other than the submitter and must be reassessed once
+// its review date passes. Purposes live in the tenant schema, so callers
+// load and pass them in.
+type DPIAService struct {
+	repo          *repository.DPIARepository
+	flowRepo      *repository.DataFlowRepository
+	vendorRepo    repository.VendorRepository
+	notifications *NotificationService
+}
+
+func NewDPIAService(
+	repo *repository.DPIARepository,
+	flowRepo *repository.DataFlowRepository,
+	vendorRepo repository.VendorRepository,
+	notifications *NotificationService,
+) *DPIAService {
+	return &DPIAService{repo: repo, flowRepo: flowRepo, vendorRepo: vendorRepo, notifications: notifications}
+}
+
+func dpiaJSON(v interface{}) datatypes.JSON {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func dpiaIDs(raw datatypes.JSON) []uuid.UUID {
+	ids := []uuid.UUID{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &ids)
+	}
+	return ids
+}
+
+// dedupeIDs drops repeated IDs, keeping the first occurrence
+func dedupeIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	out := []uuid.UUID{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// checkScope makes sure every linked purpose, data source and vendor exists
+func (s *DPIAService) checkScope(tenantID uuid.UUID, purposes []models.Purpose, scope *DPIAScope) error {
+	scope.PurposeIDs = dedupeIDs(scope.PurposeIDs)
+	scope.SystemIDs = dedupeIDs(scope.SystemIDs)
+	scope.VendorIDs = dedupeIDs(scope.VendorIDs)
+	for _, id := range scope.PurposeIDs {
+		if _, ok := findPurpose(purposes, id); !ok {
+			return fmt.Errorf("%w: unknown purpose %s", ErrInvalidDPIA, id)
+		}
+	}
+	for _, id := range scope.SystemIDs {
+		if _, err := s.flowRepo.GetDataSource(tenantID, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown data source %s", ErrInvalidDPIA, id)
+			}
+			return err
+		}
+	}
+	for _, id := range scope.VendorIDs {
+		if _, err := s.vendorRepo.GetByID(id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: unknown vendor %s", ErrInvalidDPIA, id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// links names the purposes, data sources and vendors behind the IDs
+func (s *DPIAService) links(tenantID uuid.UUID, purposes []models.Purpose, purposeIDs, systemIDs, vendorIDs []uuid.UUID) DPIAScopeLinks {
+	out := DPIAScopeLinks{Purposes: []DPIALink{}, Systems: []DPIALink{}, Vendors: []DPIALink{}}
+	for _, id := range purposeIDs {
+		name := "(deleted purpose)"
+		if p, ok := findPurpose(purposes, id); ok {
+			name = p.Name
+		}
+		out.Purposes = append(out.Purposes, DPIALink{ID: id, Name: name})
+	}
+	for _, id := range systemIDs {
+		name := "(deleted data source)"
+		if ds, err := s.flowRepo.GetDataSource(tenantID, id); err == nil {
+			name = ds.Name
+		}
+		out.Systems = append(out.Systems, DPIALink{ID: id, Name: name})
+	}
+	for _, id := range vendorIDs {
+		name := "(deleted vendor)"
+		if v, err := s.vendorRepo.GetByID(id); err == nil {
+			name = v.Company
+		}
+		out.Vendors = append(out.Vendors, DPIALink{ID: id, Name: name})
+	}
+	return out
+}
+
+// DefaultTemplate is the built-in DPDP Section 10 template
+func (s *DPIAService) DefaultTemplate() dpia.Template {
+	return dpia.Default()
+}
+
+func (s *DPIAService) ListTemplates(tenantID uuid.UUID) ([]models.DPIATemplate, error) {
+	return s.repo.ListTemplates(tenantID)
+}
+
+func (s *DPIAService) GetTemplate(tenantID, id uuid.UUID) (*models.DPIATemplate, error) {
+	t, err := s.repo.GetTemplate(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDPIATemplateNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// SaveTemplate creates a template, or replaces one when id is given. DPIAs
+// already started keep the copy they were created with.
+func (s *DPIAService) SaveTemplate(tenantID, userID uuid.UUID, id *uuid.UUID, name, description string, content dpia.Template) (*models.DPIATemplate, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: a template needs a name", ErrInvalidDPIA)
+	}
+	content.Normalize()
+	if issues := content.Validate(); len(issues) > 0 {
+		return nil, &QuestionnaireInvalidError{Issues: issues}
+	}
+	if id == nil {
+		t := &models.DPIATemplate{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			Name:        name,
+			Description: strings.TrimSpace(description),
+			Content:     dpiaJSON(content),
+			CreatedBy:   userID,
+		}
+		return t, s.repo.CreateTemplate(t)
+	}
+	t, err := s.GetTemplate(tenantID, *id)
+	if err != nil {
+		return nil, err
+	}
+	t.Name, t.Description, t.Content = name, strings.TrimSpace(description), dpiaJSON(content)
+	return t, s.repo.UpdateTemplate(t)
+}
+
+func (s *DPIAService) get(tenantID, id uuid.UUID) (*models.DPIA, error) {
+	d, err := s.repo.Get(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDPIANotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// editable loads a DPIA that can still be changed
+func (s *DPIAService) editable(tenantID, id uuid.UUID) (*models.DPIA, error) {
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.DPIADraft && d.Status != models.DPIAChangesRequested {
+		return nil, ErrDPIANotEditable
+	}
+	return d, nil
+}
+
+func dpiaTemplate(d *models.DPIA) (dpia.Template, error) {
+	var t dpia.Template
+	err := json.Unmarshal(d.Template, &t)
+	return t, err
+}
+
+func dpiaAnswers(d *models.DPIA) map[string]DPIAAnswer {
+	answers := map[string]DPIAAnswer{}
+	if len(d.Answers) > 0 {
+		_ = json.Unmarshal(d.Answers, &answers)
+	}
+	return answers
+}
+
+func questionnaireAnswers(answers map[string]DPIAAnswer) map[string]questionnaire.Answer {
+	out := make(map[string]questionnaire.Answer, len(answers))
+	for id, a := range answers {
+		out[id] = questionnaire.Answer{Value: a.Value}
+	}
+	return out
+}
+
+func (s *DPIAService) event(d *models.DPIA, action, status string, actorID *uuid.UUID, note string) *models.DPIAEvent {
+	return &models.DPIAEvent{
+		ID:       uuid.New(),
+		TenantID: d.TenantID,
+		DPIAID:   d.ID,
+		Action:   action,
+		Status:   status,
+		Cycle:    d.Cycle,
+		ActorID:  actorID,
+		Note:     note,
+	}
+}
+
+// Create starts a DPIA on a tenant template, or the built-in one when no
+// template is given
+func (s *DPIAService) Create(tenantID, userID uuid.UUID, purposes []models.Purpose, in DPIAInput) (*models.DPIA, error) {
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return nil, fmt.Errorf("%w: a DPIA needs a title", ErrInvalidDPIA)
+	}
+	if in.ReviewMonths < 0 || in.ReviewMonths > 60 {
+		return nil, fmt.Errorf("%w: review interval must be between 1 and 60 months", ErrInvalidDPIA)
+	}
+	if err := s.checkScope(tenantID, purposes, &in.DPIAScope); err != nil {
+		return nil, err
+	}
+	tmpl, name := dpia.Default(), "DPDP Section 10 DPIA (built-in)"
+	if in.TemplateID != nil {
+		t, err := s.GetTemplate(tenantID, *in.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(t.Content, &tmpl); err != nil {
+			return nil, err
+		}
+		name = t.Name
+	}
+	review := tmpl.ReviewMonths
+	if in.ReviewMonths > 0 {
+		review = in.ReviewMonths
+	}
+	d := &models.DPIA{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Title:        in.Title,
+		Description:  strings.TrimSpace(in.Description),
+		TemplateID:   in.TemplateID,
+		TemplateName: name,
+		Template:     dpiaJSON(tmpl),
+		Answers:      dpiaJSON(map[string]DPIAAnswer{}),
+		PurposeIDs:   dpiaJSON(in.PurposeIDs),
+		SystemIDs:    dpiaJSON(in.SystemIDs),
+		VendorIDs:    dpiaJSON(in.VendorIDs),
+		Status:       models.DPIADraft,
+		Cycle:        1,
+		ReviewMonths: review,
+		CreatedBy:    userID,
+	}
+	d.QuestionnaireRisk = tmpl.Questionnaire.Score(nil).RiskScore
+	if err := s.repo.Create(d, s.event(d, "created", models.DPIADraft, &userID, "")); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DPIAService) List(tenantID uuid.UUID, status string) ([]models.DPIA, error) {
+	return s.repo.List(tenantID, status)
+}
+
+// Update edits a DPIA's title, description, review interval and scope
+func (s *DPIAService) Update(tenantID, id uuid.UUID, purposes []models.Purpose, in DPIAInput) (*models.DPIA, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return nil, fmt.Errorf("%w: a DPIA needs a title", ErrInvalidDPIA)
+	}
+	if in.ReviewMonths < 0 || in.ReviewMonths > 60 {
+		return nil, fmt.Errorf("%w: review interval must be between 1 and 60 months", ErrInvalidDPIA)
+	}
+	if err := s.checkScope(tenantID, purposes, &in.DPIAScope); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{
+		"title":       in.Title,
+		"description": strings.TrimSpace(in.Description),
+		"purpose_ids": dpiaJSON(in.PurposeIDs),
+		"system_ids":  dpiaJSON(in.SystemIDs),
+		"vendor_ids":  dpiaJSON(in.VendorIDs),
+	}
+	if in.ReviewMonths > 0 {
+		fields["review_months"] = in.ReviewMonths
+	}
+	if err := s.repo.Update(d.ID, fields); err != nil {
+		return nil, err
+	}
+	return s.get(tenantID, id)
+}
+
+// SaveAnswers merges answers into the DPIA; a blank value clears an answer.
+// Each template risk whose question is now answered poorly is added to the
+// risk register, once.
+func (s *DPIAService) SaveAnswers(tenantID, id uuid.UUID, answers map[string]DPIAAnswer) (*DPIAAnswersResult, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := dpiaTemplate(d)
+	if err != nil {
+		return nil, err
+	}
+	current := dpiaAnswers(d)
+	issues := map[string]string{}
+	for qid, a := range answers {
+		q, ok := tmpl.Questionnaire.Question(qid)
+		if !ok {
+			issues[qid] = "no such question in this DPIA's template"
+			continue
+		}
+		a.Value, a.Comment = strings.TrimSpace(a.Value), strings.TrimSpace(a.Comment)
+		if a.Value == "" {
+			delete(current, qid)
+			continue
+		}
+		if msg := q.CheckAnswer(questionnaire.Answer{Value: a.Value}); msg != "" {
+			issues[qid] = msg
+			continue
+		}
+		current[qid] = a
+	}
+	if len(issues) > 0 {
+		return nil, &AnswersRejectedError{Issues: issues}
+	}
+
+	qa := questionnaireAnswers(current)
+	result := tmpl.Questionnaire.Score(qa)
+	if err := s.repo.Update(d.ID, map[string]interface{}{
+		"answers": dpiaJSON(current), "questionnaire_risk": result.RiskScore,
+	}); err != nil {
+		return nil, err
+	}
+
+	risks, err := s.repo.ListRisks(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	raisedBy := map[string]bool{}
+	for _, r := range risks {
+		if r.QuestionID != "" {
+			raisedBy[r.QuestionID] = true
+		}
+	}
+	out := &DPIAAnswersResult{Result: result, Raised: []models.DPIARisk{}}
+	for _, rule := range tmpl.RaisedRisks(qa, result) {
+		if raisedBy[rule.QuestionID] {
+			continue
+		}
+		raisedBy[rule.QuestionID] = true
+		score := dpia.Score(rule.Likelihood, rule.Impact)
+		risk := models.DPIARisk{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			DPIAID:      d.ID,
+			Title:       rule.Title,
+			Category:    rule.Category,
+			Description: rule.Description,
+			QuestionID:  rule.QuestionID,
+			Likelihood:  rule.Likelihood,
+			Impact:      rule.Impact,
+			Score:       score,
+			Level:       dpia.Level(score),
+		}
+		if err := s.repo.CreateRisk(&risk); err != nil {
+			return nil, err
+		}
+		out.Raised = append(out.Raised, risk)
+	}
+	if len(out.Raised) > 0 {
+		if err := s.refreshRiskLevel(d.ID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// applyRisk validates a risk's ratings and computes its scores
+func applyRisk(risk *models.DPIARisk, in DPIARiskInput) error {
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return fmt.Errorf("%w: a risk needs a title", ErrInvalidDPIA)
+	}
+	if !dpia.ValidRating(in.Likelihood) || !dpia.ValidRating(in.Impact) {
+		return fmt.Errorf("%w: likelihood and impact must be from %d to %d", ErrInvalidDPIA, dpia.MinRating, dpia.MaxRating)
+	}
+	if (in.ResidualLikelihood == nil) != (in.ResidualImpact == nil) {
+		return fmt.Errorf("%w: give both residual likelihood and impact, or neither", ErrInvalidDPIA)
+	}
+	risk.Title = in.Title
+	risk.Category = strings.TrimSpace(in.Category)
+	risk.Description = strings.TrimSpace(in.Description)
+	risk.Likelihood, risk.Impact = in.Likelihood, in.Impact
+	risk.Score = dpia.Score(in.Likelihood, in.Impact)
+	risk.Level = dpia.Level(risk.Score)
+	risk.ResidualLikelihood, risk.ResidualImpact, risk.ResidualScore, risk.ResidualLevel = nil, nil, nil, ""
+	if in.ResidualLikelihood != nil {
+		l, i := *in.ResidualLikelihood, *in.ResidualImpact
+		if !dpia.ValidRating(l) || !dpia.ValidRating(i) {
+			return fmt.Errorf("%w: residual likelihood and impact must be from %d to %d", ErrInvalidDPIA, dpia.MinRating, dpia.MaxRating)
+		}
+		score := dpia.Score(l, i)
+		risk.ResidualLikelihood, risk.ResidualImpact, risk.ResidualScore = &l, &i, &score
+		risk.ResidualLevel = dpia.Level(score)
+	}
+	risk.Accepted = in.Accepted
+	return nil
+}
+
+// currentLevel is a risk's residual level once rated, else its inherent one
+func currentLevel(r models.DPIARisk) string {
+	if r.ResidualLevel != "" {
+		return r.ResidualLevel
+	}
+	return r.Level
+}
+
+// refreshRiskLevel stores the highest current level across the DPIA's risks
+func (s *DPIAService) refreshRiskLevel(dpiaID uuid.UUID) error {
+	risks, err := s.repo.ListRisks(dpiaID)
+	if err != nil {
+		return err
+	}
+	level := ""
+	for _, r := range risks {
+		if l := currentLevel(r); dpia.LevelRank(l) > dpia.LevelRank(level) {
+			level = l
+		}
+	}
+	return s.repo.Update(dpiaID, map[string]interface{}{"risk_level": level})
+}
+
+func (s *DPIAService) AddRisk(tenantID, id uuid.UUID, in DPIARiskInput) (*models.DPIARisk, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	risk := &models.DPIARisk{ID: uuid.New(), TenantID: tenantID, DPIAID: d.ID}
+	if err := applyRisk(risk, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRisk(risk); err != nil {
+		return nil, err
+	}
+	return risk, s.refreshRiskLevel(d.ID)
+}
+
+// UpdateRisk re-rates a risk, including its residual rating
+func (s *DPIAService) UpdateRisk(tenantID, id, riskID uuid.UUID, in DPIARiskInput) (*models.DPIARisk, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	risk, err := s.repo.GetRisk(d.ID, riskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDPIARiskNotFound
+		}
+		return nil, err
+	}
+	if err := applyRisk(risk, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveRisk(risk); err != nil {
+		return nil, err
+	}
+	return risk, s.refreshRiskLevel(d.ID)
+}
+
+// DeleteRisk removes a risk with its mitigations
+func (s *DPIAService) DeleteRisk(tenantID, id, riskID uuid.UUID) error {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteRisk(d.ID, riskID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDPIARiskNotFound
+	}
+	return s.refreshRiskLevel(d.ID)
+}
+
+// applyMitigation validates a mitigation and copies the input onto it
+func (s *DPIAService) applyMitigation(tenantID uuid.UUID, purposes []models.Purpose, m *models.DPIAMitigation, in DPIAMitigationInput) error {
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return fmt.Errorf("%w: a mitigation needs a title", ErrInvalidDPIA)
+	}
+	if in.Status == "" {
+		in.Status = models.MitigationPlanned
+	}
+	switch in.Status {
+	case models.MitigationPlanned, models.MitigationInProgress, models.MitigationImplemented:
+	default:
+		return fmt.Errorf("%w: unknown mitigation status %q", ErrInvalidDPIA, in.Status)
+	}
+	if _, err := s.repo.GetRisk(m.DPIAID, in.RiskID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDPIARiskNotFound
+		}
+		return err
+	}
+	if err := s.checkScope(tenantID, purposes, &in.DPIAScope); err != nil {
+		return err
+	}
+	m.RiskID = in.RiskID
+	m.Title = in.Title
+	m.Description = strings.TrimSpace(in.Description)
+	m.Owner = strings.TrimSpace(in.Owner)
+	m.DueDate = in.DueDate
+	m.PurposeIDs = dpiaJSON(in.PurposeIDs)
+	m.SystemIDs = dpiaJSON(in.SystemIDs)
+	m.VendorIDs = dpiaJSON(in.VendorIDs)
+	if in.Status == models.MitigationImplemented && m.Status != models.MitigationImplemented {
+		now := time.Now().UTC()
+		m.CompletedAt = &now
+	} else if in.Status != models.MitigationImplemented {
+		m.CompletedAt = nil
+	}
+	m.Status = in.Status
+	return nil
+}
+
+func (s *DPIAService) AddMitigation(tenantID, id uuid.UUID, purposes []models.Purpose, in DPIAMitigationInput) (*models.DPIAMitigation, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	m := &models.DPIAMitigation{ID: uuid.New(), TenantID: tenantID, DPIAID: d.ID}
+	if err := s.applyMitigation(tenantID, purposes, m, in); err != nil {
+		return nil, err
+	}
+	return m, s.repo.CreateMitigation(m)
+}
+
+// UpdateMitigation changes a mitigation. Progress is tracked after approval
+// too, so only a DPIA awaiting sign-off is locked.
+func (s *DPIAService) UpdateMitigation(tenantID, id, mitigationID uuid.UUID, purposes []models.Purpose, in DPIAMitigationInput) (*models.DPIAMitigation, error) {
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status == models.DPIASubmitted {
+		return nil, ErrDPIANotEditable
+	}
+	m, err := s.repo.GetMitigation(d.ID, mitigationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDPIAMitigationNotFound
+		}
+		return nil, err
+	}
+	if err := s.applyMitigation(tenantID, purposes, m, in); err != nil {
+		return nil, err
+	}
+	return m, s.repo.SaveMitigation(m)
+}
+
+func (s *DPIAService) DeleteMitigation(tenantID, id, mitigationID uuid.UUID) error {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.DeleteMitigation(d.ID, mitigationID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDPIAMitigationNotFound
+	}
+	return nil
+}
+
+// submissionIssues lists what stops a DPIA from being submitted: required
+// questions left unanswered, and high or critical risks with neither a
+// mitigation nor an explicit acceptance
+func submissionIssues(result *questionnaire.Result, risks []models.DPIARisk, mitigations []models.DPIAMitigation) []string {
+	issues := []string{}
+	if len(result.MissingRequired) > 0 {
+		issues = append(issues, "required questions are unanswered: "+strings.Join(result.MissingRequired, ", "))
+	}
+	mitigated := map[uuid.UUID]bool{}
+	for _, m := range mitigations {
+		mitigated[m.RiskID] = true
+	}
+	for _, r := range risks {
+		if dpia.LevelRank(currentLevel(r)) >= dpia.LevelRank(dpia.LevelHigh) && !mitigated[r.ID] && !r.Accepted {
+			issues = append(issues, fmt.Sprintf("%s risk %q has no mitigation and is not accepted", currentLevel(r), r.Title))
+		}
+	}
+	return issues
+}
+
+// Get returns a DPIA with its answers, score, risks, mitigations and history
+func (s *DPIAService) Get(tenantID, id uuid.UUID, purposes []models.Purpose) (*DPIADetail, error) {
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := dpiaTemplate(d)
+	if err != nil {
+		return nil, err
+	}
+	answers := dpiaAnswers(d)
+	risks, err := s.repo.ListRisks(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	mitigations, err := s.repo.ListMitigations(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.ListEvents(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := tmpl.Questionnaire.Score(questionnaireAnswers(answers))
+	out := &DPIADetail{
+		DPIA:        d,
+		Template:    tmpl,
+		Answers:     answers,
+		Result:      result,
+		Scope:       s.links(tenantID, purposes, dpiaIDs(d.PurposeIDs), dpiaIDs(d.SystemIDs), dpiaIDs(d.VendorIDs)),
+		Risks:       risks,
+		Mitigations: make([]DPIAMitigationView, 0, len(mitigations)),
+		Events:      events,
+		Issues:      submissionIssues(result, risks, mitigations),
+	}
+	now := time.Now()
+	for _, m := range mitigations {
+		out.Mitigations = append(out.Mitigations, DPIAMitigationView{
+			DPIAMitigation: m,
+			Links:          s.links(tenantID, purposes, dpiaIDs(m.PurposeIDs), dpiaIDs(m.SystemIDs), dpiaIDs(m.VendorIDs)),
+			Overdue:        m.Status != models.MitigationImplemented && m.DueDate != nil && m.DueDate.Before(now),
+		})
+	}
+
+	people := []uuid.UUID{d.CreatedBy}
+	for _, p := range []*uuid.UUID{d.SubmittedBy, d.ApprovedBy} {
+		if p != nil {
+			people = append(people, *p)
+		}
+	}
+	for _, e := range events {
+		if e.ActorID != nil {
+			people = append(people, *e.ActorID)
+		}
+	}
+	if out.People, err = s.repo.UserNames(tenantID, dedupeIDs(people)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Submit sends a complete DPIA to the DPO for sign-off
+func (s *DPIAService) Submit(tenantID, id, userID uuid.UUID) (*models.DPIA, error) {
+	d, err := s.editable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := dpiaTemplate(d)
+	if err != nil {
+		return nil, err
+	}
+	risks, err := s.repo.ListRisks(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	mitigations, err := s.repo.ListMitigations(d.ID)
+	if err != nil {
+		return nil, err
+	}
+	if issues := submissionIssues(tmpl.Questionnaire.Score(questionnaireAnswers(dpiaAnswers(d))), risks, mitigations); len(issues) > 0 {
+		return nil, &DPIAIncompleteError{Issues: issues}
+	}
+	now := time.Now().UTC()
+	ok, err := s.repo.Transition(d.ID, dpiaEditable, map[string]interface{}{
+		"status": models.DPIASubmitted, "submitted_by": userID, "submitted_at": now,
+	}, s.event(d, "submitted", models.DPIASubmitted, &userID, ""))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDPIANotEditable
+	}
+	return s.get(tenantID, id)
+}
+
+// Approve records the DPO's sign-off and sets the date the DPIA must be
+// reviewed by. The submitter cannot approve their own DPIA.
+func (s *DPIAService) Approve(tenantID, id, userID uuid.UUID, note string) (*models.DPIA, error) {
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.DPIASubmitted {
+		return nil, ErrDPIAWrongStatus
+	}
+	if d.SubmittedBy != nil && *d.SubmittedBy == userID {
+		return nil, ErrDPIASelfApproval
+	}
+	now := time.Now().UTC()
+	note = strings.TrimSpace(note)
+	ok, err := s.repo.Transition(d.ID, []string{models.DPIASubmitted}, map[string]interface{}{
+		"status": models.DPIAApproved, "approved_by": userID, "approved_at": now, "decision_note": note,
+		"next_review_at": now.AddDate(0, d.ReviewMonths, 0), "review_notified_at": nil,
+	}, s.event(d, "approved", models.DPIAApproved, &userID, note))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDPIAWrongStatus
+	}
+	return s.get(tenantID, id)
+}
+
+// RequestChanges returns a submitted DPIA to its authors with the DPO's note
+func (s *DPIAService) RequestChanges(tenantID, id, userID uuid.UUID, note string) (*models.DPIA, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: say what needs to change", ErrInvalidDPIA)
+	}
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Transition(d.ID, []string{models.DPIASubmitted}, map[string]interface{}{
+		"status": models.DPIAChangesRequested, "decision_note": note,
+	}, s.event(d, "changes_requested", models.DPIAChangesRequested, &userID, note))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDPIAWrongStatus
+	}
+	return s.get(tenantID, id)
+}
+
+// Reassess reopens an approved DPIA, or one due for review, as a draft for
+// its next cycle. Answers, risks and mitigations carry over to be revisited.
+func (s *DPIAService) Reassess(tenantID, id, userID uuid.UUID, note string) (*models.DPIA, error) {
+	d, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	d.Cycle++
+	note = strings.TrimSpace(note)
+	ok, err := s.repo.Transition(d.ID, []string{models.DPIAApproved, models.DPIAReviewDue}, map[string]interface{}{
+		"status": models.DPIADraft, "cycle": d.Cycle,
+		"submitted_by": nil, "submitted_at": nil, "approved_by": nil, "approved_at": nil, "decision_note": "",
+	}, s.event(d, "reassessment_started", models.DPIADraft, &userID, note))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrDPIAWrongStatus
+	}
+	return s.get(tenantID, id)
+}
+
+func (s *DPIAService) RegisterJobs(r *JobRunner) {
+	r.Register("dpia.reviews", func(ctx context.Context, job *models.Job) error {
+		_, err := s.ProcessReviews(time.Now())
+		return err
+	})
+	r.Schedule("dpia.reviews", "30 6 * * *", "dpia.reviews", nil)
+}
+
+// ProcessReviews marks approved DPIAs whose review date has passed as due for
+// reassessment and tells the tenant's DPIA approvers. It returns how many
+// were marked.
+func (s *DPIAService) ProcessReviews(now time.Time) (int, error) {
+	due, err := s.repo.ListReviewsDue(now)
+	if err != nil {
+		return 0, err
+	}
+	marked := 0
+	for i := range due {
+		d := &due[i]
+		ok, err := s.repo.Transition(d.ID, []string{models.DPIAApproved}, map[string]interface{}{
+			"status": models.DPIAReviewDue, "review_notified_at": now,
+		}, s.event(d, "review_due", models.DPIAReviewDue, nil, "review date "+d.NextReviewAt.Format("2006-01-02")+" passed"))
+		if err != nil {
+			return marked, err
+		}
+		if ok {
+			marked++
+			s.notifyReviewDue(d)
+		}
+	}
+	return marked, nil
+}
+
+func (s *DPIAService) notifyReviewDue(d *models.DPIA) {
+	if s.notifications == nil {
+		return
+	}
+	users, err := s.repo.ListUserIDsWithPermission(d.TenantID, "dpia:approve")
+	if err != nil {
+		log.Logger.Error().Err(err).Str("dpia_id", d.ID.String()).Msg("failed to find users to notify about DPIA review")
+		return
+	}
+	for _, userID := range users {
+		_ = s.notifications.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     "DPIA due for review",
+			Body:      fmt.Sprintf("The DPIA %q reached its review date and must be reassessed.", d.Title),
+			Icon:      "clipboard-check",
+			Link:      "/dpia/" + d.ID.String(),
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// Export renders a DPIA as an audit report in "pdf" or "json". The PDF
+// footer carries the SHA-256 of the JSON report so the two can be matched.
+// It returns the document, its content type and a file name.
+func (s *DPIAService) Export(tenantID, id uuid.UUID, purposes []models.Purpose, format string) ([]byte, string, string, error) {
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "json" {
+		return nil, "", "", fmt.Errorf("%w: %q", ErrUnsupportedDPIAFormat, format)
+	}
+	detail, err := s.Get(tenantID, id, purposes)
+	if err != nil {
+		return nil, "", "", err
+	}
+	report, err := json.MarshalIndent(detail, "", "  ")
+	if err != nil {
+		return nil, "", "", err
+	}
+	name := fmt.Sprintf("dpia-%s-cycle%d", detail.DPIA.ID.String()[:8], detail.DPIA.Cycle)
+	if format == "json" {
+		return report, "application/json", name + ".json", nil
+	}
+	sum := sha256.Sum256(report)
+	doc, err := renderDPIAPDF(detail, hex.EncodeToString(sum[:]))
+	return doc, "application/pdf", name + ".pdf", err
+}
+
+func linkNames(links []DPIALink) string {
+	names := make([]string, 0, len(links))
+	for _, l := range links {
+		names = append(names, l.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func renderDPIAPDF(d *DPIADetail, sha string) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("DPIA %s | cycle %d | %s | report SHA-256 %s", d.DPIA.ID, d.DPIA.Cycle, d.DPIA.Status, sha)), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	person := func(id *uuid.UUID) string {
+		if id == nil {
+			return "system"
+		}
+		if name, ok := d.People[*id]; ok {
+			return name
+		}
+		return id.String()
+	}
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format("02 Jan 2006")
+	}
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.Cell(50, 5, tr(label))
+		pdf.SetFont("Arial", "", 9)
+		pdf.MultiCell(0, 5, tr(value), "", "L", false)
+	}
+	heading := func(text string) {
+		if pdf.GetY() > 250 {
+			pdf.AddPage()
+		}
+		pdf.Ln(3)
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 7, tr(text))
+		pdf.Ln(8)
+	}
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.MultiCell(0, 8, tr("Data Protection Impact Assessment: "+d.DPIA.Title), "", "L", false)
+	pdf.SetFont("Arial", "", 9)
+	pdf.Cell(0, 5, "Generated: "+time.Now().UTC().Format("02 Jan 2006 15:04 UTC"))
+	pdf.Ln(8)
+
+	field("Status", strings.ReplaceAll(d.DPIA.Status, "_", " "))
+	field("Assessment cycle", strconv.Itoa(d.DPIA.Cycle))
+	field("Template", d.DPIA.TemplateName)
+	field("Overall risk level", d.DPIA.RiskLevel)
+	field("Questionnaire score", fmt.Sprintf("%.2f%% (risk score %.2f)", d.Result.Percent, d.Result.RiskScore))
+	field("Prepared by", person(&d.DPIA.CreatedBy))
+	field("Submitted", strings.TrimSpace(person(d.DPIA.SubmittedBy)+" "+date(d.DPIA.SubmittedAt)))
+	if d.DPIA.ApprovedBy != nil {
+		field("Approved by DPO", person(d.DPIA.ApprovedBy)+" "+date(d.DPIA.ApprovedAt))
+	}
+	field("Next review", date(d.DPIA.NextReviewAt))
+	field("Description", d.DPIA.Description)
+
+	heading("Scope")
+	field("Purposes", linkNames(d.Scope.Purposes))
+	field("Systems", linkNames(d.Scope.Systems))
+	field("Vendors", linkNames(d.Scope.Vendors))
+
+	heading("Assessment")
+	for _, sec := range d.Template.Questionnaire.Sections {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(0, 6, tr(sec.Title))
+		pdf.Ln(6)
+		for _, q := range sec.Questions {
+			a, ok := d.Answers[q.ID]
+			if !ok {
+				continue
+			}
+			answer := a.Value
+			for _, o := range q.Options {
+				if o.Value == a.Value && o.Label != "" {
+					answer = o.Label
+				}
+			}
+			if a.Comment != "" {
+				answer += "\n" + a.Comment
+			}
+			pdf.SetFont("Arial", "B", 9)
+			pdf.MultiCell(0, 5, tr(q.Text), "", "L", false)
+			pdf.SetFont("Arial", "", 9)
+			pdf.MultiCell(0, 5, tr(answer), "", "L", false)
+			pdf.Ln(1)
+		}
+	}
+
+	heading("Risk register")
+	mitigationsByRisk := map[uuid.UUID][]DPIAMitigationView{}
+	for _, m := range d.Mitigations {
+		mitigationsByRisk[m.RiskID] = append(mitigationsByRisk[m.RiskID], m)
+	}
+	if len(d.Risks) == 0 {
+		field("Risks", "none identified")
+	}
+	for _, r := range d.Risks {
+		if pdf.GetY() > 240 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Arial", "B", 10)
+		pdf.MultiCell(0, 6, tr(fmt.Sprintf("%s [%s]", r.Title, r.Level)), "", "L", false)
+		field("Category", r.Category)
+		field("Inherent risk", fmt.Sprintf("likelihood %d x impact %d = %d (%s)", r.Likelihood, r.Impact, r.Score, r.Level))
+		residual := ""
+		if r.ResidualScore != nil {
+			residual = fmt.Sprintf("likelihood %d x impact %d = %d (%s)", *r.ResidualLikelihood, *r.ResidualImpact, *r.ResidualScore, r.ResidualLevel)
+		}
+		field("Residual risk", residual)
+		if r.Accepted {
+			field("Acceptance", "accepted")
+		}
+		if r.Description != "" {
+			field("Description", r.Description)
+		}
+		for _, m := range mitigationsByRisk[r.ID] {
+			status := strings.ReplaceAll(m.Status, "_", " ")
+			if m.CompletedAt != nil {
+				status += " " + date(m.CompletedAt)
+			} else if m.Overdue {
+				status += " (overdue)"
+			}
+			var links []string
+			for _, l := range [][]DPIALink{m.Links.Purposes, m.Links.Systems, m.Links.Vendors} {
+				if names := linkNames(l); names != "" {
+					links = append(links, names)
+				}
+			}
+			text := fmt.Sprintf("%s\nOwner: %s | Due: %s | Status: %s", m.Title, m.Owner, date(m.DueDate), status)
+			if len(links) > 0 {
+				text += "\nApplies to: " + strings.Join(links, "; ")
+			}
+			field("Mitigation", text)
+		}
+		pdf.Ln(2)
+	}
+
+	heading("Approval trail")
+	for _, e := range d.Events {
+		text := fmt.Sprintf("%s by %s (cycle %d)", strings.ReplaceAll(e.Action, "_", " "), person(e.ActorID), e.Cycle)
+		if e.Note != "" {
+			text += ": " + e.Note
+		}
+		field(e.CreatedAt.UTC().Format("02 Jan 2006 15:04"), text)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/dpia"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dpiaFixture struct {
+	svc      *DPIAService
+	tenantID uuid.UUID
+	author   uuid.UUID
+	dpo      uuid.UUID
+	purposes []models.Purpose
+	usersDB  uuid.UUID
+	crm      models.Vendor
+}
+
+func setupDPIA(t *testing.T) *dpiaFixture {
+	_, _, _, db := setupTransfers(t)
+	require.NoError(t, db.AutoMigrate(&models.DataSource{}, &models.FiduciaryUser{},
+		&models.DPIATemplate{}, &models.DPIA{}, &models.DPIARisk{}, &models.DPIAMitigation{}, &models.DPIAEvent{}))
+	f := &dpiaFixture{
+		svc:      NewDPIAService(repository.NewDPIARepository(db), repository.NewDataFlowRepository(db), repository.NewVendorRepository(db), nil),
+		tenantID: uuid.New(),
+		author:   uuid.New(),
+		dpo:      uuid.New(),
+		usersDB:  uuid.New(),
+	}
+	require.NoError(t, db.Create(&[]models.FiduciaryUser{
+		{ID: f.author, TenantID: f.tenantID, Name: "Asha", Email: "asha@acme.example", Phone: "1"},
+		{ID: f.dpo, TenantID: f.tenantID, Name: "Dev (DPO)", Email: "dpo@acme.example", Phone: "2"},
+	}).Error)
+	require.NoError(t, db.Create(&models.DataSource{ID: f.usersDB, TenantID: f.tenantID, Name: "users-db", Type: "postgres"}).Error)
+	f.crm = models.Vendor{VendorID: uuid.New(), Company: "CRMCo", Email: "dpo@crm.example"}
+	require.NoError(t, db.Create(&f.crm).Error)
+	f.purposes = []models.Purpose{{ID: uuid.New(), Name: "Credit scoring", Active: true}}
+	return f
+}
+
+func TestDPIALevel(t *testing.T) {
+	for score, want := range map[int]string{1: "low", 4: "low", 5: "medium", 9: "medium", 10: "high", 16: "high", 20: "critical", 25: "critical"} {
+		assert.Equal(t, want, dpia.Level(score), score)
+	}
+	def := dpia.Default()
+	assert.Empty(t, def.Validate())
+	def.Risks = append(def.Risks, dpia.RiskRule{QuestionID: "nature", Title: "Vague", Likelihood: 6, Impact: 1})
+	assert.Len(t, def.Validate(), 2) // Text questions are not scored, and 6 is off the scale
+}
+
+func TestDPIA_Lifecycle(t *testing.T) {
+	f := setupDPIA(t)
+
+	_, err := f.svc.Create(f.tenantID, f.author, f.purposes, DPIAInput{Title: "Credit scoring", DPIAScope: DPIAScope{PurposeIDs: []uuid.UUID{uuid.New()}}})
+	assert.ErrorIs(t, err, ErrInvalidDPIA)
+
+	d, err := f.svc.Create(f.tenantID, f.author, f.purposes, DPIAInput{
+		Title: "Credit scoring",
+		DPIAScope: DPIAScope{
+			PurposeIDs: []uuid.UUID{f.purposes[0].ID, f.purposes[0].ID},
+			SystemIDs:  []uuid.UUID{f.usersDB},
+			VendorIDs:  []uuid.UUID{f.crm.VendorID},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.DPIADraft, d.Status)
+	assert.Equal(t, 12, d.ReviewMonths)
+
+	_, err = f.svc.SaveAnswers(f.tenantID, d.ID, map[string]DPIAAnswer{"encryption": {Value: "maybe"}})
+	var rejected *AnswersRejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Contains(t, rejected.Issues, "encryption")
+
+	answers := map[string]DPIAAnswer{
+		"nature": {Value: "Scoring loan applicants"}, "principals": {Value: "Applicants, about 50,000"},
+		"children": {Value: "no"}, "volume": {Value: "under_10k"},
+		"encryption": {Value: "no", Comment: "Backups are not encrypted yet"},
+	}
+	for _, q := range []string{"minimised", "retention", "notice", "rights", "access", "processors", "transfers", "breach", "automated"} {
+		answers[q] = DPIAAnswer{Value: "yes"}
+	}
+	res, err := f.svc.SaveAnswers(f.tenantID, d.ID, answers)
+	require.NoError(t, err)
+	assert.Empty(t, res.Result.MissingRequired) // Parental consent is not asked
+	require.Len(t, res.Raised, 1)
+	risk := res.Raised[0]
+	assert.Equal(t, "encryption", risk.QuestionID)
+	assert.Equal(t, 15, risk.Score)
+	assert.Equal(t, dpia.LevelHigh, risk.Level)
+
+	res, err = f.svc.SaveAnswers(f.tenantID, d.ID, map[string]DPIAAnswer{"encryption": {Value: "no"}})
+	require.NoError(t, err)
+	assert.Empty(t, res.Raised, "a risk is raised once per question")
+
+	_, err = f.svc.Submit(f.tenantID, d.ID, f.author)
+	var incomplete *DPIAIncompleteError
+	require.ErrorAs(t, err, &incomplete)
+	assert.Len(t, incomplete.Issues, 1)
+
+	due := time.Now().AddDate(0, 0, -1)
+	m, err := f.svc.AddMitigation(f.tenantID, d.ID, f.purposes, DPIAMitigationInput{
+		RiskID: risk.ID, Title: "Encrypt backups", Owner: "Platform team", DueDate: &due,
+		DPIAScope: DPIAScope{SystemIDs: []uuid.UUID{f.usersDB}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.MitigationPlanned, m.Status)
+	one, five := 1, 5
+	risk2, err := f.svc.UpdateRisk(f.tenantID, d.ID, risk.ID, DPIARiskInput{
+		Title: risk.Title, Likelihood: 3, Impact: 5, ResidualLikelihood: &one, ResidualImpact: &five,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dpia.LevelMedium, risk2.ResidualLevel)
+
+	_, err = f.svc.Submit(f.tenantID, d.ID, f.author)
+	require.NoError(t, err)
+	_, err = f.svc.Update(f.tenantID, d.ID, f.purposes, DPIAInput{Title: "Changed"})
+	assert.ErrorIs(t, err, ErrDPIANotEditable)
+
+	_, err = f.svc.Approve(f.tenantID, d.ID, f.author, "")
+	assert.ErrorIs(t, err, ErrDPIASelfApproval)
+	approved, err := f.svc.Approve(f.tenantID, d.ID, f.dpo, "Residual risk acceptable")
+	require.NoError(t, err)
+	assert.Equal(t, models.DPIAApproved, approved.Status)
+	assert.Equal(t, dpia.LevelMedium, approved.RiskLevel)
+	require.NotNil(t, approved.NextReviewAt)
+	assert.WithinDuration(t, time.Now().AddDate(1, 0, 0), *approved.NextReviewAt, time.Minute)
+
+	// Mitigations are still tracked after sign-off
+	m, err = f.svc.UpdateMitigation(f.tenantID, d.ID, m.ID, f.purposes, DPIAMitigationInput{
+		RiskID: risk.ID, Title: "Encrypt backups", Owner: "Platform team", Status: models.MitigationImplemented,
+		DPIAScope: DPIAScope{SystemIDs: []uuid.UUID{f.usersDB}},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, m.CompletedAt)
+
+	n, err := f.svc.ProcessReviews(time.Now().AddDate(0, 13, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = f.svc.ProcessReviews(time.Now().AddDate(0, 13, 0))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	reopened, err := f.svc.Reassess(f.tenantID, d.ID, f.author, "Annual review")
+	require.NoError(t, err)
+	assert.Equal(t, models.DPIADraft, reopened.Status)
+	assert.Equal(t, 2, reopened.Cycle)
+	assert.Nil(t, reopened.ApprovedBy)
+
+	detail, err := f.svc.Get(f.tenantID, d.ID, f.purposes)
+	require.NoError(t, err)
+	var actions []string
+	for _, e := range detail.Events {
+		actions = append(actions, e.Action)
+	}
+	assert.Equal(t, []string{"created", "submitted", "approved", "review_due", "reassessment_started"}, actions)
+	assert.Equal(t, "Credit scoring", detail.Scope.Purposes[0].Name)
+	assert.Len(t, detail.Scope.Purposes, 1)
+	assert.Equal(t, "CRMCo", detail.Scope.Vendors[0].Name)
+	assert.Equal(t, "users-db", detail.Mitigations[0].Links.Systems[0].Name)
+	assert.Equal(t, "Dev (DPO)", detail.People[f.dpo])
+	assert.Empty(t, detail.Issues)
+}
+
+func TestDPIA_ExportAndTemplates(t *testing.T) {
+	f := setupDPIA(t)
+
+	custom := dpia.Default()
+	custom.ReviewMonths = 6
+	tmpl, err := f.svc.SaveTemplate(f.tenantID, f.author, nil, "Lending DPIA", "", custom)
+	require.NoError(t, err)
+	bad := dpia.Default()
+	bad.Risks[0].QuestionID = "missing"
+	_, err = f.svc.SaveTemplate(f.tenantID, f.author, nil, "Broken", "", bad)
+	var invalid *QuestionnaireInvalidError
+	require.ErrorAs(t, err, &invalid)
+
+	d, err := f.svc.Create(f.tenantID, f.author, f.purposes, DPIAInput{Title: "Loan onboarding", TemplateID: &tmpl.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 6, d.ReviewMonths)
+	assert.Equal(t, "Lending DPIA", d.TemplateName)
+	_, err = f.svc.AddRisk(f.tenantID, d.ID, DPIARiskInput{Title: "Profiling bias", Likelihood: 2, Impact: 2})
+	require.NoError(t, err)
+
+	doc, contentType, name, err := f.svc.Export(f.tenantID, d.ID, f.purposes, "pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", contentType)
+	assert.Contains(t, name, "cycle1.pdf")
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF")))
+
+	doc, _, _, err = f.svc.Export(f.tenantID, d.ID, f.purposes, "json")
+	require.NoError(t, err)
+	var report DPIADetail
+	require.NoError(t, json.Unmarshal(doc, &report))
+	assert.Equal(t, "Profiling bias", report.Risks[0].Title)
+
+	_, _, _, err = f.svc.Export(f.tenantID, d.ID, f.purposes, "docx")
+	assert.ErrorIs(t, err, ErrUnsupportedDPIAFormat)
+}
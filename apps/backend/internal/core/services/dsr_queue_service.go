@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+)
+
+var validAssignmentStrategies = map[string]bool{"round_robin": true, "least_loaded": true}
+
+// DSRQueueService routes new requests to fiduciary users, serves the work queue
+// and escalates requests that are close to breaching their due date.
+type DSRQueueService struct {
+	repo                *repository.DSRRoutingRepository
+	notificationService *NotificationService
+}
+
+func NewDSRQueueService(repo *repository.DSRRoutingRepository, notificationService *NotificationService) *DSRQueueService {
+	return &DSRQueueService{
+		repo:                repo,
+		notificationService: notificationService,
+	}
+}
+
+// RegisterJobs schedules the escalation sweep every 15 minutes.
+func (s *DSRQueueService) RegisterJobs(r *JobRunner) {
+	r.Register("dsr.escalate", func(ctx context.Context, job *models.Job) error {
+		n, err := s.EscalateDueRequests(time.Now())
+		if n > 0 {
+			log.Logger.Info().Int("escalated", n).Msg("DSR escalation sweep complete")
+		}
+		return err
+	})
+	r.Schedule("dsr.escalate", "*/15 * * * *", "dsr.escalate", nil)
+}
+
+// Rules
+
+func (s *DSRQueueService) CreateRule(rule *models.DSRRoutingRule) error {
+	if err := validateRoutingRule(rule); err != nil {
+		return err
+	}
+	rule.ID = uuid.New()
+	return s.repo.CreateRule(rule)
+}
+
+func (s *DSRQueueService) UpdateRule(rule *models.DSRRoutingRule) error {
+	if _, err := s.repo.GetRule(rule.ID, rule.TenantID); err != nil {
+		return err
+	}
+	if err := validateRoutingRule(rule); err != nil {
+		return err
+	}
+	return s.repo.UpdateRule(rule)
+}
+
+func (s *DSRQueueService) ListRules(tenantID uuid.UUID) ([]models.DSRRoutingRule, error) {
+	return s.repo.ListRules(tenantID)
+}
+
+func (s *DSRQueueService) DeleteRule(id, tenantID uuid.UUID) error {
+	return s.repo.DeleteRule(id, tenantID)
+}
+
+func validateRoutingRule(rule *models.DSRRoutingRule) error {
+	if rule.Name == "" {
+		return errors.New("rule name is required")
+	}
+	if rule.AssigneeRole == "" {
+		return errors.New("assignee role is required")
+	}
+	if rule.Strategy == "" {
+		rule.Strategy = "round_robin"
+	}
+	if !validAssignmentStrategies[rule.Strategy] {
+		return fmt.Errorf("invalid assignment strategy: %s", rule.Strategy)
+	}
+	if rule.EscalateHoursBeforeDue <= 0 {
+		rule.EscalateHoursBeforeDue = 72
+	}
+	return nil
+}
+
+// Assignment
+
+// AssignRequest applies the first matching routing rule to an unsaved request,
+// setting AssignedTo and RoutingRuleID. A request that matches no rule, or whose
+// rule role has no members, is left unassigned.
+func (s *DSRQueueService) AssignRequest(req *models.DSRRequest) error {
+	if req.AssignedTo != nil {
+		return nil
+	}
+	rules, err := s.repo.ListActiveRules(req.TenantID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(req) {
+			continue
+		}
+		assignee, err := s.pickAssignee(rule)
+		if err != nil {
+			return err
+		}
+		ruleID := rule.ID
+		req.RoutingRuleID = &ruleID
+		if assignee != uuid.Nil {
+			req.AssignedTo = &assignee
+		}
+		return nil
+	}
+	return nil
+}
+
+// NotifyAssignee tells the assigned user about a newly routed request.
+func (s *DSRQueueService) NotifyAssignee(req *models.DSRRequest) {
+	if req.AssignedTo == nil || s.notificationService == nil {
+		return
+	}
+	_ = s.notificationService.Create(context.Background(), &models.Notification{
+		ID:        uuid.New(),
+		UserID:    *req.AssignedTo,
+		Title:     "New data request assigned",
+		Body:      fmt.Sprintf("A %s request has been assigned to you. Due %s.", req.Type, req.DueDate.Format("02 Jan 2006")),
+		Icon:      "inbox",
+		Link:      "/requests/" + req.ID.String(),
+		Unread:    true,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *DSRQueueService) pickAssignee(rule *models.DSRRoutingRule) (uuid.UUID, error) {
+	candidates, err := s.repo.ListUserIDsWithRole(rule.TenantID, rule.AssigneeRole)
+	if err != nil || len(candidates) == 0 {
+		return uuid.Nil, err
+	}
+
+	if rule.Strategy == "least_loaded" {
+		return s.leastLoaded(candidates)
+	}
+
+	// The cursor is advanced with a compare-and-set, so requests routed at
+	// the same time by the same rule take successive turns
+	last := rule.LastAssignedUserID
+	next := nextRoundRobin(candidates, last)
+	for attempt := 0; attempt < roundRobinAttempts; attempt++ {
+		advanced, err := s.repo.AdvanceRoundRobinCursor(rule.ID, last, next)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if advanced {
+			break
+		}
+		if last, err = s.repo.RoundRobinCursor(rule.ID); err != nil {
+			return uuid.Nil, err
+		}
+		next = nextRoundRobin(candidates, last)
+	}
+	rule.LastAssignedUserID = &next
+	return next, nil
+}
+
+// roundRobinAttempts bounds the retries of a round-robin turn lost to a
+// concurrent assignment. After that the request takes the turn after the
+// latest cursor without moving it.
+const roundRobinAttempts = 5
+
+func (s *DSRQueueService) leastLoaded(candidates []uuid.UUID) (uuid.UUID, error) {
+	load, err := s.repo.CountOpenAssignments(candidates)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return leastOf(candidates, load), nil
+}
+
+// leastEscalated picks the supervisor holding the fewest open escalations.
+// Escalation does not reassign a request, so supervisor load is counted on
+// EscalatedTo rather than AssignedTo.
+func (s *DSRQueueService) leastEscalated(supervisors []uuid.UUID) (uuid.UUID, error) {
+	load, err := s.repo.CountOpenEscalations(supervisors)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return leastOf(supervisors, load), nil
+}
+
+// leastOf returns the first candidate with the lowest load.
+func leastOf(candidates []uuid.UUID, load map[uuid.UUID]int64) uuid.UUID {
+	best := candidates[0]
+	for _, id := range candidates[1:] {
+		if load[id] < load[best] {
+			best = id
+		}
+	}
+	return best
+}
+
+// nextRoundRobin returns the candidate after last in the (stably ordered) list,
+// wrapping around. If last is unknown the first candidate is returned.
+func nextRoundRobin(candidates []uuid.UUID, last *uuid.UUID) uuid.UUID {
+	if last != nil {
+		for i, id := range candidates {
+			if id == *last {
+				return candidates[(i+1)%len(candidates)]
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// Queue
+
+// ListQueue returns one page of the queue and the total matching requests.
+// Callers bound the page size.
+func (s *DSRQueueService) ListQueue(filter repository.DSRQueueFilter, limit, offset int) ([]models.DSRRequest, int64, error) {
+	return s.repo.ListQueue(filter, limit, offset)
+}
+
+// BulkReassign moves the given requests to a single assignee in the same tenant.
+func (s *DSRQueueService) BulkReassign(tenantID uuid.UUID, requestIDs []uuid.UUID, assignee uuid.UUID) (int64, error) {
+	if len(requestIDs) == 0 {
+		return 0, errors.New("no requests selected")
+	}
+	if !s.repo.IsTenantUser(assignee, tenantID) {
+		return 0, errors.New("assignee does not belong to this tenant")
+	}
+	n, err := s.repo.BulkAssign(tenantID, requestIDs, assignee)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 && s.notificationService != nil {
+		_ = s.notificationService.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    assignee,
+			Title:     "Data requests reassigned to you",
+			Body:      fmt.Sprintf("%d data request(s) have been reassigned to you.", n),
+			Icon:      "inbox",
+			Link:      "/requests",
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+	return n, nil
+}
+
+// Escalation
+
+// EscalateDueRequests flags open requests that have entered their rule's
+// escalation window and hands them to the supervisor with the fewest open
+// escalations. It returns the number of requests escalated.
+func (s *DSRQueueService) EscalateDueRequests(now time.Time) (int, error) {
+	rules, err := s.repo.ListEscalatingRules()
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range rules {
+		rule := &rules[i]
+		cutoff := now.Add(time.Duration(rule.EscalateHoursBeforeDue) * time.Hour)
+		requests, err := s.repo.ListDueForEscalation(rule.ID, cutoff)
+		if err != nil {
+			return escalated, err
+		}
+		if len(requests) == 0 {
+			continue
+		}
+
+		supervisors, err := s.repo.ListUserIDsWithRole(rule.TenantID, rule.EscalationRole)
+		if err != nil {
+			return escalated, err
+		}
+		if len(supervisors) == 0 {
+			log.Logger.Warn().Str("rule_id", rule.ID.String()).Str("role", rule.EscalationRole).Msg("DSR escalation role has no members")
+			continue
+		}
+
+		for _, req := range requests {
+			supervisor, err := s.leastEscalated(supervisors)
+			if err != nil {
+				return escalated, err
+			}
+			if err := s.repo.MarkEscalated(req.ID, supervisor, now); err != nil {
+				return escalated, err
+			}
+			escalated++
+			s.notifyEscalation(&req, supervisor)
+		}
+	}
+	return escalated, nil
+}
+
+func (s *DSRQueueService) notifyEscalation(req *models.DSRRequest, supervisor uuid.UUID) {
+	if s.notificationService == nil {
+		return
+	}
+	body := fmt.Sprintf("A %s request is due on %s and has been escalated.", req.Type, req.DueDate.Format("02 Jan 2006 15:04"))
+	recipients := []uuid.UUID{supervisor}
+	if req.AssignedTo != nil && *req.AssignedTo != supervisor {
+		recipients = append(recipients, *req.AssignedTo)
+	}
+	for _, userID := range recipients {
+		_ = s.notificationService.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     "Data request nearing deadline",
+			Body:      body,
+			Icon:      "alert-triangle",
+			Link:      "/requests/" + req.ID.String(),
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+}
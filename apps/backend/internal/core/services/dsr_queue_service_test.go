@@ -0,0 +1,160 @@
+package services
+
+import (
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDSRQueue(t *testing.T) (*DSRQueueService, *gorm.DB, uuid.UUID) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Permission{}, &models.Role{}, &models.FiduciaryUser{}, &models.DSRRequest{}, &models.DSRRoutingRule{}))
+	return NewDSRQueueService(repository.NewDSRRoutingRepository(db), nil), db, uuid.New()
+}
+
+func addUsersWithRole(t *testing.T, db *gorm.DB, tenantID uuid.UUID, roleName string, n int) []uuid.UUID {
+	role := &models.Role{ID: uuid.New(), TenantID: tenantID, Name: roleName}
+	require.NoError(t, db.Create(role).Error)
+	var ids []uuid.UUID
+	for i := 0; i < n; i++ {
+		u := &models.FiduciaryUser{ID: uuid.New(), TenantID: tenantID, Email: uuid.NewString() + "@x.io", Phone: uuid.NewString(), Roles: []*models.Role{role}}
+		require.NoError(t, db.Create(u).Error)
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestAssignRequest_RoundRobinByRule(t *testing.T) {
+	svc, db, tenantID := setupDSRQueue(t)
+	users := addUsersWithRole(t, db, tenantID, "privacy-analyst", 2)
+
+	require.NoError(t, svc.CreateRule(&models.DSRRoutingRule{TenantID: tenantID, Name: "Erasure", MatchType: "erasure", AssigneeRole: "privacy-analyst", IsActive: true}))
+
+	seen := map[uuid.UUID]int{}
+	for i := 0; i < 4; i++ {
+		req := &models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "erasure"}
+		require.NoError(t, svc.AssignRequest(req))
+		require.NotNil(t, req.AssignedTo)
+		require.NotNil(t, req.RoutingRuleID)
+		seen[*req.AssignedTo]++
+	}
+	assert.Equal(t, 2, seen[users[0]])
+	assert.Equal(t, 2, seen[users[1]])
+
+	// Requests that match no rule are left unassigned
+	other := &models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "access"}
+	require.NoError(t, svc.AssignRequest(other))
+	assert.Nil(t, other.AssignedTo)
+}
+
+func TestAssignRequest_RoundRobinTakesTurnAfterConcurrentAssignment(t *testing.T) {
+	svc, db, tenantID := setupDSRQueue(t)
+	addUsersWithRole(t, db, tenantID, "privacy-analyst", 3)
+	rule := &models.DSRRoutingRule{TenantID: tenantID, Name: "Erasure", MatchType: "erasure", AssigneeRole: "privacy-analyst", IsActive: true}
+	require.NoError(t, svc.CreateRule(rule))
+	users, err := svc.repo.ListUserIDsWithRole(tenantID, "privacy-analyst")
+	require.NoError(t, err)
+
+	// Another request routed by the same rule has just taken the first turn,
+	// after this one loaded the rule
+	stale := *rule
+	require.NoError(t, db.Model(&models.DSRRoutingRule{}).Where("id = ?", rule.ID).Update("last_assigned_user_id", users[0]).Error)
+
+	got, err := svc.pickAssignee(&stale)
+	require.NoError(t, err)
+	assert.Equal(t, users[1], got)
+	var stored models.DSRRoutingRule
+	require.NoError(t, db.First(&stored, "id = ?", rule.ID).Error)
+	require.NotNil(t, stored.LastAssignedUserID)
+	assert.Equal(t, users[1], *stored.LastAssignedUserID)
+}
+
+func TestEscalateDueRequests_FlagsRequestsInsideWindow(t *testing.T) {
+	svc, db, tenantID := setupDSRQueue(t)
+	addUsersWithRole(t, db, tenantID, "privacy-analyst", 1)
+	supervisors := addUsersWithRole(t, db, tenantID, "dpo", 1)
+
+	rule := &models.DSRRoutingRule{TenantID: tenantID, Name: "All", AssigneeRole: "privacy-analyst", EscalationRole: "dpo", EscalateHoursBeforeDue: 48, IsActive: true}
+	require.NoError(t, svc.CreateRule(rule))
+
+	now := time.Now()
+	near := models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "access", Status: "Pending", DueDate: now.Add(24 * time.Hour), RoutingRuleID: &rule.ID}
+	far := models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "access", Status: "Pending", DueDate: now.Add(10 * 24 * time.Hour), RoutingRuleID: &rule.ID}
+	require.NoError(t, db.Create(&near).Error)
+	require.NoError(t, db.Create(&far).Error)
+
+	n, err := svc.EscalateDueRequests(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var got models.DSRRequest
+	require.NoError(t, db.First(&got, "id = ?", near.ID).Error)
+	require.NotNil(t, got.EscalatedTo)
+	assert.Equal(t, supervisors[0], *got.EscalatedTo)
+
+	// A second sweep does not escalate the same request again
+	n, err = svc.EscalateDueRequests(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestEscalateDueRequests_BalancesSupervisorsByEscalations(t *testing.T) {
+	svc, db, tenantID := setupDSRQueue(t)
+	supervisors := addUsersWithRole(t, db, tenantID, "dpo", 2)
+
+	rule := &models.DSRRoutingRule{TenantID: tenantID, Name: "All", AssigneeRole: "privacy-analyst", EscalationRole: "dpo", EscalateHoursBeforeDue: 48, IsActive: true}
+	require.NoError(t, svc.CreateRule(rule))
+
+	// The first supervisor already holds an open escalation; the requests
+	// assigned to the second do not count towards their escalation load
+	now := time.Now()
+	held := models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Status: "Pending", DueDate: now, EscalatedAt: &now, EscalatedTo: &supervisors[0]}
+	require.NoError(t, db.Create(&held).Error)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Status: "Pending", DueDate: now.Add(30 * 24 * time.Hour), AssignedTo: &supervisors[1]}).Error)
+	}
+	due := models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "access", Status: "Pending", DueDate: now.Add(time.Hour), RoutingRuleID: &rule.ID}
+	require.NoError(t, db.Create(&due).Error)
+
+	n, err := svc.EscalateDueRequests(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var got models.DSRRequest
+	require.NoError(t, db.First(&got, "id = ?", due.ID).Error)
+	require.NotNil(t, got.EscalatedTo)
+	assert.Equal(t, supervisors[1], *got.EscalatedTo)
+}
+
+func TestCreateRequest_RoutesByConsentFormEntity(t *testing.T) {
+	_, db, tenantID := setupDSRQueue(t)
+	require.NoError(t, db.AutoMigrate(&models.ConsentForm{}, &models.DSREvent{}))
+	queue := NewDSRQueueService(repository.NewDSRRoutingRepository(db), nil)
+	dsr := NewDSRService(repository.NewDSRRepository(db, nil), nil, queue, nil)
+	analysts := addUsersWithRole(t, db, tenantID, "retail-analyst", 1)
+
+	entityID := uuid.New()
+	form := models.ConsentForm{ID: uuid.New(), TenantID: tenantID, Name: "Retail", OrganizationEntityID: entityID, FormLink: uuid.NewString()}
+	require.NoError(t, db.Create(&form).Error)
+	require.NoError(t, queue.CreateRule(&models.DSRRoutingRule{TenantID: tenantID, Name: "Retail", MatchOrganizationEntityID: &entityID, AssigneeRole: "retail-analyst", IsActive: true}))
+
+	req := &models.DSRRequest{ID: uuid.New(), TenantID: tenantID, Type: "access", Status: "Pending", RequestedAt: time.Now()}
+	require.NoError(t, dsr.ScopeToConsentForm(req, form.ID))
+	require.NoError(t, dsr.CreateRequest(req))
+	require.NotNil(t, req.OrganizationEntityID)
+	assert.Equal(t, entityID, *req.OrganizationEntityID)
+	require.NotNil(t, req.AssignedTo)
+	assert.Equal(t, analysts[0], *req.AssignedTo)
+
+	// Forms of other tenants are not found
+	other := &models.DSRRequest{ID: uuid.New(), TenantID: uuid.New(), Type: "access"}
+	assert.Error(t, dsr.ScopeToConsentForm(other, form.ID))
+}
@@ -1,50 +1,364 @@
 package services
 
 import (
-	"pixpivot/arc/internal/storage/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"pixpivot/arc/internal/models"
-	"time"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
 
 	"github.com/google/uuid"
 )
 
 type DSRService struct {
-	repo *repository.DSRRepository
+	repo       *repository.DSRRepository
+	webhookSvc *WebhookService
+	queue      *DSRQueueService
+	tracker    *DSRTrackingService
+}
+
+func NewDSRService(repo *repository.DSRRepository, webhookSvc *WebhookService, queue *DSRQueueService, tracker *DSRTrackingService) *DSRService {
+	return &DSRService{repo: repo, webhookSvc: webhookSvc, queue: queue, tracker: tracker}
+}
+
+// ErrRectificationNotApproved is returned when changes are applied to a
+// request that has not been approved or taken into progress
+var ErrRectificationNotApproved = errors.New("rectification request must be approved before changes are applied")
+
+// rectificationApplicableStatuses are the request statuses in which reviewed
+// changes may be written to the principal record
+var rectificationApplicableStatuses = map[string]bool{
+	"Approved":    true,
+	"In Progress": true,
+}
+
+// rectifiableFields maps the field names accepted on rectification requests to
+// their DataPrincipal columns.
+var rectifiableFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"phone":      true,
+	"age":        true,
+	"location":   true,
 }
 
-func NewDSRService(repo *repository.DSRRepository) *DSRService {
-	return &DSRService{repo: repo}
+// IsRectificationType reports whether a DSR type is a correction request. The
+// dashboard files these as "Data Correction"; the API uses "rectification".
+func IsRectificationType(t string) bool {
+	return t == "rectification" || t == "Data Correction"
+}
+
+// ScopeToConsentForm files the request under the organization entity of the
+// consent form it was raised from, so routing rules can match on the entity.
+func (s *DSRService) ScopeToConsentForm(req *models.DSRRequest, formID uuid.UUID) error {
+	entityID, err := s.repo.ConsentFormOrganizationEntity(formID, req.TenantID)
+	if err != nil {
+		return fmt.Errorf("consent form not found: %w", err)
+	}
+	req.OrganizationEntityID = entityID
+	return nil
 }
 
 func (s *DSRService) CreateRequest(req *models.DSRRequest) error {
 	// Calculate SLA based on type and regulation (defaulting to 30 days for GDPR/DPDP)
 	req.DueDate = time.Now().AddDate(0, 0, 30)
-	
+
 	// Auto-assign priority based on type
-	if req.Type == "erasure" || req.Type == "rectification" {
+	if req.Type == "erasure" || IsRectificationType(req.Type) {
 		req.Priority = "high"
 	} else {
 		req.Priority = "medium"
 	}
-	
-	return s.repo.Create(req)
+
+	s.route(req)
+	s.prepareTracking(req)
+	if err := s.repo.Create(req); err != nil {
+		return err
+	}
+	s.notifyAssignee(req)
+	s.trackSubmitted(req)
+	return nil
+}
+
+// route applies the tenant's routing rules. Routing failures leave the request
+// unassigned rather than blocking its creation.
+func (s *DSRService) route(req *models.DSRRequest) {
+	if s.queue == nil {
+		return
+	}
+	if err := s.queue.AssignRequest(req); err != nil {
+		log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to route DSR")
+	}
+}
+
+func (s *DSRService) notifyAssignee(req *models.DSRRequest) {
+	if s.queue != nil {
+		go s.queue.NotifyAssignee(req)
+	}
+}
+
+func (s *DSRService) prepareTracking(req *models.DSRRequest) {
+	if s.tracker != nil {
+		s.tracker.Prepare(req)
+	}
+}
+
+func (s *DSRService) trackSubmitted(req *models.DSRRequest) {
+	if s.tracker != nil {
+		s.tracker.Submitted(req)
+	}
+}
+
+// StatusLink returns the signed public status link for a request.
+func (s *DSRService) StatusLink(req *models.DSRRequest) (string, error) {
+	if s.tracker == nil {
+		return "", errors.New("status tracking is not configured")
+	}
+	return s.tracker.StatusLink(req)
+}
+
+// RecordStatusChange adds the request's current status to its public timeline
+// and tells the requester.
+func (s *DSRService) RecordStatusChange(req *models.DSRRequest, actorID *uuid.UUID) {
+	if s.tracker != nil {
+		s.tracker.StatusChanged(req, actorID)
+	}
+}
+
+// CreateRectificationRequest files a rectification request with structured field
+// changes. The current value of each field is captured as OldValue so reviewers
+// see a diff against the record as it stood when the request was made.
+func (s *DSRService) CreateRectificationRequest(req *models.DSRRequest, changes []models.DSRFieldChange) error {
+	if len(changes) == 0 {
+		return errors.New("at least one field change is required")
+	}
+	principal, err := s.repo.GetPrincipal(req.UserID)
+	if err != nil {
+		return fmt.Errorf("data principal not found: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range changes {
+		c := &changes[i]
+		c.Field = strings.ToLower(strings.TrimSpace(c.Field))
+		if !rectifiableFields[c.Field] {
+			return fmt.Errorf("field %q cannot be rectified", c.Field)
+		}
+		if seen[c.Field] {
+			return fmt.Errorf("field %q is listed more than once", c.Field)
+		}
+		seen[c.Field] = true
+		if c.Field == "age" {
+			if _, err := strconv.Atoi(c.NewValue); err != nil {
+				return errors.New("age must be a whole number")
+			}
+		}
+		c.ID = uuid.New()
+		c.RequestID = req.ID
+		c.TenantID = req.TenantID
+		c.OldValue = principalFieldValue(principal, c.Field)
+		c.Status = "pending"
+	}
+
+	req.DueDate = time.Now().AddDate(0, 0, 30)
+	req.Priority = "high"
+	s.route(req)
+	s.prepareTracking(req)
+	if err := s.repo.CreateWithFieldChanges(req, changes); err != nil {
+		return err
+	}
+	s.notifyAssignee(req)
+	s.trackSubmitted(req)
+	return nil
+}
+
+// GetFieldChanges lists the proposed changes on one of the tenant's requests.
+func (s *DSRService) GetFieldChanges(requestID, tenantID uuid.UUID) ([]models.DSRFieldChange, error) {
+	if _, err := s.repo.GetForTenant(requestID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetFieldChanges(requestID)
+}
+
+// ReviewFieldChange approves or rejects a single proposed change.
+func (s *DSRService) ReviewFieldChange(requestID, tenantID, changeID, reviewerID uuid.UUID, approve bool, note string) (*models.DSRFieldChange, error) {
+	if _, err := s.repo.GetForTenant(requestID, tenantID); err != nil {
+		return nil, err
+	}
+	change, err := s.repo.GetFieldChange(changeID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status == "applied" {
+		return nil, errors.New("change has already been applied")
+	}
+
+	now := time.Now()
+	change.ReviewedBy = &reviewerID
+	change.ReviewedAt = &now
+	change.ReviewNote = note
+	if approve {
+		change.Status = "approved"
+	} else {
+		change.Status = "rejected"
+	}
+	if err := s.repo.UpdateFieldChange(change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// ApplyRectification applies every approved change to the principal record in a
+// single transaction, records the outcome on the request and notifies subscribed
+// vendors. The request must be approved or in progress, and every change must
+// have been reviewed first.
+func (s *DSRService) ApplyRectification(requestID, tenantID, reviewerID uuid.UUID) (*models.DSRRequest, error) {
+	req, err := s.repo.GetForTenant(requestID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !IsRectificationType(req.Type) {
+		return nil, errors.New("request is not a rectification request")
+	}
+	if !rectificationApplicableStatuses[req.Status] {
+		return nil, ErrRectificationNotApproved
+	}
+	changes, err := s.repo.GetFieldChanges(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, errors.New("request has no field changes")
+	}
+
+	updates := make(map[string]interface{})
+	var applied []models.DSRFieldChange
+	var rejected []string
+	now := time.Now()
+	for _, c := range changes {
+		switch c.Status {
+		case "pending":
+			return nil, fmt.Errorf("field %q has not been reviewed", c.Field)
+		case "applied":
+			return nil, errors.New("rectification has already been applied")
+		case "approved":
+			if c.Field == "age" {
+				age, _ := strconv.Atoi(c.NewValue)
+				updates[c.Field] = age
+			} else {
+				updates[c.Field] = c.NewValue
+			}
+			c.Status = "applied"
+			c.AppliedAt = &now
+			applied = append(applied, c)
+		default:
+			rejected = append(rejected, c.Field)
+		}
+	}
+
+	type appliedChange struct {
+		Field    string `json:"field"`
+		OldValue string `json:"oldValue"`
+		NewValue string `json:"newValue"`
+	}
+	summary := make([]appliedChange, 0, len(applied))
+	for _, c := range applied {
+		summary = append(summary, appliedChange{Field: c.Field, OldValue: c.OldValue, NewValue: c.NewValue})
+	}
+	result, _ := json.Marshal(map[string]interface{}{
+		"appliedChanges": summary,
+		"rejectedFields": rejected,
+		"appliedBy":      reviewerID.String(),
+		"appliedAt":      now,
+	})
+	req.ResultData = result
+	req.ProcessedAt = &now
+	if len(applied) == 0 {
+		req.Status = "Rejected"
+		req.ResolutionNote = "All proposed changes were rejected."
+	} else {
+		req.Status = "Completed"
+		req.ResolutionNote = fmt.Sprintf("%d field(s) rectified, %d rejected.", len(applied), len(rejected))
+	}
+
+	if err := s.repo.ApplyRectification(req, updates, applied); err != nil {
+		return nil, err
+	}
+	s.RecordStatusChange(req, &reviewerID)
+
+	if len(applied) > 0 && s.webhookSvc != nil {
+		go s.webhookSvc.Dispatch(req.TenantID, "principal.rectified", map[string]interface{}{
+			"userId":    req.UserID.String(),
+			"requestId": req.ID.String(),
+			"changes":   summary,
+			"appliedAt": now,
+		})
+	}
+	return req, nil
+}
+
+func principalFieldValue(p *models.DataPrincipal, field string) string {
+	switch field {
+	case "first_name":
+		return p.FirstName
+	case "last_name":
+		return p.LastName
+	case "email":
+		return p.Email
+	case "phone":
+		return p.Phone
+	case "age":
+		return strconv.Itoa(p.Age)
+	case "location":
+		return p.Location
+	}
+	return ""
 }
 
 func (s *DSRService) UpdateStatus(id uuid.UUID, status string, note string, userID uuid.UUID) error {
 	// TODO: Add validation for state transitions
-	return s.repo.UpdateStatus(id, status, note)
+	if err := s.repo.UpdateStatus(id, status, note); err != nil {
+		return err
+	}
+	if req, err := s.repo.GetByID(id); err == nil {
+		s.RecordStatusChange(req, &userID)
+	}
+	return nil
 }
 
 func (s *DSRService) AddComment(comment *models.DSRComment) error {
+	if strings.TrimSpace(comment.Content) == "" {
+		return errors.New("comment content is required")
+	}
+	if comment.ID == uuid.Nil {
+		comment.ID = uuid.New()
+	}
 	return s.repo.AddComment(comment)
 }
 
-func (s *DSRService) GetComments(requestID uuid.UUID) ([]models.DSRComment, error) {
-	return s.repo.GetComments(requestID)
+// GetComments lists a request's comments; internal staff notes are left out
+// unless includeInternal is set.
+func (s *DSRService) GetComments(requestID uuid.UUID, includeInternal bool) ([]models.DSRComment, error) {
+	return s.repo.GetComments(requestID, includeInternal)
 }
 
 func (s *DSRService) ApproveDeleteRequest(requestID uuid.UUID) error {
-	return s.repo.ApproveDeleteRequest(requestID)
+	// Load the request first: completing an erasure resolves (soft-deletes) it
+	req, err := s.repo.GetByID(requestID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ApproveDeleteRequest(requestID); err != nil {
+		return err
+	}
+	req.Status = "Completed"
+	req.ResolutionNote = "User data permanently deleted."
+	s.RecordStatusChange(req, nil)
+	return nil
 }
-
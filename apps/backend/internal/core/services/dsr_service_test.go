@@ -0,0 +1,92 @@
+package services
+
+import (
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDSRService(t *testing.T) (*DSRService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DataPrincipal{}, &models.DSRRequest{}, &models.DSRFieldChange{}))
+	return NewDSRService(repository.NewDSRRepository(db, nil), nil, nil, nil), db
+}
+
+func TestApplyRectification_AppliesOnlyApprovedFields(t *testing.T) {
+	svc, db := setupDSRService(t)
+	principal := models.DataPrincipal{ID: uuid.New(), Email: "old@example.com", FirstName: "Ravi", Location: "Pune", Age: 30}
+	require.NoError(t, db.Create(&principal).Error)
+
+	tenantID := uuid.New()
+	req := &models.DSRRequest{ID: uuid.New(), UserID: principal.ID, TenantID: tenantID, Type: "rectification", Status: "Pending", RequestedAt: time.Now()}
+	err := svc.CreateRectificationRequest(req, []models.DSRFieldChange{
+		{Field: "first_name", NewValue: "Ravindra"},
+		{Field: "location", NewValue: "Mumbai"},
+		{Field: "age", NewValue: "31"},
+	})
+	require.NoError(t, err)
+
+	changes, err := svc.GetFieldChanges(req.ID, tenantID)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	reviewer := uuid.New()
+	byField := map[string]models.DSRFieldChange{}
+	for _, c := range changes {
+		byField[c.Field] = c
+	}
+	assert.Equal(t, "Ravi", byField["first_name"].OldValue)
+
+	// Changes are applied only once the request has been approved
+	_, err = svc.ApplyRectification(req.ID, tenantID, reviewer)
+	assert.ErrorIs(t, err, ErrRectificationNotApproved)
+	require.NoError(t, db.Model(req).Update("status", "Approved").Error)
+
+	// Applying before every field is reviewed is refused
+	_, err = svc.ReviewFieldChange(req.ID, tenantID, byField["first_name"].ID, reviewer, true, "")
+	require.NoError(t, err)
+	_, err = svc.ApplyRectification(req.ID, tenantID, reviewer)
+	assert.Error(t, err)
+
+	_, err = svc.ReviewFieldChange(req.ID, tenantID, byField["location"].ID, reviewer, false, "No proof of address")
+	require.NoError(t, err)
+	_, err = svc.ReviewFieldChange(req.ID, tenantID, byField["age"].ID, reviewer, true, "")
+	require.NoError(t, err)
+
+	// Another tenant cannot see or apply the changes
+	_, err = svc.GetFieldChanges(req.ID, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = svc.ApplyRectification(req.ID, uuid.New(), reviewer)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	result, err := svc.ApplyRectification(req.ID, tenantID, reviewer)
+	require.NoError(t, err)
+	assert.Equal(t, "Completed", result.Status)
+
+	var updated models.DataPrincipal
+	require.NoError(t, db.First(&updated, "id = ?", principal.ID).Error)
+	assert.Equal(t, "Ravindra", updated.FirstName)
+	assert.Equal(t, 31, updated.Age)
+	assert.Equal(t, "Pune", updated.Location)
+
+	_, err = svc.ApplyRectification(req.ID, tenantID, reviewer)
+	assert.Error(t, err, "a rectification must not be applied twice")
+}
+
+func TestCreateRectificationRequest_RejectsUnknownField(t *testing.T) {
+	svc, db := setupDSRService(t)
+	principal := models.DataPrincipal{ID: uuid.New(), Email: "a@example.com"}
+	require.NoError(t, db.Create(&principal).Error)
+
+	req := &models.DSRRequest{ID: uuid.New(), UserID: principal.ID, Type: "rectification", RequestedAt: time.Now()}
+	err := svc.CreateRectificationRequest(req, []models.DSRFieldChange{{Field: "password_hash", NewValue: "x"}})
+	assert.Error(t, err)
+}
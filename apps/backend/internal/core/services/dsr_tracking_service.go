@@ -0,0 +1,423 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/jwtlink"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+)
+
+const (
+	dsrStatusLinkTTL      = 90 * 24 * time.Hour
+	dsrStatusLinkResend   = 10 * time.Minute // Minimum gap between requested resends
+	dsrStatusAwaitingInfo = "Awaiting Info"
+	referenceAlphabet     = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	referenceRandomChars  = 8
+)
+
+// DSRPublicStatus is what the requester sees on the public status page.
+type DSRPublicStatus struct {
+	TrackingRef     string            `json:"trackingRef"`
+	Type            string            `json:"type"`
+	Status          string            `json:"status"`
+	RequestedAt     time.Time         `json:"requestedAt"`
+	DueDate         time.Time         `json:"dueDate"`
+	InfoRequest     string            `json:"infoRequest,omitempty"`
+	ResultAvailable bool              `json:"resultAvailable"`
+	Timeline        []models.DSREvent `json:"timeline"`
+}
+
+// DSRTrackingService gives every DSR a tracking reference and signed status
+// link, keeps the requester-facing timeline and emails the requester as the
+// request progresses.
+type DSRTrackingService struct {
+	repo         *repository.DSRRepository
+	emailService *EmailService
+	store        *blob.Store
+	attachments  *AttachmentService
+	baseURL      string
+}
+
+func NewDSRTrackingService(repo *repository.DSRRepository, emailService *EmailService, store *blob.Store, attachments *AttachmentService, baseURL string) *DSRTrackingService {
+	return &DSRTrackingService{repo: repo, emailService: emailService, store: store, attachments: attachments, baseURL: baseURL}
+}
+
+// Prepare assigns a tracking reference and resolves the requester's email on an
+// unsaved request.
+func (s *DSRTrackingService) Prepare(req *models.DSRRequest) {
+	if req.TrackingRef == "" {
+		req.TrackingRef = newReference("DSR")
+	}
+	if req.RequesterEmail == "" {
+		if p, err := s.repo.GetPrincipal(req.UserID); err == nil {
+			req.RequesterEmail = p.Email
+		}
+	}
+}
+
+// newReference returns a short human-quotable reference such as DSR-7K2M9Q4X.
+// Ambiguous characters (0/O, 1/I/L) are left out.
+func newReference(prefix string) string {
+	b := make([]byte, referenceRandomChars)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = referenceAlphabet[int(b[i])%len(referenceAlphabet)]
+	}
+	return prefix + "-" + string(b)
+}
+
+// StatusLink returns a signed link to the public status page.
+func (s *DSRTrackingService) StatusLink(req *models.DSRRequest) (string, error) {
+	token, err := jwtlink.GenerateDSRStatusToken(req.ID.String(), req.TenantID.String(), req.TrackingRef, dsrStatusLinkTTL)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/dsr/status?token=%s", s.baseURL, token), nil
+}
+
+// ResolveToken validates a status token and loads its request.
+func (s *DSRTrackingService) ResolveToken(token string) (*models.DSRRequest, error) {
+	claims, err := jwtlink.ParseDSRStatusToken(token)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(claims.RequestID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	req, err := s.repo.GetByID(id)
+	if err != nil || req.TrackingRef != claims.TrackingRef {
+		return nil, errors.New("request not found")
+	}
+	return req, nil
+}
+
+// Timeline events
+
+func (s *DSRTrackingService) RecordEvent(req *models.DSRRequest, eventType, message string, public bool, actorID *uuid.UUID) {
+	event := &models.DSREvent{
+		ID:        uuid.New(),
+		RequestID: req.ID,
+		TenantID:  req.TenantID,
+		EventType: eventType,
+		Message:   message,
+		Public:    public,
+		ActorID:   actorID,
+	}
+	if err := s.repo.AddEvent(event); err != nil {
+		log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to record DSR event")
+	}
+}
+
+// ListEvents returns the full timeline of one of the tenant's requests.
+func (s *DSRTrackingService) ListEvents(requestID, tenantID uuid.UUID) ([]models.DSREvent, error) {
+	if _, err := s.repo.GetForTenant(requestID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(requestID, false)
+}
+
+// Submitted records the first timeline entry and sends the requester their
+// tracking reference and status link.
+func (s *DSRTrackingService) Submitted(req *models.DSRRequest) {
+	s.RecordEvent(req, "submitted", "Your request has been received.", true, nil)
+	link, err := s.StatusLink(req)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to sign DSR status link")
+		return
+	}
+	body := fmt.Sprintf(`Your %s request has been received.<br><br>Tracking reference: <b>%s</b>
+<br>We will respond by %s. You can follow its progress at any time using the link below.
+<br><br><a href="%s">View request status</a>`, req.Type, req.TrackingRef, req.DueDate.Format("02 Jan 2006"), link)
+	s.notify(req, "We have received your request "+req.TrackingRef, body)
+}
+
+// StatusChanged records a status change on the timeline and emails the requester.
+func (s *DSRTrackingService) StatusChanged(req *models.DSRRequest, actorID *uuid.UUID) {
+	message := "Status changed to " + req.Status + "."
+	if req.ResolutionNote != "" && isFinalDSRStatus(req.Status) {
+		message += " " + req.ResolutionNote
+	}
+	s.RecordEvent(req, "status_changed", message, true, actorID)
+	if resultAvailable(req) {
+		s.RecordEvent(req, "result_available", "The result of your request is ready to download.", true, actorID)
+	}
+
+	link, _ := s.StatusLink(req)
+	body := fmt.Sprintf(`The status of your request <b>%s</b> is now <b>%s</b>.<br><br><a href="%s">View request status</a>`, req.TrackingRef, req.Status, link)
+	s.notify(req, "Update on your request "+req.TrackingRef, body)
+}
+
+// Information requests
+
+// RequestInfo pauses a request while the requester is asked for more information.
+func (s *DSRTrackingService) RequestInfo(requestID, tenantID, actorID uuid.UUID, question string) (*models.DSRRequest, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, errors.New("a question for the requester is required")
+	}
+	req, err := s.repo.GetByID(requestID)
+	if err != nil || req.TenantID != tenantID {
+		return nil, errors.New("request not found")
+	}
+	if isFinalDSRStatus(req.Status) {
+		return nil, errors.New("request is already closed")
+	}
+
+	req.Status = dsrStatusAwaitingInfo
+	req.InfoRequest = question
+	if err := s.repo.Save(req); err != nil {
+		return nil, err
+	}
+	s.RecordEvent(req, "info_requested", question, true, &actorID)
+
+	link, _ := s.StatusLink(req)
+	body := fmt.Sprintf(`We need more information to progress your request <b>%s</b>:<br><br><i>%s</i>
+<br><br>Please reply using the link below.<br><br><a href="%s">Provide information</a>`, req.TrackingRef, question, link)
+	s.notify(req, "More information needed for "+req.TrackingRef, body)
+	return req, nil
+}
+
+// AddClarification stores the requester's answer and returns the request to the queue.
+func (s *DSRTrackingService) AddClarification(req *models.DSRRequest, message, fileName, contentType string, data []byte) (*models.DSRClarification, error) {
+	message = strings.TrimSpace(message)
+	if message == "" && len(data) == 0 {
+		return nil, errors.New("a message or file is required")
+	}
+	if isFinalDSRStatus(req.Status) {
+		return nil, errors.New("request is already closed")
+	}
+
+	c := &models.DSRClarification{
+		ID:        uuid.New(),
+		RequestID: req.ID,
+		TenantID:  req.TenantID,
+		Message:   message,
+	}
+	if len(data) > 0 {
+		if s.store == nil {
+			return nil, errors.New("file uploads are not configured")
+		}
+		checked, err := checkAttachmentFile(fileName, contentType, data)
+		if err != nil {
+			return nil, err
+		}
+		contentType = checked
+		sum := sha256.Sum256(data)
+		c.FileName = blob.SafeName(fileName)
+		c.ContentType = contentType
+		c.SizeBytes = int64(len(data))
+		c.SHA256 = hex.EncodeToString(sum[:])
+		key := fmt.Sprintf("dsr-clarifications/%s/%s/%s_%s", req.TenantID, req.ID, c.ID, c.FileName)
+		path, err := s.store.Put(key, contentType, data)
+		if err != nil {
+			return nil, err
+		}
+		c.FilePath = path
+	}
+	if err := s.repo.AddClarification(c); err != nil {
+		return nil, err
+	}
+
+	if req.Status == dsrStatusAwaitingInfo {
+		req.Status = "Pending"
+		req.InfoRequest = ""
+		if err := s.repo.Save(req); err != nil {
+			return nil, err
+		}
+	}
+	s.RecordEvent(req, "clarification_received", "Additional information received from the requester.", true, nil)
+	s.notify(req, "We have received your information for "+req.TrackingRef,
+		fmt.Sprintf("Thank you. The information you provided for request <b>%s</b> has been passed to our team.", req.TrackingRef))
+	return c, nil
+}
+
+// ListClarifications returns the requester's answers on one of the tenant's requests.
+func (s *DSRTrackingService) ListClarifications(requestID, tenantID uuid.UUID) ([]models.DSRClarification, error) {
+	if _, err := s.repo.GetForTenant(requestID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListClarifications(requestID)
+}
+
+// GetClarificationFile returns a clarification on one of the tenant's requests
+// and the bytes of its attached file.
+func (s *DSRTrackingService) GetClarificationFile(requestID, tenantID, clarificationID uuid.UUID) (*models.DSRClarification, []byte, error) {
+	if _, err := s.repo.GetForTenant(requestID, tenantID); err != nil {
+		return nil, nil, err
+	}
+	c, err := s.repo.GetClarification(clarificationID, requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.FilePath == "" || s.store == nil {
+		return nil, nil, errors.New("clarification has no file")
+	}
+	data, err := s.store.Get(c.FilePath)
+	return c, data, err
+}
+
+// Public status
+
+func (s *DSRTrackingService) PublicStatus(req *models.DSRRequest) (*DSRPublicStatus, error) {
+	events, err := s.repo.ListEvents(req.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	return &DSRPublicStatus{
+		TrackingRef:     req.TrackingRef,
+		Type:            req.Type,
+		Status:          req.Status,
+		RequestedAt:     req.RequestedAt,
+		DueDate:         req.DueDate,
+		InfoRequest:     req.InfoRequest,
+		ResultAvailable: resultAvailable(req),
+		Timeline:        events,
+	}, nil
+}
+
+// ResendLink emails a fresh status link when the reference and email match
+// and no link was resent for the request in the last dsrStatusLinkResend. It
+// reports whether an email went out; callers must not pass that on, so
+// references cannot be probed.
+func (s *DSRTrackingService) ResendLink(trackingRef, email string) bool {
+	req, err := s.repo.GetByTrackingRef(strings.ToUpper(strings.TrimSpace(trackingRef)))
+	if err != nil || req.RequesterEmail == "" || !strings.EqualFold(req.RequesterEmail, strings.TrimSpace(email)) {
+		return false
+	}
+	link, err := s.StatusLink(req)
+	if err != nil {
+		return false
+	}
+	// The endpoint is public, so each request gets at most one resend per
+	// dsrStatusLinkResend
+	now := time.Now()
+	claimed, err := s.repo.ClaimStatusLinkResend(req.ID, now.Add(-dsrStatusLinkResend), now)
+	if err != nil {
+		log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to record DSR status link resend")
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	s.notify(req, "Your status link for "+req.TrackingRef,
+		fmt.Sprintf(`Use the link below to view your request <b>%s</b>.<br><br><a href="%s">View request status</a>`, req.TrackingRef, link))
+	return true
+}
+
+// ResultPackage builds a zip archive with the outcome of a closed request. Access
+// and portability requests also include the principal's profile as held.
+func (s *DSRTrackingService) ResultPackage(req *models.DSRRequest) ([]byte, error) {
+	if !resultAvailable(req) {
+		return nil, errors.New("result is not available yet")
+	}
+	events, err := s.repo.ListEvents(req.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]interface{}{
+		"request.json": map[string]interface{}{
+			"trackingRef":    req.TrackingRef,
+			"type":           req.Type,
+			"status":         req.Status,
+			"requestedAt":    req.RequestedAt,
+			"processedAt":    req.ProcessedAt,
+			"resolutionNote": req.ResolutionNote,
+			"result":         req.ResultData,
+		},
+		"timeline.json": events,
+	}
+	if isDataExportType(req.Type) {
+		if p, err := s.repo.GetPrincipal(req.UserID); err == nil {
+			files["data.json"] = map[string]interface{}{
+				"email":     p.Email,
+				"phone":     p.Phone,
+				"firstName": p.FirstName,
+				"lastName":  p.LastName,
+				"age":       p.Age,
+				"location":  p.Location,
+				"createdAt": p.CreatedAt,
+			}
+		}
+	}
+
+	var attachments map[string][]byte
+	if s.attachments != nil {
+		if attachments, err = s.attachments.ForExport(req.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(content); err != nil {
+			return nil, err
+		}
+	}
+	for name, data := range attachments {
+		f, err := zw.Create("attachments/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *DSRTrackingService) notify(req *models.DSRRequest, subject, body string) {
+	if s.emailService == nil || req.RequesterEmail == "" {
+		return
+	}
+	go func() {
+		if err := s.emailService.Send(req.RequesterEmail, subject, body); err != nil {
+			log.Logger.Error().Err(err).Str("request_id", req.ID.String()).Msg("failed to send DSR status email")
+		}
+	}()
+}
+
+func isFinalDSRStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "completed", "rejected":
+		return true
+	}
+	return false
+}
+
+func resultAvailable(req *models.DSRRequest) bool {
+	return strings.EqualFold(req.Status, "completed") || strings.EqualFold(req.Status, "approved")
+}
+
+func isDataExportType(t string) bool {
+	switch strings.ToLower(t) {
+	case "access", "portability", "data access", "data portability":
+		return true
+	}
+	return false
+}
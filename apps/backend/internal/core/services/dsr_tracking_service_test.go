@@ -0,0 +1,134 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/url"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/jwtlink"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDSRTracking(t *testing.T) (*DSRService, *DSRTrackingService, *gorm.DB) {
+	jwtlink.Init("test-secret")
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DataPrincipal{}, &models.DSRRequest{}, &models.DSREvent{}, &models.DSRClarification{}))
+
+	repo := repository.NewDSRRepository(db, nil)
+	store := blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false)
+	tracker := NewDSRTrackingService(repo, nil, store, nil, "https://portal.example.com")
+	return NewDSRService(repo, nil, nil, tracker), tracker, db
+}
+
+func TestDSRTracking_StatusLinkAndInfoRequestCycle(t *testing.T) {
+	svc, tracker, db := setupDSRTracking(t)
+	principal := models.DataPrincipal{ID: uuid.New(), Email: "asha@example.com", FirstName: "Asha"}
+	require.NoError(t, db.Create(&principal).Error)
+
+	req := &models.DSRRequest{ID: uuid.New(), UserID: principal.ID, TenantID: uuid.New(), Type: "access", Status: "Pending", RequestedAt: time.Now()}
+	require.NoError(t, svc.CreateRequest(req))
+	assert.Regexp(t, `^DSR-[A-Z0-9]{8}$`, req.TrackingRef)
+	assert.Equal(t, "asha@example.com", req.RequesterEmail)
+
+	link, err := tracker.StatusLink(req)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	resolved, err := tracker.ResolveToken(u.Query().Get("token"))
+	require.NoError(t, err)
+	assert.Equal(t, req.ID, resolved.ID)
+
+	_, err = tracker.ResolveToken("not-a-token")
+	assert.Error(t, err)
+
+	// Asking for information pauses the request; a clarification resumes it
+	_, err = tracker.RequestInfo(req.ID, req.TenantID, uuid.New(), "Please confirm your registered phone number.")
+	require.NoError(t, err)
+	resolved, _ = tracker.ResolveToken(u.Query().Get("token"))
+	assert.Equal(t, "Awaiting Info", resolved.Status)
+
+	c, err := tracker.AddClarification(resolved, "It is +91 98200 00000", "proof.pdf", "application/pdf", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.Len(t, c.SHA256, 64)
+
+	_, data, err := tracker.GetClarificationFile(req.ID, req.TenantID, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("%PDF-1.4"), data)
+
+	// Another tenant can see neither the answer nor the timeline
+	_, _, err = tracker.GetClarificationFile(req.ID, uuid.New(), c.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = tracker.ListClarifications(req.ID, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = tracker.ListEvents(req.ID, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	status, err := tracker.PublicStatus(resolved)
+	require.NoError(t, err)
+	assert.Equal(t, "Pending", status.Status)
+	assert.False(t, status.ResultAvailable)
+	var types []string
+	for _, e := range status.Timeline {
+		types = append(types, e.EventType)
+	}
+	assert.Equal(t, []string{"submitted", "info_requested", "clarification_received"}, types)
+
+	_, err = tracker.ResultPackage(resolved)
+	assert.Error(t, err, "result is not available while the request is open")
+}
+
+func TestDSRTracking_ResultPackageIncludesPrincipalData(t *testing.T) {
+	svc, tracker, db := setupDSRTracking(t)
+	principal := models.DataPrincipal{ID: uuid.New(), Email: "dev@example.com", FirstName: "Dev"}
+	require.NoError(t, db.Create(&principal).Error)
+
+	req := &models.DSRRequest{ID: uuid.New(), UserID: principal.ID, TenantID: uuid.New(), Type: "Data Portability", Status: "Pending", RequestedAt: time.Now()}
+	require.NoError(t, svc.CreateRequest(req))
+
+	require.NoError(t, svc.UpdateStatus(req.ID, "Completed", "Export prepared.", uuid.New()))
+	closed, err := tracker.repo.GetByID(req.ID)
+	require.NoError(t, err)
+
+	pkg, err := tracker.ResultPackage(closed)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	require.NoError(t, err)
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	assert.True(t, names["request.json"])
+	assert.True(t, names["timeline.json"])
+	assert.True(t, names["data.json"])
+
+	_, err = tracker.AddClarification(closed, "late reply", "", "", nil)
+	assert.Error(t, err, "closed requests do not accept clarifications")
+}
+
+func TestDSRTracking_ResendLinkIsThrottled(t *testing.T) {
+	svc, tracker, db := setupDSRTracking(t)
+	principal := models.DataPrincipal{ID: uuid.New(), Email: "asha@example.com", FirstName: "Asha"}
+	require.NoError(t, db.Create(&principal).Error)
+	req := &models.DSRRequest{ID: uuid.New(), UserID: principal.ID, TenantID: uuid.New(), Type: "access", Status: "Pending", RequestedAt: time.Now()}
+	require.NoError(t, svc.CreateRequest(req))
+
+	assert.False(t, tracker.ResendLink(req.TrackingRef, "someone@example.com"))
+	assert.True(t, tracker.ResendLink(strings.ToLower(req.TrackingRef), "Asha@example.com"))
+	assert.False(t, tracker.ResendLink(req.TrackingRef, "asha@example.com"), "a second resend inside the cooldown is dropped")
+
+	// Once the cooldown has passed another link can be sent
+	earlier := time.Now().Add(-dsrStatusLinkResend - time.Minute)
+	require.NoError(t, db.Model(&models.DSRRequest{}).Where("id = ?", req.ID).Update("status_link_resent_at", earlier).Error)
+	assert.True(t, tracker.ResendLink(req.TrackingRef, "asha@example.com"))
+}
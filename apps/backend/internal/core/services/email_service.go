@@ -75,6 +75,44 @@ func (s *EmailService) Send(to, subject, body string) error {
 	return s.sendWithRetry(m, 3)
 }
 
+// SendReply sends an email whose replies go to replyTo, used to thread
+// conversations through the inbound mail channel.
+func (s *EmailService) SendReply(to, replyTo, subject, body string) error {
+	if s.mockMode {
+		log.Printf("📧 [MOCK EMAIL] To: %s | Reply-To: %s | Subject: %s\nBody: %s\n---------------------------------------------------", to, replyTo, subject, body)
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	if replyTo != "" {
+		m.SetHeader("Reply-To", replyTo)
+	}
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+
+	return s.sendWithRetry(m, 3)
+}
+
+// SendTracked sends an email with a caller-chosen Message-ID so bounce and
+// delivery reports can be matched back to the message.
+func (s *EmailService) SendTracked(to, subject, body, messageID string) error {
+	if s.mockMode {
+		log.Printf("📧 [MOCK EMAIL] To: %s | Message-ID: %s | Subject: %s\nBody: %s\n---------------------------------------------------", to, messageID, subject, body)
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", to)
+	m.SetHeader("Message-ID", messageID)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
+
+	return s.sendWithRetry(m, 3)
+}
+
 func (s *EmailService) SendEmailWithAttachment(to, subject, body string, attachment []byte, filename string) error {
 	// ✅ ADDED: Mock Mode Handler
 	if s.mockMode {
@@ -4,21 +4,39 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"pixpivot/arc/internal/dto"
 	"pixpivot/arc/internal/models"
 	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
 
 	"github.com/google/uuid"
 )
 
+// Fallback SLA when no tenant policy matches. DPDP rules require grievances to
+// be redressed within 90 days.
+const (
+	defaultGrievanceResponseHours   = 72
+	defaultGrievanceResolutionHours = 90 * 24
+)
+
+// DPBNoticeText is sent to the principal when a grievance is closed (DPDP Act s.13(3)).
+const DPBNoticeText = "Your grievance has been closed. If you are not satisfied with the outcome, you have the right to " +
+	"approach the Data Protection Board of India under Section 13(3) of the Digital Personal Data Protection Act, 2023."
+
 type GrievanceService struct {
 	repo *repository.GrievanceRepository
+
+	// activeBreach finds the tenant's open breach for triage; replaced in tests
+	activeBreach func(tenantID uuid.UUID) (*uuid.UUID, error)
 }
 
 func NewGrievanceService(repo *repository.GrievanceRepository) *GrievanceService {
-	return &GrievanceService{repo: repo}
+	return &GrievanceService{repo: repo, activeBreach: activeBreachForTenant}
 }
 
 // Raise a new grievance
@@ -34,12 +52,21 @@ func (s *GrievanceService) Raise(ctx context.Context, req dto.CreateGrievanceReq
 		AssignedTo:           nil,
 		Category:             req.Category,
 		Priority:             req.Priority,
+		ThreadToken:          newReference("GRV"),
+		Source:               req.Source,
 		CreatedAt:            time.Now(),
 		UpdatedAt:            time.Now(),
 	}
+	decision := s.triage(ctx, g)
+	if err := s.applySLA(ctx, g); err != nil {
+		return nil, err
+	}
 	if err := s.repo.Create(ctx, g); err != nil {
 		return nil, err
 	}
+	if err := s.repo.CreateTriageDecision(ctx, decision); err != nil {
+		log.Logger.Error().Err(err).Str("grievance_id", g.ID.String()).Msg("failed to record triage decision")
+	}
 	return g, nil
 }
 
@@ -48,6 +75,25 @@ func (s *GrievanceService) GetByID(ctx context.Context, grievanceID string) (*mo
 	return s.repo.GetByID(ctx, grievanceID)
 }
 
+// GetByThreadToken finds the grievance quoted in an email subject
+func (s *GrievanceService) GetByThreadToken(ctx context.Context, token string) (*models.Grievance, error) {
+	return s.repo.GetByThreadToken(ctx, token)
+}
+
+// ThreadSubject prefixes an email subject with the grievance's thread token so
+// replies are filed against the same grievance.
+func ThreadSubject(g *models.Grievance, subject string) string {
+	if g.ThreadToken == "" {
+		return subject
+	}
+	return "[" + g.ThreadToken + "] " + subject
+}
+
+// PrincipalEmail returns the email address of the grievance's principal
+func (s *GrievanceService) PrincipalEmail(ctx context.Context, g *models.Grievance) (string, error) {
+	return s.repo.GetPrincipalEmail(ctx, g.UserID)
+}
+
 // List grievances for a tenant (optional status filter)
 func (s *GrievanceService) List(ctx context.Context, tenantID string, status *string) ([]models.Grievance, error) {
 	return s.repo.List(ctx, tenantID, status)
@@ -64,7 +110,13 @@ func (s *GrievanceService) Resolve(ctx context.Context, id string, req dto.Updat
 	if req.AssignedTo != "" {
 		assignedTo = &req.AssignedTo
 	}
-	return s.repo.UpdateStatus(ctx, id, req.Status, assignedTo)
+	if err := s.repo.UpdateStatus(ctx, id, req.Status, assignedTo); err != nil {
+		return err
+	}
+	if isClosedGrievanceStatus(req.Status) {
+		return s.markResolved(ctx, id)
+	}
+	return nil
 }
 
 // Update grievance details
@@ -115,6 +167,9 @@ func (s *GrievanceService) AddComment(ctx context.Context, req dto.CreateGrievan
 	if err := s.repo.CreateComment(ctx, comment); err != nil {
 		return nil, err
 	}
+	if comment.AdminId != nil {
+		s.markResponded(ctx, req.GrievanceID)
+	}
 	return comment, nil
 }
 
@@ -128,3 +183,248 @@ func (s *GrievanceService) DeleteComment(ctx context.Context, commentID string)
 	return s.repo.DeleteComment(ctx, commentID)
 }
 
+// ===================== SLA & Escalation =====================
+
+func isClosedGrievanceStatus(status string) bool {
+	return status == "resolved" || status == "closed"
+}
+
+// applySLA stamps due dates on a new grievance from the most specific matching
+// policy, falling back to the statutory default.
+func (s *GrievanceService) applySLA(ctx context.Context, g *models.Grievance) error {
+	policies, err := s.repo.ListSLAPolicies(ctx, g.TenantID, true)
+	if err != nil {
+		return err
+	}
+	responseHours, resolutionHours := defaultGrievanceResponseHours, defaultGrievanceResolutionHours
+	if p := selectSLAPolicy(policies, g); p != nil {
+		g.SLAPolicyID = &p.ID
+		responseHours, resolutionHours = p.ResponseHours, p.ResolutionHours
+	}
+	responseDue := g.CreatedAt.Add(time.Duration(responseHours) * time.Hour)
+	resolutionDue := g.CreatedAt.Add(time.Duration(resolutionHours) * time.Hour)
+	g.ResponseDueAt = &responseDue
+	g.ResolutionDueAt = &resolutionDue
+	return nil
+}
+
+func selectSLAPolicy(policies []models.GrievanceSLAPolicy, g *models.Grievance) *models.GrievanceSLAPolicy {
+	var best *models.GrievanceSLAPolicy
+	for i := range policies {
+		p := &policies[i]
+		if !p.Matches(g) {
+			continue
+		}
+		if best == nil || p.Specificity() > best.Specificity() {
+			best = p
+		}
+	}
+	return best
+}
+
+func (s *GrievanceService) markResponded(ctx context.Context, grievanceID string) {
+	g, err := s.repo.GetByID(ctx, grievanceID)
+	if err != nil || g.FirstRespondedAt != nil {
+		return
+	}
+	now := time.Now()
+	g.FirstRespondedAt = &now
+	_ = s.repo.Save(ctx, g)
+}
+
+func (s *GrievanceService) markResolved(ctx context.Context, id string) error {
+	g, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if g.FirstRespondedAt == nil {
+		g.FirstRespondedAt = &now
+	}
+	if g.ResolvedAt == nil {
+		g.ResolvedAt = &now
+	}
+	return s.repo.Save(ctx, g)
+}
+
+// IssueDPBNotice records that the principal has been told of their right to
+// escalate to the Data Protection Board. It returns nil when the grievance is
+// not closed or the notice has already been issued.
+func (s *GrievanceService) IssueDPBNotice(ctx context.Context, id string) (*models.Grievance, error) {
+	g, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isClosedGrievanceStatus(g.Status) || g.DPBNoticeSentAt != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	g.DPBNoticeSentAt = &now
+	if err := s.repo.Save(ctx, g); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateEscalation(ctx, &models.GrievanceEscalation{
+		ID:          uuid.New(),
+		GrievanceID: g.ID,
+		TenantID:    g.TenantID,
+		Kind:        "dpb_notice",
+		Level:       g.EscalationLevel,
+		Reason:      DPBNoticeText,
+		CreatedAt:   now,
+	}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// RecordDPBComplaint keeps a record of a complaint the principal has filed with
+// the Data Protection Board about their grievance.
+func (s *GrievanceService) RecordDPBComplaint(ctx context.Context, id, userID, reference string) (*models.GrievanceEscalation, error) {
+	g, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if g.UserID.String() != userID {
+		return nil, errors.New("grievance not found")
+	}
+	if !isClosedGrievanceStatus(g.Status) && (g.ResolutionDueAt == nil || time.Now().Before(*g.ResolutionDueAt)) {
+		return nil, errors.New("the Board can be approached once the grievance is closed or its resolution period has lapsed")
+	}
+	e := &models.GrievanceEscalation{
+		ID:           uuid.New(),
+		GrievanceID:  g.ID,
+		TenantID:     g.TenantID,
+		Kind:         "dpb_complaint",
+		Level:        g.EscalationLevel,
+		Reason:       "Principal reported a complaint to the Data Protection Board",
+		DPBReference: reference,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.CreateEscalation(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *GrievanceService) ListEscalations(ctx context.Context, grievanceID string) ([]models.GrievanceEscalation, error) {
+	return s.repo.ListEscalations(ctx, grievanceID)
+}
+
+// GrievanceSLABreach describes a breach recorded by EscalateBreaches.
+type GrievanceSLABreach struct {
+	Grievance   models.Grievance
+	Kind        string // sla_response or sla_resolution
+	EscalatedTo *uuid.UUID
+}
+
+// EscalateBreaches records newly breached response and resolution deadlines,
+// raises the grievance's escalation level and hands it to the policy's
+// escalation owner.
+func (s *GrievanceService) EscalateBreaches(ctx context.Context, now time.Time) ([]GrievanceSLABreach, error) {
+	due, err := s.repo.ListSLABreaches(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	policyCache := map[uuid.UUID]*models.GrievanceSLAPolicy{}
+	var breaches []GrievanceSLABreach
+	for i := range due {
+		g := &due[i]
+		kind := "sla_resolution"
+		if g.ResolutionDueAt != nil && !g.ResolutionDueAt.After(now) && g.ResolutionBreachedAt == nil {
+			g.ResolutionBreachedAt = &now
+			if g.FirstRespondedAt == nil && g.ResponseBreachedAt == nil {
+				g.ResponseBreachedAt = &now
+			}
+		} else {
+			kind = "sla_response"
+			g.ResponseBreachedAt = &now
+		}
+
+		var escalateTo *uuid.UUID
+		if g.SLAPolicyID != nil {
+			p, ok := policyCache[*g.SLAPolicyID]
+			if !ok {
+				p, _ = s.repo.GetSLAPolicy(ctx, *g.SLAPolicyID, g.TenantID)
+				policyCache[*g.SLAPolicyID] = p
+			}
+			if p != nil && p.EscalateTo != nil {
+				escalateTo = p.EscalateTo
+				g.AssignedTo = p.EscalateTo
+			}
+		}
+		g.EscalationLevel++
+		g.EscalatedAt = &now
+		g.Status = "escalated"
+		if err := s.repo.Save(ctx, g); err != nil {
+			return breaches, err
+		}
+
+		reason := "Response deadline missed"
+		if kind == "sla_resolution" {
+			reason = "Resolution deadline missed"
+		}
+		if err := s.repo.CreateEscalation(ctx, &models.GrievanceEscalation{
+			ID:          uuid.New(),
+			GrievanceID: g.ID,
+			TenantID:    g.TenantID,
+			Kind:        kind,
+			Level:       g.EscalationLevel,
+			Reason:      reason,
+			EscalatedTo: escalateTo,
+			CreatedAt:   now,
+		}); err != nil {
+			return breaches, err
+		}
+		breaches = append(breaches, GrievanceSLABreach{Grievance: *g, Kind: kind, EscalatedTo: escalateTo})
+	}
+	return breaches, nil
+}
+
+// SLA policies
+
+func (s *GrievanceService) CreateSLAPolicy(ctx context.Context, p *models.GrievanceSLAPolicy) error {
+	if err := validateSLAPolicy(p); err != nil {
+		return err
+	}
+	p.ID = uuid.New()
+	return s.repo.CreateSLAPolicy(ctx, p)
+}
+
+func (s *GrievanceService) UpdateSLAPolicy(ctx context.Context, p *models.GrievanceSLAPolicy) error {
+	existing, err := s.repo.GetSLAPolicy(ctx, p.ID, p.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := validateSLAPolicy(p); err != nil {
+		return err
+	}
+	p.CreatedAt = existing.CreatedAt
+	return s.repo.UpdateSLAPolicy(ctx, p)
+}
+
+func (s *GrievanceService) ListSLAPolicies(ctx context.Context, tenantID uuid.UUID) ([]models.GrievanceSLAPolicy, error) {
+	policies, err := s.repo.ListSLAPolicies(ctx, tenantID, false)
+	sort.SliceStable(policies, func(i, j int) bool { return policies[i].Specificity() > policies[j].Specificity() })
+	return policies, err
+}
+
+func (s *GrievanceService) DeleteSLAPolicy(ctx context.Context, id, tenantID uuid.UUID) error {
+	return s.repo.DeleteSLAPolicy(ctx, id, tenantID)
+}
+
+func validateSLAPolicy(p *models.GrievanceSLAPolicy) error {
+	if p.Name == "" {
+		return errors.New("policy name is required")
+	}
+	if p.ResponseHours <= 0 || p.ResolutionHours <= 0 {
+		return errors.New("response and resolution hours must be positive")
+	}
+	if p.ResponseHours > p.ResolutionHours {
+		return errors.New("response deadline cannot be later than the resolution deadline")
+	}
+	if p.ResolutionHours > defaultGrievanceResolutionHours {
+		return fmt.Errorf("resolution deadline cannot exceed the statutory %d hours", defaultGrievanceResolutionHours)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pixpivot/arc/internal/db"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GrievanceSLAMonitor periodically sweeps every tenant database for grievances
+// that have missed their response or resolution deadline, escalates them and
+// alerts the people responsible.
+type GrievanceSLAMonitor struct {
+	masterDB            *gorm.DB
+	notificationService *NotificationService
+}
+
+func NewGrievanceSLAMonitor(masterDB *gorm.DB, notificationService *NotificationService) *GrievanceSLAMonitor {
+	return &GrievanceSLAMonitor{
+		masterDB:            masterDB,
+		notificationService: notificationService,
+	}
+}
+
+// RegisterJobs schedules the SLA sweep every 15 minutes.
+func (m *GrievanceSLAMonitor) RegisterJobs(r *JobRunner) {
+	r.Register("grievance.sla", func(ctx context.Context, job *models.Job) error {
+		n, err := m.Run(time.Now())
+		if n > 0 {
+			log.Logger.Info().Int("escalated", n).Msg("Grievance SLA sweep complete")
+		}
+		return err
+	})
+	r.Schedule("grievance.sla", "*/15 * * * *", "grievance.sla", nil)
+}
+
+// Run sweeps all tenants and returns the number of grievances escalated.
+// A tenant whose database cannot be reached is skipped.
+func (m *GrievanceSLAMonitor) Run(now time.Time) (int, error) {
+	var tenants []models.Tenant
+	if err := m.masterDB.Find(&tenants).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, t := range tenants {
+		tenantDB, err := db.GetTenantDB("tenant_" + t.TenantID.String()[:8])
+		if err != nil {
+			log.Logger.Warn().Err(err).Str("tenant_id", t.TenantID.String()).Msg("Skipping tenant in grievance SLA sweep")
+			continue
+		}
+		n, err := m.SweepTenant(tenantDB, now)
+		if err != nil {
+			log.Logger.Error().Err(err).Str("tenant_id", t.TenantID.String()).Msg("Grievance SLA sweep failed for tenant")
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// SweepTenant escalates breached grievances in one tenant database.
+func (m *GrievanceSLAMonitor) SweepTenant(tenantDB *gorm.DB, now time.Time) (int, error) {
+	svc := NewGrievanceService(repository.NewGrievanceRepo(tenantDB))
+	breaches, err := svc.EscalateBreaches(context.Background(), now)
+	for _, b := range breaches {
+		m.alert(b)
+	}
+	return len(breaches), err
+}
+
+func (m *GrievanceSLAMonitor) alert(b GrievanceSLABreach) {
+	if m.notificationService == nil {
+		return
+	}
+	what := "response"
+	if b.Kind == "sla_resolution" {
+		what = "resolution"
+	}
+	body := fmt.Sprintf("Grievance %q missed its %s deadline and has been escalated (level %d).",
+		b.Grievance.GrievanceSubject, what, b.Grievance.EscalationLevel)
+
+	recipients := map[uuid.UUID]bool{}
+	if b.EscalatedTo != nil {
+		recipients[*b.EscalatedTo] = true
+	}
+	if b.Grievance.AssignedTo != nil {
+		recipients[*b.Grievance.AssignedTo] = true
+	}
+	for userID := range recipients {
+		_ = m.notificationService.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     "Grievance SLA breached",
+			Body:      body,
+			Icon:      "alert-triangle",
+			Link:      "/grievances/" + b.Grievance.ID.String(),
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+}
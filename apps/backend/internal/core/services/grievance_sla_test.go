@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupGrievanceSLA(t *testing.T) (*GrievanceService, *gorm.DB, uuid.UUID) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Grievance{}, &models.GrievanceSLAPolicy{}, &models.GrievanceEscalation{}))
+	return NewGrievanceService(repository.NewGrievanceRepo(db)), db, uuid.New()
+}
+
+func TestApplySLA_MostSpecificPolicyWins(t *testing.T) {
+	svc, _, tenantID := setupGrievanceSLA(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreateSLAPolicy(ctx, &models.GrievanceSLAPolicy{TenantID: tenantID, Name: "Default", ResponseHours: 48, ResolutionHours: 720, IsActive: true}))
+	require.NoError(t, svc.CreateSLAPolicy(ctx, &models.GrievanceSLAPolicy{TenantID: tenantID, Name: "Urgent", Priority: "urgent", ResponseHours: 4, ResolutionHours: 72, IsActive: true}))
+	specific := &models.GrievanceSLAPolicy{TenantID: tenantID, Name: "Urgent billing", Category: "billing", Priority: "urgent", ResponseHours: 2, ResolutionHours: 24, IsActive: true}
+	require.NoError(t, svc.CreateSLAPolicy(ctx, specific))
+
+	created := time.Now()
+	g := &models.Grievance{TenantID: tenantID, Category: "billing", Priority: "urgent", CreatedAt: created}
+	require.NoError(t, svc.applySLA(ctx, g))
+	require.NotNil(t, g.SLAPolicyID)
+	assert.Equal(t, specific.ID, *g.SLAPolicyID)
+	assert.WithinDuration(t, created.Add(2*time.Hour), *g.ResponseDueAt, time.Second)
+
+	// With no tenant policies the statutory default applies
+	other, _, _ := setupGrievanceSLA(t)
+	g2 := &models.Grievance{TenantID: uuid.New(), CreatedAt: created}
+	require.NoError(t, other.applySLA(ctx, g2))
+	assert.Nil(t, g2.SLAPolicyID)
+	assert.WithinDuration(t, created.Add(90*24*time.Hour), *g2.ResolutionDueAt, time.Second)
+
+	assert.Error(t, svc.CreateSLAPolicy(ctx, &models.GrievanceSLAPolicy{TenantID: tenantID, Name: "Too slow", ResponseHours: 24, ResolutionHours: 100 * 24}))
+}
+
+func TestEscalateBreaches_EscalatesOnceAndReassigns(t *testing.T) {
+	svc, db, tenantID := setupGrievanceSLA(t)
+	ctx := context.Background()
+	owner := uuid.New()
+
+	policy := &models.GrievanceSLAPolicy{TenantID: tenantID, Name: "Default", ResponseHours: 24, ResolutionHours: 240, EscalateTo: &owner, IsActive: true}
+	require.NoError(t, svc.CreateSLAPolicy(ctx, policy))
+
+	created := time.Now().Add(-48 * time.Hour)
+	g := &models.Grievance{ID: uuid.New(), UserID: uuid.New(), TenantID: tenantID, Status: "open", CreatedAt: created}
+	require.NoError(t, svc.applySLA(ctx, g))
+	require.NoError(t, db.Create(g).Error)
+
+	now := time.Now()
+	breaches, err := svc.EscalateBreaches(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	assert.Equal(t, "sla_response", breaches[0].Kind)
+
+	var got models.Grievance
+	require.NoError(t, db.First(&got, "id = ?", g.ID).Error)
+	assert.Equal(t, "escalated", got.Status)
+	assert.Equal(t, 1, got.EscalationLevel)
+	require.NotNil(t, got.AssignedTo)
+	assert.Equal(t, owner, *got.AssignedTo)
+
+	// Already-recorded breaches are not escalated again
+	breaches, err = svc.EscalateBreaches(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, breaches)
+
+	// Missing the resolution deadline escalates a second time
+	breaches, err = svc.EscalateBreaches(ctx, now.Add(10*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	assert.Equal(t, "sla_resolution", breaches[0].Kind)
+	assert.Equal(t, 2, breaches[0].Grievance.EscalationLevel)
+
+	escalations, err := svc.ListEscalations(ctx, g.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, escalations, 2)
+}
+
+func TestIssueDPBNotice_OnlyOnceAfterClosure(t *testing.T) {
+	svc, db, tenantID := setupGrievanceSLA(t)
+	ctx := context.Background()
+
+	g := &models.Grievance{ID: uuid.New(), UserID: uuid.New(), TenantID: tenantID, Status: "in_progress", CreatedAt: time.Now()}
+	require.NoError(t, db.Create(g).Error)
+
+	notice, err := svc.IssueDPBNotice(ctx, g.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, notice, "open grievances do not get a DPB notice")
+
+	_, err = svc.RecordDPBComplaint(ctx, g.ID.String(), g.UserID.String(), "DPB/2026/1")
+	assert.Error(t, err, "the Board cannot be approached while the grievance is open and within SLA")
+
+	require.NoError(t, db.Model(g).Update("status", "closed").Error)
+	notice, err = svc.IssueDPBNotice(ctx, g.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, notice)
+	assert.NotNil(t, notice.DPBNoticeSentAt)
+
+	notice, err = svc.IssueDPBNotice(ctx, g.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, notice)
+
+	e, err := svc.RecordDPBComplaint(ctx, g.ID.String(), g.UserID.String(), "DPB/2026/1")
+	require.NoError(t, err)
+	assert.Equal(t, "dpb_complaint", e.Kind)
+}
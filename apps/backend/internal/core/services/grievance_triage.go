@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/db"
+	"pixpivot/arc/internal/dto"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	repeatComplainantWindow    = 90 * 24 * time.Hour
+	repeatComplainantThreshold = 2 // Earlier grievances within the window
+	triageStatsWindow          = 90 * 24 * time.Hour
+)
+
+// grievancePriorities in ascending order of urgency.
+var grievancePriorities = []string{"low", "medium", "high", "urgent"}
+
+// closedBreachStatuses are breach statuses that no longer link new grievances.
+var closedBreachStatuses = []string{"draft", "rejected", "resolved", "closed", "Resolved", "Closed"}
+
+// builtinTriageCategories apply when no tenant rule sets a category, checked in
+// order so a breach complaint that mentions deletion is still a breach.
+// Keywords match whole words, so "bill" does not fire on "mobile".
+var builtinTriageCategories = []struct {
+	Category string
+	Keywords *regexp.Regexp
+}{
+	{"breach", wordPattern("breach", "breached", "leak", "leaked", "hacked", "compromised", "stolen", "unauthorised access", "unauthorized access", "exposed")},
+	{"dsr", wordPattern("delete my", "erase", "erasure", "access my data", "copy of my data", "correct my", "rectify", "rectification", "portability", "nominee")},
+	{"consent", wordPattern("consent", "withdraw", "opt out", "opt-out", "unsubscribe", "marketing", "without my permission")},
+	{"billing", wordPattern("bill", "bills", "billed", "billing", "invoice", "charged", "refund", "payment", "subscription fee")},
+}
+
+// builtinUrgencyKeywords raise priority regardless of category.
+var builtinUrgencyKeywords = []struct {
+	Priority string
+	Keywords *regexp.Regexp
+}{
+	{"urgent", wordPattern("identity theft", "fraud", "blackmail", "harass", "harassed", "harassing", "harassment", "child", "children")},
+	{"high", wordPattern("urgent", "immediately", "lawyer", "legal action", "court", "police")},
+}
+
+// wordPattern matches any of the lower-case phrases as whole words.
+func wordPattern(phrases ...string) *regexp.Regexp {
+	quoted := make([]string, len(phrases))
+	for i, p := range phrases {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// GrievanceTriageResult is the triage engine's decision for one grievance.
+type GrievanceTriageResult struct {
+	Category       string      `json:"category"`
+	CategorySource string      `json:"categorySource"`
+	Priority       string      `json:"priority"`
+	PrioritySource string      `json:"prioritySource"`
+	AssignedTo     *uuid.UUID  `json:"assignedTo,omitempty"`
+	AssigneeSource string      `json:"assigneeSource,omitempty"`
+	LinkedBreachID *uuid.UUID  `json:"linkedBreachId,omitempty"`
+	MatchedRuleIDs []uuid.UUID `json:"matchedRuleIds"`
+	Signals        []string    `json:"signals"`
+	Reasons        []string    `json:"reasons"`
+}
+
+// triageInput is what the engine looks at besides the tenant's rules.
+type triageInput struct {
+	Subject         string
+	Description     string
+	PriorGrievances int64      // From the same principal within repeatComplainantWindow
+	ActiveBreachID  *uuid.UUID // Most recent open breach for the tenant
+}
+
+func priorityRank(p string) int {
+	for i, v := range grievancePriorities {
+		if v == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func ruleMatches(rule *models.GrievanceTriageRule, text string) bool {
+	switch rule.MatchType {
+	case "regex":
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		return err == nil && re.MatchString(text)
+	default:
+		for _, k := range strings.Split(rule.Pattern, ",") {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(text, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evaluateTriage runs tenant rules, then built-in keywords, then signals.
+func evaluateTriage(rules []models.GrievanceTriageRule, in triageInput) GrievanceTriageResult {
+	res := GrievanceTriageResult{MatchedRuleIDs: []uuid.UUID{}, Signals: []string{}, Reasons: []string{}}
+	text := strings.ToLower(in.Subject + "\n" + in.Description)
+	raise := func(priority, source, reason string) {
+		if priorityRank(priority) > priorityRank(res.Priority) {
+			res.Priority, res.PrioritySource = priority, source
+			res.Reasons = append(res.Reasons, reason)
+		}
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.IsActive || !ruleMatches(rule, text) {
+			continue
+		}
+		source := "rule:" + rule.ID.String()
+		res.MatchedRuleIDs = append(res.MatchedRuleIDs, rule.ID)
+		if rule.Category != "" && res.Category == "" {
+			res.Category, res.CategorySource = rule.Category, source
+			res.Reasons = append(res.Reasons, fmt.Sprintf("Rule %q set category %s", rule.Name, rule.Category))
+		}
+		if rule.Priority != "" {
+			raise(rule.Priority, source, fmt.Sprintf("Rule %q set priority %s", rule.Name, rule.Priority))
+		}
+		if rule.AssignTo != nil && res.AssignedTo == nil {
+			res.AssignedTo, res.AssigneeSource = rule.AssignTo, source
+			res.Reasons = append(res.Reasons, fmt.Sprintf("Rule %q assigned the grievance", rule.Name))
+		}
+	}
+
+	if res.Category == "" {
+		for _, c := range builtinTriageCategories {
+			if k := c.Keywords.FindString(text); k != "" {
+				res.Category, res.CategorySource = c.Category, "builtin:"+c.Category
+				res.Reasons = append(res.Reasons, fmt.Sprintf("Mentions %q, classified as %s", k, c.Category))
+				break
+			}
+		}
+	}
+	if res.Category == "" {
+		res.Category, res.CategorySource = "general", "default"
+	}
+
+	for _, u := range builtinUrgencyKeywords {
+		if k := u.Keywords.FindString(text); k != "" {
+			raise(u.Priority, "builtin:urgency", fmt.Sprintf("Mentions %q, priority %s", k, u.Priority))
+		}
+	}
+	if res.Priority == "" {
+		res.Priority, res.PrioritySource = "medium", "default"
+	}
+
+	// Signals
+	if in.ActiveBreachID != nil && res.Category == "breach" {
+		res.LinkedBreachID = in.ActiveBreachID
+		res.Signals = append(res.Signals, "active_breach")
+		res.Reasons = append(res.Reasons, "Linked to active breach "+in.ActiveBreachID.String())
+		raise("urgent", "signal:active_breach", "Concerns an active breach, priority urgent")
+	}
+	if in.PriorGrievances >= repeatComplainantThreshold {
+		res.Signals = append(res.Signals, "repeat_complainant")
+		res.Reasons = append(res.Reasons, fmt.Sprintf("Principal raised %d other grievances in the last 90 days", in.PriorGrievances))
+		if next := priorityRank(res.Priority) + 1; next < len(grievancePriorities) {
+			raise(grievancePriorities[next], "signal:repeat_complainant", "Repeat complainant, priority raised to "+grievancePriorities[next])
+		}
+	}
+	return res
+}
+
+// activeBreachForTenant returns the most recently detected open breach.
+// Breaches live in the master database.
+func activeBreachForTenant(tenantID uuid.UUID) (*uuid.UUID, error) {
+	if db.MasterDB == nil {
+		return nil, nil
+	}
+	return openBreachIn(db.MasterDB, tenantID)
+}
+
+// openBreachIn returns the tenant's most recently detected open breach in
+// masterDB. Simulated breaches from drills are not real incidents and are
+// never linked.
+func openBreachIn(masterDB *gorm.DB, tenantID uuid.UUID) (*uuid.UUID, error) {
+	var breach models.EncryptedBreachNotification
+	err := masterDB.Select("id").
+		Where("tenant_id = ? AND status NOT IN ? AND is_simulation = ?", tenantID, closedBreachStatuses, false).
+		Order("detection_date DESC").
+		First(&breach).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &breach.ID, nil
+}
+
+// triageInputFor gathers signals for a new grievance. Signal lookups that fail
+// are logged and skipped so a grievance is never lost to triage.
+func (s *GrievanceService) triageInputFor(ctx context.Context, g *models.Grievance) triageInput {
+	in := triageInput{Subject: g.GrievanceSubject, Description: g.GrievanceDescription}
+	if n, err := s.repo.CountForUserSince(ctx, g.UserID, g.CreatedAt.Add(-repeatComplainantWindow)); err == nil {
+		in.PriorGrievances = n
+	} else {
+		log.Logger.Warn().Err(err).Msg("triage: failed to count prior grievances")
+	}
+	if s.activeBreach != nil {
+		if id, err := s.activeBreach(g.TenantID); err == nil {
+			in.ActiveBreachID = id
+		} else {
+			log.Logger.Warn().Err(err).Msg("triage: failed to look up active breaches")
+		}
+	}
+	return in
+}
+
+// triage classifies a new grievance in place and returns the decision to store
+// once the grievance is saved.
+func (s *GrievanceService) triage(ctx context.Context, g *models.Grievance) *models.GrievanceTriageDecision {
+	rules, err := s.repo.ListTriageRules(ctx, g.TenantID, true)
+	if err != nil {
+		log.Logger.Warn().Err(err).Msg("triage: failed to load rules, using built-in rules only")
+	}
+	res := evaluateTriage(rules, s.triageInputFor(ctx, g))
+	// What the principal picked stands when nothing more specific matched
+	if res.CategorySource == "default" && g.Category != "" {
+		res.Category, res.CategorySource = g.Category, "reported"
+	}
+	if res.PrioritySource == "default" && priorityRank(g.Priority) >= 0 {
+		res.Priority, res.PrioritySource = g.Priority, "reported"
+	}
+
+	d := &models.GrievanceTriageDecision{
+		ID:               uuid.New(),
+		GrievanceID:      g.ID,
+		TenantID:         g.TenantID,
+		ReportedCategory: g.Category,
+		ReportedPriority: g.Priority,
+		Category:         res.Category,
+		CategorySource:   res.CategorySource,
+		Priority:         res.Priority,
+		PrioritySource:   res.PrioritySource,
+		AssignedTo:       res.AssignedTo,
+		AssigneeSource:   res.AssigneeSource,
+		LinkedBreachID:   res.LinkedBreachID,
+	}
+	d.MatchedRuleIDs, _ = json.Marshal(res.MatchedRuleIDs)
+	d.Signals, _ = json.Marshal(res.Signals)
+	d.Reasons, _ = json.Marshal(res.Reasons)
+
+	g.Category, g.Priority = res.Category, res.Priority
+	g.AssignedTo = res.AssignedTo
+	g.LinkedBreachID = res.LinkedBreachID
+	return d
+}
+
+// PreviewTriage runs the engine on sample text without saving anything, for
+// testing rules before enabling them.
+func (s *GrievanceService) PreviewTriage(ctx context.Context, tenantID uuid.UUID, subject, description string) (*GrievanceTriageResult, error) {
+	rules, err := s.repo.ListTriageRules(ctx, tenantID, true)
+	if err != nil {
+		return nil, err
+	}
+	res := evaluateTriage(rules, triageInput{Subject: subject, Description: description})
+	return &res, nil
+}
+
+// GetTriage returns the engine's decision for a grievance and any overrides.
+func (s *GrievanceService) GetTriage(ctx context.Context, g *models.Grievance) (*models.GrievanceTriageDecision, []models.GrievanceTriageOverride, error) {
+	d, err := s.repo.GetTriageDecision(ctx, g.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		d, err = nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	overrides, err := s.repo.ListTriageOverrides(ctx, g.TenantID, &g.ID, time.Time{})
+	return d, overrides, err
+}
+
+// ReviewTriage applies a reviewer's corrections to category, priority or
+// assignee and records each changed field as an override.
+func (s *GrievanceService) ReviewTriage(ctx context.Context, grievanceID string, reviewerID uuid.UUID, req dto.GrievanceTriageReviewRequest) ([]models.GrievanceTriageOverride, error) {
+	g, err := s.repo.GetByID(ctx, grievanceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Priority != "" && priorityRank(req.Priority) < 0 {
+		return nil, fmt.Errorf("priority must be one of %s", strings.Join(grievancePriorities, ", "))
+	}
+	var assignee *uuid.UUID
+	if req.AssignedTo != "" {
+		id, err := uuid.Parse(req.AssignedTo)
+		if err != nil {
+			return nil, errors.New("invalid assignee")
+		}
+		assignee = &id
+	}
+
+	decision, err := s.repo.GetTriageDecision(ctx, g.ID)
+	if err != nil {
+		decision = nil
+	}
+	var overrides []models.GrievanceTriageOverride
+	record := func(field, from, to, decided, source string) {
+		o := models.GrievanceTriageOverride{
+			ID:          uuid.New(),
+			GrievanceID: g.ID,
+			TenantID:    g.TenantID,
+			Field:       field,
+			FromValue:   from,
+			ToValue:     to,
+			ReviewerID:  reviewerID,
+			Reason:      req.Reason,
+		}
+		if decision != nil {
+			o.DecisionID = &decision.ID
+			// Only attribute the override to the engine if its value was still in place
+			if from == decided {
+				o.Source = source
+			}
+		}
+		overrides = append(overrides, o)
+	}
+
+	if req.Category != "" && req.Category != g.Category {
+		var decided, source string
+		if decision != nil {
+			decided, source = decision.Category, decision.CategorySource
+		}
+		record("category", g.Category, req.Category, decided, source)
+		g.Category = req.Category
+	}
+	if req.Priority != "" && req.Priority != g.Priority {
+		var decided, source string
+		if decision != nil {
+			decided, source = decision.Priority, decision.PrioritySource
+		}
+		record("priority", g.Priority, req.Priority, decided, source)
+		g.Priority = req.Priority
+	}
+	if assignee != nil && (g.AssignedTo == nil || *g.AssignedTo != *assignee) {
+		var from, decided, source string
+		if g.AssignedTo != nil {
+			from = g.AssignedTo.String()
+		}
+		if decision != nil {
+			if decision.AssignedTo != nil {
+				decided = decision.AssignedTo.String()
+			}
+			source = decision.AssigneeSource
+		}
+		record("assignee", from, assignee.String(), decided, source)
+		g.AssignedTo = assignee
+	}
+	if len(overrides) == 0 {
+		return overrides, nil
+	}
+
+	g.UpdatedAt = time.Now()
+	if err := s.repo.Save(ctx, g); err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		if err := s.repo.CreateTriageOverride(ctx, &overrides[i]); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+// GrievanceTriageRuleStats shows how often a rule's output was accepted or
+// corrected by reviewers.
+type GrievanceTriageRuleStats struct {
+	Source     string                      `json:"source"` // rule:<id>, builtin:<name>, signal:<name>, default
+	Rule       *models.GrievanceTriageRule `json:"rule,omitempty"`
+	Applied    int                         `json:"applied"`    // Values this source set on grievances
+	Overridden int                         `json:"overridden"` // Of those, values a reviewer changed
+}
+
+// TriageStats summarises the last 90 days of decisions and overrides per source.
+func (s *GrievanceService) TriageStats(ctx context.Context, tenantID uuid.UUID) ([]GrievanceTriageRuleStats, error) {
+	since := time.Now().Add(-triageStatsWindow)
+	decisions, err := s.repo.ListTriageDecisions(ctx, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.repo.ListTriageOverrides(ctx, tenantID, nil, since)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.repo.ListTriageRules(ctx, tenantID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := map[string]*GrievanceTriageRuleStats{}
+	var order []string
+	get := func(source string) *GrievanceTriageRuleStats {
+		if st, ok := stats[source]; ok {
+			return st
+		}
+		st := &GrievanceTriageRuleStats{Source: source}
+		stats[source] = st
+		order = append(order, source)
+		return st
+	}
+	for i := range rules {
+		get("rule:" + rules[i].ID.String()).Rule = &rules[i]
+	}
+	for _, d := range decisions {
+		for _, source := range []string{d.CategorySource, d.PrioritySource, d.AssigneeSource} {
+			if source != "" {
+				get(source).Applied++
+			}
+		}
+	}
+	for _, o := range overrides {
+		if o.Source != "" {
+			get(o.Source).Overridden++
+		}
+	}
+
+	out := make([]GrievanceTriageRuleStats, 0, len(order))
+	for _, source := range order {
+		out = append(out, *stats[source])
+	}
+	return out, nil
+}
+
+// Triage rules
+
+func (s *GrievanceService) CreateTriageRule(ctx context.Context, rule *models.GrievanceTriageRule) error {
+	if err := validateTriageRule(rule); err != nil {
+		return err
+	}
+	rule.ID = uuid.New()
+	return s.repo.CreateTriageRule(ctx, rule)
+}
+
+func (s *GrievanceService) UpdateTriageRule(ctx context.Context, rule *models.GrievanceTriageRule) error {
+	existing, err := s.repo.GetTriageRule(ctx, rule.ID, rule.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := validateTriageRule(rule); err != nil {
+		return err
+	}
+	rule.CreatedAt = existing.CreatedAt
+	return s.repo.UpdateTriageRule(ctx, rule)
+}
+
+func (s *GrievanceService) ListTriageRules(ctx context.Context, tenantID uuid.UUID) ([]models.GrievanceTriageRule, error) {
+	return s.repo.ListTriageRules(ctx, tenantID, false)
+}
+
+func (s *GrievanceService) DeleteTriageRule(ctx context.Context, id, tenantID uuid.UUID) error {
+	return s.repo.DeleteTriageRule(ctx, id, tenantID)
+}
+
+func validateTriageRule(rule *models.GrievanceTriageRule) error {
+	if rule.Name == "" {
+		return errors.New("rule name is required")
+	}
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return errors.New("pattern is required")
+	}
+	switch rule.MatchType {
+	case "keyword":
+	case "regex":
+		if _, err := regexp.Compile("(?i)" + rule.Pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	default:
+		return errors.New("match type must be keyword or regex")
+	}
+	if rule.Priority != "" && priorityRank(rule.Priority) < 0 {
+		return fmt.Errorf("priority must be one of %s", strings.Join(grievancePriorities, ", "))
+	}
+	if rule.Category == "" && rule.Priority == "" && rule.AssignTo == nil {
+		return errors.New("rule must set a category, priority or assignee")
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"pixpivot/arc/internal/dto"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupGrievanceTriage(t *testing.T) (*GrievanceService, *gorm.DB, uuid.UUID) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Grievance{}, &models.GrievanceTriageRule{},
+		&models.GrievanceTriageDecision{}, &models.GrievanceTriageOverride{}))
+	svc := NewGrievanceService(repository.NewGrievanceRepo(db))
+	svc.activeBreach = func(uuid.UUID) (*uuid.UUID, error) { return nil, nil }
+	return svc, db, uuid.New()
+}
+
+func TestEvaluateTriage_RulesBuiltinsAndSignals(t *testing.T) {
+	billingTeam := uuid.New()
+	rules := []models.GrievanceTriageRule{
+		{ID: uuid.New(), Name: "Refunds", MatchType: "keyword", Pattern: "refund, chargeback", Category: "billing", AssignTo: &billingTeam, IsActive: true},
+		{ID: uuid.New(), Name: "Regulator", MatchType: "regex", Pattern: `\b(dpb|data protection board)\b`, Priority: "high", IsActive: true},
+		{ID: uuid.New(), Name: "Disabled", MatchType: "keyword", Pattern: "refund", Category: "other", IsActive: false},
+	}
+
+	res := evaluateTriage(rules, triageInput{Subject: "Refund not processed", Description: "I will go to the Data Protection Board"})
+	assert.Equal(t, "billing", res.Category)
+	assert.Equal(t, "rule:"+rules[0].ID.String(), res.CategorySource)
+	assert.Equal(t, "high", res.Priority)
+	assert.Equal(t, "rule:"+rules[1].ID.String(), res.PrioritySource)
+	require.NotNil(t, res.AssignedTo)
+	assert.Equal(t, billingTeam, *res.AssignedTo)
+	assert.Len(t, res.MatchedRuleIDs, 2)
+
+	// No tenant rule matches: built-ins classify, breach wins over deletion
+	res = evaluateTriage(rules, triageInput{Subject: "Account hacked", Description: "Please delete my data"})
+	assert.Equal(t, "breach", res.Category)
+	assert.Equal(t, "builtin:breach", res.CategorySource)
+	assert.Equal(t, "medium", res.Priority)
+
+	breachID := uuid.New()
+	res = evaluateTriage(nil, triageInput{Subject: "My data was leaked", ActiveBreachID: &breachID})
+	assert.Equal(t, "urgent", res.Priority)
+	assert.Equal(t, "signal:active_breach", res.PrioritySource)
+	require.NotNil(t, res.LinkedBreachID)
+	assert.Equal(t, breachID, *res.LinkedBreachID)
+
+	res = evaluateTriage(nil, triageInput{Subject: "Still getting marketing emails", PriorGrievances: 3})
+	assert.Equal(t, "consent", res.Category)
+	assert.Equal(t, "high", res.Priority, "repeat complainants are bumped one level")
+	assert.Contains(t, res.Signals, "repeat_complainant")
+
+	res = evaluateTriage(nil, triageInput{Subject: "Question", Description: "How long do you keep logs?"})
+	assert.Equal(t, "general", res.Category)
+	assert.Equal(t, "default", res.CategorySource)
+
+	// Built-in keywords match whole words only
+	res = evaluateTriage(nil, triageInput{Subject: "Mobile app keeps crashing", Description: "The courtyard map never loads"})
+	assert.Equal(t, "general", res.Category)
+	assert.Equal(t, "medium", res.Priority)
+	res = evaluateTriage(nil, triageInput{Subject: "Rectification of my address"})
+	assert.Equal(t, "dsr", res.Category)
+	assert.Equal(t, []string{`Mentions "rectification", classified as dsr`}, res.Reasons)
+}
+
+func TestTriage_RecordsDecisionAndKeepsReportedValues(t *testing.T) {
+	svc, db, tenantID := setupGrievanceTriage(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	breachID := uuid.New()
+	svc.activeBreach = func(uuid.UUID) (*uuid.UUID, error) { return &breachID, nil }
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&models.Grievance{ID: uuid.New(), UserID: userID, TenantID: tenantID, Status: "open", CreatedAt: time.Now().Add(-24 * time.Hour)}).Error)
+	}
+	g := &models.Grievance{ID: uuid.New(), UserID: userID, TenantID: tenantID, GrievanceSubject: "Breach notice", GrievanceDescription: "Was my data exposed?", Priority: "low", CreatedAt: time.Now()}
+	d := svc.triage(ctx, g)
+	assert.Equal(t, "breach", g.Category)
+	assert.Equal(t, "urgent", g.Priority, "active breach sets urgent; repeat bump caps at urgent")
+	assert.Equal(t, &breachID, g.LinkedBreachID)
+	assert.Equal(t, "low", d.ReportedPriority)
+	assert.JSONEq(t, `["active_breach","repeat_complainant"]`, string(d.Signals))
+
+	// Nothing matches: the principal's own category and priority stand
+	g2 := &models.Grievance{ID: uuid.New(), UserID: uuid.New(), TenantID: tenantID, GrievanceSubject: "Question", Category: "other", Priority: "low", CreatedAt: time.Now()}
+	d2 := svc.triage(ctx, g2)
+	assert.Equal(t, "other", g2.Category)
+	assert.Equal(t, "reported", d2.CategorySource)
+	assert.Equal(t, "low", g2.Priority)
+}
+
+func TestReviewTriage_RecordsOverridesAndStats(t *testing.T) {
+	svc, db, tenantID := setupGrievanceTriage(t)
+	ctx := context.Background()
+	reviewer, teamA, teamB := uuid.New(), uuid.New(), uuid.New()
+
+	rule := &models.GrievanceTriageRule{TenantID: tenantID, Name: "Billing", MatchType: "keyword", Pattern: "invoice", Category: "billing", AssignTo: &teamA, IsActive: true}
+	require.NoError(t, svc.CreateTriageRule(ctx, rule))
+	assert.Error(t, svc.CreateTriageRule(ctx, &models.GrievanceTriageRule{TenantID: tenantID, Name: "Bad", MatchType: "regex", Pattern: "(", Category: "x"}))
+	assert.Error(t, svc.CreateTriageRule(ctx, &models.GrievanceTriageRule{TenantID: tenantID, Name: "Bad", MatchType: "keyword", Pattern: "x", Priority: "critical"}))
+
+	g := &models.Grievance{ID: uuid.New(), UserID: uuid.New(), TenantID: tenantID, Status: "open", GrievanceSubject: "Wrong invoice", CreatedAt: time.Now()}
+	decision := svc.triage(ctx, g)
+	require.NoError(t, db.Create(g).Error)
+	require.NoError(t, svc.repo.CreateTriageDecision(ctx, decision))
+
+	overrides, err := svc.ReviewTriage(ctx, g.ID.String(), reviewer, dto.GrievanceTriageReviewRequest{Category: "consent", AssignedTo: teamB.String(), Reason: "About marketing consent"})
+	require.NoError(t, err)
+	require.Len(t, overrides, 2)
+	ruleSource := "rule:" + rule.ID.String()
+	for _, o := range overrides {
+		assert.Equal(t, ruleSource, o.Source)
+		assert.Equal(t, decision.ID, *o.DecisionID)
+	}
+
+	// A second correction of a hand-set value is not blamed on the rule
+	overrides, err = svc.ReviewTriage(ctx, g.ID.String(), reviewer, dto.GrievanceTriageReviewRequest{Category: "dsr"})
+	require.NoError(t, err)
+	require.Len(t, overrides, 1)
+	assert.Empty(t, overrides[0].Source)
+
+	saved, err := svc.GetByID(ctx, g.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "dsr", saved.Category)
+	assert.Equal(t, teamB, *saved.AssignedTo)
+
+	_, err = svc.ReviewTriage(ctx, g.ID.String(), reviewer, dto.GrievanceTriageReviewRequest{Priority: "critical"})
+	assert.Error(t, err)
+
+	stats, err := svc.TriageStats(ctx, tenantID)
+	require.NoError(t, err)
+	var ruleStats *GrievanceTriageRuleStats
+	for i := range stats {
+		if stats[i].Source == ruleSource {
+			ruleStats = &stats[i]
+		}
+	}
+	require.NotNil(t, ruleStats)
+	assert.Equal(t, 2, ruleStats.Applied)
+	assert.Equal(t, 2, ruleStats.Overridden)
+	assert.Equal(t, "Billing", ruleStats.Rule.Name)
+}
+
+func TestOpenBreachIn_SkipsSimulations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.EncryptedBreachNotification{}))
+	tenantID := uuid.New()
+
+	actual := models.EncryptedBreachNotification{ID: uuid.New(), TenantID: tenantID, Status: "Investigating", DetectionDate: time.Now().Add(-48 * time.Hour)}
+	drill := models.EncryptedBreachNotification{ID: uuid.New(), TenantID: tenantID, Status: "Investigating", DetectionDate: time.Now(), IsSimulation: true}
+	require.NoError(t, db.Create(&actual).Error)
+	require.NoError(t, db.Create(&drill).Error)
+
+	// The newer open breach is a drill, so the earlier real one is linked
+	id, err := openBreachIn(db, tenantID)
+	require.NoError(t, err)
+	require.NotNil(t, id)
+	assert.Equal(t, actual.ID, *id)
+
+	require.NoError(t, db.Model(&actual).Update("status", "Resolved").Error)
+	id, err = openBreachIn(db, tenantID)
+	require.NoError(t, err)
+	assert.Nil(t, id, "an open simulation alone is not an active breach")
+}
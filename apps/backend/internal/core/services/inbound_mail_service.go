@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/db"
+	"pixpivot/arc/internal/dto"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/email"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxInboundAttachmentSize = 10 << 20
+
+// threadTokenPattern matches the references quoted in email subjects,
+// e.g. "[GRV-7K2M9Q4X]" or "DSR-7K2M9Q4X".
+var threadTokenPattern = regexp.MustCompile(`\b(GRV|DSR)-[A-HJ-NP-Z2-9]{8}\b`)
+
+// dsrEmailKeywords classifies a new email as a data subject request. Types use
+// the dashboard names so approval handles them the same way.
+var dsrEmailKeywords = []struct {
+	Type     string
+	Keywords []string
+}{
+	{"Data Deletion", []string{"delete my", "erase my", "erasure", "remove my data", "remove my personal", "right to be forgotten"}},
+	{"Data Correction", []string{"correct my", "rectify", "rectification", "incorrect data", "update my details"}},
+	{"Data Portability", []string{"portability", "port my data", "transfer my data"}},
+	{"Data Access", []string{"access my data", "copy of my data", "copy of my personal", "what data do you hold", "summary of my personal data", "access request"}},
+}
+
+// TenantInboundAddress returns the tenant's plus-address on the shared inbound
+// mailbox, e.g. grievance+<tenant>.<mac>@example.com. Mail is only filed for a
+// tenant when it reaches this address, and the MAC stops senders from
+// addressing another tenant by editing the tenant ID.
+func TenantInboundAddress(base, secret string, tenantID uuid.UUID) string {
+	at := strings.LastIndex(base, "@")
+	if at <= 0 || secret == "" {
+		return base
+	}
+	local, domain := base[:at], base[at:]
+	id := strings.ReplaceAll(tenantID.String(), "-", "")
+	return local + "+" + id + "." + inboundAddressMAC(secret, id) + domain
+}
+
+// tenantFromInboundAddress returns the tenant a recipient address was signed
+// for, or false when it is not a valid plus-address on the base mailbox.
+func tenantFromInboundAddress(base, secret, addr string) (uuid.UUID, bool) {
+	at := strings.LastIndex(base, "@")
+	if at <= 0 || secret == "" {
+		return uuid.Nil, false
+	}
+	local, domain := strings.ToLower(base[:at]), strings.ToLower(base[at:])
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	if !strings.HasPrefix(addr, local+"+") || !strings.HasSuffix(addr, domain) {
+		return uuid.Nil, false
+	}
+	tag := strings.TrimSuffix(strings.TrimPrefix(addr, local+"+"), domain)
+	id, mac, ok := strings.Cut(tag, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(inboundAddressMAC(secret, id))) {
+		return uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(id)
+	return tenantID, err == nil
+}
+
+func inboundAddressMAC(secret, tenantID string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("inbound-mail:" + tenantID))
+	return hex.EncodeToString(m.Sum(nil))[:16]
+}
+
+// InboundMailService turns emails sent to the grievance officer into grievances,
+// DSRs and replies on existing threads.
+type InboundMailService struct {
+	masterDB     *gorm.DB
+	dsrService   *DSRService
+	tracker      *DSRTrackingService
+	dsrRepo      *repository.DSRRepository
+	emailService *EmailService
+	store        *blob.Store
+	replyTo      string // Base inbound address; tenants are reached on plus-addresses of it
+	secret       string // Signs tenant plus-addresses
+
+	// tenantDB resolves a tenant's database; replaced in tests
+	tenantDB func(tenantID uuid.UUID) (*gorm.DB, error)
+}
+
+func NewInboundMailService(
+	masterDB *gorm.DB,
+	dsrService *DSRService,
+	tracker *DSRTrackingService,
+	dsrRepo *repository.DSRRepository,
+	emailService *EmailService,
+	store *blob.Store,
+	replyTo, secret string,
+) *InboundMailService {
+	return &InboundMailService{
+		masterDB:     masterDB,
+		dsrService:   dsrService,
+		tracker:      tracker,
+		dsrRepo:      dsrRepo,
+		emailService: emailService,
+		store:        store,
+		replyTo:      replyTo,
+		secret:       secret,
+		tenantDB: func(tenantID uuid.UUID) (*gorm.DB, error) {
+			return db.GetTenantDB("tenant_" + tenantID.String()[:8])
+		},
+	}
+}
+
+// Ingest processes one raw MIME message and returns the log entry describing
+// what was done with it. Redelivered messages return the original entry.
+func (s *InboundMailService) Ingest(ctx context.Context, raw []byte) (*models.InboundEmail, error) {
+	msg, err := email.ParseInbound(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.MessageID != "" {
+		var existing models.InboundEmail
+		if err := s.masterDB.Where("message_id = ?", msg.MessageID).First(&existing).Error; err == nil {
+			return &existing, nil
+		}
+	}
+
+	record := &models.InboundEmail{
+		ID:          uuid.New(),
+		MessageID:   msg.MessageID,
+		FromAddress: msg.From,
+		Subject:     msg.Subject,
+	}
+
+	if msg.Automated {
+		record.Outcome = "ignored"
+		record.Reason = msg.AutomatedReason
+		return record, s.masterDB.Create(record).Error
+	}
+
+	// The tenant comes from the signed address the mail was sent to; the From
+	// header is only trusted to pick a principal within that tenant. Unknown
+	// senders are logged but not answered, to avoid backscatter.
+	tenantID, ok := s.recipientTenant(msg)
+	if !ok {
+		record.Outcome = "unmatched"
+		record.Reason = "message is not addressed to a tenant inbound address"
+		return record, s.masterDB.Create(record).Error
+	}
+	var principal models.DataPrincipal
+	if err := s.masterDB.Where("tenant_id = ? AND LOWER(email) = ?", tenantID, strings.ToLower(strings.TrimSpace(msg.From))).First(&principal).Error; err != nil {
+		record.Outcome = "unmatched"
+		record.Reason = "sender is not a registered data principal"
+		return record, s.masterDB.Create(record).Error
+	}
+	record.PrincipalID = &principal.ID
+	record.TenantID = &principal.TenantID
+
+	if err := s.route(ctx, msg, &principal, record); err != nil {
+		record.Outcome = "failed"
+		record.Reason = err.Error()
+		log.Logger.Error().Err(err).Str("from", msg.From).Msg("inbound email processing failed")
+	}
+	if err := s.masterDB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	s.storeAttachments(msg, record)
+	return record, nil
+}
+
+// recipientTenant returns the tenant whose inbound address the message was sent to.
+func (s *InboundMailService) recipientTenant(msg *email.InboundMessage) (uuid.UUID, bool) {
+	for _, to := range msg.To {
+		if tenantID, ok := tenantFromInboundAddress(s.replyTo, s.secret, to); ok {
+			return tenantID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// route threads the message onto an existing grievance or DSR when its subject
+// carries a reference belonging to the sender, otherwise opens a new one.
+func (s *InboundMailService) route(ctx context.Context, msg *email.InboundMessage, p *models.DataPrincipal, record *models.InboundEmail) error {
+	if token := threadTokenPattern.FindString(strings.ToUpper(msg.Subject)); token != "" {
+		if strings.HasPrefix(token, "GRV-") {
+			if ok, err := s.replyToGrievance(ctx, token, msg, p, record); ok || err != nil {
+				return err
+			}
+		} else if ok, err := s.replyToDSR(token, msg, p, record); ok || err != nil {
+			return err
+		}
+	}
+
+	if dsrType := classifyDSREmail(msg.Subject + "\n" + msg.Text); dsrType != "" {
+		return s.createDSR(dsrType, msg, p, record)
+	}
+	return s.createGrievance(ctx, msg, p, record)
+}
+
+func (s *InboundMailService) replyToGrievance(ctx context.Context, token string, msg *email.InboundMessage, p *models.DataPrincipal, record *models.InboundEmail) (bool, error) {
+	tenantDB, err := s.tenantDB(p.TenantID)
+	if err != nil {
+		return false, err
+	}
+	svc := NewGrievanceService(repository.NewGrievanceRepo(tenantDB))
+	g, err := svc.GetByThreadToken(ctx, token)
+	if err != nil || g.UserID != p.ID {
+		return false, nil
+	}
+	if _, err := svc.AddComment(ctx, dto.CreateGrievanceCommentRequest{
+		GrievanceID: g.ID.String(),
+		UserID:      p.ID.String(),
+		Comment:     msg.Text,
+	}); err != nil {
+		return true, err
+	}
+	record.Outcome = "grievance_reply"
+	record.GrievanceID = &g.ID
+	return true, nil
+}
+
+func (s *InboundMailService) replyToDSR(token string, msg *email.InboundMessage, p *models.DataPrincipal, record *models.InboundEmail) (bool, error) {
+	if s.tracker == nil {
+		return false, nil
+	}
+	req, err := s.dsrRepo.GetByTrackingRef(token)
+	if err != nil || req.UserID != p.ID {
+		return false, nil
+	}
+
+	// Files outside the attachment allowlist stay in the mail log only
+	var files []email.InboundAttachment
+	for _, a := range msg.Attachments {
+		if _, err := checkAttachmentFile(a.FileName, a.ContentType, a.Data); err != nil {
+			log.Logger.Warn().Err(err).Str("file", a.FileName).Msg("not filing emailed attachment on DSR")
+			continue
+		}
+		files = append(files, a)
+	}
+
+	// The message body and first attachment form one clarification; further
+	// attachments are filed alongside it
+	var fileName, contentType string
+	var data []byte
+	if len(files) > 0 {
+		fileName, contentType, data = files[0].FileName, files[0].ContentType, files[0].Data
+	}
+	if _, err := s.tracker.AddClarification(req, msg.Text, fileName, contentType, data); err != nil {
+		return true, err
+	}
+	if len(files) > 1 {
+		for _, a := range files[1:] {
+			if _, err := s.tracker.AddClarification(req, "", a.FileName, a.ContentType, a.Data); err != nil {
+				log.Logger.Warn().Err(err).Str("file", a.FileName).Msg("failed to attach emailed file to DSR")
+			}
+		}
+	}
+	record.Outcome = "dsr_reply"
+	record.DSRRequestID = &req.ID
+	return true, nil
+}
+
+func (s *InboundMailService) createDSR(dsrType string, msg *email.InboundMessage, p *models.DataPrincipal, record *models.InboundEmail) error {
+	details, _ := json.Marshal(map[string]interface{}{
+		"source":  "email",
+		"subject": msg.Subject,
+		"body":    msg.Text,
+	})
+	req := &models.DSRRequest{
+		ID:             uuid.New(),
+		UserID:         p.ID,
+		TenantID:       p.TenantID,
+		Type:           dsrType,
+		Status:         "Pending",
+		RequestedAt:    time.Now(),
+		RequesterEmail: msg.From,
+		RequestDetails: details,
+	}
+	if err := s.dsrService.CreateRequest(req); err != nil {
+		return err
+	}
+	record.Outcome = "dsr_created"
+	record.DSRRequestID = &req.ID
+	return nil
+}
+
+func (s *InboundMailService) createGrievance(ctx context.Context, msg *email.InboundMessage, p *models.DataPrincipal, record *models.InboundEmail) error {
+	tenantDB, err := s.tenantDB(p.TenantID)
+	if err != nil {
+		return err
+	}
+	subject := msg.Subject
+	if subject == "" {
+		subject = "Grievance received by email"
+	}
+	svc := NewGrievanceService(repository.NewGrievanceRepo(tenantDB))
+	g, err := svc.Raise(ctx, dto.CreateGrievanceRequest{
+		UserID:               p.ID.String(),
+		GrievanceType:        "email",
+		GrievanceSubject:     subject,
+		GrievanceDescription: msg.Text,
+		Category:             "general",
+		Priority:             "medium",
+		Source:               "email",
+	}, p.TenantID.String())
+	if err != nil {
+		return err
+	}
+	record.Outcome = "grievance_created"
+	record.GrievanceID = &g.ID
+
+	s.acknowledge(p.TenantID, msg.From, ThreadSubject(g, "We have received your grievance"),
+		fmt.Sprintf(`Thank you for contacting us. Your grievance has been registered with reference <b>%s</b>.
+<br>Reply to this email to add information; please keep the reference in the subject line.`, g.ThreadToken))
+	return nil
+}
+
+func (s *InboundMailService) acknowledge(tenantID uuid.UUID, to, subject, body string) {
+	if s.emailService == nil {
+		return
+	}
+	replyTo := TenantInboundAddress(s.replyTo, s.secret, tenantID)
+	go func() {
+		if err := s.emailService.SendReply(to, replyTo, subject, body); err != nil {
+			log.Logger.Error().Err(err).Str("to", to).Msg("failed to send inbound mail acknowledgement")
+		}
+	}()
+}
+
+// storeAttachments keeps a copy of every attachment against the log entry.
+// Attachments on DSR replies are also filed as clarifications by replyToDSR.
+func (s *InboundMailService) storeAttachments(msg *email.InboundMessage, record *models.InboundEmail) {
+	if s.store == nil || record.TenantID == nil {
+		return
+	}
+	for _, a := range msg.Attachments {
+		if len(a.Data) == 0 || len(a.Data) > maxInboundAttachmentSize {
+			log.Logger.Warn().Str("file", a.FileName).Int("size", len(a.Data)).Msg("skipping inbound attachment outside size limits")
+			continue
+		}
+		sum := sha256.Sum256(a.Data)
+		att := models.InboundEmailAttachment{
+			ID:          uuid.New(),
+			EmailID:     record.ID,
+			FileName:    blob.SafeName(a.FileName),
+			ContentType: a.ContentType,
+			SizeBytes:   int64(len(a.Data)),
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+		key := fmt.Sprintf("inbound-mail/%s/%s/%s_%s", record.TenantID, record.ID, att.ID, att.FileName)
+		path, err := s.store.Put(key, a.ContentType, a.Data)
+		if err != nil {
+			log.Logger.Error().Err(err).Str("file", a.FileName).Msg("failed to store inbound attachment")
+			continue
+		}
+		att.FilePath = path
+		if err := s.masterDB.Create(&att).Error; err != nil {
+			log.Logger.Error().Err(err).Msg("failed to record inbound attachment")
+			continue
+		}
+		record.Attachments = append(record.Attachments, att)
+	}
+}
+
+// ListInbound returns the tenant's inbound mail log, newest first.
+func (s *InboundMailService) ListInbound(tenantID uuid.UUID, outcome string, limit, offset int) ([]models.InboundEmail, int64, error) {
+	q := s.masterDB.Model(&models.InboundEmail{}).Where("tenant_id = ?", tenantID)
+	if outcome != "" {
+		q = q.Where("outcome = ?", outcome)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []models.InboundEmail
+	err := q.Preload("Attachments").Order("received_at DESC").Limit(limit).Offset(offset).Find(&items).Error
+	return items, total, err
+}
+
+func classifyDSREmail(text string) string {
+	lower := strings.ToLower(text)
+	for _, c := range dsrEmailKeywords {
+		for _, k := range c.Keywords {
+			if strings.Contains(lower, k) {
+				return c.Type
+			}
+		}
+	}
+	return ""
+}
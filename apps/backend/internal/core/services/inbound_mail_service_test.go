@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/jwtlink"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupInboundMail(t *testing.T) (*InboundMailService, *gorm.DB) {
+	jwtlink.Init("test-secret")
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DataPrincipal{}, &models.DSRRequest{}, &models.DSREvent{}, &models.DSRClarification{},
+		&models.InboundEmail{}, &models.InboundEmailAttachment{}))
+
+	repo := repository.NewDSRRepository(db, nil)
+	store := blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false)
+	tracker := NewDSRTrackingService(repo, nil, store, nil, "https://portal.example.com")
+	svc := NewInboundMailService(db, NewDSRService(repo, nil, nil, tracker), tracker, repo, nil, store, "grievance@example.com", "mail-secret")
+	svc.tenantDB = func(uuid.UUID) (*gorm.DB, error) { return nil, fmt.Errorf("no tenant database in tests") }
+	return svc, db
+}
+
+func tenantInbound(tenantID uuid.UUID) string {
+	return TenantInboundAddress("grievance@example.com", "mail-secret", tenantID)
+}
+
+func rawMail(id, to, from, subject, body string, extraHeaders ...string) []byte {
+	headers := append([]string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Message-ID: <" + id + "@mail.example.com>",
+	}, extraHeaders...)
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
+
+func TestInboundMail_IgnoresAutomatedAndUnknownSenders(t *testing.T) {
+	svc, _ := setupInboundMail(t)
+	ctx := context.Background()
+
+	rec, err := svc.Ingest(ctx, rawMail("a1", "grievance@example.com", "Asha <asha@example.com>", "Out of office", "Back Monday", "Auto-Submitted: auto-replied"))
+	require.NoError(t, err)
+	assert.Equal(t, "ignored", rec.Outcome)
+
+	rec, err = svc.Ingest(ctx, rawMail("a2", "grievance@example.com", "MAILER-DAEMON@mx.example.com", "Returned mail", "User unknown"))
+	require.NoError(t, err)
+	assert.Equal(t, "ignored", rec.Outcome)
+
+	rec, err = svc.Ingest(ctx, rawMail("a3", "grievance@example.com", "stranger@example.org", "Please delete my data", "Delete my account"))
+	require.NoError(t, err)
+	assert.Equal(t, "unmatched", rec.Outcome)
+	assert.Nil(t, rec.TenantID)
+}
+
+func TestInboundMail_ResolvesTenantFromSignedAddress(t *testing.T) {
+	svc, db := setupInboundMail(t)
+	ctx := context.Background()
+
+	// The same person is a principal of two tenants
+	tenantA, tenantB := uuid.New(), uuid.New()
+	inA := models.DataPrincipal{ID: uuid.New(), TenantID: tenantA, Email: "ravi@example.com"}
+	inB := models.DataPrincipal{ID: uuid.New(), TenantID: tenantB, Email: "Ravi@Example.com"}
+	require.NoError(t, db.Create(&inA).Error)
+	require.NoError(t, db.Create(&inB).Error)
+
+	addrB := tenantInbound(tenantB)
+	assert.True(t, strings.HasPrefix(addrB, "grievance+"))
+	rec, err := svc.Ingest(ctx, rawMail("d1", addrB, "RAVI@example.com", "Request", "Please erase my personal data."))
+	require.NoError(t, err)
+	require.Equal(t, "dsr_created", rec.Outcome)
+	assert.Equal(t, tenantB, *rec.TenantID)
+	assert.Equal(t, inB.ID, *rec.PrincipalID)
+
+	// The bare mailbox names no tenant, and a tenant ID without a valid MAC
+	// is refused
+	rec, err = svc.Ingest(ctx, rawMail("d2", "grievance@example.com", "ravi@example.com", "Request", "Please erase my personal data."))
+	require.NoError(t, err)
+	assert.Equal(t, "unmatched", rec.Outcome)
+	forged := strings.Replace(addrB, strings.ReplaceAll(tenantB.String(), "-", ""), strings.ReplaceAll(tenantA.String(), "-", ""), 1)
+	rec, err = svc.Ingest(ctx, rawMail("d3", forged, "ravi@example.com", "Request", "Please erase my personal data."))
+	require.NoError(t, err)
+	assert.Equal(t, "unmatched", rec.Outcome)
+
+	// A sender unknown to the addressed tenant is not matched in another one
+	stranger := models.DataPrincipal{ID: uuid.New(), TenantID: tenantA, Email: "mina@example.com"}
+	require.NoError(t, db.Create(&stranger).Error)
+	rec, err = svc.Ingest(ctx, rawMail("d4", addrB, "mina@example.com", "Request", "Please erase my personal data."))
+	require.NoError(t, err)
+	assert.Equal(t, "unmatched", rec.Outcome)
+}
+
+func TestInboundMail_CreatesDSRAndThreadsReplies(t *testing.T) {
+	svc, db := setupInboundMail(t)
+	ctx := context.Background()
+	principal := models.DataPrincipal{ID: uuid.New(), TenantID: uuid.New(), Email: "Asha@Example.com", FirstName: "Asha"}
+	require.NoError(t, db.Create(&principal).Error)
+	tenantAddr := tenantInbound(principal.TenantID)
+
+	rec, err := svc.Ingest(ctx, rawMail("b1", tenantAddr, "Asha <asha@example.com>", "Request", "Please erase my personal data from your systems."))
+	require.NoError(t, err)
+	require.Equal(t, "dsr_created", rec.Outcome)
+	require.NotNil(t, rec.DSRRequestID)
+
+	var req models.DSRRequest
+	require.NoError(t, db.First(&req, "id = ?", *rec.DSRRequestID).Error)
+	assert.Equal(t, "Data Deletion", req.Type)
+	assert.Equal(t, principal.TenantID, req.TenantID)
+
+	// Redelivery of the same message is not processed twice
+	again, err := svc.Ingest(ctx, rawMail("b1", tenantAddr, "Asha <asha@example.com>", "Request", "Please erase my personal data from your systems."))
+	require.NoError(t, err)
+	assert.Equal(t, rec.ID, again.ID)
+	var count int64
+	db.Model(&models.DSRRequest{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// A reply quoting the reference is filed as a clarification, with its attachment
+	_, err = svc.tracker.RequestInfo(req.ID, req.TenantID, uuid.New(), "Please send proof of identity.")
+	require.NoError(t, err)
+	reply := strings.Join([]string{
+		"From: asha@example.com",
+		"To: Grievance Desk <" + strings.ToUpper(tenantAddr) + ">",
+		"Subject: Re: [" + req.TrackingRef + "] Information needed",
+		"Message-ID: <b2@mail.example.com>",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="XYZ"`,
+		"",
+		"--XYZ",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"ID attached, thanks =E2=9C=93",
+		"--XYZ",
+		`Content-Type: application/pdf; name="id.pdf"`,
+		"Content-Transfer-Encoding: base64",
+		`Content-Disposition: attachment; filename="id.pdf"`,
+		"",
+		"JVBERi0xLjQ=",
+		"--XYZ--",
+		"",
+	}, "\r\n")
+	rec, err = svc.Ingest(ctx, []byte(reply))
+	require.NoError(t, err)
+	assert.Equal(t, "dsr_reply", rec.Outcome)
+	require.Len(t, rec.Attachments, 1)
+	assert.Equal(t, int64(8), rec.Attachments[0].SizeBytes)
+
+	clarifications, err := svc.tracker.ListClarifications(req.ID, req.TenantID)
+	require.NoError(t, err)
+	require.Len(t, clarifications, 1)
+	assert.Equal(t, "ID attached, thanks ✓", clarifications[0].Message)
+	assert.Equal(t, "id.pdf", clarifications[0].FileName)
+
+	items, total, err := svc.ListInbound(principal.TenantID, "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, items, 2)
+	assert.WithinDuration(t, time.Now(), items[0].ReceivedAt, time.Minute)
+}
+
+func TestInboundMail_GrievanceFailureIsRecorded(t *testing.T) {
+	svc, db := setupInboundMail(t)
+	principal := models.DataPrincipal{ID: uuid.New(), TenantID: uuid.New(), Email: "dev@example.com"}
+	require.NoError(t, db.Create(&principal).Error)
+
+	// Mail that is not a DSR becomes a grievance in the tenant database
+	rec, err := svc.Ingest(context.Background(), rawMail("c1", tenantInbound(principal.TenantID), "dev@example.com", "Unhappy with support", "Nobody answered my call."))
+	require.NoError(t, err)
+	assert.Equal(t, "failed", rec.Outcome)
+	assert.Contains(t, rec.Reason, "no tenant database")
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrUnknownJobType  = errors.New("no handler is registered for this job type")
+	ErrJobNotRetryable = errors.New("only failed or cancelled jobs can be retried")
+	ErrJobFinished     = errors.New("job has already finished")
+)
+
+const (
+	// schedulerLease is held by the one replica that enqueues cron jobs
+	schedulerLease = "scheduler"
+	leaseTTL       = 30 * time.Second
+	// A running job's lock is refreshed every jobHeartbeat; one not refreshed
+	// for jobStaleAfter belonged to a dead worker and is requeued
+	jobHeartbeat  = time.Minute
+	jobStaleAfter = 5 * time.Minute
+	// Succeeded jobs are kept this long for inspection
+	jobRetention = 7 * 24 * time.Hour
+
+	defaultJobMaxAttempts = 5
+	jobBackoffBase        = 30 * time.Second
+	jobBackoffMax         = time.Hour
+)
+
+// JobHandlerFunc runs one job. A returned error fails the attempt; the job is
+// retried with backoff until it runs out of attempts.
+type JobHandlerFunc func(ctx context.Context, job *models.Job) error
+
+// JobOptions tune an enqueued job. Zero values take the defaults: run now,
+// five attempts, no tenant and no de-duplication.
+type JobOptions struct {
+	TenantID    *uuid.UUID
+	RunAt       time.Time
+	MaxAttempts int
+	UniqueKey   string
+}
+
+// JobSchedule is a cron entry that enqueues a job each time it fires
+type JobSchedule struct {
+	Name    string      `json:"name"`
+	Spec    string      `json:"spec"`
+	JobType string      `json:"jobType"`
+	Payload interface{} `json:"payload,omitempty"`
+	NextRun *time.Time  `json:"nextRun,omitempty"`
+	entryID cron.EntryID
+}
+
+// JobRunnerStatus describes this replica's view of the job subsystem
+type JobRunnerStatus struct {
+	Worker    string           `json:"worker"`
+	IsLeader  bool             `json:"isLeader"`
+	Leader    *models.JobLease `json:"leader,omitempty"`
+	Workers   int              `json:"workers"`
+	JobTypes  []string         `json:"jobTypes"`
+	Schedules []JobSchedule    `json:"schedules"`
+}
+
+// JobRunner is the persistent background job subsystem. Every replica runs
+// workers that claim jobs from the Postgres-backed queue; cron schedules are
+// only enqueued by the replica holding the scheduler lease, so they fire once
+// however many replicas are up.
+type JobRunner struct {
+	repo     *repository.JobRepository
+	worker   string
+	workers  int
+	poll     time.Duration
+	handlers map[string]JobHandlerFunc
+	mu       sync.RWMutex
+	leader   atomic.Bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	Cron     *cron.Cron
+	// schedules is guarded by mu
+	schedules []*JobSchedule
+}
+
+func NewJobRunner(repo *repository.JobRepository, workers int, poll time.Duration) *JobRunner {
+	if workers <= 0 {
+		workers = 1
+	}
+	if poll <= 0 {
+		poll = 2 * time.Second
+	}
+	host, _ := os.Hostname()
+	r := &JobRunner{
+		repo:     repo,
+		worker:   fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		workers:  workers,
+		poll:     poll,
+		handlers: map[string]JobHandlerFunc{},
+		stop:     make(chan struct{}),
+		Cron:     cron.New(),
+	}
+	r.Register("jobs.purge", func(ctx context.Context, job *models.Job) error {
+		n, err := r.repo.PurgeSucceeded(time.Now().Add(-jobRetention))
+		if n > 0 {
+			log.Logger.Info().Int64("purged", n).Msg("Purged finished jobs")
+		}
+		return err
+	})
+	r.Schedule("jobs.purge", "30 0 * * *", "jobs.purge", nil)
+	return r
+}
+
+// JobBackoff is the delay before retrying a job that has failed attempt
+// times: 30s, 1m, 2m, ... capped at an hour
+func JobBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := jobBackoffBase
+	for i := 1; i < attempt && d < jobBackoffMax; i++ {
+		d *= 2
+	}
+	if d > jobBackoffMax {
+		d = jobBackoffMax
+	}
+	return d
+}
+
+// DecodeJobPayload unmarshals a job's payload into v
+func DecodeJobPayload(job *models.Job, v interface{}) error {
+	if len(job.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(job.Payload, v)
+}
+
+// Register sets the handler for a job type. Handlers must be registered on
+// every replica before Start.
+func (r *JobRunner) Register(jobType string, handler JobHandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+}
+
+// Schedule enqueues jobType with payload on the cron spec. The name keys the
+// de-duplication of each run, so it must be unique across schedules.
+func (r *JobRunner) Schedule(name, spec, jobType string, payload interface{}) {
+	s := &JobSchedule{Name: name, Spec: spec, JobType: jobType, Payload: payload}
+	id, err := r.Cron.AddFunc(spec, func() {
+		if !r.leader.Load() {
+			return
+		}
+		// Both the outgoing and incoming leader may fire the same tick
+		tick := time.Now().UTC().Truncate(time.Minute)
+		if _, err := r.enqueue(jobType, payload, JobOptions{UniqueKey: name + "@" + tick.Format(time.RFC3339)}, name); err != nil {
+			log.Logger.Error().Err(err).Str("schedule", name).Msg("Failed to enqueue scheduled job")
+		}
+	})
+	if err != nil {
+		log.Logger.Error().Err(err).Str("schedule", name).Msg("Failed to schedule job")
+		return
+	}
+	s.entryID = id
+	r.mu.Lock()
+	r.schedules = append(r.schedules, s)
+	r.mu.Unlock()
+}
+
+// Enqueue adds a job to the queue. A job whose UniqueKey has already been
+// used by a stored job, even a finished one, is not added and the returned
+// job is nil.
+func (r *JobRunner) Enqueue(jobType string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	return r.enqueue(jobType, payload, opts, "")
+}
+
+func (r *JobRunner) enqueue(jobType string, payload interface{}, opts JobOptions, schedule string) (*models.Job, error) {
+	r.mu.RLock()
+	_, ok := r.handlers[jobType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownJobType
+	}
+
+	job := &models.Job{
+		ID:          uuid.New(),
+		Type:        jobType,
+		TenantID:    opts.TenantID,
+		Status:      models.JobStatusPending,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+		Schedule:    schedule,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = defaultJobMaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		key := opts.UniqueKey
+		job.UniqueKey = &key
+	}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %w", err)
+		}
+		job.Payload = raw
+	}
+
+	created, err := r.repo.Enqueue(job)
+	if err != nil || !created {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Start begins leader election, the cron scheduler and the workers
+func (r *JobRunner) Start() {
+	r.wg.Add(1)
+	go r.elect()
+	r.Cron.Start()
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+	log.Logger.Info().Str("worker", r.worker).Int("workers", r.workers).Msg("Job runner started")
+}
+
+// Stop stops taking new jobs, waits for running ones to finish and hands
+// the scheduler lease to another replica
+func (r *JobRunner) Stop() {
+	close(r.stop)
+	<-r.Cron.Stop().Done()
+	r.wg.Wait()
+	if r.leader.Load() {
+		if err := r.repo.ReleaseLease(schedulerLease, r.worker); err != nil {
+			log.Logger.Error().Err(err).Msg("Failed to release scheduler lease")
+		}
+	}
+}
+
+// elect keeps trying to take or renew the scheduler lease. The leader also
+// requeues jobs whose worker died.
+func (r *JobRunner) elect() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		r.campaign(time.Now())
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *JobRunner) campaign(now time.Time) {
+	held, err := r.repo.AcquireLease(schedulerLease, r.worker, leaseTTL, now)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("Scheduler lease check failed")
+		held = false
+	}
+	if held != r.leader.Swap(held) {
+		log.Logger.Info().Str("worker", r.worker).Bool("leader", held).Msg("Scheduler leadership changed")
+	}
+	if !held {
+		return
+	}
+	requeued, failed, err := r.repo.RequeueStale(now.Add(-jobStaleAfter), now)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("Failed to requeue stale jobs")
+		return
+	}
+	if requeued > 0 {
+		log.Logger.Warn().Int64("jobs", requeued).Msg("Requeued jobs from lost workers")
+	}
+	if failed > 0 {
+		log.Logger.Error().Int64("jobs", failed).Msg("Failed jobs from lost workers; no attempts left")
+	}
+}
+
+func (r *JobRunner) work() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		if r.processNext(context.Background()) {
+			continue
+		}
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(r.poll):
+		}
+	}
+}
+
+func (r *JobRunner) jobTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// processNext claims and runs one due job. It reports whether there was one.
+func (r *JobRunner) processNext(ctx context.Context) bool {
+	job, err := r.repo.Claim(r.worker, r.jobTypes(), time.Now())
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("Failed to claim job")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+	r.run(ctx, job)
+	return true
+}
+
+func (r *JobRunner) run(ctx context.Context, job *models.Job) {
+	r.mu.RLock()
+	handler := r.handlers[job.Type]
+	r.mu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if err := r.repo.Heartbeat(job.ID, r.worker, now); err != nil {
+					log.Logger.Error().Err(err).Str("job", job.ID.String()).Msg("Job heartbeat failed")
+				}
+			}
+		}
+	}()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("job panicked: %v", p)
+			}
+		}()
+		return handler(ctx, job)
+	}()
+	close(done)
+
+	now := time.Now()
+	logger := log.Logger.With().Str("job", job.ID.String()).Str("type", job.Type).Int("attempt", job.Attempts).Logger()
+	switch {
+	case err == nil:
+		if err := r.repo.Complete(job.ID, now); err != nil {
+			logger.Error().Err(err).Msg("Failed to mark job complete")
+		}
+	case job.Attempts >= job.MaxAttempts:
+		logger.Error().Err(err).Msg("Job failed; no attempts left")
+		if err := r.repo.Fail(job.ID, err.Error(), now); err != nil {
+			logger.Error().Err(err).Msg("Failed to mark job failed")
+		}
+	default:
+		retryAt := now.Add(JobBackoff(job.Attempts))
+		logger.Warn().Err(err).Time("retryAt", retryAt).Msg("Job failed; will retry")
+		if err := r.repo.Reschedule(job.ID, err.Error(), retryAt); err != nil {
+			logger.Error().Err(err).Msg("Failed to reschedule job")
+		}
+	}
+}
+
+// Admin
+
+func (r *JobRunner) List(filter repository.JobFilter) ([]models.Job, error) {
+	return r.repo.List(filter)
+}
+
+func (r *JobRunner) Get(id uuid.UUID) (*models.Job, error) {
+	job, err := r.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Retry requeues a failed or cancelled job to run now with fresh attempts
+func (r *JobRunner) Retry(id uuid.UUID) (*models.Job, error) {
+	if _, err := r.Get(id); err != nil {
+		return nil, err
+	}
+	ok, err := r.repo.Retry(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJobNotRetryable
+	}
+	return r.Get(id)
+}
+
+// Cancel stops a pending or running job. A running handler finishes its
+// current attempt but the job is not retried.
+func (r *JobRunner) Cancel(id uuid.UUID) (*models.Job, error) {
+	if _, err := r.Get(id); err != nil {
+		return nil, err
+	}
+	ok, err := r.repo.Cancel(id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrJobFinished
+	}
+	return r.Get(id)
+}
+
+// Status reports leadership, registered job types and the cron schedules
+func (r *JobRunner) Status() (*JobRunnerStatus, error) {
+	lease, err := r.repo.GetLease(schedulerLease)
+	if err != nil {
+		return nil, err
+	}
+	st := &JobRunnerStatus{
+		Worker:    r.worker,
+		IsLeader:  r.leader.Load(),
+		Leader:    lease,
+		Workers:   r.workers,
+		JobTypes:  r.jobTypes(),
+		Schedules: []JobSchedule{},
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.schedules {
+		entry := *s
+		if next := r.Cron.Entry(s.entryID).Next; !next.IsZero() {
+			entry.NextRun = &next
+		}
+		st.Schedules = append(st.Schedules, entry)
+	}
+	return st, nil
+}
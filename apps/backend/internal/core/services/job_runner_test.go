@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupJobRunner(t *testing.T) (*JobRunner, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Job{}, &models.JobLease{}))
+	return NewJobRunner(repository.NewJobRepository(db), 1, time.Second), db
+}
+
+func TestJobRunner_RetriesWithBackoffThenFails(t *testing.T) {
+	runner, db := setupJobRunner(t)
+	calls := 0
+	runner.Register("flaky", func(ctx context.Context, job *models.Job) error {
+		calls++
+		var p struct {
+			N int `json:"n"`
+		}
+		require.NoError(t, DecodeJobPayload(job, &p))
+		assert.Equal(t, 7, p.N)
+		if calls == 3 {
+			return nil
+		}
+		return errors.New("upstream unavailable")
+	})
+
+	_, err := runner.Enqueue("unknown", nil, JobOptions{})
+	assert.ErrorIs(t, err, ErrUnknownJobType)
+
+	job, err := runner.Enqueue("flaky", map[string]int{"n": 7}, JobOptions{MaxAttempts: 2})
+	require.NoError(t, err)
+
+	// The first attempt fails and is pushed back by the backoff
+	before := time.Now()
+	require.True(t, runner.processNext(context.Background()))
+	stored, err := runner.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, "upstream unavailable", stored.LastError)
+	assert.False(t, stored.RunAt.Before(before.Add(JobBackoff(1))))
+	assert.False(t, runner.processNext(context.Background()), "not due until the backoff passes")
+
+	// The last attempt fails for good
+	require.NoError(t, db.Model(&models.Job{}).Where("id = ?", job.ID).Update("run_at", time.Now().Add(-time.Second)).Error)
+	require.True(t, runner.processNext(context.Background()))
+	stored, _ = runner.Get(job.ID)
+	assert.Equal(t, models.JobStatusFailed, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.NotNil(t, stored.CompletedAt)
+
+	_, err = runner.Cancel(job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+
+	// An admin retry gives it a fresh set of attempts
+	stored, err = runner.Retry(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, 0, stored.Attempts)
+	require.True(t, runner.processNext(context.Background()))
+	stored, _ = runner.Get(job.ID)
+	assert.Equal(t, models.JobStatusSucceeded, stored.Status)
+	assert.Empty(t, stored.LastError)
+	assert.Equal(t, 3, calls)
+
+	_, err = runner.Retry(job.ID)
+	assert.ErrorIs(t, err, ErrJobNotRetryable)
+}
+
+func TestJobRunner_CancelAndDeduplicate(t *testing.T) {
+	runner, _ := setupJobRunner(t)
+	runner.Register("noop", func(ctx context.Context, job *models.Job) error {
+		t.Fatal("cancelled job was run")
+		return nil
+	})
+
+	first, err := runner.Enqueue("noop", nil, JobOptions{UniqueKey: "noop@tick"})
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	second, err := runner.Enqueue("noop", nil, JobOptions{UniqueKey: "noop@tick"})
+	require.NoError(t, err)
+	assert.Nil(t, second, "a scheduled run is only queued once")
+
+	cancelled, err := runner.Cancel(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, cancelled.Status)
+	assert.False(t, runner.processNext(context.Background()))
+
+	jobs, err := runner.List(repository.JobFilter{Status: models.JobStatusCancelled})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, first.ID, jobs[0].ID)
+}
+
+func TestJobRunner_LeaderElectionAndLostWorkers(t *testing.T) {
+	a, db := setupJobRunner(t)
+	repo := repository.NewJobRepository(db)
+	b := NewJobRunner(repo, 1, time.Second)
+
+	now := time.Now()
+	a.campaign(now)
+	b.campaign(now)
+	assert.True(t, a.leader.Load())
+	assert.False(t, b.leader.Load(), "only one replica schedules cron jobs")
+
+	// The leader renews; once it stops, another replica takes over
+	a.campaign(now.Add(leaseTTL / 2))
+	assert.True(t, a.leader.Load())
+	b.campaign(now.Add(leaseTTL / 2))
+	assert.False(t, b.leader.Load())
+	b.campaign(now.Add(2 * leaseTTL))
+	assert.True(t, b.leader.Load())
+	a.campaign(now.Add(2 * leaseTTL))
+	assert.False(t, a.leader.Load())
+
+	// A job left running by a dead worker is requeued by the leader
+	job, err := b.Enqueue("jobs.purge", nil, JobOptions{})
+	require.NoError(t, err)
+	claimed, err := repo.Claim("dead-worker", []string{"jobs.purge"}, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	b.campaign(time.Now().Add(jobStaleAfter + time.Minute))
+	stored, _ := b.Get(job.ID)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Empty(t, stored.LockedBy)
+
+	// One that has used its last attempt is failed rather than run again
+	last, err := b.Enqueue("jobs.purge", nil, JobOptions{MaxAttempts: 1, RunAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	claimed, err = repo.Claim("dead-worker", []string{"jobs.purge"}, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, last.ID, claimed.ID)
+	b.campaign(time.Now().Add(jobStaleAfter + time.Minute))
+	stored, _ = b.Get(last.ID)
+	assert.Equal(t, models.JobStatusFailed, stored.Status)
+	assert.NotNil(t, stored.CompletedAt)
+	assert.Empty(t, stored.LockedBy)
+
+	status, err := b.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsLeader)
+	require.NotNil(t, status.Leader)
+	assert.Equal(t, b.worker, status.Leader.Holder)
+	assert.Contains(t, status.JobTypes, "jobs.purge")
+}
+
+func TestJobBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, JobBackoff(1))
+	assert.Equal(t, time.Minute, JobBackoff(2))
+	assert.Equal(t, 4*time.Minute, JobBackoff(4))
+	assert.Equal(t, time.Hour, JobBackoff(20))
+}
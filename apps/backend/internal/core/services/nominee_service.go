@@ -0,0 +1,313 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/auth"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+)
+
+const nomineeVerificationTTL = 72 * time.Hour
+
+var validNomineeClaimTypes = map[string]bool{"death": true, "incapacity": true}
+
+type NomineeService struct {
+	repo         *repository.NomineeRepository
+	emailService *EmailService
+	baseURL      string
+	store        *blob.Store
+}
+
+func NewNomineeService(repo *repository.NomineeRepository, emailService *EmailService, baseURL string, store *blob.Store) *NomineeService {
+	return &NomineeService{repo: repo, emailService: emailService, baseURL: baseURL, store: store}
+}
+
+// AddNominee registers a nominee for the principal and emails them a verification link.
+func (s *NomineeService) AddNominee(principalID, tenantID uuid.UUID, name, email, phone, relationship string) (*models.Nominee, error) {
+	name = strings.TrimSpace(name)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if name == "" || email == "" {
+		return nil, errors.New("nominee name and email are required")
+	}
+
+	n := &models.Nominee{
+		ID:                      uuid.New(),
+		PrincipalID:             principalID,
+		TenantID:                tenantID,
+		Name:                    name,
+		Email:                   email,
+		Phone:                   strings.TrimSpace(phone),
+		Relationship:            relationship,
+		Status:                  "pending_verification",
+		VerificationToken:       auth.GenerateSecureToken(),
+		VerificationTokenExpiry: time.Now().Add(nomineeVerificationTTL),
+	}
+	if err := s.repo.CreateNominee(n); err != nil {
+		return nil, err
+	}
+
+	s.sendVerificationEmail(n)
+	return n, nil
+}
+
+// ResendVerification issues a fresh verification token for a nominee that has not yet verified.
+func (s *NomineeService) ResendVerification(nomineeID, principalID, tenantID uuid.UUID) error {
+	n, err := s.repo.GetNominee(nomineeID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n.PrincipalID != principalID {
+		return errors.New("unauthorized: principal ID mismatch")
+	}
+	if n.Status != "pending_verification" {
+		return errors.New("nominee is not pending verification")
+	}
+
+	n.VerificationToken = auth.GenerateSecureToken()
+	n.VerificationTokenExpiry = time.Now().Add(nomineeVerificationTTL)
+	if err := s.repo.UpdateNominee(n); err != nil {
+		return err
+	}
+	s.sendVerificationEmail(n)
+	return nil
+}
+
+// VerifyNominee confirms the nominee's contact details via the emailed token.
+func (s *NomineeService) VerifyNominee(token string) (*models.Nominee, error) {
+	if token == "" {
+		return nil, errors.New("verification token is missing")
+	}
+	n, err := s.repo.GetNomineeByToken(token)
+	if err != nil {
+		return nil, errors.New("invalid or expired verification token")
+	}
+	if time.Now().After(n.VerificationTokenExpiry) {
+		return nil, errors.New("verification token has expired")
+	}
+
+	now := time.Now()
+	n.Status = "verified"
+	n.VerifiedAt = &now
+	n.VerificationToken = ""
+	if err := s.repo.UpdateNominee(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (s *NomineeService) ListNominees(principalID, tenantID uuid.UUID) ([]models.Nominee, error) {
+	return s.repo.ListNomineesByPrincipal(principalID, tenantID)
+}
+
+// ListNominations returns the nominations naming the caller's email.
+func (s *NomineeService) ListNominations(callerEmail string, tenantID uuid.UUID) ([]models.Nominee, error) {
+	return s.repo.ListNominationsByEmail(strings.TrimSpace(callerEmail), tenantID)
+}
+
+// RevokeNominee withdraws a nomination, which also ends any authority granted through its claims.
+func (s *NomineeService) RevokeNominee(nomineeID, principalID, tenantID uuid.UUID) error {
+	n, err := s.repo.GetNominee(nomineeID, tenantID)
+	if err != nil {
+		return err
+	}
+	if n.PrincipalID != principalID {
+		return errors.New("unauthorized: principal ID mismatch")
+	}
+	if n.Status == "revoked" {
+		return errors.New("nominee is already revoked")
+	}
+
+	now := time.Now()
+	n.Status = "revoked"
+	n.RevokedAt = &now
+	n.VerificationToken = ""
+	return s.repo.UpdateNominee(n)
+}
+
+// SubmitClaim lets a verified nominee ask to act for the principal. The caller is
+// identified by email, which must match a verified nominee record.
+func (s *NomineeService) SubmitClaim(nomineeID, tenantID uuid.UUID, callerEmail, claimType, statement string) (*models.NomineeClaim, error) {
+	n, err := s.repo.GetNominee(nomineeID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(n.Email, strings.TrimSpace(callerEmail)) {
+		return nil, errors.New("unauthorized: caller is not this nominee")
+	}
+	if n.Status != "verified" {
+		return nil, errors.New("nominee must be verified before submitting a claim")
+	}
+	if !validNomineeClaimTypes[claimType] {
+		return nil, fmt.Errorf("invalid claim type: %s", claimType)
+	}
+
+	c := &models.NomineeClaim{
+		ID:          uuid.New(),
+		NomineeID:   n.ID,
+		PrincipalID: n.PrincipalID,
+		TenantID:    tenantID,
+		ClaimType:   claimType,
+		Status:      "submitted",
+		Statement:   statement,
+	}
+	if err := s.repo.CreateClaim(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// AddClaimEvidence stores a supporting document against a submitted claim.
+func (s *NomineeService) AddClaimEvidence(claimID, tenantID uuid.UUID, callerEmail, documentType, fileName, contentType string, data []byte) (*models.NomineeClaimEvidence, error) {
+	c, err := s.repo.GetClaim(claimID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.repo.GetNominee(c.NomineeID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(n.Email, strings.TrimSpace(callerEmail)) {
+		return nil, errors.New("unauthorized: caller is not this nominee")
+	}
+	if c.Status != "submitted" {
+		return nil, errors.New("evidence can only be added to a submitted claim")
+	}
+
+	safeName := blob.SafeName(fileName)
+	key := fmt.Sprintf("nominee-claims/%s/%s/%s_%s", tenantID, claimID, uuid.NewString(), safeName)
+	storedPath, err := s.store.Put(key, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	e := &models.NomineeClaimEvidence{
+		ID:           uuid.New(),
+		ClaimID:      claimID,
+		TenantID:     tenantID,
+		DocumentType: documentType,
+		FileName:     safeName,
+		FilePath:     storedPath,
+		ContentType:  contentType,
+		SizeBytes:    int64(len(data)),
+		SHA256:       hex.EncodeToString(sum[:]),
+		UploadedAt:   time.Now(),
+	}
+	if err := s.repo.AddEvidence(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListClaimsForNominee returns all claims filed under the caller's email.
+func (s *NomineeService) ListClaimsForNominee(callerEmail string, tenantID uuid.UUID) ([]models.NomineeClaim, error) {
+	return s.repo.ListClaimsByNomineeEmail(callerEmail, tenantID)
+}
+
+func (s *NomineeService) ListClaims(tenantID uuid.UUID, status string) ([]models.NomineeClaim, error) {
+	return s.repo.ListClaimsByStatus(tenantID, status)
+}
+
+func (s *NomineeService) GetClaim(claimID, tenantID uuid.UUID) (*models.NomineeClaim, error) {
+	return s.repo.GetClaim(claimID, tenantID)
+}
+
+// GetClaimEvidence returns a claim's evidence file for the reviewers who
+// must assess it
+func (s *NomineeService) GetClaimEvidence(claimID, evidenceID, tenantID uuid.UUID) (*models.NomineeClaimEvidence, []byte, error) {
+	e, err := s.repo.GetEvidence(claimID, evidenceID, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.store.Get(e.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, data, nil
+}
+
+// ReviewClaim records a fiduciary decision on a submitted claim and notifies the nominee.
+func (s *NomineeService) ReviewClaim(claimID, tenantID, reviewerID uuid.UUID, approve bool, note string) (*models.NomineeClaim, error) {
+	c, err := s.repo.GetClaim(claimID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != "submitted" {
+		return nil, errors.New("claim is not pending review")
+	}
+	if approve && len(c.Evidence) == 0 {
+		return nil, errors.New("claim cannot be approved without supporting evidence")
+	}
+
+	now := time.Now()
+	c.ReviewedBy = &reviewerID
+	c.ReviewedAt = &now
+	c.ReviewNote = note
+	if approve {
+		c.Status = "approved"
+	} else {
+		c.Status = "rejected"
+	}
+	if err := s.repo.UpdateClaim(c); err != nil {
+		return nil, err
+	}
+
+	if n, err := s.repo.GetNominee(c.NomineeID, tenantID); err == nil {
+		subject := "Your nominee claim has been " + c.Status
+		body := fmt.Sprintf("Dear %s,<br><br>Your claim to act on behalf of the data principal has been <b>%s</b>.", html.EscapeString(n.Name), c.Status)
+		if note != "" {
+			body += "<br><br>Reviewer note: " + html.EscapeString(note)
+		}
+		if err := s.emailService.Send(n.Email, subject, body); err != nil {
+			log.Logger.Error().Err(err).Str("nominee_id", n.ID.String()).Msg("failed to send nominee claim decision email")
+		}
+	}
+	return c, nil
+}
+
+// RevokeClaim withdraws a previously approved authority.
+func (s *NomineeService) RevokeClaim(claimID, tenantID, reviewerID uuid.UUID, note string) error {
+	c, err := s.repo.GetClaim(claimID, tenantID)
+	if err != nil {
+		return err
+	}
+	if c.Status != "approved" {
+		return errors.New("only approved claims can be revoked")
+	}
+	now := time.Now()
+	c.Status = "revoked"
+	c.ReviewedBy = &reviewerID
+	c.ReviewedAt = &now
+	c.ReviewNote = note
+	return s.repo.UpdateClaim(c)
+}
+
+// ResolveAuthority returns the approved claim under which the caller may act for principalID.
+func (s *NomineeService) ResolveAuthority(callerEmail string, principalID, tenantID uuid.UUID) (*models.NomineeClaim, error) {
+	c, err := s.repo.FindApprovedClaim(callerEmail, principalID, tenantID)
+	if err != nil {
+		return nil, errors.New("no approved nominee authority for this principal")
+	}
+	return c, nil
+}
+
+func (s *NomineeService) sendVerificationEmail(n *models.Nominee) {
+	link := fmt.Sprintf("%s/nominee/verify?token=%s", s.baseURL, n.VerificationToken)
+	body := fmt.Sprintf(`Dear %s,<br><br>You have been named as a nominee under the Digital Personal Data Protection Act, 2023.
+<br>Please confirm your contact details by clicking the link below. The link expires in 72 hours.
+<br><br><a href="%s">Confirm nomination</a>`, html.EscapeString(n.Name), link)
+	if err := s.emailService.Send(n.Email, "Confirm your nomination", body); err != nil {
+		log.Logger.Error().Err(err).Str("nominee_id", n.ID.String()).Msg("failed to send nominee verification email")
+	}
+}
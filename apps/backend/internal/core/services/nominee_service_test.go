@@ -0,0 +1,86 @@
+package services
+
+import (
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupNomineeService(t *testing.T) *NomineeService {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Nominee{}, &models.NomineeClaim{}, &models.NomineeClaimEvidence{}))
+
+	emailService := NewEmailService("", 0, "", "", "noreply@test.local")
+	return NewNomineeService(repository.NewNomineeRepository(db), emailService, "http://localhost", blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false))
+}
+
+func TestNomineeWorkflow_ApproveAndResolveAuthority(t *testing.T) {
+	svc := setupNomineeService(t)
+	principalID, tenantID, reviewerID := uuid.New(), uuid.New(), uuid.New()
+
+	nominee, err := svc.AddNominee(principalID, tenantID, "Asha Rao", "Asha@Example.com", "", "Sibling")
+	require.NoError(t, err)
+	assert.Equal(t, "pending_verification", nominee.Status)
+
+	// Claims are refused until the nominee has verified their contact details
+	_, err = svc.SubmitClaim(nominee.ID, tenantID, "asha@example.com", "death", "")
+	assert.Error(t, err)
+
+	_, err = svc.VerifyNominee(nominee.VerificationToken)
+	require.NoError(t, err)
+
+	claim, err := svc.SubmitClaim(nominee.ID, tenantID, "asha@example.com", "death", "Principal passed away")
+	require.NoError(t, err)
+
+	// Approval requires at least one supporting document
+	_, err = svc.ReviewClaim(claim.ID, tenantID, reviewerID, true, "")
+	assert.Error(t, err)
+
+	evidence, err := svc.AddClaimEvidence(claim.ID, tenantID, "asha@example.com", "death_certificate", "certificate.pdf", "application/pdf", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.Len(t, evidence.SHA256, 64)
+
+	got, data, err := svc.GetClaimEvidence(claim.ID, evidence.ID, tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, "certificate.pdf", got.FileName)
+	assert.Equal(t, []byte("%PDF-1.4"), data)
+	_, _, err = svc.GetClaimEvidence(claim.ID, evidence.ID, uuid.New())
+	assert.Error(t, err, "evidence must not be readable from another tenant")
+
+	_, err = svc.ResolveAuthority("asha@example.com", principalID, tenantID)
+	assert.Error(t, err, "authority must not exist before approval")
+
+	approved, err := svc.ReviewClaim(claim.ID, tenantID, reviewerID, true, "Certificate checked")
+	require.NoError(t, err)
+	assert.Equal(t, "approved", approved.Status)
+
+	resolved, err := svc.ResolveAuthority("asha@example.com", principalID, tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, claim.ID, resolved.ID)
+
+	// Revoking the nominee ends the authority
+	require.NoError(t, svc.RevokeNominee(nominee.ID, principalID, tenantID))
+	_, err = svc.ResolveAuthority("asha@example.com", principalID, tenantID)
+	assert.Error(t, err)
+}
+
+func TestNomineeWorkflow_SubmitClaimRejectsOtherCaller(t *testing.T) {
+	svc := setupNomineeService(t)
+	principalID, tenantID := uuid.New(), uuid.New()
+
+	nominee, err := svc.AddNominee(principalID, tenantID, "Asha Rao", "asha@example.com", "", "Sibling")
+	require.NoError(t, err)
+	_, err = svc.VerifyNominee(nominee.VerificationToken)
+	require.NoError(t, err)
+
+	_, err = svc.SubmitClaim(nominee.ID, tenantID, "someone@else.com", "incapacity", "")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"pixpivot/arc/internal/models"
 	"pixpivot/arc/internal/storage/repository"
 	"crypto/rand"
@@ -552,3 +553,11 @@ func (s *ReceiptService) CleanupExpiredReceipts() error {
 	return s.receiptRepo.DeleteExpiredReceipts()
 }
 
+// RegisterJobs removes expired receipts nightly
+func (s *ReceiptService) RegisterJobs(r *JobRunner) {
+	r.Register("receipts.cleanup", func(ctx context.Context, job *models.Job) error {
+		return s.CleanupExpiredReceipts()
+	})
+	r.Schedule("receipts.cleanup", "0 1 * * *", "receipts.cleanup", nil)
+}
+
@@ -0,0 +1,716 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/xlsx"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRopaEntityNotFound    = errors.New("organization entity not found")
+	ErrRopaVersionNotFound   = errors.New("ROPA version not found")
+	ErrRopaNotDraft          = errors.New("only a draft ROPA version can be signed off")
+	ErrInvalidRopaProfile    = errors.New("invalid ROPA profile")
+	ErrUnsupportedRopaFormat = errors.New("unsupported ROPA export format")
+)
+
+// RopaActivityFields are the hand-filled parts of one processing activity
+type RopaActivityFields struct {
+	DataSubjects     string `json:"dataSubjects,omitempty"`
+	SecurityMeasures string `json:"securityMeasures,omitempty"`
+	Notes            string `json:"notes,omitempty"`
+}
+
+// RopaProfileInput replaces an entity's hand-filled ROPA fields. Activities
+// are keyed by purpose ID; an activity's security measures default to the
+// entity's.
+type RopaProfileInput struct {
+	ControllerContact string                        `json:"controllerContact"`
+	DPOContact        string                        `json:"dpoContact"`
+	SecurityMeasures  string                        `json:"securityMeasures"`
+	Activities        map[string]RopaActivityFields `json:"activities"`
+}
+
+// RopaController identifies the Data Fiduciary the register belongs to
+type RopaController struct {
+	EntityID          uuid.UUID `json:"entityId"`
+	Name              string    `json:"name"`
+	Address           string    `json:"address,omitempty"`
+	Country           string    `json:"country,omitempty"`
+	Email             string    `json:"email,omitempty"`
+	Phone             string    `json:"phone,omitempty"`
+	Website           string    `json:"website,omitempty"`
+	ControllerContact string    `json:"controllerContact,omitempty"`
+	DPOContact        string    `json:"dpoContact,omitempty"`
+}
+
+// RopaRecipient is a vendor or sub-processor personal data is disclosed to
+type RopaRecipient struct {
+	Type     string     `json:"type"` // vendor, sub_processor
+	VendorID *uuid.UUID `json:"vendorId,omitempty"`
+	Name     string     `json:"name"`
+	Via      string     `json:"via,omitempty"`
+	Location string     `json:"location,omitempty"`
+	Country  string     `json:"country,omitempty"`
+	// Agreement describes the DPAs in place, e.g. "DPA-7 (active until 2027-03-31)"
+	Agreement string `json:"agreement,omitempty"`
+}
+
+// RopaActivity is one processing activity: a purpose, what it processes, on
+// what basis, for how long and with whom
+type RopaActivity struct {
+	PurposeID         uuid.UUID       `json:"purposeId"`
+	Purpose           string          `json:"purpose"`
+	Description       string          `json:"description,omitempty"`
+	LegalBasis        string          `json:"legalBasis,omitempty"`
+	RetentionDays     int             `json:"retentionDays"`
+	DataObjects       []string        `json:"dataObjects"`
+	Recipients        []RopaRecipient `json:"recipients"`
+	TransferCountries []string        `json:"transferCountries"`
+	DataSubjects      string          `json:"dataSubjects,omitempty"`
+	SecurityMeasures  string          `json:"securityMeasures,omitempty"`
+	Notes             string          `json:"notes,omitempty"`
+}
+
+// RopaRegister is an entity's Record of Processing Activities
+type RopaRegister struct {
+	Controller  RopaController `json:"controller"`
+	HomeCountry string         `json:"homeCountry"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Activities  []RopaActivity `json:"activities"`
+	// Missing lists fields that are still blank and must be filled in by hand
+	Missing []string `json:"missing"`
+}
+
+// RopaStaleness reports whether the sources of a version changed after it
+// was generated
+type RopaStaleness struct {
+	Stale    bool     `json:"stale"`
+	Warnings []string `json:"warnings"`
+}
+
+// RopaVersionView is a stored version with its register and staleness
+type RopaVersionView struct {
+	Version   *models.RopaVersion `json:"version"`
+	Register  *RopaRegister       `json:"register"`
+	Staleness RopaStaleness       `json:"staleness"`
+}
+
+// RopaEntityStatus summarises the register of one entity
+type RopaEntityStatus struct {
+	EntityID  uuid.UUID           `json:"entityId"`
+	Name      string              `json:"name"`
+	Latest    *models.RopaVersion `json:"latest,omitempty"`
+	Staleness RopaStaleness       `json:"staleness"`
+}
+
+// RopaService assembles each organization entity's Record of Processing
+// Activities from the purposes on its consent forms, their vendors,
+// sub-processors and DPAs, and hand-filled fields. Registers are frozen as
+// versions for sign-off. Purposes live in the tenant schema, so callers load
+// and pass them in.
+type RopaService struct {
+	repo       *repository.RopaRepository
+	vendorRepo repository.VendorRepository
+	subRepo    *repository.SubProcessorRepository
+	dpaRepo    *repository.EncryptedDPARepository
+	transfers  *TransferService
+}
+
+func NewRopaService(
+	repo *repository.RopaRepository,
+	vendorRepo repository.VendorRepository,
+	subRepo *repository.SubProcessorRepository,
+	dpaRepo *repository.EncryptedDPARepository,
+	transfers *TransferService,
+) *RopaService {
+	return &RopaService{repo: repo, vendorRepo: vendorRepo, subRepo: subRepo, dpaRepo: dpaRepo, transfers: transfers}
+}
+
+func (s *RopaService) entity(tenantID, entityID uuid.UUID) (*models.OrganizationEntity, error) {
+	e, err := s.repo.GetEntity(tenantID, entityID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRopaEntityNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// Profile returns the entity's hand-filled fields, blank if never saved
+func (s *RopaService) Profile(tenantID, entityID uuid.UUID) (*models.RopaProfile, error) {
+	if _, err := s.entity(tenantID, entityID); err != nil {
+		return nil, err
+	}
+	p, err := s.repo.GetProfile(tenantID, entityID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.RopaProfile{TenantID: tenantID, OrganizationEntityID: entityID, Activities: []byte("{}")}, nil
+	}
+	return p, err
+}
+
+// SaveProfile replaces the entity's hand-filled fields
+func (s *RopaService) SaveProfile(tenantID, entityID, userID uuid.UUID, in RopaProfileInput) (*models.RopaProfile, error) {
+	if _, err := s.entity(tenantID, entityID); err != nil {
+		return nil, err
+	}
+	activities := map[string]RopaActivityFields{}
+	for key, a := range in.Activities {
+		id, err := uuid.Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: activity key %q is not a purpose ID", ErrInvalidRopaProfile, key)
+		}
+		a.DataSubjects = strings.TrimSpace(a.DataSubjects)
+		a.SecurityMeasures = strings.TrimSpace(a.SecurityMeasures)
+		a.Notes = strings.TrimSpace(a.Notes)
+		if a != (RopaActivityFields{}) {
+			activities[id.String()] = a
+		}
+	}
+	raw, err := json.Marshal(activities)
+	if err != nil {
+		return nil, err
+	}
+	p := &models.RopaProfile{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		OrganizationEntityID: entityID,
+		ControllerContact:    strings.TrimSpace(in.ControllerContact),
+		DPOContact:           strings.TrimSpace(in.DPOContact),
+		SecurityMeasures:     strings.TrimSpace(in.SecurityMeasures),
+		Activities:           raw,
+		UpdatedBy:            userID,
+	}
+	if err := s.repo.SaveProfile(p); err != nil {
+		return nil, err
+	}
+	return s.repo.GetProfile(tenantID, entityID)
+}
+
+// Build assembles the entity's register from the current state of its
+// sources
+func (s *RopaService) Build(tenantID, entityID uuid.UUID, purposes []models.Purpose) (*RopaRegister, error) {
+	e, err := s.entity(tenantID, entityID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.Profile(tenantID, entityID)
+	if err != nil {
+		return nil, err
+	}
+	manual := map[string]RopaActivityFields{}
+	if len(profile.Activities) > 0 {
+		_ = json.Unmarshal(profile.Activities, &manual)
+	}
+	formPurposes, err := s.repo.ListEntityFormPurposes(tenantID, entityID)
+	if err != nil {
+		return nil, err
+	}
+	objects := map[uuid.UUID]map[string]bool{}
+	formVendors := map[uuid.UUID][]string{}
+	for _, fp := range formPurposes {
+		if objects[fp.PurposeID] == nil {
+			objects[fp.PurposeID] = map[string]bool{}
+		}
+		for _, obj := range fp.DataObjects {
+			objects[fp.PurposeID][obj] = true
+		}
+		formVendors[fp.PurposeID] = append(formVendors[fp.PurposeID], fp.VendorIDs...)
+	}
+
+	var active []models.Purpose
+	for _, p := range purposes {
+		if _, ok := objects[p.ID]; !ok || !p.Active {
+			continue
+		}
+		p.Vendors = append(append([]string{}, p.Vendors...), formVendors[p.ID]...)
+		active = append(active, p)
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Name < active[j].Name })
+
+	home := s.transfers.HomeCountry(tenantID)
+	reg := &RopaRegister{
+		Controller: RopaController{
+			EntityID: e.ID, Name: e.Name, Address: e.Address, Country: e.Country, Email: e.Email,
+			Phone: e.Phone, Website: e.Website, ControllerContact: profile.ControllerContact, DPOContact: profile.DPOContact,
+		},
+		HomeCountry: home.Code,
+		GeneratedAt: time.Now().UTC(),
+		Activities:  []RopaActivity{},
+		Missing:     []string{},
+	}
+	if reg.Controller.ControllerContact == "" {
+		reg.Missing = append(reg.Missing, "controller contact")
+	}
+	if profile.SecurityMeasures == "" {
+		reg.Missing = append(reg.Missing, "security measures")
+	}
+
+	vendors, ids := resolvePurposeVendors(s.vendorRepo, active)
+	subs, err := s.subRepo.ListApproved(tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	subsByVendor := map[uuid.UUID][]models.VendorSubProcessor{}
+	for _, sp := range subs {
+		subsByVendor[sp.VendorID] = append(subsByVendor[sp.VendorID], sp)
+	}
+	agreements, err := s.agreements(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range active {
+		m := manual[p.ID.String()]
+		a := RopaActivity{
+			PurposeID:         p.ID,
+			Purpose:           p.Name,
+			Description:       p.Description,
+			LegalBasis:        p.LegalBasis,
+			RetentionDays:     p.RetentionPeriodDays,
+			DataObjects:       sortedKeys(objects[p.ID]),
+			Recipients:        []RopaRecipient{},
+			TransferCountries: []string{},
+			DataSubjects:      m.DataSubjects,
+			SecurityMeasures:  m.SecurityMeasures,
+			Notes:             m.Notes,
+		}
+		if a.SecurityMeasures == "" {
+			a.SecurityMeasures = profile.SecurityMeasures
+		}
+		countries := map[string]bool{}
+		seen := map[string]bool{}
+		for _, ref := range p.Vendors {
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			v := vendors[ref]
+			if v == nil {
+				a.Recipients = append(a.Recipients, RopaRecipient{Type: "vendor", Name: ref})
+				continue
+			}
+			id := v.VendorID
+			rec := RopaRecipient{Type: "vendor", VendorID: &id, Name: v.Company, Location: v.ProcessingLocation,
+				Country: vendorCountry(v), Agreement: agreements[id]}
+			a.Recipients = append(a.Recipients, rec)
+			if rec.Country != "" && rec.Country != home.Code {
+				countries[rec.Country] = true
+			}
+			for i := range subsByVendor[id] {
+				sp := &subsByVendor[id][i]
+				sub := RopaRecipient{Type: "sub_processor", Name: sp.Name, Via: v.Company, Location: sp.Location, Country: subProcessorCountry(sp)}
+				a.Recipients = append(a.Recipients, sub)
+				if sub.Country != "" && sub.Country != home.Code {
+					countries[sub.Country] = true
+				}
+			}
+		}
+		a.TransferCountries = sortedKeys(countries)
+
+		if a.LegalBasis == "" {
+			reg.Missing = append(reg.Missing, fmt.Sprintf("%s: legal basis", p.Name))
+		}
+		if a.RetentionDays == 0 {
+			reg.Missing = append(reg.Missing, fmt.Sprintf("%s: retention period", p.Name))
+		}
+		if a.DataSubjects == "" {
+			reg.Missing = append(reg.Missing, fmt.Sprintf("%s: categories of data principals", p.Name))
+		}
+		reg.Activities = append(reg.Activities, a)
+	}
+	return reg, nil
+}
+
+// agreements describes the tenant's DPAs per vendor
+func (s *RopaService) agreements(tenantID uuid.UUID) (map[uuid.UUID]string, error) {
+	out := map[uuid.UUID]string{}
+	if s.dpaRepo == nil {
+		return out, nil
+	}
+	dpas, err := s.dpaRepo.GetDPAsByTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(dpas, func(i, j int) bool { return dpas[i].AgreementNumber < dpas[j].AgreementNumber })
+	for _, d := range dpas {
+		if d.Status == "terminated" || d.Status == "revoked" {
+			continue
+		}
+		desc := fmt.Sprintf("%s (%s", d.AgreementNumber, d.Status)
+		if d.ExpiryDate != nil {
+			desc += " until " + d.ExpiryDate.Format("2006-01-02")
+		}
+		desc += ")"
+		if out[d.VendorID] != "" {
+			desc = out[d.VendorID] + "; " + desc
+		}
+		out[d.VendorID] = desc
+	}
+	return out, nil
+}
+
+// Generate freezes the current register as a new draft version, superseding
+// any earlier draft
+func (s *RopaService) Generate(tenantID, entityID, userID uuid.UUID, purposes []models.Purpose, changeNote string) (*models.RopaVersion, error) {
+	reg, err := s.Build(tenantID, entityID, purposes)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(reg)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	v := &models.RopaVersion{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		OrganizationEntityID: entityID,
+		Status:               models.RopaDraft,
+		Content:              content,
+		ContentSHA256:        hex.EncodeToString(sum[:]),
+		ChangeNote:           strings.TrimSpace(changeNote),
+		CreatedBy:            userID,
+	}
+	if err := s.repo.CreateVersion(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *RopaService) Versions(tenantID, entityID uuid.UUID) ([]models.RopaVersion, error) {
+	if _, err := s.entity(tenantID, entityID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(tenantID, entityID)
+}
+
+func (s *RopaService) version(tenantID, versionID uuid.UUID) (*models.RopaVersion, *RopaRegister, error) {
+	v, err := s.repo.GetVersion(tenantID, versionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrRopaVersionNotFound
+		}
+		return nil, nil, err
+	}
+	var reg RopaRegister
+	if err := json.Unmarshal(v.Content, &reg); err != nil {
+		return nil, nil, err
+	}
+	return v, &reg, nil
+}
+
+// Version returns a stored version with its register and whether its
+// sources changed since
+func (s *RopaService) Version(tenantID, versionID uuid.UUID, purposes []models.Purpose) (*RopaVersionView, error) {
+	v, reg, err := s.version(tenantID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.Build(tenantID, v.OrganizationEntityID, purposes)
+	if err != nil {
+		return nil, err
+	}
+	return &RopaVersionView{Version: v, Register: reg, Staleness: ropaStaleness(reg, current)}, nil
+}
+
+// SignOff records a reviewer's approval of a draft
+func (s *RopaService) SignOff(tenantID, versionID, userID uuid.UUID, note string) (*models.RopaVersion, error) {
+	v, _, err := s.version(tenantID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.SignOff(v.ID, userID, strings.TrimSpace(note), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRopaNotDraft
+	}
+	return s.repo.GetVersion(tenantID, versionID)
+}
+
+// Status lists each of the tenant's entities with its latest version and
+// whether that version is stale
+func (s *RopaService) Status(tenantID uuid.UUID, purposes []models.Purpose) ([]RopaEntityStatus, error) {
+	entities, err := s.repo.ListEntities(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RopaEntityStatus, 0, len(entities))
+	for _, e := range entities {
+		st := RopaEntityStatus{EntityID: e.ID, Name: e.Name, Staleness: RopaStaleness{Warnings: []string{}}}
+		latest, err := s.repo.LatestVersion(tenantID, e.ID)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			st.Staleness = RopaStaleness{Stale: true, Warnings: []string{"no register has been generated"}}
+		case err != nil:
+			return nil, err
+		default:
+			view, err := s.Version(tenantID, latest.ID, purposes)
+			if err != nil {
+				return nil, err
+			}
+			latest.Content = nil
+			st.Latest = latest
+			st.Staleness = view.Staleness
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// ropaStaleness compares a stored register with the current one and says
+// which purposes, vendors and hand-filled fields changed
+func ropaStaleness(stored, current *RopaRegister) RopaStaleness {
+	st := RopaStaleness{Warnings: []string{}}
+	warn := func(format string, args ...interface{}) {
+		st.Stale = true
+		st.Warnings = append(st.Warnings, fmt.Sprintf(format, args...))
+	}
+	before := map[uuid.UUID]RopaActivity{}
+	for _, a := range stored.Activities {
+		before[a.PurposeID] = a
+	}
+	for _, a := range current.Activities {
+		old, ok := before[a.PurposeID]
+		delete(before, a.PurposeID)
+		if !ok {
+			warn("purpose %q was added", a.Purpose)
+			continue
+		}
+		var changed []string
+		if old.Purpose != a.Purpose || old.Description != a.Description {
+			changed = append(changed, "name or description")
+		}
+		if old.LegalBasis != a.LegalBasis {
+			changed = append(changed, "legal basis")
+		}
+		if old.RetentionDays != a.RetentionDays {
+			changed = append(changed, "retention")
+		}
+		if !reflect.DeepEqual(old.DataObjects, a.DataObjects) {
+			changed = append(changed, "data objects")
+		}
+		if !reflect.DeepEqual(old.Recipients, a.Recipients) {
+			changed = append(changed, "vendors")
+		}
+		if old.DataSubjects != a.DataSubjects || old.SecurityMeasures != a.SecurityMeasures || old.Notes != a.Notes {
+			changed = append(changed, "hand-filled fields")
+		}
+		if len(changed) > 0 {
+			warn("purpose %q changed: %s", a.Purpose, strings.Join(changed, ", "))
+		}
+	}
+	removed := make([]string, 0, len(before))
+	for _, a := range before {
+		removed = append(removed, a.Purpose)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		warn("purpose %q is no longer active on the entity's consent forms", name)
+	}
+	if stored.Controller != current.Controller {
+		warn("controller details changed")
+	}
+	return st
+}
+
+// Export renders a stored version as "xlsx", "csv" or "pdf". It returns the
+// document, its content type and a file name.
+func (s *RopaService) Export(tenantID, versionID uuid.UUID, format string) ([]byte, string, string, error) {
+	if format == "" {
+		format = "xlsx"
+	}
+	v, reg, err := s.version(tenantID, versionID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	name := fmt.Sprintf("ropa-v%d-%s", v.Version, v.OrganizationEntityID.String()[:8])
+	switch format {
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := xlsx.Write(&buf, ropaSheets(v, reg)...); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", name + ".xlsx", nil
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(ropaActivityRows(reg)); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "text/csv", name + ".csv", nil
+	case "pdf":
+		doc, err := renderRopaPDF(v, reg)
+		return doc, "application/pdf", name + ".pdf", err
+	default:
+		return nil, "", "", fmt.Errorf("%w: %q", ErrUnsupportedRopaFormat, format)
+	}
+}
+
+func ropaRetention(days int) string {
+	if days == 0 {
+		return "Not set"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func ropaRecipientText(r RopaRecipient) string {
+	text := r.Name
+	if r.Via != "" {
+		text += " (via " + r.Via + ")"
+	}
+	if r.Country != "" {
+		text += " [" + r.Country + "]"
+	}
+	return text
+}
+
+// ropaActivityRows is the register as a table with a header row
+func ropaActivityRows(reg *RopaRegister) [][]string {
+	rows := [][]string{{
+		"Purpose", "Description", "Legal basis", "Categories of data principals", "Personal data",
+		"Recipients", "Transfers outside " + reg.HomeCountry, "Retention", "Security measures", "Notes",
+	}}
+	for _, a := range reg.Activities {
+		recipients := make([]string, 0, len(a.Recipients))
+		for _, r := range a.Recipients {
+			recipients = append(recipients, ropaRecipientText(r))
+		}
+		rows = append(rows, []string{
+			a.Purpose, a.Description, a.LegalBasis, a.DataSubjects, strings.Join(a.DataObjects, "; "),
+			strings.Join(recipients, "; "), strings.Join(a.TransferCountries, "; "), ropaRetention(a.RetentionDays),
+			a.SecurityMeasures, a.Notes,
+		})
+	}
+	return rows
+}
+
+func ropaSheets(v *models.RopaVersion, reg *RopaRegister) []xlsx.Sheet {
+	signed := ""
+	if v.SignedOffAt != nil {
+		signed = v.SignedOffAt.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	c := reg.Controller
+	controller := xlsx.Sheet{Name: "Controller", Header: true, Rows: [][]string{
+		{"Field", "Value"},
+		{"Data Fiduciary", c.Name},
+		{"Address", c.Address},
+		{"Country", c.Country},
+		{"Email", c.Email},
+		{"Phone", c.Phone},
+		{"Website", c.Website},
+		{"Controller contact", c.ControllerContact},
+		{"Data Protection Officer", c.DPOContact},
+		{"Version", fmt.Sprintf("%d", v.Version)},
+		{"Status", v.Status},
+		{"Generated", reg.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC")},
+		{"Signed off", signed},
+		{"Content SHA-256", v.ContentSHA256},
+	}}
+	recipients := xlsx.Sheet{Name: "Recipients", Header: true, Rows: [][]string{
+		{"Purpose", "Recipient", "Type", "Via", "Processing location", "Country", "Agreement"},
+	}}
+	for _, a := range reg.Activities {
+		for _, r := range a.Recipients {
+			recipients.Rows = append(recipients.Rows, []string{a.Purpose, r.Name, r.Type, r.Via, r.Location, r.Country, r.Agreement})
+		}
+	}
+	return []xlsx.Sheet{
+		controller,
+		{Name: "Processing activities", Header: true, Rows: ropaActivityRows(reg)},
+		recipients,
+	}
+}
+
+func renderRopaPDF(v *models.RopaVersion, reg *RopaRegister) ([]byte, error) {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Arial", "", 7)
+		status := "DRAFT - not signed off"
+		if v.Status == models.RopaSignedOff && v.SignedOffAt != nil {
+			status = "Signed off " + v.SignedOffAt.UTC().Format("02 Jan 2006")
+		}
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("Version %d | %s | content SHA-256 %s", v.Version, status, v.ContentSHA256)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(0, 10, tr("Record of Processing Activities: "+reg.Controller.Name))
+	pdf.Ln(9)
+	pdf.SetFont("Arial", "", 9)
+	pdf.Cell(0, 5, "Generated: "+reg.GeneratedAt.UTC().Format("02 Jan 2006 15:04 UTC"))
+	pdf.Ln(8)
+
+	field := func(label, value string) {
+		if value == "" {
+			value = "-"
+		}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.Cell(55, 5, tr(label))
+		pdf.SetFont("Arial", "", 9)
+		pdf.MultiCell(0, 5, tr(value), "", "L", false)
+	}
+	c := reg.Controller
+	field("Data Fiduciary", c.Name)
+	field("Address", strings.TrimSpace(c.Address+" "+c.Country))
+	field("Contact", c.ControllerContact)
+	field("Data Protection Officer", c.DPOContact)
+	pdf.Ln(4)
+
+	for _, a := range reg.Activities {
+		if pdf.GetY() > 160 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(0, 7, tr(a.Purpose))
+		pdf.Ln(7)
+		recipients := make([]string, 0, len(a.Recipients))
+		for _, r := range a.Recipients {
+			text := ropaRecipientText(r)
+			if r.Agreement != "" {
+				text += " - " + r.Agreement
+			}
+			recipients = append(recipients, text)
+		}
+		field("Description", a.Description)
+		field("Legal basis", a.LegalBasis)
+		field("Data principals", a.DataSubjects)
+		field("Personal data", strings.Join(a.DataObjects, "; "))
+		field("Recipients", strings.Join(recipients, "\n"))
+		field("Transfers outside "+reg.HomeCountry, strings.Join(a.TransferCountries, ", "))
+		field("Retention", ropaRetention(a.RetentionDays))
+		field("Security measures", a.SecurityMeasures)
+		if a.Notes != "" {
+			field("Notes", a.Notes)
+		}
+		pdf.Ln(4)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
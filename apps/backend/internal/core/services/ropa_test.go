@@ -0,0 +1,256 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type ropaFixture struct {
+	svc       *RopaService
+	db        *gorm.DB
+	tenantID  uuid.UUID
+	entityID  uuid.UUID
+	purposes  []models.Purpose
+	crm       models.Vendor
+	kyc       uuid.UUID
+	marketing uuid.UUID
+}
+
+func setupRopa(t *testing.T) *ropaFixture {
+	transfers, subs, dpaRepo, db := setupTransfers(t)
+	require.NoError(t, db.AutoMigrate(&models.OrganizationEntity{}, &models.ConsentForm{}, &models.ConsentFormPurpose{},
+		&models.RopaProfile{}, &models.RopaVersion{}))
+	f := &ropaFixture{
+		svc:       NewRopaService(repository.NewRopaRepository(db), repository.NewVendorRepository(db), repository.NewSubProcessorRepository(db), dpaRepo, transfers),
+		db:        db,
+		tenantID:  uuid.New(),
+		entityID:  uuid.New(),
+		kyc:       uuid.New(),
+		marketing: uuid.New(),
+	}
+	require.NoError(t, db.Create(&models.Tenant{TenantID: f.tenantID, Name: "Acme", Cluster: "mumbai"}).Error)
+	require.NoError(t, db.Create(&models.OrganizationEntity{ID: f.entityID, TenantID: f.tenantID, Name: "Acme Retail Pvt Ltd",
+		Email: "privacy@acme.example", Address: "Bandra, Mumbai", Country: "India"}).Error)
+	require.NoError(t, db.Create(&models.OrganizationEntity{ID: uuid.New(), TenantID: f.tenantID, Name: "Acme Labs",
+		Email: "labs@acme.example"}).Error)
+
+	f.crm = models.Vendor{VendorID: uuid.New(), Company: "CRMCo", Email: "dpo@crm.example", ProcessingLocation: "Oregon, USA"}
+	require.NoError(t, db.Create(&f.crm).Error)
+	expiry := time.Date(2027, 3, 31, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, dpaRepo.CreateDPA(&models.DataProcessingAgreement{ID: uuid.New(), TenantID: f.tenantID, VendorID: f.crm.VendorID,
+		AgreementNumber: "DPA-7", Status: "active", ExpiryDate: &expiry}))
+	_, err := subs.Declare(f.tenantID, f.crm.VendorID, uuid.New(), SubProcessorInput{Name: "MailCo", Location: "Dublin", Services: "Email", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+
+	f.purposes = []models.Purpose{
+		{ID: f.kyc, Name: "KYC", Active: true, LegalBasis: "legal_obligation", RetentionPeriodDays: 3650},
+		{ID: f.marketing, Name: "Marketing", Active: true, LegalBasis: "consent", IsThirdParty: true},
+		{ID: uuid.New(), Name: "Other entity", Active: true},
+	}
+	form := models.ConsentForm{ID: uuid.New(), TenantID: f.tenantID, Name: "Signup", FormLink: "signup", OrganizationEntityID: f.entityID}
+	other := models.ConsentForm{ID: uuid.New(), TenantID: f.tenantID, Name: "Labs", FormLink: "labs"}
+	require.NoError(t, db.Create(&[]models.ConsentForm{form, other}).Error)
+	require.NoError(t, db.Create(&[]models.ConsentFormPurpose{
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: f.kyc, DataObjects: pq.StringArray{"PAN", "Name"}},
+		{ID: uuid.New(), ConsentFormID: form.ID, PurposeID: f.marketing, DataObjects: pq.StringArray{"Email"},
+			VendorIDs: pq.StringArray{f.crm.VendorID.String()}},
+		{ID: uuid.New(), ConsentFormID: other.ID, PurposeID: f.purposes[2].ID, DataObjects: pq.StringArray{"Email"}},
+	}).Error)
+	return f
+}
+
+func TestRopa_BuildsRegisterFromSources(t *testing.T) {
+	f := setupRopa(t)
+	reg, err := f.svc.Build(f.tenantID, f.entityID, f.purposes)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Retail Pvt Ltd", reg.Controller.Name)
+	assert.Equal(t, "IN", reg.HomeCountry)
+	require.Len(t, reg.Activities, 2) // Only purposes on the entity's forms
+
+	kyc := reg.Activities[0]
+	assert.Equal(t, "KYC", kyc.Purpose)
+	assert.Equal(t, []string{"Name", "PAN"}, kyc.DataObjects)
+	assert.Empty(t, kyc.Recipients)
+
+	marketing := reg.Activities[1]
+	require.Len(t, marketing.Recipients, 2)
+	assert.Equal(t, "CRMCo", marketing.Recipients[0].Name)
+	assert.Equal(t, "US", marketing.Recipients[0].Country)
+	assert.Equal(t, "DPA-7 (active until 2027-03-31)", marketing.Recipients[0].Agreement)
+	assert.Equal(t, "MailCo", marketing.Recipients[1].Name)
+	assert.Equal(t, "CRMCo", marketing.Recipients[1].Via)
+	assert.Equal(t, []string{"IE", "US"}, marketing.TransferCountries)
+
+	assert.Contains(t, reg.Missing, "controller contact")
+	assert.Contains(t, reg.Missing, "security measures")
+	assert.Contains(t, reg.Missing, "Marketing: retention period")
+	assert.Contains(t, reg.Missing, "KYC: categories of data principals")
+
+	_, err = f.svc.Build(uuid.New(), f.entityID, f.purposes)
+	assert.ErrorIs(t, err, ErrRopaEntityNotFound)
+}
+
+func TestRopa_ProfileFillsManualFields(t *testing.T) {
+	f := setupRopa(t)
+	userID := uuid.New()
+	_, err := f.svc.SaveProfile(f.tenantID, f.entityID, userID, RopaProfileInput{Activities: map[string]RopaActivityFields{"kyc": {}}})
+	assert.ErrorIs(t, err, ErrInvalidRopaProfile)
+
+	_, err = f.svc.SaveProfile(f.tenantID, f.entityID, userID, RopaProfileInput{
+		ControllerContact: "Grievance Officer, privacy@acme.example",
+		DPOContact:        "dpo@acme.example",
+		SecurityMeasures:  "Encryption at rest; role-based access",
+		Activities: map[string]RopaActivityFields{
+			f.kyc.String(): {DataSubjects: "Customers", SecurityMeasures: "Vaulted PAN storage"},
+		},
+	})
+	require.NoError(t, err)
+	// Saving again replaces the profile
+	p, err := f.svc.SaveProfile(f.tenantID, f.entityID, userID, RopaProfileInput{
+		ControllerContact: "Grievance Officer, privacy@acme.example",
+		SecurityMeasures:  "Encryption at rest; role-based access",
+		Activities: map[string]RopaActivityFields{
+			f.kyc.String():       {DataSubjects: "Customers", SecurityMeasures: "Vaulted PAN storage"},
+			f.marketing.String(): {DataSubjects: "Subscribers"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, p.DPOContact)
+
+	reg, err := f.svc.Build(f.tenantID, f.entityID, f.purposes)
+	require.NoError(t, err)
+	assert.Equal(t, "Vaulted PAN storage", reg.Activities[0].SecurityMeasures)
+	assert.Equal(t, "Encryption at rest; role-based access", reg.Activities[1].SecurityMeasures) // Entity default
+	assert.Equal(t, "Subscribers", reg.Activities[1].DataSubjects)
+	assert.NotContains(t, reg.Missing, "controller contact")
+	assert.Equal(t, []string{"Marketing: retention period"}, reg.Missing)
+}
+
+func TestRopa_VersionsSignOffAndStaleness(t *testing.T) {
+	f := setupRopa(t)
+	userID, reviewer := uuid.New(), uuid.New()
+
+	v1, err := f.svc.Generate(f.tenantID, f.entityID, userID, f.purposes, "Initial register")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+	assert.Equal(t, models.RopaDraft, v1.Status)
+	assert.Len(t, v1.ContentSHA256, 64)
+
+	// A second draft supersedes the first
+	v2, err := f.svc.Generate(f.tenantID, f.entityID, userID, f.purposes, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+	_, err = f.svc.SignOff(f.tenantID, v1.ID, reviewer, "")
+	assert.ErrorIs(t, err, ErrRopaNotDraft)
+
+	signed, err := f.svc.SignOff(f.tenantID, v2.ID, reviewer, "Reviewed with legal")
+	require.NoError(t, err)
+	assert.Equal(t, models.RopaSignedOff, signed.Status)
+	require.NotNil(t, signed.SignedOffBy)
+	assert.Equal(t, reviewer, *signed.SignedOffBy)
+	_, err = f.svc.SignOff(f.tenantID, v2.ID, reviewer, "")
+	assert.ErrorIs(t, err, ErrRopaNotDraft)
+	_, err = f.svc.SignOff(uuid.New(), v2.ID, reviewer, "")
+	assert.ErrorIs(t, err, ErrRopaVersionNotFound)
+
+	view, err := f.svc.Version(f.tenantID, v2.ID, f.purposes)
+	require.NoError(t, err)
+	assert.False(t, view.Staleness.Stale)
+	assert.Len(t, view.Register.Activities, 2)
+
+	// Change a purpose and a vendor after sign-off
+	f.purposes[0].RetentionPeriodDays = 1825
+	f.purposes[1].Active = false
+	require.NoError(t, f.db.Model(&models.Vendor{}).Where("vendor_id = ?", f.crm.VendorID).
+		Update("processing_location", "Frankfurt, Germany").Error)
+	f.purposes = append(f.purposes, models.Purpose{ID: uuid.New(), Name: "Analytics", Active: true})
+	require.NoError(t, f.db.Create(&models.ConsentFormPurpose{ID: uuid.New(), ConsentFormID: f.formID(t), PurposeID: f.purposes[3].ID,
+		DataObjects: pq.StringArray{"Device ID"}}).Error)
+
+	view, err = f.svc.Version(f.tenantID, v2.ID, f.purposes)
+	require.NoError(t, err)
+	assert.True(t, view.Staleness.Stale)
+	assert.Equal(t, []string{
+		`purpose "Analytics" was added`,
+		`purpose "KYC" changed: retention`,
+		`purpose "Marketing" is no longer active on the entity's consent forms`,
+	}, view.Staleness.Warnings)
+
+	f.purposes[1].Active = true
+	view, err = f.svc.Version(f.tenantID, v2.ID, f.purposes)
+	require.NoError(t, err)
+	assert.Contains(t, view.Staleness.Warnings, `purpose "Marketing" changed: vendors`)
+
+	status, err := f.svc.Status(f.tenantID, f.purposes)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	assert.Equal(t, "Acme Labs", status[0].Name)
+	assert.True(t, status[0].Staleness.Stale)
+	assert.Nil(t, status[0].Latest)
+	require.NotNil(t, status[1].Latest)
+	assert.Equal(t, v2.ID, status[1].Latest.ID)
+	assert.True(t, status[1].Staleness.Stale)
+}
+
+func (f *ropaFixture) formID(t *testing.T) uuid.UUID {
+	var form models.ConsentForm
+	require.NoError(t, f.db.First(&form, "organization_entity_id = ?", f.entityID).Error)
+	return form.ID
+}
+
+func TestRopa_Export(t *testing.T) {
+	f := setupRopa(t)
+	v, err := f.svc.Generate(f.tenantID, f.entityID, uuid.New(), f.purposes, "")
+	require.NoError(t, err)
+
+	doc, contentType, filename, err := f.svc.Export(f.tenantID, v.ID, "csv")
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", contentType)
+	assert.Equal(t, "ropa-v1-"+f.entityID.String()[:8]+".csv", filename)
+	rows, err := csv.NewReader(bytes.NewReader(doc)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Purpose", rows[0][0])
+	assert.Equal(t, "Transfers outside IN", rows[0][6])
+	assert.Equal(t, "Marketing", rows[2][0])
+	assert.Equal(t, "CRMCo [US]; MailCo (via CRMCo) [IE]", rows[2][5])
+
+	doc, contentType, _, err = f.svc.Export(f.tenantID, v.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", contentType)
+	zr, err := zip.NewReader(bytes.NewReader(doc), int64(len(doc)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[zf.Name] = string(body)
+	}
+	assert.Contains(t, files["xl/workbook.xml"], `name="Processing activities"`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], "Acme Retail Pvt Ltd")
+	assert.Contains(t, files["xl/worksheets/sheet3.xml"], "DPA-7 (active until 2027-03-31)")
+
+	doc, contentType, _, err = f.svc.Export(f.tenantID, v.ID, "pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", contentType)
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF")))
+
+	_, _, _, err = f.svc.Export(f.tenantID, v.ID, "docx")
+	assert.ErrorIs(t, err, ErrUnsupportedRopaFormat)
+}
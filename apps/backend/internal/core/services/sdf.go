@@ -0,0 +1,840 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTenantNotFound          = errors.New("tenant not found")
+	ErrNotSDF                  = errors.New("the tenant is not designated a Significant Data Fiduciary")
+	ErrSDFObligationNotFound   = errors.New("SDF obligation not found")
+	ErrSDFEvidenceNotFound     = errors.New("SDF evidence not found")
+	ErrDPONotFound             = errors.New("Data Protection Officer not found")
+	ErrInvalidSDFObligation    = errors.New("invalid SDF obligation")
+	ErrInvalidDPO              = errors.New("invalid Data Protection Officer")
+	ErrInvalidSDFCalendarRange = errors.New("invalid calendar range")
+	ErrDPONotInIndia           = errors.New("a Significant Data Fiduciary's Data Protection Officer must be based in India")
+	ErrDPOAppointmentEnded     = errors.New("the appointment has ended; appoint a new Data Protection Officer instead")
+	ErrSDFObligationClosed     = errors.New("the obligation has been met and is not due again")
+	ErrSDFObligationHasHistory = errors.New("an obligation that has been completed cannot be deleted")
+	ErrSDFEvidenceRequired     = errors.New("upload evidence before completing this obligation")
+)
+
+// sdfDPOCountry is where a Significant Data Fiduciary's DPO must be based,
+// per DPDP Section 10(2)(a)
+const sdfDPOCountry = "IN"
+
+// sdfDueSoon is how far ahead an obligation counts as coming due
+const sdfDueSoon = 30 * 24 * time.Hour
+
+// sdfCalendarMaxRange caps how far a calendar request may span
+const sdfCalendarMaxRange = 5 * 366 * 24 * time.Hour
+
+// sdfDefault is an obligation seeded when a tenant is designated
+type sdfDefault struct {
+	typ, title, description, reference string
+	recurrenceMonths                   int
+	dueAfter                           func(time.Time) time.Time
+	evidenceRequired                   bool
+}
+
+var sdfDefaults = []sdfDefault{
+	{
+		typ:         models.SDFObligationDPO,
+		title:       "Appoint a Data Protection Officer based in India",
+		description: "The DPO represents the Significant Data Fiduciary, is responsible to its Board of Directors and is the point of contact for grievance redressal. Completed automatically when an India-based DPO is entered in the registry.",
+		reference:   "Section 10(2)(a)",
+		dueAfter:    func(t time.Time) time.Time { return t.AddDate(0, 0, 30) },
+	},
+	{
+		typ:              models.SDFObligationIndependentAudit,
+		title:            "Engage an independent data auditor",
+		description:      "Appoint or renew an independent data auditor to evaluate compliance with the Act. Upload the engagement letter.",
+		reference:        "Section 10(2)(b)",
+		recurrenceMonths: 12,
+		dueAfter:         func(t time.Time) time.Time { return t.AddDate(0, 3, 0) },
+		evidenceRequired: true,
+	},
+	{
+		typ:              models.SDFObligationDPIA,
+		title:            "Carry out a Data Protection Impact Assessment",
+		description:      "Assess the rights of the Data Principals affected by the processing, its purpose and how the risks to them are managed. Upload the approved DPIA report.",
+		reference:        "Section 10(2)(c)(i)",
+		recurrenceMonths: 12,
+		dueAfter:         func(t time.Time) time.Time { return t.AddDate(1, 0, 0) },
+		evidenceRequired: true,
+	},
+	{
+		typ:              models.SDFObligationPeriodicAudit,
+		title:            "Periodic data audit",
+		description:      "Have the independent data auditor audit compliance with the Act and report its significant observations to the Board. Upload the audit report.",
+		reference:        "Section 10(2)(c)(ii)",
+		recurrenceMonths: 12,
+		dueAfter:         func(t time.Time) time.Time { return t.AddDate(1, 0, 0) },
+		evidenceRequired: true,
+	},
+}
+
+// SDFService runs the duties of a Significant Data Fiduciary: the
+// designation itself, the obligations calendar with its evidence, and the
+// DPO registry
+type SDFService struct {
+	repo          *repository.SDFRepository
+	tenantRepo    *repository.TenantRepository
+	store         *blob.Store
+	notifications *NotificationService
+}
+
+func NewSDFService(repo *repository.SDFRepository, tenantRepo *repository.TenantRepository, store *blob.Store, notifications *NotificationService) *SDFService {
+	return &SDFService{repo: repo, tenantRepo: tenantRepo, store: store, notifications: notifications}
+}
+
+// DPOContact is the part of the DPO registry published to Data Principals
+type DPOContact struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+	Country string `json:"country"`
+}
+
+// SDFStatus is a tenant's designation with a summary of its obligations and
+// what needs attention
+type SDFStatus struct {
+	Designated   bool                          `json:"designated"`
+	DesignatedAt *time.Time                    `json:"designatedAt,omitempty"`
+	DPO          *models.DataProtectionOfficer `json:"dpo,omitempty"`
+	Summary      *models.SDFAnalytics          `json:"summary"`
+	Issues       []string                      `json:"issues"`
+}
+
+// SDFDesignationInput sets or clears the designation. DesignatedAt is the
+// date of the notification and defaults to now.
+type SDFDesignationInput struct {
+	Designated   bool       `json:"designated"`
+	DesignatedAt *time.Time `json:"designatedAt"`
+}
+
+// SDFObligationInput creates or replaces an obligation
+type SDFObligationInput struct {
+	Type             string     `json:"type"`
+	Title            string     `json:"title"`
+	Description      string     `json:"description"`
+	Reference        string     `json:"reference"`
+	OwnerID          *uuid.UUID `json:"ownerId"`
+	Owner            string     `json:"owner"`
+	RecurrenceMonths int        `json:"recurrenceMonths"`
+	NextDueAt        *time.Time `json:"nextDueAt"`
+	EvidenceRequired bool       `json:"evidenceRequired"`
+}
+
+// SDFObligationView is an obligation with whether it is overdue
+type SDFObligationView struct {
+	models.SDFObligation
+	Overdue bool `json:"overdue"`
+}
+
+// SDFCalendarEntry is one due date on the obligations calendar
+type SDFCalendarEntry struct {
+	ObligationID uuid.UUID  `json:"obligationId"`
+	Type         string     `json:"type"`
+	Title        string     `json:"title"`
+	OwnerID      *uuid.UUID `json:"ownerId,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	DueAt        time.Time  `json:"dueAt"`
+	Overdue      bool       `json:"overdue"`
+	// Projected marks a later recurrence. Recurring obligations fall due
+	// again counting from each completion, so these dates are estimates.
+	Projected   bool       `json:"projected"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// SDFEvidenceUpload describes an uploaded evidence file
+type SDFEvidenceUpload struct {
+	FileName    string
+	ContentType string
+	Note        string
+}
+
+// DPOInput appoints a DPO or corrects the current one's details. Country
+// accepts a code or a name and is read from the address when left empty.
+type DPOInput struct {
+	Name        string     `json:"name"`
+	Email       string     `json:"email"`
+	Phone       string     `json:"phone"`
+	Address     string     `json:"address"`
+	Country     string     `json:"country"`
+	AppointedAt *time.Time `json:"appointedAt"`
+}
+
+func (s *SDFService) tenant(tenantID uuid.UUID) (*models.Tenant, error) {
+	t, err := s.tenantRepo.GetByID(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTenantNotFound
+	}
+	return t, err
+}
+
+func (s *SDFService) requireSDF(tenantID uuid.UUID) (*models.Tenant, error) {
+	t, err := s.tenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.SignificantDataFiduciary {
+		return nil, ErrNotSDF
+	}
+	return t, nil
+}
+
+// Designation
+
+// sdfAnalytics counts what a tenant's SDF dashboard and alerts report on
+func sdfAnalytics(repo *repository.SDFRepository, t *models.Tenant, now time.Time) (*models.SDFAnalytics, error) {
+	a := &models.SDFAnalytics{Designated: t.SignificantDataFiduciary}
+	var err error
+	if a.TotalObligations, err = repo.CountObligations(t.TenantID); err != nil {
+		return nil, err
+	}
+	if a.OverdueObligations, a.DueWithin30Days, err = repo.CountDue(t.TenantID, now, now.Add(sdfDueSoon)); err != nil {
+		return nil, err
+	}
+	dpo, err := repo.CurrentDPO(t.TenantID)
+	switch {
+	case err == nil:
+		a.DPOAppointed = true
+		a.DPOInIndia = dpo.Country == sdfDPOCountry
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	return a, nil
+}
+
+// Status returns the tenant's designation, its DPO and what needs attention
+func (s *SDFService) Status(tenantID uuid.UUID) (*SDFStatus, error) {
+	t, err := s.tenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := sdfAnalytics(s.repo, t, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	st := &SDFStatus{Designated: t.SignificantDataFiduciary, DesignatedAt: t.SDFDesignatedAt, Summary: summary, Issues: []string{}}
+	if st.DPO, err = s.CurrentDPO(tenantID); err != nil {
+		return nil, err
+	}
+	if !st.Designated {
+		return st, nil
+	}
+	switch {
+	case !summary.DPOAppointed:
+		st.Issues = append(st.Issues, "no Data Protection Officer is appointed")
+	case !summary.DPOInIndia:
+		st.Issues = append(st.Issues, "the Data Protection Officer is not based in India")
+	}
+	if summary.OverdueObligations > 0 {
+		st.Issues = append(st.Issues, fmt.Sprintf("%d obligation(s) overdue", summary.OverdueObligations))
+	}
+	return st, nil
+}
+
+// Designate sets or clears the tenant's SDF designation. The first
+// designation seeds the calendar with the Section 10(2) duties, timed from
+// the designation date; a later one keeps the calendar as it was left.
+// Clearing it keeps the calendar and its history but stops reminders.
+func (s *SDFService) Designate(tenantID, userID uuid.UUID, in SDFDesignationInput) (*SDFStatus, error) {
+	t, err := s.tenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !in.Designated {
+		t.SignificantDataFiduciary = false
+		t.SDFDesignatedAt = nil
+		if err := s.tenantRepo.Update(t); err != nil {
+			return nil, err
+		}
+		return s.Status(tenantID)
+	}
+
+	at := time.Now().UTC()
+	if in.DesignatedAt != nil {
+		at = in.DesignatedAt.UTC()
+	}
+	t.SignificantDataFiduciary = true
+	t.SDFDesignatedAt = &at
+	if err := s.tenantRepo.Update(t); err != nil {
+		return nil, err
+	}
+	n, err := s.repo.CountObligations(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if err := s.seed(tenantID, userID, at); err != nil {
+			return nil, err
+		}
+	}
+	return s.Status(tenantID)
+}
+
+func (s *SDFService) seed(tenantID, userID uuid.UUID, at time.Time) error {
+	list := make([]models.SDFObligation, 0, len(sdfDefaults))
+	for _, d := range sdfDefaults {
+		due := d.dueAfter(at)
+		list = append(list, models.SDFObligation{
+			ID:               uuid.New(),
+			TenantID:         tenantID,
+			Type:             d.typ,
+			Title:            d.title,
+			Description:      d.description,
+			Reference:        d.reference,
+			RecurrenceMonths: d.recurrenceMonths,
+			NextDueAt:        &due,
+			Status:           models.SDFObligationOpen,
+			EvidenceRequired: d.evidenceRequired,
+			CreatedBy:        userID,
+		})
+	}
+	if err := s.repo.CreateObligations(list); err != nil {
+		return err
+	}
+	dpo, err := s.CurrentDPO(tenantID)
+	if err != nil || dpo == nil {
+		return err
+	}
+	return s.completeDPOObligations(tenantID, dpo)
+}
+
+// Obligations
+
+func (s *SDFService) obligation(tenantID, id uuid.UUID) (*models.SDFObligation, error) {
+	o, err := s.repo.GetObligation(tenantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSDFObligationNotFound
+	}
+	return o, err
+}
+
+func sdfObligationType(typ string) bool {
+	if typ == models.SDFObligationCustom {
+		return true
+	}
+	for _, d := range sdfDefaults {
+		if d.typ == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// apply validates the input and copies it onto o. A completed one-off is
+// reopened by giving it a new due date.
+func (s *SDFService) apply(o *models.SDFObligation, in SDFObligationInput) error {
+	in.Type = strings.TrimSpace(in.Type)
+	if in.Type == "" {
+		in.Type = models.SDFObligationCustom
+	}
+	if !sdfObligationType(in.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidSDFObligation, in.Type)
+	}
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return fmt.Errorf("%w: a title is required", ErrInvalidSDFObligation)
+	}
+	if in.RecurrenceMonths < 0 || in.RecurrenceMonths > 60 {
+		return fmt.Errorf("%w: recurrence must be between 0 and 60 months", ErrInvalidSDFObligation)
+	}
+	if in.NextDueAt == nil && o.Status != models.SDFObligationCompleted {
+		return fmt.Errorf("%w: a due date is required", ErrInvalidSDFObligation)
+	}
+	owner := strings.TrimSpace(in.Owner)
+	if in.OwnerID != nil {
+		name, err := s.repo.UserName(o.TenantID, *in.OwnerID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: the owner is not a user of this organisation", ErrInvalidSDFObligation)
+		} else if err != nil {
+			return err
+		}
+		if owner == "" {
+			owner = name
+		}
+	}
+
+	o.Type = in.Type
+	o.Title = in.Title
+	o.Description = strings.TrimSpace(in.Description)
+	o.Reference = strings.TrimSpace(in.Reference)
+	o.OwnerID = in.OwnerID
+	o.Owner = owner
+	o.RecurrenceMonths = in.RecurrenceMonths
+	o.EvidenceRequired = in.EvidenceRequired
+	if in.NextDueAt != nil {
+		due := in.NextDueAt.UTC()
+		if o.NextDueAt == nil || !o.NextDueAt.Equal(due) {
+			o.OverdueNotifiedAt = nil
+		}
+		o.NextDueAt = &due
+		o.Status = models.SDFObligationOpen
+	}
+	return nil
+}
+
+// ListObligations returns the tenant's obligations, soonest due first
+func (s *SDFService) ListObligations(tenantID uuid.UUID) ([]SDFObligationView, error) {
+	list, err := s.repo.ListObligations(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]SDFObligationView, 0, len(list))
+	for _, o := range list {
+		out = append(out, SDFObligationView{SDFObligation: o, Overdue: sdfOverdue(&o, now)})
+	}
+	return out, nil
+}
+
+func sdfOverdue(o *models.SDFObligation, now time.Time) bool {
+	return o.Status == models.SDFObligationOpen && o.NextDueAt != nil && o.NextDueAt.Before(now)
+}
+
+func (s *SDFService) CreateObligation(tenantID, userID uuid.UUID, in SDFObligationInput) (*models.SDFObligation, error) {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return nil, err
+	}
+	o := &models.SDFObligation{ID: uuid.New(), TenantID: tenantID, Status: models.SDFObligationOpen, CreatedBy: userID}
+	if err := s.apply(o, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateObligation(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// UpdateObligation replaces an obligation's details, owner and schedule
+func (s *SDFService) UpdateObligation(tenantID, id uuid.UUID, in SDFObligationInput) (*models.SDFObligation, error) {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return nil, err
+	}
+	o, err := s.obligation(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(o, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveObligation(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// DeleteObligation removes an obligation that has never been completed,
+// with any evidence uploaded for it
+func (s *SDFService) DeleteObligation(tenantID, id uuid.UUID) error {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return err
+	}
+	if _, err := s.obligation(tenantID, id); err != nil {
+		return err
+	}
+	done, err := s.repo.ListCompletions(tenantID, id)
+	if err != nil {
+		return err
+	}
+	if len(done) > 0 {
+		return ErrSDFObligationHasHistory
+	}
+	return s.repo.DeleteObligation(tenantID, id)
+}
+
+// Complete records that the obligation's current due date was met and,
+// for a recurring obligation, sets the next one RecurrenceMonths from now.
+// Evidence uploaded since the last completion is attached to this one.
+func (s *SDFService) Complete(tenantID, id, userID uuid.UUID, note string) (*models.SDFObligationCompletion, error) {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return nil, err
+	}
+	o, err := s.obligation(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.complete(o, &userID, note)
+}
+
+func (s *SDFService) complete(o *models.SDFObligation, by *uuid.UUID, note string) (*models.SDFObligationCompletion, error) {
+	if o.Status != models.SDFObligationOpen || o.NextDueAt == nil {
+		return nil, ErrSDFObligationClosed
+	}
+	if o.EvidenceRequired {
+		n, err := s.repo.CountPendingEvidence(o.ID)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrSDFEvidenceRequired
+		}
+	}
+	now := time.Now().UTC()
+	c := &models.SDFObligationCompletion{
+		ID:           uuid.New(),
+		TenantID:     o.TenantID,
+		ObligationID: o.ID,
+		DueAt:        *o.NextDueAt,
+		CompletedAt:  now,
+		CompletedBy:  by,
+		Note:         strings.TrimSpace(note),
+		Late:         now.After(*o.NextDueAt),
+	}
+	fields := map[string]interface{}{"last_completed_at": now, "overdue_notified_at": nil}
+	if o.RecurrenceMonths > 0 {
+		fields["next_due_at"] = now.AddDate(0, o.RecurrenceMonths, 0)
+	} else {
+		fields["next_due_at"] = nil
+		fields["status"] = models.SDFObligationCompleted
+	}
+	if err := s.repo.Complete(c, fields); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCompletions returns an obligation's completions, newest first
+func (s *SDFService) ListCompletions(tenantID, id uuid.UUID) ([]models.SDFObligationCompletion, error) {
+	if _, err := s.obligation(tenantID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListCompletions(tenantID, id)
+}
+
+// Calendar lists the due dates falling in [from, to): completed ones, the
+// current due date of each open obligation and its projected recurrences.
+// Overdue obligations are listed even when due before from.
+func (s *SDFService) Calendar(tenantID uuid.UUID, from, to time.Time) ([]SDFCalendarEntry, error) {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return nil, err
+	}
+	if !to.After(from) || to.Sub(from) > sdfCalendarMaxRange {
+		return nil, ErrInvalidSDFCalendarRange
+	}
+	list, err := s.repo.ListObligations(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uuid.UUID]*models.SDFObligation{}
+	now := time.Now()
+	entries := []SDFCalendarEntry{}
+	for i := range list {
+		o := &list[i]
+		byID[o.ID] = o
+		if o.Status != models.SDFObligationOpen || o.NextDueAt == nil {
+			continue
+		}
+		entry := SDFCalendarEntry{ObligationID: o.ID, Type: o.Type, Title: o.Title, OwnerID: o.OwnerID, Owner: o.Owner}
+		due := *o.NextDueAt
+		if due.Before(to) && (!due.Before(from) || sdfOverdue(o, now)) {
+			e := entry
+			e.DueAt, e.Overdue = due, sdfOverdue(o, now)
+			entries = append(entries, e)
+		}
+		if o.RecurrenceMonths == 0 {
+			continue
+		}
+		for n := 1; ; n++ {
+			next := due.AddDate(0, n*o.RecurrenceMonths, 0)
+			if !next.Before(to) {
+				break
+			}
+			if next.Before(from) {
+				continue
+			}
+			e := entry
+			e.DueAt, e.Projected = next, true
+			entries = append(entries, e)
+		}
+	}
+
+	done, err := s.repo.ListCompletionsBetween(tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range done {
+		c := &done[i]
+		o, ok := byID[c.ObligationID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, SDFCalendarEntry{
+			ObligationID: o.ID, Type: o.Type, Title: o.Title, OwnerID: o.OwnerID, Owner: o.Owner,
+			DueAt: c.DueAt, CompletedAt: &c.CompletedAt,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DueAt.Before(entries[j].DueAt) })
+	return entries, nil
+}
+
+// Evidence
+
+// UploadEvidence stores a file against the obligation's current due date
+func (s *SDFService) UploadEvidence(tenantID, id, userID uuid.UUID, in SDFEvidenceUpload, data []byte) (*models.SDFEvidence, error) {
+	if _, err := s.requireSDF(tenantID); err != nil {
+		return nil, err
+	}
+	o, err := s.obligation(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != models.SDFObligationOpen || o.NextDueAt == nil {
+		return nil, ErrSDFObligationClosed
+	}
+	if len(data) == 0 {
+		return nil, ErrEvidenceEmpty
+	}
+	e := &models.SDFEvidence{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		ObligationID: o.ID,
+		DueAt:        *o.NextDueAt,
+		FileName:     blob.SafeName(in.FileName),
+		ContentType:  in.ContentType,
+		SizeBytes:    int64(len(data)),
+		SHA256:       sha256Hex(data),
+		Note:         strings.TrimSpace(in.Note),
+		UploadedBy:   userID,
+	}
+	if e.ContentType == "" {
+		e.ContentType = "application/octet-stream"
+	}
+	key := fmt.Sprintf("sdf-evidence/%s/%s/%s/%s", tenantID, o.ID, e.ID, e.FileName)
+	if e.FilePath, err = s.store.Put(key, e.ContentType, data); err != nil {
+		return nil, fmt.Errorf("failed to store evidence: %w", err)
+	}
+	if err := s.repo.CreateEvidence(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListEvidence returns an obligation's evidence, newest first
+func (s *SDFService) ListEvidence(tenantID, id uuid.UUID) ([]models.SDFEvidence, error) {
+	if _, err := s.obligation(tenantID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvidence(tenantID, id)
+}
+
+// DownloadEvidence returns an evidence record and its file
+func (s *SDFService) DownloadEvidence(tenantID, evidenceID uuid.UUID) (*models.SDFEvidence, []byte, error) {
+	e, err := s.repo.GetEvidence(tenantID, evidenceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrSDFEvidenceNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.store.Get(e.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, data, nil
+}
+
+// DPO registry
+
+// CurrentDPO returns the tenant's active DPO, or nil when none is appointed
+func (s *SDFService) CurrentDPO(tenantID uuid.UUID) (*models.DataProtectionOfficer, error) {
+	d, err := s.repo.CurrentDPO(tenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// PublicDPO returns the contact details published to Data Principals, or
+// nil when no DPO is appointed
+func (s *SDFService) PublicDPO(tenantID uuid.UUID) (*DPOContact, error) {
+	d, err := s.CurrentDPO(tenantID)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	return &DPOContact{Name: d.Name, Email: d.Email, Phone: d.Phone, Address: d.Address, Country: d.Country}, nil
+}
+
+// ListDPOs returns every appointment, most recent first
+func (s *SDFService) ListDPOs(tenantID uuid.UUID) ([]models.DataProtectionOfficer, error) {
+	return s.repo.ListDPOs(tenantID)
+}
+
+// applyDPO validates the input and copies it onto d. A Significant Data
+// Fiduciary's DPO must be based in India.
+func (s *SDFService) applyDPO(d *models.DataProtectionOfficer, in DPOInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Email = strings.TrimSpace(in.Email)
+	if in.Name == "" {
+		return fmt.Errorf("%w: a name is required", ErrInvalidDPO)
+	}
+	if _, err := mail.ParseAddress(in.Email); err != nil {
+		return fmt.Errorf("%w: a valid email address is required", ErrInvalidDPO)
+	}
+	country, err := ResolveCountry(in.Country, in.Address)
+	if err != nil {
+		return err
+	}
+	t, err := s.tenant(d.TenantID)
+	if err != nil {
+		return err
+	}
+	if t.SignificantDataFiduciary && country.Code != sdfDPOCountry {
+		return ErrDPONotInIndia
+	}
+	d.Name = in.Name
+	d.Email = in.Email
+	d.Phone = strings.TrimSpace(in.Phone)
+	d.Address = strings.TrimSpace(in.Address)
+	d.Country = country.Code
+	if in.AppointedAt != nil {
+		d.AppointedAt = in.AppointedAt.UTC()
+	}
+	return nil
+}
+
+// AppointDPO records a new DPO, ending the current appointment. For a
+// Significant Data Fiduciary it also completes the open DPO appointment
+// obligation.
+func (s *SDFService) AppointDPO(tenantID, userID uuid.UUID, in DPOInput) (*models.DataProtectionOfficer, error) {
+	d := &models.DataProtectionOfficer{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Active:      true,
+		AppointedAt: time.Now().UTC(),
+		AppointedBy: userID,
+	}
+	if err := s.applyDPO(d, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AppointDPO(d); err != nil {
+		return nil, err
+	}
+	t, err := s.tenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if t.SignificantDataFiduciary {
+		if err := s.completeDPOObligations(tenantID, d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// UpdateDPO corrects the current DPO's details
+func (s *SDFService) UpdateDPO(tenantID, id uuid.UUID, in DPOInput) (*models.DataProtectionOfficer, error) {
+	d, err := s.repo.GetDPO(tenantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDPONotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !d.Active {
+		return nil, ErrDPOAppointmentEnded
+	}
+	if err := s.applyDPO(d, in); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateDPO(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// completeDPOObligations completes open DPO appointment obligations once an
+// India-based DPO is in the registry
+func (s *SDFService) completeDPOObligations(tenantID uuid.UUID, d *models.DataProtectionOfficer) error {
+	if d.Country != sdfDPOCountry {
+		return nil
+	}
+	list, err := s.repo.ListObligations(tenantID)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		o := &list[i]
+		if o.Type != models.SDFObligationDPO || o.Status != models.SDFObligationOpen || o.EvidenceRequired {
+			continue
+		}
+		if _, err := s.complete(o, nil, fmt.Sprintf("%s appointed Data Protection Officer", d.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Overdue reminders
+
+// RegisterJobs schedules the daily overdue obligations check
+func (s *SDFService) RegisterJobs(r *JobRunner) {
+	r.Register("sdf.overdue", func(ctx context.Context, job *models.Job) error {
+		_, err := s.ProcessOverdue(time.Now())
+		return err
+	})
+	r.Schedule("sdf.overdue", "0 7 * * *", "sdf.overdue", nil)
+}
+
+// ProcessOverdue tells the owners of obligations that have gone past due,
+// once per due date. Obligations without an owner go to the tenant's holders
+// of "sdf:manage". It returns how many obligations were reported.
+func (s *SDFService) ProcessOverdue(now time.Time) (int, error) {
+	list, err := s.repo.ListOverdue(now)
+	if err != nil {
+		return 0, err
+	}
+	for i := range list {
+		o := &list[i]
+		if err := s.repo.MarkOverdueNotified(o.ID, now); err != nil {
+			return i, err
+		}
+		s.notifyOverdue(o)
+	}
+	return len(list), nil
+}
+
+func (s *SDFService) notifyOverdue(o *models.SDFObligation) {
+	if s.notifications == nil {
+		return
+	}
+	var users []uuid.UUID
+	if o.OwnerID != nil {
+		users = []uuid.UUID{*o.OwnerID}
+	} else {
+		var err error
+		if users, err = s.repo.ListUserIDsWithPermission(o.TenantID, "sdf:manage"); err != nil {
+			log.Logger.Error().Err(err).Str("obligation_id", o.ID.String()).Msg("failed to find users to notify about overdue SDF obligation")
+			return
+		}
+	}
+	for _, userID := range users {
+		_ = s.notifications.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     "SDF obligation overdue",
+			Body:      fmt.Sprintf("%q was due on %s and has not been completed.", o.Title, o.NextDueAt.Format("2006-01-02")),
+			Icon:      "calendar-x",
+			Link:      "/sdf/obligations/" + o.ID.String(),
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+}
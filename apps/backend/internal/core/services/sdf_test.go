@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/blob"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupSDF(t *testing.T) (*SDFService, *gorm.DB, uuid.UUID, uuid.UUID) {
+	_, _, _, db := setupTransfers(t)
+	require.NoError(t, db.AutoMigrate(&models.FiduciaryUser{},
+		&models.SDFObligation{}, &models.SDFObligationCompletion{}, &models.SDFEvidence{}, &models.DataProtectionOfficer{}))
+	tenantID, userID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme"}).Error)
+	require.NoError(t, db.Create(&models.FiduciaryUser{ID: userID, TenantID: tenantID, Name: "Asha", Email: "asha@acme.example", Phone: "1"}).Error)
+	svc := NewSDFService(repository.NewSDFRepository(db), repository.NewTenantRepository(db),
+		blob.NewStore("local", t.TempDir(), "", "", "", "", "", false, false), nil)
+	return svc, db, tenantID, userID
+}
+
+func TestSDF_DesignationAndDPO(t *testing.T) {
+	svc, _, tenantID, userID := setupSDF(t)
+
+	// Any tenant may publish a DPO; only an SDF's must be in India
+	dpo, err := svc.AppointDPO(tenantID, userID, DPOInput{Name: "Jane", Email: "dpo@acme.example", Address: "London, United Kingdom"})
+	require.NoError(t, err)
+	assert.Equal(t, "GB", dpo.Country)
+	_, err = svc.CreateObligation(tenantID, userID, SDFObligationInput{Title: "Board report"})
+	assert.ErrorIs(t, err, ErrNotSDF)
+
+	st, err := svc.Designate(tenantID, userID, SDFDesignationInput{Designated: true})
+	require.NoError(t, err)
+	assert.True(t, st.Designated)
+	assert.EqualValues(t, 4, st.Summary.TotalObligations)
+	assert.Equal(t, []string{"the Data Protection Officer is not based in India"}, st.Issues)
+
+	_, err = svc.UpdateDPO(tenantID, dpo.ID, DPOInput{Name: "Jane", Email: "dpo@acme.example", Country: "GB"})
+	assert.ErrorIs(t, err, ErrDPONotInIndia)
+	_, err = svc.AppointDPO(tenantID, userID, DPOInput{Name: "Ravi", Email: "not-an-email", Country: "IN"})
+	assert.ErrorIs(t, err, ErrInvalidDPO)
+	ravi, err := svc.AppointDPO(tenantID, userID, DPOInput{Name: "Ravi", Email: "ravi@acme.example", Phone: "+91 22 5555 0100", Address: "Mumbai, India"})
+	require.NoError(t, err)
+
+	history, err := svc.ListDPOs(tenantID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.False(t, history[1].Active)
+	assert.NotNil(t, history[1].EndedAt)
+	_, err = svc.UpdateDPO(tenantID, dpo.ID, DPOInput{Name: "Jane", Email: "dpo@acme.example", Country: "IN"})
+	assert.ErrorIs(t, err, ErrDPOAppointmentEnded)
+
+	contact, err := svc.PublicDPO(tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, &DPOContact{Name: "Ravi", Email: "ravi@acme.example", Phone: "+91 22 5555 0100", Address: "Mumbai, India", Country: "IN"}, contact)
+
+	// Appointing an India-based DPO meets the appointment obligation
+	list, err := svc.ListObligations(tenantID)
+	require.NoError(t, err)
+	var appointment SDFObligationView
+	for _, o := range list {
+		if o.Type == models.SDFObligationDPO {
+			appointment = o
+		}
+	}
+	assert.Equal(t, models.SDFObligationCompleted, appointment.Status)
+	assert.Nil(t, appointment.NextDueAt)
+	done, err := svc.ListCompletions(tenantID, appointment.ID)
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Nil(t, done[0].CompletedBy)
+	assert.Contains(t, done[0].Note, ravi.Name)
+
+	st, err = svc.Status(tenantID)
+	require.NoError(t, err)
+	assert.Empty(t, st.Issues)
+
+	st, err = svc.Designate(tenantID, userID, SDFDesignationInput{Designated: false})
+	require.NoError(t, err)
+	assert.False(t, st.Designated)
+	assert.Nil(t, st.DesignatedAt)
+	_, err = svc.Calendar(tenantID, time.Now(), time.Now().AddDate(1, 0, 0))
+	assert.ErrorIs(t, err, ErrNotSDF)
+}
+
+func TestSDF_CalendarEvidenceAndOverdue(t *testing.T) {
+	svc, db, tenantID, userID := setupSDF(t)
+	_, err := svc.Designate(tenantID, userID, SDFDesignationInput{Designated: true})
+	require.NoError(t, err)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	entries, err := svc.Calendar(tenantID, today, today.AddDate(2, 0, 0))
+	require.NoError(t, err)
+	// The appointment, two rounds of auditor engagement, one DPIA and one audit
+	require.Len(t, entries, 5)
+	assert.Equal(t, models.SDFObligationDPO, entries[0].Type)
+	assert.False(t, entries[0].Projected)
+	assert.Equal(t, models.SDFObligationIndependentAudit, entries[1].Type)
+	assert.True(t, entries[4].Projected)
+	_, err = svc.Calendar(tenantID, today, today)
+	assert.ErrorIs(t, err, ErrInvalidSDFCalendarRange)
+
+	stranger := uuid.New()
+	_, err = svc.CreateObligation(tenantID, userID, SDFObligationInput{Title: "Board report", NextDueAt: &today, OwnerID: &stranger})
+	assert.ErrorIs(t, err, ErrInvalidSDFObligation)
+	lastWeek := today.AddDate(0, 0, -7)
+	o, err := svc.CreateObligation(tenantID, userID, SDFObligationInput{
+		Title: "Algorithmic due diligence", RecurrenceMonths: 12, NextDueAt: &lastWeek, OwnerID: &userID, EvidenceRequired: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.SDFObligationCustom, o.Type)
+	assert.Equal(t, "Asha", o.Owner)
+
+	// Overdue obligations raise an analytics alert and notify once per due date
+	analytics := NewAnalyticsService(db, nil)
+	dash, err := analytics.GetDashboard(context.Background(), tenantID, nil)
+	require.NoError(t, err)
+	require.NotNil(t, dash.SDFAnalytics)
+	assert.EqualValues(t, 1, dash.SDFAnalytics.OverdueObligations)
+	var metrics []string
+	for _, a := range dash.ActiveAlerts {
+		metrics = append(metrics, a.Metric)
+	}
+	assert.ElementsMatch(t, []string{"sdf_overdue_obligations", "sdf_dpo_in_india"}, metrics)
+	n, err := svc.ProcessOverdue(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = svc.ProcessOverdue(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = svc.Complete(tenantID, o.ID, userID, "")
+	assert.ErrorIs(t, err, ErrSDFEvidenceRequired)
+	_, err = svc.UploadEvidence(tenantID, o.ID, userID, SDFEvidenceUpload{FileName: "empty.pdf"}, nil)
+	assert.ErrorIs(t, err, ErrEvidenceEmpty)
+	ev, err := svc.UploadEvidence(tenantID, o.ID, userID, SDFEvidenceUpload{FileName: "../review.pdf", ContentType: "application/pdf"}, []byte("%PDF-1.4 review"))
+	require.NoError(t, err)
+	assert.Equal(t, "review.pdf", ev.FileName)
+
+	c, err := svc.Complete(tenantID, o.ID, userID, "Reviewed by the Board")
+	require.NoError(t, err)
+	assert.True(t, c.Late)
+	assert.True(t, c.DueAt.Equal(lastWeek))
+	got, data, err := svc.DownloadEvidence(tenantID, ev.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("%PDF-1.4 review"), data)
+	require.NotNil(t, got.CompletionID)
+	assert.Equal(t, c.ID, *got.CompletionID)
+
+	list, err := svc.ListObligations(tenantID)
+	require.NoError(t, err)
+	for _, v := range list {
+		if v.ID == o.ID {
+			assert.False(t, v.Overdue)
+			assert.Nil(t, v.OverdueNotifiedAt)
+			assert.WithinDuration(t, time.Now().AddDate(1, 0, 0), *v.NextDueAt, time.Minute)
+		}
+	}
+	assert.ErrorIs(t, svc.DeleteObligation(tenantID, o.ID), ErrSDFObligationHasHistory)
+
+	dash, err = analytics.GetDashboard(context.Background(), tenantID, nil)
+	require.NoError(t, err)
+	assert.Zero(t, dash.SDFAnalytics.OverdueObligations)
+}
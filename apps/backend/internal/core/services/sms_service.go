@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrSMSSend = errors.New("failed to send SMS")
+
+// SMSService sends text messages through an HTTP SMS gateway. The gateway
+// takes a JSON {to, from, body} POST with a bearer key and answers with the
+// provider's message ID, which delivery callbacks quote back.
+type SMSService struct {
+	gatewayURL string
+	apiKey     string
+	senderID   string
+	client     *http.Client
+	mockMode   bool
+}
+
+func NewSMSService(gatewayURL, apiKey, senderID string) *SMSService {
+	if gatewayURL == "" || apiKey == "" {
+		log.Println("[SMSService] ⚠️ SMS gateway not configured. Running in MOCK MODE (SMS will be logged to stdout)")
+		return &SMSService{senderID: senderID, mockMode: true}
+	}
+	return &SMSService{
+		gatewayURL: gatewayURL,
+		apiKey:     apiKey,
+		senderID:   senderID,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Send delivers one message and returns the gateway's message ID
+func (s *SMSService) Send(to, body string) (string, error) {
+	if s.mockMode {
+		id := "mock-" + uuid.NewString()
+		log.Printf("📱 [MOCK SMS] To: %s | ID: %s\nBody: %s\n---------------------------------------------------", to, id, body)
+		return id, nil
+	}
+
+	payload, _ := json.Marshal(map[string]string{"to": to, "from": s.senderID, "body": body})
+	req, err := http.NewRequest(http.MethodPost, s.gatewayURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSMSSend, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%w: gateway returned %d: %s", ErrSMSSend, resp.StatusCode, bytes.TrimSpace(raw))
+	}
+
+	var out struct {
+		ID        string `json:"id"`
+		MessageID string `json:"messageId"`
+	}
+	_ = json.Unmarshal(raw, &out)
+	if out.MessageID != "" {
+		return out.MessageID, nil
+	}
+	return out.ID, nil
+}
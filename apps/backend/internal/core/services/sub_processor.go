@@ -0,0 +1,637 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSubProcessorNotFound = errors.New("sub-processor not found")
+	ErrInvalidSubProcessor  = errors.New("invalid sub-processor")
+	ErrSubProcessorDecided  = errors.New("sub-processor is not awaiting a decision")
+	ErrSubProcessorInactive = errors.New("sub-processor is no longer in use")
+)
+
+// subProcessorObjectionWindow is how long a tenant has to object to a
+// proposed sub-processor before it can be deemed approved
+const subProcessorObjectionWindow = 30 * 24 * time.Hour
+
+// subProcessorMailer is the part of EmailService used to tell vendors about
+// decisions
+type subProcessorMailer interface {
+	Send(to, subject, body string) error
+}
+
+// SubProcessorService keeps the register of sub-processors each vendor
+// engages for a tenant. Vendors propose sub-processors through the vendor
+// portal and the tenant approves or objects within an objection window; DPDP
+// Section 8(2) only lets a processor be engaged with the fiduciary's prior
+// approval. A proposal nobody decides on is deemed approved when the window
+// lapses only if the vendor's active DPA generally allows sub-processing;
+// otherwise it waits for an explicit decision.
+type SubProcessorService struct {
+	repo          *repository.SubProcessorRepository
+	vendorRepo    repository.VendorRepository
+	dpaRepo       *repository.EncryptedDPARepository
+	transfers     *TransferService
+	notifications *NotificationService
+	email         subProcessorMailer
+	portalURL     string
+}
+
+func NewSubProcessorService(
+	repo *repository.SubProcessorRepository,
+	vendorRepo repository.VendorRepository,
+	dpaRepo *repository.EncryptedDPARepository,
+	transfers *TransferService,
+	notifications *NotificationService,
+	emailService *EmailService,
+	portalURL string,
+) *SubProcessorService {
+	s := &SubProcessorService{
+		repo:          repo,
+		vendorRepo:    vendorRepo,
+		dpaRepo:       dpaRepo,
+		transfers:     transfers,
+		notifications: notifications,
+		portalURL:     strings.TrimRight(portalURL, "/"),
+	}
+	if emailService != nil {
+		s.email = emailService
+	}
+	return s
+}
+
+// SubProcessorInput is a sub-processor declaration. Country is resolved
+// from Location when not given. ReplacesID names the approved declaration it
+// changes, if any.
+type SubProcessorInput struct {
+	Name           string     `json:"name"`
+	Location       string     `json:"location"`
+	Country        string     `json:"country,omitempty"`
+	Services       string     `json:"services"`
+	DataCategories []string   `json:"dataCategories"`
+	ReplacesID     *uuid.UUID `json:"replacesId,omitempty"`
+	Note           string     `json:"note,omitempty"`
+}
+
+func (s *SubProcessorService) build(tenantID, vendorID uuid.UUID, in SubProcessorInput) (*models.VendorSubProcessor, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Location = strings.TrimSpace(in.Location)
+	in.Services = strings.TrimSpace(in.Services)
+	switch {
+	case in.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSubProcessor)
+	case in.Location == "":
+		return nil, fmt.Errorf("%w: location is required", ErrInvalidSubProcessor)
+	case in.Services == "":
+		return nil, fmt.Errorf("%w: services are required", ErrInvalidSubProcessor)
+	}
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, c := range in.DataCategories {
+		c = strings.TrimSpace(c)
+		if c != "" && !seen[strings.ToLower(c)] {
+			seen[strings.ToLower(c)] = true
+			categories = append(categories, c)
+		}
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("%w: at least one data category is required", ErrInvalidSubProcessor)
+	}
+	country, err := ResolveCountry(in.Country, in.Location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubProcessor, err)
+	}
+	flagged := false
+	if s.transfers != nil {
+		if flagged, err = s.transfers.CheckTransfer(tenantID, country.Code); err != nil {
+			return nil, err
+		}
+	}
+	if in.ReplacesID != nil {
+		prev, err := s.repo.Get(tenantID, *in.ReplacesID)
+		if err != nil || prev.VendorID != vendorID {
+			return nil, fmt.Errorf("%w: the sub-processor being changed was not found", ErrInvalidSubProcessor)
+		}
+		if prev.Status != models.SubProcessorApproved {
+			return nil, fmt.Errorf("%w: only an approved sub-processor can be changed", ErrInvalidSubProcessor)
+		}
+	}
+	cats, _ := json.Marshal(categories)
+	return &models.VendorSubProcessor{
+		ID:                 uuid.New(),
+		TenantID:           tenantID,
+		VendorID:           vendorID,
+		Name:               in.Name,
+		Location:           in.Location,
+		Country:            country.Code,
+		Services:           in.Services,
+		DataCategories:     cats,
+		ReplacesID:         in.ReplacesID,
+		ProposedAt:         time.Now(),
+		RestrictedTransfer: flagged,
+	}, nil
+}
+
+func subProcessorEvent(sp *models.VendorSubProcessor, event, actorType, actor, note string) models.VendorSubProcessorEvent {
+	return models.VendorSubProcessorEvent{
+		ID:             uuid.New(),
+		SubProcessorID: sp.ID,
+		TenantID:       sp.TenantID,
+		VendorID:       sp.VendorID,
+		Event:          event,
+		ActorType:      actorType,
+		Actor:          actor,
+		Note:           note,
+		Name:           sp.Name,
+		Location:       sp.Location,
+		Country:        sp.Country,
+		Services:       sp.Services,
+		DataCategories: sp.DataCategories,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// Fiduciary side
+
+// Declare records a sub-processor the tenant has already approved, such as
+// one listed in a signed DPA annex
+func (s *SubProcessorService) Declare(tenantID, vendorID, userID uuid.UUID, in SubProcessorInput) (*models.VendorSubProcessor, error) {
+	if _, err := s.vendorRepo.GetByID(vendorID); err != nil {
+		return nil, ErrVendorNotFound
+	}
+	sp, err := s.build(tenantID, vendorID, in)
+	if err != nil {
+		return nil, err
+	}
+	now := sp.ProposedAt
+	sp.Status = models.SubProcessorProposed
+	sp.ProposedBy = userID.String()
+	ev := subProcessorEvent(sp, "declared", "fiduciary", userID.String(), in.Note)
+	if err := s.repo.Create(sp, &ev); err != nil {
+		return nil, err
+	}
+	// Approving goes through the same transition as a proposal so a
+	// replaced declaration is retired in step
+	if _, err := s.decide(sp, "approved", "fiduciary", userID.String(), in.Note, map[string]interface{}{
+		"status": models.SubProcessorApproved, "decided_at": now, "decided_by": userID, "decision_note": in.Note,
+	}); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(tenantID, sp.ID)
+}
+
+func (s *SubProcessorService) List(tenantID, vendorID uuid.UUID) ([]models.VendorSubProcessor, error) {
+	return s.repo.ListByVendor(tenantID, vendorID)
+}
+
+// Pending returns the proposals awaiting the tenant, oldest first
+func (s *SubProcessorService) Pending(tenantID uuid.UUID) ([]models.VendorSubProcessor, error) {
+	return s.repo.ListByStatus(tenantID, models.SubProcessorProposed)
+}
+
+// History returns every change to a vendor's sub-processor register
+func (s *SubProcessorService) History(tenantID, vendorID uuid.UUID) ([]models.VendorSubProcessorEvent, error) {
+	return s.repo.ListEvents(tenantID, vendorID)
+}
+
+func (s *SubProcessorService) get(tenantID, id uuid.UUID) (*models.VendorSubProcessor, error) {
+	sp, err := s.repo.Get(tenantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubProcessorNotFound
+	}
+	return sp, err
+}
+
+// Approve accepts a proposed sub-processor. An approval can still be given
+// after the objection window has lapsed.
+func (s *SubProcessorService) Approve(tenantID, id, userID uuid.UUID, note string) (*models.VendorSubProcessor, error) {
+	sp, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.decide(sp, "approved", "fiduciary", userID.String(), note, map[string]interface{}{
+		"status": models.SubProcessorApproved, "decided_at": time.Now(), "decided_by": userID, "decision_note": note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSubProcessorDecided
+	}
+	s.notifyVendor(sp, true, note)
+	return s.repo.Get(tenantID, id)
+}
+
+// Reject records the tenant's objection to a proposed sub-processor. A
+// reason is required so the vendor knows what to address.
+func (s *SubProcessorService) Reject(tenantID, id, userID uuid.UUID, note string) (*models.VendorSubProcessor, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: a reason for the objection is required", ErrInvalidSubProcessor)
+	}
+	sp, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.decide(sp, "rejected", "fiduciary", userID.String(), note, map[string]interface{}{
+		"status": models.SubProcessorRejected, "decided_at": time.Now(), "decided_by": userID, "decision_note": note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSubProcessorDecided
+	}
+	s.notifyVendor(sp, false, note)
+	return s.repo.Get(tenantID, id)
+}
+
+// Remove takes a sub-processor out of use, for instance when the tenant
+// withdraws an earlier approval
+func (s *SubProcessorService) Remove(tenantID, id, userID uuid.UUID, note string) (*models.VendorSubProcessor, error) {
+	sp, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.remove(sp, "fiduciary", userID.String(), note)
+}
+
+// decide moves a proposal to its outcome, retiring the declaration it
+// replaces when approved
+func (s *SubProcessorService) decide(sp *models.VendorSubProcessor, event, actorType, actor, note string, fields map[string]interface{}) (bool, error) {
+	events := []models.VendorSubProcessorEvent{subProcessorEvent(sp, event, actorType, actor, note)}
+	var replaces *uuid.UUID
+	if fields["status"] == models.SubProcessorApproved && sp.ReplacesID != nil {
+		replaces = sp.ReplacesID
+		if prev, err := s.repo.Get(sp.TenantID, *sp.ReplacesID); err == nil && prev.Status == models.SubProcessorApproved {
+			events = append(events, subProcessorEvent(prev, "replaced", actorType, actor, "replaced by "+sp.ID.String()))
+		}
+	}
+	return s.repo.Transition(sp.ID, models.SubProcessorProposed, fields, replaces, events)
+}
+
+func (s *SubProcessorService) remove(sp *models.VendorSubProcessor, actorType, actor, note string) (*models.VendorSubProcessor, error) {
+	from := sp.Status
+	if from != models.SubProcessorApproved && from != models.SubProcessorProposed {
+		return nil, ErrSubProcessorInactive
+	}
+	ok, err := s.repo.Transition(sp.ID, from, map[string]interface{}{
+		"status": models.SubProcessorRemoved, "removed_at": time.Now(),
+	}, nil, []models.VendorSubProcessorEvent{subProcessorEvent(sp, "removed", actorType, actor, note)})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSubProcessorInactive
+	}
+	return s.repo.Get(sp.TenantID, sp.ID)
+}
+
+// Vendor portal side
+
+// Propose submits a new or changed sub-processor for the tenant's approval
+// and opens the objection window
+func (s *SubProcessorService) Propose(sess *VendorSession, in SubProcessorInput) (*models.VendorSubProcessor, error) {
+	if err := sess.require(models.VendorScopeSubProcessors); err != nil {
+		return nil, err
+	}
+	sp, err := s.build(sess.TenantID(), sess.VendorID(), in)
+	if err != nil {
+		return nil, err
+	}
+	deadline := sp.ProposedAt.Add(subProcessorObjectionWindow)
+	sp.Status = models.SubProcessorProposed
+	sp.ProposedBy = sess.Invitation.Email
+	sp.ObjectionDeadline = &deadline
+	ev := subProcessorEvent(sp, "proposed", "vendor", sess.Invitation.Email, in.Note)
+	if err := s.repo.Create(sp, &ev); err != nil {
+		return nil, err
+	}
+	body := fmt.Sprintf("%s proposes to engage %s (%s) for %s. Objections close on %s.",
+		s.vendorName(sp.VendorID), sp.Name, sp.Location, sp.Services, deadline.Format("02 Jan 2006"))
+	if sp.RestrictedTransfer {
+		body += " Its country is flagged on your restricted-country list."
+	}
+	s.notifyTenant(sp, "Sub-processor proposed", body)
+	return sp, nil
+}
+
+// ListForPortal returns the vendor's declarations in the tenant
+func (s *SubProcessorService) ListForPortal(sess *VendorSession) ([]models.VendorSubProcessor, error) {
+	if err := sess.require(models.VendorScopeSubProcessors); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByVendor(sess.TenantID(), sess.VendorID())
+}
+
+// RemoveFromPortal lets the vendor withdraw a proposal or report that it no
+// longer uses a sub-processor
+func (s *SubProcessorService) RemoveFromPortal(sess *VendorSession, id uuid.UUID, note string) (*models.VendorSubProcessor, error) {
+	if err := sess.require(models.VendorScopeSubProcessors); err != nil {
+		return nil, err
+	}
+	sp, err := s.get(sess.TenantID(), id)
+	if err != nil {
+		return nil, err
+	}
+	if sp.VendorID != sess.VendorID() {
+		return nil, ErrSubProcessorNotFound
+	}
+	return s.remove(sp, "vendor", sess.Invitation.Email, note)
+}
+
+// Objection windows
+
+// RegisterJobs settles lapsed objection windows hourly
+func (s *SubProcessorService) RegisterJobs(r *JobRunner) {
+	r.Register("sub_processors.lapse", func(ctx context.Context, job *models.Job) error {
+		_, err := s.ProcessLapsed(time.Now())
+		return err
+	})
+	r.Schedule("sub_processors.lapse", "20 * * * *", "sub_processors.lapse", nil)
+}
+
+// ProcessLapsed deals with proposals whose objection window has closed. Under
+// an active DPA that allows sub-processing the proposal is deemed approved;
+// otherwise the tenant is told, once, that an explicit decision is needed.
+// It returns how many proposals were deemed approved.
+func (s *SubProcessorService) ProcessLapsed(now time.Time) (int, error) {
+	lapsed, err := s.repo.ListLapsed(now)
+	if err != nil {
+		return 0, err
+	}
+	approved := 0
+	for i := range lapsed {
+		sp := &lapsed[i]
+		allowed, err := s.generallyAuthorised(sp.TenantID, sp.VendorID)
+		if err != nil {
+			return approved, err
+		}
+		if !allowed {
+			s.notifyTenant(sp, "Sub-processor awaiting approval",
+				fmt.Sprintf("The objection window for %s proposed by %s has closed. The vendor's DPA does not generally allow sub-processing, so it needs your explicit approval.",
+					sp.Name, s.vendorName(sp.VendorID)))
+			if err := s.repo.MarkLapseNotified(sp.ID, now); err != nil {
+				return approved, err
+			}
+			continue
+		}
+		note := "objection window lapsed without objection"
+		ok, err := s.decide(sp, "deemed_approved", "system", "", note, map[string]interface{}{
+			"status": models.SubProcessorApproved, "decided_at": now, "decision_note": note, "deemed_approved": true,
+		})
+		if err != nil {
+			return approved, err
+		}
+		if ok {
+			approved++
+			s.notifyVendor(sp, true, note)
+		}
+	}
+	return approved, nil
+}
+
+// generallyAuthorised reports whether the vendor has an active DPA with the
+// tenant that allows sub-processing
+func (s *SubProcessorService) generallyAuthorised(tenantID, vendorID uuid.UUID) (bool, error) {
+	if s.dpaRepo == nil {
+		return false, nil
+	}
+	dpas, err := s.dpaRepo.GetDPAsByVendor(vendorID)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range dpas {
+		if d.TenantID == tenantID && d.Status == "active" && d.SubProcessingAllowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *SubProcessorService) vendorName(vendorID uuid.UUID) string {
+	if v, err := s.vendorRepo.GetByID(vendorID); err == nil && v.Company != "" {
+		return v.Company
+	}
+	return "A vendor"
+}
+
+// notifyTenant alerts the tenant's vendor managers
+func (s *SubProcessorService) notifyTenant(sp *models.VendorSubProcessor, title, body string) {
+	if s.notifications == nil {
+		return
+	}
+	users, err := s.repo.ListUserIDsWithPermission(sp.TenantID, "vendors:manage")
+	if err != nil {
+		log.Logger.Error().Err(err).Str("sub_processor_id", sp.ID.String()).Msg("failed to find users to notify about sub-processor")
+		return
+	}
+	for _, userID := range users {
+		_ = s.notifications.Create(context.Background(), &models.Notification{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Title:     title,
+			Body:      body,
+			Icon:      "git-pull-request",
+			Link:      "/vendors/" + sp.VendorID.String() + "/sub-processors",
+			Unread:    true,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// notifyVendor emails the vendor's contact the tenant's decision
+func (s *SubProcessorService) notifyVendor(sp *models.VendorSubProcessor, approved bool, note string) {
+	if s.email == nil {
+		return
+	}
+	v, err := s.vendorRepo.GetByID(sp.VendorID)
+	if err != nil || v.Email == "" {
+		return
+	}
+	outcome, advice := "approved", "You may engage it for the services declared."
+	if !approved {
+		outcome, advice = "objected to", "It must not be engaged to process the tenant's personal data."
+	}
+	subject := fmt.Sprintf("Sub-processor %s %s", sp.Name, outcome)
+	body := fmt.Sprintf("Hello,<br><br>Your proposed sub-processor <b>%s</b> (%s) has been %s. %s",
+		html.EscapeString(sp.Name), html.EscapeString(sp.Location), outcome, advice)
+	if note != "" {
+		body += "<br><br>Note: " + html.EscapeString(note)
+	}
+	body += fmt.Sprintf("<br><br><a href=\"%s/vendor-portal\">Open the vendor portal</a>", s.portalURL)
+	if err := s.email.Send(v.Email, subject, body); err != nil {
+		log.Logger.Error().Err(err).Str("sub_processor_id", sp.ID.String()).Msg("failed to send sub-processor decision")
+	}
+}
+
+// Data map
+
+// resolvePurposeVendors looks up the vendors named by the purposes. The map
+// is keyed by the purpose's reference and holds nil for references that are
+// not in the vendor register; ids lists the vendors found.
+func resolvePurposeVendors(vendorRepo repository.VendorRepository, purposes []models.Purpose) (map[string]*models.Vendor, []uuid.UUID) {
+	vendors := map[string]*models.Vendor{}
+	var ids []uuid.UUID
+	for _, p := range purposes {
+		for _, ref := range p.Vendors {
+			if _, done := vendors[ref]; done {
+				continue
+			}
+			vendors[ref] = nil
+			if id, err := uuid.Parse(ref); err == nil {
+				if v, err := vendorRepo.GetByID(id); err == nil {
+					vendors[ref] = v
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	return vendors, ids
+}
+
+// DataMapVendor is a vendor a purpose shares data with, and the approved
+// sub-processors the data flows on to. VendorID is nil when the purpose
+// names a vendor that is not in the vendor register.
+type DataMapVendor struct {
+	VendorID           *uuid.UUID                  `json:"vendorId,omitempty"`
+	Name               string                      `json:"name"`
+	ProcessingLocation string                      `json:"processingLocation,omitempty"`
+	Country            string                      `json:"country,omitempty"`
+	SubProcessors      []models.VendorSubProcessor `json:"subProcessors"`
+	PendingProposals   int                         `json:"pendingProposals"`
+}
+
+// DataMapPurpose is one purpose and who processes data for it
+type DataMapPurpose struct {
+	PurposeID  uuid.UUID       `json:"purposeId"`
+	Name       string          `json:"name"`
+	LegalBasis string          `json:"legalBasis,omitempty"`
+	Active     bool            `json:"active"`
+	Vendors    []DataMapVendor `json:"vendors"`
+}
+
+// DataMap joins the tenant's third-party purposes to their vendors and each
+// vendor's approved sub-processors. Purposes name vendors by ID; names are
+// accepted for purposes created before that.
+func (s *SubProcessorService) DataMap(tenantID uuid.UUID, purposes []models.Purpose) ([]DataMapPurpose, error) {
+	vendors, ids := resolvePurposeVendors(s.vendorRepo, purposes)
+	approved, err := s.repo.ListApproved(tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byVendor := map[uuid.UUID][]models.VendorSubProcessor{}
+	for _, sp := range approved {
+		byVendor[sp.VendorID] = append(byVendor[sp.VendorID], sp)
+	}
+	pending, err := s.repo.ListByStatus(tenantID, models.SubProcessorProposed)
+	if err != nil {
+		return nil, err
+	}
+	pendingByVendor := map[uuid.UUID]int{}
+	for _, sp := range pending {
+		pendingByVendor[sp.VendorID]++
+	}
+
+	out := []DataMapPurpose{}
+	for _, p := range purposes {
+		if !p.IsThirdParty || len(p.Vendors) == 0 {
+			continue
+		}
+		dp := DataMapPurpose{PurposeID: p.ID, Name: p.Name, LegalBasis: p.LegalBasis, Active: p.Active, Vendors: []DataMapVendor{}}
+		for _, ref := range p.Vendors {
+			v := vendors[ref]
+			if v == nil {
+				dp.Vendors = append(dp.Vendors, DataMapVendor{Name: ref, SubProcessors: []models.VendorSubProcessor{}})
+				continue
+			}
+			id := v.VendorID
+			subs := byVendor[id]
+			if subs == nil {
+				subs = []models.VendorSubProcessor{}
+			}
+			dp.Vendors = append(dp.Vendors, DataMapVendor{
+				VendorID:           &id,
+				Name:               v.Company,
+				ProcessingLocation: v.ProcessingLocation,
+				Country:            vendorCountry(v),
+				SubProcessors:      subs,
+				PendingProposals:   pendingByVendor[id],
+			})
+		}
+		out = append(out, dp)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// PrincipalSubProcessor is what a data principal is shown about a
+// sub-processor
+type PrincipalSubProcessor struct {
+	Name           string   `json:"name"`
+	Location       string   `json:"location"`
+	Services       string   `json:"services"`
+	DataCategories []string `json:"dataCategories"`
+}
+
+// PrincipalProcessor is a vendor as shown to a data principal
+type PrincipalProcessor struct {
+	Name          string                  `json:"name"`
+	Location      string                  `json:"location,omitempty"`
+	SubProcessors []PrincipalSubProcessor `json:"subProcessors"`
+}
+
+// PrincipalProcessorPurpose lists who processes a principal's data for a
+// purpose
+type PrincipalProcessorPurpose struct {
+	Purpose    string               `json:"purpose"`
+	Processors []PrincipalProcessor `json:"processors"`
+}
+
+// ForPrincipal lists, for each active purpose of the tenant that shares
+// data with vendors, the vendors and their approved sub-processors. Only
+// what a principal needs is included: no contacts, IDs or pending proposals.
+func (s *SubProcessorService) ForPrincipal(tenantID uuid.UUID, purposes []models.Purpose) ([]PrincipalProcessorPurpose, error) {
+	active := make([]models.Purpose, 0, len(purposes))
+	for _, p := range purposes {
+		if p.Active {
+			active = append(active, p)
+		}
+	}
+	dataMap, err := s.DataMap(tenantID, active)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PrincipalProcessorPurpose, 0, len(dataMap))
+	for _, dp := range dataMap {
+		pp := PrincipalProcessorPurpose{Purpose: dp.Name, Processors: []PrincipalProcessor{}}
+		for _, v := range dp.Vendors {
+			proc := PrincipalProcessor{Name: v.Name, Location: v.ProcessingLocation, SubProcessors: []PrincipalSubProcessor{}}
+			for _, sp := range v.SubProcessors {
+				cats := []string{}
+				_ = json.Unmarshal(sp.DataCategories, &cats)
+				proc.SubProcessors = append(proc.SubProcessors, PrincipalSubProcessor{
+					Name: sp.Name, Location: sp.Location, Services: sp.Services, DataCategories: cats,
+				})
+			}
+			pp.Processors = append(pp.Processors, proc)
+		}
+		out = append(out, pp)
+	}
+	return out, nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSubProcessors(t *testing.T) (*SubProcessorService, *repository.EncryptedDPARepository, *capturedMail, *gorm.DB) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Vendor{}, &models.EncryptedDataProcessingAgreement{},
+		&models.VendorSubProcessor{}, &models.VendorSubProcessorEvent{},
+	))
+	dpaRepo := repository.NewEncryptedDPARepository(db)
+	svc := NewSubProcessorService(repository.NewSubProcessorRepository(db), repository.NewVendorRepository(db), dpaRepo, nil, nil, nil, "https://app.example.com")
+	mail := &capturedMail{last: map[string]string{}, attached: map[string]int{}}
+	svc.email = mail
+	return svc, dpaRepo, mail, db
+}
+
+func subProcessorSession(tenantID uuid.UUID, v models.Vendor, scopes ...string) *VendorSession {
+	sess := &VendorSession{
+		Invitation: &models.VendorPortalInvitation{ID: uuid.New(), TenantID: tenantID, VendorID: v.VendorID, Email: v.Email},
+		Scopes:     map[string]bool{},
+	}
+	for _, sc := range scopes {
+		sess.Scopes[sc] = true
+	}
+	return sess
+}
+
+func TestSubProcessors_ProposalWorkflow(t *testing.T) {
+	svc, _, mail, db := setupSubProcessors(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "dpo@acme.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+
+	_, err := svc.Propose(subProcessorSession(tenantID, vendor, models.VendorScopeDPAs), SubProcessorInput{})
+	assert.ErrorIs(t, err, ErrVendorScopeDenied)
+
+	sess := subProcessorSession(tenantID, vendor, models.VendorScopeSubProcessors)
+	_, err = svc.Propose(sess, SubProcessorInput{Name: "CloudCo", Location: "Singapore", Services: "Hosting"})
+	assert.ErrorIs(t, err, ErrInvalidSubProcessor) // No data categories
+
+	hosting, err := svc.Propose(sess, SubProcessorInput{
+		Name: "CloudCo", Location: "Singapore", Services: "Hosting", DataCategories: []string{"contact", "Contact", "usage"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorProposed, hosting.Status)
+	require.NotNil(t, hosting.ObjectionDeadline)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), *hosting.ObjectionDeadline, time.Minute)
+	assert.JSONEq(t, `["contact","usage"]`, string(hosting.DataCategories))
+
+	// An objection needs a reason, and a decided proposal cannot be decided again
+	_, err = svc.Reject(tenantID, hosting.ID, userID, " ")
+	assert.ErrorIs(t, err, ErrInvalidSubProcessor)
+	rejected, err := svc.Reject(tenantID, hosting.ID, userID, "Data must stay in India")
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorRejected, rejected.Status)
+	assert.Contains(t, mail.last[vendor.Email], "objected to")
+	_, err = svc.Approve(tenantID, hosting.ID, userID, "")
+	assert.ErrorIs(t, err, ErrSubProcessorDecided)
+
+	// Another tenant cannot see or decide on the proposal
+	_, err = svc.Approve(uuid.New(), hosting.ID, userID, "")
+	assert.ErrorIs(t, err, ErrSubProcessorNotFound)
+
+	local, err := svc.Propose(sess, SubProcessorInput{Name: "CloudCo India", Location: "India", Services: "Hosting", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	_, err = svc.Approve(tenantID, local.ID, userID, "")
+	require.NoError(t, err)
+	assert.Contains(t, mail.last[vendor.Email], "approved")
+
+	// Changing an approved sub-processor replaces it once the change is approved
+	_, err = svc.Propose(sess, SubProcessorInput{ReplacesID: &hosting.ID, Name: "CloudCo", Location: "India", Services: "Hosting", DataCategories: []string{"contact"}})
+	assert.ErrorIs(t, err, ErrInvalidSubProcessor)
+	change, err := svc.Propose(sess, SubProcessorInput{ReplacesID: &local.ID, Name: "CloudCo India", Location: "India", Services: "Hosting and backups", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	prev, err := svc.repo.Get(tenantID, local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorApproved, prev.Status) // Still in force while the change is pending
+	_, err = svc.Approve(tenantID, change.ID, userID, "")
+	require.NoError(t, err)
+	prev, err = svc.repo.Get(tenantID, local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorReplaced, prev.Status)
+
+	// The vendor can stop using it, but not twice
+	_, err = svc.RemoveFromPortal(sess, change.ID, "moved in-house")
+	require.NoError(t, err)
+	_, err = svc.RemoveFromPortal(sess, change.ID, "")
+	assert.ErrorIs(t, err, ErrSubProcessorInactive)
+
+	history, err := svc.History(tenantID, vendor.VendorID)
+	require.NoError(t, err)
+	events := []string{}
+	for _, e := range history {
+		events = append(events, e.Event)
+	}
+	assert.Equal(t, []string{"proposed", "rejected", "proposed", "approved", "proposed", "approved", "replaced", "removed"}, events)
+	assert.Equal(t, "Singapore", history[1].Location) // Each entry keeps the details as they stood
+}
+
+func TestSubProcessors_LapsedWindow(t *testing.T) {
+	svc, dpaRepo, mail, db := setupSubProcessors(t)
+	tenantID := uuid.New()
+	general := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "dpo@acme.example"}
+	specific := models.Vendor{VendorID: uuid.New(), Company: "Globex", Email: "dpo@globex.example"}
+	require.NoError(t, db.Create(&general).Error)
+	require.NoError(t, db.Create(&specific).Error)
+	require.NoError(t, dpaRepo.CreateDPA(&models.DataProcessingAgreement{
+		ID: uuid.New(), TenantID: tenantID, VendorID: general.VendorID, AgreementNumber: "DPA-1", Status: "active", SubProcessingAllowed: true,
+	}))
+	require.NoError(t, dpaRepo.CreateDPA(&models.DataProcessingAgreement{
+		ID: uuid.New(), TenantID: tenantID, VendorID: specific.VendorID, AgreementNumber: "DPA-2", Status: "active",
+	}))
+
+	in := SubProcessorInput{Name: "MailCo", Location: "Ireland", Services: "Transactional email", DataCategories: []string{"email"}}
+	a, err := svc.Propose(subProcessorSession(tenantID, general, models.VendorScopeSubProcessors), in)
+	require.NoError(t, err)
+	b, err := svc.Propose(subProcessorSession(tenantID, specific, models.VendorScopeSubProcessors), in)
+	require.NoError(t, err)
+
+	n, err := svc.ProcessLapsed(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n) // Windows still open
+
+	later := time.Now().Add(31 * 24 * time.Hour)
+	n, err = svc.ProcessLapsed(later)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err := svc.repo.Get(tenantID, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorApproved, got.Status)
+	assert.True(t, got.DeemedApproved)
+	assert.Nil(t, got.DecidedBy)
+	assert.Contains(t, mail.last[general.Email], "approved")
+
+	// Without a general authorisation the proposal waits for a decision
+	got, err = svc.repo.Get(tenantID, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubProcessorProposed, got.Status)
+	assert.NotNil(t, got.LapseNotifiedAt)
+	assert.Empty(t, mail.last[specific.Email])
+	lapsed, err := svc.repo.ListLapsed(later)
+	require.NoError(t, err)
+	assert.Empty(t, lapsed)
+}
+
+func TestSubProcessors_DataMap(t *testing.T) {
+	svc, _, _, db := setupSubProcessors(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "dpo@acme.example", ProcessingLocation: "India"}
+	require.NoError(t, db.Create(&vendor).Error)
+
+	_, err := svc.Declare(tenantID, vendor.VendorID, userID, SubProcessorInput{Name: "CloudCo", Location: "India", Services: "Hosting", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	_, err = svc.Propose(subProcessorSession(tenantID, vendor, models.VendorScopeSubProcessors),
+		SubProcessorInput{Name: "AnalyticsCo", Location: "USA", Services: "Analytics", DataCategories: []string{"usage"}})
+	require.NoError(t, err)
+	// Another tenant's approvals stay out of this tenant's map
+	_, err = svc.Declare(uuid.New(), vendor.VendorID, userID, SubProcessorInput{Name: "OtherCo", Location: "UK", Services: "Support", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+
+	purposes := []models.Purpose{
+		{ID: uuid.New(), Name: "Marketing", Active: true, IsThirdParty: true, Vendors: pq.StringArray{vendor.VendorID.String(), "Legacy Mailer"}},
+		{ID: uuid.New(), Name: "Billing", Active: true},
+		{ID: uuid.New(), Name: "Retired", Active: false, IsThirdParty: true, Vendors: pq.StringArray{vendor.VendorID.String()}},
+	}
+	m, err := svc.DataMap(tenantID, purposes)
+	require.NoError(t, err)
+	require.Len(t, m, 2)
+	assert.Equal(t, "Marketing", m[0].Name)
+	require.Len(t, m[0].Vendors, 2)
+	acme := m[0].Vendors[0]
+	assert.Equal(t, "Acme", acme.Name)
+	require.Len(t, acme.SubProcessors, 1)
+	assert.Equal(t, "CloudCo", acme.SubProcessors[0].Name)
+	assert.Equal(t, 1, acme.PendingProposals)
+	assert.Nil(t, m[0].Vendors[1].VendorID) // Not in the vendor register
+	assert.Equal(t, "Legacy Mailer", m[0].Vendors[1].Name)
+
+	// Principals only see active purposes and approved sub-processors
+	view, err := svc.ForPrincipal(tenantID, purposes)
+	require.NoError(t, err)
+	require.Len(t, view, 1)
+	assert.Equal(t, "Marketing", view[0].Purpose)
+	assert.Equal(t, []PrincipalSubProcessor{{Name: "CloudCo", Location: "India", Services: "Hosting", DataCategories: []string{"contact"}}},
+		view[0].Processors[0].SubProcessors)
+}
@@ -349,38 +349,3 @@ func (s *TPRMService) SubmitAuditResponse(assessmentID uuid.UUID, responses []mo
 	return nil
 }
 
-func (s *TPRMService) CreateDPATemplate(tenantID uuid.UUID, name, content string) (*models.DPATemplate, error) {
-	t := &models.DPATemplate{
-		ID:       uuid.New(),
-		TenantID: tenantID,
-		Name:     name,
-		Content:  content,
-		Version:  "1.0",
-		IsActive: true,
-	}
-	return t, s.repo.CreateDPATemplate(t)
-}
-
-func (s *TPRMService) GenerateDPA(tenantID, vendorID, templateID uuid.UUID) (*models.DPAAgreement, error) {
-	// In a real app, this would merge template content with vendor data and generate a PDF
-	// For now, we create the agreement record
-	a := &models.DPAAgreement{
-		ID:         uuid.New(),
-		TenantID:   tenantID,
-		VendorID:   vendorID,
-		TemplateID: &templateID,
-		Status:     "generated",
-	}
-	return a, s.repo.CreateDPAAgreement(a)
-}
-
-func (s *TPRMService) UploadSignedDPA(agreementID uuid.UUID, fileData []byte) error {
-	// Mock upload
-	// In real implementation, upload to S3/Local and update SignedURL
-	// For now, just update status
-	a := &models.DPAAgreement{ID: agreementID, Status: "signed"} // Partial update
-	// We need to fetch first to update properly or use a specific update method
-	// Assuming simple update for now
-	return s.repo.UpdateDPAAgreement(a)
-}
-
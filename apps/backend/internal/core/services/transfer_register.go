@@ -0,0 +1,346 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/localization"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownCountry             = errors.New("unknown country")
+	ErrRestrictedCountryNotFound  = errors.New("country is not on the restricted list")
+	ErrInvalidTransferRestriction = errors.New("invalid transfer restriction")
+)
+
+// defaultHomeCountry is assumed for tenants whose cluster location is unknown
+const defaultHomeCountry = "IN"
+
+// TransferRestrictedError is returned when personal data would go to a
+// country the tenant has blocked
+type TransferRestrictedError struct {
+	Country localization.Country
+	Rule    *models.RestrictedCountry
+}
+
+func (e *TransferRestrictedError) Error() string {
+	msg := fmt.Sprintf("transfers of personal data to %s are blocked", e.Country.Name)
+	if e.Rule.Reason != "" {
+		msg += ": " + e.Rule.Reason
+	}
+	return msg
+}
+
+// TransferService keeps each tenant's restricted-country list and builds the
+// register of personal data leaving the tenant's home country. DPDP Section
+// 16(1) lets the government restrict transfers to notified countries; tenants
+// record those, and any they restrict by policy, as blocked or flagged.
+type TransferService struct {
+	repo       *repository.TransferRepository
+	vendorRepo repository.VendorRepository
+	subRepo    *repository.SubProcessorRepository
+	dpaRepo    *repository.EncryptedDPARepository
+	tenantRepo *repository.TenantRepository
+}
+
+func NewTransferService(
+	repo *repository.TransferRepository,
+	vendorRepo repository.VendorRepository,
+	subRepo *repository.SubProcessorRepository,
+	dpaRepo *repository.EncryptedDPARepository,
+	tenantRepo *repository.TenantRepository,
+) *TransferService {
+	return &TransferService{repo: repo, vendorRepo: vendorRepo, subRepo: subRepo, dpaRepo: dpaRepo, tenantRepo: tenantRepo}
+}
+
+// ResolveCountry turns a country code, name or free-text location into a
+// country. An explicit code wins over the location.
+func ResolveCountry(code, location string) (localization.Country, error) {
+	if strings.TrimSpace(code) != "" {
+		if c, ok := localization.NormalizeCountry(code); ok {
+			return c, nil
+		}
+		return localization.Country{}, fmt.Errorf("%w: %q", ErrUnknownCountry, code)
+	}
+	if c, ok := localization.NormalizeCountry(location); ok {
+		return c, nil
+	}
+	return localization.Country{}, fmt.Errorf("%w: %q does not name a recognised country", ErrUnknownCountry, location)
+}
+
+// Restricted-country list
+
+// RestrictionInput adds or updates a restricted country. Country accepts a
+// code or a name; Action defaults to block.
+type RestrictionInput struct {
+	Country   string `json:"country"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference"`
+}
+
+func (s *TransferService) ListRestricted(tenantID uuid.UUID) ([]models.RestrictedCountry, error) {
+	return s.repo.ListRestricted(tenantID)
+}
+
+func (s *TransferService) SetRestricted(tenantID, userID uuid.UUID, in RestrictionInput) (*models.RestrictedCountry, error) {
+	c, ok := localization.NormalizeCountry(in.Country)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCountry, in.Country)
+	}
+	action := strings.ToLower(strings.TrimSpace(in.Action))
+	if action == "" {
+		action = models.TransferRestrictionBlock
+	}
+	if action != models.TransferRestrictionBlock && action != models.TransferRestrictionFlag {
+		return nil, fmt.Errorf("%w: action must be %q or %q", ErrInvalidTransferRestriction, models.TransferRestrictionBlock, models.TransferRestrictionFlag)
+	}
+	if c.Code == s.HomeCountry(tenantID).Code {
+		return nil, fmt.Errorf("%w: %s is the tenant's home country", ErrInvalidTransferRestriction, c.Name)
+	}
+	err := s.repo.UpsertRestricted(&models.RestrictedCountry{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		CountryCode: c.Code,
+		Action:      action,
+		Reason:      strings.TrimSpace(in.Reason),
+		Reference:   strings.TrimSpace(in.Reference),
+		UpdatedBy:   userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetRestricted(tenantID, c.Code)
+}
+
+func (s *TransferService) RemoveRestricted(tenantID uuid.UUID, code string) error {
+	c, ok := localization.LookupCountry(code)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCountry, code)
+	}
+	removed, err := s.repo.DeleteRestricted(tenantID, c.Code)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrRestrictedCountryNotFound
+	}
+	return nil
+}
+
+// restriction returns the tenant's rule for a country, or nil
+func (s *TransferService) restriction(tenantID uuid.UUID, code string) (*models.RestrictedCountry, error) {
+	if code == "" {
+		return nil, nil
+	}
+	rc, err := s.repo.GetRestricted(tenantID, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return rc, err
+}
+
+// CheckTransfer vets a new recipient in the given country. It returns a
+// *TransferRestrictedError when the tenant blocks the country, and reports
+// flagged when the tenant only flags it.
+func (s *TransferService) CheckTransfer(tenantID uuid.UUID, code string) (flagged bool, err error) {
+	rc, err := s.restriction(tenantID, code)
+	if err != nil || rc == nil {
+		return false, err
+	}
+	if rc.Action == models.TransferRestrictionBlock {
+		c, _ := localization.LookupCountry(code)
+		return false, &TransferRestrictedError{Country: c, Rule: rc}
+	}
+	return true, nil
+}
+
+// HomeCountry is the country hosting the tenant's data cluster
+func (s *TransferService) HomeCountry(tenantID uuid.UUID) localization.Country {
+	if s.tenantRepo != nil {
+		if t, err := s.tenantRepo.GetByID(tenantID); err == nil {
+			if c, ok := localization.ClusterCountry(t.Cluster); ok {
+				return c
+			}
+		}
+	}
+	c, _ := localization.LookupCountry(defaultHomeCountry)
+	return c
+}
+
+// Transfer register
+
+// TransferEntry is one flow of personal data to a recipient outside the
+// tenant's home country, or whose location could not be resolved
+type TransferEntry struct {
+	PurposeID     uuid.UUID  `json:"purposeId"`
+	Purpose       string     `json:"purpose"`
+	RecipientType string     `json:"recipientType"` // vendor, sub_processor
+	RecipientID   *uuid.UUID `json:"recipientId,omitempty"`
+	Recipient     string     `json:"recipient"`
+	// Via is the vendor a sub-processor receives the data through
+	Via                string   `json:"via,omitempty"`
+	Location           string   `json:"location,omitempty"`
+	Country            string   `json:"country,omitempty"`
+	CountryName        string   `json:"countryName,omitempty"`
+	Region             string   `json:"region,omitempty"`
+	DataCategories     []string `json:"dataCategories"`
+	Restriction        string   `json:"restriction,omitempty"` // block, flag
+	LocationUnresolved bool     `json:"locationUnresolved"`
+}
+
+// TransferRegister lists every purpose and data category flowing abroad
+type TransferRegister struct {
+	HomeCountry localization.Country       `json:"homeCountry"`
+	GeneratedAt time.Time                  `json:"generatedAt"`
+	Restricted  []models.RestrictedCountry `json:"restricted"`
+	Transfers   []TransferEntry            `json:"transfers"`
+	// Blocked counts transfers to blocked countries, which need remediation
+	Blocked int `json:"blocked"`
+	Flagged int `json:"flagged"`
+}
+
+// Register builds the transfer register from the tenant's active
+// third-party purposes, the vendors they name, the data categories in each
+// vendor's active DPA and the vendors' approved sub-processors
+func (s *TransferService) Register(tenantID uuid.UUID, purposes []models.Purpose) (*TransferRegister, error) {
+	home := s.HomeCountry(tenantID)
+	restricted, err := s.repo.ListRestricted(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rules := map[string]string{}
+	for _, rc := range restricted {
+		rules[rc.CountryCode] = rc.Action
+	}
+
+	active := make([]models.Purpose, 0, len(purposes))
+	for _, p := range purposes {
+		if p.Active && p.IsThirdParty {
+			active = append(active, p)
+		}
+	}
+	vendors, ids := resolvePurposeVendors(s.vendorRepo, active)
+	subs, err := s.subRepo.ListApproved(tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	subsByVendor := map[uuid.UUID][]models.VendorSubProcessor{}
+	for _, sp := range subs {
+		subsByVendor[sp.VendorID] = append(subsByVendor[sp.VendorID], sp)
+	}
+	categories := map[uuid.UUID][]string{}
+	for _, id := range ids {
+		if categories[id], err = s.dpaCategories(tenantID, id); err != nil {
+			return nil, err
+		}
+	}
+
+	reg := &TransferRegister{HomeCountry: home, GeneratedAt: time.Now(), Restricted: restricted, Transfers: []TransferEntry{}}
+	add := func(e TransferEntry, countryCode string) {
+		if countryCode == "" {
+			e.LocationUnresolved = true
+		} else {
+			if countryCode == home.Code {
+				return
+			}
+			c, _ := localization.LookupCountry(countryCode)
+			e.Country, e.CountryName, e.Region = c.Code, c.Name, c.Region
+			e.Restriction = rules[c.Code]
+		}
+		if e.DataCategories == nil {
+			e.DataCategories = []string{}
+		}
+		switch e.Restriction {
+		case models.TransferRestrictionBlock:
+			reg.Blocked++
+		case models.TransferRestrictionFlag:
+			reg.Flagged++
+		}
+		reg.Transfers = append(reg.Transfers, e)
+	}
+
+	for _, p := range active {
+		for _, ref := range p.Vendors {
+			v := vendors[ref]
+			if v == nil {
+				add(TransferEntry{PurposeID: p.ID, Purpose: p.Name, RecipientType: "vendor", Recipient: ref}, "")
+				continue
+			}
+			id := v.VendorID
+			add(TransferEntry{
+				PurposeID: p.ID, Purpose: p.Name, RecipientType: "vendor", RecipientID: &id, Recipient: v.Company,
+				Location: v.ProcessingLocation, DataCategories: categories[id],
+			}, vendorCountry(v))
+			for _, sp := range subsByVendor[id] {
+				spID := sp.ID
+				var cats []string
+				_ = json.Unmarshal(sp.DataCategories, &cats)
+				add(TransferEntry{
+					PurposeID: p.ID, Purpose: p.Name, RecipientType: "sub_processor", RecipientID: &spID, Recipient: sp.Name,
+					Via: v.Company, Location: sp.Location, DataCategories: cats,
+				}, subProcessorCountry(&sp))
+			}
+		}
+	}
+	sort.SliceStable(reg.Transfers, func(i, j int) bool {
+		if reg.Transfers[i].Purpose != reg.Transfers[j].Purpose {
+			return reg.Transfers[i].Purpose < reg.Transfers[j].Purpose
+		}
+		return reg.Transfers[i].Recipient < reg.Transfers[j].Recipient
+	})
+	return reg, nil
+}
+
+// dpaCategories merges the data categories of the vendor's active DPAs with
+// the tenant
+func (s *TransferService) dpaCategories(tenantID, vendorID uuid.UUID) ([]string, error) {
+	out := []string{}
+	if s.dpaRepo == nil {
+		return out, nil
+	}
+	dpas, err := s.dpaRepo.GetDPAsByVendor(vendorID)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, d := range dpas {
+		if d.TenantID != tenantID || d.Status != "active" {
+			continue
+		}
+		var cats []string
+		_ = json.Unmarshal(d.DataCategories, &cats)
+		for _, c := range cats {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out, nil
+}
+
+func vendorCountry(v *models.Vendor) string {
+	if v.ProcessingCountry != "" {
+		return v.ProcessingCountry
+	}
+	c, _ := localization.NormalizeCountry(v.ProcessingLocation)
+	return c.Code
+}
+
+func subProcessorCountry(sp *models.VendorSubProcessor) string {
+	if sp.Country != "" {
+		return sp.Country
+	}
+	c, _ := localization.NormalizeCountry(sp.Location)
+	return c.Code
+}
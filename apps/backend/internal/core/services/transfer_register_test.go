@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/encryption"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTransfers(t *testing.T) (*TransferService, *SubProcessorService, *repository.EncryptedDPARepository, *gorm.DB) {
+	require.NoError(t, encryption.InitEncryption())
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Tenant{}, &models.Vendor{}, &models.EncryptedDataProcessingAgreement{},
+		&models.VendorSubProcessor{}, &models.VendorSubProcessorEvent{}, &models.RestrictedCountry{},
+	))
+	vendorRepo := repository.NewVendorRepository(db)
+	subRepo := repository.NewSubProcessorRepository(db)
+	dpaRepo := repository.NewEncryptedDPARepository(db)
+	transfers := NewTransferService(repository.NewTransferRepository(db), vendorRepo, subRepo, dpaRepo, repository.NewTenantRepository(db))
+	subs := NewSubProcessorService(subRepo, vendorRepo, dpaRepo, transfers, nil, nil, "")
+	return transfers, subs, dpaRepo, db
+}
+
+func TestResolveCountry(t *testing.T) {
+	for text, want := range map[string]string{
+		"IN": "IN", "ind": "IN", "India": "IN", "Mumbai, India": "IN", "AWS ap-south-1": "IN",
+		"USA": "US", "Frankfurt (Germany)": "DE", "eu-west-1": "IE", "United Kingdom": "GB",
+	} {
+		c, err := ResolveCountry("", text)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, c.Code, text)
+	}
+	_, err := ResolveCountry("", "Somewhere in the cloud")
+	assert.ErrorIs(t, err, ErrUnknownCountry)
+	_, err = ResolveCountry("XX", "India")
+	assert.ErrorIs(t, err, ErrUnknownCountry) // An explicit code must be valid
+	c, err := ResolveCountry("sg", "Mumbai")
+	require.NoError(t, err)
+	assert.Equal(t, "SG", c.Code)
+}
+
+func TestTransfers_RestrictedList(t *testing.T) {
+	svc, subs, _, db := setupTransfers(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme", Cluster: "india-central"}).Error)
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "dpo@acme.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+
+	_, err := svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "Atlantis"})
+	assert.ErrorIs(t, err, ErrUnknownCountry)
+	_, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "CN", Action: "warn"})
+	assert.ErrorIs(t, err, ErrInvalidTransferRestriction)
+	_, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "India"})
+	assert.ErrorIs(t, err, ErrInvalidTransferRestriction) // Home country
+
+	rc, err := svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "china", Reason: "Notified under Section 16"})
+	require.NoError(t, err)
+	assert.Equal(t, "CN", rc.CountryCode)
+	assert.Equal(t, models.TransferRestrictionBlock, rc.Action)
+	_, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "US", Action: "flag"})
+	require.NoError(t, err)
+	// Updating an entry keeps one row per country
+	rc, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "CHN", Action: "block", Reference: "G.S.R. 1(E)"})
+	require.NoError(t, err)
+	assert.Equal(t, "G.S.R. 1(E)", rc.Reference)
+	list, err := svc.ListRestricted(tenantID)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	sess := subProcessorSession(tenantID, vendor, models.VendorScopeSubProcessors)
+	_, err = subs.Propose(sess, SubProcessorInput{Name: "DataCo", Location: "Shanghai", Services: "Support", DataCategories: []string{"contact"}})
+	var restricted *TransferRestrictedError
+	require.True(t, errors.As(err, &restricted))
+	assert.Equal(t, "China", restricted.Country.Name)
+
+	sp, err := subs.Propose(sess, SubProcessorInput{Name: "MailCo", Location: "Virginia, USA", Services: "Email", DataCategories: []string{"email"}})
+	require.NoError(t, err)
+	assert.Equal(t, "US", sp.Country)
+	assert.True(t, sp.RestrictedTransfer)
+
+	_, err = subs.Propose(sess, SubProcessorInput{Name: "Nowhere", Location: "the cloud", Services: "Email", DataCategories: []string{"email"}})
+	assert.ErrorIs(t, err, ErrInvalidSubProcessor)
+
+	// Other tenants are unaffected
+	other := subProcessorSession(uuid.New(), vendor, models.VendorScopeSubProcessors)
+	sp, err = subs.Propose(other, SubProcessorInput{Name: "DataCo", Location: "Shanghai", Services: "Support", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	assert.False(t, sp.RestrictedTransfer)
+
+	require.NoError(t, svc.RemoveRestricted(tenantID, "cn"))
+	assert.ErrorIs(t, svc.RemoveRestricted(tenantID, "CN"), ErrRestrictedCountryNotFound)
+}
+
+func TestTransfers_Register(t *testing.T) {
+	svc, subs, dpaRepo, db := setupTransfers(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&models.Tenant{TenantID: tenantID, Name: "Acme", Cluster: "mumbai"}).Error)
+	crm := models.Vendor{VendorID: uuid.New(), Company: "CRMCo", Email: "dpo@crm.example", ProcessingLocation: "Oregon, USA", ProcessingCountry: "US"}
+	local := models.Vendor{VendorID: uuid.New(), Company: "LocalCo", Email: "dpo@local.example", ProcessingLocation: "Chennai"}
+	require.NoError(t, db.Create(&crm).Error)
+	require.NoError(t, db.Create(&local).Error)
+	require.NoError(t, dpaRepo.CreateDPA(&models.DataProcessingAgreement{
+		ID: uuid.New(), TenantID: tenantID, VendorID: crm.VendorID, AgreementNumber: "DPA-1", Status: "active",
+		DataCategories: datatypes.JSON(`["contact","purchase history"]`),
+	}))
+
+	_, err := subs.Declare(tenantID, crm.VendorID, userID, SubProcessorInput{Name: "IndiaHost", Location: "Mumbai", Services: "Hosting", DataCategories: []string{"contact"}})
+	require.NoError(t, err)
+	_, err = subs.Declare(tenantID, local.VendorID, userID, SubProcessorInput{Name: "SupportCo", Location: "Manila", Services: "Support", DataCategories: []string{"contact", "tickets"}})
+	require.NoError(t, err)
+	_, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "PH", Action: "flag"})
+	require.NoError(t, err)
+	// Blocking a country later shows existing flows that need remediation
+	_, err = svc.SetRestricted(tenantID, userID, RestrictionInput{Country: "US"})
+	require.NoError(t, err)
+
+	purposes := []models.Purpose{
+		{ID: uuid.New(), Name: "Marketing", Active: true, IsThirdParty: true, Vendors: pq.StringArray{crm.VendorID.String(), "Legacy Mailer"}},
+		{ID: uuid.New(), Name: "Support", Active: true, IsThirdParty: true, Vendors: pq.StringArray{local.VendorID.String()}},
+		{ID: uuid.New(), Name: "Retired", Active: false, IsThirdParty: true, Vendors: pq.StringArray{crm.VendorID.String()}},
+	}
+	reg, err := svc.Register(tenantID, purposes)
+	require.NoError(t, err)
+	assert.Equal(t, "IN", reg.HomeCountry.Code)
+	require.Len(t, reg.Transfers, 3)
+
+	crmFlow := reg.Transfers[0]
+	assert.Equal(t, "Marketing", crmFlow.Purpose)
+	assert.Equal(t, "CRMCo", crmFlow.Recipient)
+	assert.Equal(t, "US", crmFlow.Country)
+	assert.Equal(t, []string{"contact", "purchase history"}, crmFlow.DataCategories)
+	assert.Equal(t, models.TransferRestrictionBlock, crmFlow.Restriction)
+
+	unknown := reg.Transfers[1]
+	assert.Equal(t, "Legacy Mailer", unknown.Recipient)
+	assert.True(t, unknown.LocationUnresolved)
+
+	// LocalCo and IndiaHost stay in India; SupportCo receives data through LocalCo
+	support := reg.Transfers[2]
+	assert.Equal(t, "sub_processor", support.RecipientType)
+	assert.Equal(t, "LocalCo", support.Via)
+	assert.Equal(t, "PH", support.Country)
+	assert.Equal(t, []string{"contact", "tickets"}, support.DataCategories)
+	assert.Equal(t, models.TransferRestrictionFlag, support.Restriction)
+
+	assert.Equal(t, 1, reg.Blocked)
+	assert.Equal(t, 1, reg.Flagged)
+}
@@ -0,0 +1,550 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/auth"
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+	"pixpivot/arc/pkg/log"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVendorPortalTokenInvalid = errors.New("vendor portal link is invalid or has expired")
+	ErrVendorScopeDenied        = errors.New("this vendor portal link does not allow that action")
+	ErrVendorNotFound           = errors.New("vendor not found")
+	ErrVendorInvitationNotFound = errors.New("vendor portal invitation not found")
+	ErrInvalidVendorInvitation  = errors.New("invalid vendor portal invitation")
+	ErrFindingNotFound          = errors.New("finding not found")
+	ErrFindingClosed            = errors.New("finding has already been resolved")
+	ErrInvalidFindingResponse   = errors.New("invalid finding response")
+	ErrDPANotFound              = errors.New("data processing agreement not found")
+	ErrDPAAcknowledged          = errors.New("data processing agreement has already been acknowledged")
+)
+
+const (
+	vendorInvitationDefaultTTL = 14 * 24 * time.Hour
+	vendorInvitationMaxTTL     = 90 * 24 * time.Hour
+)
+
+var vendorPortalScopes = []string{
+	models.VendorScopeAssessments,
+	models.VendorScopeEvidence,
+	models.VendorScopeFindings,
+	models.VendorScopeDPAs,
+	models.VendorScopeSubProcessors,
+}
+
+// vendorReminderStages are sent as an open assessment's due date approaches,
+// each once, latest applicable stage only
+var vendorReminderStages = []struct {
+	name   string
+	before time.Duration
+}{
+	{"overdue", 0},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// VendorPortalService lets vendor contacts work on their own assessments,
+// findings and DPAs through emailed links. A link carries a token bound to
+// one vendor in one tenant, a set of scopes and optionally a fixed list of
+// assessments; nothing outside that is reachable with it.
+type VendorPortalService struct {
+	repo           *repository.VendorPortalRepository
+	tprm           *TPRMService
+	questionnaires *VendorQuestionnaireService
+	emailService   *EmailService
+	portalURL      string
+}
+
+func NewVendorPortalService(repo *repository.VendorPortalRepository, tprm *TPRMService, questionnaires *VendorQuestionnaireService, emailService *EmailService, portalURL string) *VendorPortalService {
+	return &VendorPortalService{
+		repo:           repo,
+		tprm:           tprm,
+		questionnaires: questionnaires,
+		emailService:   emailService,
+		portalURL:      strings.TrimRight(portalURL, "/"),
+	}
+}
+
+func hashVendorToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VendorSession is an authenticated vendor portal caller
+type VendorSession struct {
+	Invitation  *models.VendorPortalInvitation
+	Scopes      map[string]bool
+	Assessments map[uuid.UUID]bool // Nil when every vendor assessment is in scope
+}
+
+func (s *VendorSession) TenantID() uuid.UUID { return s.Invitation.TenantID }
+func (s *VendorSession) VendorID() uuid.UUID { return s.Invitation.VendorID }
+
+func (s *VendorSession) require(scope string) error {
+	if !s.Scopes[scope] {
+		return ErrVendorScopeDenied
+	}
+	return nil
+}
+
+func (s *VendorSession) covers(a *models.TPRMAssessment) bool {
+	if a.TenantID != s.TenantID() || a.VendorID != s.VendorID() {
+		return false
+	}
+	return s.Assessments == nil || s.Assessments[a.ID]
+}
+
+func newVendorSession(inv *models.VendorPortalInvitation) *VendorSession {
+	sess := &VendorSession{Invitation: inv, Scopes: map[string]bool{}}
+	var scopes []string
+	_ = json.Unmarshal(inv.Scopes, &scopes)
+	for _, sc := range scopes {
+		sess.Scopes[sc] = true
+	}
+	var ids []uuid.UUID
+	_ = json.Unmarshal(inv.AssessmentIDs, &ids)
+	if len(ids) > 0 {
+		sess.Assessments = map[uuid.UUID]bool{}
+		for _, id := range ids {
+			sess.Assessments[id] = true
+		}
+	}
+	return sess
+}
+
+// Invitations
+
+// InviteInput describes a new vendor portal invitation. Email defaults to the
+// vendor's contact email, Scopes to every scope and TTL to 14 days.
+type InviteInput struct {
+	Email         string        `json:"email"`
+	Scopes        []string      `json:"scopes"`
+	AssessmentIDs []uuid.UUID   `json:"assessmentIds"`
+	TTL           time.Duration `json:"-"`
+}
+
+// Invite creates an invitation and emails its link to the vendor contact.
+// The token is returned so callers can deliver it another way, but is never
+// stored.
+func (s *VendorPortalService) Invite(tenantID, vendorID, invitedBy uuid.UUID, in InviteInput) (*models.VendorPortalInvitation, string, error) {
+	vendor, err := s.repo.GetVendor(tenantID, vendorID)
+	if err != nil {
+		return nil, "", ErrVendorNotFound
+	}
+	email := strings.ToLower(strings.TrimSpace(in.Email))
+	if email == "" {
+		email = strings.ToLower(strings.TrimSpace(vendor.Email))
+	}
+	if email == "" {
+		return nil, "", fmt.Errorf("%w: the vendor has no contact email", ErrInvalidVendorInvitation)
+	}
+
+	scopes := in.Scopes
+	if len(scopes) == 0 {
+		scopes = vendorPortalScopes
+	}
+	known := map[string]bool{}
+	for _, sc := range vendorPortalScopes {
+		known[sc] = true
+	}
+	for _, sc := range scopes {
+		if !known[sc] {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidVendorInvitation, sc)
+		}
+	}
+
+	ttl := in.TTL
+	if ttl <= 0 {
+		ttl = vendorInvitationDefaultTTL
+	}
+	if ttl > vendorInvitationMaxTTL {
+		return nil, "", fmt.Errorf("%w: links can be valid for at most 90 days", ErrInvalidVendorInvitation)
+	}
+
+	for _, id := range in.AssessmentIDs {
+		a, err := s.tprm.repo.GetAssessmentByID(id)
+		if err != nil || a.TenantID != tenantID || a.VendorID != vendorID {
+			return nil, "", fmt.Errorf("%w: assessment %s is not an assessment of this vendor", ErrInvalidVendorInvitation, id)
+		}
+	}
+
+	scopesJSON, _ := json.Marshal(scopes)
+	inv := &models.VendorPortalInvitation{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		VendorID:  vendorID,
+		Email:     email,
+		Scopes:    scopesJSON,
+		ExpiresAt: time.Now().Add(ttl),
+		InvitedBy: invitedBy,
+	}
+	if len(in.AssessmentIDs) > 0 {
+		inv.AssessmentIDs, _ = json.Marshal(in.AssessmentIDs)
+	}
+	token := auth.GenerateSecureToken()
+	inv.TokenHash = hashVendorToken(token)
+	if err := s.repo.CreateInvitation(inv); err != nil {
+		return nil, "", err
+	}
+
+	link := fmt.Sprintf("%s/vendor-portal?token=%s", s.portalURL, token)
+	body := fmt.Sprintf("Hello,<br><br>You have been invited to the vendor portal to respond on behalf of <b>%s</b>.<br><br>"+
+		"<a href=\"%s\">Open the vendor portal</a><br><br>This link is personal to you and expires on %s.",
+		html.EscapeString(vendor.Company), link, inv.ExpiresAt.Format("2 Jan 2006"))
+	if s.emailService != nil {
+		if err := s.emailService.Send(email, "Vendor portal invitation", body); err != nil {
+			log.Logger.Error().Err(err).Str("invitation_id", inv.ID.String()).Msg("failed to send vendor portal invitation")
+		}
+	}
+	return inv, token, nil
+}
+
+func (s *VendorPortalService) ListInvitations(tenantID, vendorID uuid.UUID) ([]models.VendorPortalInvitation, error) {
+	return s.repo.ListInvitations(tenantID, vendorID)
+}
+
+// RevokeInvitation cuts off the link immediately
+func (s *VendorPortalService) RevokeInvitation(tenantID, id uuid.UUID) (*models.VendorPortalInvitation, error) {
+	inv, err := s.repo.GetInvitation(tenantID, id)
+	if err != nil {
+		return nil, ErrVendorInvitationNotFound
+	}
+	if inv.RevokedAt == nil {
+		now := time.Now()
+		inv.RevokedAt = &now
+		if err := s.repo.UpdateInvitation(inv); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// Authenticate resolves a portal token to a session. Unknown, revoked and
+// expired tokens are indistinguishable to the caller.
+func (s *VendorPortalService) Authenticate(token string) (*VendorSession, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrVendorPortalTokenInvalid
+	}
+	inv, err := s.repo.GetInvitationByTokenHash(hashVendorToken(token))
+	if err != nil {
+		return nil, ErrVendorPortalTokenInvalid
+	}
+	now := time.Now()
+	if inv.RevokedAt != nil || !now.Before(inv.ExpiresAt) {
+		return nil, ErrVendorPortalTokenInvalid
+	}
+	if err := s.repo.TouchInvitation(inv.ID, now); err != nil {
+		log.Logger.Warn().Err(err).Str("invitation_id", inv.ID.String()).Msg("failed to record vendor portal use")
+	}
+	return newVendorSession(inv), nil
+}
+
+// Vendor-facing operations
+
+// VendorPortalOverview is what the vendor sees on landing in the portal
+type VendorPortalOverview struct {
+	VendorID  uuid.UUID `json:"vendorId"`
+	Company   string    `json:"company"`
+	Email     string    `json:"email"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (s *VendorPortalService) Overview(sess *VendorSession) (*VendorPortalOverview, error) {
+	vendor, err := s.repo.GetVendor(sess.Invitation.TenantID, sess.VendorID())
+	if err != nil {
+		return nil, ErrVendorNotFound
+	}
+	o := &VendorPortalOverview{
+		VendorID:  vendor.VendorID,
+		Company:   vendor.Company,
+		Email:     sess.Invitation.Email,
+		Scopes:    []string{},
+		ExpiresAt: sess.Invitation.ExpiresAt,
+	}
+	for _, sc := range vendorPortalScopes {
+		if sess.Scopes[sc] {
+			o.Scopes = append(o.Scopes, sc)
+		}
+	}
+	return o, nil
+}
+
+func (s *VendorPortalService) ListAssessments(sess *VendorSession) ([]models.TPRMAssessment, error) {
+	if err := sess.require(models.VendorScopeAssessments); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.ListVendorAssessments(sess.TenantID(), sess.VendorID())
+	if err != nil {
+		return nil, err
+	}
+	list := []models.TPRMAssessment{}
+	for i := range all {
+		if sess.covers(&all[i]) {
+			list = append(list, all[i])
+		}
+	}
+	return list, nil
+}
+
+// assessment loads an assessment in the session's scope; anything outside
+// it is reported as not found
+func (s *VendorPortalService) assessment(sess *VendorSession, id uuid.UUID) (*models.TPRMAssessment, error) {
+	a, err := s.tprm.repo.GetAssessmentByID(id)
+	if err != nil || !sess.covers(a) {
+		return nil, ErrAssessmentNotFound
+	}
+	return a, nil
+}
+
+func (s *VendorPortalService) GetQuestionnaire(sess *VendorSession, assessmentID uuid.UUID) (*AssessmentQuestionnaire, error) {
+	if err := sess.require(models.VendorScopeAssessments); err != nil {
+		return nil, err
+	}
+	if _, err := s.assessment(sess, assessmentID); err != nil {
+		return nil, err
+	}
+	return s.questionnaires.GetAssessmentQuestionnaire(sess.TenantID(), assessmentID)
+}
+
+// SubmitAnswers records the vendor's answers. Answers are attributed to the
+// invitation they were given through.
+func (s *VendorPortalService) SubmitAnswers(sess *VendorSession, assessmentID uuid.UUID, inputs []AnswerInput) error {
+	if err := sess.require(models.VendorScopeAssessments); err != nil {
+		return err
+	}
+	if _, err := s.assessment(sess, assessmentID); err != nil {
+		return err
+	}
+	_, err := s.questionnaires.SubmitAnswers(sess.TenantID(), assessmentID, sess.Invitation.ID, inputs)
+	return err
+}
+
+func (s *VendorPortalService) UploadEvidence(sess *VendorSession, assessmentID uuid.UUID, fileName, contentType string, data []byte) (*models.TPRMEvidence, error) {
+	if err := sess.require(models.VendorScopeEvidence); err != nil {
+		return nil, err
+	}
+	a, err := s.assessment(sess, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == "completed" {
+		return nil, ErrAssessmentCompleted
+	}
+	return s.tprm.StoreEvidence(sess.TenantID(), sess.VendorID(), a.ID, fileName, contentType, data, sess.Invitation.ID)
+}
+
+// VendorFinding is a remediation request with the vendor's replies so far
+type VendorFinding struct {
+	models.TPRMFinding
+	Responses []models.VendorFindingResponse `json:"responses"`
+}
+
+func (s *VendorPortalService) ListFindings(sess *VendorSession) ([]VendorFinding, error) {
+	if err := sess.require(models.VendorScopeFindings); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.ListVendorFindings(sess.TenantID(), sess.VendorID())
+	if err != nil {
+		return nil, err
+	}
+	list := []VendorFinding{}
+	for _, f := range all {
+		if sess.Assessments != nil && !sess.Assessments[f.AssessmentID] {
+			continue
+		}
+		responses, err := s.repo.ListFindingResponses(f.ID)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, VendorFinding{TPRMFinding: f, Responses: responses})
+	}
+	return list, nil
+}
+
+// FindingResponseInput is a vendor's reply to a remediation request.
+// Resolved claims the fix is done; the finding stays open until the
+// fiduciary verifies it.
+type FindingResponseInput struct {
+	Response   string     `json:"response"`
+	EvidenceID *uuid.UUID `json:"evidenceId"`
+	TargetDate *time.Time `json:"targetDate"`
+	Resolved   bool       `json:"resolved"`
+}
+
+func (s *VendorPortalService) RespondToFinding(sess *VendorSession, findingID uuid.UUID, in FindingResponseInput) (*models.VendorFindingResponse, error) {
+	if err := sess.require(models.VendorScopeFindings); err != nil {
+		return nil, err
+	}
+	f, err := s.repo.GetFinding(sess.TenantID(), findingID)
+	if err != nil || f.VendorID != sess.VendorID() || (sess.Assessments != nil && !sess.Assessments[f.AssessmentID]) {
+		return nil, ErrFindingNotFound
+	}
+	if f.Status == "resolved" {
+		return nil, ErrFindingClosed
+	}
+	text := strings.TrimSpace(in.Response)
+	if text == "" {
+		return nil, fmt.Errorf("%w: response is required", ErrInvalidFindingResponse)
+	}
+	if in.EvidenceID != nil {
+		e, err := s.tprm.repo.GetEvidenceByID(*in.EvidenceID)
+		if err != nil || e.TenantID != sess.TenantID() || e.VendorID != sess.VendorID() {
+			return nil, fmt.Errorf("%w: evidence not found for this vendor", ErrInvalidFindingResponse)
+		}
+	}
+	resp := &models.VendorFindingResponse{
+		ID:             uuid.New(),
+		FindingID:      f.ID,
+		TenantID:       f.TenantID,
+		VendorID:       f.VendorID,
+		InvitationID:   sess.Invitation.ID,
+		RespondedBy:    sess.Invitation.Email,
+		Response:       text,
+		EvidenceID:     in.EvidenceID,
+		TargetDate:     in.TargetDate,
+		MarkedResolved: in.Resolved,
+	}
+	if err := s.repo.AddFindingResponse(resp, "in_progress"); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListDPAs returns the vendor's agreements; drafts are not shown
+func (s *VendorPortalService) ListDPAs(sess *VendorSession) ([]models.DPAAgreement, error) {
+	if err := sess.require(models.VendorScopeDPAs); err != nil {
+		return nil, err
+	}
+	all, err := s.repo.ListVendorDPAs(sess.TenantID(), sess.VendorID())
+	if err != nil {
+		return nil, err
+	}
+	list := []models.DPAAgreement{}
+	for _, a := range all {
+		if a.Status != "draft" {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+func (s *VendorPortalService) AcknowledgeDPA(sess *VendorSession, dpaID uuid.UUID) (*models.DPAAgreement, error) {
+	if err := sess.require(models.VendorScopeDPAs); err != nil {
+		return nil, err
+	}
+	a, err := s.repo.GetDPA(sess.TenantID(), dpaID)
+	if err != nil || a.VendorID != sess.VendorID() || a.Status == "draft" {
+		return nil, ErrDPANotFound
+	}
+	if a.AcknowledgedAt != nil {
+		return nil, ErrDPAAcknowledged
+	}
+	now := time.Now()
+	a.AcknowledgedAt = &now
+	a.AcknowledgedBy = sess.Invitation.Email
+	if err := s.repo.UpdateDPA(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Due-date reminders
+
+// RegisterJobs sends assessment due-date reminders hourly
+func (s *VendorPortalService) RegisterJobs(r *JobRunner) {
+	r.Register("vendor_portal.reminders", func(ctx context.Context, job *models.Job) error {
+		_, err := s.SendDueReminders(time.Now())
+		return err
+	})
+	r.Schedule("vendor_portal.reminders", "15 * * * *", "vendor_portal.reminders", nil)
+}
+
+// SendDueReminders emails vendor contacts about open assessments due within
+// seven days, a day before, and once overdue. Only contacts holding a live
+// invitation covering the assessment are reminded. It returns how many
+// reminders went out.
+func (s *VendorPortalService) SendDueReminders(now time.Time) (int, error) {
+	due, err := s.repo.ListAssessmentsDueBefore(now.Add(vendorReminderStages[len(vendorReminderStages)-1].before))
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		a := &due[i]
+		left := a.DueDate.Sub(now)
+		stage := ""
+		for _, st := range vendorReminderStages {
+			if left <= st.before {
+				stage = st.name
+				break
+			}
+		}
+
+		invitations, err := s.repo.ListActiveInvitations(a.TenantID, a.VendorID, now)
+		if err != nil {
+			return sent, err
+		}
+		recipients := []string{}
+		seen := map[string]bool{}
+		for j := range invitations {
+			sess := newVendorSession(&invitations[j])
+			if !sess.Scopes[models.VendorScopeAssessments] || !sess.covers(a) || seen[sess.Invitation.Email] {
+				continue
+			}
+			seen[sess.Invitation.Email] = true
+			recipients = append(recipients, sess.Invitation.Email)
+		}
+		if len(recipients) == 0 {
+			continue
+		}
+
+		fresh, err := s.repo.RecordReminder(&models.VendorAssessmentReminder{
+			ID:           uuid.New(),
+			AssessmentID: a.ID,
+			Stage:        stage,
+			TenantID:     a.TenantID,
+			SentTo:       strings.Join(recipients, ","),
+			SentAt:       now,
+		})
+		if err != nil {
+			return sent, err
+		}
+		if !fresh {
+			continue
+		}
+
+		subject := fmt.Sprintf("Reminder: %s is due %s", a.Title, a.DueDate.Format("2 Jan 2006"))
+		if stage == "overdue" {
+			subject = fmt.Sprintf("Overdue: %s was due %s", a.Title, a.DueDate.Format("2 Jan 2006"))
+		}
+		body := fmt.Sprintf("Hello,<br><br>The assessment <b>%s</b> is due on %s. "+
+			"Please complete it using the vendor portal link you were sent.<br><br><a href=\"%s/vendor-portal\">Open the vendor portal</a>",
+			html.EscapeString(a.Title), a.DueDate.Format("2 Jan 2006 15:04 MST"), s.portalURL)
+		for _, to := range recipients {
+			if s.emailService == nil {
+				continue
+			}
+			if err := s.emailService.Send(to, subject, body); err != nil {
+				log.Logger.Error().Err(err).Str("assessment_id", a.ID.String()).Msg("failed to send vendor assessment reminder")
+				continue
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
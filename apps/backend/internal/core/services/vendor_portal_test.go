@@ -0,0 +1,193 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupVendorPortal(t *testing.T) (*VendorPortalService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Vendor{}, &models.TPRMAssessment{}, &models.TPRMEvidence{}, &models.TPRMFinding{}, &models.DPAAgreement{},
+		&models.VendorQuestionnaire{}, &models.VendorQuestionnaireVersion{}, &models.VendorQuestionnaireAnswer{},
+		&models.VendorPortalInvitation{}, &models.VendorFindingResponse{}, &models.VendorAssessmentReminder{},
+	))
+	tprmRepo := repository.NewTPRMRepository(db)
+	tprm := NewTPRMService(tprmRepo, "", "local", t.TempDir(), "", "", "", "", "", false, false)
+	questionnaires := NewVendorQuestionnaireService(repository.NewVendorQuestionnaireRepository(db), tprmRepo)
+	return NewVendorPortalService(repository.NewVendorPortalRepository(db), tprm, questionnaires, nil, "https://app.example.com"), db
+}
+
+func TestVendorPortal_TokenIsScopedToVendor(t *testing.T) {
+	svc, db := setupVendorPortal(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "Security@Acme.example"}
+	other := models.Vendor{VendorID: uuid.New(), Company: "Globex", Email: "sec@globex.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	mine := models.TPRMAssessment{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Title: "Annual review", Status: "pending"}
+	second := models.TPRMAssessment{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Title: "Pen test follow-up", Status: "pending"}
+	theirs := models.TPRMAssessment{ID: uuid.New(), TenantID: tenantID, VendorID: other.VendorID, Title: "Annual review", Status: "pending"}
+	for _, a := range []*models.TPRMAssessment{&mine, &second, &theirs} {
+		require.NoError(t, db.Create(a).Error)
+	}
+
+	_, _, err := svc.Invite(tenantID, vendor.VendorID, userID, InviteInput{AssessmentIDs: []uuid.UUID{theirs.ID}})
+	assert.ErrorIs(t, err, ErrInvalidVendorInvitation)
+
+	// A vendor another tenant works with cannot be invited
+	stranger := models.Vendor{VendorID: uuid.New(), Company: "Initech", Email: "sec@initech.example"}
+	require.NoError(t, db.Create(&stranger).Error)
+	require.NoError(t, db.Create(&models.TPRMAssessment{ID: uuid.New(), TenantID: uuid.New(), VendorID: stranger.VendorID, Title: "Review", Status: "pending"}).Error)
+	_, _, err = svc.Invite(tenantID, stranger.VendorID, userID, InviteInput{})
+	assert.ErrorIs(t, err, ErrVendorNotFound)
+
+	inv, token, err := svc.Invite(tenantID, vendor.VendorID, userID, InviteInput{
+		Scopes:        []string{models.VendorScopeAssessments, models.VendorScopeEvidence},
+		AssessmentIDs: []uuid.UUID{mine.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "security@acme.example", inv.Email)
+	assert.Equal(t, hashVendorToken(token), inv.TokenHash) // Only the hash is kept
+
+	_, err = svc.Authenticate("not-a-token")
+	assert.ErrorIs(t, err, ErrVendorPortalTokenInvalid)
+	sess, err := svc.Authenticate(token)
+	require.NoError(t, err)
+
+	// Only the assigned assessment is visible
+	list, err := svc.ListAssessments(sess)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, mine.ID, list[0].ID)
+	_, err = svc.UploadEvidence(sess, second.ID, "soc2.pdf", "application/pdf", []byte("%PDF"))
+	assert.ErrorIs(t, err, ErrAssessmentNotFound)
+	_, err = svc.UploadEvidence(sess, theirs.ID, "soc2.pdf", "application/pdf", []byte("%PDF"))
+	assert.ErrorIs(t, err, ErrAssessmentNotFound)
+
+	e, err := svc.UploadEvidence(sess, mine.ID, "soc2.pdf", "application/pdf", []byte("%PDF"))
+	require.NoError(t, err)
+	assert.Equal(t, inv.ID, e.UploadedBy)
+
+	// Scopes not granted are refused
+	_, err = svc.ListFindings(sess)
+	assert.ErrorIs(t, err, ErrVendorScopeDenied)
+	_, err = svc.ListDPAs(sess)
+	assert.ErrorIs(t, err, ErrVendorScopeDenied)
+
+	_, err = svc.RevokeInvitation(uuid.New(), inv.ID)
+	assert.ErrorIs(t, err, ErrVendorInvitationNotFound)
+	_, err = svc.RevokeInvitation(tenantID, inv.ID)
+	require.NoError(t, err)
+	_, err = svc.Authenticate(token)
+	assert.ErrorIs(t, err, ErrVendorPortalTokenInvalid)
+
+	// Expired links stop working too
+	_, expiring, err := svc.Invite(tenantID, vendor.VendorID, userID, InviteInput{})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.VendorPortalInvitation{}).Where("token_hash = ?", hashVendorToken(expiring)).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = svc.Authenticate(expiring)
+	assert.ErrorIs(t, err, ErrVendorPortalTokenInvalid)
+}
+
+func TestVendorPortal_FindingsAndDPAs(t *testing.T) {
+	svc, db := setupVendorPortal(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "sec@acme.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+	a := models.TPRMAssessment{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Title: "Review", Status: "in_progress"}
+	require.NoError(t, db.Create(&a).Error)
+	finding := models.TPRMFinding{ID: uuid.New(), AssessmentID: a.ID, TenantID: tenantID, VendorID: vendor.VendorID, Severity: "high", Title: "No MFA", Status: "open"}
+	foreign := models.TPRMFinding{ID: uuid.New(), AssessmentID: uuid.New(), TenantID: uuid.New(), VendorID: vendor.VendorID, Severity: "low", Title: "Other tenant", Status: "open"}
+	require.NoError(t, db.Create(&finding).Error)
+	require.NoError(t, db.Create(&foreign).Error)
+	draft := models.DPAAgreement{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Status: "draft"}
+	sent := models.DPAAgreement{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Status: "sent"}
+	require.NoError(t, db.Create(&draft).Error)
+	require.NoError(t, db.Create(&sent).Error)
+
+	_, token, err := svc.Invite(tenantID, vendor.VendorID, userID, InviteInput{})
+	require.NoError(t, err)
+	sess, err := svc.Authenticate(token)
+	require.NoError(t, err)
+
+	findings, err := svc.ListFindings(sess)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, finding.ID, findings[0].ID)
+
+	_, err = svc.RespondToFinding(sess, foreign.ID, FindingResponseInput{Response: "done"})
+	assert.ErrorIs(t, err, ErrFindingNotFound)
+	_, err = svc.RespondToFinding(sess, finding.ID, FindingResponseInput{Response: " "})
+	assert.ErrorIs(t, err, ErrInvalidFindingResponse)
+	resp, err := svc.RespondToFinding(sess, finding.ID, FindingResponseInput{Response: "MFA rolled out to all staff", Resolved: true})
+	require.NoError(t, err)
+	assert.Equal(t, "sec@acme.example", resp.RespondedBy)
+
+	var stored models.TPRMFinding
+	require.NoError(t, db.First(&stored, "id = ?", finding.ID).Error)
+	assert.Equal(t, "in_progress", stored.Status) // Resolution is for the fiduciary to confirm
+
+	dpas, err := svc.ListDPAs(sess)
+	require.NoError(t, err)
+	require.Len(t, dpas, 1)
+	_, err = svc.AcknowledgeDPA(sess, draft.ID)
+	assert.ErrorIs(t, err, ErrDPANotFound)
+	ack, err := svc.AcknowledgeDPA(sess, sent.ID)
+	require.NoError(t, err)
+	require.NotNil(t, ack.AcknowledgedAt)
+	_, err = svc.AcknowledgeDPA(sess, sent.ID)
+	assert.ErrorIs(t, err, ErrDPAAcknowledged)
+}
+
+func TestVendorPortal_DueRemindersSentOncePerStage(t *testing.T) {
+	svc, db := setupVendorPortal(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "sec@acme.example"}
+	require.NoError(t, db.Create(&vendor).Error)
+	now := time.Now()
+	due := now.Add(3 * 24 * time.Hour)
+	a := models.TPRMAssessment{ID: uuid.New(), TenantID: tenantID, VendorID: vendor.VendorID, Title: "Review", Status: "pending", DueDate: &due}
+	require.NoError(t, db.Create(&a).Error)
+
+	stages := func() []string {
+		var list []models.VendorAssessmentReminder
+		require.NoError(t, db.Order("sent_at ASC").Find(&list).Error)
+		out := []string{}
+		for _, r := range list {
+			out = append(out, r.Stage)
+		}
+		return out
+	}
+
+	// No one holds a link yet, so nothing is recorded
+	_, err := svc.SendDueReminders(now)
+	require.NoError(t, err)
+	assert.Empty(t, stages())
+
+	_, _, err = svc.Invite(tenantID, vendor.VendorID, userID, InviteInput{})
+	require.NoError(t, err)
+	_, err = svc.SendDueReminders(now)
+	require.NoError(t, err)
+	_, err = svc.SendDueReminders(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"7d"}, stages())
+
+	_, err = svc.SendDueReminders(due.Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = svc.SendDueReminders(due.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"7d", "1d", "overdue"}, stages())
+}
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/questionnaire"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQuestionnaireNotFound        = errors.New("questionnaire not found")
+	ErrQuestionnaireVersionNotFound = errors.New("questionnaire version not found")
+	ErrQuestionnaireNotPublished    = errors.New("questionnaire has no published version")
+	ErrQuestionnaireVersionLocked   = errors.New("only draft versions can be changed")
+	ErrInvalidQuestionnaire         = errors.New("invalid questionnaire")
+	ErrAssessmentNotFound           = errors.New("assessment not found")
+	ErrAssessmentNoQuestionnaire    = errors.New("assessment is not a questionnaire assessment")
+	ErrAssessmentCompleted          = errors.New("assessment has already been completed")
+)
+
+// QuestionnaireInvalidError lists why a version cannot be published
+type QuestionnaireInvalidError struct {
+	Issues []string
+}
+
+func (e *QuestionnaireInvalidError) Error() string {
+	return "questionnaire is not valid: " + strings.Join(e.Issues, "; ")
+}
+
+// AnswersRejectedError lists the answers that were not accepted, by question
+type AnswersRejectedError struct {
+	Issues map[string]string
+}
+
+func (e *AnswersRejectedError) Error() string {
+	return fmt.Sprintf("%d answer(s) were rejected", len(e.Issues))
+}
+
+// QuestionnaireIncompleteError lists required questions still unanswered
+type QuestionnaireIncompleteError struct {
+	Missing []string
+}
+
+func (e *QuestionnaireIncompleteError) Error() string {
+	return "required questions are unanswered: " + strings.Join(e.Missing, ", ")
+}
+
+// answerReuseWindow is how old an earlier answer can be and still be carried
+// into a new assessment
+const answerReuseWindow = 365 * 24 * time.Hour
+
+// VendorQuestionnaireService lets tenants author versioned vendor
+// questionnaires and run assessments against them. An assessment pins the
+// version it was started on, so later edits never change what a vendor was
+// asked or how they were scored.
+type VendorQuestionnaireService struct {
+	repo     *repository.VendorQuestionnaireRepository
+	tprmRepo *repository.TPRMRepository
+}
+
+func NewVendorQuestionnaireService(repo *repository.VendorQuestionnaireRepository, tprmRepo *repository.TPRMRepository) *VendorQuestionnaireService {
+	return &VendorQuestionnaireService{repo: repo, tprmRepo: tprmRepo}
+}
+
+// QuestionnaireDetail is a questionnaire with its versions, newest first
+type QuestionnaireDetail struct {
+	models.VendorQuestionnaire
+	Versions []models.VendorQuestionnaireVersion `json:"versions"`
+}
+
+// AnswerInput is one submitted answer
+type AnswerInput struct {
+	QuestionID string     `json:"questionId"`
+	Value      string     `json:"value"`
+	EvidenceID *uuid.UUID `json:"evidenceId,omitempty"`
+	Comments   string     `json:"comments,omitempty"`
+}
+
+// AssessmentQuestionnaire is what an assessor or vendor sees: the pinned
+// definition, the answers so far, which questions are currently asked and
+// the running score
+type AssessmentQuestionnaire struct {
+	Assessment      *models.TPRMAssessment             `json:"assessment"`
+	QuestionnaireID uuid.UUID                          `json:"questionnaireId"`
+	Version         int                                `json:"version"`
+	Definition      questionnaire.Definition           `json:"definition"`
+	Answers         []models.VendorQuestionnaireAnswer `json:"answers"`
+	Visible         []string                           `json:"visible"`
+	Result          *questionnaire.Result              `json:"result"`
+}
+
+func encodeDefinition(def *questionnaire.Definition) ([]byte, error) {
+	def.Normalize()
+	return json.Marshal(def)
+}
+
+func decodeDefinition(v *models.VendorQuestionnaireVersion) (*questionnaire.Definition, error) {
+	var def questionnaire.Definition
+	if err := json.Unmarshal(v.Definition, &def); err != nil {
+		return nil, fmt.Errorf("failed to read questionnaire version %d: %w", v.Version, err)
+	}
+	def.Normalize()
+	return &def, nil
+}
+
+// CreateQuestionnaire starts a questionnaire with a draft first version
+func (s *VendorQuestionnaireService) CreateQuestionnaire(tenantID, createdBy uuid.UUID, name, description string, def questionnaire.Definition) (*QuestionnaireDetail, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidQuestionnaire)
+	}
+	raw, err := encodeDefinition(&def)
+	if err != nil {
+		return nil, err
+	}
+	q := &models.VendorQuestionnaire{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		Name:          name,
+		Description:   strings.TrimSpace(description),
+		LatestVersion: 1,
+		CreatedBy:     createdBy,
+	}
+	v := &models.VendorQuestionnaireVersion{
+		ID:              uuid.New(),
+		QuestionnaireID: q.ID,
+		TenantID:        tenantID,
+		Version:         1,
+		Status:          models.QuestionnaireVersionDraft,
+		Definition:      raw,
+	}
+	if err := s.repo.Create(q, v); err != nil {
+		return nil, err
+	}
+	return &QuestionnaireDetail{VendorQuestionnaire: *q, Versions: []models.VendorQuestionnaireVersion{*v}}, nil
+}
+
+func (s *VendorQuestionnaireService) ListQuestionnaires(tenantID uuid.UUID) ([]models.VendorQuestionnaire, error) {
+	return s.repo.ListByTenant(tenantID)
+}
+
+func (s *VendorQuestionnaireService) questionnaireForTenant(tenantID, id uuid.UUID) (*models.VendorQuestionnaire, error) {
+	q, err := s.repo.GetByID(tenantID, id)
+	if err != nil {
+		return nil, ErrQuestionnaireNotFound
+	}
+	return q, nil
+}
+
+func (s *VendorQuestionnaireService) GetQuestionnaire(tenantID, id uuid.UUID) (*QuestionnaireDetail, error) {
+	q, err := s.questionnaireForTenant(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.ListVersions(q.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestionnaireDetail{VendorQuestionnaire: *q, Versions: versions}, nil
+}
+
+func (s *VendorQuestionnaireService) GetVersion(tenantID, id uuid.UUID, version int) (*models.VendorQuestionnaireVersion, error) {
+	q, err := s.questionnaireForTenant(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.repo.GetVersion(q.ID, version)
+	if err != nil {
+		return nil, ErrQuestionnaireVersionNotFound
+	}
+	return v, nil
+}
+
+// SaveDraft replaces the content of the draft version, opening a new draft
+// after the latest version if that one is already published
+func (s *VendorQuestionnaireService) SaveDraft(tenantID, id uuid.UUID, def questionnaire.Definition, changelog string) (*models.VendorQuestionnaireVersion, error) {
+	q, err := s.questionnaireForTenant(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := encodeDefinition(&def)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.repo.GetVersion(q.ID, q.LatestVersion)
+	if err != nil {
+		return nil, ErrQuestionnaireVersionNotFound
+	}
+	if latest.Status == models.QuestionnaireVersionDraft {
+		latest.Definition = raw
+		latest.Changelog = strings.TrimSpace(changelog)
+		return latest, s.repo.UpdateVersion(latest)
+	}
+	v := &models.VendorQuestionnaireVersion{
+		ID:              uuid.New(),
+		QuestionnaireID: q.ID,
+		TenantID:        tenantID,
+		Version:         q.LatestVersion + 1,
+		Status:          models.QuestionnaireVersionDraft,
+		Definition:      raw,
+		Changelog:       strings.TrimSpace(changelog),
+	}
+	return v, s.repo.AddVersion(q, v)
+}
+
+// Publish validates a draft version and makes it the one new assessments
+// are started on
+func (s *VendorQuestionnaireService) Publish(tenantID, id uuid.UUID, version int, publishedBy uuid.UUID) (*models.VendorQuestionnaireVersion, error) {
+	q, err := s.questionnaireForTenant(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.repo.GetVersion(q.ID, version)
+	if err != nil {
+		return nil, ErrQuestionnaireVersionNotFound
+	}
+	if v.Status != models.QuestionnaireVersionDraft {
+		return nil, ErrQuestionnaireVersionLocked
+	}
+	def, err := decodeDefinition(v)
+	if err != nil {
+		return nil, err
+	}
+	if issues := def.Validate(); len(issues) > 0 {
+		return nil, &QuestionnaireInvalidError{Issues: issues}
+	}
+
+	now := time.Now()
+	v.Status = models.QuestionnaireVersionPublished
+	v.PublishedAt = &now
+	v.PublishedBy = &publishedBy
+	if err := s.repo.Publish(q, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ImportChecklist turns a built-in checklist, such as the DPDPA one, into a
+// published questionnaire the tenant can then revise. Each question becomes a
+// required yes/no question mapped to its DPDPA section.
+func (s *VendorQuestionnaireService) ImportChecklist(tenantID, createdBy uuid.UUID, checklist models.AuditChecklist) (*QuestionnaireDetail, error) {
+	def := questionnaire.Definition{}
+	for i, cat := range checklist.Categories {
+		sec := questionnaire.Section{ID: fmt.Sprintf("section-%d", i+1), Title: cat.Name}
+		for _, q := range cat.Questions {
+			sec.Questions = append(sec.Questions, questionnaire.Question{
+				ID:       q.ID,
+				Text:     q.Text,
+				Type:     questionnaire.TypeYesNo,
+				Required: true,
+				Weight:   float64(q.RiskWeight),
+				Controls: []questionnaire.ControlMapping{{Framework: questionnaire.FrameworkDPDPA, Control: q.ReferenceSection}},
+			})
+		}
+		def.Sections = append(def.Sections, sec)
+	}
+	detail, err := s.CreateQuestionnaire(tenantID, createdBy, checklist.Name, checklist.Description, def)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Publish(tenantID, detail.ID, 1, createdBy); err != nil {
+		return nil, err
+	}
+	return s.GetQuestionnaire(tenantID, detail.ID)
+}
+
+// Assessments
+
+// StartAssessment opens an assessment of a vendor on the questionnaire's
+// published version. With reuse set, the vendor's answers from the past year
+// are carried over where the question is unchanged.
+func (s *VendorQuestionnaireService) StartAssessment(tenantID, vendorID, questionnaireID uuid.UUID, title string, dueDate *time.Time, assessorID *uuid.UUID, reuse bool, startedBy uuid.UUID) (*AssessmentQuestionnaire, error) {
+	q, err := s.questionnaireForTenant(tenantID, questionnaireID)
+	if err != nil {
+		return nil, err
+	}
+	if q.PublishedVersion == 0 {
+		return nil, ErrQuestionnaireNotPublished
+	}
+	v, err := s.repo.GetVersion(q.ID, q.PublishedVersion)
+	if err != nil {
+		return nil, ErrQuestionnaireVersionNotFound
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = q.Name
+	}
+	a := &models.TPRMAssessment{
+		ID:                     uuid.New(),
+		TenantID:               tenantID,
+		VendorID:               vendorID,
+		Title:                  title,
+		Framework:              "QUESTIONNAIRE",
+		Status:                 "pending",
+		DueDate:                dueDate,
+		AssessorID:             assessorID,
+		QuestionnaireVersionID: &v.ID,
+	}
+	if err := s.tprmRepo.CreateAssessment(a); err != nil {
+		return nil, err
+	}
+	if reuse {
+		if _, err := s.ReuseAnswers(tenantID, a.ID, startedBy, answerReuseWindow); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetAssessmentQuestionnaire(tenantID, a.ID)
+}
+
+// pinned loads a questionnaire assessment and the definition it is pinned to
+func (s *VendorQuestionnaireService) pinned(tenantID, assessmentID uuid.UUID) (*models.TPRMAssessment, *models.VendorQuestionnaireVersion, *questionnaire.Definition, error) {
+	a, err := s.tprmRepo.GetAssessmentByID(assessmentID)
+	if err != nil || a.TenantID != tenantID {
+		return nil, nil, nil, ErrAssessmentNotFound
+	}
+	if a.QuestionnaireVersionID == nil {
+		return nil, nil, nil, ErrAssessmentNoQuestionnaire
+	}
+	v, err := s.repo.GetVersionByID(tenantID, *a.QuestionnaireVersionID)
+	if err != nil {
+		return nil, nil, nil, ErrQuestionnaireVersionNotFound
+	}
+	def, err := decodeDefinition(v)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return a, v, def, nil
+}
+
+func answerMap(answers []models.VendorQuestionnaireAnswer) map[string]questionnaire.Answer {
+	m := make(map[string]questionnaire.Answer, len(answers))
+	for _, a := range answers {
+		m[a.QuestionID] = questionnaire.Answer{Value: a.Value, HasFile: a.EvidenceID != nil}
+	}
+	return m
+}
+
+func (s *VendorQuestionnaireService) GetAssessmentQuestionnaire(tenantID, assessmentID uuid.UUID) (*AssessmentQuestionnaire, error) {
+	a, v, def, err := s.pinned(tenantID, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	answers, err := s.repo.ListAnswers(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	m := answerMap(answers)
+	view := &AssessmentQuestionnaire{
+		Assessment:      a,
+		QuestionnaireID: v.QuestionnaireID,
+		Version:         v.Version,
+		Definition:      *def,
+		Answers:         answers,
+		Visible:         []string{},
+		Result:          def.Score(m),
+	}
+	visible := def.Visible(m)
+	for _, q := range def.Questions() {
+		if visible[q.ID] {
+			view.Visible = append(view.Visible, q.ID)
+		}
+	}
+	return view, nil
+}
+
+// SubmitAnswers records answers against the pinned version and rescores the
+// assessment. Nothing is saved if any answer is rejected.
+func (s *VendorQuestionnaireService) SubmitAnswers(tenantID, assessmentID, answeredBy uuid.UUID, inputs []AnswerInput) (*questionnaire.Result, error) {
+	a, v, def, err := s.pinned(tenantID, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == "completed" {
+		return nil, ErrAssessmentCompleted
+	}
+
+	issues := map[string]string{}
+	now := time.Now()
+	rows := make([]models.VendorQuestionnaireAnswer, 0, len(inputs))
+	for _, in := range inputs {
+		q, ok := def.Question(in.QuestionID)
+		if !ok {
+			issues[in.QuestionID] = "no such question in this questionnaire version"
+			continue
+		}
+		if in.EvidenceID != nil {
+			e, err := s.tprmRepo.GetEvidenceByID(*in.EvidenceID)
+			if err != nil || e.TenantID != tenantID || e.VendorID != a.VendorID {
+				issues[q.ID] = "evidence not found for this vendor"
+				continue
+			}
+		}
+		if msg := q.CheckAnswer(questionnaire.Answer{Value: in.Value, HasFile: in.EvidenceID != nil}); msg != "" {
+			issues[q.ID] = msg
+			continue
+		}
+		rows = append(rows, models.VendorQuestionnaireAnswer{
+			ID:                     uuid.New(),
+			TenantID:               tenantID,
+			VendorID:               a.VendorID,
+			AssessmentID:           a.ID,
+			QuestionnaireVersionID: v.ID,
+			QuestionID:             q.ID,
+			QuestionType:           string(q.Type),
+			Value:                  strings.TrimSpace(in.Value),
+			EvidenceID:             in.EvidenceID,
+			Comments:               strings.TrimSpace(in.Comments),
+			AnsweredBy:             &answeredBy,
+			AnsweredAt:             now,
+		})
+	}
+	if len(issues) > 0 {
+		return nil, &AnswersRejectedError{Issues: issues}
+	}
+	for i := range rows {
+		if err := s.repo.SaveAnswer(&rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s.rescore(a, def)
+}
+
+// ReuseAnswers fills the assessment's unanswered questions with the vendor's
+// most recent answers from other assessments, given within maxAge. An answer
+// is only carried over if the question still has the same type and the
+// answer is still valid for it. It returns how many answers were reused.
+func (s *VendorQuestionnaireService) ReuseAnswers(tenantID, assessmentID, reusedBy uuid.UUID, maxAge time.Duration) (int, error) {
+	a, v, def, err := s.pinned(tenantID, assessmentID)
+	if err != nil {
+		return 0, err
+	}
+	if a.Status == "completed" {
+		return 0, ErrAssessmentCompleted
+	}
+	existing, err := s.repo.ListAnswers(a.ID)
+	if err != nil {
+		return 0, err
+	}
+	answered := map[string]bool{}
+	for _, e := range existing {
+		answered[e.QuestionID] = true
+	}
+	earlier, err := s.repo.ListVendorAnswers(tenantID, a.VendorID, a.ID, time.Now().Add(-maxAge))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	reused := 0
+	for _, prev := range earlier {
+		if answered[prev.QuestionID] {
+			continue
+		}
+		q, ok := def.Question(prev.QuestionID)
+		if !ok || string(q.Type) != prev.QuestionType {
+			continue
+		}
+		if q.CheckAnswer(questionnaire.Answer{Value: prev.Value, HasFile: prev.EvidenceID != nil}) != "" {
+			continue
+		}
+		// Listed newest first, so the first match per question wins
+		answered[prev.QuestionID] = true
+		source := prev.ID
+		if prev.ReusedFromID != nil {
+			source = *prev.ReusedFromID
+		}
+		row := &models.VendorQuestionnaireAnswer{
+			ID:                     uuid.New(),
+			TenantID:               tenantID,
+			VendorID:               a.VendorID,
+			AssessmentID:           a.ID,
+			QuestionnaireVersionID: v.ID,
+			QuestionID:             prev.QuestionID,
+			QuestionType:           prev.QuestionType,
+			Value:                  prev.Value,
+			EvidenceID:             prev.EvidenceID,
+			Comments:               prev.Comments,
+			ReusedFromID:           &source,
+			AnsweredBy:             &reusedBy,
+			AnsweredAt:             now,
+		}
+		if err := s.repo.SaveAnswer(row); err != nil {
+			return reused, err
+		}
+		reused++
+	}
+	if reused > 0 {
+		if _, err := s.rescore(a, def); err != nil {
+			return reused, err
+		}
+	}
+	return reused, nil
+}
+
+// rescore scores the assessment's answers and stores the result on it
+func (s *VendorQuestionnaireService) rescore(a *models.TPRMAssessment, def *questionnaire.Definition) (*questionnaire.Result, error) {
+	answers, err := s.repo.ListAnswers(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := def.Score(answerMap(answers))
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	a.RiskScore = result.RiskScore
+	a.ScoreResult = raw
+	if a.Status == "pending" {
+		a.Status = "in_progress"
+	}
+	if err := s.tprmRepo.UpdateAssessment(a); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CompleteAssessment closes the assessment once every required question that
+// is asked has an answer, and records the vendor's resulting risk
+func (s *VendorQuestionnaireService) CompleteAssessment(tenantID, assessmentID uuid.UUID) (*questionnaire.Result, error) {
+	a, _, def, err := s.pinned(tenantID, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status == "completed" {
+		return nil, ErrAssessmentCompleted
+	}
+	answers, err := s.repo.ListAnswers(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	if missing := def.Score(answerMap(answers)).MissingRequired; len(missing) > 0 {
+		return nil, &QuestionnaireIncompleteError{Missing: missing}
+	}
+
+	now := time.Now()
+	a.Status = "completed"
+	a.CompletedAt = &now
+	result, err := s.rescore(a, def)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tprmRepo.UpdateVendorRisk(a.VendorID, result.RiskScore, riskLevel(result.RiskScore)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
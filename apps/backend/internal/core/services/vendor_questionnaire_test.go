@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"pixpivot/arc/internal/models"
+	"pixpivot/arc/internal/questionnaire"
+	"pixpivot/arc/internal/storage/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupVendorQuestionnaire(t *testing.T) (*VendorQuestionnaireService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Vendor{}, &models.TPRMAssessment{}, &models.TPRMEvidence{},
+		&models.VendorQuestionnaire{}, &models.VendorQuestionnaireVersion{}, &models.VendorQuestionnaireAnswer{},
+	))
+	return NewVendorQuestionnaireService(repository.NewVendorQuestionnaireRepository(db), repository.NewTPRMRepository(db)), db
+}
+
+func securityQuestionnaire() questionnaire.Definition {
+	return questionnaire.Definition{
+		Scoring: questionnaire.Scoring{PassMark: 70},
+		Sections: []questionnaire.Section{{
+			ID:    "access",
+			Title: "Access control",
+			Questions: []questionnaire.Question{
+				{
+					ID: "mfa", Text: "Is MFA enforced for staff?", Type: questionnaire.TypeYesNo, Required: true, Weight: 2,
+					Controls: []questionnaire.ControlMapping{{Framework: questionnaire.FrameworkISO27001, Control: "A.8.5"}},
+				},
+				{
+					ID: "mfa_gap", Text: "Which systems lack MFA?", Type: questionnaire.TypeText, Required: true,
+					ShowIf: &questionnaire.Condition{QuestionID: "mfa", Operator: questionnaire.OpEquals, Value: "no"},
+				},
+			},
+		}, {
+			ID:    "ops",
+			Title: "Operations",
+			Questions: []questionnaire.Question{
+				{
+					ID: "patching", Text: "How quickly are critical patches applied?", Type: questionnaire.TypeChoice, Weight: 1,
+					Options:  []questionnaire.Option{{Value: "7d", Score: 1}, {Value: "30d", Score: 0.5}, {Value: "never", Score: 0}},
+					Controls: []questionnaire.ControlMapping{{Framework: questionnaire.FrameworkSOC2, Control: "CC7.1"}},
+				},
+			},
+		}},
+	}
+}
+
+func TestVendorQuestionnaire_PublishRejectsInvalidDraft(t *testing.T) {
+	svc, _ := setupVendorQuestionnaire(t)
+	tenantID, userID := uuid.New(), uuid.New()
+
+	def := securityQuestionnaire()
+	def.Sections[0].Questions[1].ShowIf.QuestionID = "patching" // Not asked before the follow-up
+	q, err := svc.CreateQuestionnaire(tenantID, userID, "Security", "", def)
+	require.NoError(t, err)
+
+	_, err = svc.Publish(tenantID, q.ID, 1, userID)
+	var invalid *QuestionnaireInvalidError
+	require.True(t, errors.As(err, &invalid))
+	assert.NotEmpty(t, invalid.Issues)
+
+	_, err = svc.StartAssessment(tenantID, uuid.New(), q.ID, "", nil, nil, false, userID)
+	assert.ErrorIs(t, err, ErrQuestionnaireNotPublished)
+
+	// Other tenants cannot see the questionnaire
+	_, err = svc.GetQuestionnaire(uuid.New(), q.ID)
+	assert.ErrorIs(t, err, ErrQuestionnaireNotFound)
+}
+
+func TestVendorQuestionnaire_AssessmentPinsVersionAndScores(t *testing.T) {
+	svc, db := setupVendorQuestionnaire(t)
+	tenantID, userID := uuid.New(), uuid.New()
+	vendor := models.Vendor{VendorID: uuid.New(), Company: "Acme", Email: "acme@example.com"}
+	require.NoError(t, db.Create(&vendor).Error)
+
+	q, err := svc.CreateQuestionnaire(tenantID, userID, "Security", "", securityQuestionnaire())
+	require.NoError(t, err)
+	_, err = svc.Publish(tenantID, q.ID, 1, userID)
+	require.NoError(t, err)
+
+	view, err := svc.StartAssessment(tenantID, vendor.VendorID, q.ID, "", nil, nil, false, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, view.Version)
+	assert.Equal(t, []string{"mfa", "patching"}, view.Visible)
+	assessmentID := view.Assessment.ID
+
+	// A published version cannot be edited; saving opens version 2
+	_, err = svc.Publish(tenantID, q.ID, 1, userID)
+	assert.ErrorIs(t, err, ErrQuestionnaireVersionLocked)
+	def := securityQuestionnaire()
+	def.Sections[1].Questions[0].Weight = 10
+	v2, err := svc.SaveDraft(tenantID, q.ID, def, "heavier patching")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+	_, err = svc.Publish(tenantID, q.ID, 2, userID)
+	require.NoError(t, err)
+
+	// Answers are checked against the pinned version
+	_, err = svc.SubmitAnswers(tenantID, assessmentID, userID, []AnswerInput{{QuestionID: "mfa", Value: "maybe"}})
+	var rejected *AnswersRejectedError
+	require.True(t, errors.As(err, &rejected))
+	assert.Contains(t, rejected.Issues, "mfa")
+
+	result, err := svc.SubmitAnswers(tenantID, assessmentID, userID, []AnswerInput{
+		{QuestionID: "mfa", Value: "no"},
+		{QuestionID: "patching", Value: "30d"},
+	})
+	require.NoError(t, err)
+	// Version 1 weights: mfa 2 * 0 + patching 1 * 0.5 out of 3
+	assert.InDelta(t, 16.67, result.Percent, 0.01)
+	assert.Equal(t, []string{"mfa_gap"}, result.MissingRequired)
+	require.NotNil(t, result.Passed)
+	assert.False(t, *result.Passed)
+	require.Len(t, result.Controls, 2)
+	assert.Equal(t, []string{"mfa"}, result.Controls[0].Gaps)
+
+	// The "no" answer opened a required follow-up
+	_, err = svc.CompleteAssessment(tenantID, assessmentID)
+	var incomplete *QuestionnaireIncompleteError
+	require.True(t, errors.As(err, &incomplete))
+	assert.Equal(t, []string{"mfa_gap"}, incomplete.Missing)
+
+	_, err = svc.SubmitAnswers(tenantID, assessmentID, userID, []AnswerInput{
+		{QuestionID: "mfa", Value: "yes"},
+		{QuestionID: "patching", Value: "7d"},
+	})
+	require.NoError(t, err)
+	result, err = svc.CompleteAssessment(tenantID, assessmentID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, result.Percent)
+	assert.Equal(t, 0.0, result.RiskScore)
+
+	_, err = svc.SubmitAnswers(tenantID, assessmentID, userID, []AnswerInput{{QuestionID: "mfa", Value: "no"}})
+	assert.ErrorIs(t, err, ErrAssessmentCompleted)
+
+	var stored models.TPRMAssessment
+	require.NoError(t, db.First(&stored, "id = ?", assessmentID).Error)
+	assert.Equal(t, "completed", stored.Status)
+	assert.NotEmpty(t, stored.ScoreResult)
+}
+
+func TestVendorQuestionnaire_ReusesEarlierAnswers(t *testing.T) {
+	svc, _ := setupVendorQuestionnaire(t)
+	tenantID, userID, vendorID := uuid.New(), uuid.New(), uuid.New()
+
+	q, err := svc.CreateQuestionnaire(tenantID, userID, "Security", "", securityQuestionnaire())
+	require.NoError(t, err)
+	_, err = svc.Publish(tenantID, q.ID, 1, userID)
+	require.NoError(t, err)
+
+	first, err := svc.StartAssessment(tenantID, vendorID, q.ID, "2025 review", nil, nil, false, userID)
+	require.NoError(t, err)
+	_, err = svc.SubmitAnswers(tenantID, first.Assessment.ID, userID, []AnswerInput{
+		{QuestionID: "mfa", Value: "yes"},
+		{QuestionID: "patching", Value: "7d"},
+	})
+	require.NoError(t, err)
+
+	// The next version turns patching into a yes/no question, so that answer
+	// no longer fits and is not carried over
+	def := securityQuestionnaire()
+	def.Sections[1].Questions[0] = questionnaire.Question{ID: "patching", Text: "Are critical patches applied within 7 days?", Type: questionnaire.TypeYesNo, Weight: 1}
+	_, err = svc.SaveDraft(tenantID, q.ID, def, "")
+	require.NoError(t, err)
+	_, err = svc.Publish(tenantID, q.ID, 2, userID)
+	require.NoError(t, err)
+
+	second, err := svc.StartAssessment(tenantID, vendorID, q.ID, "2026 review", nil, nil, true, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+	require.Len(t, second.Answers, 1)
+	assert.Equal(t, "mfa", second.Answers[0].QuestionID)
+	require.NotNil(t, second.Answers[0].ReusedFromID)
+
+	// Nothing from other vendors is reused
+	other, err := svc.StartAssessment(tenantID, uuid.New(), q.ID, "", nil, nil, true, userID)
+	require.NoError(t, err)
+	assert.Empty(t, other.Answers)
+
+	n, err := svc.ReuseAnswers(tenantID, second.Assessment.ID, userID, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestVendorQuestionnaire_ScoringFormulas(t *testing.T) {
+	min, max := 0.0, 48.0
+	def := securityQuestionnaire()
+	def.Sections[1].Questions = append(def.Sections[1].Questions, questionnaire.Question{
+		ID: "rto", Text: "Recovery time objective", Type: questionnaire.TypeNumeric, Weight: 1,
+		Numeric: &questionnaire.Numeric{Min: &min, Max: &max, Unit: "hours"},
+	})
+	answers := map[string]questionnaire.Answer{
+		"mfa":      {Value: "yes"},
+		"patching": {Value: "30d"},
+	}
+
+	def.Normalize()
+	avg := def.Score(answers)
+	// rto is unanswered and scores zero: (2 + 0.5 + 0) / 4
+	assert.Equal(t, 62.5, avg.Percent)
+
+	def.Scoring.Unanswered = questionnaire.UnansweredSkip
+	assert.InDelta(t, 83.33, def.Score(answers).Percent, 0.01)
+
+	def.Scoring.Formula = questionnaire.FormulaLowestSection
+	lowest := def.Score(answers)
+	assert.Equal(t, 50.0, lowest.Percent)
+	assert.Equal(t, 50.0, lowest.RiskScore)
+
+	def.Scoring.Formula = questionnaire.FormulaWeightedSum
+	answers["rto"] = questionnaire.Answer{Value: "12"}
+	assert.Equal(t, 3.25, def.Score(answers).Score)
+}
@@ -24,9 +24,25 @@ func NewVendorService(repo repository.VendorRepository) VendorService {
 }
 
 func (s *vendorService) CreateVendor(vendor *models.Vendor) error {
+	if err := setVendorCountry(vendor, vendor.ProcessingCountry, vendor.ProcessingLocation); err != nil {
+		return err
+	}
 	return s.repo.Create(vendor)
 }
 
+// setVendorCountry records where the vendor processes data. A location that
+// names no recognised country is kept as text with no country; an explicit
+// country code must be valid.
+func setVendorCountry(vendor *models.Vendor, code, location string) error {
+	vendor.ProcessingLocation = location
+	c, err := ResolveCountry(code, location)
+	if err != nil && code != "" {
+		return err
+	}
+	vendor.ProcessingCountry = c.Code
+	return nil
+}
+
 func (s *vendorService) UpdateVendor(id uuid.UUID, data models.Vendor) (*models.Vendor, error) {
 	vendor, err := s.repo.GetByID(id)
 	if err != nil {
@@ -35,6 +51,11 @@ func (s *vendorService) UpdateVendor(id uuid.UUID, data models.Vendor) (*models.
 	vendor.Company = data.Company
 	vendor.Email = data.Email
 	vendor.Address = data.Address
+	if data.ProcessingLocation != "" || data.ProcessingCountry != "" {
+		if err := setVendorCountry(vendor, data.ProcessingCountry, data.ProcessingLocation); err != nil {
+			return nil, err
+		}
+	}
 	return vendor, s.repo.Update(vendor)
 }
 
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"pixpivot/arc/internal/models"
 	"crypto/hmac"
 	"crypto/sha256"
@@ -18,13 +19,17 @@ import (
 )
 
 type WebhookService struct {
-	DB *gorm.DB
+	DB   *gorm.DB
+	jobs *JobRunner
 }
 
 func NewWebhookService(db *gorm.DB) *WebhookService {
 	return &WebhookService{DB: db}
 }
 
+// webhookMaxAttempts bounds delivery of one event to one webhook
+const webhookMaxAttempts = 4
+
 // Event represents a webhook event payload.
 type Event struct {
 	ID        string      `json:"id"`
@@ -33,6 +38,33 @@ type Event struct {
 	Data      interface{} `json:"data"`
 }
 
+// webhookDelivery is the payload of a webhook.deliver job
+type webhookDelivery struct {
+	WebhookID uuid.UUID `json:"webhookId"`
+	EventType string    `json:"eventType"`
+	Body      []byte    `json:"body"` // Kept as bytes so the signed body is exactly what was dispatched
+}
+
+// RegisterJobs moves webhook delivery onto the job runner, whose retries
+// with backoff replace the in-process ones
+func (s *WebhookService) RegisterJobs(r *JobRunner) {
+	s.jobs = r
+	r.Register("webhook.deliver", func(ctx context.Context, job *models.Job) error {
+		var d webhookDelivery
+		if err := DecodeJobPayload(job, &d); err != nil {
+			return err
+		}
+		var webhook models.Webhook
+		if err := s.DB.First(&webhook, "id = ?", d.WebhookID).Error; err != nil {
+			return fmt.Errorf("failed to load webhook: %w", err)
+		}
+		if !webhook.IsActive {
+			return nil
+		}
+		return s.deliver(webhook, d.EventType, d.Body, job.ID, job.Attempts)
+	})
+}
+
 // Dispatch sends an event to all registered and active webhooks for a given tenant and event type.
 func (s *WebhookService) Dispatch(tenantID uuid.UUID, eventType string, data interface{}) {
 	var webhooks []models.Webhook
@@ -57,78 +89,81 @@ func (s *WebhookService) Dispatch(tenantID uuid.UUID, eventType string, data int
 	}
 
 	for _, webhook := range webhooks {
-		go s.send(webhook, payloadBytes)
+		if s.jobs != nil {
+			_, err := s.jobs.Enqueue("webhook.deliver",
+				webhookDelivery{WebhookID: webhook.ID, EventType: eventType, Body: payloadBytes},
+				JobOptions{TenantID: &tenantID, MaxAttempts: webhookMaxAttempts})
+			if err == nil {
+				continue
+			}
+			log.Logger.Error().Err(err).Str("webhookId", webhook.ID.String()).Msg("Failed to queue webhook delivery")
+		}
+		go s.send(webhook, eventType, payloadBytes)
+	}
+}
+
+// send delivers to a single webhook in-process, retrying with backoff. It is
+// used when no job runner is wired or the queue is unavailable.
+func (s *WebhookService) send(webhook models.Webhook, eventType string, payload []byte) {
+	deliveryID := uuid.New()
+	baseDelay := 1 * time.Second
+	for i := 1; i <= webhookMaxAttempts; i++ {
+		if i > 1 {
+			time.Sleep(baseDelay * time.Duration(1<<uint(i-2))) // Exponential backoff: 1s, 2s, 4s
+		}
+		err := s.deliver(webhook, eventType, payload, deliveryID, i)
+		if err == nil {
+			return
+		}
+		log.Logger.Warn().
+			Str("webhookId", webhook.ID.String()).
+			Int("attempt", i).
+			Err(err).
+			Msg("Webhook delivery failed")
 	}
 }
 
-// send performs the HTTP POST request for a single webhook with retries.
-func (s *WebhookService) send(webhook models.Webhook, payload []byte) {
+// deliver makes one signed POST to the webhook and logs the attempt. The
+// delivery ID is the same on every attempt so receivers can de-duplicate.
+func (s *WebhookService) deliver(webhook models.Webhook, eventType string, payload []byte, deliveryID uuid.UUID, attempt int) error {
 	// Sign the payload
 	mac := hmac.New(sha256.New, []byte(webhook.Secret))
 	mac.Write(payload)
 	signature := hex.EncodeToString(mac.Sum(nil))
 
-	maxRetries := 3
-	baseDelay := 1 * time.Second
-	var resp *http.Response
-	var err error
-
-	// Log the event attempt
 	eventLog := models.WebhookEvent{
 		ID:          uuid.New(),
 		WebhookID:   webhook.ID,
-		EventType:   "unknown", // This could be improved by passing eventType to send()
+		EventType:   eventType,
 		Payload:     payload,
 		AttemptedAt: time.Now(),
 	}
 
-	for i := 0; i <= maxRetries; i++ {
-		if i > 0 {
-			time.Sleep(baseDelay * time.Duration(1<<uint(i-1))) // Exponential backoff: 1s, 2s, 4s
-		}
-
-		req, reqErr := http.NewRequest("POST", webhook.URL, bytes.NewBuffer(payload))
-		if reqErr != nil {
-			log.Logger.Error().Err(reqErr).Str("webhookId", webhook.ID.String()).Msg("Failed to create webhook request")
-			// Fatal error, do not retry
-			eventLog.Success = false
-			eventLog.Response = "Failed to create request: " + reqErr.Error()
-			s.DB.Create(&eventLog)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-Consent-Manager-Signature", signature)
-		req.Header.Set("X-Consent-Manager-Event-ID", eventLog.ID.String())
-		req.Header.Set("X-Consent-Manager-Delivery-Attempt", fmt.Sprintf("%d", i+1))
-
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err = client.Do(req)
-
-		if err == nil && resp.StatusCode < 300 {
-			// Success
-			eventLog.Success = true
-			eventLog.Response = resp.Status
-			s.DB.Create(&eventLog)
-			return
-		}
-
-		// Log warning for retry
-		log.Logger.Warn().
-			Str("webhookId", webhook.ID.String()).
-			Int("attempt", i+1).
-			Err(err).
-			Msg("Webhook delivery failed, retrying...")
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewBuffer(payload))
+	if err != nil {
+		eventLog.Response = "Failed to create request: " + err.Error()
+		s.DB.Create(&eventLog)
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Consent-Manager-Signature", signature)
+	req.Header.Set("X-Consent-Manager-Event-ID", deliveryID.String())
+	req.Header.Set("X-Consent-Manager-Delivery-Attempt", fmt.Sprintf("%d", attempt))
 
-	// All retries failed
-	eventLog.Success = false
-	eventLog.Response = "Failed after retries"
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
 	if err != nil {
-		eventLog.Response += ": " + err.Error()
-	} else if resp != nil {
-		eventLog.Response += ": " + resp.Status
+		eventLog.Response = err.Error()
+		s.DB.Create(&eventLog)
+		return err
 	}
+	defer resp.Body.Close()
+
+	eventLog.Success = resp.StatusCode < 300
+	eventLog.Response = resp.Status
 	s.DB.Create(&eventLog)
+	if !eventLog.Success {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
 }
-
@@ -59,6 +59,57 @@ func InitDB(cfg config.Config) {
 		&models.BreachEvidence{},
 		&models.BreachTimeline{},
 		&models.BreachNotificationTemplate{},
+		&models.BreachDPBReport{},
+		&models.BreachRecipientList{},
+		&models.BreachRecipient{},
+		&models.BreachNotificationCampaign{},
+		&models.BreachEvidenceCustodyEvent{},
+		&models.BreachSimulationMessage{},
+		&models.Job{},
+		&models.JobLease{},
+		&models.TPRMAssessment{},
+		&models.TPRMEvidence{},
+		&models.TPRMFinding{},
+		&models.DPATemplate{},
+		&models.DPATemplateVersion{},
+		&models.DPAClause{},
+		&models.DPAAgreement{},
+		&models.EncryptedDataProcessingAgreement{},
+		&models.DPAComplianceCheck{},
+		&models.DPASigningRequest{},
+		&models.DPASigner{},
+		&models.DPASigningEvent{},
+		&models.VendorPortalInvitation{},
+		&models.VendorFindingResponse{},
+		&models.VendorAssessmentReminder{},
+		&models.VendorSubProcessor{},
+		&models.VendorSubProcessorEvent{},
+		&models.RestrictedCountry{},
+		&models.PurposeSystem{},
+		&models.RopaProfile{},
+		&models.RopaVersion{},
+		&models.DPIATemplate{},
+		&models.DPIA{},
+		&models.DPIARisk{},
+		&models.DPIAMitigation{},
+		&models.DPIAEvent{},
+		&models.SDFObligation{},
+		&models.SDFObligationCompletion{},
+		&models.SDFEvidence{},
+		&models.DataProtectionOfficer{},
+		&models.VendorQuestionnaire{},
+		&models.VendorQuestionnaireVersion{},
+		&models.VendorQuestionnaireAnswer{},
+		&models.Nominee{},
+		&models.NomineeClaim{},
+		&models.NomineeClaimEvidence{},
+		&models.DSRFieldChange{},
+		&models.DSRRoutingRule{},
+		&models.DSREvent{},
+		&models.DSRClarification{},
+		&models.InboundEmail{},
+		&models.InboundEmailAttachment{},
+		&models.Attachment{},
 	); err != nil {
 		log.Fatalf("InitDB: public-schema migration failed on master: %v", err)
 	}
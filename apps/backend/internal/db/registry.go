@@ -81,6 +81,11 @@ func loadTenantDB(tenantID string) (*gorm.DB, error) {
 		&models.Purpose{},
 		&models.DataPrincipal{},
 		&models.Grievance{},
+		&models.GrievanceSLAPolicy{},
+		&models.GrievanceEscalation{},
+		&models.GrievanceTriageRule{},
+		&models.GrievanceTriageDecision{},
+		&models.GrievanceTriageOverride{},
 		&models.Notification{},
 		&models.AuditLog{},
 		&models.DSRRequest{},
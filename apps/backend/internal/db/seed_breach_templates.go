@@ -51,6 +51,16 @@ func SeedBreachNotificationTemplates(db *gorm.DB) {
 			IsActive:         true,
 			IsSystemTemplate: true,
 		},
+		{
+			TemplateName:     "certin_incident_report",
+			RecipientType:    "cert_in",
+			TemplateType:     "email",
+			Subject:          "Cyber Security Incident Report - {{certin_incident_type}} - {{company_name}}",
+			Body:             templates.CERTInIncidentReportTemplate,
+			Language:         "en",
+			IsActive:         true,
+			IsSystemTemplate: true,
+		},
 	}
 
 	for _, template := range templates {
@@ -0,0 +1,341 @@
+package dpa
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// FieldType is the type of a template merge field
+type FieldType string
+
+const (
+	FieldText   FieldType = "text"
+	FieldDate   FieldType = "date" // YYYY-MM-DD
+	FieldList   FieldType = "list"
+	FieldBool   FieldType = "bool"
+	FieldNumber FieldType = "number"
+)
+
+// Field is a merge field a DPA template can use. Templates refer to fields
+// by key: {{field "vendor_name"}}, {{range list "data_categories"}},
+// {{if flag "sub_processing_allowed"}}.
+type Field struct {
+	Key         string    `json:"key"`
+	Label       string    `json:"label"`
+	Type        FieldType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// StandardFields are available to every template. The vendor and fiduciary
+// fields are filled in from the vendor and tenant records unless overridden.
+var StandardFields = []Field{
+	{Key: "fiduciary_name", Label: "Data Fiduciary", Type: FieldText, Required: true},
+	{Key: "vendor_name", Label: "Data Processor", Type: FieldText, Required: true},
+	{Key: "vendor_email", Label: "Processor contact email", Type: FieldText},
+	{Key: "vendor_address", Label: "Processor address", Type: FieldText},
+	{Key: "processing_location", Label: "Processing location", Type: FieldText},
+	{Key: "effective_date", Label: "Effective date", Type: FieldDate, Required: true},
+	{Key: "expiry_date", Label: "Expiry date", Type: FieldDate},
+	{Key: "processing_purposes", Label: "Processing purposes", Type: FieldList, Required: true},
+	{Key: "data_categories", Label: "Categories of personal data", Type: FieldList, Required: true},
+	{Key: "sub_processing_allowed", Label: "Sub-processing allowed", Type: FieldBool},
+	{Key: "sub_processing_terms", Label: "Sub-processing terms", Type: FieldText},
+	{Key: "liability_cap", Label: "Liability cap", Type: FieldText},
+	{Key: "governing_law", Label: "Governing law", Type: FieldText},
+}
+
+// Clause is a reusable block of agreement text from the tenant's clause
+// library. Clause bodies may use merge fields but not other clauses.
+type Clause struct {
+	Key     string `json:"key"`
+	Version int    `json:"version"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+// ClauseRef records which version of a clause a document was built with
+type ClauseRef struct {
+	Key     string `json:"key"`
+	Version int    `json:"version"`
+}
+
+// ClauseLookup returns the clause to use for a key
+type ClauseLookup func(key string) (*Clause, bool)
+
+// Document is a rendered agreement
+type Document struct {
+	Text    string      `json:"text"`
+	Clauses []ClauseRef `json:"clauses"`
+}
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Fields returns the standard fields followed by a template's own fields
+func Fields(custom []Field) []Field {
+	return append(append([]Field{}, StandardFields...), custom...)
+}
+
+// ValidateFields checks a template's own field declarations
+func ValidateFields(custom []Field) []string {
+	var issues []string
+	seen := map[string]bool{}
+	for _, f := range StandardFields {
+		seen[f.Key] = true
+	}
+	for _, f := range custom {
+		switch {
+		case !fieldKeyPattern.MatchString(f.Key):
+			issues = append(issues, fmt.Sprintf("field key %q must be lower case letters, digits and underscores", f.Key))
+		case seen[f.Key]:
+			issues = append(issues, fmt.Sprintf("field %q is already defined", f.Key))
+		}
+		seen[f.Key] = true
+		switch f.Type {
+		case FieldText, FieldDate, FieldList, FieldBool, FieldNumber:
+		default:
+			issues = append(issues, fmt.Sprintf("field %q has unknown type %q", f.Key, f.Type))
+		}
+	}
+	return issues
+}
+
+// CheckValues converts submitted values to their field types. Values for
+// undeclared fields are rejected; missing required fields are reported.
+func CheckValues(fields []Field, values map[string]interface{}) (map[string]interface{}, []string) {
+	out := map[string]interface{}{}
+	var issues []string
+	byKey := map[string]Field{}
+	for _, f := range fields {
+		byKey[f.Key] = f
+	}
+	for k := range values {
+		if _, ok := byKey[k]; !ok {
+			issues = append(issues, fmt.Sprintf("%s is not a field of this template", k))
+		}
+	}
+	for _, f := range fields {
+		raw, ok := values[f.Key]
+		if !ok || raw == nil || raw == "" {
+			if f.Required {
+				issues = append(issues, fmt.Sprintf("%s is required", f.Key))
+			}
+			continue
+		}
+		v, err := convert(f.Type, raw)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("%s: %v", f.Key, err))
+			continue
+		}
+		if l, isList := v.([]string); isList && len(l) == 0 {
+			if f.Required {
+				issues = append(issues, fmt.Sprintf("%s is required", f.Key))
+			}
+			continue
+		}
+		out[f.Key] = v
+	}
+	return out, issues
+}
+
+func convert(t FieldType, raw interface{}) (interface{}, error) {
+	switch t {
+	case FieldText:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be text")
+		}
+		return strings.TrimSpace(s), nil
+	case FieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date")
+		}
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("must be a date as YYYY-MM-DD")
+		}
+		return d, nil
+	case FieldList:
+		switch l := raw.(type) {
+		case []string:
+			return l, nil
+		case []interface{}:
+			out := make([]string, 0, len(l))
+			for _, item := range l {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be a list of text")
+				}
+				if s = strings.TrimSpace(s); s != "" {
+					out = append(out, s)
+				}
+			}
+			return out, nil
+		}
+		return nil, fmt.Errorf("must be a list")
+	case FieldBool:
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	case FieldNumber:
+		switch n := raw.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("must be a number")
+	}
+	return nil, fmt.Errorf("unknown field type %q", t)
+}
+
+// format renders a field value as agreement text
+func format(v interface{}) string {
+	switch x := v.(type) {
+	case time.Time:
+		return x.Format("2 January 2006")
+	case []string:
+		switch len(x) {
+		case 0:
+			return ""
+		case 1:
+			return x[0]
+		}
+		return strings.Join(x[:len(x)-1], ", ") + " and " + x[len(x)-1]
+	case bool:
+		if x {
+			return "Yes"
+		}
+		return "No"
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	}
+	return ""
+}
+
+type renderer struct {
+	fields  map[string]Field
+	values  map[string]interface{}
+	clauses ClauseLookup
+	used    []ClauseRef
+	seen    map[string]bool
+}
+
+func (r *renderer) field(key string) (interface{}, error) {
+	if _, ok := r.fields[key]; !ok {
+		return nil, fmt.Errorf("unknown field %q", key)
+	}
+	return r.values[key], nil
+}
+
+func (r *renderer) funcs(inClause bool) template.FuncMap {
+	return template.FuncMap{
+		"field": func(key string) (string, error) {
+			v, err := r.field(key)
+			return format(v), err
+		},
+		"list": func(key string) ([]string, error) {
+			v, err := r.field(key)
+			l, _ := v.([]string)
+			return l, err
+		},
+		"flag": func(key string) (bool, error) {
+			v, err := r.field(key)
+			b, _ := v.(bool)
+			return b, err
+		},
+		"has": func(key string) (bool, error) {
+			v, err := r.field(key)
+			return v != nil, err
+		},
+		"clause": func(key string) (string, error) {
+			if inClause {
+				return "", fmt.Errorf("clause %q cannot be used inside another clause", key)
+			}
+			c, ok := r.clauses(key)
+			if !ok {
+				return "", fmt.Errorf("unknown clause %q", key)
+			}
+			if !r.seen[key] {
+				r.seen[key] = true
+				r.used = append(r.used, ClauseRef{Key: c.Key, Version: c.Version})
+			}
+			body, err := r.execute("clause:"+key, c.Body, true)
+			if err != nil {
+				return "", err
+			}
+			if c.Title != "" {
+				return "## " + c.Title + "\n\n" + body, nil
+			}
+			return body, nil
+		},
+	}
+}
+
+func (r *renderer) execute(name, body string, inClause bool) (string, error) {
+	t, err := template.New(name).Funcs(r.funcs(inClause)).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Render merges checked values and clauses into the template body
+func Render(body string, fields []Field, values map[string]interface{}, clauses ClauseLookup) (*Document, error) {
+	r := &renderer{fields: map[string]Field{}, values: values, clauses: clauses, used: []ClauseRef{}, seen: map[string]bool{}}
+	for _, f := range fields {
+		r.fields[f.Key] = f
+	}
+	text, err := r.execute("dpa", body, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Text: strings.TrimSpace(text), Clauses: r.used}, nil
+}
+
+// CheckTemplate dry-runs a template body with sample values for every field,
+// reporting syntax errors and references to unknown fields or clauses
+func CheckTemplate(body string, fields []Field, clauses ClauseLookup) []string {
+	if strings.TrimSpace(body) == "" {
+		return []string{"template body is empty"}
+	}
+	sample := map[string]interface{}{}
+	for _, f := range fields {
+		switch f.Type {
+		case FieldDate:
+			sample[f.Key] = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		case FieldList:
+			sample[f.Key] = []string{"sample"}
+		case FieldBool:
+			sample[f.Key] = true
+		case FieldNumber:
+			sample[f.Key] = 1.0
+		default:
+			sample[f.Key] = "sample"
+		}
+	}
+	if _, err := Render(body, fields, sample, clauses); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package dpia holds Data Protection Impact Assessment templates and the
+// likelihood × impact risk scale. Templates reuse the questionnaire engine:
+// answers are scored like a vendor questionnaire, and a poorly answered
+// question raises the risks tied to it.
+package dpia
+
+import (
+	"fmt"
+	"strings"
+
+	"pixpivot/arc/internal/questionnaire"
+)
+
+// Ratings run from 1 (rare, negligible) to 5 (almost certain, severe)
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Risk levels
+const (
+	LevelLow      = "low"
+	LevelMedium   = "medium"
+	LevelHigh     = "high"
+	LevelCritical = "critical"
+)
+
+// DefaultReviewMonths is how long an approved DPIA stands before it must be
+// reassessed, unless the template says otherwise
+const DefaultReviewMonths = 12
+
+// ValidRating reports whether r is on the 1 to 5 scale
+func ValidRating(r int) bool {
+	return r >= MinRating && r <= MaxRating
+}
+
+// Score is likelihood × impact, from 1 to 25
+func Score(likelihood, impact int) int {
+	return likelihood * impact
+}
+
+// Level buckets a likelihood × impact score
+func Level(score int) string {
+	switch {
+	case score >= 17:
+		return LevelCritical
+	case score >= 10:
+		return LevelHigh
+	case score >= 5:
+		return LevelMedium
+	default:
+		return LevelLow
+	}
+}
+
+// LevelRank orders levels so the highest can be picked; unknown levels rank 0
+func LevelRank(level string) int {
+	switch level {
+	case LevelLow:
+		return 1
+	case LevelMedium:
+		return 2
+	case LevelHigh:
+		return 3
+	case LevelCritical:
+		return 4
+	}
+	return 0
+}
+
+// RiskRule is a risk raised when its question earns less than half marks,
+// e.g. answering "no" to "Is the data encrypted at rest?"
+type RiskRule struct {
+	QuestionID  string `json:"questionId"`
+	Title       string `json:"title"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	Likelihood  int    `json:"likelihood"`
+	Impact      int    `json:"impact"`
+}
+
+// Template is the content of a DPIA template: the questions asked about the
+// processing and the risks that poor answers raise
+type Template struct {
+	Questionnaire questionnaire.Definition `json:"questionnaire"`
+	Risks         []RiskRule               `json:"risks"`
+	ReviewMonths  int                      `json:"reviewMonths,omitempty"` // Defaults to 12
+}
+
+// Normalize fills defaults in place
+func (t *Template) Normalize() {
+	t.Questionnaire.Normalize()
+	if t.ReviewMonths == 0 {
+		t.ReviewMonths = DefaultReviewMonths
+	}
+	for i := range t.Risks {
+		t.Risks[i].QuestionID = strings.TrimSpace(t.Risks[i].QuestionID)
+		t.Risks[i].Title = strings.TrimSpace(t.Risks[i].Title)
+	}
+}
+
+// Validate returns every problem with the template; none means it can be used
+func (t *Template) Validate() []string {
+	issues := t.Questionnaire.Validate()
+	add := func(format string, args ...interface{}) { issues = append(issues, fmt.Sprintf(format, args...)) }
+
+	if t.ReviewMonths < 1 || t.ReviewMonths > 60 {
+		add("review interval must be between 1 and 60 months")
+	}
+	for _, q := range t.Questionnaire.Questions() {
+		if q.Type == questionnaire.TypeFile {
+			add("question %q asks for a file, which DPIA templates do not support", q.ID)
+		}
+	}
+	for i, r := range t.Risks {
+		if r.Title == "" {
+			add("risk %d needs a title", i+1)
+		}
+		q, ok := t.Questionnaire.Question(r.QuestionID)
+		switch {
+		case !ok:
+			add("risk %q is tied to unknown question %q", r.Title, r.QuestionID)
+		case q.Weight == 0 || q.Type == questionnaire.TypeText:
+			add("risk %q is tied to question %q, which is not scored", r.Title, r.QuestionID)
+		}
+		if !ValidRating(r.Likelihood) || !ValidRating(r.Impact) {
+			add("risk %q needs a likelihood and impact from %d to %d", r.Title, MinRating, MaxRating)
+		}
+	}
+	return issues
+}
+
+// RaisedRisks returns the rules whose question was answered and earned less
+// than half marks. Unanswered questions raise nothing until they are answered.
+func (t *Template) RaisedRisks(answers map[string]questionnaire.Answer, result *questionnaire.Result) []RiskRule {
+	gaps := map[string]bool{}
+	for _, id := range result.Gaps {
+		if _, ok := answers[id]; ok {
+			gaps[id] = true
+		}
+	}
+	var out []RiskRule
+	for _, r := range t.Risks {
+		if gaps[r.QuestionID] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func yesNo(id, text string, weight float64, control string) questionnaire.Question {
+	q := questionnaire.Question{ID: id, Text: text, Type: questionnaire.TypeYesNo, Required: true, Weight: weight}
+	if control != "" {
+		q.Controls = []questionnaire.ControlMapping{{Framework: questionnaire.FrameworkDPDPA, Control: control}}
+	}
+	return q
+}
+
+// Default is the built-in template covering what the DPDP Act expects of a
+// Significant Data Fiduciary's periodic DPIA under Section 10(2)(c)
+func Default() Template {
+	t := Template{
+		Questionnaire: questionnaire.Definition{
+			Sections: []questionnaire.Section{
+				{
+					ID:    "processing",
+					Title: "Description of the processing",
+					Questions: []questionnaire.Question{
+						{ID: "nature", Text: "Describe the processing, its purposes and the personal data involved", Type: questionnaire.TypeText, Required: true},
+						{ID: "principals", Text: "Whose personal data is processed, and roughly how many Data Principals?", Type: questionnaire.TypeText, Required: true},
+						{ID: "children", Text: "Is personal data of children or persons with disabilities processed?", Type: questionnaire.TypeYesNo, Required: true, Weight: 1,
+							Options: []questionnaire.Option{{Value: "yes", Score: 0}, {Value: "no", Score: 1}}},
+						yesNo("parental_consent", "Is verifiable parental or guardian consent obtained?", 3, "9(1)"),
+						{ID: "volume", Text: "How much personal data is processed?", Type: questionnaire.TypeChoice, Required: true, Weight: 1,
+							Options: []questionnaire.Option{
+								{Value: "under_10k", Label: "Fewer than 10,000 Data Principals", Score: 1},
+								{Value: "under_1m", Label: "10,000 to 1 million", Score: 0.6},
+								{Value: "over_1m", Label: "More than 1 million", Score: 0.2},
+							}},
+					},
+				},
+				{
+					ID:    "necessity",
+					Title: "Necessity and proportionality",
+					Questions: []questionnaire.Question{
+						yesNo("minimised", "Is only the personal data necessary for the purpose collected?", 2, "6(1)"),
+						yesNo("retention", "Is a retention period set, with erasure once the purpose is served?", 2, "8(7)"),
+						yesNo("notice", "Are Data Principals given a notice describing this processing?", 2, "5"),
+						yesNo("rights", "Can Data Principals access, correct and erase their data and withdraw consent?", 2, "11-13"),
+					},
+				},
+				{
+					ID:    "safeguards",
+					Title: "Safeguards",
+					Questions: []questionnaire.Question{
+						yesNo("encryption", "Is the personal data encrypted in transit and at rest?", 3, "8(5)"),
+						yesNo("access", "Is access limited to the staff and systems that need it, and logged?", 3, "8(5)"),
+						yesNo("processors", "Is every Data Processor bound by a contract covering this processing?", 2, "8(2)"),
+						yesNo("transfers", "Does the personal data stay in India or in countries not restricted under Section 16?", 2, "16"),
+						yesNo("breach", "Is there a tested plan to detect and report a personal data breach?", 2, "8(6)"),
+						yesNo("automated", "Are decisions made about Data Principals reviewed by a person before they take effect?", 1, "10(2)(c)"),
+					},
+				},
+			},
+			Scoring: questionnaire.Scoring{Formula: questionnaire.FormulaWeightedAverage, Unanswered: questionnaire.UnansweredSkip},
+		},
+		Risks: []RiskRule{
+			{QuestionID: "children", Title: "Processing of children's or vulnerable persons' data", Category: "rights", Likelihood: 3, Impact: 4},
+			{QuestionID: "parental_consent", Title: "Children's data processed without verifiable parental consent", Category: "legal", Likelihood: 4, Impact: 5},
+			{QuestionID: "volume", Title: "Large-scale processing increases the impact of any breach", Category: "security", Likelihood: 2, Impact: 4},
+			{QuestionID: "minimised", Title: "Personal data collected beyond what the purpose needs", Category: "legal", Likelihood: 3, Impact: 3},
+			{QuestionID: "retention", Title: "Personal data kept after the purpose is served", Category: "legal", Likelihood: 4, Impact: 3},
+			{QuestionID: "notice", Title: "Data Principals not informed of the processing", Category: "rights", Likelihood: 4, Impact: 3},
+			{QuestionID: "rights", Title: "Data Principals unable to exercise their rights", Category: "rights", Likelihood: 3, Impact: 4},
+			{QuestionID: "encryption", Title: "Unencrypted personal data exposed in a breach", Category: "security", Likelihood: 3, Impact: 5},
+			{QuestionID: "access", Title: "Unauthorised access to personal data", Category: "security", Likelihood: 4, Impact: 4},
+			{QuestionID: "processors", Title: "Data Processor handles personal data without contractual safeguards", Category: "vendor", Likelihood: 3, Impact: 4},
+			{QuestionID: "transfers", Title: "Personal data transferred to a restricted country", Category: "legal", Likelihood: 2, Impact: 5},
+			{QuestionID: "breach", Title: "Breach detected or reported late", Category: "security", Likelihood: 3, Impact: 4},
+			{QuestionID: "automated", Title: "Automated decisions harm Data Principals without human review", Category: "rights", Likelihood: 2, Impact: 4},
+		},
+	}
+	// Parental consent is only asked when children's data is processed
+	t.Questionnaire.Sections[0].Questions[3].ShowIf = &questionnaire.Condition{QuestionID: "children", Operator: questionnaire.OpEquals, Value: "yes"}
+	t.Normalize()
+	return t
+}
@@ -83,6 +83,7 @@ type CreateGrievanceRequest struct {
 	GrievanceDescription string `json:"grievanceDescription"`
 	Category             string `json:"category,omitempty"`
 	Priority             string `json:"priority,omitempty"`
+	Source               string `json:"-"` // set server-side: portal or email
 }
 
 type UpdateGrievanceRequest struct {
@@ -467,3 +468,10 @@ type CookieScanResponse struct {
 	UpdatedAt    time.Time        `json:"updatedAt"`
 }
 
+
+type GrievanceTriageReviewRequest struct {
+	Category   string `json:"category,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	AssignedTo string `json:"assignedTo,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
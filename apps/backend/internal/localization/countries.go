@@ -0,0 +1,243 @@
+package localization
+
+import (
+	"sort"
+	"strings"
+)
+
+// Country is a jurisdiction data can be transferred to, identified by its
+// ISO 3166-1 alpha-2 code
+type Country struct {
+	Code   string `json:"code"`
+	Alpha3 string `json:"alpha3"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// Regions used to group countries
+const (
+	RegionSouthAsia    = "South Asia"
+	RegionEastAsia     = "East Asia"
+	RegionSoutheast    = "Southeast Asia"
+	RegionMiddleEast   = "Middle East"
+	RegionEurope       = "Europe"
+	RegionNorthAmerica = "North America"
+	RegionLatinAmerica = "Latin America"
+	RegionAfrica       = "Africa"
+	RegionOceania      = "Oceania"
+	RegionCentralAsia  = "Central Asia"
+)
+
+var countries = []Country{
+	{"AE", "ARE", "United Arab Emirates", RegionMiddleEast},
+	{"AR", "ARG", "Argentina", RegionLatinAmerica},
+	{"AT", "AUT", "Austria", RegionEurope},
+	{"AU", "AUS", "Australia", RegionOceania},
+	{"BD", "BGD", "Bangladesh", RegionSouthAsia},
+	{"BE", "BEL", "Belgium", RegionEurope},
+	{"BH", "BHR", "Bahrain", RegionMiddleEast},
+	{"BR", "BRA", "Brazil", RegionLatinAmerica},
+	{"BT", "BTN", "Bhutan", RegionSouthAsia},
+	{"BY", "BLR", "Belarus", RegionEurope},
+	{"CA", "CAN", "Canada", RegionNorthAmerica},
+	{"CH", "CHE", "Switzerland", RegionEurope},
+	{"CL", "CHL", "Chile", RegionLatinAmerica},
+	{"CN", "CHN", "China", RegionEastAsia},
+	{"CO", "COL", "Colombia", RegionLatinAmerica},
+	{"CZ", "CZE", "Czechia", RegionEurope},
+	{"DE", "DEU", "Germany", RegionEurope},
+	{"DK", "DNK", "Denmark", RegionEurope},
+	{"EG", "EGY", "Egypt", RegionAfrica},
+	{"ES", "ESP", "Spain", RegionEurope},
+	{"FI", "FIN", "Finland", RegionEurope},
+	{"FR", "FRA", "France", RegionEurope},
+	{"GB", "GBR", "United Kingdom", RegionEurope},
+	{"GR", "GRC", "Greece", RegionEurope},
+	{"HK", "HKG", "Hong Kong", RegionEastAsia},
+	{"HU", "HUN", "Hungary", RegionEurope},
+	{"ID", "IDN", "Indonesia", RegionSoutheast},
+	{"IE", "IRL", "Ireland", RegionEurope},
+	{"IL", "ISR", "Israel", RegionMiddleEast},
+	{"IN", "IND", "India", RegionSouthAsia},
+	{"IR", "IRN", "Iran", RegionMiddleEast},
+	{"IT", "ITA", "Italy", RegionEurope},
+	{"JP", "JPN", "Japan", RegionEastAsia},
+	{"KE", "KEN", "Kenya", RegionAfrica},
+	{"KP", "PRK", "North Korea", RegionEastAsia},
+	{"KR", "KOR", "South Korea", RegionEastAsia},
+	{"KW", "KWT", "Kuwait", RegionMiddleEast},
+	{"KZ", "KAZ", "Kazakhstan", RegionCentralAsia},
+	{"LK", "LKA", "Sri Lanka", RegionSouthAsia},
+	{"LU", "LUX", "Luxembourg", RegionEurope},
+	{"MV", "MDV", "Maldives", RegionSouthAsia},
+	{"MX", "MEX", "Mexico", RegionLatinAmerica},
+	{"MY", "MYS", "Malaysia", RegionSoutheast},
+	{"NG", "NGA", "Nigeria", RegionAfrica},
+	{"NL", "NLD", "Netherlands", RegionEurope},
+	{"NO", "NOR", "Norway", RegionEurope},
+	{"NP", "NPL", "Nepal", RegionSouthAsia},
+	{"NZ", "NZL", "New Zealand", RegionOceania},
+	{"OM", "OMN", "Oman", RegionMiddleEast},
+	{"PH", "PHL", "Philippines", RegionSoutheast},
+	{"PK", "PAK", "Pakistan", RegionSouthAsia},
+	{"PL", "POL", "Poland", RegionEurope},
+	{"PT", "PRT", "Portugal", RegionEurope},
+	{"QA", "QAT", "Qatar", RegionMiddleEast},
+	{"RO", "ROU", "Romania", RegionEurope},
+	{"RU", "RUS", "Russia", RegionEurope},
+	{"SA", "SAU", "Saudi Arabia", RegionMiddleEast},
+	{"SE", "SWE", "Sweden", RegionEurope},
+	{"SG", "SGP", "Singapore", RegionSoutheast},
+	{"TH", "THA", "Thailand", RegionSoutheast},
+	{"TR", "TUR", "Turkey", RegionMiddleEast},
+	{"TW", "TWN", "Taiwan", RegionEastAsia},
+	{"UA", "UKR", "Ukraine", RegionEurope},
+	{"US", "USA", "United States", RegionNorthAmerica},
+	{"VN", "VNM", "Vietnam", RegionSoutheast},
+	{"ZA", "ZAF", "South Africa", RegionAfrica},
+}
+
+// countryAliases lists other names and major data-centre cities for each
+// country, lower-cased
+var countryAliases = map[string][]string{
+	"AE": {"uae", "dubai", "abu dhabi"},
+	"AU": {"sydney", "melbourne"},
+	"BE": {"brussels"},
+	"BR": {"sao paulo", "são paulo"},
+	"CA": {"toronto", "montreal"},
+	"CH": {"zurich"},
+	"CN": {"prc", "people's republic of china", "beijing", "shanghai"},
+	"CZ": {"czech republic"},
+	"DE": {"frankfurt", "berlin"},
+	"ES": {"madrid"},
+	"FR": {"paris"},
+	"GB": {"uk", "great britain", "britain", "england", "london"},
+	"ID": {"jakarta"},
+	"IE": {"dublin"},
+	"IN": {"bharat", "mumbai", "bombay", "delhi", "new delhi", "bangalore", "bengaluru", "chennai", "hyderabad", "pune", "kolkata", "noida", "gurgaon", "gurugram"},
+	"IT": {"milan"},
+	"JP": {"tokyo", "osaka"},
+	"KP": {"dprk"},
+	"KR": {"korea", "republic of korea", "seoul"},
+	"MY": {"kuala lumpur"},
+	"NL": {"amsterdam", "holland", "the netherlands"},
+	"PH": {"manila"},
+	"PL": {"warsaw"},
+	"QA": {"doha"},
+	"RU": {"russian federation", "moscow"},
+	"SA": {"riyadh"},
+	"SE": {"stockholm"},
+	"TH": {"bangkok"},
+	"TR": {"türkiye", "turkiye", "istanbul"},
+	"US": {"usa", "u.s.", "u.s.a.", "united states of america", "america", "virginia", "california", "oregon", "ohio", "new york"},
+	"VN": {"viet nam"},
+	"ZA": {"johannesburg", "cape town"},
+}
+
+var (
+	countriesByCode  = map[string]Country{}
+	countriesByName  = map[string]Country{}
+	countriesByAlpha = map[string]Country{}
+)
+
+func init() {
+	for _, c := range countries {
+		countriesByCode[c.Code] = c
+		countriesByAlpha[c.Alpha3] = c
+		countriesByName[strings.ToLower(c.Name)] = c
+	}
+	for code, aliases := range countryAliases {
+		for _, a := range aliases {
+			countriesByName[a] = countriesByCode[code]
+		}
+	}
+}
+
+// Countries returns every known country, sorted by name
+func Countries() []Country {
+	list := make([]Country, len(countries))
+	copy(list, countries)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupCountry returns the country with the given ISO alpha-2 code
+func LookupCountry(code string) (Country, bool) {
+	c, ok := countriesByCode[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// NormalizeCountry resolves free text such as "IN", "IND", "India",
+// "Mumbai, India" or "AWS ap-south-1" to a country. Each comma, slash or
+// parenthesis separated part is tried, last first, since addresses usually
+// end with the country.
+func NormalizeCountry(text string) (Country, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Country{}, false
+	}
+	if c, ok := matchCountry(text); ok {
+		return c, true
+	}
+	parts := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '/' || r == '(' || r == ')' || r == ';' || r == '|'
+	})
+	for i := len(parts) - 1; i >= 0; i-- {
+		if c, ok := matchCountry(parts[i]); ok {
+			return c, true
+		}
+	}
+	for _, word := range strings.Fields(text) {
+		if c, ok := ClusterCountry(word); ok {
+			return c, true
+		}
+	}
+	return Country{}, false
+}
+
+func matchCountry(s string) (Country, bool) {
+	s = strings.TrimSpace(s)
+	switch len(s) {
+	case 2:
+		if c, ok := countriesByCode[strings.ToUpper(s)]; ok {
+			return c, true
+		}
+	case 3:
+		if c, ok := countriesByAlpha[strings.ToUpper(s)]; ok {
+			return c, true
+		}
+	}
+	lower := strings.ToLower(s)
+	if c, ok := countriesByName[lower]; ok {
+		return c, true
+	}
+	return ClusterCountry(lower)
+}
+
+// clusterCountries maps the names of our database clusters and the cloud
+// regions they run in to the country hosting them
+var clusterCountries = map[string]string{
+	"india-central": "IN", "india-south": "IN", "india-west": "IN",
+	"mumbai": "IN", "bangalore": "IN", "chennai": "IN", "hyderabad": "IN",
+	"ap-south-1": "IN", "ap-south-2": "IN", "centralindia": "IN", "southindia": "IN", "westindia": "IN",
+	"asia-south1": "IN", "asia-south2": "IN",
+	"ap-southeast-1": "SG", "southeastasia": "SG", "asia-southeast1": "SG",
+	"ap-southeast-2": "AU", "australiaeast": "AU",
+	"ap-northeast-1": "JP", "japaneast": "JP",
+	"eu-west-1": "IE", "northeurope": "IE", "europe-west1": "BE",
+	"eu-west-2": "GB", "uksouth": "GB", "europe-west2": "GB",
+	"eu-central-1": "DE", "germanywestcentral": "DE", "europe-west3": "DE",
+	"us-east-1": "US", "us-east-2": "US", "us-west-1": "US", "us-west-2": "US",
+	"eastus": "US", "westus": "US", "us-central1": "US",
+	"me-central-1": "AE", "uaenorth": "AE",
+}
+
+// ClusterCountry returns the country hosting a database cluster or cloud
+// region
+func ClusterCountry(cluster string) (Country, bool) {
+	code, ok := clusterCountries[strings.ToLower(strings.TrimSpace(cluster))]
+	if !ok {
+		return Country{}, false
+	}
+	return countriesByCode[code], true
+}
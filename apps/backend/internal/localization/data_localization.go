@@ -60,18 +60,8 @@ func (dls *DataLocalizationService) requiresIndianLocalization(tenant *models.Te
 
 // isIndianCluster checks if a database cluster is located in India
 func (dls *DataLocalizationService) isIndianCluster(cluster string) bool {
-	// Define which clusters are located in India
-	// In a real implementation, this would check actual cluster locations
-	indianClusters := []string{"india-central", "india-south", "india-west", "mumbai", "bangalore", "chennai"}
-	
-	for _, indianCluster := range indianClusters {
-		if cluster == indianCluster {
-			return true
-		}
-	}
-	
-	// Default to false for non-Indian clusters
-	return false
+	c, ok := ClusterCountry(cluster)
+	return ok && c.Code == "IN"
 }
 
 // GetCompliantClusterForTenant returns the appropriate cluster for a tenant based on data localization requirements
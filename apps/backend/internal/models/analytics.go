@@ -238,6 +238,16 @@ type RevenueAnalytics struct {
 	PaybackPeriod           float64            `json:"payback_period_months"`
 }
 
+// SDFAnalytics tracks the recurring obligations of a Significant Data Fiduciary
+type SDFAnalytics struct {
+	Designated         bool  `json:"designated"`
+	TotalObligations   int64 `json:"total_obligations"`
+	OverdueObligations int64 `json:"overdue_obligations"`
+	DueWithin30Days    int64 `json:"due_within_30_days"`
+	DPOAppointed       bool  `json:"dpo_appointed"`
+	DPOInIndia         bool  `json:"dpo_in_india"`
+}
+
 // AnalyticsDashboard represents the complete analytics dashboard
 type AnalyticsDashboard struct {
 	ID                      uuid.UUID                `json:"id"`
@@ -254,6 +264,7 @@ type AnalyticsDashboard struct {
 	ComplianceAnalytics     *ComplianceAnalytics     `json:"compliance_analytics"`
 	PerformanceAnalytics    *PerformanceAnalytics    `json:"performance_analytics"`
 	RevenueAnalytics        *RevenueAnalytics        `json:"revenue_analytics"`
+	SDFAnalytics            *SDFAnalytics            `json:"sdf_analytics,omitempty"`
 	
 	// Trends
 	ConsentTrend            []TrendPoint             `json:"consent_trend"`
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file uploaded as evidence on a grievance or DSR, or on one of
+// their comments. Grievances live in tenant databases, so attachments are kept
+// in the master database and point at their parent by ID.
+type Attachment struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Scope        string     `gorm:"type:varchar(20);index:idx_attachment_parent;not null" json:"scope"` // grievance, dsr
+	ParentID     uuid.UUID  `gorm:"type:uuid;index:idx_attachment_parent;not null" json:"parentId"`     // Grievance or DSR request
+	CommentID    *uuid.UUID `gorm:"type:uuid;index" json:"commentId,omitempty"`
+	IsInternal   bool       `gorm:"default:false" json:"isInternal"` // Follows internal DSR comments; hidden from the principal
+	FileName     string     `gorm:"type:text" json:"fileName"`
+	FilePath     string     `gorm:"type:text" json:"-"`
+	ContentType  string     `gorm:"type:varchar(100)" json:"contentType"`
+	SizeBytes    int64      `json:"sizeBytes"`
+	SHA256       string     `gorm:"type:varchar(64)" json:"sha256"`
+	ScanStatus   string     `gorm:"type:varchar(20);default:'not_scanned'" json:"scanStatus"` // not_scanned, clean
+	ScannedAt    *time.Time `json:"scannedAt,omitempty"`
+	UploadedBy   uuid.UUID  `gorm:"type:uuid" json:"uploadedBy"`
+	UploaderType string     `gorm:"type:varchar(20)" json:"uploaderType"` // principal, staff
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (Attachment) TableName() string {
+	return "attachments"
+}
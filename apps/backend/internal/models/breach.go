@@ -59,11 +59,20 @@ type BreachCommunication struct {
 	SendMethod        string    `gorm:"type:varchar(50)"` // email, sms, postal, portal
 	SentAt            *time.Time
 	DeliveredAt       *time.Time
-	Status            string    `gorm:"type:varchar(50)"` // draft, queued, sent, delivered, failed
+	Status            string    `gorm:"type:varchar(50)"` // draft, queued, sending, sent, delivered, bounced, failed, cancelled
 	ErrorMessage      *string   `gorm:"type:text"`
 	CreatedBy         uuid.UUID `gorm:"type:uuid"`
 	CreatedAt         time.Time `gorm:"autoCreateTime"`
 	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
+
+	// Campaign delivery tracking
+	CampaignID        *uuid.UUID `gorm:"type:uuid;index"`
+	DataPrincipalID   *uuid.UUID `gorm:"type:uuid;index"`
+	Attempts          int        `gorm:"default:0"`
+	NextAttemptAt     *time.Time `gorm:"index"`
+	BouncedAt         *time.Time
+	ProviderMessageID string `gorm:"type:varchar(255);index"` // Gateway ID or email Message-ID
+	ContentSHA256     string `gorm:"type:varchar(64)"`        // Hash of the rendered subject and body
 }
 
 // BreachWorkflowStage tracks the workflow progression with approval gates
@@ -95,15 +104,39 @@ type BreachEvidence struct {
 	EvidenceType   string    `gorm:"type:varchar(50)"` // logs, screenshots, forensic_report, email, document, video
 	Title          string    `gorm:"type:varchar(255)"`
 	Description    string    `gorm:"type:text"`
-	FilePath       string    `gorm:"type:text"`
+	FileName       string    `gorm:"type:varchar(255)"`
+	ContentType    string    `gorm:"type:varchar(100)"`
+	FilePath       string    `gorm:"type:text"` // Write-once storage location
 	FileSize       int64     `gorm:"default:0"`
-	FileHash       string    `gorm:"type:varchar(255)"` // For integrity verification
+	FileHash       string    `gorm:"type:varchar(255)"` // SHA-256 (hex) taken at upload
 	CollectedBy    uuid.UUID `gorm:"type:uuid"`
 	CollectedAt    time.Time
-	ChainOfCustody string    `gorm:"type:text"` // JSON array of custody transfers
-	IsConfidential bool      `gorm:"default:false"`
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	RetainUntil    *time.Time // Object lock on the stored file, when enabled
+	ChainOfCustody string     `gorm:"type:text"` // Legacy free-text transfers; see BreachEvidenceCustodyEvent
+	IsConfidential bool       `gorm:"default:false"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+}
+
+// BreachEvidenceCustodyEvent is one entry in an evidence item's custody log:
+// upload, each view and download, verification and export. Entries are
+// numbered per item and hash-chained, each EventHash covering the entry and
+// the previous hash, so a removed or edited entry breaks the chain.
+type BreachEvidenceCustodyEvent struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	EvidenceID   uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_custody_evidence_seq;not null" json:"evidenceId"`
+	Sequence     int        `gorm:"uniqueIndex:idx_custody_evidence_seq;not null" json:"sequence"`
+	BreachID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"breachId"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	Action       string     `gorm:"type:varchar(30)" json:"action"` // uploaded, listed, viewed, downloaded, verified, integrity_failed, exported
+	ActorID      *uuid.UUID `gorm:"type:uuid" json:"actorId,omitempty"`
+	IPAddress    string     `gorm:"type:varchar(64)" json:"ipAddress,omitempty"`
+	UserAgent    string     `gorm:"type:text" json:"userAgent,omitempty"`
+	SHA256       string     `gorm:"type:varchar(64)" json:"sha256,omitempty"` // Digest of the file as read, where the action read it
+	Note         string     `gorm:"type:text" json:"note,omitempty"`
+	PreviousHash string     `gorm:"type:varchar(64)" json:"previousHash"`
+	EventHash    string     `gorm:"type:varchar(64)" json:"eventHash"`
+	OccurredAt   time.Time  `gorm:"not null" json:"occurredAt"`
 }
 
 // BreachTimeline tracks all significant events in breach lifecycle
@@ -133,3 +166,101 @@ type BreachNotificationTemplate struct {
 	CreatedAt        time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt        time.Time  `gorm:"autoUpdateTime"`
 }
+
+// BreachDPBReport is a generated intimation to the Data Protection Board.
+// Payload holds the structured report; the PDF rendering is kept in blob
+// storage. Both are signed so the copy on file can be shown to be the one
+// submitted. Each regeneration adds a new version.
+type BreachDPBReport struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	BreachID         uuid.UUID      `gorm:"type:uuid;index;uniqueIndex:idx_dpb_report_version;not null" json:"breachId"`
+	TenantID         uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	Kind             string         `gorm:"type:varchar(20);uniqueIndex:idx_dpb_report_version;not null" json:"kind"` // initial, detailed
+	Version          int            `gorm:"uniqueIndex:idx_dpb_report_version;not null" json:"version"`
+	Payload          datatypes.JSON `gorm:"type:jsonb" json:"payload"`
+	Warnings         datatypes.JSON `gorm:"type:jsonb" json:"warnings"` // Non-blocking validation issues at generation
+	PayloadSHA256    string         `gorm:"type:varchar(64)" json:"payloadSha256"`
+	PayloadSignature string         `gorm:"type:text" json:"payloadSignature"`
+	PDFPath          string         `gorm:"type:text" json:"-"`
+	PDFSHA256        string         `gorm:"type:varchar(64)" json:"pdfSha256"`
+	PDFSignature     string         `gorm:"type:text" json:"pdfSignature"`
+	SigningKeyID     string         `gorm:"type:varchar(32)" json:"signingKeyId"`
+	GeneratedBy      uuid.UUID      `gorm:"type:uuid" json:"generatedBy"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// BreachRecipientList is a frozen set of Data Principals affected by a
+// breach, resolved from the breached systems, the exposure window and the
+// data categories. Lists are never edited; recomputing adds a new version.
+// The latest version drives the notification batch and the DPB report.
+type BreachRecipientList struct {
+	ID                    uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	BreachID              uuid.UUID      `gorm:"type:uuid;index;not null" json:"breachId"`
+	TenantID              uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	Version               int            `gorm:"not null" json:"version"`
+	Query                 datatypes.JSON `gorm:"type:jsonb" json:"query"`                 // breach.ImpactQuery as resolved
+	DataCategories        datatypes.JSON `gorm:"type:jsonb" json:"dataCategories"`        // Categories the list covers
+	UnconfirmedCategories datatypes.JSON `gorm:"type:jsonb" json:"unconfirmedCategories"` // Not found by discovery in the breached systems
+	CategoryCounts        datatypes.JSON `gorm:"type:jsonb" json:"categoryCounts"`        // {"contact": 1200, "financial": 300}
+	TotalRecipients       int            `gorm:"default:0" json:"totalRecipients"`
+	ContentSHA256         string         `gorm:"type:varchar(64)" json:"contentSha256"` // Over the sorted recipient rows
+	CreatedBy             uuid.UUID      `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt             time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// BreachRecipient is one affected Data Principal on a recipient list. Contact
+// details are looked up from the principal when notifying.
+type BreachRecipient struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ListID          uuid.UUID      `gorm:"type:uuid;index;not null" json:"listId"`
+	BreachID        uuid.UUID      `gorm:"type:uuid;index" json:"breachId"`
+	TenantID        uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	DataPrincipalID uuid.UUID      `gorm:"type:uuid;index" json:"dataPrincipalId"`
+	Categories      datatypes.JSON `gorm:"type:jsonb" json:"categories"`
+	PurposeIDs      datatypes.JSON `gorm:"type:jsonb" json:"purposeIds"` // Consented purposes that put the principal in scope
+}
+
+// BreachNotificationCampaign sends a breach notice to everyone on a recipient
+// list over one or more channels. Each message is a BreachCommunication that a
+// background runner sends at the channel's rate limit, retrying failures. The
+// counts are refreshed as messages are sent and delivery reports arrive.
+type BreachNotificationCampaign struct {
+	ID                   uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	BreachID             uuid.UUID      `gorm:"type:uuid;index;not null" json:"breachId"`
+	TenantID             uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	RecipientListID      uuid.UUID      `gorm:"type:uuid;index" json:"recipientListId"`
+	RecipientListVersion int            `json:"recipientListVersion"`
+	Channels             datatypes.JSON `gorm:"type:jsonb" json:"channels"`           // ["email", "sms", "in_app"]
+	Content              datatypes.JSON `gorm:"type:jsonb" json:"content"`            // Rendered subject/body per channel
+	Status               string         `gorm:"type:varchar(20);index" json:"status"` // queued, running, completed, cancelled
+	TotalMessages        int            `json:"totalMessages"`
+	Skipped              int            `json:"skipped"` // Recipient/channel pairs with no contact on record
+	Queued               int            `json:"queued"`
+	Sent                 int            `json:"sent"`
+	Delivered            int            `json:"delivered"`
+	Bounced              int            `json:"bounced"`
+	Failed               int            `json:"failed"`
+	Cancelled            int            `json:"cancelled"`
+	CreatedBy            uuid.UUID      `gorm:"type:uuid" json:"createdBy"`
+	IsSimulation         bool           `gorm:"default:false" json:"isSimulation"` // Messages go to the simulation sink
+	StartedAt            *time.Time     `json:"startedAt,omitempty"`
+	CompletedAt          *time.Time     `json:"completedAt,omitempty"`
+	CreatedAt            time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt            time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// BreachSimulationMessage is a notification captured by the simulation sink
+// in place of being sent: simulated breaches render every notice as usual but
+// deliver it here, so the exercise can be reviewed without anyone receiving it.
+type BreachSimulationMessage struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	BreachID        uuid.UUID  `gorm:"type:uuid;index;not null" json:"breachId"`
+	TenantID        uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	CommunicationID *uuid.UUID `gorm:"type:uuid" json:"communicationId,omitempty"` // The BreachCommunication it stands in for
+	RecipientType   string     `gorm:"type:varchar(50)" json:"recipientType"`      // dpb, data_principal
+	Channel         string     `gorm:"type:varchar(20)" json:"channel"`            // email, sms, in_app
+	Recipient       string     `gorm:"type:varchar(255)" json:"recipient"`         // Intended recipient; nothing was sent
+	Subject         string     `gorm:"type:varchar(500)" json:"subject,omitempty"`
+	Body            string     `gorm:"type:text" json:"body"`
+	CapturedAt      time.Time  `gorm:"not null" json:"capturedAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PurposeSystem declares that data collected for a purpose is processed in a
+// system, i.e. a discovery data source. Together with consent form data
+// objects, discovery findings and vendors it makes up the data flow map.
+type PurposeSystem struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_purpose_system;not null" json:"tenantId"`
+	PurposeID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_purpose_system;not null" json:"purposeId"`
+	DataSourceID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_purpose_system;not null" json:"dataSourceId"`
+	Note         string    `gorm:"type:text" json:"note,omitempty"`
+	DeclaredBy   uuid.UUID `gorm:"type:uuid" json:"declaredBy"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
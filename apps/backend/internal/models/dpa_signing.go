@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DPA signing parties
+const (
+	DPASignerTenant = "tenant"
+	DPASignerVendor = "vendor"
+)
+
+// DPASigningRequest is one signing ceremony for a DataProcessingAgreement.
+// Every signer reviews the same rendered document, identified by its hash;
+// once all have signed the platform seals a final PDF with a certificate of
+// completion and signs it with the document signing key.
+type DPASigningRequest struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	DPAID          uuid.UUID  `gorm:"type:uuid;index;not null" json:"dpaId"`
+	Status         string     `gorm:"type:varchar(20);not null" json:"status"` // pending, completed, cancelled
+	DocumentPath   string     `gorm:"type:text" json:"-"`
+	DocumentSHA256 string     `gorm:"type:varchar(64)" json:"documentSha256"`
+	SealedPath     string     `gorm:"type:text" json:"-"`
+	SealedSHA256   string     `gorm:"type:varchar(64)" json:"sealedSha256,omitempty"`
+	SealSignature  string     `gorm:"type:text" json:"sealSignature,omitempty"`
+	SigningKeyID   string     `gorm:"type:varchar(32)" json:"signingKeyId,omitempty"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expiresAt"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	CancelledAt    *time.Time `json:"cancelledAt,omitempty"`
+	CreatedBy      uuid.UUID  `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+
+	Signers []DPASigner `gorm:"foreignKey:RequestID" json:"signers,omitempty"`
+}
+
+// DPASigner is a signatory in a signing ceremony. Only hashes of the signing
+// link token and the one-time passcode are stored.
+type DPASigner struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	RequestID        uuid.UUID  `gorm:"type:uuid;index;not null" json:"requestId"`
+	TenantID         uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Party            string     `gorm:"type:varchar(10);not null" json:"party"` // DPASignerTenant or DPASignerVendor
+	Name             string     `gorm:"type:text;not null" json:"name"`
+	Title            string     `gorm:"type:text" json:"title,omitempty"`
+	Email            string     `gorm:"type:text;not null" json:"email"`
+	TokenHash        string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	OTPHash          string     `gorm:"type:varchar(64)" json:"-"`
+	OTPExpiresAt     *time.Time `json:"-"`
+	OTPSentAt        *time.Time `json:"-"`
+	OTPAttempts      int        `json:"-"`
+	OTPVerifiedAt    *time.Time `json:"otpVerifiedAt,omitempty"`
+	ViewedAt         *time.Time `json:"viewedAt,omitempty"`
+	SignedAt         *time.Time `json:"signedAt,omitempty"`
+	AdoptedSignature string     `gorm:"type:text" json:"adoptedSignature,omitempty"`
+	SignedIP         string     `gorm:"type:varchar(64)" json:"signedIp,omitempty"`
+	SignedUserAgent  string     `gorm:"type:text" json:"signedUserAgent,omitempty"`
+	SignedSHA256     string     `gorm:"type:varchar(64)" json:"signedSha256,omitempty"` // Document hash the signer confirmed
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// DPASigningEvent is an entry in a signing ceremony's audit trail
+type DPASigningEvent struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	RequestID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"requestId"`
+	SignerID       *uuid.UUID `gorm:"type:uuid;index" json:"signerId,omitempty"`
+	Event          string     `gorm:"type:varchar(30);not null" json:"event"` // created, link_sent, viewed, otp_sent, otp_failed, signed, sealed, cancelled
+	IP             string     `gorm:"type:varchar(64)" json:"ip,omitempty"`
+	UserAgent      string     `gorm:"type:text" json:"userAgent,omitempty"`
+	DocumentSHA256 string     `gorm:"type:varchar(64)" json:"documentSha256,omitempty"`
+	CreatedAt      time.Time  `gorm:"not null" json:"createdAt"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// DPIA statuses
+const (
+	DPIADraft            = "draft"
+	DPIASubmitted        = "submitted" // Awaiting the DPO's sign-off
+	DPIAApproved         = "approved"
+	DPIAChangesRequested = "changes_requested"
+	DPIAReviewDue        = "review_due" // The review date passed; it must be reassessed
+)
+
+// DPIA mitigation statuses
+const (
+	MitigationPlanned     = "planned"
+	MitigationInProgress  = "in_progress"
+	MitigationImplemented = "implemented"
+)
+
+// DPIATemplate is a tenant's own DPIA template; the built-in one is not stored
+type DPIATemplate struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name        string         `gorm:"type:varchar(255);not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Content     datatypes.JSON `gorm:"type:jsonb" json:"content"` // dpia.Template
+	CreatedBy   uuid.UUID      `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// DPIA is a Data Protection Impact Assessment of some processing, scoped to
+// the purposes, systems and vendors involved. The template is copied in when
+// the DPIA is created so later template edits do not change it.
+type DPIA struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Title        string         `gorm:"type:varchar(255);not null" json:"title"`
+	Description  string         `gorm:"type:text" json:"description,omitempty"`
+	TemplateID   *uuid.UUID     `gorm:"type:uuid" json:"templateId,omitempty"`
+	TemplateName string         `gorm:"type:varchar(255)" json:"templateName"`
+	Template     datatypes.JSON `gorm:"type:jsonb" json:"template"`
+	// Answers are keyed by question ID: {"value": "...", "comment": "..."}
+	Answers    datatypes.JSON `gorm:"type:jsonb" json:"answers"`
+	PurposeIDs datatypes.JSON `gorm:"type:jsonb" json:"purposeIds"`
+	SystemIDs  datatypes.JSON `gorm:"type:jsonb" json:"systemIds"` // Data sources
+	VendorIDs  datatypes.JSON `gorm:"type:jsonb" json:"vendorIds"`
+
+	Status string `gorm:"type:varchar(20);not null;index" json:"status"`
+	// Cycle counts assessments: 1 for the first, one more per reassessment
+	Cycle int `gorm:"not null;default:1" json:"cycle"`
+	// QuestionnaireRisk is 100 minus the template score of the answers
+	QuestionnaireRisk float64 `json:"questionnaireRisk"`
+	// RiskLevel is the highest level across the DPIA's risks, residual where rated
+	RiskLevel string `gorm:"type:varchar(20)" json:"riskLevel"`
+
+	ReviewMonths     int        `gorm:"not null" json:"reviewMonths"`
+	NextReviewAt     *time.Time `gorm:"index" json:"nextReviewAt,omitempty"`
+	ReviewNotifiedAt *time.Time `json:"reviewNotifiedAt,omitempty"`
+
+	CreatedBy    uuid.UUID  `gorm:"type:uuid" json:"createdBy"`
+	SubmittedBy  *uuid.UUID `gorm:"type:uuid" json:"submittedBy,omitempty"`
+	SubmittedAt  *time.Time `json:"submittedAt,omitempty"`
+	ApprovedBy   *uuid.UUID `gorm:"type:uuid" json:"approvedBy,omitempty"`
+	ApprovedAt   *time.Time `json:"approvedAt,omitempty"`
+	DecisionNote string     `gorm:"type:text" json:"decisionNote,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// DPIARisk is a risk identified in a DPIA, rated for likelihood and impact
+// before and, once mitigated, after its mitigations
+type DPIARisk struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID `gorm:"type:uuid;index;not null" json:"tenantId"`
+	DPIAID      uuid.UUID `gorm:"type:uuid;index;not null" json:"dpiaId"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Category    string    `gorm:"type:varchar(50)" json:"category,omitempty"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	// QuestionID is set on risks raised by a template question
+	QuestionID string `gorm:"type:varchar(100)" json:"questionId,omitempty"`
+	Likelihood int    `gorm:"not null" json:"likelihood"`
+	Impact     int    `gorm:"not null" json:"impact"`
+	Score      int    `gorm:"not null" json:"score"`
+	Level      string `gorm:"type:varchar(20)" json:"level"`
+
+	ResidualLikelihood *int   `json:"residualLikelihood,omitempty"`
+	ResidualImpact     *int   `json:"residualImpact,omitempty"`
+	ResidualScore      *int   `json:"residualScore,omitempty"`
+	ResidualLevel      string `gorm:"type:varchar(20)" json:"residualLevel,omitempty"`
+	// Accepted records that the risk is accepted without (further) mitigation
+	Accepted  bool      `gorm:"default:false" json:"accepted"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// DPIAMitigation is a measure addressing a risk, linked to the purposes,
+// systems and vendors it applies to
+type DPIAMitigation struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	DPIAID      uuid.UUID      `gorm:"type:uuid;index;not null" json:"dpiaId"`
+	RiskID      uuid.UUID      `gorm:"type:uuid;index;not null" json:"riskId"`
+	Title       string         `gorm:"type:varchar(255);not null" json:"title"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Owner       string         `gorm:"type:varchar(255)" json:"owner,omitempty"`
+	DueDate     *time.Time     `json:"dueDate,omitempty"`
+	Status      string         `gorm:"type:varchar(20);not null" json:"status"`
+	PurposeIDs  datatypes.JSON `gorm:"type:jsonb" json:"purposeIds"`
+	SystemIDs   datatypes.JSON `gorm:"type:jsonb" json:"systemIds"`
+	VendorIDs   datatypes.JSON `gorm:"type:jsonb" json:"vendorIds"`
+	CompletedAt *time.Time     `json:"completedAt,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// DPIAEvent is one step in a DPIA's history: creation, submission, sign-off,
+// review reminders and reassessments
+type DPIAEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	DPIAID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"dpiaId"`
+	Action    string     `gorm:"type:varchar(50);not null" json:"action"`
+	Status    string     `gorm:"type:varchar(20)" json:"status"` // Status after the event
+	Cycle     int        `json:"cycle"`
+	ActorID   *uuid.UUID `gorm:"type:uuid" json:"actorId,omitempty"` // Nil for the scheduler
+	Note      string     `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
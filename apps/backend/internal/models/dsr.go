@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DSRRoutingRule decides who picks up a new DSR. Rules are evaluated in
+// ascending Position; empty match fields act as wildcards and the first
+// matching active rule wins.
+type DSRRoutingRule struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID uuid.UUID `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name     string    `gorm:"type:text;not null" json:"name"`
+	Position int       `gorm:"default:100" json:"position"`
+	IsActive bool      `gorm:"default:true" json:"isActive"`
+
+	// Match criteria
+	MatchType                 string     `gorm:"type:varchar(50)" json:"matchType,omitempty"`
+	MatchSubjectType          string     `gorm:"type:varchar(50)" json:"matchSubjectType,omitempty"`
+	MatchPriority             string     `gorm:"type:varchar(20)" json:"matchPriority,omitempty"`
+	MatchOrganizationEntityID *uuid.UUID `gorm:"type:uuid" json:"matchOrganizationEntityId,omitempty"`
+
+	// Assignment
+	AssigneeRole       string     `gorm:"type:varchar(100);not null" json:"assigneeRole"`         // Role name whose members receive the work
+	Strategy           string     `gorm:"type:varchar(20);default:'round_robin'" json:"strategy"` // round_robin, least_loaded
+	LastAssignedUserID *uuid.UUID `gorm:"type:uuid" json:"lastAssignedUserId,omitempty"`          // Round-robin cursor
+
+	// Escalation
+	EscalationRole         string `gorm:"type:varchar(100)" json:"escalationRole,omitempty"` // Supervisor role; empty disables escalation
+	EscalateHoursBeforeDue int    `gorm:"default:72" json:"escalateHoursBeforeDue"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (DSRRoutingRule) TableName() string {
+	return "dsr_routing_rules"
+}
+
+// Matches reports whether the rule applies to the request.
+func (r *DSRRoutingRule) Matches(req *DSRRequest) bool {
+	if r.MatchType != "" && r.MatchType != req.Type {
+		return false
+	}
+	if r.MatchSubjectType != "" && r.MatchSubjectType != req.SubjectType {
+		return false
+	}
+	if r.MatchPriority != "" && r.MatchPriority != req.Priority {
+		return false
+	}
+	if r.MatchOrganizationEntityID != nil {
+		if req.OrganizationEntityID == nil || *req.OrganizationEntityID != *r.MatchOrganizationEntityID {
+			return false
+		}
+	}
+	return true
+}
+
+// DSREvent is an entry on a request's timeline. Public events are shown to the
+// requester on the status page; the rest are for fiduciary staff only.
+type DSREvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	RequestID uuid.UUID  `gorm:"type:uuid;index;not null" json:"requestId"`
+	TenantID  uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	EventType string     `gorm:"type:varchar(50);not null" json:"eventType"` // submitted, status_changed, info_requested, clarification_received, result_available
+	Message   string     `gorm:"type:text" json:"message"`
+	Public    bool       `gorm:"default:true" json:"public"`
+	ActorID   *uuid.UUID `gorm:"type:uuid" json:"actorId,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (DSREvent) TableName() string {
+	return "dsr_events"
+}
+
+// DSRClarification is information supplied by the requester in answer to an
+// info request, optionally with a supporting file.
+type DSRClarification struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	RequestID   uuid.UUID `gorm:"type:uuid;index;not null" json:"requestId"`
+	TenantID    uuid.UUID `gorm:"type:uuid;index" json:"tenantId"`
+	Message     string    `gorm:"type:text" json:"message"`
+	FileName    string    `gorm:"type:text" json:"fileName,omitempty"`
+	FilePath    string    `gorm:"type:text" json:"-"`
+	ContentType string    `gorm:"type:varchar(100)" json:"contentType,omitempty"`
+	SizeBytes   int64     `json:"sizeBytes,omitempty"`
+	SHA256      string    `gorm:"type:varchar(64)" json:"sha256,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (DSRClarification) TableName() string {
+	return "dsr_clarifications"
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// GrievanceSLAPolicy sets response and resolution deadlines for grievances.
+// Empty Category or Priority act as wildcards; the most specific active policy
+// wins. Policies live in the tenant database alongside grievances.
+type GrievanceSLAPolicy struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID        uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name            string     `gorm:"type:text;not null" json:"name"`
+	Category        string     `gorm:"type:varchar(100)" json:"category,omitempty"`
+	Priority        string     `gorm:"type:varchar(20)" json:"priority,omitempty"`
+	ResponseHours   int        `gorm:"not null" json:"responseHours"`
+	ResolutionHours int        `gorm:"not null" json:"resolutionHours"`
+	EscalateTo      *uuid.UUID `gorm:"type:uuid" json:"escalateTo,omitempty"` // Fiduciary user who takes over breached grievances
+	IsActive        bool       `gorm:"default:true" json:"isActive"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+func (GrievanceSLAPolicy) TableName() string {
+	return "grievance_sla_policies"
+}
+
+// Matches reports whether the policy applies to the grievance.
+func (p *GrievanceSLAPolicy) Matches(g *Grievance) bool {
+	if p.Category != "" && p.Category != g.Category {
+		return false
+	}
+	if p.Priority != "" && p.Priority != g.Priority {
+		return false
+	}
+	return true
+}
+
+// Specificity ranks matching policies: category and priority beat either alone,
+// which beat a catch-all.
+func (p *GrievanceSLAPolicy) Specificity() int {
+	n := 0
+	if p.Category != "" {
+		n += 2
+	}
+	if p.Priority != "" {
+		n++
+	}
+	return n
+}
+
+// GrievanceEscalation records each escalation of a grievance: internal
+// escalations on SLA breach, the DPB notice sent at closure and any complaint
+// the principal reports having filed with the Data Protection Board.
+type GrievanceEscalation struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GrievanceID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"grievanceId"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	Kind         string     `gorm:"type:varchar(30);not null" json:"kind"` // sla_response, sla_resolution, dpb_notice, dpb_complaint
+	Level        int        `json:"level"`
+	Reason       string     `gorm:"type:text" json:"reason"`
+	EscalatedTo  *uuid.UUID `gorm:"type:uuid" json:"escalatedTo,omitempty"`
+	DPBReference string     `gorm:"type:text" json:"dpbReference,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func (GrievanceEscalation) TableName() string {
+	return "grievance_escalations"
+}
+
+// GrievanceTriageRule classifies incoming grievances. Pattern is a
+// comma-separated keyword list or a regular expression, matched
+// case-insensitively against the subject and description. Rules run in
+// Position order: the first match sets category and assignee, and the highest
+// priority among matches wins.
+type GrievanceTriageRule struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name      string     `gorm:"type:text;not null" json:"name"`
+	MatchType string     `gorm:"type:varchar(20);not null" json:"matchType"` // keyword, regex
+	Pattern   string     `gorm:"type:text;not null" json:"pattern"`
+	Category  string     `gorm:"type:varchar(100)" json:"category,omitempty"`
+	Priority  string     `gorm:"type:varchar(20)" json:"priority,omitempty"`
+	AssignTo  *uuid.UUID `gorm:"type:uuid" json:"assignTo,omitempty"`
+	Position  int        `gorm:"default:0" json:"position"`
+	IsActive  bool       `gorm:"default:true" json:"isActive"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+func (GrievanceTriageRule) TableName() string {
+	return "grievance_triage_rules"
+}
+
+// GrievanceTriageDecision records what the triage engine set on a grievance
+// and why. Each *Source names what produced the value: "rule:<id>",
+// "builtin:<name>", "signal:<name>", "reported" (kept from the principal) or
+// "default".
+type GrievanceTriageDecision struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	GrievanceID      uuid.UUID      `gorm:"type:uuid;uniqueIndex;not null" json:"grievanceId"`
+	TenantID         uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	ReportedCategory string         `gorm:"type:varchar(100)" json:"reportedCategory,omitempty"` // As entered by the principal
+	ReportedPriority string         `gorm:"type:varchar(20)" json:"reportedPriority,omitempty"`
+	Category         string         `gorm:"type:varchar(100)" json:"category"`
+	CategorySource   string         `gorm:"type:varchar(60)" json:"categorySource"`
+	Priority         string         `gorm:"type:varchar(20)" json:"priority"`
+	PrioritySource   string         `gorm:"type:varchar(60)" json:"prioritySource"`
+	AssignedTo       *uuid.UUID     `gorm:"type:uuid" json:"assignedTo,omitempty"`
+	AssigneeSource   string         `gorm:"type:varchar(60)" json:"assigneeSource,omitempty"`
+	LinkedBreachID   *uuid.UUID     `gorm:"type:uuid" json:"linkedBreachId,omitempty"`
+	MatchedRuleIDs   datatypes.JSON `gorm:"type:jsonb" json:"matchedRuleIds"`
+	Signals          datatypes.JSON `gorm:"type:jsonb" json:"signals"`
+	Reasons          datatypes.JSON `gorm:"type:jsonb" json:"reasons"`
+	CreatedAt        time.Time      `json:"createdAt"`
+}
+
+func (GrievanceTriageDecision) TableName() string {
+	return "grievance_triage_decisions"
+}
+
+// GrievanceTriageOverride captures a reviewer correcting a triaged value, with
+// the source that produced it, so rules that are often overridden can be tuned.
+type GrievanceTriageOverride struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	GrievanceID uuid.UUID  `gorm:"type:uuid;index;not null" json:"grievanceId"`
+	DecisionID  *uuid.UUID `gorm:"type:uuid;index" json:"decisionId,omitempty"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	Field       string     `gorm:"type:varchar(20);not null" json:"field"` // category, priority, assignee
+	FromValue   string     `gorm:"type:text" json:"fromValue"`
+	ToValue     string     `gorm:"type:text" json:"toValue"`
+	Source      string     `gorm:"type:varchar(60);index" json:"source,omitempty"` // Decision source of the overridden value; empty if already changed by hand
+	ReviewerID  uuid.UUID  `gorm:"type:uuid" json:"reviewerId"`
+	Reason      string     `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func (GrievanceTriageOverride) TableName() string {
+	return "grievance_triage_overrides"
+}
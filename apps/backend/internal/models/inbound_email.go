@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboundEmail logs every message received on the inbound mail channel and
+// what was done with it.
+type InboundEmail struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	MessageID    string     `gorm:"type:text;index" json:"messageId"` // RFC 5322 Message-ID, used to drop redeliveries
+	TenantID     *uuid.UUID `gorm:"type:uuid;index" json:"tenantId,omitempty"`
+	PrincipalID  *uuid.UUID `gorm:"type:uuid" json:"principalId,omitempty"`
+	FromAddress  string     `gorm:"type:text" json:"fromAddress"`
+	Subject      string     `gorm:"type:text" json:"subject"`
+	Outcome      string     `gorm:"type:varchar(30);index" json:"outcome"` // grievance_created, grievance_reply, dsr_created, dsr_reply, ignored, unmatched, failed
+	Reason       string     `gorm:"type:text" json:"reason,omitempty"`
+	GrievanceID  *uuid.UUID `gorm:"type:uuid" json:"grievanceId,omitempty"`
+	DSRRequestID *uuid.UUID `gorm:"type:uuid" json:"dsrRequestId,omitempty"`
+	ReceivedAt   time.Time  `gorm:"autoCreateTime" json:"receivedAt"`
+
+	Attachments []InboundEmailAttachment `gorm:"foreignKey:EmailID" json:"attachments,omitempty"`
+}
+
+func (InboundEmail) TableName() string {
+	return "inbound_emails"
+}
+
+type InboundEmailAttachment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	EmailID     uuid.UUID `gorm:"type:uuid;index;not null" json:"emailId"`
+	FileName    string    `gorm:"type:text" json:"fileName"`
+	FilePath    string    `gorm:"type:text" json:"-"`
+	ContentType string    `gorm:"type:varchar(100)" json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	SHA256      string    `gorm:"type:varchar(64)" json:"sha256"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (InboundEmailAttachment) TableName() string {
+	return "inbound_email_attachments"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed" // Out of attempts; only an admin retry brings it back
+	JobStatusCancelled = "cancelled"
+)
+
+// Job is one unit of background work in the persistent queue. Workers on any
+// replica claim due jobs with SELECT ... FOR UPDATE SKIP LOCKED, so a job is
+// run by exactly one of them, and a job left running by a dead pod is
+// requeued once its lock goes stale.
+type Job struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	Type        string         `gorm:"type:varchar(100);index;not null" json:"type"`
+	TenantID    *uuid.UUID     `gorm:"type:uuid;index" json:"tenantId,omitempty"`
+	Payload     datatypes.JSON `gorm:"type:jsonb" json:"payload,omitempty"`
+	Status      string         `gorm:"type:varchar(20);index:idx_jobs_due,priority:1;not null" json:"status"`
+	RunAt       time.Time      `gorm:"index:idx_jobs_due,priority:2;not null" json:"runAt"`
+	Attempts    int            `gorm:"default:0" json:"attempts"`
+	MaxAttempts int            `gorm:"default:5" json:"maxAttempts"`
+	LastError   string         `gorm:"type:text" json:"lastError,omitempty"`
+	Schedule    string         `gorm:"type:varchar(100)" json:"schedule,omitempty"` // Cron schedule that enqueued it, if any
+	// UniqueKey stops the same scheduled run being enqueued twice when
+	// leadership changes hands mid-tick. A key is taken for as long as its
+	// job is kept, whatever its status, so scheduled runs key on their tick.
+	UniqueKey   *string    `gorm:"type:varchar(255);uniqueIndex" json:"uniqueKey,omitempty"`
+	LockedBy    string     `gorm:"type:varchar(255)" json:"lockedBy,omitempty"`
+	LockedAt    *time.Time `json:"lockedAt,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// JobLease is a named lease held by one replica at a time. The holder of the
+// "scheduler" lease is the leader that enqueues cron jobs.
+type JobLease struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey" json:"name"`
+	Holder    string    `gorm:"type:varchar(255);not null" json:"holder"`
+	ExpiresAt time.Time `gorm:"not null" json:"expiresAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -385,6 +385,23 @@ type DSRRequest struct {
 	VerifiedAt  *time.Time
 	ProcessedAt *time.Time
 
+	// Routing & escalation
+	OrganizationEntityID *uuid.UUID `gorm:"type:uuid;index"`
+	RoutingRuleID        *uuid.UUID `gorm:"type:uuid"`
+	EscalatedAt          *time.Time
+	EscalatedTo          *uuid.UUID `gorm:"type:uuid"`
+
+	// Public tracking
+	TrackingRef    string `gorm:"type:varchar(20);index"` // Reference quoted to the requester, e.g. DSR-7K2M9Q4X
+	RequesterEmail string `gorm:"type:text"`              // Where status updates are sent
+	InfoRequest    string `gorm:"type:text"`              // Open question to the requester while awaiting info
+	// Last time a status link was resent on request; resends are throttled
+	StatusLinkResentAt *time.Time
+
+	// Set when the request was filed by an approved nominee acting for the principal
+	ActingNomineeID *uuid.UUID `gorm:"type:uuid;index"`
+	NomineeClaimID  *uuid.UUID `gorm:"type:uuid"`
+
 	// Data
 	RequestDetails datatypes.JSON `gorm:"type:jsonb"` // Specifics of what is requested
 	ResultData     datatypes.JSON `gorm:"type:jsonb"` // Link to export or summary of action
@@ -397,12 +414,31 @@ type DSRRequest struct {
 }
 
 type DSRComment struct {
-	ID         uuid.UUID `gorm:"primaryKey"`
-	RequestID  uuid.UUID `gorm:"index"`
-	AuthorID   uuid.UUID `gorm:"index"`
-	Content    string    `gorm:"type:text"`
-	IsInternal bool      `gorm:"default:true"`
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	ID         uuid.UUID `gorm:"primaryKey" json:"id"`
+	RequestID  uuid.UUID `gorm:"index" json:"requestId"`
+	AuthorID   uuid.UUID `gorm:"index" json:"authorId"`
+	AuthorType string    `gorm:"type:varchar(20);default:'staff'" json:"authorType"` // staff, principal
+	Content    string    `gorm:"type:text" json:"content"`
+	IsInternal bool      `gorm:"default:true" json:"isInternal"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// DSRFieldChange is a single proposed correction on a rectification request.
+// OldValue is captured from the principal record when the change is proposed.
+type DSRFieldChange struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	RequestID  uuid.UUID  `gorm:"type:uuid;index;not null" json:"requestId"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	Field      string     `gorm:"type:varchar(50);not null" json:"field"` // first_name, last_name, email, phone, age, location
+	OldValue   string     `gorm:"type:text" json:"oldValue"`
+	NewValue   string     `gorm:"type:text" json:"newValue"`
+	Evidence   string     `gorm:"type:text" json:"evidence,omitempty"`          // Supporting note or document reference
+	Status     string     `gorm:"type:varchar(20);default:'pending'" json:"status"` // pending, approved, rejected, applied
+	ReviewedBy *uuid.UUID `gorm:"type:uuid" json:"reviewedBy,omitempty"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+	ReviewNote string     `gorm:"type:text" json:"reviewNote,omitempty"`
+	AppliedAt  *time.Time `json:"appliedAt,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 type AuditLog struct {
@@ -446,6 +482,22 @@ type Grievance struct {
 	AssignedTo           *uuid.UUID `gorm:"index" json:"assignedTo,omitempty"`
 	Category             string     `json:"category"` // e.g., billing, technical, general
 	Priority             string     `json:"priority"` // e.g., low, medium, high, urgent
+	ThreadToken          string     `gorm:"type:varchar(20);index" json:"threadToken,omitempty"` // e.g. GRV-7K2M9Q4X, quoted in email subjects to thread replies
+	Source               string     `gorm:"type:varchar(20);default:'portal'" json:"source"`     // portal, email
+	LinkedBreachID       *uuid.UUID `gorm:"type:uuid;index" json:"linkedBreachId,omitempty"` // Set by triage when the grievance concerns an active breach
+
+	// SLA tracking
+	SLAPolicyID          *uuid.UUID `gorm:"type:uuid" json:"slaPolicyId,omitempty"`
+	ResponseDueAt        *time.Time `gorm:"index" json:"responseDueAt,omitempty"`
+	ResolutionDueAt      *time.Time `gorm:"index" json:"resolutionDueAt,omitempty"`
+	FirstRespondedAt     *time.Time `json:"firstRespondedAt,omitempty"`
+	ResolvedAt           *time.Time `json:"resolvedAt,omitempty"`
+	ResponseBreachedAt   *time.Time `json:"responseBreachedAt,omitempty"`
+	ResolutionBreachedAt *time.Time `json:"resolutionBreachedAt,omitempty"`
+	EscalationLevel      int        `gorm:"default:0" json:"escalationLevel"`
+	EscalatedAt          *time.Time `json:"escalatedAt,omitempty"`
+	DPBNoticeSentAt      *time.Time `json:"dpbNoticeSentAt,omitempty"` // Principal told of their right to approach the Data Protection Board
+
 	CreatedAt            time.Time  `json:"createdAt"`
 	UpdatedAt            time.Time  `json:"updatedAt"`
 }
@@ -502,7 +554,12 @@ type Tenant struct {
 	CompanySize           string
 	Config                datatypes.JSON
 	ReviewFrequencyMonths int `gorm:"default:6"`
-	CreatedAt             time.Time
+	// SignificantDataFiduciary is set when the tenant is notified as a
+	// Significant Data Fiduciary under DPDP Section 10, which unlocks the
+	// obligations calendar
+	SignificantDataFiduciary bool `gorm:"default:false"`
+	SDFDesignatedAt          *time.Time
+	CreatedAt                time.Time
 }
 
 // -------------------------------
@@ -516,6 +573,7 @@ type Vendor struct {
 	// DPA-related fields
 	DPAAgreementID         *uuid.UUID `gorm:"type:uuid" json:"dpaAgreementId,omitempty"`
 	ProcessingLocation     string     `gorm:"type:text" json:"processingLocation,omitempty"`
+	ProcessingCountry      string     `gorm:"type:varchar(2);index" json:"processingCountry,omitempty"` // ISO 3166-1 alpha-2, resolved from ProcessingLocation when not given
 	SecurityCertifications string     `gorm:"type:text" json:"securityCertifications,omitempty"`
 	LastComplianceCheck    *time.Time `gorm:"type:timestamp" json:"lastComplianceCheck,omitempty"`
 	ComplianceStatus       string     `gorm:"type:text" json:"complianceStatus,omitempty"`
@@ -545,8 +603,11 @@ type TPRMAssessment struct {
 	EvidenceCount int        `gorm:"default:0" json:"evidenceCount"`
 	AssessorID    *uuid.UUID `gorm:"type:uuid" json:"assessorId,omitempty"`
 	Notes         string     `gorm:"type:text" json:"notes,omitempty"`
-	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+	// Questionnaire assessments pin the version they are answered against
+	QuestionnaireVersionID *uuid.UUID     `gorm:"type:uuid;index" json:"questionnaireVersionId,omitempty"`
+	ScoreResult            datatypes.JSON `gorm:"type:jsonb" json:"scoreResult,omitempty"` // Latest questionnaire score breakdown
+	CreatedAt              time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt              time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
 // TPRMEvidence stores uploaded evidence files for an assessment
@@ -680,6 +741,15 @@ type BreachNotification struct {
 	DPBReportReference      *string    `gorm:"type:text"`
 	DPBNotificationDeadline *time.Time // Calculated deadline for DPB notification
 
+	// CERT-In incident reporting, a parallel track with a 6-hour deadline
+	RequiresCERTInReporting bool       `gorm:"default:false"`
+	CERTInIncidentType      string     `gorm:"type:varchar(50)"` // CERT-In Annexure I type, see breach.CERTInIncidentTypes
+	CERTInDeadline          *time.Time // 6 hours from detection
+	CERTInStatus            string     `gorm:"type:varchar(20)"` // not_required, pending, overdue, reported, reported_late
+	CERTInReportedAt        *time.Time
+	CERTInReference         *string        `gorm:"type:text"`
+	CERTInDetails           datatypes.JSON `gorm:"type:jsonb"` // breach.CERTInIncidentDetails
+
 	// Data categories affected (DPDP requirement)
 	DataCategoriesAffected datatypes.JSON `gorm:"type:jsonb"` // ["personal_identifiable", "financial", "health", "biometric", etc.]
 
@@ -716,6 +786,12 @@ type BreachNotification struct {
 	ComplianceNotes  *string `gorm:"type:text"`
 	IsOverdue        bool    `gorm:"default:false"`
 
+	// Tabletop exercise: the workflow runs in full but notifications go to the
+	// simulation sink, regulator submissions are suppressed and the breach is
+	// kept out of the register and statistics
+	IsSimulation          bool `gorm:"default:false;index"`
+	SimulationCompletedAt *time.Time
+
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
@@ -745,10 +821,39 @@ type EncryptedBreachNotification struct {
 	InvestigatedBy       *string `gorm:"type:text"`
 	InvestigationDate    *time.Time
 	// Compliance details
-	ComplianceStatus string    `gorm:"type:varchar(50)"`
-	ComplianceNotes  *string   `gorm:"type:text"`
-	CreatedAt        time.Time `gorm:"autoCreateTime"`
-	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+	ComplianceStatus string  `gorm:"type:varchar(50)"`
+	ComplianceNotes  *string `gorm:"type:text"`
+	// Fields needed for regulator reports
+	Title                             string `gorm:"type:text"`
+	ContainmentDate                   *time.Time
+	DPBNotificationDeadline           *time.Time
+	DataCategoriesAffected            datatypes.JSON `gorm:"type:jsonb"`
+	RequiresDataPrincipalNotification bool           `gorm:"default:false"`
+	DataPrincipalNotificationSentAt   *time.Time
+	LikelihoodOfHarm                  string    `gorm:"type:varchar(20)"`
+	ImpactOnDataPrincipals            string    `gorm:"type:text"`
+	RootCause                         *string   `gorm:"type:text"`
+	// Notification approval and workflow
+	DataPrincipalNotificationApproved   bool       `gorm:"default:false"`
+	DataPrincipalNotificationApprovedBy *uuid.UUID `gorm:"type:uuid"`
+	DataPrincipalNotificationApprovedAt *time.Time
+	DataPrincipalNotificationDeadline   *time.Time
+	CurrentWorkflowStage                string     `gorm:"type:varchar(50)"`
+	VerifiedBy                          *uuid.UUID `gorm:"type:uuid"`
+	VerifiedAt                          *time.Time
+	// CERT-In incident reporting track
+	RequiresCERTInReporting bool   `gorm:"default:false"`
+	CERTInIncidentType      string `gorm:"type:varchar(50)"`
+	CERTInDeadline          *time.Time
+	CERTInStatus            string `gorm:"type:varchar(20)"`
+	CERTInReportedAt        *time.Time
+	CERTInReference         *string        `gorm:"type:text"`
+	CERTInDetails           datatypes.JSON `gorm:"type:jsonb"`
+	// Tabletop exercise
+	IsSimulation          bool `gorm:"default:false;index"`
+	SimulationCompletedAt *time.Time
+	CreatedAt             time.Time `gorm:"autoCreateTime"`
+	UpdatedAt             time.Time `gorm:"autoUpdateTime"`
 }
 
 func (b BreachNotification) BreachSeverity(severity string) string {
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Nominee is a person registered by a data principal to exercise their rights
+// in the event of death or incapacity (DPDP Act, Section 14).
+type Nominee struct {
+	ID                      uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	PrincipalID             uuid.UUID  `gorm:"type:uuid;index;not null" json:"principalId"` // The DataPrincipal who nominated
+	TenantID                uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name                    string     `gorm:"type:text;not null" json:"name"`
+	Email                   string     `gorm:"type:text;index;not null" json:"email"`
+	Phone                   string     `gorm:"type:varchar(30)" json:"phone,omitempty"`
+	Relationship            string     `gorm:"type:varchar(50)" json:"relationship"`                          // e.g., "Spouse", "Child", "Sibling"
+	Status                  string     `gorm:"type:varchar(30);default:'pending_verification'" json:"status"` // pending_verification, verified, revoked
+	VerificationToken       string     `gorm:"type:text;index" json:"-"`
+	VerificationTokenExpiry time.Time  `json:"-"`
+	VerifiedAt              *time.Time `json:"verifiedAt,omitempty"`
+	RevokedAt               *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt               time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt               time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (Nominee) TableName() string {
+	return "nominees"
+}
+
+// NomineeClaim is a nominee's request to act on behalf of a principal. It must be
+// reviewed and approved by a fiduciary user before the nominee gains any authority.
+type NomineeClaim struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	NomineeID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"nomineeId"`
+	PrincipalID uuid.UUID  `gorm:"type:uuid;index;not null" json:"principalId"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	ClaimType   string     `gorm:"type:varchar(20);not null" json:"claimType"`         // death, incapacity
+	Status      string     `gorm:"type:varchar(20);default:'submitted'" json:"status"` // submitted, approved, rejected, revoked
+	Statement   string     `gorm:"type:text" json:"statement,omitempty"`
+	ReviewedBy  *uuid.UUID `gorm:"type:uuid" json:"reviewedBy,omitempty"` // FiduciaryUser ID
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	ReviewNote  string     `gorm:"type:text" json:"reviewNote,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+
+	Evidence []NomineeClaimEvidence `gorm:"foreignKey:ClaimID" json:"evidence,omitempty"`
+}
+
+func (NomineeClaim) TableName() string {
+	return "nominee_claims"
+}
+
+// NomineeClaimEvidence is a supporting document (death certificate, medical
+// certificate, court order) attached to a claim.
+type NomineeClaimEvidence struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ClaimID      uuid.UUID `gorm:"type:uuid;index;not null" json:"claimId"`
+	TenantID     uuid.UUID `gorm:"type:uuid;index;not null" json:"tenantId"`
+	DocumentType string    `gorm:"type:varchar(50)" json:"documentType"` // death_certificate, medical_certificate, court_order, other
+	FileName     string    `gorm:"type:text" json:"fileName"`
+	FilePath     string    `gorm:"type:text" json:"-"`
+	ContentType  string    `gorm:"type:varchar(100)" json:"contentType"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	SHA256       string    `gorm:"type:varchar(64)" json:"sha256"`
+	UploadedAt   time.Time `gorm:"autoCreateTime" json:"uploadedAt"`
+}
+
+func (NomineeClaimEvidence) TableName() string {
+	return "nominee_claim_evidence"
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ROPA version statuses
+const (
+	RopaDraft      = "draft"
+	RopaSignedOff  = "signed_off"
+	RopaSuperseded = "superseded" // A newer version was generated before sign-off
+)
+
+// RopaProfile holds the parts of an organization entity's Record of
+// Processing Activities that cannot be derived from purposes, vendors and
+// DPAs and are filled in by hand
+type RopaProfile struct {
+	ID                   uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID             uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_ropa_profile;not null" json:"tenantId"`
+	OrganizationEntityID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_ropa_profile;not null" json:"organizationEntityId"`
+	ControllerContact    string    `gorm:"type:text" json:"controllerContact"`
+	DPOContact           string    `gorm:"type:text" json:"dpoContact"`
+	SecurityMeasures     string    `gorm:"type:text" json:"securityMeasures"`
+	// Activities holds per-purpose entries keyed by purpose ID:
+	// {"dataSubjects": "...", "securityMeasures": "...", "notes": "..."}
+	Activities datatypes.JSON `gorm:"type:jsonb" json:"activities"`
+	UpdatedBy  uuid.UUID      `gorm:"type:uuid" json:"updatedBy"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// RopaVersion is a generated register, frozen as JSON. Drafts are signed off
+// by a reviewer; signed-off versions never change.
+type RopaVersion struct {
+	ID                   uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID             uuid.UUID      `gorm:"type:uuid;index;uniqueIndex:idx_ropa_version;not null" json:"tenantId"`
+	OrganizationEntityID uuid.UUID      `gorm:"type:uuid;index;uniqueIndex:idx_ropa_version;not null" json:"organizationEntityId"`
+	Version              int            `gorm:"uniqueIndex:idx_ropa_version;not null" json:"version"`
+	Status               string         `gorm:"type:varchar(20);not null;index" json:"status"`
+	Content              datatypes.JSON `gorm:"type:jsonb" json:"content"`
+	ContentSHA256        string         `gorm:"type:varchar(64)" json:"contentSha256"`
+	ChangeNote           string         `gorm:"type:text" json:"changeNote,omitempty"`
+	CreatedBy            uuid.UUID      `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt            time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	SignedOffBy          *uuid.UUID     `gorm:"type:uuid" json:"signedOffBy,omitempty"`
+	SignedOffAt          *time.Time     `json:"signedOffAt,omitempty"`
+	SignOffNote          string         `gorm:"type:text" json:"signOffNote,omitempty"`
+}
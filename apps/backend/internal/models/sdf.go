@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Significant Data Fiduciary obligation types. The first four are the
+// recurring duties of DPDP Section 10(2); tenants may add their own.
+const (
+	SDFObligationDPO              = "dpo_appointment"
+	SDFObligationIndependentAudit = "independent_audit"
+	SDFObligationDPIA             = "dpia"
+	SDFObligationPeriodicAudit    = "periodic_audit"
+	SDFObligationCustom           = "custom"
+)
+
+// SDF obligation statuses
+const (
+	SDFObligationOpen      = "open"
+	SDFObligationCompleted = "completed" // A one-off obligation that has been met
+)
+
+// SDFObligation is a duty on a Significant Data Fiduciary's obligations
+// calendar. Recurring obligations fall due again RecurrenceMonths after each
+// completion; one-off obligations close once completed.
+type SDFObligation struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Type        string    `gorm:"type:varchar(30);not null" json:"type"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	Reference   string    `gorm:"type:varchar(100)" json:"reference,omitempty"` // e.g. "Section 10(2)(b)"
+	// OwnerID is the fiduciary user responsible; Owner is their name or, for
+	// owners outside the platform, free text
+	OwnerID          *uuid.UUID `gorm:"type:uuid" json:"ownerId,omitempty"`
+	Owner            string     `gorm:"type:varchar(255)" json:"owner,omitempty"`
+	RecurrenceMonths int        `gorm:"not null;default:0" json:"recurrenceMonths"` // 0 for one-off
+	NextDueAt        *time.Time `gorm:"index" json:"nextDueAt,omitempty"`           // Nil once a one-off is completed
+	Status           string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	// EvidenceRequired blocks completion until evidence has been uploaded
+	EvidenceRequired  bool       `gorm:"default:false" json:"evidenceRequired"`
+	LastCompletedAt   *time.Time `json:"lastCompletedAt,omitempty"`
+	OverdueNotifiedAt *time.Time `json:"overdueNotifiedAt,omitempty"`
+	CreatedBy         uuid.UUID  `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// SDFObligationCompletion records one occurrence of an obligation being met
+type SDFObligationCompletion struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     uuid.UUID `gorm:"type:uuid;index;not null" json:"tenantId"`
+	ObligationID uuid.UUID `gorm:"type:uuid;index;not null" json:"obligationId"`
+	DueAt        time.Time `gorm:"index" json:"dueAt"` // The due date the completion met
+	CompletedAt  time.Time `json:"completedAt"`
+	// CompletedBy is nil when the platform completed it, e.g. on appointing a DPO
+	CompletedBy *uuid.UUID `gorm:"type:uuid" json:"completedBy,omitempty"`
+	Note        string     `gorm:"type:text" json:"note,omitempty"`
+	Late        bool       `json:"late"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// SDFEvidence is a file uploaded against an obligation, such as an audit
+// report or an auditor's engagement letter. It is attached to the next
+// completion of the obligation.
+type SDFEvidence struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	ObligationID uuid.UUID  `gorm:"type:uuid;index;not null" json:"obligationId"`
+	CompletionID *uuid.UUID `gorm:"type:uuid;index" json:"completionId,omitempty"`
+	DueAt        time.Time  `json:"dueAt"` // The due date open when it was uploaded
+	FileName     string     `gorm:"type:varchar(255);not null" json:"fileName"`
+	FilePath     string     `gorm:"type:text" json:"-"`
+	ContentType  string     `gorm:"type:varchar(100)" json:"contentType"`
+	SizeBytes    int64      `json:"sizeBytes"`
+	SHA256       string     `gorm:"type:varchar(64)" json:"sha256"`
+	Note         string     `gorm:"type:text" json:"note,omitempty"`
+	UploadedBy   uuid.UUID  `gorm:"type:uuid" json:"uploadedBy"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// DataProtectionOfficer is an entry in a tenant's DPO registry. One entry is
+// active at a time; earlier appointments are kept with the date they ended.
+// The active DPO's contact details are published on consent notices and in
+// the privacy policy.
+type DataProtectionOfficer struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	Name        string     `gorm:"type:varchar(255);not null" json:"name"`
+	Email       string     `gorm:"type:varchar(255);not null" json:"email"`
+	Phone       string     `gorm:"type:varchar(50)" json:"phone,omitempty"`
+	Address     string     `gorm:"type:text" json:"address,omitempty"`
+	Country     string     `gorm:"type:varchar(2);not null" json:"country"` // ISO 3166-1 alpha-2
+	Active      bool       `gorm:"index" json:"active"`
+	AppointedAt time.Time  `json:"appointedAt"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"`
+	AppointedBy uuid.UUID  `gorm:"type:uuid" json:"appointedBy"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Sub-processor statuses
+const (
+	SubProcessorProposed = "proposed" // Awaiting the tenant within the objection window
+	SubProcessorApproved = "approved" // In use, approved explicitly or deemed approved
+	SubProcessorRejected = "rejected" // The tenant objected; must not be used
+	SubProcessorReplaced = "replaced" // Superseded by an approved later declaration
+	SubProcessorRemoved  = "removed"  // No longer used by the vendor
+)
+
+// VendorSubProcessor is a vendor's declaration of a sub-processor it engages
+// for a tenant. Changing an approved declaration means proposing a new one
+// that replaces it, so every declaration that was ever in force is kept.
+type VendorSubProcessor struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	VendorID       uuid.UUID      `gorm:"type:uuid;index;not null" json:"vendorId"`
+	Name           string         `gorm:"type:text;not null" json:"name"`
+	Location       string         `gorm:"type:varchar(100)" json:"location"`    // Where the sub-processor handles the data, as declared
+	Country        string         `gorm:"type:varchar(2);index" json:"country"` // ISO 3166-1 alpha-2 code resolved from Location
+	Services       string         `gorm:"type:text" json:"services"`            // What the sub-processor does for the vendor
+	DataCategories datatypes.JSON `gorm:"type:jsonb" json:"dataCategories"`     // []string
+	Status         string         `gorm:"type:varchar(20);index;not null" json:"status"`
+	// ReplacesID is the approved declaration this one changes, if any
+	ReplacesID *uuid.UUID `gorm:"type:uuid;index" json:"replacesId,omitempty"`
+	ProposedBy string     `gorm:"type:text" json:"proposedBy"` // Vendor contact email, or the fiduciary user ID
+	ProposedAt time.Time  `json:"proposedAt"`
+	// ObjectionDeadline is when the tenant's window to object closes
+	ObjectionDeadline *time.Time `gorm:"index" json:"objectionDeadline,omitempty"`
+	DecidedAt         *time.Time `json:"decidedAt,omitempty"`
+	DecidedBy         *uuid.UUID `gorm:"type:uuid" json:"decidedBy,omitempty"`
+	DecisionNote      string     `gorm:"type:text" json:"decisionNote,omitempty"`
+	// DeemedApproved is set when the window lapsed without objection under a
+	// DPA that generally authorises sub-processing
+	DeemedApproved bool `gorm:"default:false" json:"deemedApproved"`
+	// RestrictedTransfer is set when Country is on the tenant's restricted
+	// list with the flag action
+	RestrictedTransfer bool `gorm:"default:false" json:"restrictedTransfer"`
+	// LapseNotifiedAt is set once the tenant has been told that a window
+	// lapsed and an explicit decision is still needed
+	LapseNotifiedAt *time.Time `json:"lapseNotifiedAt,omitempty"`
+	RemovedAt       *time.Time `json:"removedAt,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// VendorSubProcessorEvent is one entry in the history of a sub-processor
+// declaration, with the declared details as they stood at that point
+type VendorSubProcessorEvent struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	SubProcessorID uuid.UUID      `gorm:"type:uuid;index;not null" json:"subProcessorId"`
+	TenantID       uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	VendorID       uuid.UUID      `gorm:"type:uuid;index;not null" json:"vendorId"`
+	Event          string         `gorm:"type:varchar(30);not null" json:"event"` // proposed, declared, approved, deemed_approved, rejected, replaced, removed
+	ActorType      string         `gorm:"type:varchar(20)" json:"actorType"`      // vendor, fiduciary, system
+	Actor          string         `gorm:"type:text" json:"actor,omitempty"`
+	Note           string         `gorm:"type:text" json:"note,omitempty"`
+	Name           string         `gorm:"type:text" json:"name"`
+	Location       string         `gorm:"type:varchar(100)" json:"location"`
+	Country        string         `gorm:"type:varchar(2)" json:"country"`
+	Services       string         `gorm:"type:text" json:"services"`
+	DataCategories datatypes.JSON `gorm:"type:jsonb" json:"dataCategories"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
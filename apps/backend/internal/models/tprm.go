@@ -4,19 +4,52 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-
+	"gorm.io/datatypes"
 )
 
-// DPATemplate represents a standard Data Processing Agreement template
+// DPATemplate represents a standard Data Processing Agreement template.
+// Content and Version mirror the latest DPATemplateVersion.
 type DPATemplate struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	TenantID  uuid.UUID `gorm:"type:uuid;index"`
-	Name      string    `gorm:"type:text;not null"`
-	Content   string    `gorm:"type:text"` // HTML or Markdown content
-	Version   string    `gorm:"type:varchar(20)"`
-	IsActive  bool      `gorm:"default:true"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID      uuid.UUID `gorm:"type:uuid;index"`
+	Name          string    `gorm:"type:text;not null"`
+	Description   string    `gorm:"type:text"`
+	Content       string    `gorm:"type:text"` // Template body, see dpa.Render
+	Version       string    `gorm:"type:varchar(20)"`
+	LatestVersion int       `gorm:"default:0"`
+	IsActive      bool      `gorm:"default:true"`
+	CreatedBy     *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// DPATemplateVersion is an immutable revision of a DPA template. Agreements
+// record the version they were generated from.
+type DPATemplateVersion struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TemplateID uuid.UUID      `gorm:"type:uuid;uniqueIndex:idx_dpa_template_version;not null" json:"templateId"`
+	TenantID   uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	Version    int            `gorm:"uniqueIndex:idx_dpa_template_version;not null" json:"version"`
+	Body       string         `gorm:"type:text;not null" json:"body"`
+	Fields     datatypes.JSON `gorm:"type:jsonb" json:"fields"` // Template-specific []dpa.Field on top of dpa.StandardFields
+	Changelog  string         `gorm:"type:text" json:"changelog,omitempty"`
+	CreatedBy  uuid.UUID      `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// DPAClause is one version of a clause in a tenant's clause library.
+// Saving a clause adds a version; templates always pick up the current one.
+type DPAClause struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_dpa_clause_version;not null" json:"tenantId"`
+	Key       string    `gorm:"type:varchar(64);uniqueIndex:idx_dpa_clause_version;not null" json:"key"`
+	Version   int       `gorm:"uniqueIndex:idx_dpa_clause_version;not null" json:"version"`
+	Title     string    `gorm:"type:text" json:"title"`
+	Category  string    `gorm:"type:varchar(50)" json:"category,omitempty"` // e.g. security, sub_processing, liability
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	IsCurrent bool      `gorm:"index" json:"isCurrent"`
+	CreatedBy uuid.UUID `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 // DPAAgreement represents a signed agreement with a vendor
@@ -26,9 +59,23 @@ type DPAAgreement struct {
 	VendorID     uuid.UUID `gorm:"type:uuid;index"`
 	TemplateID   *uuid.UUID `gorm:"type:uuid;index"`
 	Status       string    `gorm:"type:varchar(20);default:'draft'"` // draft, sent, signed, expired
-	SignedURL    string    `gorm:"type:text"` // Path to signed PDF
+	SignedURL    string    `gorm:"type:text" json:"-"` // Storage location of the signed copy
 	ValidFrom    *time.Time
 	ValidUntil   *time.Time
+	// Generated document
+	TemplateVersion int            `gorm:"default:0"`
+	Variables       datatypes.JSON `gorm:"type:jsonb"` // Merge field values used
+	ClauseVersions  datatypes.JSON `gorm:"type:jsonb"` // []dpa.ClauseRef used
+	DocumentPath    string         `gorm:"type:text" json:"-"`
+	DocumentSHA256  string         `gorm:"type:varchar(64)"`
+	GeneratedBy     *uuid.UUID     `gorm:"type:uuid"`
+	// Signed copy, stored as uploaded
+	SignedSHA256     string `gorm:"type:varchar(64)"`
+	SignedUploadedAt *time.Time
+	SignedUploadedBy *uuid.UUID `gorm:"type:uuid"`
+	// Set when the vendor acknowledges the agreement through the vendor portal
+	AcknowledgedAt *time.Time
+	AcknowledgedBy string `gorm:"type:text"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
 }
@@ -65,3 +112,60 @@ type AuditResponse struct {
 	UpdatedAt    time.Time      `gorm:"autoUpdateTime"`
 }
 
+
+const (
+	QuestionnaireVersionDraft      = "draft"
+	QuestionnaireVersionPublished  = "published"
+	QuestionnaireVersionSuperseded = "superseded" // A newer version was published; pinned assessments still use it
+)
+
+// VendorQuestionnaire is a tenant-authored vendor questionnaire. Its content
+// lives in numbered versions; only the published one can start assessments.
+type VendorQuestionnaire struct {
+	ID               uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID         uuid.UUID `gorm:"type:uuid;index" json:"tenantId"`
+	Name             string    `gorm:"type:varchar(255);not null" json:"name"`
+	Description      string    `gorm:"type:text" json:"description,omitempty"`
+	LatestVersion    int       `gorm:"default:1" json:"latestVersion"`
+	PublishedVersion int       `gorm:"default:0" json:"publishedVersion"` // 0 until a version is published
+	CreatedBy        uuid.UUID `gorm:"type:uuid" json:"createdBy"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// VendorQuestionnaireVersion holds one version's sections, questions and
+// scoring as a questionnaire.Definition. Published versions are immutable.
+type VendorQuestionnaireVersion struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	QuestionnaireID uuid.UUID      `gorm:"type:uuid;uniqueIndex:idx_questionnaire_version" json:"questionnaireId"`
+	TenantID        uuid.UUID      `gorm:"type:uuid;index" json:"tenantId"`
+	Version         int            `gorm:"uniqueIndex:idx_questionnaire_version" json:"version"`
+	Status          string         `gorm:"type:varchar(20);default:'draft'" json:"status"`
+	Definition      datatypes.JSON `gorm:"type:jsonb" json:"definition"`
+	Changelog       string         `gorm:"type:text" json:"changelog,omitempty"`
+	PublishedAt     *time.Time     `json:"publishedAt,omitempty"`
+	PublishedBy     *uuid.UUID     `gorm:"type:uuid" json:"publishedBy,omitempty"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// VendorQuestionnaireAnswer is one answer in a questionnaire assessment.
+// Answers are keyed by question ID, which stays stable across versions, so a
+// vendor's earlier answers can be carried into a new assessment.
+type VendorQuestionnaireAnswer struct {
+	ID                     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID               uuid.UUID  `gorm:"type:uuid;index" json:"tenantId"`
+	VendorID               uuid.UUID  `gorm:"type:uuid;index" json:"vendorId"`
+	AssessmentID           uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_assessment_question" json:"assessmentId"`
+	QuestionnaireVersionID uuid.UUID  `gorm:"type:uuid" json:"questionnaireVersionId"`
+	QuestionID             string     `gorm:"type:varchar(100);uniqueIndex:idx_assessment_question" json:"questionId"`
+	QuestionType           string     `gorm:"type:varchar(20)" json:"questionType"`
+	Value                  string     `gorm:"type:text" json:"value"`
+	EvidenceID             *uuid.UUID `gorm:"type:uuid" json:"evidenceId,omitempty"` // TPRMEvidence for file questions
+	Comments               string     `gorm:"type:text" json:"comments,omitempty"`
+	ReusedFromID           *uuid.UUID `gorm:"type:uuid" json:"reusedFromId,omitempty"` // The earlier answer this was copied from
+	AnsweredBy             *uuid.UUID `gorm:"type:uuid" json:"answeredBy,omitempty"`
+	AnsweredAt             time.Time  `json:"answeredAt"`
+	CreatedAt              time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt              time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Restricted country actions
+const (
+	TransferRestrictionBlock = "block" // New vendors and sub-processors there are refused
+	TransferRestrictionFlag  = "flag"  // Allowed, but marked for review in the transfer register
+)
+
+// RestrictedCountry is a country a tenant does not allow personal data to be
+// transferred to, such as one notified by the government under DPDP
+// Section 16(1)
+type RestrictedCountry struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_restricted_country;not null" json:"tenantId"`
+	CountryCode string    `gorm:"type:varchar(2);uniqueIndex:idx_restricted_country;not null" json:"countryCode"` // ISO 3166-1 alpha-2
+	Action      string    `gorm:"type:varchar(10);not null" json:"action"`                                        // block, flag
+	Reason      string    `gorm:"type:text" json:"reason,omitempty"`
+	Reference   string    `gorm:"type:text" json:"reference,omitempty"` // e.g. the government notification
+	UpdatedBy   uuid.UUID `gorm:"type:uuid" json:"updatedBy"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
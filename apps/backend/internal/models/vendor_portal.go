@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Vendor portal scopes: what an invitation lets the vendor contact do
+const (
+	VendorScopeAssessments   = "assessments"    // View and answer assigned assessments
+	VendorScopeEvidence      = "evidence"       // Upload evidence to assigned assessments
+	VendorScopeFindings      = "findings"       // Respond to remediation requests
+	VendorScopeDPAs          = "dpas"           // Acknowledge data processing agreements
+	VendorScopeSubProcessors = "sub_processors" // Declare and propose sub-processors
+)
+
+// VendorPortalInvitation grants a vendor contact scoped, expiring access to
+// the vendor self-service portal of one tenant. Only the SHA-256 of the
+// token is stored; the token itself is sent once, in the invitation email.
+type VendorPortalInvitation struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  uuid.UUID      `gorm:"type:uuid;index;not null" json:"tenantId"`
+	VendorID  uuid.UUID      `gorm:"type:uuid;index;not null" json:"vendorId"`
+	Email     string         `gorm:"type:text;not null" json:"email"`
+	TokenHash string         `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes    datatypes.JSON `gorm:"type:jsonb" json:"scopes"` // []string of VendorScope*
+	// AssessmentIDs limits the invitation to these assessments; empty means
+	// every assessment of the vendor in the tenant
+	AssessmentIDs datatypes.JSON `gorm:"type:jsonb" json:"assessmentIds,omitempty"`
+	ExpiresAt     time.Time      `gorm:"not null" json:"expiresAt"`
+	RevokedAt     *time.Time     `json:"revokedAt,omitempty"`
+	LastUsedAt    *time.Time     `json:"lastUsedAt,omitempty"`
+	InvitedBy     uuid.UUID      `gorm:"type:uuid" json:"invitedBy"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// VendorFindingResponse is a vendor's reply to a remediation request
+type VendorFindingResponse struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	FindingID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"findingId"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;index;not null" json:"tenantId"`
+	VendorID       uuid.UUID  `gorm:"type:uuid;index;not null" json:"vendorId"`
+	InvitationID   uuid.UUID  `gorm:"type:uuid;index" json:"invitationId"`
+	RespondedBy    string     `gorm:"type:text" json:"respondedBy"` // Vendor contact email
+	Response       string     `gorm:"type:text;not null" json:"response"`
+	EvidenceID     *uuid.UUID `gorm:"type:uuid" json:"evidenceId,omitempty"`
+	TargetDate     *time.Time `json:"targetDate,omitempty"` // When the vendor expects remediation to be done
+	MarkedResolved bool       `json:"markedResolved"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// VendorAssessmentReminder records that a due-date reminder stage was sent
+// for an assessment, so each stage goes out once
+type VendorAssessmentReminder struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	AssessmentID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_vendor_reminder_stage;not null" json:"assessmentId"`
+	Stage        string    `gorm:"type:varchar(20);uniqueIndex:idx_vendor_reminder_stage;not null" json:"stage"`
+	TenantID     uuid.UUID `gorm:"type:uuid;index" json:"tenantId"`
+	SentTo       string    `gorm:"type:text" json:"sentTo"`
+	SentAt       time.Time `json:"sentAt"`
+}
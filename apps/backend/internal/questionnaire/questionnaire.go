@@ -0,0 +1,471 @@
+package questionnaire
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QuestionType is how a question is answered
+type QuestionType string
+
+const (
+	TypeYesNo   QuestionType = "yes_no"
+	TypeChoice  QuestionType = "choice"
+	TypeText    QuestionType = "text"
+	TypeFile    QuestionType = "file"
+	TypeNumeric QuestionType = "numeric"
+)
+
+// Control frameworks a question can be mapped to
+const (
+	FrameworkISO27001 = "ISO27001"
+	FrameworkSOC2     = "SOC2"
+	FrameworkDPDPA    = "DPDPA"
+)
+
+// Scoring formulas
+const (
+	// FormulaWeightedAverage scores answered questions by weight, as a
+	// percentage of the most they could have scored
+	FormulaWeightedAverage = "weighted_average"
+	// FormulaWeightedSum adds up weighted points; the percentage is taken
+	// against the total weight
+	FormulaWeightedSum = "weighted_sum"
+	// FormulaLowestSection scores the vendor on its weakest section
+	FormulaLowestSection = "lowest_section"
+)
+
+// Unanswered question policies
+const (
+	UnansweredZero = "zero" // An unanswered visible question scores nothing
+	UnansweredSkip = "skip" // Unanswered questions are left out of the score
+)
+
+// Condition operators
+const (
+	OpEquals    = "equals"
+	OpNotEquals = "not_equals"
+	OpIn        = "in"
+	OpGreater   = "gt"
+	OpLess      = "lt"
+)
+
+// Definition is the content of one questionnaire version
+type Definition struct {
+	Sections []Section `json:"sections"`
+	Scoring  Scoring   `json:"scoring"`
+}
+
+// Scoring configures how answers turn into a score
+type Scoring struct {
+	Formula    string  `json:"formula,omitempty"`    // Defaults to weighted_average
+	Unanswered string  `json:"unanswered,omitempty"` // Defaults to zero
+	PassMark   float64 `json:"passMark,omitempty"`   // Percentage a vendor must reach; 0 leaves pass/fail unset
+	// SectionWeights multiply the weights of the questions in a section
+	SectionWeights map[string]float64 `json:"sectionWeights,omitempty"`
+}
+
+type Section struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Questions   []Question `json:"questions"`
+}
+
+// Question is one item. Its ID must stay the same across versions for its
+// answers to be reused.
+type Question struct {
+	ID       string       `json:"id"`
+	Text     string       `json:"text"`
+	Help     string       `json:"help,omitempty"`
+	Type     QuestionType `json:"type"`
+	Required bool         `json:"required,omitempty"`
+	// Weight is how much the question counts towards the score; text
+	// questions are never scored
+	Weight  float64  `json:"weight"`
+	AllowNA bool     `json:"allowNA,omitempty"` // "na" answers are left out of the score
+	Options []Option `json:"options,omitempty"` // Choice options; optional score overrides for yes/no
+	Numeric *Numeric `json:"numeric,omitempty"`
+	// ShowIf makes this a follow-up, asked only when an earlier answer matches
+	ShowIf   *Condition       `json:"showIf,omitempty"`
+	Controls []ControlMapping `json:"controls,omitempty"`
+}
+
+// Option is a choice and the score, from 0 to 1, it earns
+type Option struct {
+	Value string  `json:"value"`
+	Label string  `json:"label,omitempty"`
+	Score float64 `json:"score"`
+}
+
+// Numeric scores a number linearly between Min and Max. Without both bounds
+// the answer is recorded but not scored.
+type Numeric struct {
+	Min            *float64 `json:"min,omitempty"`
+	Max            *float64 `json:"max,omitempty"`
+	HigherIsBetter bool     `json:"higherIsBetter,omitempty"`
+	Unit           string   `json:"unit,omitempty"`
+}
+
+// Condition compares an earlier question's answer
+type Condition struct {
+	QuestionID string   `json:"questionId"`
+	Operator   string   `json:"operator"`
+	Value      string   `json:"value,omitempty"`
+	Values     []string `json:"values,omitempty"` // For "in"
+}
+
+// ControlMapping ties a question to a control of a framework, e.g. ISO27001
+// A.8.24 or DPDPA 8(4)
+type ControlMapping struct {
+	Framework string `json:"framework"`
+	Control   string `json:"control"`
+}
+
+// Answer is the response to one question. File questions are answered with
+// an evidence ID.
+type Answer struct {
+	Value   string
+	HasFile bool
+}
+
+// NormalizeFramework maps common spellings onto the framework constants
+func NormalizeFramework(f string) string {
+	k := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(f))
+	switch k {
+	case "ISO27001", "ISO270012013", "ISO270012022":
+		return FrameworkISO27001
+	case "SOC2", "SOCII", "SOC2TYPE2", "SOC2TYPEII":
+		return FrameworkSOC2
+	case "DPDPA", "DPDPA2023", "DPDP":
+		return FrameworkDPDPA
+	}
+	return ""
+}
+
+// Questions lists every question in order with its section ID
+func (d *Definition) Questions() []SectionQuestion {
+	var out []SectionQuestion
+	for _, s := range d.Sections {
+		for _, q := range s.Questions {
+			out = append(out, SectionQuestion{SectionID: s.ID, Question: q})
+		}
+	}
+	return out
+}
+
+// SectionQuestion is a question with the section it sits in
+type SectionQuestion struct {
+	SectionID string
+	Question
+}
+
+// Question looks up a question by ID
+func (d *Definition) Question(id string) (*Question, bool) {
+	for si := range d.Sections {
+		for qi := range d.Sections[si].Questions {
+			if d.Sections[si].Questions[qi].ID == id {
+				return &d.Sections[si].Questions[qi], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Normalize fills defaults and canonical framework names in place
+func (d *Definition) Normalize() {
+	if d.Scoring.Formula == "" {
+		d.Scoring.Formula = FormulaWeightedAverage
+	}
+	if d.Scoring.Unanswered == "" {
+		d.Scoring.Unanswered = UnansweredZero
+	}
+	for si := range d.Sections {
+		for qi := range d.Sections[si].Questions {
+			q := &d.Sections[si].Questions[qi]
+			q.ID = strings.TrimSpace(q.ID)
+			for ci := range q.Controls {
+				if f := NormalizeFramework(q.Controls[ci].Framework); f != "" {
+					q.Controls[ci].Framework = f
+				}
+				q.Controls[ci].Control = strings.TrimSpace(q.Controls[ci].Control)
+			}
+		}
+	}
+}
+
+// Validate returns every problem with the definition; none means it can be
+// published
+func (d *Definition) Validate() []string {
+	var issues []string
+	add := func(format string, args ...interface{}) { issues = append(issues, fmt.Sprintf(format, args...)) }
+
+	if len(d.Sections) == 0 {
+		add("a questionnaire needs at least one section")
+	}
+	switch d.Scoring.Formula {
+	case FormulaWeightedAverage, FormulaWeightedSum, FormulaLowestSection:
+	default:
+		add("unknown scoring formula %q", d.Scoring.Formula)
+	}
+	switch d.Scoring.Unanswered {
+	case UnansweredZero, UnansweredSkip:
+	default:
+		add("unknown unanswered policy %q", d.Scoring.Unanswered)
+	}
+	if d.Scoring.PassMark < 0 || d.Scoring.PassMark > 100 {
+		add("pass mark must be between 0 and 100")
+	}
+
+	sections := map[string]bool{}
+	seen := map[string]*Question{}
+	for si := range d.Sections {
+		s := &d.Sections[si]
+		if s.ID == "" || s.Title == "" {
+			add("section %d needs an ID and a title", si+1)
+		} else if sections[s.ID] {
+			add("section ID %q is used twice", s.ID)
+		}
+		sections[s.ID] = true
+		if len(s.Questions) == 0 {
+			add("section %q has no questions", s.ID)
+		}
+		for qi := range s.Questions {
+			q := &s.Questions[qi]
+			if q.ID == "" {
+				add("question %d in section %q needs an ID", qi+1, s.ID)
+				continue
+			}
+			if seen[q.ID] != nil {
+				add("question ID %q is used twice", q.ID)
+			}
+			if strings.TrimSpace(q.Text) == "" {
+				add("question %q has no text", q.ID)
+			}
+			if q.Weight < 0 {
+				add("question %q has a negative weight", q.ID)
+			}
+			switch q.Type {
+			case TypeYesNo:
+				for _, o := range q.Options {
+					if o.Value != "yes" && o.Value != "no" {
+						add("yes/no question %q can only score the options yes and no", q.ID)
+					}
+				}
+			case TypeChoice:
+				if len(q.Options) < 2 {
+					add("choice question %q needs at least two options", q.ID)
+				}
+				values := map[string]bool{}
+				for _, o := range q.Options {
+					if o.Value == "" || values[o.Value] {
+						add("choice question %q has a blank or repeated option", q.ID)
+					}
+					values[o.Value] = true
+				}
+			case TypeNumeric:
+				if n := q.Numeric; n != nil && n.Min != nil && n.Max != nil && *n.Min >= *n.Max {
+					add("numeric question %q has a minimum that is not below its maximum", q.ID)
+				}
+			case TypeText, TypeFile:
+			default:
+				add("question %q has unknown type %q", q.ID, q.Type)
+			}
+			for _, o := range q.Options {
+				if o.Score < 0 || o.Score > 1 {
+					add("option %q of question %q must score between 0 and 1", o.Value, q.ID)
+				}
+			}
+			for _, c := range q.Controls {
+				if NormalizeFramework(c.Framework) == "" {
+					add("question %q maps to unknown framework %q", q.ID, c.Framework)
+				}
+				if c.Control == "" {
+					add("question %q has a control mapping without a control", q.ID)
+				}
+			}
+			if c := q.ShowIf; c != nil {
+				// Only earlier questions can be referenced, which also rules out cycles
+				src := seen[c.QuestionID]
+				switch {
+				case src == nil:
+					add("question %q follows up %q, which is not an earlier question", q.ID, c.QuestionID)
+				case c.Operator == OpGreater || c.Operator == OpLess:
+					if src.Type != TypeNumeric {
+						add("question %q compares non-numeric question %q", q.ID, c.QuestionID)
+					} else if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+						add("question %q compares %q against a non-number", q.ID, c.QuestionID)
+					}
+				case c.Operator == OpIn:
+					if len(c.Values) == 0 {
+						add("question %q has an empty \"in\" condition", q.ID)
+					}
+				case c.Operator == OpEquals || c.Operator == OpNotEquals:
+				default:
+					add("question %q has unknown condition operator %q", q.ID, c.Operator)
+				}
+			}
+			seen[q.ID] = q
+		}
+	}
+	for id := range d.Scoring.SectionWeights {
+		if !sections[id] {
+			add("section weight given for unknown section %q", id)
+		}
+		if d.Scoring.SectionWeights[id] < 0 {
+			add("section %q has a negative weight", id)
+		}
+	}
+	return issues
+}
+
+// CheckAnswer reports why an answer is not acceptable for the question, or
+// "" if it is
+func (q *Question) CheckAnswer(a Answer) string {
+	v := strings.TrimSpace(a.Value)
+	if v == "na" {
+		if q.AllowNA {
+			return ""
+		}
+		return "this question cannot be answered n/a"
+	}
+	switch q.Type {
+	case TypeYesNo:
+		if v != "yes" && v != "no" {
+			return "answer yes or no"
+		}
+	case TypeChoice:
+		for _, o := range q.Options {
+			if o.Value == v {
+				return ""
+			}
+		}
+		return "answer one of the listed options"
+	case TypeNumeric:
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "answer with a number"
+		}
+	case TypeFile:
+		if !a.HasFile {
+			return "attach an evidence file"
+		}
+	case TypeText:
+		if v == "" {
+			return "answer cannot be blank"
+		}
+	}
+	return ""
+}
+
+// score returns the question's score from 0 to 1 for an answer, and whether
+// the answer counts towards the score at all
+func (q *Question) score(a Answer) (float64, bool) {
+	if q.Weight == 0 || q.Type == TypeText || strings.TrimSpace(a.Value) == "na" {
+		return 0, false
+	}
+	v := strings.TrimSpace(a.Value)
+	switch q.Type {
+	case TypeYesNo:
+		for _, o := range q.Options {
+			if o.Value == v {
+				return o.Score, true
+			}
+		}
+		if v == "yes" {
+			return 1, true
+		}
+		return 0, true
+	case TypeChoice:
+		for _, o := range q.Options {
+			if o.Value == v {
+				return o.Score, true
+			}
+		}
+		return 0, true
+	case TypeFile:
+		if a.HasFile {
+			return 1, true
+		}
+		return 0, true
+	case TypeNumeric:
+		n := q.Numeric
+		if n == nil || n.Min == nil || n.Max == nil {
+			return 0, false
+		}
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, true
+		}
+		s := (x - *n.Min) / (*n.Max - *n.Min)
+		s = math.Max(0, math.Min(1, s))
+		if !n.HigherIsBetter {
+			s = 1 - s
+		}
+		return s, true
+	}
+	return 0, false
+}
+
+// scorable reports whether the question can contribute to a score
+func (q *Question) scorable() bool {
+	if q.Weight == 0 || q.Type == TypeText {
+		return false
+	}
+	if q.Type == TypeNumeric {
+		return q.Numeric != nil && q.Numeric.Min != nil && q.Numeric.Max != nil
+	}
+	return true
+}
+
+func (c *Condition) holds(a Answer, answered bool) bool {
+	if !answered {
+		return c.Operator == OpNotEquals
+	}
+	v := strings.TrimSpace(a.Value)
+	switch c.Operator {
+	case OpEquals:
+		return v == c.Value
+	case OpNotEquals:
+		return v != c.Value
+	case OpIn:
+		for _, x := range c.Values {
+			if v == x {
+				return true
+			}
+		}
+		return false
+	case OpGreater, OpLess:
+		x, err1 := strconv.ParseFloat(v, 64)
+		y, err2 := strconv.ParseFloat(c.Value, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if c.Operator == OpGreater {
+			return x > y
+		}
+		return x < y
+	}
+	return false
+}
+
+// Visible returns the IDs of the questions that are asked given the answers
+// so far: every question without a condition, and each follow-up whose
+// condition holds on a visible earlier question
+func (d *Definition) Visible(answers map[string]Answer) map[string]bool {
+	visible := map[string]bool{}
+	for _, q := range d.Questions() {
+		if c := q.ShowIf; c != nil {
+			if !visible[c.QuestionID] {
+				continue
+			}
+			a, ok := answers[c.QuestionID]
+			if !c.holds(a, ok) {
+				continue
+			}
+		}
+		visible[q.ID] = true
+	}
+	return visible
+}
@@ -0,0 +1,171 @@
+package questionnaire
+
+import "math"
+
+// Result is a scored set of answers
+type Result struct {
+	Formula string `json:"formula"`
+	// Score is in the formula's unit: weighted points for weighted_sum, a
+	// percentage otherwise
+	Score     float64 `json:"score"`
+	Percent   float64 `json:"percent"`
+	RiskScore float64 `json:"riskScore"` // 100 - Percent
+	Passed    *bool   `json:"passed,omitempty"`
+
+	Sections        []SectionResult `json:"sections"`
+	Controls        []ControlResult `json:"controls"`
+	Visible         int             `json:"visible"`  // Questions asked, follow-ups included
+	Answered        int             `json:"answered"` // Of those, how many have an answer
+	MissingRequired []string        `json:"missingRequired"`
+	// Gaps are the scored questions that earned less than half marks
+	Gaps []string `json:"gaps"`
+}
+
+type SectionResult struct {
+	SectionID string   `json:"sectionId"`
+	Title     string   `json:"title"`
+	Points    float64  `json:"points"`
+	MaxPoints float64  `json:"maxPoints"`
+	Percent   *float64 `json:"percent,omitempty"` // Nil when nothing in the section was scored
+}
+
+// ControlResult is how well the questions mapped to one framework control
+// were answered. Gaps are questions that earned less than half marks.
+type ControlResult struct {
+	Framework string   `json:"framework"`
+	Control   string   `json:"control"`
+	Percent   *float64 `json:"percent,omitempty"`
+	Questions []string `json:"questions"`
+	Gaps      []string `json:"gaps"`
+}
+
+type tally struct {
+	points, max float64
+}
+
+func (t tally) percent() *float64 {
+	if t.max == 0 {
+		return nil
+	}
+	p := round2(t.points / t.max * 100)
+	return &p
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// Score scores answers keyed by question ID. Hidden follow-ups are ignored;
+// unanswered visible questions count as zero or are skipped according to the
+// definition's unanswered policy.
+func (d *Definition) Score(answers map[string]Answer) *Result {
+	r := &Result{
+		Formula:         d.Scoring.Formula,
+		Sections:        []SectionResult{},
+		Controls:        []ControlResult{},
+		MissingRequired: []string{},
+		Gaps:            []string{},
+	}
+	visible := d.Visible(answers)
+
+	var total tally
+	controls := map[ControlMapping]*ControlResult{}
+	controlTally := map[ControlMapping]*tally{}
+	var controlOrder []ControlMapping
+
+	for _, s := range d.Sections {
+		sw := 1.0
+		if w, ok := d.Scoring.SectionWeights[s.ID]; ok {
+			sw = w
+		}
+		var st tally
+		for i := range s.Questions {
+			q := &s.Questions[i]
+			if !visible[q.ID] {
+				continue
+			}
+			r.Visible++
+			a, answered := answers[q.ID]
+			if answered {
+				r.Answered++
+			} else if q.Required {
+				r.MissingRequired = append(r.MissingRequired, q.ID)
+			}
+			if !q.scorable() {
+				continue
+			}
+
+			var score float64
+			counts := true
+			if answered {
+				score, counts = q.score(a)
+			} else if d.Scoring.Unanswered == UnansweredSkip {
+				counts = false
+			}
+			if !counts {
+				continue
+			}
+			if score < 0.5 {
+				r.Gaps = append(r.Gaps, q.ID)
+			}
+			w := q.Weight * sw
+			st.points += w * score
+			st.max += w
+
+			for _, m := range q.Controls {
+				c, ok := controls[m]
+				if !ok {
+					c = &ControlResult{Framework: m.Framework, Control: m.Control, Questions: []string{}, Gaps: []string{}}
+					controls[m] = c
+					controlTally[m] = &tally{}
+					controlOrder = append(controlOrder, m)
+				}
+				c.Questions = append(c.Questions, q.ID)
+				if score < 0.5 {
+					c.Gaps = append(c.Gaps, q.ID)
+				}
+				controlTally[m].points += w * score
+				controlTally[m].max += w
+			}
+		}
+		r.Sections = append(r.Sections, SectionResult{
+			SectionID: s.ID,
+			Title:     s.Title,
+			Points:    round2(st.points),
+			MaxPoints: round2(st.max),
+			Percent:   st.percent(),
+		})
+		total.points += st.points
+		total.max += st.max
+	}
+	for _, m := range controlOrder {
+		c := controls[m]
+		c.Percent = controlTally[m].percent()
+		r.Controls = append(r.Controls, *c)
+	}
+
+	if p := total.percent(); p != nil {
+		r.Percent = *p
+	}
+	r.Score = r.Percent
+	switch d.Scoring.Formula {
+	case FormulaWeightedSum:
+		r.Score = round2(total.points)
+	case FormulaLowestSection:
+		lowest := -1.0
+		for _, s := range r.Sections {
+			if s.Percent != nil && (lowest < 0 || *s.Percent < lowest) {
+				lowest = *s.Percent
+			}
+		}
+		if lowest >= 0 {
+			r.Percent, r.Score = lowest, lowest
+		}
+	}
+	r.RiskScore = round2(100 - r.Percent)
+	if d.Scoring.PassMark > 0 {
+		passed := r.Percent >= d.Scoring.PassMark
+		r.Passed = &passed
+	}
+	return r
+}
@@ -0,0 +1,176 @@
+// Package blob stores uploaded files on local disk or S3, following the
+// STORAGE_TYPE configuration shared with receipts and TPRM evidence.
+package blob
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrObjectExists is returned by PutOnce when the key is already taken
+var ErrObjectExists = errors.New("object already exists")
+
+type Store struct {
+	storageType string
+	storagePath string
+	s3Bucket    string
+	s3Client    *s3.S3
+}
+
+func NewStore(
+	storageType, storagePath, s3Bucket string,
+	s3Endpoint, s3AccessKey, s3SecretKey, s3Region string,
+	useSSL, forcePathStyle bool,
+) *Store {
+	st := &Store{
+		storageType: storageType,
+		storagePath: storagePath,
+		s3Bucket:    s3Bucket,
+	}
+
+	if storageType == "s3" {
+		sess, err := session.NewSession(&aws.Config{
+			Region:           aws.String(s3Region),
+			Endpoint:         aws.String(s3Endpoint),
+			S3ForcePathStyle: aws.Bool(forcePathStyle),
+			DisableSSL:       aws.Bool(!useSSL),
+			Credentials:      credentials.NewStaticCredentials(s3AccessKey, s3SecretKey, ""),
+		})
+		if err == nil {
+			st.s3Client = s3.New(sess)
+		}
+	}
+	return st
+}
+
+// SafeName strips path components from a client-supplied file name.
+func SafeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "..", ""))
+	if name == "." || name == string(filepath.Separator) {
+		return "file"
+	}
+	return name
+}
+
+// Put writes data under key and returns the stored location (a file path for
+// local storage, the object key for S3).
+func (s *Store) Put(key, contentType string, data []byte) (string, error) {
+	if s.storageType == "local" {
+		fp := filepath.Join(s.storagePath, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(fp, data, 0o644); err != nil {
+			return "", err
+		}
+		return fp, nil
+	} else if s.storageType == "s3" && s.s3Client != nil {
+		objectKey := filepath.ToSlash(key)
+		_, err := s.s3Client.PutObject(&s3.PutObjectInput{
+			Bucket:      aws.String(s.s3Bucket),
+			Key:         aws.String(objectKey),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String(contentType),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to upload to s3: %w", err)
+		}
+		return objectKey, nil
+	}
+	return "", fmt.Errorf("unsupported storageType: %s", s.storageType)
+}
+
+// PutOnce writes data under key only if nothing is stored there yet, for
+// records that must never be overwritten. Local files are created exclusively
+// and made read-only. On S3 the upload carries a SHA-256 checksum and, when
+// retainUntil is set, a compliance-mode object lock, which needs a bucket
+// with Object Lock enabled.
+func (s *Store) PutOnce(key, contentType string, data []byte, retainUntil time.Time) (string, error) {
+	if s.storageType == "local" {
+		fp := filepath.Join(s.storagePath, filepath.FromSlash(key))
+		if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o444)
+		if err != nil {
+			if os.IsExist(err) {
+				return "", ErrObjectExists
+			}
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", err
+		}
+		return fp, nil
+	} else if s.storageType == "s3" && s.s3Client != nil {
+		objectKey := filepath.ToSlash(key)
+		if _, err := s.s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(s.s3Bucket), Key: aws.String(objectKey)}); err == nil {
+			return "", ErrObjectExists
+		}
+		sum := sha256.Sum256(data)
+		in := &s3.PutObjectInput{
+			Bucket:            aws.String(s.s3Bucket),
+			Key:               aws.String(objectKey),
+			Body:              bytes.NewReader(data),
+			ContentType:       aws.String(contentType),
+			ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmSha256),
+			ChecksumSHA256:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+		}
+		if !retainUntil.IsZero() {
+			in.ObjectLockMode = aws.String(s3.ObjectLockModeCompliance)
+			in.ObjectLockRetainUntilDate = aws.Time(retainUntil)
+		}
+		if _, err := s.s3Client.PutObject(in); err != nil {
+			return "", fmt.Errorf("failed to upload to s3: %w", err)
+		}
+		return objectKey, nil
+	}
+	return "", fmt.Errorf("unsupported storageType: %s", s.storageType)
+}
+
+// Get reads back a location returned by Put.
+func (s *Store) Get(location string) ([]byte, error) {
+	if s.storageType == "local" {
+		return os.ReadFile(location)
+	} else if s.storageType == "s3" && s.s3Client != nil {
+		out, err := s.s3Client.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(s.s3Bucket),
+			Key:    aws.String(location),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to download from s3: %w", err)
+		}
+		defer out.Body.Close()
+		return io.ReadAll(out.Body)
+	}
+	return nil, fmt.Errorf("unsupported storageType: %s", s.storageType)
+}
+
+// PresignURL returns a time-limited download URL when using S3.
+func (s *Store) PresignURL(location string, expiry time.Duration) (string, error) {
+	if s.storageType == "s3" && s.s3Client != nil {
+		req, _ := s.s3Client.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(s.s3Bucket),
+			Key:    aws.String(location),
+		})
+		return req.Presign(expiry)
+	}
+	return "", fmt.Errorf("presign not available for storageType=%s", s.storageType)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(a *models.Attachment) error {
+	return r.db.Create(a).Error
+}
+
+func (r *AttachmentRepository) GetByID(id uuid.UUID) (*models.Attachment, error) {
+	var a models.Attachment
+	err := r.db.First(&a, "id = ?", id).Error
+	return &a, err
+}
+
+// ListByParent returns a grievance's or DSR's attachments, oldest first.
+// Internal attachments are left out unless includeInternal is set.
+func (r *AttachmentRepository) ListByParent(scope string, parentID uuid.UUID, includeInternal bool) ([]models.Attachment, error) {
+	q := r.db.Where("scope = ? AND parent_id = ?", scope, parentID)
+	if !includeInternal {
+		q = q.Where("is_internal = ?", false)
+	}
+	var list []models.Attachment
+	err := q.Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *AttachmentRepository) CountByParent(scope string, parentID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.Attachment{}).Where("scope = ? AND parent_id = ?", scope, parentID).Count(&n).Error
+	return n, err
+}
+
+// GetDSRRequest loads a request including resolved (soft-deleted) ones, whose
+// evidence must stay downloadable.
+func (r *AttachmentRepository) GetDSRRequest(id uuid.UUID) (*models.DSRRequest, error) {
+	var req models.DSRRequest
+	err := r.db.Unscoped().First(&req, "id = ?", id).Error
+	return &req, err
+}
+
+func (r *AttachmentRepository) GetDSRComment(id uuid.UUID) (*models.DSRComment, error) {
+	var c models.DSRComment
+	err := r.db.First(&c, "id = ?", id).Error
+	return &c, err
+}
@@ -28,6 +28,21 @@ func (r *BreachNotificationRepository) ListBreachNotifications(tenantID uuid.UUI
 	return r.encryptedRepo.GetBreachNotificationsByTenant(tenantID)
 }
 
+// ListSimulatedBreachNotifications lists the tenant's tabletop exercises,
+// which ListBreachNotifications leaves out
+func (r *BreachNotificationRepository) ListSimulatedBreachNotifications(tenantID uuid.UUID) ([]models.BreachNotification, error) {
+	return r.encryptedRepo.GetSimulatedBreachNotificationsByTenant(tenantID)
+}
+
+// ListBreachTenantIDs returns the tenants that have recorded a breach, for
+// sweeps that run across tenants
+func (r *BreachNotificationRepository) ListBreachTenantIDs() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.EncryptedBreachNotification{}).
+		Distinct().Pluck("tenant_id", &ids).Error
+	return ids, err
+}
+
 func (r *BreachNotificationRepository) UpdateBreachNotification(notification *models.BreachNotification) error {
 	return r.encryptedRepo.UpdateBreachNotification(notification)
 }
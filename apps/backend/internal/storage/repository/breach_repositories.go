@@ -1,6 +1,12 @@
 package repository
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
 	"pixpivot/arc/internal/models"
 
 	"github.com/google/uuid"
@@ -164,8 +170,87 @@ func (r *BreachEvidenceRepository) GetByID(id uuid.UUID) (*models.BreachEvidence
 	return &evidence, err
 }
 
-func (r *BreachEvidenceRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.BreachEvidence{}, "id = ?", id).Error
+// Evidence is write-once: there is deliberately no update or delete.
+
+// BreachEvidenceCustodyRepository keeps the hash-chained custody log of
+// breach evidence
+type BreachEvidenceCustodyRepository struct {
+	db *gorm.DB
+}
+
+func NewBreachEvidenceCustodyRepository(db *gorm.DB) *BreachEvidenceCustodyRepository {
+	return &BreachEvidenceCustodyRepository{db: db}
+}
+
+// CustodyEventHash is the SHA-256 over an entry's fields and the previous
+// entry's hash
+func CustodyEventHash(e *models.BreachEvidenceCustodyEvent) string {
+	actor := ""
+	if e.ActorID != nil {
+		actor = e.ActorID.String()
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%d|%s|%s|%s|%s|%s|%s|%s|%s",
+		e.PreviousHash, e.EvidenceID, e.Sequence, e.Action, actor, e.IPAddress, e.UserAgent,
+		e.SHA256, e.Note, e.OccurredAt.UTC().Format(time.RFC3339Nano), e.ID)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Append adds an entry to the end of an item's log, numbering and chaining it
+func (r *BreachEvidenceCustodyRepository) Append(event *models.BreachEvidenceCustodyEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last models.BreachEvidenceCustodyEvent
+		err := tx.Where("evidence_id = ?", event.EvidenceID).Order("sequence DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		if event.ID == uuid.Nil {
+			event.ID = uuid.New()
+		}
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now()
+		}
+		// Stored timestamps lose precision in some databases; hash what is stored
+		event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+		event.Sequence = last.Sequence + 1
+		event.PreviousHash = last.EventHash
+		event.EventHash = CustodyEventHash(event)
+		return tx.Create(event).Error
+	})
+}
+
+// ByEvidence returns an item's log in order
+func (r *BreachEvidenceCustodyRepository) ByEvidence(evidenceID uuid.UUID) ([]models.BreachEvidenceCustodyEvent, error) {
+	var events []models.BreachEvidenceCustodyEvent
+	err := r.db.Where("evidence_id = ?", evidenceID).Order("sequence").Find(&events).Error
+	return events, err
+}
+
+// BreachSimulationRepository is the sink for notifications of simulated breaches
+type BreachSimulationRepository struct {
+	db *gorm.DB
+}
+
+func NewBreachSimulationRepository(db *gorm.DB) *BreachSimulationRepository {
+	return &BreachSimulationRepository{db: db}
+}
+
+// Capture records a notification in place of sending it
+func (r *BreachSimulationRepository) Capture(msg *models.BreachSimulationMessage) error {
+	if msg.ID == uuid.Nil {
+		msg.ID = uuid.New()
+	}
+	if msg.CapturedAt.IsZero() {
+		msg.CapturedAt = time.Now()
+	}
+	return r.db.Create(msg).Error
+}
+
+// ByBreach returns what the sink captured for a breach, oldest first
+func (r *BreachSimulationRepository) ByBreach(breachID uuid.UUID) ([]models.BreachSimulationMessage, error) {
+	var msgs []models.BreachSimulationMessage
+	err := r.db.Where("breach_id = ?", breachID).Order("captured_at").Find(&msgs).Error
+	return msgs, err
 }
 
 // BreachTimelineRepository handles audit trail
@@ -228,3 +313,253 @@ func (r *BreachNotificationTemplateRepository) Update(template *models.BreachNot
 func (r *BreachNotificationTemplateRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.BreachNotificationTemplate{}, "id = ? AND is_system_template = false", id).Error
 }
+
+// BreachDPBReportRepository handles generated Data Protection Board reports
+type BreachDPBReportRepository struct {
+	db *gorm.DB
+}
+
+func NewBreachDPBReportRepository(db *gorm.DB) *BreachDPBReportRepository {
+	return &BreachDPBReportRepository{db: db}
+}
+
+// Create stores a report as the next version of its kind for the breach
+// Create stores the report as the next version of its kind. Concurrent
+// generations can pick the same number; the loser retries with the next one.
+func (r *BreachDPBReportRepository) Create(report *models.BreachDPBReport) error {
+	var err error
+	for attempt := 0; attempt < versionInsertAttempts; attempt++ {
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			var latest int
+			if err := tx.Model(&models.BreachDPBReport{}).
+				Where("breach_id = ? AND kind = ?", report.BreachID, report.Kind).
+				Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+				return err
+			}
+			report.Version = latest + 1
+			return tx.Create(report).Error
+		})
+		if !isUniqueViolation(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// versionInsertAttempts bounds the retries of MAX(version)+1 inserts that
+// lose a race for the version's unique index
+const versionInsertAttempts = 3
+
+// isUniqueViolation reports whether err is a unique constraint violation from
+// Postgres (SQLSTATE 23505) or SQLite
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "SQLSTATE 23505") || strings.Contains(msg, "duplicate key value") || strings.Contains(msg, "UNIQUE constraint failed")
+}
+
+func (r *BreachDPBReportRepository) GetByID(id uuid.UUID) (*models.BreachDPBReport, error) {
+	var report models.BreachDPBReport
+	err := r.db.First(&report, "id = ?", id).Error
+	return &report, err
+}
+
+func (r *BreachDPBReportRepository) GetByBreachID(breachID uuid.UUID) ([]models.BreachDPBReport, error) {
+	var reports []models.BreachDPBReport
+	err := r.db.Where("breach_id = ?", breachID).Order("kind ASC, version DESC").Find(&reports).Error
+	return reports, err
+}
+
+// BreachRecipientListRepository stores frozen lists of affected Data Principals.
+// There is deliberately no update or delete.
+type BreachRecipientListRepository struct {
+	db *gorm.DB
+}
+
+func NewBreachRecipientListRepository(db *gorm.DB) *BreachRecipientListRepository {
+	return &BreachRecipientListRepository{db: db}
+}
+
+// Create stores the list and its recipients as the breach's next version
+func (r *BreachRecipientListRepository) Create(list *models.BreachRecipientList, recipients []models.BreachRecipient) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.BreachRecipientList{}).
+			Where("breach_id = ?", list.BreachID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		list.Version = latest + 1
+		if err := tx.Create(list).Error; err != nil {
+			return err
+		}
+		for i := range recipients {
+			recipients[i].ListID = list.ID
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(recipients, 500).Error
+	})
+}
+
+func (r *BreachRecipientListRepository) GetByID(id uuid.UUID) (*models.BreachRecipientList, error) {
+	var list models.BreachRecipientList
+	err := r.db.First(&list, "id = ?", id).Error
+	return &list, err
+}
+
+func (r *BreachRecipientListRepository) GetByBreachID(breachID uuid.UUID) ([]models.BreachRecipientList, error) {
+	var lists []models.BreachRecipientList
+	err := r.db.Where("breach_id = ?", breachID).Order("version DESC").Find(&lists).Error
+	return lists, err
+}
+
+// GetLatest returns the newest list for a breach
+func (r *BreachRecipientListRepository) GetLatest(breachID uuid.UUID) (*models.BreachRecipientList, error) {
+	var list models.BreachRecipientList
+	err := r.db.Where("breach_id = ?", breachID).Order("version DESC").First(&list).Error
+	return &list, err
+}
+
+// GetRecipients pages through a list's recipients; limit <= 0 returns them all
+func (r *BreachRecipientListRepository) GetRecipients(listID uuid.UUID, limit, offset int) ([]models.BreachRecipient, error) {
+	var recipients []models.BreachRecipient
+	q := r.db.Where("list_id = ?", listID).Order("data_principal_id ASC")
+	if limit > 0 {
+		q = q.Limit(limit).Offset(offset)
+	}
+	err := q.Find(&recipients).Error
+	return recipients, err
+}
+
+// BreachCampaignRepository handles notification campaigns and the queue of
+// campaign messages, which are BreachCommunication rows with a campaign ID
+type BreachCampaignRepository struct {
+	db *gorm.DB
+}
+
+func NewBreachCampaignRepository(db *gorm.DB) *BreachCampaignRepository {
+	return &BreachCampaignRepository{db: db}
+}
+
+// Create stores a campaign with its queued messages
+func (r *BreachCampaignRepository) Create(campaign *models.BreachNotificationCampaign, messages []models.BreachCommunication) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(messages, 500).Error
+	})
+}
+
+func (r *BreachCampaignRepository) GetByID(id uuid.UUID) (*models.BreachNotificationCampaign, error) {
+	var campaign models.BreachNotificationCampaign
+	err := r.db.First(&campaign, "id = ?", id).Error
+	return &campaign, err
+}
+
+func (r *BreachCampaignRepository) GetByBreachID(breachID uuid.UUID) ([]models.BreachNotificationCampaign, error) {
+	var campaigns []models.BreachNotificationCampaign
+	err := r.db.Where("breach_id = ?", breachID).Order("created_at DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+func (r *BreachCampaignRepository) Update(campaign *models.BreachNotificationCampaign) error {
+	return r.db.Save(campaign).Error
+}
+
+// DueMessages returns up to limit queued campaign messages on a channel whose
+// next attempt is due
+func (r *BreachCampaignRepository) DueMessages(channel string, now time.Time, limit int) ([]models.BreachCommunication, error) {
+	var messages []models.BreachCommunication
+	err := r.db.Where("campaign_id IS NOT NULL AND status = ? AND send_method = ? AND next_attempt_at <= ?", "queued", channel, now).
+		Order("next_attempt_at ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// Claim moves a queued message to sending; it reports false if another
+// worker got there first
+func (r *BreachCampaignRepository) Claim(id uuid.UUID) (bool, error) {
+	res := r.db.Model(&models.BreachCommunication{}).Where("id = ? AND status = ?", id, "queued").Update("status", "sending")
+	return res.RowsAffected == 1, res.Error
+}
+
+// RequeueStale returns messages left in sending by a crashed worker to the queue
+func (r *BreachCampaignRepository) RequeueStale(before time.Time) (int64, error) {
+	res := r.db.Model(&models.BreachCommunication{}).
+		Where("campaign_id IS NOT NULL AND status = ? AND updated_at < ?", "sending", before).
+		Updates(map[string]interface{}{"status": "queued", "next_attempt_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+func (r *BreachCampaignRepository) SaveMessage(message *models.BreachCommunication) error {
+	return r.db.Save(message).Error
+}
+
+// FindMessage looks a campaign message up by its ID or provider message ID
+func (r *BreachCampaignRepository) FindMessage(ref string) (*models.BreachCommunication, error) {
+	var message models.BreachCommunication
+	q := r.db.Where("campaign_id IS NOT NULL")
+	if id, err := uuid.Parse(ref); err == nil {
+		q = q.Where("id = ? OR provider_message_id = ?", id, ref)
+	} else {
+		q = q.Where("provider_message_id = ?", ref)
+	}
+	err := q.First(&message).Error
+	return &message, err
+}
+
+// Messages pages through a campaign's messages, optionally by status
+func (r *BreachCampaignRepository) Messages(campaignID uuid.UUID, status string, limit, offset int) ([]models.BreachCommunication, error) {
+	var messages []models.BreachCommunication
+	q := r.db.Where("campaign_id = ?", campaignID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	q = q.Order("created_at ASC, id ASC")
+	if limit > 0 {
+		q = q.Limit(limit).Offset(offset)
+	}
+	err := q.Find(&messages).Error
+	return messages, err
+}
+
+// StatusCounts counts a campaign's messages by status
+func (r *BreachCampaignRepository) StatusCounts(campaignID uuid.UUID) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := r.db.Model(&models.BreachCommunication{}).Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// NotifiedPrincipals counts the distinct Data Principals a breach notice has
+// reached on at least one channel
+func (r *BreachCampaignRepository) NotifiedPrincipals(breachID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.BreachCommunication{}).
+		Where("breach_id = ? AND data_principal_id IS NOT NULL AND status IN ?", breachID, []string{"sent", "delivered"}).
+		Distinct("data_principal_id").Count(&n).Error
+	return n, err
+}
+
+// CancelQueued cancels a campaign's messages that have not been sent yet
+func (r *BreachCampaignRepository) CancelQueued(campaignID uuid.UUID) (int64, error) {
+	res := r.db.Model(&models.BreachCommunication{}).
+		Where("campaign_id = ? AND status = ?", campaignID, "queued").Update("status", "cancelled")
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"testing"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// raceVersionedInsert makes the first insert into table lose a race: another
+// row with the same version is written just before it, as a concurrent
+// request would
+func raceVersionedInsert(t *testing.T, db *gorm.DB, table string, competitor func(tx *gorm.DB, version int) error) *int {
+	inserts := 0
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:race_"+table, func(tx *gorm.DB) {
+		if tx.Statement.Table != table {
+			return
+		}
+		inserts++
+		if inserts == 1 {
+			version := tx.Statement.ReflectValue.FieldByName("Version").Interface().(int)
+			require.NoError(t, competitor(tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}), version))
+		}
+	}))
+	return &inserts
+}
+
+func TestBreachDPBReportRepository_CreateRetriesVersionConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.BreachDPBReport{}))
+	repo := NewBreachDPBReportRepository(db)
+	breachID := uuid.New()
+
+	inserts := raceVersionedInsert(t, db, "breach_dpb_reports", func(tx *gorm.DB, version int) error {
+		return tx.Exec("INSERT INTO breach_dpb_reports (id, breach_id, kind, version) VALUES (?, ?, ?, ?)",
+			uuid.New(), breachID, "initial", version).Error
+	})
+
+	report := &models.BreachDPBReport{ID: uuid.New(), BreachID: breachID, Kind: "initial"}
+	require.NoError(t, repo.Create(report))
+	assert.Equal(t, 2, *inserts)
+	assert.Equal(t, 1, report.Version)
+
+	// The index itself refuses a duplicate version
+	dup := &models.BreachDPBReport{ID: uuid.New(), BreachID: breachID, Kind: "initial", Version: 1}
+	assert.True(t, isUniqueViolation(db.Create(dup).Error))
+}
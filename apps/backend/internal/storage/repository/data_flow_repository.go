@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DataFlowRepository reads the pieces of the data flow map held in the master
+// database and stores the systems declared for each purpose
+type DataFlowRepository struct {
+	db *gorm.DB
+}
+
+func NewDataFlowRepository(db *gorm.DB) *DataFlowRepository {
+	return &DataFlowRepository{db: db}
+}
+
+// DeclareSystem records that a purpose uses a system; declaring it again
+// updates the note
+func (r *DataFlowRepository) DeclareSystem(ps *models.PurposeSystem) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "purpose_id"}, {Name: "data_source_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"note", "declared_by"}),
+	}).Create(ps).Error
+}
+
+// RemoveSystem removes a declaration, reporting whether it existed
+func (r *DataFlowRepository) RemoveSystem(tenantID, purposeID, dataSourceID uuid.UUID) (bool, error) {
+	res := r.db.Where("tenant_id = ? AND purpose_id = ? AND data_source_id = ?", tenantID, purposeID, dataSourceID).
+		Delete(&models.PurposeSystem{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *DataFlowRepository) ListPurposeSystems(tenantID uuid.UUID) ([]models.PurposeSystem, error) {
+	var list []models.PurposeSystem
+	err := r.db.Where("tenant_id = ?", tenantID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *DataFlowRepository) GetDataSource(tenantID, id uuid.UUID) (*models.DataSource, error) {
+	var ds models.DataSource
+	err := r.db.First(&ds, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &ds, err
+}
+
+func (r *DataFlowRepository) ListDataSources(tenantID uuid.UUID) ([]models.DataSource, error) {
+	var list []models.DataSource
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// ListFindings returns the tenant's discovery findings, one per PII column
+func (r *DataFlowRepository) ListFindings(tenantID uuid.UUID) ([]models.DiscoveryResult, error) {
+	var list []models.DiscoveryResult
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("data_source_id, table_name, column_name").Find(&list).Error
+	return list, err
+}
+
+// ListFormPurposes returns the purposes on every consent form of the tenant
+func (r *DataFlowRepository) ListFormPurposes(tenantID uuid.UUID) ([]models.ConsentFormPurpose, error) {
+	var list []models.ConsentFormPurpose
+	err := r.db.Joins("JOIN consent_forms ON consent_forms.id = consent_form_purposes.consent_form_id").
+		Where("consent_forms.tenant_id = ?", tenantID).
+		Find(&list).Error
+	return list, err
+}
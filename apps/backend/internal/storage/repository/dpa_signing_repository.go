@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DPASigningRepository stores DPA signing ceremonies, their signers and
+// audit trail
+type DPASigningRepository struct {
+	db *gorm.DB
+}
+
+func NewDPASigningRepository(db *gorm.DB) *DPASigningRepository {
+	return &DPASigningRepository{db: db}
+}
+
+// CreateRequest stores a ceremony with its signers and opening events
+func (r *DPASigningRepository) CreateRequest(req *models.DPASigningRequest, events []models.DPASigningEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(req).Error; err != nil {
+			return err
+		}
+		if len(events) > 0 {
+			return tx.Create(&events).Error
+		}
+		return nil
+	})
+}
+
+func (r *DPASigningRepository) GetRequest(tenantID, id uuid.UUID) (*models.DPASigningRequest, error) {
+	var req models.DPASigningRequest
+	err := r.db.Preload("Signers", func(db *gorm.DB) *gorm.DB { return db.Order("party ASC, created_at ASC") }).
+		First(&req, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &req, err
+}
+
+func (r *DPASigningRepository) ListRequestsByDPA(tenantID, dpaID uuid.UUID) ([]models.DPASigningRequest, error) {
+	var list []models.DPASigningRequest
+	err := r.db.Preload("Signers", func(db *gorm.DB) *gorm.DB { return db.Order("party ASC, created_at ASC") }).
+		Where("tenant_id = ? AND dpa_id = ?", tenantID, dpaID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// HasOpenRequest reports whether the agreement has an unexpired ceremony
+// still collecting signatures, or one being sealed
+func (r *DPASigningRepository) HasOpenRequest(dpaID uuid.UUID, now time.Time) (bool, error) {
+	var n int64
+	err := r.db.Model(&models.DPASigningRequest{}).
+		Where("dpa_id = ? AND ((status = ? AND expires_at > ?) OR status = ?)", dpaID, "pending", now, "sealing").Count(&n).Error
+	return n > 0, err
+}
+
+func (r *DPASigningRepository) UpdateRequest(req *models.DPASigningRequest) error {
+	return r.db.Omit("Signers").Save(req).Error
+}
+
+// SetRequestStatus moves a ceremony from one status to another, reporting
+// whether this caller made the change
+func (r *DPASigningRepository) SetRequestStatus(id uuid.UUID, from, to string) (bool, error) {
+	res := r.db.Model(&models.DPASigningRequest{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *DPASigningRepository) GetSignerByTokenHash(hash string) (*models.DPASigner, error) {
+	var s models.DPASigner
+	err := r.db.First(&s, "token_hash = ?", hash).Error
+	return &s, err
+}
+
+func (r *DPASigningRepository) UpdateSigner(s *models.DPASigner) error {
+	return r.db.Save(s).Error
+}
+
+// RecordSignature stores a signer's signature unless they have already signed,
+// reporting whether it was stored
+func (r *DPASigningRepository) RecordSignature(s *models.DPASigner) (bool, error) {
+	res := r.db.Model(&models.DPASigner{}).Where("id = ? AND signed_at IS NULL", s.ID).Updates(map[string]interface{}{
+		"signed_at":         s.SignedAt,
+		"adopted_signature": s.AdoptedSignature,
+		"signed_ip":         s.SignedIP,
+		"signed_user_agent": s.SignedUserAgent,
+		"signed_sha256":     s.SignedSHA256,
+		"otp_hash":          "",
+		"otp_verified_at":   s.OTPVerifiedAt,
+		"updated_at":        time.Now(),
+	})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *DPASigningRepository) CountUnsigned(requestID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.DPASigner{}).Where("request_id = ? AND signed_at IS NULL", requestID).Count(&n).Error
+	return n, err
+}
+
+func (r *DPASigningRepository) AddEvent(e *models.DPASigningEvent) error {
+	return r.db.Create(e).Error
+}
+
+func (r *DPASigningRepository) ListEvents(requestID uuid.UUID) ([]models.DPASigningEvent, error) {
+	var list []models.DPASigningEvent
+	err := r.db.Where("request_id = ?", requestID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DPIARepository stores DPIA templates, assessments, their risks,
+// mitigations and history
+type DPIARepository struct {
+	db *gorm.DB
+}
+
+func NewDPIARepository(db *gorm.DB) *DPIARepository {
+	return &DPIARepository{db: db}
+}
+
+func (r *DPIARepository) CreateTemplate(t *models.DPIATemplate) error {
+	return r.db.Create(t).Error
+}
+
+func (r *DPIARepository) UpdateTemplate(t *models.DPIATemplate) error {
+	return r.db.Save(t).Error
+}
+
+func (r *DPIARepository) GetTemplate(tenantID, id uuid.UUID) (*models.DPIATemplate, error) {
+	var t models.DPIATemplate
+	err := r.db.First(&t, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &t, err
+}
+
+func (r *DPIARepository) ListTemplates(tenantID uuid.UUID) ([]models.DPIATemplate, error) {
+	var list []models.DPIATemplate
+	err := r.db.Omit("content").Where("tenant_id = ?", tenantID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// Create saves a DPIA with its first history event
+func (r *DPIARepository) Create(d *models.DPIA, ev *models.DPIAEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(d).Error; err != nil {
+			return err
+		}
+		return tx.Create(ev).Error
+	})
+}
+
+func (r *DPIARepository) Get(tenantID, id uuid.UUID) (*models.DPIA, error) {
+	var d models.DPIA
+	err := r.db.First(&d, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &d, err
+}
+
+// List returns the tenant's DPIAs, newest first, optionally by status
+func (r *DPIARepository) List(tenantID uuid.UUID, status string) ([]models.DPIA, error) {
+	q := r.db.Omit("template", "answers").Where("tenant_id = ?", tenantID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var list []models.DPIA
+	err := q.Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// Update changes the given fields of a DPIA
+func (r *DPIARepository) Update(id uuid.UUID, fields map[string]interface{}) error {
+	return r.db.Model(&models.DPIA{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Transition moves a DPIA on from one of the given statuses and records the
+// event, reporting whether the DPIA was still in one of them
+func (r *DPIARepository) Transition(id uuid.UUID, from []string, fields map[string]interface{}, ev *models.DPIAEvent) (bool, error) {
+	var ok bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.DPIA{}).Where("id = ? AND status IN ?", id, from).Updates(fields)
+		if res.Error != nil {
+			return res.Error
+		}
+		if ok = res.RowsAffected > 0; !ok {
+			return nil
+		}
+		return tx.Create(ev).Error
+	})
+	return ok, err
+}
+
+// ListReviewsDue returns approved DPIAs whose review date has passed
+func (r *DPIARepository) ListReviewsDue(now time.Time) ([]models.DPIA, error) {
+	var list []models.DPIA
+	err := r.db.Omit("template", "answers").
+		Where("status = ? AND next_review_at IS NOT NULL AND next_review_at <= ?", models.DPIAApproved, now).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *DPIARepository) CreateEvent(ev *models.DPIAEvent) error {
+	return r.db.Create(ev).Error
+}
+
+func (r *DPIARepository) ListEvents(dpiaID uuid.UUID) ([]models.DPIAEvent, error) {
+	var list []models.DPIAEvent
+	err := r.db.Where("dpia_id = ?", dpiaID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *DPIARepository) CreateRisk(risk *models.DPIARisk) error {
+	return r.db.Create(risk).Error
+}
+
+func (r *DPIARepository) SaveRisk(risk *models.DPIARisk) error {
+	return r.db.Save(risk).Error
+}
+
+func (r *DPIARepository) GetRisk(dpiaID, id uuid.UUID) (*models.DPIARisk, error) {
+	var risk models.DPIARisk
+	err := r.db.First(&risk, "dpia_id = ? AND id = ?", dpiaID, id).Error
+	return &risk, err
+}
+
+// ListRisks returns a DPIA's risks, highest score first
+func (r *DPIARepository) ListRisks(dpiaID uuid.UUID) ([]models.DPIARisk, error) {
+	var list []models.DPIARisk
+	err := r.db.Where("dpia_id = ?", dpiaID).Order("score DESC, created_at ASC").Find(&list).Error
+	return list, err
+}
+
+// DeleteRisk removes a risk and its mitigations
+func (r *DPIARepository) DeleteRisk(dpiaID, id uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("dpia_id = ? AND id = ?", dpiaID, id).Delete(&models.DPIARisk{})
+		if res.Error != nil {
+			return res.Error
+		}
+		ok = res.RowsAffected > 0
+		return tx.Where("dpia_id = ? AND risk_id = ?", dpiaID, id).Delete(&models.DPIAMitigation{}).Error
+	})
+	return ok, err
+}
+
+func (r *DPIARepository) CreateMitigation(m *models.DPIAMitigation) error {
+	return r.db.Create(m).Error
+}
+
+func (r *DPIARepository) SaveMitigation(m *models.DPIAMitigation) error {
+	return r.db.Save(m).Error
+}
+
+func (r *DPIARepository) GetMitigation(dpiaID, id uuid.UUID) (*models.DPIAMitigation, error) {
+	var m models.DPIAMitigation
+	err := r.db.First(&m, "dpia_id = ? AND id = ?", dpiaID, id).Error
+	return &m, err
+}
+
+func (r *DPIARepository) ListMitigations(dpiaID uuid.UUID) ([]models.DPIAMitigation, error) {
+	var list []models.DPIAMitigation
+	err := r.db.Where("dpia_id = ?", dpiaID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *DPIARepository) DeleteMitigation(dpiaID, id uuid.UUID) (bool, error) {
+	res := r.db.Where("dpia_id = ? AND id = ?", dpiaID, id).Delete(&models.DPIAMitigation{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListUserIDsWithPermission returns the fiduciary users in the tenant whose
+// roles grant the named permission
+func (r *DPIARepository) ListUserIDsWithPermission(tenantID uuid.UUID, permission string) ([]uuid.UUID, error) {
+	return listUserIDsWithPermission(r.db, tenantID, permission)
+}
+
+// UserNames returns the names of the tenant's fiduciary users, falling back
+// to their email
+func (r *DPIARepository) UserNames(tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	names := map[uuid.UUID]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var users []models.FiduciaryUser
+	if err := r.db.Select("id", "name", "email").Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if u.Name != "" {
+			names[u.ID] = u.Name
+		} else {
+			names[u.ID] = u.Email
+		}
+	}
+	return names, nil
+}
@@ -85,12 +85,160 @@ func (r *DSRRepository) UpdateStatus(id uuid.UUID, status string, note string) e
 }
 
 func (r *DSRRepository) AddComment(comment *models.DSRComment) error {
-	return r.MasterDB.Create(comment).Error
+	internal := comment.IsInternal
+	if err := r.MasterDB.Create(comment).Error; err != nil {
+		return err
+	}
+	// gorm swaps a false IsInternal for the column default, so set it explicitly
+	if !internal {
+		comment.IsInternal = false
+		return r.MasterDB.Model(comment).Update("is_internal", false).Error
+	}
+	return nil
 }
 
-func (r *DSRRepository) GetComments(requestID uuid.UUID) ([]models.DSRComment, error) {
+func (r *DSRRepository) GetComments(requestID uuid.UUID, includeInternal bool) ([]models.DSRComment, error) {
 	var comments []models.DSRComment
-	err := r.MasterDB.Where("request_id = ?", requestID).Order("created_at asc").Find(&comments).Error
+	q := r.MasterDB.Where("request_id = ?", requestID)
+	if !includeInternal {
+		q = q.Where("is_internal = ?", false)
+	}
+	err := q.Order("created_at asc").Find(&comments).Error
 	return comments, err
 }
 
+func (r *DSRRepository) GetByID(id uuid.UUID) (*models.DSRRequest, error) {
+	var req models.DSRRequest
+	err := r.MasterDB.First(&req, "id = ?", id).Error
+	return &req, err
+}
+
+// GetForTenant loads a request only when it belongs to the tenant
+func (r *DSRRepository) GetForTenant(id, tenantID uuid.UUID) (*models.DSRRequest, error) {
+	var req models.DSRRequest
+	err := r.MasterDB.First(&req, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &req, err
+}
+
+// ConsentFormOrganizationEntity returns the organization entity of one of the
+// tenant's consent forms, or nil when the form names none
+func (r *DSRRepository) ConsentFormOrganizationEntity(formID, tenantID uuid.UUID) (*uuid.UUID, error) {
+	var form models.ConsentForm
+	if err := r.MasterDB.Select("id", "organization_entity_id").First(&form, "id = ? AND tenant_id = ?", formID, tenantID).Error; err != nil {
+		return nil, err
+	}
+	if form.OrganizationEntityID == uuid.Nil {
+		return nil, nil
+	}
+	return &form.OrganizationEntityID, nil
+}
+
+func (r *DSRRepository) GetPrincipal(id uuid.UUID) (*models.DataPrincipal, error) {
+	var dp models.DataPrincipal
+	err := r.MasterDB.First(&dp, "id = ?", id).Error
+	return &dp, err
+}
+
+// CreateWithFieldChanges stores a rectification request together with its proposed changes.
+func (r *DSRRepository) CreateWithFieldChanges(req *models.DSRRequest, changes []models.DSRFieldChange) error {
+	return r.MasterDB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(req).Error; err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Create(&changes).Error
+	})
+}
+
+func (r *DSRRepository) GetFieldChanges(requestID uuid.UUID) ([]models.DSRFieldChange, error) {
+	var changes []models.DSRFieldChange
+	err := r.MasterDB.Where("request_id = ?", requestID).Order("created_at asc").Find(&changes).Error
+	return changes, err
+}
+
+func (r *DSRRepository) GetFieldChange(id, requestID uuid.UUID) (*models.DSRFieldChange, error) {
+	var change models.DSRFieldChange
+	err := r.MasterDB.First(&change, "id = ? AND request_id = ?", id, requestID).Error
+	return &change, err
+}
+
+func (r *DSRRepository) UpdateFieldChange(change *models.DSRFieldChange) error {
+	return r.MasterDB.Save(change).Error
+}
+
+// ApplyRectification writes the approved field values to the principal record, marks
+// the changes applied and closes the request, all in one transaction.
+func (r *DSRRepository) ApplyRectification(req *models.DSRRequest, updates map[string]interface{}, applied []models.DSRFieldChange) error {
+	return r.MasterDB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			updates["updated_at"] = time.Now()
+			res := tx.Model(&models.DataPrincipal{}).Where("id = ?", req.UserID).Updates(updates)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		for i := range applied {
+			if err := tx.Save(&applied[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(req).Error
+	})
+}
+
+// Tracking Methods
+
+func (r *DSRRepository) GetByTrackingRef(ref string) (*models.DSRRequest, error) {
+	var req models.DSRRequest
+	err := r.MasterDB.First(&req, "tracking_ref = ?", ref).Error
+	return &req, err
+}
+
+// ClaimStatusLinkResend records a status link resend at now unless one was
+// already resent after the cutoff, and reports whether it did
+func (r *DSRRepository) ClaimStatusLinkResend(id uuid.UUID, cutoff, now time.Time) (bool, error) {
+	res := r.MasterDB.Model(&models.DSRRequest{}).
+		Where("id = ? AND (status_link_resent_at IS NULL OR status_link_resent_at < ?)", id, cutoff).
+		Update("status_link_resent_at", now)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *DSRRepository) Save(req *models.DSRRequest) error {
+	return r.MasterDB.Save(req).Error
+}
+
+func (r *DSRRepository) AddEvent(event *models.DSREvent) error {
+	return r.MasterDB.Create(event).Error
+}
+
+// ListEvents returns the request timeline, oldest first.
+func (r *DSRRepository) ListEvents(requestID uuid.UUID, publicOnly bool) ([]models.DSREvent, error) {
+	var events []models.DSREvent
+	q := r.MasterDB.Where("request_id = ?", requestID)
+	if publicOnly {
+		q = q.Where("public = ?", true)
+	}
+	err := q.Order("created_at asc").Find(&events).Error
+	return events, err
+}
+
+func (r *DSRRepository) AddClarification(c *models.DSRClarification) error {
+	return r.MasterDB.Create(c).Error
+}
+
+func (r *DSRRepository) ListClarifications(requestID uuid.UUID) ([]models.DSRClarification, error) {
+	var items []models.DSRClarification
+	err := r.MasterDB.Where("request_id = ?", requestID).Order("created_at asc").Find(&items).Error
+	return items, err
+}
+
+func (r *DSRRepository) GetClarification(id, requestID uuid.UUID) (*models.DSRClarification, error) {
+	var c models.DSRClarification
+	err := r.MasterDB.First(&c, "id = ? AND request_id = ?", id, requestID).Error
+	return &c, err
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// closedDSRStatuses are the statuses that take a request out of the working queue.
+var closedDSRStatuses = []string{"Completed", "Rejected", "completed", "rejected"}
+
+// DSRQueueFilter narrows a queue listing. Zero values are ignored.
+type DSRQueueFilter struct {
+	TenantID    uuid.UUID
+	Status      string
+	Type        string
+	SubjectType string
+	Priority    string
+	AssignedTo  *uuid.UUID
+	Unassigned  bool
+	OpenOnly    bool
+	DueBefore   *time.Time
+}
+
+type DSRRoutingRepository struct {
+	db *gorm.DB
+}
+
+func NewDSRRoutingRepository(db *gorm.DB) *DSRRoutingRepository {
+	return &DSRRoutingRepository{db: db}
+}
+
+// Rule Methods
+
+func (r *DSRRoutingRepository) CreateRule(rule *models.DSRRoutingRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *DSRRoutingRepository) GetRule(id, tenantID uuid.UUID) (*models.DSRRoutingRule, error) {
+	var rule models.DSRRoutingRule
+	err := r.db.First(&rule, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &rule, err
+}
+
+func (r *DSRRoutingRepository) ListRules(tenantID uuid.UUID) ([]models.DSRRoutingRule, error) {
+	var rules []models.DSRRoutingRule
+	err := r.db.Where("tenant_id = ?", tenantID).Order("position ASC, created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *DSRRoutingRepository) ListActiveRules(tenantID uuid.UUID) ([]models.DSRRoutingRule, error) {
+	var rules []models.DSRRoutingRule
+	err := r.db.Where("tenant_id = ? AND is_active = ?", tenantID, true).Order("position ASC, created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// ListEscalatingRules returns active rules, across tenants, that name a supervisor role.
+func (r *DSRRoutingRepository) ListEscalatingRules() ([]models.DSRRoutingRule, error) {
+	var rules []models.DSRRoutingRule
+	err := r.db.Where("is_active = ? AND escalation_role <> ''", true).Find(&rules).Error
+	return rules, err
+}
+
+func (r *DSRRoutingRepository) UpdateRule(rule *models.DSRRoutingRule) error {
+	return r.db.Where("id = ? AND tenant_id = ?", rule.ID, rule.TenantID).Save(rule).Error
+}
+
+func (r *DSRRoutingRepository) DeleteRule(id, tenantID uuid.UUID) error {
+	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.DSRRoutingRule{}).Error
+}
+
+// Assignment Methods
+
+// ListUserIDsWithRole returns the fiduciary users in the tenant holding the named role.
+func (r *DSRRoutingRepository) ListUserIDsWithRole(tenantID uuid.UUID, roleName string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Table("fiduciary_users").
+		Select("DISTINCT fiduciary_users.id").
+		Joins("JOIN fiduciary_user_roles ON fiduciary_user_roles.fiduciary_user_id = fiduciary_users.id").
+		Joins("JOIN roles ON roles.id = fiduciary_user_roles.role_id").
+		Where("fiduciary_users.tenant_id = ? AND roles.name = ?", tenantID, roleName).
+		Order("fiduciary_users.id").
+		Pluck("fiduciary_users.id", &ids).Error
+	return ids, err
+}
+
+// CountOpenAssignments returns the number of open requests assigned to each user.
+func (r *DSRRoutingRepository) CountOpenAssignments(userIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		AssignedTo uuid.UUID
+		Total      int64
+	}
+	err := r.db.Model(&models.DSRRequest{}).
+		Select("assigned_to, COUNT(*) AS total").
+		Where("assigned_to IN ? AND status NOT IN ?", userIDs, closedDSRStatuses).
+		Group("assigned_to").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.AssignedTo] = row.Total
+	}
+	return counts, err
+}
+
+// CountOpenEscalations returns the number of open requests escalated to each user.
+func (r *DSRRoutingRepository) CountOpenEscalations(userIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		EscalatedTo uuid.UUID
+		Total       int64
+	}
+	err := r.db.Model(&models.DSRRequest{}).
+		Select("escalated_to, COUNT(*) AS total").
+		Where("escalated_to IN ? AND status NOT IN ?", userIDs, closedDSRStatuses).
+		Group("escalated_to").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.EscalatedTo] = row.Total
+	}
+	return counts, err
+}
+
+// AdvanceRoundRobinCursor moves the rule's cursor from prev to next. It
+// reports false if another assignment moved the cursor first.
+func (r *DSRRoutingRepository) AdvanceRoundRobinCursor(ruleID uuid.UUID, prev *uuid.UUID, next uuid.UUID) (bool, error) {
+	q := r.db.Model(&models.DSRRoutingRule{}).Where("id = ?", ruleID)
+	if prev == nil {
+		q = q.Where("last_assigned_user_id IS NULL")
+	} else {
+		q = q.Where("last_assigned_user_id = ?", *prev)
+	}
+	res := q.Update("last_assigned_user_id", next)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RoundRobinCursor returns the user the rule last assigned, if any
+func (r *DSRRoutingRepository) RoundRobinCursor(ruleID uuid.UUID) (*uuid.UUID, error) {
+	var rule models.DSRRoutingRule
+	err := r.db.Select("id", "last_assigned_user_id").First(&rule, "id = ?", ruleID).Error
+	return rule.LastAssignedUserID, err
+}
+
+// Queue Methods
+
+func (r *DSRRoutingRepository) ListQueue(f DSRQueueFilter, limit, offset int) ([]models.DSRRequest, int64, error) {
+	q := r.db.Model(&models.DSRRequest{}).Where("tenant_id = ?", f.TenantID)
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.OpenOnly {
+		q = q.Where("status NOT IN ?", closedDSRStatuses)
+	}
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.SubjectType != "" {
+		q = q.Where("subject_type = ?", f.SubjectType)
+	}
+	if f.Priority != "" {
+		q = q.Where("priority = ?", f.Priority)
+	}
+	if f.AssignedTo != nil {
+		q = q.Where("assigned_to = ?", *f.AssignedTo)
+	} else if f.Unassigned {
+		q = q.Where("assigned_to IS NULL")
+	}
+	if f.DueBefore != nil {
+		q = q.Where("due_date <= ?", *f.DueBefore)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var requests []models.DSRRequest
+	err := q.Order("due_date ASC").Limit(limit).Offset(offset).Find(&requests).Error
+	return requests, total, err
+}
+
+// BulkAssign reassigns the given requests within the tenant and returns how many were updated.
+func (r *DSRRoutingRepository) BulkAssign(tenantID uuid.UUID, requestIDs []uuid.UUID, assignee uuid.UUID) (int64, error) {
+	res := r.db.Model(&models.DSRRequest{}).
+		Where("tenant_id = ? AND id IN ?", tenantID, requestIDs).
+		Updates(map[string]interface{}{"assigned_to": assignee, "updated_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// ListDueForEscalation returns open, not yet escalated requests routed by the rule
+// whose due date falls on or before the cutoff.
+func (r *DSRRoutingRepository) ListDueForEscalation(ruleID uuid.UUID, cutoff time.Time) ([]models.DSRRequest, error) {
+	var requests []models.DSRRequest
+	err := r.db.Where("routing_rule_id = ? AND escalated_at IS NULL AND due_date <= ? AND status NOT IN ?", ruleID, cutoff, closedDSRStatuses).
+		Find(&requests).Error
+	return requests, err
+}
+
+func (r *DSRRoutingRepository) MarkEscalated(requestID, escalatedTo uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.DSRRequest{}).Where("id = ?", requestID).Updates(map[string]interface{}{
+		"escalated_at": at,
+		"escalated_to": escalatedTo,
+		"priority":     "critical",
+		"updated_at":   at,
+	}).Error
+}
+
+// IsTenantUser reports whether the fiduciary user belongs to the tenant.
+func (r *DSRRoutingRepository) IsTenantUser(userID, tenantID uuid.UUID) bool {
+	var count int64
+	r.db.Model(&models.FiduciaryUser{}).Where("id = ? AND tenant_id = ?", userID, tenantID).Count(&count)
+	return count > 0
+}
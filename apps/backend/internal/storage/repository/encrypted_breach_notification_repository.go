@@ -61,9 +61,20 @@ func (r *EncryptedBreachNotificationRepository) ListBreachNotifications() ([]mod
 	return breaches, nil
 }
 
+// GetBreachNotificationsByTenant lists a tenant's real breaches; simulations
+// are listed separately
 func (r *EncryptedBreachNotificationRepository) GetBreachNotificationsByTenant(tenantID uuid.UUID) ([]models.BreachNotification, error) {
+	return r.listByTenant(tenantID, false)
+}
+
+// GetSimulatedBreachNotificationsByTenant lists a tenant's tabletop exercises
+func (r *EncryptedBreachNotificationRepository) GetSimulatedBreachNotificationsByTenant(tenantID uuid.UUID) ([]models.BreachNotification, error) {
+	return r.listByTenant(tenantID, true)
+}
+
+func (r *EncryptedBreachNotificationRepository) listByTenant(tenantID uuid.UUID, simulated bool) ([]models.BreachNotification, error) {
 	var encryptedBreaches []models.EncryptedBreachNotification
-	if err := r.db.Where("tenant_id = ?", tenantID).Find(&encryptedBreaches).Error; err != nil {
+	if err := r.db.Where("tenant_id = ? AND is_simulation = ?", tenantID, simulated).Find(&encryptedBreaches).Error; err != nil {
 		return nil, err
 	}
 
@@ -100,9 +111,47 @@ func (r *EncryptedBreachNotificationRepository) encryptBreachNotification(breach
 		ComplianceStatus:     breach.ComplianceStatus,
 		CreatedAt:            breach.CreatedAt,
 		UpdatedAt:            breach.UpdatedAt,
+
+		ContainmentDate:                   breach.ContainmentDate,
+		DPBNotificationDeadline:           breach.DPBNotificationDeadline,
+		DataCategoriesAffected:            breach.DataCategoriesAffected,
+		RequiresDataPrincipalNotification: breach.RequiresDataPrincipalNotification,
+		DataPrincipalNotificationSentAt:   breach.DataPrincipalNotificationSentAt,
+		LikelihoodOfHarm:                  breach.LikelihoodOfHarm,
+
+		DataPrincipalNotificationApproved:   breach.DataPrincipalNotificationApproved,
+		DataPrincipalNotificationApprovedBy: breach.DataPrincipalNotificationApprovedBy,
+		DataPrincipalNotificationApprovedAt: breach.DataPrincipalNotificationApprovedAt,
+		DataPrincipalNotificationDeadline:   breach.DataPrincipalNotificationDeadline,
+		CurrentWorkflowStage:                breach.CurrentWorkflowStage,
+		VerifiedBy:                          breach.VerifiedBy,
+		VerifiedAt:                          breach.VerifiedAt,
+
+		RequiresCERTInReporting: breach.RequiresCERTInReporting,
+		CERTInIncidentType:      breach.CERTInIncidentType,
+		CERTInDeadline:          breach.CERTInDeadline,
+		CERTInStatus:            breach.CERTInStatus,
+		CERTInReportedAt:        breach.CERTInReportedAt,
+		CERTInReference:         breach.CERTInReference,
+		CERTInDetails:           breach.CERTInDetails,
+
+		IsSimulation:          breach.IsSimulation,
+		SimulationCompletedAt: breach.SimulationCompletedAt,
 	}
 
 	// Encrypt sensitive fields
+	if err := r.encryptStringField(breach.Title, &encryptedBreach.Title); err != nil {
+		return nil, err
+	}
+
+	if err := r.encryptStringField(breach.ImpactOnDataPrincipals, &encryptedBreach.ImpactOnDataPrincipals); err != nil {
+		return nil, err
+	}
+
+	if err := r.encryptStringFieldPtr(breach.RootCause, &encryptedBreach.RootCause); err != nil {
+		return nil, err
+	}
+
 	if err := r.encryptStringField(breach.Description, &encryptedBreach.Description); err != nil {
 		return nil, err
 	}
@@ -144,10 +193,48 @@ func (r *EncryptedBreachNotificationRepository) decryptBreachNotification(encryp
 		ComplianceStatus:     encryptedBreach.ComplianceStatus,
 		CreatedAt:            encryptedBreach.CreatedAt,
 		UpdatedAt:            encryptedBreach.UpdatedAt,
+
+		ContainmentDate:                   encryptedBreach.ContainmentDate,
+		DPBNotificationDeadline:           encryptedBreach.DPBNotificationDeadline,
+		DataCategoriesAffected:            encryptedBreach.DataCategoriesAffected,
+		RequiresDataPrincipalNotification: encryptedBreach.RequiresDataPrincipalNotification,
+		DataPrincipalNotificationSentAt:   encryptedBreach.DataPrincipalNotificationSentAt,
+		LikelihoodOfHarm:                  encryptedBreach.LikelihoodOfHarm,
+
+		DataPrincipalNotificationApproved:   encryptedBreach.DataPrincipalNotificationApproved,
+		DataPrincipalNotificationApprovedBy: encryptedBreach.DataPrincipalNotificationApprovedBy,
+		DataPrincipalNotificationApprovedAt: encryptedBreach.DataPrincipalNotificationApprovedAt,
+		DataPrincipalNotificationDeadline:   encryptedBreach.DataPrincipalNotificationDeadline,
+		CurrentWorkflowStage:                encryptedBreach.CurrentWorkflowStage,
+		VerifiedBy:                          encryptedBreach.VerifiedBy,
+		VerifiedAt:                          encryptedBreach.VerifiedAt,
+
+		RequiresCERTInReporting: encryptedBreach.RequiresCERTInReporting,
+		CERTInIncidentType:      encryptedBreach.CERTInIncidentType,
+		CERTInDeadline:          encryptedBreach.CERTInDeadline,
+		CERTInStatus:            encryptedBreach.CERTInStatus,
+		CERTInReportedAt:        encryptedBreach.CERTInReportedAt,
+		CERTInReference:         encryptedBreach.CERTInReference,
+		CERTInDetails:           encryptedBreach.CERTInDetails,
+
+		IsSimulation:          encryptedBreach.IsSimulation,
+		SimulationCompletedAt: encryptedBreach.SimulationCompletedAt,
 	}
 
 	// Decrypt sensitive fields
 	var err error
+	if breach.Title, err = r.decryptStringField(encryptedBreach.Title); err != nil {
+		return nil, err
+	}
+
+	if breach.ImpactOnDataPrincipals, err = r.decryptStringField(encryptedBreach.ImpactOnDataPrincipals); err != nil {
+		return nil, err
+	}
+
+	if breach.RootCause, err = r.decryptStringFieldPtr(encryptedBreach.RootCause); err != nil {
+		return nil, err
+	}
+
 	if breach.Description, err = r.decryptStringField(encryptedBreach.Description); err != nil {
 		return nil, err
 	}
@@ -65,6 +65,26 @@ func (r *GrievanceRepository) GetByID(ctx context.Context, grievanceID string) (
 	return &grievance, nil
 }
 
+// Get a grievance by the thread token quoted in email subjects
+func (r *GrievanceRepository) GetByThreadToken(ctx context.Context, token string) (*models.Grievance, error) {
+	var grievance models.Grievance
+	if err := r.db.WithContext(ctx).
+		Where("thread_token = ?", token).
+		First(&grievance).Error; err != nil {
+		return nil, err
+	}
+	return &grievance, nil
+}
+
+// GetPrincipalEmail looks up a principal's email in the tenant database
+func (r *GrievanceRepository) GetPrincipalEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	var principal models.DataPrincipal
+	if err := r.db.WithContext(ctx).Select("email").First(&principal, "id = ?", userID).Error; err != nil {
+		return "", err
+	}
+	return principal.Email, nil
+}
+
 // List grievances for a tenant, optionally filter by status
 func (r *GrievanceRepository) List(ctx context.Context, tenantID string, status *string) ([]models.Grievance, error) {
 	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
@@ -161,6 +181,15 @@ func (r *GrievanceRepository) ListComments(ctx context.Context, grievanceID stri
 	return comments, nil
 }
 
+// Get a single comment by ID
+func (r *GrievanceRepository) GetComment(ctx context.Context, commentID string) (*models.GrievanceComment, error) {
+	var comment models.GrievanceComment
+	if err := r.db.WithContext(ctx).First(&comment, "id = ?", commentID).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
 // Delete a specific comment
 func (r *GrievanceRepository) DeleteComment(ctx context.Context, commentID string) error {
 	if err := r.db.WithContext(ctx).
@@ -172,3 +201,130 @@ func (r *GrievanceRepository) DeleteComment(ctx context.Context, commentID strin
 	return nil
 }
 
+
+// ===================== SLA & Escalation =====================
+
+// closedGrievanceStatuses stop the SLA clock.
+var closedGrievanceStatuses = []string{"resolved", "closed"}
+
+func (r *GrievanceRepository) Save(ctx context.Context, g *models.Grievance) error {
+	return r.db.WithContext(ctx).Save(g).Error
+}
+
+func (r *GrievanceRepository) CreateSLAPolicy(ctx context.Context, p *models.GrievanceSLAPolicy) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+func (r *GrievanceRepository) GetSLAPolicy(ctx context.Context, id, tenantID uuid.UUID) (*models.GrievanceSLAPolicy, error) {
+	var p models.GrievanceSLAPolicy
+	err := r.db.WithContext(ctx).First(&p, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &p, err
+}
+
+func (r *GrievanceRepository) ListSLAPolicies(ctx context.Context, tenantID uuid.UUID, activeOnly bool) ([]models.GrievanceSLAPolicy, error) {
+	var policies []models.GrievanceSLAPolicy
+	q := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if activeOnly {
+		q = q.Where("is_active = ?", true)
+	}
+	err := q.Order("created_at ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *GrievanceRepository) UpdateSLAPolicy(ctx context.Context, p *models.GrievanceSLAPolicy) error {
+	return r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", p.ID, p.TenantID).Save(p).Error
+}
+
+func (r *GrievanceRepository) DeleteSLAPolicy(ctx context.Context, id, tenantID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.GrievanceSLAPolicy{}).Error
+}
+
+// ListSLABreaches returns open grievances whose response or resolution deadline
+// has passed without the breach having been recorded yet.
+func (r *GrievanceRepository) ListSLABreaches(ctx context.Context, now time.Time) ([]models.Grievance, error) {
+	var grievances []models.Grievance
+	err := r.db.WithContext(ctx).
+		Where("status NOT IN ?", closedGrievanceStatuses).
+		Where("((response_due_at <= ? AND first_responded_at IS NULL AND response_breached_at IS NULL) OR (resolution_due_at <= ? AND resolved_at IS NULL AND resolution_breached_at IS NULL))", now, now).
+		Find(&grievances).Error
+	return grievances, err
+}
+
+func (r *GrievanceRepository) CreateEscalation(ctx context.Context, e *models.GrievanceEscalation) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+func (r *GrievanceRepository) ListEscalations(ctx context.Context, grievanceID string) ([]models.GrievanceEscalation, error) {
+	var escalations []models.GrievanceEscalation
+	err := r.db.WithContext(ctx).Where("grievance_id = ?", grievanceID).Order("created_at ASC").Find(&escalations).Error
+	return escalations, err
+}
+
+// ===================== Triage =====================
+
+// CountForUserSince counts a principal's grievances raised since the given time
+func (r *GrievanceRepository) CountForUserSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&models.Grievance{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *GrievanceRepository) CreateTriageRule(ctx context.Context, rule *models.GrievanceTriageRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *GrievanceRepository) GetTriageRule(ctx context.Context, id, tenantID uuid.UUID) (*models.GrievanceTriageRule, error) {
+	var rule models.GrievanceTriageRule
+	err := r.db.WithContext(ctx).First(&rule, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &rule, err
+}
+
+func (r *GrievanceRepository) ListTriageRules(ctx context.Context, tenantID uuid.UUID, activeOnly bool) ([]models.GrievanceTriageRule, error) {
+	var rules []models.GrievanceTriageRule
+	q := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if activeOnly {
+		q = q.Where("is_active = ?", true)
+	}
+	err := q.Order("position ASC, created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *GrievanceRepository) UpdateTriageRule(ctx context.Context, rule *models.GrievanceTriageRule) error {
+	return r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", rule.ID, rule.TenantID).Save(rule).Error
+}
+
+func (r *GrievanceRepository) DeleteTriageRule(ctx context.Context, id, tenantID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.GrievanceTriageRule{}).Error
+}
+
+func (r *GrievanceRepository) CreateTriageDecision(ctx context.Context, d *models.GrievanceTriageDecision) error {
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+func (r *GrievanceRepository) GetTriageDecision(ctx context.Context, grievanceID uuid.UUID) (*models.GrievanceTriageDecision, error) {
+	var d models.GrievanceTriageDecision
+	err := r.db.WithContext(ctx).First(&d, "grievance_id = ?", grievanceID).Error
+	return &d, err
+}
+
+func (r *GrievanceRepository) ListTriageDecisions(ctx context.Context, tenantID uuid.UUID, since time.Time) ([]models.GrievanceTriageDecision, error) {
+	var decisions []models.GrievanceTriageDecision
+	err := r.db.WithContext(ctx).Where("tenant_id = ? AND created_at >= ?", tenantID, since).Find(&decisions).Error
+	return decisions, err
+}
+
+func (r *GrievanceRepository) CreateTriageOverride(ctx context.Context, o *models.GrievanceTriageOverride) error {
+	return r.db.WithContext(ctx).Create(o).Error
+}
+
+func (r *GrievanceRepository) ListTriageOverrides(ctx context.Context, tenantID uuid.UUID, grievanceID *uuid.UUID, since time.Time) ([]models.GrievanceTriageOverride, error) {
+	var overrides []models.GrievanceTriageOverride
+	q := r.db.WithContext(ctx).Where("tenant_id = ? AND created_at >= ?", tenantID, since)
+	if grievanceID != nil {
+		q = q.Where("grievance_id = ?", *grievanceID)
+	}
+	err := q.Order("created_at ASC").Find(&overrides).Error
+	return overrides, err
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobFilter narrows a job listing. Zero values are ignored.
+type JobFilter struct {
+	Status   string
+	Type     string
+	TenantID *uuid.UUID
+	Limit    int
+}
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Enqueue inserts a job. A job whose UniqueKey belongs to any stored job,
+// finished or not, is skipped and false is returned.
+func (r *JobRepository) Enqueue(job *models.Job) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	return res.RowsAffected == 1, res.Error
+}
+
+// Claim locks the oldest due pending job of one of the given types and marks
+// it running for the worker. SKIP LOCKED lets concurrent workers on other
+// replicas pass over a row being claimed instead of waiting on it. It returns
+// nil when nothing is due.
+func (r *JobRepository) Claim(worker string, types []string, now time.Time) (*models.Job, error) {
+	var claimed *models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var due []models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND type IN ?", models.JobStatusPending, now, types).
+			Order("run_at ASC").
+			Limit(1).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		job := due[0]
+
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.LockedBy = worker
+		job.LockedAt = &now
+		job.StartedAt = &now
+		res := tx.Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobStatusPending).
+			Updates(map[string]interface{}{
+				"status":     job.Status,
+				"attempts":   job.Attempts,
+				"locked_by":  worker,
+				"locked_at":  now,
+				"started_at": now,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+// finish moves a running job on. A job cancelled while it ran stays cancelled.
+func (r *JobRepository) finish(id uuid.UUID, updates map[string]interface{}) error {
+	updates["locked_by"] = ""
+	updates["locked_at"] = nil
+	return r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusRunning).
+		Updates(updates).Error
+}
+
+// Complete marks a running job succeeded
+func (r *JobRepository) Complete(id uuid.UUID, now time.Time) error {
+	return r.finish(id, map[string]interface{}{
+		"status":       models.JobStatusSucceeded,
+		"completed_at": now,
+		"last_error":   "",
+	})
+}
+
+// Reschedule puts a failed attempt back in the queue to run again at runAt
+func (r *JobRepository) Reschedule(id uuid.UUID, lastError string, runAt time.Time) error {
+	return r.finish(id, map[string]interface{}{
+		"status":     models.JobStatusPending,
+		"run_at":     runAt,
+		"last_error": lastError,
+	})
+}
+
+// Fail marks a running job failed for good
+func (r *JobRepository) Fail(id uuid.UUID, lastError string, now time.Time) error {
+	return r.finish(id, map[string]interface{}{
+		"status":       models.JobStatusFailed,
+		"completed_at": now,
+		"last_error":   lastError,
+	})
+}
+
+// Heartbeat refreshes the lock on a job the worker is still running
+func (r *JobRepository) Heartbeat(id uuid.UUID, worker string, now time.Time) error {
+	return r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobStatusRunning, worker).
+		Update("locked_at", now).Error
+}
+
+// RequeueStale returns running jobs locked before the cutoff to the queue.
+// Their worker is presumed dead; the attempt it used still counts, so a job
+// that has used its last attempt is failed instead of being run again.
+func (r *JobRepository) RequeueStale(lockedBefore, now time.Time) (requeued, failed int64, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		stale := func() *gorm.DB {
+			return tx.Model(&models.Job{}).Where("status = ? AND locked_at < ?", models.JobStatusRunning, lockedBefore)
+		}
+		res := stale().Where("attempts >= max_attempts").
+			Updates(map[string]interface{}{
+				"status":       models.JobStatusFailed,
+				"completed_at": now,
+				"locked_by":    "",
+				"locked_at":    nil,
+				"last_error":   "worker lost while running; no attempts left",
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		failed = res.RowsAffected
+		res = stale().
+			Updates(map[string]interface{}{
+				"status":     models.JobStatusPending,
+				"run_at":     now,
+				"locked_by":  "",
+				"locked_at":  nil,
+				"last_error": "worker lost while running",
+			})
+		requeued = res.RowsAffected
+		return res.Error
+	})
+	return requeued, failed, err
+}
+
+// PurgeSucceeded deletes succeeded jobs completed before the cutoff
+func (r *JobRepository) PurgeSucceeded(completedBefore time.Time) (int64, error) {
+	res := r.db.Where("status = ? AND completed_at < ?", models.JobStatusSucceeded, completedBefore).
+		Delete(&models.Job{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *JobRepository) GetByID(id uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	err := r.db.First(&job, "id = ?", id).Error
+	return &job, err
+}
+
+// List returns jobs, newest first
+func (r *JobRepository) List(filter JobFilter) ([]models.Job, error) {
+	q := r.db.Model(&models.Job{})
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+	if filter.TenantID != nil {
+		q = q.Where("tenant_id = ?", *filter.TenantID)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	var jobs []models.Job
+	err := q.Order("created_at DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// Retry requeues a failed or cancelled job with a fresh set of attempts.
+// It reports false if the job was in any other state.
+func (r *JobRepository) Retry(id uuid.UUID, now time.Time) (bool, error) {
+	res := r.db.Model(&models.Job{}).
+		Where("id = ? AND status IN ?", id, []string{models.JobStatusFailed, models.JobStatusCancelled}).
+		Updates(map[string]interface{}{
+			"status":       models.JobStatusPending,
+			"run_at":       now,
+			"attempts":     0,
+			"completed_at": nil,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// Cancel stops a pending or running job from being run again. A running
+// handler is not interrupted, but its outcome is discarded. It reports false
+// if the job had already finished.
+func (r *JobRepository) Cancel(id uuid.UUID, now time.Time) (bool, error) {
+	res := r.db.Model(&models.Job{}).
+		Where("id = ? AND status IN ?", id, []string{models.JobStatusPending, models.JobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":       models.JobStatusCancelled,
+			"completed_at": now,
+			"locked_by":    "",
+			"locked_at":    nil,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// AcquireLease takes or renews the named lease for holder until now+ttl. It
+// succeeds if the lease is free, expired or already held by holder.
+func (r *JobRepository) AcquireLease(name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	expires := now.Add(ttl)
+	res := r.db.Model(&models.JobLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expires})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return true, nil
+	}
+	res = r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.JobLease{Name: name, Holder: holder, ExpiresAt: expires})
+	return res.RowsAffected == 1, res.Error
+}
+
+// ReleaseLease gives up the named lease if holder has it
+func (r *JobRepository) ReleaseLease(name, holder string) error {
+	return r.db.Where("name = ? AND holder = ?", name, holder).Delete(&models.JobLease{}).Error
+}
+
+// GetLease returns the named lease, or nil if nobody has taken it
+func (r *JobRepository) GetLease(name string) (*models.JobLease, error) {
+	var lease models.JobLease
+	err := r.db.First(&lease, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &lease, err
+}
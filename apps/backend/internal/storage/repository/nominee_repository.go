@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NomineeRepository struct {
+	db *gorm.DB
+}
+
+func NewNomineeRepository(db *gorm.DB) *NomineeRepository {
+	return &NomineeRepository{db: db}
+}
+
+// Nominee Methods
+
+func (r *NomineeRepository) CreateNominee(n *models.Nominee) error {
+	return r.db.Create(n).Error
+}
+
+func (r *NomineeRepository) GetNominee(id, tenantID uuid.UUID) (*models.Nominee, error) {
+	var n models.Nominee
+	err := r.db.First(&n, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &n, err
+}
+
+func (r *NomineeRepository) GetNomineeByToken(token string) (*models.Nominee, error) {
+	var n models.Nominee
+	err := r.db.First(&n, "verification_token = ?", token).Error
+	return &n, err
+}
+
+func (r *NomineeRepository) ListNomineesByPrincipal(principalID, tenantID uuid.UUID) ([]models.Nominee, error) {
+	var nominees []models.Nominee
+	err := r.db.Where("principal_id = ? AND tenant_id = ? AND status <> ?", principalID, tenantID, "revoked").
+		Order("created_at ASC").Find(&nominees).Error
+	return nominees, err
+}
+
+// ListNominationsByEmail returns nominee records naming the given email, i.e. the
+// principals the caller has been nominated by.
+func (r *NomineeRepository) ListNominationsByEmail(email string, tenantID uuid.UUID) ([]models.Nominee, error) {
+	var nominees []models.Nominee
+	err := r.db.Where("LOWER(email) = LOWER(?) AND tenant_id = ? AND status <> ?", email, tenantID, "revoked").
+		Order("created_at ASC").Find(&nominees).Error
+	return nominees, err
+}
+
+func (r *NomineeRepository) UpdateNominee(n *models.Nominee) error {
+	return r.db.Where("id = ? AND tenant_id = ?", n.ID, n.TenantID).Save(n).Error
+}
+
+// Claim Methods
+
+func (r *NomineeRepository) CreateClaim(c *models.NomineeClaim) error {
+	return r.db.Create(c).Error
+}
+
+func (r *NomineeRepository) GetClaim(id, tenantID uuid.UUID) (*models.NomineeClaim, error) {
+	var c models.NomineeClaim
+	err := r.db.Preload("Evidence").First(&c, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &c, err
+}
+
+func (r *NomineeRepository) ListClaimsByStatus(tenantID uuid.UUID, status string) ([]models.NomineeClaim, error) {
+	var claims []models.NomineeClaim
+	q := r.db.Preload("Evidence").Where("tenant_id = ?", tenantID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Order("created_at DESC").Find(&claims).Error
+	return claims, err
+}
+
+// ListClaimsByNomineeEmail returns claims filed by any nominee record registered
+// under the given email, across principals within the tenant.
+func (r *NomineeRepository) ListClaimsByNomineeEmail(email string, tenantID uuid.UUID) ([]models.NomineeClaim, error) {
+	var claims []models.NomineeClaim
+	err := r.db.Joins("JOIN nominees ON nominees.id = nominee_claims.nominee_id").
+		Where("LOWER(nominees.email) = LOWER(?) AND nominee_claims.tenant_id = ?", email, tenantID).
+		Order("nominee_claims.created_at DESC").Find(&claims).Error
+	return claims, err
+}
+
+// FindApprovedClaim returns the approved claim that lets the nominee registered
+// under email act for principalID, if one exists.
+func (r *NomineeRepository) FindApprovedClaim(email string, principalID, tenantID uuid.UUID) (*models.NomineeClaim, error) {
+	var c models.NomineeClaim
+	err := r.db.Joins("JOIN nominees ON nominees.id = nominee_claims.nominee_id").
+		Where("LOWER(nominees.email) = LOWER(?) AND nominees.status = ?", email, "verified").
+		Where("nominee_claims.principal_id = ? AND nominee_claims.tenant_id = ? AND nominee_claims.status = ?", principalID, tenantID, "approved").
+		First(&c).Error
+	return &c, err
+}
+
+func (r *NomineeRepository) UpdateClaim(c *models.NomineeClaim) error {
+	return r.db.Where("id = ? AND tenant_id = ?", c.ID, c.TenantID).Omit("Evidence").Save(c).Error
+}
+
+func (r *NomineeRepository) AddEvidence(e *models.NomineeClaimEvidence) error {
+	return r.db.Create(e).Error
+}
+
+func (r *NomineeRepository) GetEvidence(claimID, id, tenantID uuid.UUID) (*models.NomineeClaimEvidence, error) {
+	var e models.NomineeClaimEvidence
+	err := r.db.First(&e, "id = ? AND claim_id = ? AND tenant_id = ?", id, claimID, tenantID).Error
+	return &e, err
+}
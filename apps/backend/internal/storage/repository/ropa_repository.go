@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RopaRepository stores the hand-filled ROPA fields and the generated
+// register versions of each organization entity
+type RopaRepository struct {
+	db *gorm.DB
+}
+
+func NewRopaRepository(db *gorm.DB) *RopaRepository {
+	return &RopaRepository{db: db}
+}
+
+func (r *RopaRepository) GetProfile(tenantID, entityID uuid.UUID) (*models.RopaProfile, error) {
+	var p models.RopaProfile
+	err := r.db.First(&p, "tenant_id = ? AND organization_entity_id = ?", tenantID, entityID).Error
+	return &p, err
+}
+
+// SaveProfile creates the entity's profile or replaces its fields
+func (r *RopaRepository) SaveProfile(p *models.RopaProfile) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "tenant_id"}, {Name: "organization_entity_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"controller_contact", "dpo_contact", "security_measures", "activities", "updated_by", "updated_at",
+		}),
+	}).Create(p).Error
+}
+
+// CreateVersion numbers and saves a new version, superseding any draft not
+// yet signed off. A concurrent generation taking the same number is retried.
+func (r *RopaRepository) CreateVersion(v *models.RopaVersion) error {
+	var err error
+	for attempt := 0; attempt < versionInsertAttempts; attempt++ {
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			var last int
+			if err := tx.Model(&models.RopaVersion{}).
+				Where("tenant_id = ? AND organization_entity_id = ?", v.TenantID, v.OrganizationEntityID).
+				Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.RopaVersion{}).
+				Where("tenant_id = ? AND organization_entity_id = ? AND status = ?", v.TenantID, v.OrganizationEntityID, models.RopaDraft).
+				Update("status", models.RopaSuperseded).Error; err != nil {
+				return err
+			}
+			v.Version = last + 1
+			return tx.Create(v).Error
+		})
+		if !isUniqueViolation(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *RopaRepository) GetVersion(tenantID, id uuid.UUID) (*models.RopaVersion, error) {
+	var v models.RopaVersion
+	err := r.db.First(&v, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &v, err
+}
+
+// ListVersions returns an entity's versions, newest first, without content
+func (r *RopaRepository) ListVersions(tenantID, entityID uuid.UUID) ([]models.RopaVersion, error) {
+	var list []models.RopaVersion
+	err := r.db.Omit("content").
+		Where("tenant_id = ? AND organization_entity_id = ?", tenantID, entityID).
+		Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+// LatestVersion returns the entity's newest version that is not superseded
+func (r *RopaRepository) LatestVersion(tenantID, entityID uuid.UUID) (*models.RopaVersion, error) {
+	var v models.RopaVersion
+	err := r.db.Where("tenant_id = ? AND organization_entity_id = ? AND status <> ?", tenantID, entityID, models.RopaSuperseded).
+		Order("version DESC").First(&v).Error
+	return &v, err
+}
+
+// SignOff marks a draft signed off, reporting whether it was still a draft
+func (r *RopaRepository) SignOff(id, by uuid.UUID, note string, at time.Time) (bool, error) {
+	res := r.db.Model(&models.RopaVersion{}).
+		Where("id = ? AND status = ?", id, models.RopaDraft).
+		Updates(map[string]interface{}{
+			"status": models.RopaSignedOff, "signed_off_by": by, "signed_off_at": at, "sign_off_note": note,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListEntities returns the tenant's organization entities
+func (r *RopaRepository) ListEntities(tenantID uuid.UUID) ([]models.OrganizationEntity, error) {
+	var list []models.OrganizationEntity
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *RopaRepository) GetEntity(tenantID, entityID uuid.UUID) (*models.OrganizationEntity, error) {
+	var e models.OrganizationEntity
+	err := r.db.First(&e, "tenant_id = ? AND id = ?", tenantID, entityID).Error
+	return &e, err
+}
+
+// ListEntityFormPurposes returns the purposes on the entity's consent forms
+func (r *RopaRepository) ListEntityFormPurposes(tenantID, entityID uuid.UUID) ([]models.ConsentFormPurpose, error) {
+	var list []models.ConsentFormPurpose
+	err := r.db.Joins("JOIN consent_forms ON consent_forms.id = consent_form_purposes.consent_form_id").
+		Where("consent_forms.tenant_id = ? AND consent_forms.organization_entity_id = ?", tenantID, entityID).
+		Find(&list).Error
+	return list, err
+}
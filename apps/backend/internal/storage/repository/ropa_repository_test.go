@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"testing"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRopaRepository_CreateVersionRetriesVersionConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.RopaVersion{}))
+	repo := NewRopaRepository(db)
+	tenantID, entityID := uuid.New(), uuid.New()
+
+	inserts := raceVersionedInsert(t, db, "ropa_versions", func(tx *gorm.DB, version int) error {
+		return tx.Exec("INSERT INTO ropa_versions (id, tenant_id, organization_entity_id, version, status) VALUES (?, ?, ?, ?, ?)",
+			uuid.New(), tenantID, entityID, version, models.RopaDraft).Error
+	})
+
+	v := &models.RopaVersion{ID: uuid.New(), TenantID: tenantID, OrganizationEntityID: entityID, Status: models.RopaDraft}
+	require.NoError(t, repo.CreateVersion(v))
+	assert.Equal(t, 2, *inserts)
+	assert.Equal(t, 1, v.Version)
+
+	// Versions are numbered per entity, and the index refuses a duplicate
+	other := &models.RopaVersion{ID: uuid.New(), TenantID: tenantID, OrganizationEntityID: uuid.New(), Status: models.RopaDraft}
+	require.NoError(t, repo.CreateVersion(other))
+	assert.Equal(t, 1, other.Version)
+	dup := &models.RopaVersion{ID: uuid.New(), TenantID: tenantID, OrganizationEntityID: entityID, Version: 1, Status: models.RopaDraft}
+	assert.True(t, isUniqueViolation(db.Create(dup).Error))
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SDFRepository stores a Significant Data Fiduciary's obligations calendar,
+// the evidence and completions recorded against it, and the DPO registry
+type SDFRepository struct {
+	db *gorm.DB
+}
+
+func NewSDFRepository(db *gorm.DB) *SDFRepository {
+	return &SDFRepository{db: db}
+}
+
+func (r *SDFRepository) CreateObligations(list []models.SDFObligation) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return r.db.Create(&list).Error
+}
+
+func (r *SDFRepository) CreateObligation(o *models.SDFObligation) error {
+	return r.db.Create(o).Error
+}
+
+func (r *SDFRepository) SaveObligation(o *models.SDFObligation) error {
+	return r.db.Save(o).Error
+}
+
+func (r *SDFRepository) GetObligation(tenantID, id uuid.UUID) (*models.SDFObligation, error) {
+	var o models.SDFObligation
+	err := r.db.First(&o, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &o, err
+}
+
+// ListObligations returns the tenant's obligations, soonest due first and
+// completed one-offs last
+func (r *SDFRepository) ListObligations(tenantID uuid.UUID) ([]models.SDFObligation, error) {
+	var list []models.SDFObligation
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("CASE WHEN next_due_at IS NULL THEN 1 ELSE 0 END, next_due_at ASC, title ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *SDFRepository) CountObligations(tenantID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.SDFObligation{}).Where("tenant_id = ?", tenantID).Count(&n).Error
+	return n, err
+}
+
+// CountDue counts the tenant's open obligations due before soon, and how many
+// of those are already overdue at now
+func (r *SDFRepository) CountDue(tenantID uuid.UUID, now, soon time.Time) (overdue, dueSoon int64, err error) {
+	q := r.db.Model(&models.SDFObligation{}).Where("tenant_id = ? AND status = ?", tenantID, models.SDFObligationOpen)
+	if err = q.Session(&gorm.Session{}).Where("next_due_at < ?", now).Count(&overdue).Error; err != nil {
+		return
+	}
+	err = q.Session(&gorm.Session{}).Where("next_due_at >= ? AND next_due_at < ?", now, soon).Count(&dueSoon).Error
+	return
+}
+
+// DeleteObligation removes an obligation and the evidence not yet attached
+// to a completion
+func (r *SDFRepository) DeleteObligation(tenantID, id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("obligation_id = ? AND completion_id IS NULL", id).Delete(&models.SDFEvidence{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&models.SDFObligation{}).Error
+	})
+}
+
+// ListOverdue returns open obligations of designated tenants that are past
+// due and whose owners have not been told yet
+func (r *SDFRepository) ListOverdue(now time.Time) ([]models.SDFObligation, error) {
+	var list []models.SDFObligation
+	err := r.db.Joins("JOIN tenants ON tenants.tenant_id = sdf_obligations.tenant_id").
+		Where("tenants.significant_data_fiduciary = ?", true).
+		Where("sdf_obligations.status = ? AND sdf_obligations.next_due_at < ? AND sdf_obligations.overdue_notified_at IS NULL",
+			models.SDFObligationOpen, now).
+		Find(&list).Error
+	return list, err
+}
+
+func (r *SDFRepository) MarkOverdueNotified(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.SDFObligation{}).Where("id = ?", id).Update("overdue_notified_at", at).Error
+}
+
+// Complete records a completion, attaches the obligation's pending evidence
+// to it and moves the obligation on
+func (r *SDFRepository) Complete(c *models.SDFObligationCompletion, fields map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(c).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SDFEvidence{}).
+			Where("obligation_id = ? AND completion_id IS NULL", c.ObligationID).
+			Update("completion_id", c.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SDFObligation{}).Where("id = ?", c.ObligationID).Updates(fields).Error
+	})
+}
+
+func (r *SDFRepository) ListCompletions(tenantID, obligationID uuid.UUID) ([]models.SDFObligationCompletion, error) {
+	var list []models.SDFObligationCompletion
+	err := r.db.Where("tenant_id = ? AND obligation_id = ?", tenantID, obligationID).Order("completed_at DESC").Find(&list).Error
+	return list, err
+}
+
+// ListCompletionsBetween returns the tenant's completions of due dates
+// falling in [from, to)
+func (r *SDFRepository) ListCompletionsBetween(tenantID uuid.UUID, from, to time.Time) ([]models.SDFObligationCompletion, error) {
+	var list []models.SDFObligationCompletion
+	err := r.db.Where("tenant_id = ? AND due_at >= ? AND due_at < ?", tenantID, from, to).Order("due_at ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *SDFRepository) CreateEvidence(e *models.SDFEvidence) error {
+	return r.db.Create(e).Error
+}
+
+func (r *SDFRepository) GetEvidence(tenantID, id uuid.UUID) (*models.SDFEvidence, error) {
+	var e models.SDFEvidence
+	err := r.db.First(&e, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &e, err
+}
+
+// ListEvidence returns an obligation's evidence, newest first
+func (r *SDFRepository) ListEvidence(tenantID, obligationID uuid.UUID) ([]models.SDFEvidence, error) {
+	var list []models.SDFEvidence
+	err := r.db.Where("tenant_id = ? AND obligation_id = ?", tenantID, obligationID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// CountPendingEvidence counts evidence not yet attached to a completion
+func (r *SDFRepository) CountPendingEvidence(obligationID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.SDFEvidence{}).Where("obligation_id = ? AND completion_id IS NULL", obligationID).Count(&n).Error
+	return n, err
+}
+
+// CurrentDPO returns the tenant's active DPO, or gorm.ErrRecordNotFound
+func (r *SDFRepository) CurrentDPO(tenantID uuid.UUID) (*models.DataProtectionOfficer, error) {
+	var d models.DataProtectionOfficer
+	err := r.db.Where("tenant_id = ? AND active = ?", tenantID, true).Order("appointed_at DESC").First(&d).Error
+	return &d, err
+}
+
+func (r *SDFRepository) GetDPO(tenantID, id uuid.UUID) (*models.DataProtectionOfficer, error) {
+	var d models.DataProtectionOfficer
+	err := r.db.First(&d, "tenant_id = ? AND id = ?", tenantID, id).Error
+	return &d, err
+}
+
+// ListDPOs returns every appointment, most recent first
+func (r *SDFRepository) ListDPOs(tenantID uuid.UUID) ([]models.DataProtectionOfficer, error) {
+	var list []models.DataProtectionOfficer
+	err := r.db.Where("tenant_id = ?", tenantID).Order("appointed_at DESC, created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// AppointDPO ends the tenant's current appointment and records the new one
+func (r *SDFRepository) AppointDPO(d *models.DataProtectionOfficer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DataProtectionOfficer{}).
+			Where("tenant_id = ? AND active = ?", d.TenantID, true).
+			Updates(map[string]interface{}{"active": false, "ended_at": d.AppointedAt}).Error; err != nil {
+			return err
+		}
+		return tx.Create(d).Error
+	})
+}
+
+func (r *SDFRepository) UpdateDPO(d *models.DataProtectionOfficer) error {
+	return r.db.Save(d).Error
+}
+
+// ListUserIDsWithPermission returns the fiduciary users in the tenant whose
+// roles grant the named permission
+func (r *SDFRepository) ListUserIDsWithPermission(tenantID uuid.UUID, permission string) ([]uuid.UUID, error) {
+	return listUserIDsWithPermission(r.db, tenantID, permission)
+}
+
+// UserName returns a fiduciary user's name, falling back to their email, or
+// gorm.ErrRecordNotFound when they are not in the tenant
+func (r *SDFRepository) UserName(tenantID, userID uuid.UUID) (string, error) {
+	var u models.FiduciaryUser
+	if err := r.db.Select("id", "name", "email").First(&u, "tenant_id = ? AND id = ?", tenantID, userID).Error; err != nil {
+		return "", err
+	}
+	if u.Name != "" {
+		return u.Name, nil
+	}
+	return u.Email, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubProcessorRepository stores vendors' sub-processor declarations and
+// their history
+type SubProcessorRepository struct {
+	db *gorm.DB
+}
+
+func NewSubProcessorRepository(db *gorm.DB) *SubProcessorRepository {
+	return &SubProcessorRepository{db: db}
+}
+
+// Create stores a declaration with its opening history entry
+func (r *SubProcessorRepository) Create(sp *models.VendorSubProcessor, event *models.VendorSubProcessorEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sp).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+func (r *SubProcessorRepository) Get(tenantID, id uuid.UUID) (*models.VendorSubProcessor, error) {
+	var sp models.VendorSubProcessor
+	err := r.db.First(&sp, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &sp, err
+}
+
+// ListByVendor returns a vendor's declarations, newest first, optionally
+// only those in the given statuses
+func (r *SubProcessorRepository) ListByVendor(tenantID, vendorID uuid.UUID, statuses ...string) ([]models.VendorSubProcessor, error) {
+	var list []models.VendorSubProcessor
+	q := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID)
+	if len(statuses) > 0 {
+		q = q.Where("status IN ?", statuses)
+	}
+	err := q.Order("proposed_at DESC").Find(&list).Error
+	return list, err
+}
+
+// ListByStatus returns the tenant's declarations in one status across vendors
+func (r *SubProcessorRepository) ListByStatus(tenantID uuid.UUID, status string) ([]models.VendorSubProcessor, error) {
+	var list []models.VendorSubProcessor
+	err := r.db.Where("tenant_id = ? AND status = ?", tenantID, status).Order("proposed_at ASC").Find(&list).Error
+	return list, err
+}
+
+// ListApproved returns the approved sub-processors of the given vendors
+func (r *SubProcessorRepository) ListApproved(tenantID uuid.UUID, vendorIDs []uuid.UUID) ([]models.VendorSubProcessor, error) {
+	var list []models.VendorSubProcessor
+	if len(vendorIDs) == 0 {
+		return list, nil
+	}
+	err := r.db.Where("tenant_id = ? AND vendor_id IN ? AND status = ?", tenantID, vendorIDs, models.SubProcessorApproved).
+		Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// ListLapsed returns proposals whose objection window closed before now and
+// that have not yet been dealt with after lapsing
+func (r *SubProcessorRepository) ListLapsed(now time.Time) ([]models.VendorSubProcessor, error) {
+	var list []models.VendorSubProcessor
+	err := r.db.Where("status = ? AND objection_deadline <= ? AND lapse_notified_at IS NULL", models.SubProcessorProposed, now).
+		Order("objection_deadline ASC").Find(&list).Error
+	return list, err
+}
+
+// Transition moves a declaration out of status from, applying fields and
+// recording events. When replaces is set, that approved declaration is
+// marked replaced in the same transaction. It reports whether this caller
+// made the change.
+func (r *SubProcessorRepository) Transition(id uuid.UUID, from string, fields map[string]interface{}, replaces *uuid.UUID, events []models.VendorSubProcessorEvent) (bool, error) {
+	changed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		fields["updated_at"] = time.Now()
+		res := tx.Model(&models.VendorSubProcessor{}).Where("id = ? AND status = ?", id, from).Updates(fields)
+		if res.Error != nil || res.RowsAffected != 1 {
+			return res.Error
+		}
+		if replaces != nil {
+			err := tx.Model(&models.VendorSubProcessor{}).Where("id = ? AND status = ?", *replaces, models.SubProcessorApproved).
+				Updates(map[string]interface{}{"status": models.SubProcessorReplaced, "removed_at": time.Now(), "updated_at": time.Now()}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+func (r *SubProcessorRepository) MarkLapseNotified(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.VendorSubProcessor{}).Where("id = ?", id).Update("lapse_notified_at", at).Error
+}
+
+// ListEvents returns the history of a vendor's declarations, oldest first
+func (r *SubProcessorRepository) ListEvents(tenantID, vendorID uuid.UUID) ([]models.VendorSubProcessorEvent, error) {
+	var list []models.VendorSubProcessorEvent
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+// ListUserIDsWithPermission returns the fiduciary users in the tenant whose
+// roles grant the named permission
+func (r *SubProcessorRepository) ListUserIDsWithPermission(tenantID uuid.UUID, permission string) ([]uuid.UUID, error) {
+	return listUserIDsWithPermission(r.db, tenantID, permission)
+}
+
+func listUserIDsWithPermission(db *gorm.DB, tenantID uuid.UUID, permission string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.Table("fiduciary_users").
+		Select("DISTINCT fiduciary_users.id").
+		Joins("JOIN fiduciary_user_roles ON fiduciary_user_roles.fiduciary_user_id = fiduciary_users.id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = fiduciary_user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("fiduciary_users.tenant_id = ? AND permissions.name = ?", tenantID, permission).
+		Order("fiduciary_users.id").
+		Pluck("fiduciary_users.id", &ids).Error
+	return ids, err
+}
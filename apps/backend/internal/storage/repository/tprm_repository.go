@@ -115,6 +115,62 @@ func (r *TPRMRepository) GetDPATemplate(id uuid.UUID) (*models.DPATemplate, erro
 	return &t, err
 }
 
+func (r *TPRMRepository) ListDPATemplates(tenantID uuid.UUID) ([]models.DPATemplate, error) {
+	var list []models.DPATemplate
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// AddDPATemplateVersion stores a new version and makes it the template's
+// current content; the template row is created if it is new
+func (r *TPRMRepository) AddDPATemplateVersion(t *models.DPATemplate, v *models.DPATemplateVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(t).Error; err != nil {
+			return err
+		}
+		return tx.Create(v).Error
+	})
+}
+
+func (r *TPRMRepository) GetDPATemplateVersion(templateID uuid.UUID, version int) (*models.DPATemplateVersion, error) {
+	var v models.DPATemplateVersion
+	err := r.db.First(&v, "template_id = ? AND version = ?", templateID, version).Error
+	return &v, err
+}
+
+func (r *TPRMRepository) ListDPATemplateVersions(templateID uuid.UUID) ([]models.DPATemplateVersion, error) {
+	var list []models.DPATemplateVersion
+	err := r.db.Where("template_id = ?", templateID).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+// DPA clause library
+
+// AddDPAClauseVersion stores a new version of a clause and retires the
+// previous current version
+func (r *TPRMRepository) AddDPAClauseVersion(c *models.DPAClause) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DPAClause{}).
+			Where("tenant_id = ? AND key = ? AND is_current = ?", c.TenantID, c.Key, true).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	})
+}
+
+func (r *TPRMRepository) ListCurrentDPAClauses(tenantID uuid.UUID) ([]models.DPAClause, error) {
+	var list []models.DPAClause
+	err := r.db.Where("tenant_id = ? AND is_current = ?", tenantID, true).Order("key ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *TPRMRepository) ListDPAClauseVersions(tenantID uuid.UUID, key string) ([]models.DPAClause, error) {
+	var list []models.DPAClause
+	err := r.db.Where("tenant_id = ? AND key = ?", tenantID, key).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
 // DPA Agreements
 func (r *TPRMRepository) CreateDPAAgreement(a *models.DPAAgreement) error {
 	return r.db.Create(a).Error
@@ -124,6 +180,18 @@ func (r *TPRMRepository) UpdateDPAAgreement(a *models.DPAAgreement) error {
 	return r.db.Save(a).Error
 }
 
+func (r *TPRMRepository) GetDPAAgreement(id uuid.UUID) (*models.DPAAgreement, error) {
+	var a models.DPAAgreement
+	err := r.db.First(&a, "id = ?", id).Error
+	return &a, err
+}
+
+func (r *TPRMRepository) ListDPAAgreementsByVendor(tenantID, vendorID uuid.UUID) ([]models.DPAAgreement, error) {
+	var list []models.DPAAgreement
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
 // Audit Responses
 func (r *TPRMRepository) SaveAuditResponse(resp *models.AuditResponse) error {
 	// Upsert based on AssessmentID + QuestionID
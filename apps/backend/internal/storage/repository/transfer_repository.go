@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransferRepository stores each tenant's restricted-country list
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferRepository(db *gorm.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+func (r *TransferRepository) ListRestricted(tenantID uuid.UUID) ([]models.RestrictedCountry, error) {
+	var list []models.RestrictedCountry
+	err := r.db.Where("tenant_id = ?", tenantID).Order("country_code ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *TransferRepository) GetRestricted(tenantID uuid.UUID, code string) (*models.RestrictedCountry, error) {
+	var rc models.RestrictedCountry
+	err := r.db.First(&rc, "tenant_id = ? AND country_code = ?", tenantID, code).Error
+	return &rc, err
+}
+
+// UpsertRestricted adds a country to the list or updates its entry
+func (r *TransferRepository) UpsertRestricted(rc *models.RestrictedCountry) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "country_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "reason", "reference", "updated_by", "updated_at"}),
+	}).Create(rc).Error
+}
+
+// DeleteRestricted removes a country from the list, reporting whether it was
+// on it
+func (r *TransferRepository) DeleteRestricted(tenantID uuid.UUID, code string) (bool, error) {
+	res := r.db.Where("tenant_id = ? AND country_code = ?", tenantID, code).Delete(&models.RestrictedCountry{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VendorPortalRepository backs the vendor self-service portal: invitations,
+// and the vendor-scoped views of assessments, findings and DPAs
+type VendorPortalRepository struct {
+	db *gorm.DB
+}
+
+func NewVendorPortalRepository(db *gorm.DB) *VendorPortalRepository {
+	return &VendorPortalRepository{db: db}
+}
+
+// Invitations
+
+func (r *VendorPortalRepository) CreateInvitation(inv *models.VendorPortalInvitation) error {
+	return r.db.Create(inv).Error
+}
+
+func (r *VendorPortalRepository) UpdateInvitation(inv *models.VendorPortalInvitation) error {
+	return r.db.Save(inv).Error
+}
+
+func (r *VendorPortalRepository) GetInvitation(tenantID, id uuid.UUID) (*models.VendorPortalInvitation, error) {
+	var inv models.VendorPortalInvitation
+	err := r.db.First(&inv, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &inv, err
+}
+
+func (r *VendorPortalRepository) GetInvitationByTokenHash(hash string) (*models.VendorPortalInvitation, error) {
+	var inv models.VendorPortalInvitation
+	err := r.db.First(&inv, "token_hash = ?", hash).Error
+	return &inv, err
+}
+
+func (r *VendorPortalRepository) ListInvitations(tenantID, vendorID uuid.UUID) ([]models.VendorPortalInvitation, error) {
+	var list []models.VendorPortalInvitation
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// ListActiveInvitations returns the vendor's unrevoked, unexpired invitations
+func (r *VendorPortalRepository) ListActiveInvitations(tenantID, vendorID uuid.UUID, now time.Time) ([]models.VendorPortalInvitation, error) {
+	var list []models.VendorPortalInvitation
+	err := r.db.Where("tenant_id = ? AND vendor_id = ? AND revoked_at IS NULL AND expires_at > ?", tenantID, vendorID, now).
+		Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *VendorPortalRepository) TouchInvitation(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.VendorPortalInvitation{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// GetVendor returns a vendor the tenant has engaged, through an assessment or
+// a DPA, or gorm.ErrRecordNotFound. The vendor register itself is shared
+// across tenants.
+func (r *VendorPortalRepository) GetVendor(tenantID, id uuid.UUID) (*models.Vendor, error) {
+	var v models.Vendor
+	err := r.db.Where("vendor_id = ?", id).
+		Where("EXISTS (SELECT 1 FROM tprm_assessments WHERE tprm_assessments.tenant_id = ? AND tprm_assessments.vendor_id = vendors.vendor_id)"+
+			" OR EXISTS (SELECT 1 FROM dpa_agreements WHERE dpa_agreements.tenant_id = ? AND dpa_agreements.vendor_id = vendors.vendor_id)",
+			tenantID, tenantID).
+		First(&v).Error
+	return &v, err
+}
+
+// Assessments
+
+func (r *VendorPortalRepository) ListVendorAssessments(tenantID, vendorID uuid.UUID) ([]models.TPRMAssessment, error) {
+	var list []models.TPRMAssessment
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+// ListAssessmentsDueBefore returns open assessments with a due date before
+// the cutoff, across tenants
+func (r *VendorPortalRepository) ListAssessmentsDueBefore(cutoff time.Time) ([]models.TPRMAssessment, error) {
+	var list []models.TPRMAssessment
+	err := r.db.Where("due_date IS NOT NULL AND due_date <= ? AND status NOT IN ?", cutoff, []string{"completed", "failed"}).
+		Order("due_date ASC").Find(&list).Error
+	return list, err
+}
+
+// RecordReminder stores a reminder stage and reports whether it was new
+func (r *VendorPortalRepository) RecordReminder(rem *models.VendorAssessmentReminder) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(rem)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Findings
+
+func (r *VendorPortalRepository) ListVendorFindings(tenantID, vendorID uuid.UUID) ([]models.TPRMFinding, error) {
+	var list []models.TPRMFinding
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *VendorPortalRepository) GetFinding(tenantID, id uuid.UUID) (*models.TPRMFinding, error) {
+	var f models.TPRMFinding
+	err := r.db.First(&f, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &f, err
+}
+
+// AddFindingResponse stores the response and moves the finding on
+func (r *VendorPortalRepository) AddFindingResponse(resp *models.VendorFindingResponse, findingStatus string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(resp).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.TPRMFinding{}).Where("id = ?", resp.FindingID).Update("status", findingStatus).Error
+	})
+}
+
+func (r *VendorPortalRepository) ListFindingResponses(findingID uuid.UUID) ([]models.VendorFindingResponse, error) {
+	var list []models.VendorFindingResponse
+	err := r.db.Where("finding_id = ?", findingID).Order("created_at ASC").Find(&list).Error
+	return list, err
+}
+
+// DPAs
+
+func (r *VendorPortalRepository) ListVendorDPAs(tenantID, vendorID uuid.UUID) ([]models.DPAAgreement, error) {
+	var list []models.DPAAgreement
+	err := r.db.Where("tenant_id = ? AND vendor_id = ?", tenantID, vendorID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *VendorPortalRepository) GetDPA(tenantID, id uuid.UUID) (*models.DPAAgreement, error) {
+	var a models.DPAAgreement
+	err := r.db.First(&a, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &a, err
+}
+
+func (r *VendorPortalRepository) UpdateDPA(a *models.DPAAgreement) error {
+	return r.db.Save(a).Error
+}
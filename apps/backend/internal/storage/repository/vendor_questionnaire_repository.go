@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"time"
+
+	"pixpivot/arc/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VendorQuestionnaireRepository stores questionnaires, their versions and
+// the answers given in questionnaire assessments
+type VendorQuestionnaireRepository struct {
+	db *gorm.DB
+}
+
+func NewVendorQuestionnaireRepository(db *gorm.DB) *VendorQuestionnaireRepository {
+	return &VendorQuestionnaireRepository{db: db}
+}
+
+// Create stores a new questionnaire with its first version
+func (r *VendorQuestionnaireRepository) Create(q *models.VendorQuestionnaire, v *models.VendorQuestionnaireVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(q).Error; err != nil {
+			return err
+		}
+		return tx.Create(v).Error
+	})
+}
+
+func (r *VendorQuestionnaireRepository) Update(q *models.VendorQuestionnaire) error {
+	return r.db.Save(q).Error
+}
+
+func (r *VendorQuestionnaireRepository) GetByID(tenantID, id uuid.UUID) (*models.VendorQuestionnaire, error) {
+	var q models.VendorQuestionnaire
+	err := r.db.First(&q, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &q, err
+}
+
+func (r *VendorQuestionnaireRepository) ListByTenant(tenantID uuid.UUID) ([]models.VendorQuestionnaire, error) {
+	var list []models.VendorQuestionnaire
+	err := r.db.Where("tenant_id = ?", tenantID).Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+// Versions
+
+// AddVersion stores a new version and bumps the questionnaire's latest version
+func (r *VendorQuestionnaireRepository) AddVersion(q *models.VendorQuestionnaire, v *models.VendorQuestionnaireVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+		return tx.Model(q).Update("latest_version", v.Version).Error
+	})
+}
+
+func (r *VendorQuestionnaireRepository) UpdateVersion(v *models.VendorQuestionnaireVersion) error {
+	return r.db.Save(v).Error
+}
+
+func (r *VendorQuestionnaireRepository) GetVersion(questionnaireID uuid.UUID, version int) (*models.VendorQuestionnaireVersion, error) {
+	var v models.VendorQuestionnaireVersion
+	err := r.db.First(&v, "questionnaire_id = ? AND version = ?", questionnaireID, version).Error
+	return &v, err
+}
+
+func (r *VendorQuestionnaireRepository) GetVersionByID(tenantID, id uuid.UUID) (*models.VendorQuestionnaireVersion, error) {
+	var v models.VendorQuestionnaireVersion
+	err := r.db.First(&v, "id = ? AND tenant_id = ?", id, tenantID).Error
+	return &v, err
+}
+
+func (r *VendorQuestionnaireRepository) ListVersions(questionnaireID uuid.UUID) ([]models.VendorQuestionnaireVersion, error) {
+	var list []models.VendorQuestionnaireVersion
+	err := r.db.Where("questionnaire_id = ?", questionnaireID).Order("version DESC").Find(&list).Error
+	return list, err
+}
+
+// Publish makes the version the questionnaire's published one; the version
+// published before it is superseded
+func (r *VendorQuestionnaireRepository) Publish(q *models.VendorQuestionnaire, v *models.VendorQuestionnaireVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.VendorQuestionnaireVersion{}).
+			Where("questionnaire_id = ? AND status = ?", q.ID, models.QuestionnaireVersionPublished).
+			Update("status", models.QuestionnaireVersionSuperseded).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(v).Error; err != nil {
+			return err
+		}
+		return tx.Model(q).Update("published_version", v.Version).Error
+	})
+}
+
+// Answers
+
+// SaveAnswer inserts or replaces the answer to a question in an assessment
+func (r *VendorQuestionnaireRepository) SaveAnswer(a *models.VendorQuestionnaireAnswer) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "assessment_id"}, {Name: "question_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"question_type", "value", "evidence_id", "comments", "reused_from_id", "answered_by", "answered_at", "updated_at",
+		}),
+	}).Create(a).Error
+}
+
+func (r *VendorQuestionnaireRepository) ListAnswers(assessmentID uuid.UUID) ([]models.VendorQuestionnaireAnswer, error) {
+	var list []models.VendorQuestionnaireAnswer
+	err := r.db.Where("assessment_id = ?", assessmentID).Order("question_id ASC").Find(&list).Error
+	return list, err
+}
+
+// ListVendorAnswers returns the vendor's answers from other assessments given
+// since the cutoff, newest first
+func (r *VendorQuestionnaireRepository) ListVendorAnswers(tenantID, vendorID, excludeAssessmentID uuid.UUID, since time.Time) ([]models.VendorQuestionnaireAnswer, error) {
+	var list []models.VendorQuestionnaireAnswer
+	err := r.db.Where("tenant_id = ? AND vendor_id = ? AND assessment_id <> ? AND answered_at >= ?", tenantID, vendorID, excludeAssessmentID, since).
+		Order("answered_at DESC").Find(&list).Error
+	return list, err
+}
@@ -143,3 +143,49 @@ Sincerely,
 {{company_name}}
 {{submission_date}}
 `
+
+// CERTInIncidentReportTemplate lays out a cyber incident report in the fields
+// of CERT-In's incident reporting form, for email to incident@cert-in.org.in
+const CERTInIncidentReportTemplate = `Indian Computer Emergency Response Team (CERT-In)
+incident@cert-in.org.in
+
+INCIDENT REPORTING FORM
+
+1. Organisation
+   Name: {{company_name}}
+   Sector: {{sector}}
+   Address: {{organisation_address}}
+
+2. Point of Contact
+   Name: {{poc_name}}
+   Designation: {{poc_designation}}
+   Email: {{poc_email}}
+   Phone: {{poc_phone}}
+
+3. Type of incident: {{certin_incident_number}}. {{certin_incident_type}}
+
+4. Date and time of occurrence: {{occurred_at}}
+5. Date and time the incident was noticed: {{detected_at}}
+6. Location of affected systems: {{location}}
+
+7. Affected systems/network (name, IP address, hostname, operating system):
+{{affected_systems}}
+
+8. Affected system is critical to the organisation's mission: {{critical_system}}
+
+9. Description of the incident:
+{{breach_description}}
+
+10. Unusual behaviour/symptoms observed:
+{{symptoms}}
+
+11. Personal data affected: {{data_categories}} ({{affected_count}} Data Principals)
+
+12. Actions taken to mitigate the incident:
+{{remedial_actions}}
+
+13. Incident also reported to: {{reported_elsewhere}}
+
+Internal reference: {{breach_id}}
+Reported under CERT-In Directions No. 20(3)/2022-CERT-In of 28 April 2022, which require reporting within 6 hours of noticing the incident.
+`
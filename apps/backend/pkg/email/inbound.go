@@ -0,0 +1,212 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// InboundMessage is a parsed inbound email.
+type InboundMessage struct {
+	MessageID   string
+	InReplyTo   string
+	From        string   // Bare sender address, lower-cased
+	To          []string // Recipient addresses from To, Cc and the delivery headers, lower-cased
+	FromName    string
+	Subject     string
+	Text        string
+	Attachments []InboundAttachment
+
+	// Set when the message is a bounce or automatic reply that must not open a thread
+	Automated       bool
+	AutomatedReason string
+}
+
+type InboundAttachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+const maxInboundParts = 50
+
+var htmlTagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// ParseInbound parses a raw RFC 5322 message, decoding transfer encodings and
+// multipart bodies. The first text/plain part is used as the body, falling back
+// to text/html with tags stripped.
+func ParseInbound(raw []byte) (*InboundMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	m := &InboundMessage{
+		MessageID: strings.Trim(msg.Header.Get("Message-ID"), "<> "),
+		InReplyTo: strings.Trim(msg.Header.Get("In-Reply-To"), "<> "),
+		Subject:   strings.TrimSpace(subject),
+	}
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		m.From = strings.ToLower(from[0].Address)
+		m.FromName = from[0].Name
+	} else {
+		return nil, errors.New("message has no valid From address")
+	}
+
+	// The delivery headers carry the envelope recipient when the mailbox is
+	// reached by forwarding or Bcc
+	for _, h := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		if list, err := msg.Header.AddressList(h); err == nil {
+			for _, a := range list {
+				m.To = append(m.To, strings.ToLower(a.Address))
+			}
+		}
+	}
+
+	m.Automated, m.AutomatedReason = isAutomated(msg.Header, m.From, m.Subject)
+
+	var html string
+	parts := 0
+	if err := walkPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body, m, &html, &parts); err != nil {
+		return nil, err
+	}
+	if m.Text == "" && html != "" {
+		m.Text = strings.TrimSpace(htmlTagPattern.ReplaceAllString(html, ""))
+	}
+	return m, nil
+}
+
+func walkPart(contentType, transferEncoding, disposition string, body io.Reader, m *InboundMessage, html *string, parts *int) error {
+	*parts++
+	if *parts > maxInboundParts {
+		return errors.New("message has too many parts")
+	}
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart body: %w", err)
+			}
+			if err := walkPart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p.Header.Get("Content-Disposition"), p, m, html, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(body, transferEncoding))
+	if err != nil {
+		return fmt.Errorf("failed to decode part: %w", err)
+	}
+
+	dispType, dispParams, _ := mime.ParseMediaType(disposition)
+	fileName := dispParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	if dispType == "attachment" || fileName != "" {
+		if fileName == "" {
+			fileName = "attachment"
+		}
+		m.Attachments = append(m.Attachments, InboundAttachment{FileName: fileName, ContentType: mediaType, Data: data})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/plain":
+		if m.Text == "" {
+			m.Text = strings.TrimSpace(string(data))
+		}
+	case "text/html":
+		if *html == "" {
+			*html = string(data)
+		}
+	}
+	return nil
+}
+
+func decodeTransfer(r io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// newlineStripper drops CR/LF so line-wrapped base64 decodes cleanly.
+type newlineStripper struct{ r io.Reader }
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	c, err := n.r.Read(buf)
+	j := 0
+	for _, b := range buf[:c] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// isAutomated detects delivery status notifications and automatic replies
+// (RFC 3464 and RFC 3834, plus common vendor headers).
+func isAutomated(h mail.Header, from, subject string) (bool, string) {
+	if ct := strings.ToLower(h.Get("Content-Type")); strings.Contains(ct, "multipart/report") {
+		return true, "delivery status report"
+	}
+	local := from
+	if i := strings.Index(from, "@"); i >= 0 {
+		local = from[:i]
+	}
+	if local == "mailer-daemon" || local == "postmaster" {
+		return true, "bounce from " + local
+	}
+	if rp := strings.TrimSpace(h.Get("Return-Path")); rp == "<>" {
+		return true, "null return path"
+	}
+	if as := strings.ToLower(h.Get("Auto-Submitted")); as != "" && as != "no" {
+		return true, "auto-submitted: " + as
+	}
+	switch strings.ToLower(h.Get("Precedence")) {
+	case "bulk", "junk", "list", "auto_reply":
+		return true, "precedence: " + strings.ToLower(h.Get("Precedence"))
+	}
+	if h.Get("X-Autoreply") != "" || h.Get("X-Autorespond") != "" {
+		return true, "auto-reply header"
+	}
+	lower := strings.ToLower(subject)
+	for _, prefix := range []string{"auto:", "automatic reply", "out of office", "autoreply", "undeliverable", "delivery status notification"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true, "auto-reply subject"
+		}
+	}
+	return false, ""
+}
@@ -62,3 +62,53 @@ func ParseReviewToken(tokenStr string) (*ReviewClaims, error) {
 	return claims, nil
 }
 
+
+// DSRStatusClaims scope a public status link to a single data subject request.
+type DSRStatusClaims struct {
+	RequestID   string `json:"requestId"`
+	TenantID    string `json:"tenantId"`
+	TrackingRef string `json:"ref"`
+	jwt.RegisteredClaims
+}
+
+// GenerateDSRStatusToken signs a token for the public DSR status page.
+func GenerateDSRStatusToken(requestID, tenantID, trackingRef string, ttl time.Duration) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("JWT secret not initialized")
+	}
+	claims := DSRStatusClaims{
+		RequestID:   requestID,
+		TenantID:    tenantID,
+		TrackingRef: trackingRef,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "consent-manager",
+			Subject:   "dsr-status",
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
+
+// ParseDSRStatusToken validates a public DSR status token.
+func ParseDSRStatusToken(tokenStr string) (*DSRStatusClaims, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("JWT secret not initialized")
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, &DSRStatusClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("token parse error: %w", err)
+	}
+
+	claims, ok := token.Claims.(*DSRStatusClaims)
+	if !ok || !token.Valid || claims.Subject != "dsr-status" {
+		return nil, errors.New("invalid or expired token claims")
+	}
+	return claims, nil
+}